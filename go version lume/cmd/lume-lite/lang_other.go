@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// windowsUILanguage names no language outside Windows, where the locale variables do.
+func windowsUILanguage() string { return "" }
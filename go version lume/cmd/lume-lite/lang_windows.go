@@ -0,0 +1,23 @@
+//go:build windows
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// localeNameMax is LOCALE_NAME_MAX_LENGTH, in UTF-16 code units with the terminating zero.
+const localeNameMax = 85
+
+// windowsUILanguage is the Windows display language, GetUserDefaultUILanguage as a locale name such
+// as "de-DE". It is only asked when nothing else names a language.
+func windowsUILanguage() string {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	id, _, _ := kernel32.NewProc("GetUserDefaultUILanguage").Call()
+	name := make([]uint16, localeNameMax)
+	if n, _, _ := kernel32.NewProc("LCIDToLocaleName").Call(id, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name)), 0); n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(name)
+}
@@ -0,0 +1,1290 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"lume-go/internal/audit"
+	"lume-go/internal/catalog"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/fsys"
+	"lume-go/internal/history"
+	"lume-go/internal/hook"
+	"lume-go/internal/i18n"
+	"lume-go/internal/lock"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/plan"
+	"lume-go/internal/ratelimit"
+	"lume-go/internal/routing"
+	"lume-go/internal/scan"
+	"lume-go/internal/sums"
+	"lume-go/internal/units"
+	"lume-go/internal/validator"
+	"lume-go/internal/version"
+	"lume-go/pkg/lume"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppVersion is the version lume-lite reports, Lume's with a -LITE suffix.
+var AppVersion = lume.Version() + "-LITE"
+
+func main() {
+	if err := pickLanguage(takeLangFlag()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(engine.ExitUsage)
+	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "stats":
+			os.Exit(runStats(os.Args[2:]))
+		case "rebuild":
+			os.Exit(runRebuild(os.Args[2:]))
+		case "reorg":
+			os.Exit(runReorg(os.Args[2:]))
+		case "audit":
+			os.Exit(runAudit(os.Args[2:]))
+		case "where":
+			os.Exit(runWhere(os.Args[2:]))
+		case "manifest":
+			os.Exit(runManifest(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		}
+	}
+
+	dryRun := flag.Bool("dry-run", false, T("lite_flag_dry_run"))
+	pruneEmpty := flag.Bool("prune-empty", false, T("lite_flag_prune_empty"))
+	batchSize := flag.Int("batch", 10000, T("lite_flag_batch"))
+	nearDup := flag.Bool("near-dup", false, T("lite_flag_near_dup"))
+	nearDist := flag.Int("near-dist", 10, T("lite_flag_near_dist"))
+	template := flag.String("template", "{year}/{month}", T("lite_flag_template"))
+	layoutName := flag.String("layout", "", T("lite_flag_layout"))
+	structure := flag.Bool("preserve-structure", false, T("lite_flag_preserve_structure"))
+	minSize := flag.String("min-size", "", T("lite_flag_min_size"))
+	maxSize := flag.String("max-size", "", T("lite_flag_max_size"))
+	minMP := flag.Float64("min-mp", 0, T("lite_flag_min_mp"))
+	hardDelete := flag.Bool("hard-delete", false, T("lite_flag_hard_delete"))
+	includeHidden := flag.Bool("include-hidden", false, T("lite_flag_include_hidden"))
+	workers := flag.Int("workers", runtime.NumCPU(), T("lite_flag_workers"))
+	noCache := flag.Bool("no-cache", false, T("lite_flag_no_cache"))
+	verbose := flag.Bool("verbose", false, T("lite_flag_verbose"))
+	jsonOut := flag.Bool("json", false, T("lite_flag_json"))
+	minYear := flag.Int("min-year", metadata.DefaultMinYear, T("lite_flag_min_year"))
+	once := flag.Bool("once", false, T("lite_flag_once"))
+	forceUnlock := flag.Bool("force-unlock", false, T("lite_flag_force_unlock"))
+	hookURL := flag.String("hook-url", "", T("lite_flag_hook_url"))
+	hookCmd := flag.String("hook-cmd", "", T("lite_flag_hook_cmd"))
+	hookTimeout := flag.Int("hook-timeout", int(hook.DefaultTimeout/time.Second), T("lite_flag_hook_timeout"))
+	showVersion := flag.Bool("version", false, T("lite_flag_version"))
+	yes := flag.Bool("yes", false, T("lite_flag_start_yes"))
+	conflict := flag.String("conflict", organizer.ConflictNumber, T("lite_flag_conflict"))
+	limit := flag.String("limit", "", T("lite_flag_limit"))
+	anomalyExit := flag.Bool("anomaly-exit", false, T("lite_flag_anomaly_exit"))
+	order := flag.String("order", engine.OrderInput, T("lite_flag_order"))
+	// Not in the usage text: a developer switch for soak tests against a scratch folder.
+	chaosRate := flag.Float64("chaos", 0, T("lite_flag_chaos"))
+	var routes routeFlags
+	flag.Var(&routes, "route", T("lite_flag_route"))
+	var shifts shiftFlags
+	flag.Var(&shifts, "shift", T("lite_flag_shift"))
+	flag.Usage = func() {
+		fmt.Printf(T("lite_usage"), AppVersion, engine.ExitBusy, strings.Join(languageCodes(), ", "))
+	}
+	flag.Parse()
+	if *showVersion {
+		fmt.Println(version.String("lume-lite"))
+		return
+	}
+	templateSet := false
+	flag.Visit(func(f *flag.Flag) { templateSet = templateSet || f.Name == "template" })
+	lay, layoutUsed, layoutOK := pickLayout(*layoutName, *template, templateSet)
+	if !layoutOK || flag.NArg() < 2 || *batchSize <= 0 || *workers <= 0 || (*hookURL != "" && *hookCmd != "") || !slices.Contains(organizer.ConflictStrategies, *conflict) || !engine.ValidOrder(*order) || *chaosRate < 0 || *chaosRate > 1 {
+		flag.Usage()
+		os.Exit(engine.ExitUsage)
+	}
+	// With --json standard output carries the JSON lines alone; the text in the output language goes
+	// to standard error.
+	var records *json.Encoder
+	if *jsonOut {
+		records = json.NewEncoder(os.Stdout)
+		os.Stdout = os.Stderr
+	}
+	if *chaosRate > 0 {
+		say("🧪 ", "lite_chaos", *chaosRate*100)
+	}
+	metadata.Plausible.MinYear = *minYear
+	skipped := make(map[string]int)
+	filters := engine.Filters{MinMegapixels: *minMP, Links: metadata.LinkSkip, IncludeHidden: *includeHidden,
+		OnSkip: func(_, reason string) { skipped[reason]++ }}
+	for _, f := range []struct {
+		val string
+		dst *int64
+	}{{*minSize, &filters.MinSize}, {*maxSize, &filters.MaxSize}} {
+		if f.val == "" {
+			continue
+		}
+		n, err := units.ParseSize(f.val)
+		if err != nil {
+			say("❌ ", "lite_bad_size", f.val)
+			os.Exit(engine.ExitUsage)
+		}
+		*f.dst = n
+	}
+	var limiter *ratelimit.Limiter
+	if *limit != "" {
+		rate, err := units.ParseRate(*limit)
+		if err != nil {
+			say("❌ ", "lite_bad_size", *limit)
+			os.Exit(engine.ExitUsage)
+		}
+		if rate > 0 {
+			limiter = ratelimit.New(rate)
+			say("🐢 ", "lite_limit", formatBytes(rate))
+		}
+	}
+
+	src, dst := flag.Arg(0), flag.Arg(1)
+
+	absSrc, _ := filepath.Abs(src)
+	absDst, _ := filepath.Abs(dst)
+	if validator.SameFile(absSrc, absDst) {
+		say("❌ ", "err_same_path")
+		os.Exit(engine.ExitUsage)
+	}
+	if validator.IsSubPath(absSrc, absDst) {
+		say("❌ ", "lite_target_in_source")
+		os.Exit(engine.ExitUsage)
+	}
+
+	st, err := os.Stat(src)
+	if os.IsNotExist(err) {
+		say("❌ ", "lite_source_not_found", src)
+		os.Exit(engine.ExitUsage)
+	}
+	for _, r := range routes {
+		if validator.IsSubPath(absSrc, r.Target) {
+			say("❌ ", "lite_route_in_source", r.Target)
+			os.Exit(engine.ExitUsage)
+		}
+	}
+	router := routing.Router{Rules: routes, Default: absDst}
+	targets := router.Targets()
+	// Folders holding a .lume_protected marker are never written into; a protected target fails the
+	// run before anything happens, a protected folder within one skips the files going there.
+	protect := validator.NewProtection(nil)
+	for _, d := range targets {
+		var pe *validator.ProtectedError
+		if errors.As(protect.Check(d), &pe) {
+			say("❌ ", "lite_target_protected", d, pe.Root)
+			os.Exit(engine.FailureExitCode(map[string]int{organizer.CategoryProtected: 1}))
+		}
+	}
+	if !*dryRun {
+		for _, d := range targets {
+			if err := os.MkdirAll(d, 0755); err != nil {
+				say("❌ ", "lite_target_mkdir_failed", err)
+				os.Exit(engine.FailureExitCode(map[string]int{organizer.Category(err): 1}))
+			}
+		}
+	}
+
+	release := func() {}
+	if !*dryRun {
+		var locked *lock.LockedError
+		if release, err = lock.AcquireAll(targets, "lume-lite "+AppVersion, *forceUnlock); errors.As(err, &locked) && *once {
+			say("⏭️  ", "lite_lock_skipped", locked.Owner.App, locked.Owner.PID)
+			os.Exit(engine.ExitBusy)
+		} else if locked != nil {
+			say("❌ ", "lite_locked", locked.Owner.App, locked.Owner.PID, locked.Owner.Host, locked.Owner.Started.Local().Format("02.01.2006 15:04"))
+			say("   ", "lite_force_unlock_hint")
+			os.Exit(engine.ExitBusy)
+		} else if err != nil {
+			say("❌ ", "lite_lock_failed", err)
+			os.Exit(engine.ExitUsage)
+		}
+	}
+
+	fmt.Printf("🚀 Lume LITE v%s\n", AppVersion)
+	fmt.Printf("📂 %s → %s\n", src, dst)
+	say("🗂️  ", "lite_layout", layoutUsed)
+	fmt.Println(strings.Repeat("-", 40))
+
+	if *dryRun {
+		say("🔍 ", "lite_dry_run")
+	}
+
+	// The scan cache keeps what was read of unchanged files for the next run, as Lume's does.
+	var cache *scan.Cache
+	cachePath, err := config.DataPath("lite_scan_cache.json")
+	if !*noCache && err == nil {
+		cache = scan.OpenCache(cachePath, 0)
+	}
+
+	// Ctrl+C stops the run after removing the partial copy in flight; a second one ends it at once.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	// The plan is worked out from the files found before anything happens, so that what is about to
+	// happen is known first.
+	var roots []string
+	if st != nil && st.IsDir() {
+		roots = []string{absSrc}
+	}
+	files, _ := engine.Collect([]string{absSrc}, targets, filters)
+	hist := openHistory()
+	p := engine.MakePlan(engine.PlanFiles(router, lay, nil, files, roots, *structure), absDst, true, hist, protect)
+	say("📋 ", "lite_plan", p.Text(lang))
+	if !*dryRun && !*yes && p.Files > 0 && !confirm(ctx, T("lite_plan_confirm"), true) {
+		say("⏹️  ", "lite_plan_declined")
+		release()
+		os.Exit(engine.ExitOther)
+	}
+
+	// A dry run goes through a file system that only records the changes, verifying included.
+	var runFS fsys.FS = fsys.OS
+	if *dryRun {
+		runFS = fsys.NewRecorder(nil)
+	}
+	if *chaosRate > 0 {
+		faulty := fsys.NewFaulty(runFS)
+		faulty.Chaos(*chaosRate, uint64(time.Now().UnixNano()))
+		runFS = faulty
+	}
+	journal := openJournal()
+	out := &printer{target: absDst, fs: runFS, dryRun: *dryRun, records: records, layout: layoutUsed, prune: *pruneEmpty && roots != nil, meter: plan.NewMeter(validator.VolumeSerial)}
+	opts := engine.Options{Target: absDst, Layout: lay, Routes: routes, HardDelete: *hardDelete, Conflicts: *conflict,
+		Catalog: !*dryRun, NearDuplicates: *nearDup, PHashMaxSize: config.DefaultPHashMaxSize, Links: metadata.LinkSkip,
+		BatchSize: *batchSize, Cache: cache, Workers: *workers, FS: runFS, PreserveStructure: *structure, Roots: roots,
+		Shifts: shifts, Limit: limiter, Order: *order,
+		// Files another program holds open, e.g. a video in a player, are tried once more after the rest.
+		InUse: func(paths []string) []string {
+			say("🔁 ", "lite_in_use_retry", formatCount(len(paths)))
+			return paths
+		}}
+	started := time.Now()
+	sum, runErr := engine.Run(ctx, engine.RunSpec{Pending: files, Options: opts, Progress: out, Journal: journal})
+
+	if *nearDup {
+		var fresh []metadata.HashedImage
+		for r := range sum.All() {
+			if r.Success && r.PHash != 0 {
+				fresh = append(fresh, metadata.HashedImage{Path: r.Dest, Size: r.Size, Date: r.Date, PHash: r.PHash})
+			}
+		}
+		pairs := metadata.FindNearDuplicates(fresh, nil, *nearDist)
+		review := filepath.Join(absDst, "lume_review.csv")
+		switch {
+		case *dryRun:
+			say("🔍 ", "lite_pairs_dry", len(pairs))
+		case len(pairs) == 0:
+		default:
+			if err := writeReview(review, pairs); err != nil {
+				say("⚠️  ", "lite_review_failed", err)
+			} else {
+				say("🔎 ", "lite_pairs", len(pairs), review)
+			}
+		}
+	}
+
+	if *pruneEmpty && roots != nil {
+		pruned, err := organizer.PruneEmptyDirs(absSrc, out.moved, *dryRun)
+		for _, d := range pruned {
+			if *dryRun {
+				say("🔍 ", "lite_prune_dry", d)
+			} else {
+				say("🧹 ", "lite_pruned", d)
+			}
+		}
+		if err != nil {
+			say("⚠️  ", "lite_prune_failed", err)
+		}
+	}
+
+	fmt.Println(strings.Repeat("-", 40))
+	failed := sum.Missing
+	for _, n := range sum.Failures {
+		failed += n
+	}
+	say("✨ ", "lite_summary", formatCount(sum.Moved-out.archived), formatCount(failed))
+	if runErr != nil && !errors.Is(runErr, validator.ErrInsufficientSpace) {
+		say("⛔ ", "lite_run_cancelled")
+	}
+	for _, c := range organizer.Categories {
+		n := sum.Failures[c]
+		if c == organizer.CategoryMissing {
+			n += sum.Missing
+		}
+		if n > 0 {
+			fmt.Printf("   %s: %s\n", T("cat_"+c), formatCount(n))
+		}
+	}
+	if len(sum.Unattempted) > 0 {
+		var size int64
+		for _, p := range sum.Unattempted {
+			if st, err := os.Stat(p); err == nil {
+				size += st.Size()
+			}
+		}
+		say("💾 ", "lite_not_attempted", formatCount(len(sum.Unattempted)), formatBytes(size))
+		for i, p := range sum.Unattempted {
+			if i == maxListed {
+				say("   ", "lite_and_more", formatCount(len(sum.Unattempted)-maxListed))
+				break
+			}
+			fmt.Printf("   %s\n", p)
+		}
+	}
+	for _, s := range []struct{ reason, icon, key string }{
+		{engine.SkipSize, "📏 ", "lite_size_skipped"},
+		{engine.SkipMegapixels, "🖼️  ", "lite_mp_skipped"},
+		{engine.SkipLink, "🔗 ", "lite_links_skipped"},
+		{engine.SkipHidden, "👻 ", "lite_hidden_skipped"},
+		{engine.SkipCloud, "☁️  ", "lite_cloud_skipped"},
+	} {
+		if skipped[s.reason] > 0 {
+			say(s.icon, s.key, formatCount(skipped[s.reason]))
+		}
+	}
+	if out.badDates > 0 {
+		say("📅 ", "lite_bad_dates", formatCount(out.badDates), *minYear)
+	}
+	if err := cache.Save(); err != nil {
+		say("⚠️  ", "lite_cache_save_failed", err)
+	}
+	// A cancelled run says nothing of the source.
+	var anomalies []history.Anomaly
+	if hist != nil && !*dryRun && ctx.Err() == nil {
+		anomalies = hist.AddRun(history.Run{At: time.Now(), Source: absSrc, Files: len(files), Errors: sum.Errors}, config.LoadConfig().Anomalies)
+	}
+	if hist != nil && !*dryRun {
+		out.meter.Record(hist)
+		if err := hist.Save(); err != nil {
+			say("⚠️  ", "lite_history_save_failed", err)
+		}
+	}
+	if *verbose {
+		for _, t := range sum.Throttles {
+			say("⚙️  ", "throttle_verbose", t.Target, t.Workers, T("drive_"+t.Kind))
+		}
+	}
+	if *verbose && cache != nil {
+		hits, misses := cache.Stats()
+		say("🗃️  ", "lite_cache_stats", formatCount(hits), formatCount(misses), formatCount(cache.Len()), cachePath)
+	}
+	if sum.Moved > out.archived && !*dryRun && !*once {
+		say("📂 ", "lite_open_hint", absDst)
+	}
+	s := engine.HookSummary("lume-lite", AppVersion, started, targets, sum, runErr)
+	s.Layout = layoutUsed
+	if records != nil {
+		records.Encode(struct {
+			Type string `json:"type"`
+			hook.Summary
+		}{"summary", s})
+	}
+	if (*hookURL != "" || *hookCmd != "") && !*dryRun {
+		if err := hook.Send(context.Background(), hook.Config{URL: *hookURL, Command: *hookCmd, Timeout: *hookTimeout}, s); err != nil {
+			say("⚠️  ", "lite_hook_failed", err)
+		}
+	}
+	for _, a := range anomalies {
+		fmt.Fprintln(os.Stderr, "⚠️  "+anomalyText(a))
+	}
+	release()
+	journal.Close()
+	code := engine.ExitCode(sum, runErr)
+	if code == 0 && len(anomalies) > 0 && *anomalyExit {
+		code = engine.ExitAnomaly
+	}
+	os.Exit(code)
+}
+
+// printer prints a run as it goes, a line for every file, and keeps what the summary reports
+// besides the run's own figures.
+type printer struct {
+	target string
+	// fs is the file system of the run, which shows a dry run's moves as done.
+	fs     fsys.FS
+	dryRun bool
+	meter  *plan.Meter
+	// records takes the --json line of every file, recording the layout that placed it; nil without
+	// --json.
+	records *json.Encoder
+	layout  string
+	// badDates counts the files whose own date was implausible; archived those that were already in
+	// the archive, which the run counts as moved; moved the sources moved away, kept only when prune
+	// asks for them (--prune-empty), so a long batched run does not hold every path.
+	badDates int
+	archived int
+	prune    bool
+	moved    []string
+}
+
+func (p *printer) Phase(name string) {
+	// The retry of the files open in another program is announced by Options.InUse.
+	if name != engine.PhaseRetrying {
+		say("⏳ ", name)
+	}
+}
+
+func (p *printer) Step(_, _ int, r engine.OrganizeResult) {
+	if r.RejectedSource != "" {
+		p.badDates++
+		say("📅 ", "lite_bad_date", r.File, r.Rejected.Format("2006-01-02"), T("date_src_"+r.DateSource))
+	}
+	if !r.ShiftedFrom.IsZero() {
+		say("🕒 ", "lite_shifted", r.File, r.ShiftedFrom.Format("2006-01-02 15:04"), r.Date.Format("2006-01-02 15:04"))
+	}
+	var pe *validator.ProtectedError
+	outcome := "failed"
+	switch {
+	case r.Unverified:
+		outcome = "unverified"
+		say("⚠️  ", "lite_unverified", r.File, r.Dest, r.Error)
+	case r.DuplicateOf != "":
+		outcome = "duplicate"
+		say("⏭️  ", "lite_duplicate", r.File)
+	case r.Success && fsys.Exists(p.fs, r.Path):
+		// A copy of a file already archived succeeds as the archived one, its source left.
+		outcome = "archived"
+		p.archived++
+		say("⏭️  ", "lite_duplicate", r.File)
+	case r.Success:
+		outcome = "moved"
+		icon := "✅"
+		if p.dryRun {
+			icon = "🔍"
+		}
+		fmt.Printf("%s %s → %s\n", icon, r.File, p.rel(r.Dest))
+		p.meter.Add(r.Path, r.Target, r.Size, r.Started, r.Elapsed)
+	case r.InPlace:
+		outcome = "in_place"
+		say("✅ ", "lite_where_in_place", r.File, p.rel(r.Dest))
+	case r.RolledBack:
+		outcome = "cancelled"
+		say("⛔ ", "lite_file_cancelled", r.File)
+	case errors.As(r.Error, &pe):
+		say("🔒 ", "lite_protected", r.File, pe.Root)
+	case errors.Is(r.Error, organizer.ErrIntegrityMismatch):
+		say("❌ ", "lite_verify_failed", r.File)
+	default:
+		fmt.Printf("❌ %s: %v\n", r.File, r.Error)
+	}
+	if p.prune && r.MovedFrom(p.fs) {
+		p.moved = append(p.moved, r.Path)
+	}
+	if p.records != nil {
+		rec := fileRecord{Type: "file", Source: r.Path, Dest: r.Dest, DuplicateOf: r.DuplicateOf, Outcome: outcome, Date: r.Date, Layout: p.layout}
+		if r.Error != nil {
+			rec.Category, rec.Error = organizer.Category(r.Error), r.Error.Error()
+		}
+		p.records.Encode(rec)
+	}
+}
+
+// fileRecord is the --json line of a file. It reads the same in every output language; Layout is the
+// --layout that placed Dest, as in the summary.
+type fileRecord struct {
+	Type        string    `json:"type"`
+	Source      string    `json:"source"`
+	Dest        string    `json:"dest,omitempty"`
+	DuplicateOf string    `json:"duplicate_of,omitempty"`
+	Outcome     string    `json:"outcome"`
+	Category    string    `json:"category,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	Date        time.Time `json:"date,omitzero"`
+	Layout      string    `json:"layout"`
+}
+
+// rel is path as the output shows it: within the target relative to it, elsewhere in full.
+func (p *printer) rel(path string) string {
+	if validator.IsSubPath(p.target, path) {
+		if rel, err := filepath.Rel(p.target, path); err == nil {
+			return rel
+		}
+	}
+	return path
+}
+
+// openJournal starts the results file of the run beside Lume's settings, which keeps the results out
+// of memory; without one they are kept in memory.
+func openJournal() *engine.Journal {
+	path, err := config.DataPath("results_lite.jsonl")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		var j *engine.Journal
+		if j, err = engine.CreateJournal(path, engine.JournalRecent); err == nil {
+			return j
+		}
+	}
+	say("⚠️  ", "lite_journal_unavailable", err)
+	j, _ := engine.CreateJournal("", engine.JournalRecent)
+	return j
+}
+
+// writeReview writes the near-duplicate pairs as CSV for manual review; nothing is ever deleted.
+func writeReview(path string, pairs []metadata.NearPair) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"path_a", "size_a", "date_a", "path_b", "size_b", "date_b", "distance"})
+	for _, p := range pairs {
+		w.Write([]string{
+			p.A.Path, fmt.Sprint(p.A.Size), p.A.Date.Format(time.RFC3339),
+			p.B.Path, fmt.Sprint(p.B.Size), p.B.Date.Format(time.RFC3339),
+			fmt.Sprint(p.Distance),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// maxListed caps the files listed by name in the summary; the rest are only counted.
+const maxListed = 20
+
+// anomalyText says what is out of line in a run, in Lume's words.
+func anomalyText(a history.Anomaly) string {
+	switch a.Kind {
+	case history.AnomalyNoFiles:
+		return fmt.Sprintf(T("anomaly_no_files"), formatCount(a.Runs), a.Since.Local().Format("2006-01-02"), a.Source)
+	case history.AnomalyFewFiles:
+		return fmt.Sprintf(T("anomaly_few_files"), formatCount(a.Files), formatCount(int(math.Round(a.Typical))), a.Source)
+	}
+	return fmt.Sprintf(T("anomaly_errors"), a.Rate*100, a.Typical*100, a.Source)
+}
+
+// openCatalog opens the catalog of the archive at root, which must have one already.
+func openCatalog(root string) (*catalog.Catalog, error) {
+	if _, err := os.Stat(filepath.Join(root, catalog.FileName)); err != nil {
+		return nil, err
+	}
+	return catalog.Open(root)
+}
+
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	from := fs.String("from", "", T("lite_flag_from"))
+	to := fs.String("to", "", T("lite_flag_to"))
+	device := fs.String("device", "", T("lite_flag_device"))
+	source := fs.String("source", "", T("lite_flag_source"))
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Println(T("lite_query_usage"))
+		return 1
+	}
+
+	filter := catalog.Filter{Device: *device, Source: *source}
+	for _, d := range []struct {
+		val string
+		dst *time.Time
+	}{{*from, &filter.From}, {*to, &filter.To}} {
+		if d.val == "" {
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02", d.val, time.Local)
+		if err != nil {
+			say("❌ ", "lite_bad_date_arg", d.val)
+			return 1
+		}
+		*d.dst = t
+	}
+
+	cat, err := openCatalog(fs.Arg(0))
+	if err != nil {
+		say("❌ ", "lite_no_catalog", err)
+		return 1
+	}
+	matches, err := cat.Query(filter)
+	if err != nil {
+		say("❌ ", "lite_no_catalog", err)
+		return 1
+	}
+
+	var total int64
+	for _, r := range matches {
+		fmt.Printf("%s  %-20s %-12s %s\n", r.Date.Format("2006-01-02 15:04"), r.Device, r.Source, r.Path)
+		total += r.Size
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	say("✨ ", "stats_total", formatCount(len(matches)), formatBytes(total))
+	return 0
+}
+
+// statsMaxKeys bounds each breakdown like Lume's history file; the long tail is folded into "Other".
+const statsMaxKeys = 100
+
+type statsBucket struct {
+	key   string
+	files int
+	bytes int64
+}
+
+// runStats prints how the archive splits by source and by device, from its catalog.
+func runStats(args []string) int {
+	if len(args) < 1 {
+		fmt.Println(T("lite_stats_usage"))
+		return 1
+	}
+	cat, err := openCatalog(args[0])
+	var records []catalog.Record
+	if err == nil {
+		records, err = cat.Query(catalog.Filter{})
+	}
+	if err != nil {
+		say("❌ ", "lite_no_catalog", err)
+		return 1
+	}
+	// A file re-recorded after a move or reorganization counts once, with its latest record.
+	latest := make(map[string]catalog.Record)
+	for _, r := range records {
+		latest[r.Hash+"|"+r.Path] = r
+	}
+	var total statsBucket
+	sources, devices := map[string]*statsBucket{}, map[string]*statsBucket{}
+	add := func(m map[string]*statsBucket, key string, size int64) {
+		if key == "" {
+			key = "Unknown"
+		}
+		if m[key] == nil {
+			if len(m) >= statsMaxKeys {
+				key = "Other"
+			}
+			if m[key] == nil {
+				m[key] = &statsBucket{key: key}
+			}
+		}
+		m[key].files++
+		m[key].bytes += size
+	}
+	for _, r := range latest {
+		total.files++
+		total.bytes += r.Size
+		add(sources, r.Source, r.Size)
+		add(devices, r.Device, r.Size)
+	}
+	for _, part := range []struct {
+		title string
+		m     map[string]*statsBucket
+	}{{T("col_source"), sources}, {T("col_device"), devices}} {
+		list := make([]*statsBucket, 0, len(part.m))
+		for _, b := range part.m {
+			list = append(list, b)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].bytes != list[j].bytes {
+				return list[i].bytes > list[j].bytes
+			}
+			return list[i].key < list[j].key
+		})
+		fmt.Printf("%-28s %10s %12s %7s\n", part.title, T("col_files"), T("col_size"), T("col_share"))
+		for _, b := range list {
+			share := 0.0
+			if total.bytes > 0 {
+				share = float64(b.bytes) * 100 / float64(total.bytes)
+			}
+			fmt.Printf("%-28s %10s %12s %6.1f%%\n", b.key, formatCount(b.files), formatBytes(b.bytes), share)
+		}
+		fmt.Println()
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	say("✨ ", "stats_total", formatCount(total.files), formatBytes(total.bytes))
+	return 0
+}
+
+// runRebuild recreates the catalog from the archive folders (Yıl/Ay/Cihaz), for recovery.
+func runRebuild(args []string) int {
+	if len(args) < 1 {
+		fmt.Println(T("lite_rebuild_usage"))
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	n, err := catalog.Rebuild(ctx, args[0])
+	if err != nil {
+		say("❌ ", "lite_catalog_write_failed", err)
+		return 1
+	}
+	say("✨ ", "lite_rebuild_done", formatCount(n))
+	return 0
+}
+
+// runManifest regenerates the SHA256SUMS of every year folder of the archive: manifest rebuild <arşiv>.
+func runManifest(args []string) int {
+	if len(args) < 2 || args[0] != "rebuild" {
+		fmt.Println(T("lite_manifest_usage"))
+		return 1
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	n, err := sums.Rebuild(ctx, args[1], func(done, total int) {
+		if done%500 == 0 {
+			say("🔐 ", "lite_sums_progress", formatCount(done), formatCount(total))
+		}
+	})
+	if err != nil {
+		say("❌ ", "lite_write_failed", sums.FileName, err)
+		return engine.FailureExitCode(map[string]int{organizer.Category(err): 1})
+	}
+	say("✨ ", "lite_sums_done", formatCount(n))
+	return 0
+}
+
+// runConfig exports Lume's settings, those of the lume_config.json beside this program, to a bundle
+// another computer can import, or imports one after showing what it changes.
+func runConfig(args []string) int {
+	if len(args) < 1 || args[0] != "export" && args[0] != "import" {
+		fmt.Println(T("lite_config_usage"))
+		return 1
+	}
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	paths := fs.Bool("paths", false, T("lite_flag_paths"))
+	yes := fs.Bool("yes", false, T("lite_flag_yes"))
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		fmt.Println(T("lite_config_usage"))
+		return 1
+	}
+	file, current := fs.Arg(0), config.LoadConfig()
+
+	if args[0] == "export" {
+		data, err := config.Export(current, *paths)
+		if err == nil {
+			err = os.WriteFile(file, data, 0644)
+		}
+		if err != nil {
+			say("❌ ", "lite_write_failed", file, err)
+			return 2
+		}
+		say("✨ ", "lite_config_exported", file)
+		return 0
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		say("❌ ", "lite_config_unreadable", file, err)
+		return 2
+	}
+	b, err := config.ReadBundle(data)
+	var newer *config.BundleError
+	switch {
+	case errors.As(err, &newer):
+		say("❌ ", "lite_config_newer", newer.Version, config.BundleVersion)
+		return 1
+	case err != nil:
+		say("❌ ", "lite_config_not_bundle", file)
+		return 1
+	}
+	next, err := b.Apply(current)
+	if err != nil {
+		say("❌ ", "lite_config_not_bundle", file)
+		return 1
+	}
+	changes, _ := config.Diff(current, next)
+	if len(changes) == 0 {
+		say("✨ ", "lite_config_same")
+		return 0
+	}
+	for _, c := range changes {
+		fmt.Printf("  %s: %s → %s\n", c.Key, c.Old, c.New)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if !*yes && !confirm(ctx, fmt.Sprintf(T("lite_config_confirm"), len(changes)), false) {
+		say("⏹️  ", "lite_config_declined")
+		return 1
+	}
+	if err := config.SaveConfig(next); err != nil {
+		say("❌ ", "lite_write_failed", "lume_config.json", err)
+		return 2
+	}
+	say("✨ ", "lite_config_imported", len(changes))
+	return 0
+}
+
+// routeFlags collects the repeated --route flags; the first matching route wins.
+type routeFlags []routing.Rule
+
+func (r *routeFlags) String() string { return "" }
+
+// Set parses "ext=.mp4,.mov;min=BYTES:TARGET". Only the first colon separates the target,
+// so drive letters like E:\Video stay intact.
+func (r *routeFlags) Set(v string) error {
+	spec, target, ok := strings.Cut(v, ":")
+	if !ok || spec == "" || target == "" {
+		return errors.New(T("lite_route_format"))
+	}
+	rt := routing.Rule{Target: target}
+	if abs, err := filepath.Abs(target); err == nil {
+		rt.Target = abs
+	}
+	for _, m := range strings.Split(spec, ";") {
+		key, val, _ := strings.Cut(strings.TrimSpace(m), "=")
+		switch key {
+		case "ext":
+			for _, e := range strings.Split(val, ",") {
+				if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+					if !strings.HasPrefix(e, ".") {
+						e = "." + e
+					}
+					rt.Extensions = append(rt.Extensions, e)
+				}
+			}
+		case "min":
+			n, err := units.ParseSize(val)
+			if err != nil {
+				return fmt.Errorf(T("lite_bad_size"), val)
+			}
+			rt.MinSize = units.Size(n)
+		default:
+			return fmt.Errorf(T("lite_route_matcher"), key)
+		}
+	}
+	*r = append(*r, rt)
+	return nil
+}
+
+// shiftFlags collects the repeated --shift flags; the first shift that selects a file applies. The
+// files themselves keep their dates: the archived copies only go where the shifted date files them.
+type shiftFlags []metadata.Shift
+
+func (s *shiftFlags) String() string { return "" }
+
+// Set parses "device=EOS70D;offset=-1h", as metadata.ParseShift reads it.
+func (s *shiftFlags) Set(v string) error {
+	sh, err := metadata.ParseShift(v)
+	if err != nil {
+		return err
+	}
+	*s = append(*s, sh)
+	return nil
+}
+
+// formatCount groups digits the way of the output language (12.345 in Turkish, 12,345 in English).
+func formatCount(n int) string {
+	return i18n.FormatCount(lang, int64(n))
+}
+
+// formatBytes renders a size in binary units with one decimal (1,5 MB in Turkish).
+func formatBytes(n int64) string {
+	return i18n.FormatBytes(lang, n)
+}
+
+// The output language. Its strings are Lume's internal/i18n tables, the lite_ keys for what only
+// lume-lite says and Lume's own keys where both say the same thing (failure categories, statistics
+// columns), so the two read alike. The summary hook's JSON stays the same in every language.
+
+// lang is the output language, set by pickLanguage before anything is printed.
+var lang = "tr"
+
+// T returns the string for key in the output language, falling back to English and then to the key.
+func T(key string) string {
+	return i18n.T(lang, key)
+}
+
+// say prints icon and then the string for key formatted with args, as one line.
+func say(icon, key string, args ...any) {
+	fmt.Println(icon + fmt.Sprintf(T(key), args...))
+}
+
+// languageCodes lists the languages with a table.
+func languageCodes() []string {
+	var codes []string
+	for _, l := range i18n.Languages() {
+		codes = append(codes, l.Code)
+	}
+	return codes
+}
+
+// langCode reduces a locale name such as de_DE.UTF-8 or tr-TR to its language; C and POSIX name none.
+func langCode(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if i := strings.IndexAny(s, "_-.@"); i >= 0 {
+		s = s[:i]
+	}
+	if s == "c" || s == "posix" {
+		return ""
+	}
+	return s
+}
+
+// takeLangFlag removes --lang L (or -lang L, --lang=L) from os.Args, so that neither the main flags
+// nor a subcommand's see it, and returns L.
+func takeLangFlag() string {
+	value, args := "", []string{os.Args[0]}
+	for i := 1; i < len(os.Args); i++ {
+		a := os.Args[i]
+		if a == "--" {
+			args = append(args, os.Args[i:]...)
+			break
+		}
+		name, v, hasValue := strings.Cut(a, "=")
+		if name != "--lang" && name != "-lang" {
+			args = append(args, a)
+			continue
+		}
+		if !hasValue && i+1 < len(os.Args) {
+			i++
+			v = os.Args[i]
+		}
+		value = v
+	}
+	os.Args = args
+	return value
+}
+
+// pickLanguage sets the output language to flagLang, or when it is empty to the system's. An unknown
+// flagLang is an error, reported in the system's language.
+func pickLanguage(flagLang string) error {
+	if code := langCode(flagLang); flagLang != "" && i18n.Has(code) {
+		lang = code
+		return nil
+	}
+	lang = systemLanguage()
+	if flagLang != "" {
+		return fmt.Errorf(T("lite_bad_lang"), flagLang, strings.Join(languageCodes(), ", "))
+	}
+	return nil
+}
+
+// systemLanguage is the first language with a table among LC_ALL, LC_MESSAGES, LANG, the language of
+// Lume's settings beside this program and the Windows display language. Only languages without a
+// table named there gives English; nothing named at all keeps the Turkish the CLI always printed.
+func systemLanguage() string {
+	named := false
+	for _, get := range []func() string{
+		func() string { return os.Getenv("LC_ALL") },
+		func() string { return os.Getenv("LC_MESSAGES") },
+		func() string { return os.Getenv("LANG") },
+		configLanguage,
+		windowsUILanguage,
+	} {
+		if code := langCode(get()); code != "" {
+			if i18n.Has(code) {
+				return code
+			}
+			named = true
+		}
+	}
+	if named {
+		return i18n.Fallback
+	}
+	return "tr"
+}
+
+// configLanguage is the language of Lume's lume_config.json when it sits beside this program, as it
+// does when both come from one download.
+func configLanguage() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(exe), "lume_config.json"))
+	if err != nil {
+		return ""
+	}
+	var c struct {
+		Language string `json:"language"`
+	}
+	json.Unmarshal(data, &c)
+	return c.Language
+}
+
+// runReorg re-lays out an archive with a new folder template, re-reading the metadata of every file
+// as Lume does; files whose metadata no longer fills the template stay where they are. Only a
+// preview is printed unless --apply is given, and every applied move is journaled for --undo.
+func runReorg(args []string) int {
+	fs := flag.NewFlagSet("reorg", flag.ExitOnError)
+	template := fs.String("template", "{year}/{month}/{device}", T("lite_flag_template"))
+	apply := fs.Bool("apply", false, T("lite_flag_apply"))
+	undo := fs.Bool("undo", false, T("lite_flag_undo"))
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fmt.Println(T("lite_reorg_usage"))
+		return 1
+	}
+	if *undo {
+		if _, err := os.Stat(fs.Arg(0)); err != nil {
+			say("❌ ", "lite_journal_read_failed", err)
+			return 1
+		}
+		n, err := organizer.UndoReorganize(fs.Arg(0))
+		say("✨ ", "lite_undo_done", formatCount(n))
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	root, _ := filepath.Abs(fs.Arg(0))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	p, err := organizer.PlanReorganize(ctx, root, organizer.Layout{Template: *template}, validator.NewProtection(nil))
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return engine.FailureExitCode(map[string]int{organizer.Category(err): 1})
+	}
+	for _, s := range p.Skipped {
+		say("⏸️  ", "lite_stays", s.Path, s.Reason)
+	}
+	for _, m := range p.Moves {
+		fmt.Printf("🔍 %s → %s\n", m.From, m.To)
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	say("✨ ", "lite_reorg_preview", formatCount(len(p.Moves)), formatCount(p.Unchanged), formatCount(len(p.Skipped)))
+	if !*apply || len(p.Moves) == 0 {
+		if len(p.Moves) > 0 {
+			say("🔍 ", "lite_reorg_apply_hint")
+		}
+		return 0
+	}
+
+	res, err := organizer.ApplyReorganize(ctx, p, nil)
+	for _, f := range res.Failed {
+		fmt.Printf("❌ %s: %s\n", f.Path, f.Reason)
+	}
+	switch {
+	case ctx.Err() != nil:
+		say("⛔ ", "lite_run_cancelled")
+	case err != nil && res.Moved == 0:
+		say("❌ ", "lite_journal_write_failed", err)
+		return 1
+	case err != nil:
+		say("❌ ", "lite_journal_stopped", err)
+	}
+	say("✨ ", "lite_reorg_done", formatCount(res.Moved), formatCount(len(res.Failed)))
+	say("↩️  ", "lite_undo_hint", res.Journal)
+	if err != nil || len(res.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runAudit checks the structure of an archive laid out as --layout or --template say and prints
+// what it finds, a few examples of each kind: audit [--fix] <arşiv>. With --fix, the files outside
+// the date folders are filed as Lume files them and empty folders removed; nothing else changes.
+func runAudit(args []string) int {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	layoutName := fs.String("layout", "", T("lite_flag_layout"))
+	template := fs.String("template", "{year}/{month}", T("lite_flag_template"))
+	fix := fs.Bool("fix", false, T("lite_flag_fix"))
+	workers := fs.Int("workers", runtime.NumCPU(), T("lite_flag_workers"))
+	fs.Parse(args)
+	templateSet := false
+	fs.Visit(func(f *flag.Flag) { templateSet = templateSet || f.Name == "template" })
+	lay, _, ok := pickLayout(*layoutName, *template, templateSet)
+	if !ok || fs.NArg() < 1 || *workers <= 0 {
+		fmt.Println(T("lite_audit_usage"))
+		return 1
+	}
+	root, _ := filepath.Abs(fs.Arg(0))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r, err := audit.Audit(ctx, root, audit.Options{Layout: lay, Workers: *workers, Progress: func(done, total int) {
+		if done%500 == 0 || done == total {
+			say("🔍 ", "lite_audit_progress", formatCount(done), formatCount(total))
+		}
+	}})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	if len(r.Findings) == 0 {
+		say("✨ ", "audit_clean", formatCount(r.Files), root)
+		return 0
+	}
+	say("📋 ", "audit_header", formatCount(r.Files), root)
+	const examples = 5
+	for _, kind := range audit.Kinds {
+		found := r.Of(kind)
+		if len(found) == 0 {
+			continue
+		}
+		say("⚠️  ", "audit_"+kind, formatCount(len(found)))
+		for i, f := range found {
+			if i == examples {
+				fmt.Println("   " + fmt.Sprintf(T("audit_more"), formatCount(len(found)-i)))
+				break
+			}
+			switch {
+			case f.Kind == audit.Duplicate:
+				fmt.Printf("   - %s = %s\n", f.Path, f.Detail)
+			case f.Detail != "":
+				fmt.Printf("   - %s → %s\n", f.Path, f.Detail)
+			default:
+				fmt.Printf("   - %s\n", f.Path)
+			}
+		}
+	}
+	strays, empty := r.Count(audit.Stray), r.Count(audit.Empty)
+	if !*fix {
+		if strays+empty > 0 {
+			say("🔍 ", "lite_audit_fix_hint", formatCount(strays), formatCount(empty))
+		}
+		return 0
+	}
+	res, err := audit.Fix(ctx, r, engine.Options{Layout: lay, Workers: *workers}, nil)
+	for _, f := range res.Failed {
+		fmt.Printf("❌ %s: %s\n", f.Path, f.Detail)
+	}
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	say("✨ ", "audit_fixed", formatCount(res.Moved), formatCount(res.Removed), formatCount(len(res.Failed)))
+	if res.Duplicates > 0 {
+		say("🔁 ", "audit_fixed_dups", formatCount(res.Duplicates))
+	}
+	if len(res.Failed) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runWhere shows where the given photos and videos would be filed in an archive, dated as a run
+// dates them, and which already lie where they belong; nothing is moved: where [--template T]
+// [--name N] <dosya|klasör>... <arşiv>. It goes through pkg/lume, as a program embedding Lume does.
+func runWhere(args []string) int {
+	fs := flag.NewFlagSet("where", flag.ExitOnError)
+	template := fs.String("template", lume.DefaultTemplate, T("lite_flag_template"))
+	name := fs.String("name", "", T("lite_flag_name"))
+	fs.Parse(args)
+	if fs.NArg() < 2 {
+		fmt.Println(T("lite_where_usage"))
+		return 1
+	}
+	sources, root := fs.Args()[:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	opts := lume.Options{Template: *template, NameTemplate: *name}
+	files, err := lume.Scan(ctx, sources, opts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return engine.ExitOther
+	}
+	p, err := lume.Plan(files, root, opts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	placed := 0
+	for _, m := range p.Moves {
+		rel, _ := filepath.Rel(p.Target, m.Dest)
+		if m.InPlace {
+			placed++
+			say("✅ ", "lite_where_in_place", m.File.Name, rel)
+			continue
+		}
+		fmt.Printf("📍 %s → %s\n", m.File.Name, rel)
+	}
+	fmt.Println(strings.Repeat("-", 40))
+	say("✨ ", "lite_where_done", formatCount(len(p.Moves)-placed), formatCount(placed))
+	return 0
+}
+
+// layouts are the folder layouts of --layout. flat puts every file in the target itself, its name
+// prefixed with its date so that thousands of IMG_0001.JPG from different cameras do not all collide.
+var layouts = map[string]organizer.Layout{
+	"year":           {Template: "{year}"},
+	"year-month":     {Template: "{year}/{month}"},
+	"year-month-day": {Template: "{year}/{month}/{day}"},
+	"flat":           {Folders: ".", NameTemplate: "{year}-{month}-{day}_{name}"},
+}
+
+// defaultLayout is the layout lume-lite has always used.
+const defaultLayout = "year-month"
+
+// pickLayout is the layout of the --layout name, or of --template when that is set instead, with the
+// name the run reports it by. Both at once, or a name not in layouts, is not ok.
+func pickLayout(name, template string, templateSet bool) (organizer.Layout, string, bool) {
+	switch {
+	case name != "" && templateSet:
+		return organizer.Layout{}, "", false
+	case templateSet:
+		return organizer.Layout{Template: template}, "template", true
+	case name == "":
+		name = defaultLayout
+	}
+	lay, ok := layouts[name]
+	return lay, name, ok
+}
+
+// openHistory loads the history Lume keeps its statistics and speeds in, which the CLI shares. It is
+// nil when there is none to read, so that an unreadable file is left for Lume to deal with.
+func openHistory() *history.History {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	h, err := history.Load(filepath.Join(dir, "Lume", history.FileName))
+	if err != nil {
+		say("⚠️  ", "lite_history_unreadable", err)
+		return nil
+	}
+	return h
+}
+
+// confirm prints question and reads a yes or no from the terminal; an empty answer is def. Without a
+// terminal to ask, as under a scheduler, it returns def too. Ctrl+C or the end of input is a no.
+func confirm(ctx context.Context, question string, def bool) bool {
+	if !stdinIsTerminal() {
+		return def
+	}
+	fmt.Print(question)
+	read := make(chan string, 1)
+	go func() {
+		if line, err := bufio.NewReader(os.Stdin).ReadString('\n'); err == nil || line != "" {
+			read <- line
+		}
+		close(read)
+	}()
+	var answer string
+	select {
+	case line, ok := <-read:
+		if !ok {
+			fmt.Println()
+			return false
+		}
+		answer = strings.ToLower(strings.TrimSpace(line))
+	case <-ctx.Done():
+		fmt.Println()
+		return false
+	}
+	if answer == "" {
+		return def
+	}
+	for _, a := range append(strings.Split(T("lite_yes_answers"), ","), "y", "yes") {
+		if answer == a {
+			return true
+		}
+	}
+	return false
+}
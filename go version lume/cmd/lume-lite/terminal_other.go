@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import "os"
+
+// stdinIsTerminal reports whether standard input is a terminal someone can answer at: a character
+// device other than the null device.
+func stdinIsTerminal() bool {
+	st, err := os.Stdin.Stat()
+	if err != nil || st.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	null, err := os.Stat(os.DevNull)
+	return err != nil || !os.SameFile(st, null)
+}
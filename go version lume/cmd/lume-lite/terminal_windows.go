@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "syscall"
+
+// stdinIsTerminal reports whether standard input is a console someone can answer at.
+func stdinIsTerminal() bool {
+	var mode uint32
+	return syscall.GetConsoleMode(syscall.Stdin, &mode) == nil
+}
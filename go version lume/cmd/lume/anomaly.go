@@ -0,0 +1,61 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/history"
+	"lume-go/internal/logger"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// runSource names the source of an automated run in the history: its paths, absolute and in order, so
+// the scheduled job and a command line over the same folder share their runs.
+func runSource(paths []string) string {
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		abs[i] = p
+		if a, err := filepath.Abs(p); err == nil {
+			abs[i] = a
+		}
+	}
+	sort.Strings(abs)
+	return strings.Join(abs, ";")
+}
+
+// recordRun adds a scheduled or headless run that found files and saw errs of them fail to the history,
+// and returns what is out of line with the earlier runs of its source.
+func (ui *LumeUI) recordRun(source string, files, errs int) []history.Anomaly {
+	found := ui.history.AddRun(history.Run{At: time.Now(), Source: source, Files: files, Errors: errs}, ui.Config.Anomalies)
+	if err := ui.history.Save(); err != nil {
+		logger.Error("History save failed: %v", err)
+	}
+	for _, a := range found {
+		logger.Info("Run anomaly: %s", ui.anomalyText(a))
+	}
+	return found
+}
+
+// anomalyText says what is out of line in a run, and what to check.
+func (ui *LumeUI) anomalyText(a history.Anomaly) string {
+	switch a.Kind {
+	case history.AnomalyNoFiles:
+		return fmt.Sprintf(ui.T("anomaly_no_files"), ui.count(a.Runs), a.Since.Local().Format("2006-01-02"), a.Source)
+	case history.AnomalyFewFiles:
+		return fmt.Sprintf(ui.T("anomaly_few_files"), ui.count(a.Files), ui.count(int(math.Round(a.Typical))), a.Source)
+	}
+	return fmt.Sprintf(ui.T("anomaly_errors"), a.Rate*100, a.Typical*100, a.Source)
+}
+
+// anomalySummary lists the anomalies of a run for its summary.
+func (ui *LumeUI) anomalySummary(found []history.Anomaly) string {
+	var b strings.Builder
+	for _, a := range found {
+		b.WriteString("\n\n⚠ " + ui.anomalyText(a))
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/audit"
+	"lume-go/internal/logger"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxn/walk"
+)
+
+// auditExamples is how many paths of each kind of finding the audit report shows.
+const auditExamples = 5
+
+// AuditArchive checks the structure of the target folder in the background and reports what it
+// finds. Nothing changes unless the user then has the safe findings fixed.
+func (ui *LumeUI) AuditArchive() {
+	ui.mutex.Lock()
+	if ui.TargetFolder == "" {
+		ui.mutex.Unlock()
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("warn_select"), walk.MsgBoxIconWarning)
+		return
+	}
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	ui.isProcessing = true
+	target := ui.TargetFolder
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(ui.T("audit_scanning"))
+	go func() {
+		defer ui.guard()
+		r, err := audit.Audit(ctx, target, audit.Options{Layout: ui.layout(), Progress: ui.busyProgress})
+		ui.MainWindow.Synchronize(func() {
+			switch {
+			case ctx.Err() != nil:
+				ui.finishBusy(ui.T("cancelled"))
+			case err != nil:
+				logger.Error("Audit of %s failed: %v", target, err)
+				walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+				ui.finishBusy("")
+			case len(r.Findings) == 0:
+				logger.Info("Audit of %s: %d files, no findings", target, r.Files)
+				walk.MsgBox(ui.MainWindow, ui.T("audit_title"), fmt.Sprintf(ui.T("audit_clean"), ui.count(r.Files), target), walk.MsgBoxIconInformation)
+				ui.finishBusy("")
+			default:
+				logger.Info("Audit of %s: %d files, %d findings", target, r.Files, len(r.Findings))
+				for _, f := range r.Findings {
+					logger.Info("Audit %s: %s %s", f.Kind, f.Path, f.Detail)
+				}
+				ui.showAudit(ctx, r)
+			}
+		})
+	}()
+}
+
+// busyProgress shows the progress of a background task on the progress bar and the status line.
+func (ui *LumeUI) busyProgress(done, total int) {
+	ui.MainWindow.Synchronize(func() {
+		ui.ProgressBar.SetValue(done * 100 / max(total, 1))
+		ui.StatusLabel.SetText(fmt.Sprintf(ui.T("proc_count"), ui.count(done), ui.count(total)))
+	})
+}
+
+// auditText is the report of an audit: the count of each kind of finding with a few examples.
+func (ui *LumeUI) auditText(r audit.Report) string {
+	rel := func(p string) string {
+		if s, err := filepath.Rel(r.Root, p); err == nil {
+			return s
+		}
+		return p
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, ui.T("audit_header"), ui.count(r.Files), r.Root)
+	for _, kind := range audit.Kinds {
+		found := r.Of(kind)
+		if len(found) == 0 {
+			continue
+		}
+		b.WriteString("\n\n" + fmt.Sprintf(ui.T("audit_"+kind), ui.count(len(found))))
+		for i, f := range found {
+			if i == auditExamples {
+				b.WriteString("\n  " + fmt.Sprintf(ui.T("audit_more"), ui.count(len(found)-i)))
+				break
+			}
+			switch {
+			case f.Kind == audit.Duplicate:
+				fmt.Fprintf(&b, "\n  - %s = %s", rel(f.Path), rel(f.Detail))
+			case f.Detail != "":
+				fmt.Fprintf(&b, "\n  - %s → %s", rel(f.Path), f.Detail)
+			default:
+				fmt.Fprintf(&b, "\n  - %s", rel(f.Path))
+			}
+		}
+	}
+	return b.String()
+}
+
+// showAudit reports the findings of r and, when some of them are safe to fix, offers to fix them.
+func (ui *LumeUI) showAudit(ctx context.Context, r audit.Report) {
+	msg := ui.auditText(r)
+	strays, empty := r.Count(audit.Stray), r.Count(audit.Empty)
+	if strays+empty == 0 {
+		walk.MsgBox(ui.MainWindow, ui.T("audit_title"), msg, walk.MsgBoxIconWarning)
+		ui.finishBusy("")
+		return
+	}
+	msg += "\n\n" + fmt.Sprintf(ui.T("audit_fix_confirm"), ui.count(strays), ui.count(empty))
+	if walk.MsgBox(ui.MainWindow, ui.T("audit_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		ui.finishBusy("")
+		return
+	}
+	release := ui.lockRun(r.Root)
+	if release == nil {
+		ui.finishBusy("")
+		return
+	}
+	opts := ui.runOptions(r.Root)
+	go func() {
+		defer ui.guard()
+		defer release()
+		res, err := audit.Fix(ctx, r, opts, ui.busyProgress)
+		for _, f := range res.Failed {
+			logger.Error("Audit fix of %s failed: %s", f.Path, f.Detail)
+		}
+		logger.Info("Audit fix of %s: %d filed, %d already archived, %d folders removed, %d failed", r.Root, res.Moved, res.Duplicates, res.Removed, len(res.Failed))
+		ui.finishRun(nil, res.Run)
+		ui.MainWindow.Synchronize(func() {
+			if err != nil {
+				ui.finishBusy(ui.T("cancelled"))
+				return
+			}
+			msg := fmt.Sprintf(ui.T("audit_fixed"), ui.count(res.Moved), ui.count(res.Removed), ui.count(len(res.Failed)))
+			if res.Duplicates > 0 {
+				msg += " " + fmt.Sprintf(ui.T("audit_fixed_dups"), ui.count(res.Duplicates))
+			}
+			ui.finishBusy(msg)
+		})
+	}()
+}
@@ -0,0 +1,114 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"os"
+	"strings"
+
+	"github.com/lxn/walk"
+)
+
+// bundleFilter is the file dialog filter of settings bundles.
+const bundleFilter = "JSON (*.json)|*.json"
+
+// ExportSettings writes the settings to a bundle another computer can import, asking whether the
+// folders of this one go along.
+func (ui *LumeUI) ExportSettings() {
+	dlg := &walk.FileDialog{Title: ui.T("settings_export_btn"), Filter: bundleFilter, FilePath: "lume_settings.json"}
+	if ok, _ := dlg.ShowSave(ui.MainWindow); !ok {
+		return
+	}
+	paths := walk.MsgBox(ui.MainWindow, ui.T("settings_export_btn"), ui.T("settings_export_paths"), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion|walk.MsgBoxDefButton2) == walk.DlgCmdYes
+	data, err := config.Export(ui.Config, paths)
+	if err == nil {
+		err = os.WriteFile(dlg.FilePath, data, 0644)
+	}
+	if err != nil {
+		logger.Error("Settings export failed: %v", err)
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+		return
+	}
+	logger.Info("Settings exported to %s (folders: %v)", dlg.FilePath, paths)
+	walk.MsgBox(ui.MainWindow, ui.T("settings_title"), fmt.Sprintf(ui.T("settings_exported"), dlg.FilePath), walk.MsgBoxIconInformation)
+}
+
+// ImportSettings applies a bundle after listing what it changes. A bundle that cannot be read, or
+// that a newer Lume wrote, leaves the settings alone.
+func (ui *LumeUI) ImportSettings() {
+	dlg := &walk.FileDialog{Title: ui.T("settings_import_btn"), Filter: bundleFilter}
+	if ok, _ := dlg.ShowOpen(ui.MainWindow); !ok {
+		return
+	}
+	next, changes, err := ui.readBundle(dlg.FilePath)
+	if err != nil {
+		logger.Error("Settings import of %s refused: %v", dlg.FilePath, err)
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.bundleErrText(dlg.FilePath, err), walk.MsgBoxIconError)
+		return
+	}
+	if len(changes) == 0 {
+		walk.MsgBox(ui.MainWindow, ui.T("settings_title"), ui.T("settings_import_same"), walk.MsgBoxIconInformation)
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, ui.T("settings_import_confirm"), ui.count(len(changes)))
+	for i, c := range changes {
+		if i == MaxErrorsDisplay {
+			b.WriteString("\n…")
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s: %s → %s", c.Key, c.Old, c.New)
+	}
+	if walk.MsgBox(ui.MainWindow, ui.T("settings_import_btn"), b.String(), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+		return
+	}
+	if err := config.SaveConfig(next); err != nil {
+		logger.Error("Config save failed: %v", err)
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+		return
+	}
+	logger.Info("Imported %d settings from %s", len(changes), dlg.FilePath)
+	ui.Config = next
+	if next.TargetFolder != "" && next.TargetFolder != ui.TargetFolder {
+		ui.setTarget(next.TargetFolder)
+	} else {
+		ui.refreshTargets()
+	}
+	ui.RefreshLocalization()
+	ui.ApplyTheme()
+	walk.MsgBox(ui.MainWindow, ui.T("settings_title"), ui.T("settings_imported"), walk.MsgBoxIconInformation)
+}
+
+// readBundle reads the bundle at path and returns the settings it makes with what they change.
+func (ui *LumeUI) readBundle(path string) (config.Config, []config.Change, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ui.Config, nil, err
+	}
+	b, err := config.ReadBundle(data)
+	if err != nil {
+		return ui.Config, nil, err
+	}
+	next, err := b.Apply(ui.Config)
+	if err != nil {
+		return ui.Config, nil, err
+	}
+	changes, err := config.Diff(ui.Config, next)
+	return next, changes, err
+}
+
+// bundleErrText is why the bundle at path was refused, for the user.
+func (ui *LumeUI) bundleErrText(path string, err error) string {
+	var newer *config.BundleError
+	switch {
+	case errors.As(err, &newer):
+		return fmt.Sprintf(ui.T("settings_import_newer"), newer.Version, config.BundleVersion)
+	case errors.Is(err, config.ErrNotBundle):
+		return fmt.Sprintf(ui.T("settings_import_not_bundle"), path)
+	}
+	return fmt.Sprintf(ui.T("err_val"), err)
+}
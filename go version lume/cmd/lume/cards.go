@@ -0,0 +1,135 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"lume-go/internal/cards"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/validator"
+	"os"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// loadCardMemory opens the memory of imported cards. A corrupt file is logged and started over.
+func loadCardMemory() *cards.Memory {
+	path, err := config.DataPath(cards.MemoryFile)
+	if err != nil {
+		logger.Error("Card memory unavailable: %v", err)
+		path = cards.MemoryFile
+	}
+	m, err := cards.LoadMemory(path)
+	if err != nil {
+		logger.Error("Card memory unreadable, starting over: %v", err)
+	}
+	return m
+}
+
+// startCardWatch watches for memory cards in the background while the setting is on. The drives are
+// listed and probed off the UI thread; only the prompt is shown on it.
+func (ui *LumeUI) startCardWatch() {
+	if !ui.Config.WatchCards || ui.stopCards != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.stopCards = cancel
+	go func() {
+		defer ui.guard()
+		cards.Watch(ctx, cards.PollInterval, cards.Settle, cards.Removable, ui.cardInserted, func(err error) { logger.Debug("Card watch: %v", err) })
+	}()
+	logger.Info("Watching for memory cards")
+}
+
+// stopCardWatch ends the watch, if one runs.
+func (ui *LumeUI) stopCardWatch() {
+	if ui.stopCards != nil {
+		ui.stopCards()
+		ui.stopCards = nil
+	}
+}
+
+// cardInserted offers a card with photos for import, unless it holds nothing taken since it was last
+// imported. It runs on the watch's goroutine.
+func (ui *LumeUI) cardInserted(v cards.Volume) {
+	last := ui.cardMemory.Imported(v.Serial)
+	if !cards.HasNew(v.DCIM(), last) {
+		logger.Info("Card %s (%s) inserted, nothing new since its import on %s", v.Name(), v.Serial, last.Format(time.RFC3339))
+		return
+	}
+	logger.Info("Card %s (%s) inserted with new photos", v.Name(), v.Serial)
+	ui.MainWindow.Synchronize(func() { ui.offerCard(v) })
+}
+
+// offerCard asks, without blocking the window, whether to add the DCIM folder of a card to the list.
+// The drop filters apply to it as to any dropped folder.
+func (ui *LumeUI) offerCard(v cards.Volume) {
+	var dlg *walk.Dialog
+	add := func() {
+		dlg.Accept()
+		ui.mutex.Lock()
+		busy := ui.isProcessing
+		ui.mutex.Unlock()
+		if busy {
+			walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("card_busy"), walk.MsgBoxIconWarning)
+			return
+		}
+		dcim := v.DCIM()
+		if _, err := os.Stat(dcim); err != nil {
+			logger.Info("Card %s removed before its import: %v", v.Name(), err)
+			return
+		}
+		ui.mutex.Lock()
+		ui.cardRoots[dcim] = v.Serial
+		ui.mutex.Unlock()
+		ui.HandleDrop([]string{dcim})
+	}
+	if err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("card_title"), Layout: VBox{},
+		Children: []Widget{
+			Label{Text: fmt.Sprintf(ui.T("card_prompt"), v.Name())},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				HSpacer{},
+				PushButton{Text: ui.T("card_import_btn"), OnClicked: add},
+				PushButton{Text: ui.T("card_later_btn"), OnClicked: func() { dlg.Cancel() }},
+			}},
+		},
+	}).Create(ui.MainWindow); err != nil {
+		logger.Error("Card prompt failed: %v", err)
+		return
+	}
+	dlg.Show()
+}
+
+// markCards remembers the cards a run imported from: those among roots with a file archived, found in
+// place or imported before.
+func (ui *LumeUI) markCards(roots []string, res iter.Seq[OrganizeResult]) {
+	ui.mutex.Lock()
+	serials := make(map[string]string)
+	for _, root := range roots {
+		if serial, ok := ui.cardRoots[root]; ok {
+			serials[root] = serial
+		}
+	}
+	ui.mutex.Unlock()
+	if len(serials) == 0 {
+		return
+	}
+	now := time.Now()
+	for root, serial := range serials {
+		for r := range res {
+			if (r.Success || r.InPlace || r.Previously) && validator.IsSubPath(root, r.Path) {
+				ui.cardMemory.Mark(serial, now)
+				break
+			}
+		}
+	}
+	if err := ui.cardMemory.Save(); err != nil {
+		logger.Error("Card memory save failed: %v", err)
+	}
+}
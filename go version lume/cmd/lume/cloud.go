@@ -0,0 +1,43 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/metadata"
+	"os"
+
+	"github.com/lxn/walk"
+)
+
+// hydrateTotal counts the queued cloud placeholders and the bytes moving them will download.
+// The caller holds ui.mutex.
+func (ui *LumeUI) hydrateTotal() (int, int64) {
+	n, size := 0, int64(0)
+	for _, f := range ui.FilesToMove {
+		if f.Placeholder {
+			n++
+			size += f.Size
+		}
+	}
+	for _, p := range ui.PendingPaths {
+		if st, err := os.Stat(p); err == nil && metadata.IsPlaceholder(st) {
+			n++
+			size += st.Size()
+		}
+	}
+	return n, size
+}
+
+// confirmHydrate shows how much the run will download from the cloud and lets the user back out.
+// It returns true when there is nothing to download.
+func (ui *LumeUI) confirmHydrate() bool {
+	ui.mutex.Lock()
+	n, size := ui.hydrateTotal()
+	ui.mutex.Unlock()
+	if n == 0 {
+		return true
+	}
+	msg := fmt.Sprintf(ui.T("cloud_confirm"), ui.count(n), ui.bytes(size))
+	return walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes
+}
@@ -0,0 +1,86 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/crash"
+	"lume-go/internal/engine"
+	"lume-go/internal/i18n"
+	"lume-go/internal/logger"
+	"lume-go/internal/shell"
+	"lume-go/pkg/lume"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"syscall"
+	"time"
+
+	"github.com/lxn/walk"
+)
+
+// reportCrash handles a panic that reached the top of a goroutine: it saves a crash report under the
+// config directory, logs it and tells the user where the report is, in a dialog offering to show it
+// or, for a headless run nobody may be watching, on the console. A panic of a background goroutine
+// passes the main window as owner, whose UI thread then shows the dialog.
+func reportCrash(conf config.Config, r any, stack []byte, ask bool, owner *walk.MainWindow) {
+	logger.Fatal("Panic: %v\n%s", r, stack)
+	dir, err := config.DataPath("crash")
+	if err != nil {
+		dir = filepath.Join(os.TempDir(), "Lume", "crash")
+	}
+	path, err := crash.Write(dir, crash.Info{App: "Lume", Version: lume.Version(), OS: osVersion(), Config: conf}, r, stack, time.Now())
+	if err != nil {
+		logger.Fatal("Crash report not saved: %v", err)
+		if !ask {
+			fmt.Println(i18n.T(conf.Language, "crash_unsaved"))
+			return
+		}
+		crashBox(owner, i18n.T(conf.Language, "crash_title"), i18n.T(conf.Language, "crash_unsaved"), walk.MsgBoxIconError)
+		return
+	}
+	logger.Fatal("Crash report saved to %s", path)
+	if !ask {
+		fmt.Printf(i18n.T(conf.Language, "crash_saved")+"\n", path)
+		return
+	}
+	msg := fmt.Sprintf(i18n.T(conf.Language, "crash_msg"), path)
+	if crashBox(owner, i18n.T(conf.Language, "crash_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconError) == walk.DlgCmdYes {
+		if err := shell.Reveal(path); err != nil {
+			logger.Error("Reveal crash report: %v", err)
+		}
+	}
+}
+
+// crashBox shows a crash dialog and returns the button chosen. Walk's windows must only be used from
+// the UI thread, so with an owner the dialog is synchronized to it and the caller waits for the answer.
+// Once the window is gone nothing would run it, and the dialog goes without an owner.
+func crashBox(owner *walk.MainWindow, title, msg string, style walk.MsgBoxStyle) int {
+	if owner == nil || owner.IsDisposed() {
+		return walk.MsgBox(nil, title, msg, style)
+	}
+	done := make(chan int)
+	owner.Synchronize(func() { done <- walk.MsgBox(owner, title, msg, style) })
+	return <-done
+}
+
+// guard is deferred at the top of background goroutines. The main goroutine's recover does not see
+// their panics, which would end Lume on the spot; guard reports them and exits instead.
+func (ui *LumeUI) guard() {
+	if r := recover(); r != nil {
+		reportCrash(ui.Config, r, debug.Stack(), true, ui.MainWindow)
+		logger.Close()
+		os.Exit(engine.ExitOther)
+	}
+}
+
+// osVersion is the Windows version and build Lume runs on.
+func osVersion() string {
+	v, err := syscall.GetVersion()
+	if err != nil {
+		return "Windows (" + runtime.GOARCH + ")"
+	}
+	return fmt.Sprintf("Windows %d.%d.%d (%s)", byte(v), byte(v>>8), uint16(v>>16), runtime.GOARCH)
+}
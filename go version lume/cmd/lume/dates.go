@@ -0,0 +1,136 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"strings"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// categorySuspect files the archived results whose date is suspect for the results filter.
+const categorySuspect = "date_suspect"
+
+// dateNote tells which date a file was filed by when its own date was implausible.
+func (ui *LumeUI) dateNote(r OrganizeResult) string {
+	if r.RejectedSource == "" {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("date_rejected"), r.Rejected.Format("2006-01-02 15:04"), ui.T("date_src_"+r.DateSource))
+}
+
+// metadataNote tells why an image was not dated by its EXIF date and what dated it instead.
+func (ui *LumeUI) metadataNote(note, source string) string {
+	if note == "" {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("exif_note"), ui.T("note_"+note), ui.T("date_src_"+source))
+}
+
+// exifNote is metadataNote as a remark after the archive path of a result.
+func (ui *LumeUI) exifNote(r OrganizeResult) string {
+	if r.MetadataNote == "" {
+		return ""
+	}
+	return " — " + ui.metadataNote(r.MetadataNote, r.DateSource)
+}
+
+// clockNote tells by how much the clock offset of its device corrected a file's date.
+func (ui *LumeUI) clockNote(r OrganizeResult) string {
+	if r.ClockOffset == 0 {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("clock_corrected"), metadata.FormatOffset(r.ClockOffset))
+}
+
+// dateSummary counts the archived files whose own date was rejected, and those whose date is suspect.
+func (ui *LumeUI) dateSummary(res iter.Seq[OrganizeResult]) string {
+	rejected, suspect := 0, 0
+	for r := range res {
+		if r.Success && r.RejectedSource != "" {
+			rejected++
+		}
+		if r.Success && r.DateSuspect {
+			suspect++
+		}
+	}
+	var s string
+	if rejected > 0 {
+		s += fmt.Sprintf(ui.T("date_summary"), ui.count(rejected))
+	}
+	if suspect > 0 {
+		s += fmt.Sprintf(ui.T("date_suspect_summary"), ui.count(suspect))
+	}
+	return s
+}
+
+// clocks corrects the dates of the devices the config has clock offsets for.
+func (ui *LumeUI) clocks() *metadata.Clocks {
+	c, err := metadata.NewClocks(ui.Config.ClockOffsets, metadata.NewDeviceNames(ui.Config.DeviceAliases))
+	if err != nil {
+		logger.Error("%v", err)
+	}
+	return c
+}
+
+// suspectAfter is the configured threshold of suspect dates; zero turns the check off.
+func (ui *LumeUI) suspectAfter() time.Duration {
+	if ui.Config.DateSuspectHours < 0 {
+		return 0
+	}
+	return time.Duration(ui.Config.DateSuspectHours) * time.Hour
+}
+
+// ShowClockOffsets edits the clock offsets, one "device = ±hh:mm [from..to]" per line. Like the
+// device aliases they apply from the next drop; files already archived keep their folders.
+func (ui *LumeUI) ShowClockOffsets() {
+	var dlg *walk.Dialog
+	var edit *walk.TextEdit
+	save := func() {
+		offsets, err := metadata.ParseClockOffsets(edit.Text())
+		if err != nil {
+			walk.MsgBox(dlg, ui.T("warn_title"), fmt.Sprintf(ui.T("clock_offsets_invalid"), err), walk.MsgBoxIconWarning)
+			return
+		}
+		ui.Config.ClockOffsets = nil
+		for _, o := range offsets {
+			ui.Config.ClockOffsets = append(ui.Config.ClockOffsets, o.String())
+		}
+		if err := config.SaveConfig(ui.Config); err != nil {
+			logger.Error("Config save failed: %v", err)
+		}
+		dlg.Accept()
+	}
+	text := strings.Join(ui.Config.ClockOffsets, "\r\n")
+	if text != "" {
+		text += "\r\n"
+	}
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("clock_offsets_title"), MinSize: Size{Width: 460, Height: 320}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: ui.T("clock_offsets_hint")},
+			TextEdit{AssignTo: &edit, Text: text, VScroll: true},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				HSpacer{},
+				PushButton{Text: ui.T("ok_btn"), OnClicked: save},
+				PushButton{Text: ui.T("cancel_btn"), OnClicked: func() { dlg.Cancel() }},
+			}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Clock offset dialog failed: %v", err)
+	}
+}
+
+// setDateWindow applies the configured earliest plausible year.
+func (ui *LumeUI) setDateWindow() {
+	if ui.Config.DateMinYear > 0 {
+		metadata.Plausible.MinYear = ui.Config.DateMinYear
+	}
+}
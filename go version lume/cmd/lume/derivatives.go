@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+)
+
+// derivatives tells the edited copies of photos by the built-in names and those of the config, unless
+// the config files them by their own date.
+func (ui *LumeUI) derivatives() *metadata.Derivatives {
+	if ui.Config.NoDerivatives {
+		return nil
+	}
+	d, err := metadata.NewDerivatives(ui.Config.DerivativePatterns)
+	if err != nil {
+		logger.Error("%v", err)
+	}
+	return d
+}
+
+// derivativeNote names the original an edited copy was filed beside.
+func (ui *LumeUI) derivativeNote(r OrganizeResult) string {
+	if r.DerivativeOf == "" {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("derivative_note"), r.DerivativeOf)
+}
+
+// derivativeSummary counts the edited copies filed beside their originals.
+func (ui *LumeUI) derivativeSummary(res iter.Seq[OrganizeResult]) string {
+	n := 0
+	for r := range res {
+		if r.Success && r.DerivativeOf != "" {
+			n++
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("derivative_summary"), ui.count(n))
+}
@@ -0,0 +1,46 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// ShowDeviceAliases edits the device aliases, one "model = name" per line. The new names apply from
+// the next drop; folders already in the archive keep theirs.
+func (ui *LumeUI) ShowDeviceAliases() {
+	var dlg *walk.Dialog
+	var edit *walk.TextEdit
+	save := func() {
+		aliases, err := metadata.ParseDeviceAliases(edit.Text())
+		if err != nil {
+			walk.MsgBox(dlg, ui.T("warn_title"), fmt.Sprintf(ui.T("device_aliases_invalid"), err), walk.MsgBoxIconWarning)
+			return
+		}
+		ui.Config.DeviceAliases = aliases
+		if err := config.SaveConfig(ui.Config); err != nil {
+			logger.Error("Config save failed: %v", err)
+		}
+		dlg.Accept()
+	}
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("device_aliases_title"), MinSize: Size{Width: 420, Height: 320}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: ui.T("device_aliases_hint")},
+			TextEdit{AssignTo: &edit, Text: metadata.FormatDeviceAliases(ui.Config.DeviceAliases), VScroll: true},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				HSpacer{},
+				PushButton{Text: ui.T("ok_btn"), OnClicked: save},
+				PushButton{Text: ui.T("cancel_btn"), OnClicked: func() { dlg.Cancel() }},
+			}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Device alias dialog failed: %v", err)
+	}
+}
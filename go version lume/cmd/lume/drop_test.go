@@ -0,0 +1,37 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"lume-go/internal/config"
+	"lume-go/internal/queue"
+)
+
+func TestQueueDropDedupes(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "IMG_0001.jpg"), filepath.Join(dir, "IMG_0002.jpg")
+	for _, p := range []string{a, b} {
+		if err := os.WriteFile(p, []byte("not really a jpeg"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ui := &LumeUI{Config: config.Config{MaxFilesLimit: 100}, queued: queue.New()}
+
+	// A file dropped twice at once and again through its folder is queued once.
+	if got := ui.queueDrop([]string{a, a, dir}); !slices.Equal(got, []string{a, b}) {
+		t.Errorf("first drop queued %v; want %v", got, []string{a, b})
+	}
+	// Dropped again, under another spelling too, nothing is new.
+	if got := ui.queueDrop([]string{dir, strings.ToUpper(a), filepath.Join(dir, ".", "IMG_0002.jpg")}); len(got) != 0 {
+		t.Errorf("second drop queued %v; want nothing", got)
+	}
+	if ui.queued.Len() != 2 {
+		t.Errorf("%d files queued; want 2", ui.queued.Len())
+	}
+}
@@ -0,0 +1,83 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/scan"
+)
+
+// openScanCache keeps the scan cache in the user's config directory, so unchanged sources are not
+// read again on the next start. With no_cache it only lives for the session.
+func openScanCache(conf config.Config) *scan.Cache {
+	if conf.NoCache {
+		return scan.NewCache()
+	}
+	path, err := config.DataPath("scan_cache.json")
+	if err != nil {
+		logger.Error("Scan cache unavailable: %v", err)
+		return scan.NewCache()
+	}
+	return scan.OpenCache(path, conf.ScanCacheMax)
+}
+
+// scanDrop reads the metadata of dropped files in the background with the session's scan cache,
+// so re-dropping files after a cancelled run does not read them again. No run can start while the
+// scan goes on; cancelling keeps the files read so far queued.
+func (ui *LumeUI) scanDrop(paths []string) {
+	ui.mutex.Lock()
+	ui.isProcessing = true
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.updateQueueButtons()
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(fmt.Sprintf(ui.T("drop_scanning"), ui.count(0), ui.count(len(paths))))
+	hits, misses := ui.scanCache.Stats()
+	go func() {
+		defer ui.guard()
+		res, err := scan.All(ctx, paths, scan.Options{Links: ui.Config.LinkPolicy, Cache: ui.scanCache, Devices: metadata.NewDeviceNames(ui.Config.DeviceAliases), Clocks: ui.clocks(), Progress: func(done, total int) {
+			ui.MainWindow.Synchronize(func() {
+				ui.ProgressBar.SetValue(done * 100 / total)
+				ui.StatusLabel.SetText(fmt.Sprintf(ui.T("drop_scanning"), ui.count(done), ui.count(total)))
+			})
+		}})
+		h, m := ui.scanCache.Stats()
+		logger.Info("Scanned %d of %d dropped files (%d cached, %d read)", len(res), len(paths), h-hits, m-misses)
+		ui.MainWindow.Synchronize(func() {
+			ui.mutex.Lock()
+			read := make(map[string]bool, len(res))
+			for _, r := range res {
+				read[r.Path] = true
+				if r.Err != nil {
+					logger.Error("Drop check err: %v", r.Err)
+					ui.queued.Remove(r.Path)
+					continue
+				}
+				ui.FilesToMove = append(ui.FilesToMove, r.Info)
+				ui.FileCount++
+			}
+			for _, p := range paths {
+				if !read[p] {
+					ui.queued.Remove(p)
+				}
+			}
+			ui.mutex.Unlock()
+			status := ""
+			if err != nil {
+				status = ui.T("cancelled")
+			}
+			ui.finishBusy(status)
+			ui.mutex.Lock()
+			ui.updateQueueButtons()
+			ui.mutex.Unlock()
+		})
+	}()
+}
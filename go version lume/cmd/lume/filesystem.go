@@ -0,0 +1,75 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"lume-go/internal/validator"
+	"strings"
+
+	"github.com/lxn/walk"
+)
+
+// oversized are the queued files larger than the file system of their target holds.
+type oversized struct {
+	target, fileSystem string
+	paths              []string // largest first
+	sizes              map[string]int64
+}
+
+// tooLarge finds, per target, the queued files larger than its file system holds, such as videos
+// over 4 GB bound for a stick formatted FAT32. The run refuses them before copying anything.
+func (ui *LumeUI) tooLarge() []oversized {
+	ui.mutex.Lock()
+	rt := ui.router()
+	files := engine.PlanFiles(rt, ui.layout(), ui.FilesToMove, ui.PendingPaths, ui.SourceRoots, ui.Config.PreserveStructure)
+	ui.mutex.Unlock()
+	byTarget := make(map[string]map[string]int64)
+	for _, f := range files {
+		if byTarget[f.Target] == nil {
+			byTarget[f.Target] = make(map[string]int64)
+		}
+		byTarget[f.Target][f.Path] = f.Size
+	}
+	var found []oversized
+	for _, target := range rt.Targets() {
+		sizes := byTarget[target]
+		if len(sizes) == 0 {
+			continue
+		}
+		fs, paths := validator.TooLarge(target, sizes, validator.FileSystem)
+		if len(paths) == 0 {
+			continue
+		}
+		logger.Info("%d queued files are too large for %s on %s: %s", len(paths), fs, target, strings.Join(paths, ", "))
+		found = append(found, oversized{target, fs, paths, sizes})
+	}
+	return found
+}
+
+// tooLargeText describes a file too large for the file system of its target.
+func (ui *LumeUI) tooLargeText(o oversized, path string) string {
+	return fmt.Sprintf(ui.T("err_too_large"), ui.bytes(o.sizes[path]), o.fileSystem, o.target, ui.bytes(validator.MaxFileSize(o.fileSystem)))
+}
+
+// confirmTooLarge lists the queued files too large for their target and lets the user back out before
+// anything moves.
+func (ui *LumeUI) confirmTooLarge() bool {
+	for _, o := range ui.tooLarge() {
+		var b strings.Builder
+		for i, p := range o.paths {
+			if i == MaxErrorsDisplay {
+				b.WriteString("\n" + ui.T("see_log"))
+				break
+			}
+			fmt.Fprintf(&b, "\n- %s (%s)", p, ui.bytes(o.sizes[p]))
+		}
+		msg := fmt.Sprintf(ui.T("too_large_confirm"), ui.count(len(o.paths)), o.target, o.fileSystem, ui.bytes(validator.MaxFileSize(o.fileSystem)), b.String())
+		if walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) != walk.DlgCmdYes {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/history"
+	"lume-go/internal/lock"
+	"lume-go/internal/logger"
+	"lume-go/internal/organizer"
+	"lume-go/internal/queue"
+	"lume-go/internal/ratelimit"
+	"lume-go/internal/units"
+	"lume-go/internal/validator"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// headlessExitCode is 0 when every file was archived, already in place or imported before, else the code of the highest-priority failure.
+func headlessExitCode(res []OrganizeResult) int {
+	failed := make(map[string]int)
+	for _, r := range res {
+		if !r.Success && !r.InPlace && !r.Previously || r.Unverified {
+			failed[organizer.Category(r.Error)]++
+		}
+	}
+	return engine.FailureExitCode(failed)
+}
+
+// isHeadless reports whether the command line asks for a headless run: options (--headless) run
+// without a window, while Explorer only ever passes paths.
+func isHeadless(args []string) bool {
+	return len(args) > 0 && strings.HasPrefix(args[0], "-")
+}
+
+// runHeadless organizes without a window: lume --headless [--force-unlock] [--preserve-structure]
+// [--order O] <source>... <target>. It follows the config like a run from the window, --preserve-structure
+// turning on its PreserveStructure for this run, and prints its progress to the console.
+func runHeadless(args []string) int {
+	attachConsole()
+	conf := config.LoadConfig()
+	ui := &LumeUI{Config: conf, queued: queue.New()}
+	ui.scanCache = openScanCache(conf)
+	ui.history = loadHistory()
+	ui.imports = loadImports()
+	ui.setDateWindow()
+	logger.SetDebug(conf.DebugLog)
+
+	fs := flag.NewFlagSet("lume", flag.ContinueOnError)
+	fs.Bool("headless", false, "run without a window")
+	forceUnlock := fs.Bool("force-unlock", false, "take over the target lock of another run")
+	structure := fs.Bool("preserve-structure", false, "keep the folders of the sources instead of sorting by date")
+	verbose := fs.Bool("verbose", false, "also print how many files are moved into each target at once")
+	limit := fs.String("limit", "", "copy at most this many bytes per second, e.g. 20MB/s")
+	anomalyExit := fs.Bool("anomaly-exit", false, "exit with code 11 when the run is out of line with the earlier ones")
+	order := fs.String("order", "", "process the files in this order: input, newest, oldest or smallest")
+	fs.Usage = func() { fmt.Println(ui.T("headless_usage")) }
+	if err := fs.Parse(args); err != nil || fs.NArg() < 2 {
+		fs.Usage()
+		return engine.ExitUsage
+	}
+	sources, target := fs.Args()[:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+	if *limit != "" {
+		rate, err := units.ParseRate(*limit)
+		if err != nil {
+			fmt.Println(err)
+			fs.Usage()
+			return engine.ExitUsage
+		}
+		ui.Config.RateLimit = units.Size(rate)
+	}
+	if *order != "" {
+		if !engine.ValidOrder(*order) {
+			fs.Usage()
+			return engine.ExitUsage
+		}
+		ui.Config.ProcessingOrder = *order
+	}
+	if ui.Config.RateLimit > 0 {
+		ui.limiter = ratelimit.New(int64(ui.Config.RateLimit))
+	}
+	ui.Config.PreserveStructure = ui.Config.PreserveStructure || *structure
+	if abs, err := filepath.Abs(target); err == nil {
+		target = abs
+	}
+	if err := validator.CheckWritability(target); err != nil {
+		fmt.Printf(ui.T("err_val")+"\n", ui.errText(err))
+		return headlessExitCode([]OrganizeResult{{Error: err}})
+	}
+	var roots []string
+	for _, src := range sources {
+		st, err := os.Stat(src)
+		if err != nil {
+			fmt.Printf(ui.T("err_val")+"\n", err)
+			return headlessExitCode([]OrganizeResult{{Error: err}})
+		}
+		if validator.SameFile(src, target) || validator.IsSubPath(target, src) {
+			fmt.Println(ui.T("err_same_path"))
+			return engine.ExitUsage
+		}
+		if st.IsDir() {
+			if abs, err := filepath.Abs(src); err == nil {
+				src = abs
+			}
+			roots = append(roots, src)
+		}
+	}
+
+	// Warnings go to stderr, so a task that keeps stdout still sees them.
+	warn := func(found []history.Anomaly, code int) int {
+		for _, a := range found {
+			fmt.Fprintln(os.Stderr, "⚠ "+ui.anomalyText(a))
+		}
+		if code == 0 && len(found) > 0 && *anomalyExit {
+			return engine.ExitAnomaly
+		}
+		return code
+	}
+	files, _ := engine.Collect(sources, ui.runTargets(target), ui.filters())
+	if len(files) == 0 {
+		fmt.Printf(ui.T("sched_nothing")+"\n", strings.Join(sources, ", "))
+		return warn(ui.recordRun(runSource(sources), 0, 0), 0)
+	}
+	ui.TargetFolder, ui.PendingPaths = target, files
+	if msg, err := ui.checkTargets(); err != nil {
+		fmt.Println(msg)
+		return headlessExitCode([]OrganizeResult{{Error: err}})
+	}
+	for _, o := range ui.tooLarge() {
+		for _, p := range o.paths {
+			fmt.Fprintln(os.Stderr, "⚠ "+p+": "+ui.tooLargeText(o, p))
+		}
+	}
+	release, err := ui.lockTargets(ui.runTargets(target), *forceUnlock)
+	var locked *lock.LockedError
+	if errors.As(err, &locked) {
+		fmt.Println(ui.lockedText(locked))
+		return engine.ExitBusy
+	} else if err != nil {
+		fmt.Printf(ui.T("err_val")+"\n", err)
+		return headlessExitCode([]OrganizeResult{{Error: err}})
+	}
+	defer release()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	started := time.Now()
+	defer ui.backgroundPriority()()
+	journal := openJournal(headlessJournal)
+	defer journal.Close()
+	opts := ui.runOptions(target)
+	opts.Roots = roots
+	sum, runErr := engine.Run(ctx, engine.RunSpec{Pending: files, Options: opts, Progress: consoleProgress{ui}, Journal: journal})
+	fmt.Println()
+	if *verbose {
+		for _, t := range sum.Throttles {
+			fmt.Printf(ui.T("throttle_verbose")+"\n", t.Target, t.Workers, ui.T("drive_"+t.Kind))
+		}
+	}
+	spaceStop := errors.Is(runErr, validator.ErrInsufficientSpace)
+	if runErr != nil && !spaceStop {
+		fmt.Println(ui.T("cancelled"))
+	}
+	nearPairs := ui.finishRun(roots, sum)
+	// A cancelled run says nothing of the source.
+	var found []history.Anomaly
+	if ctx.Err() == nil {
+		found = ui.recordRun(runSource(sources), len(files), sum.Errors)
+	}
+
+	res := sum.All()
+	missing := missingReport(res)
+	errs := 0
+	for _, n := range sum.Failures {
+		errs += n
+	}
+	summary := fmt.Sprintf(ui.T("success_msg"), ui.count(sum.Moved), ui.count(errs)) + ui.routeSummary(res) + ui.dateSummary(res) + ui.shiftSummary(res) + ui.missingSummary(missing) + ui.inPlaceSummary(sum) + ui.previouslySummary(sum) + ui.reconcileSummary(sum) + ui.unverifiedSummary(sum) + ui.orphanSummary(sum) + ui.derivativeSummary(res) + ui.inUseSummary(sum.InUse, false)
+	fmt.Println(summary)
+	for r := range res {
+		if r.Failed() {
+			fmt.Printf("- %s: %s%s\n", r.File, ui.errorText(r.Error), ui.retriedNote(r))
+		}
+	}
+	if len(nearPairs) > 0 {
+		fmt.Printf(ui.T("neardup_info")+"\n", len(nearPairs))
+	}
+	if spaceStop {
+		stopped := fmt.Sprintf(ui.T("space_stopped"), ui.count(len(sum.Unattempted)), ui.errText(runErr))
+		summary += "\n\n" + stopped
+		fmt.Println(stopped)
+		for _, p := range sum.Unattempted {
+			fmt.Printf("- %s\n", p)
+		}
+	}
+	if warn := ui.runHook(started, target, sum, runErr); warn != "" {
+		fmt.Println(warn)
+	}
+	ui.deliverReport(ui.runReport(sum, summary, journal.Path()))
+	return warn(found, engine.ExitCode(sum, runErr))
+}
+
+// consoleProgress prints a run's progress on one console line.
+type consoleProgress struct{ ui *LumeUI }
+
+func (p consoleProgress) Phase(name string) { fmt.Println(p.ui.T(name)) }
+
+func (p consoleProgress) Step(done, total int, _ OrganizeResult) {
+	fmt.Printf("\r"+p.ui.T("proc_count"), p.ui.count(done), p.ui.count(total))
+}
+
+// attachConsole borrows the console of the shell that started Lume, which a GUI program does not get
+// by itself. Started without one (e.g. from Task Scheduler), the output only reaches the log.
+func attachConsole() {
+	const attachParentProcess = ^uintptr(0)
+	if r, _, _ := syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole").Call(attachParentProcess); r == 0 {
+		return
+	}
+	if out, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+		os.Stdout, os.Stderr = out, out
+	}
+}
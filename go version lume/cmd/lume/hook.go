@@ -0,0 +1,29 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/engine"
+	"lume-go/internal/hook"
+	"lume-go/internal/logger"
+	"lume-go/pkg/lume"
+	"time"
+)
+
+// runHook sends the summary of a finished run into target to the configured hook. A failed hook is
+// logged and returned as a warning for the user; it never changes the outcome of the run.
+func (ui *LumeUI) runHook(started time.Time, target string, sum engine.RunSummary, runErr error) string {
+	c := ui.Config.Hook
+	if !c.Enabled() {
+		return ""
+	}
+	s := engine.HookSummary("lume", lume.Version(), started, ui.runTargets(target), sum, runErr)
+	if err := hook.Send(context.Background(), c, s); err != nil {
+		logger.Error("Run hook failed: %v", err)
+		return fmt.Sprintf(ui.T("hook_failed"), err)
+	}
+	logger.Info("Run hook delivered: %s, %d moved", s.Status, s.Moved)
+	return ""
+}
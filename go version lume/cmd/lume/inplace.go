@@ -0,0 +1,24 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/engine"
+)
+
+// categoryInPlace files the results already where the layout puts them for the results filter.
+const categoryInPlace = "in_place"
+
+// inPlaceSummary notes under the run summary the files left where they were, already organized or
+// copies of another queued file; they are neither moved nor errors.
+func (ui *LumeUI) inPlaceSummary(sum engine.RunSummary) string {
+	s := ""
+	if sum.InPlace > 0 {
+		s += "\n\n" + fmt.Sprintf(ui.T("in_place_summary"), ui.count(sum.InPlace))
+	}
+	if sum.Duplicates > 0 {
+		s += "\n\n" + fmt.Sprintf(ui.T("dup_queued_summary"), ui.count(sum.Duplicates))
+	}
+	return s
+}
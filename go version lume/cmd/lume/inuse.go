@@ -0,0 +1,87 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"lume-go/internal/logger"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// retryInUse is the engine.Options.InUse of a run nobody watches: every file that was open in
+// another program is tried once more at the end of the run.
+func retryInUse(paths []string) []string {
+	logger.Info("%d files were open in another program; trying them again", len(paths))
+	return paths
+}
+
+// askInUse is the engine.Options.InUse of a run from the window. Once every other file is done, it
+// lists the files that were open in another program, so the user can close them and pick which to
+// try again; the others are skipped and stay queued. It is called from the run's goroutine and
+// waits for the answer.
+func (ui *LumeUI) askInUse(paths []string) []string {
+	answer := make(chan []string, 1)
+	ui.MainWindow.Synchronize(func() { answer <- ui.showInUse(paths) })
+	retry := <-answer
+	logger.Info("%d of %d files open in another program tried again", len(retry), len(paths))
+	return retry
+}
+
+// showInUse is the dialog of askInUse: retry the selected files, all of them or none.
+func (ui *LumeUI) showInUse(paths []string) []string {
+	rows := make([]*pendingRow, len(paths))
+	for i, p := range paths {
+		rows[i] = &pendingRow{File: filepath.Base(p), Folder: filepath.Dir(p), Path: p}
+	}
+	var dlg *walk.Dialog
+	var tv *walk.TableView
+	var retry []string
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("in_use_title"), MinSize: Size{Width: 620, Height: 360}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: fmt.Sprintf(ui.T("in_use_text"), ui.count(len(paths)))},
+			TableView{AssignTo: &tv, Model: &pendingModel{rows: rows}, MultiSelection: true,
+				Columns: []TableViewColumn{{DataMember: "File", Title: ui.T("col_file"), Width: 200}, {DataMember: "Folder", Title: ui.T("col_folder"), Width: 360}},
+			},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				PushButton{Text: ui.T("in_use_retry_sel"), OnClicked: func() {
+					for _, i := range tv.SelectedIndexes() {
+						retry = append(retry, rows[i].Path)
+					}
+					dlg.Accept()
+				}},
+				PushButton{Text: ui.T("in_use_retry_all"), OnClicked: func() { retry = paths; dlg.Accept() }},
+				HSpacer{},
+				PushButton{Text: ui.T("in_use_skip"), OnClicked: func() { dlg.Cancel() }},
+			}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("In-use dialog failed: %v", err)
+	}
+	return retry
+}
+
+// inUseSummary tells of the files still open in another program after the run, whose sources are
+// untouched; a run from the window keeps them queued.
+func (ui *LumeUI) inUseSummary(paths []string, queued bool) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	key := "in_use_left"
+	if queued {
+		key = "in_use_requeued"
+	}
+	return "\n\n" + fmt.Sprintf(ui.T(key), ui.count(len(paths)))
+}
+
+// retriedNote marks a file tried a second time, having been open in another program at first.
+func (ui *LumeUI) retriedNote(r OrganizeResult) string {
+	if !r.Retried {
+		return ""
+	}
+	return ui.T("in_use_retried")
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package main
+
+import (
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+)
+
+// The journals of the last window and headless runs; each run replaces its own.
+const (
+	resultsJournal  = "results.jsonl"
+	headlessJournal = "results_headless.jsonl"
+)
+
+// openJournal starts the journal a run streams its results to. When its file cannot be created the
+// results are kept in memory instead, as they were before journals.
+func openJournal(name string) *engine.Journal {
+	path, err := config.DataPath(name)
+	if err == nil {
+		var j *engine.Journal
+		if j, err = engine.CreateJournal(path, engine.JournalRecent); err == nil {
+			return j
+		}
+	}
+	logger.Error("Results journal unavailable, keeping results in memory: %v", err)
+	j, _ := engine.CreateJournal("", engine.JournalRecent)
+	return j
+}
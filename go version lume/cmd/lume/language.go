@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"lume-go/internal/config"
+	"lume-go/internal/i18n"
+	"lume-go/internal/logger"
+)
+
+// languageNames lists the embedded languages by their own names, in i18n.Languages order.
+func (ui *LumeUI) languageNames() []string {
+	var names []string
+	for _, l := range i18n.Languages() {
+		names = append(names, l.Name)
+	}
+	return names
+}
+
+func (ui *LumeUI) languageIndex() int {
+	for i, l := range i18n.Languages() {
+		if l.Code == ui.Config.Language {
+			return i
+		}
+	}
+	return -1
+}
+
+// rtl reports whether the UI language is written right to left; windows mirror their layout for it.
+func (ui *LumeUI) rtl() bool { return i18n.IsRTL(ui.Config.Language) }
+
+// LanguageChosen switches the UI to the language picked from the dropdown.
+func (ui *LumeUI) LanguageChosen() {
+	langs := i18n.Languages()
+	i := ui.LangCombo.CurrentIndex()
+	if i < 0 || i >= len(langs) || langs[i].Code == ui.Config.Language {
+		return
+	}
+	ui.Config.Language = langs[i].Code
+	if err := config.SaveConfig(ui.Config); err != nil {
+		logger.Error("Config save failed: %v", err)
+	}
+	ui.RefreshLocalization()
+}
+
+// count formats a file count with the digit grouping of the UI language.
+func (ui *LumeUI) count(n int) string { return i18n.FormatCount(ui.Config.Language, int64(n)) }
+
+// bytes formats a size in the largest fitting unit for the UI language.
+func (ui *LumeUI) bytes(n int64) string { return i18n.FormatBytes(ui.Config.Language, n) }
@@ -0,0 +1,389 @@
+//go:build windows
+
+// Command lume is the Windows desktop app. It is built on walk, so it only builds for Windows;
+// lume-lite is the command line app for every system.
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"lume-go/internal/cards"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/fsys"
+	"lume-go/internal/history"
+	"lume-go/internal/i18n"
+	"lume-go/internal/imports"
+	"lume-go/internal/instance"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/queue"
+	"lume-go/internal/ratelimit"
+	"lume-go/internal/runstate"
+	"lume-go/internal/scan"
+	"lume-go/internal/staging"
+	"lume-go/internal/validator"
+	"lume-go/internal/version"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// Elite Constants (Audit Point 1)
+const (
+	MaxErrorsDisplay = 10
+)
+
+// OrganizeResult is the outcome of one file of a run.
+type OrganizeResult = engine.OrganizeResult
+
+type LumeUI struct {
+	MainWindow     *walk.MainWindow
+	TargetCombo    *walk.ComboBox
+	StartBtn       *walk.PushButton
+	StatusLabel    *walk.Label
+	ThemeBtn       *walk.PushButton
+	LangCombo      *walk.ComboBox
+	SettingsBtn    *walk.PushButton
+	ArchiveHeader  *walk.Label
+	TargetHeader   *walk.Label
+	SelectionLabel *walk.Label
+	
+	TargetFolder string
+	FileCount    int
+	SizeFiltered int
+	MPFiltered   int
+	LinksSkipped int
+	HiddenSkipped int
+	PathsRejected int
+	PlaceholdersSkipped int
+	FilesToMove  []metadata.FileInfo
+	SourceRoots  []string
+	PendingPaths []string
+	Config       config.Config
+
+	GroupBox       *walk.GroupBox
+	SelectBtn      *walk.PushButton
+	ReorgBtn       *walk.PushButton
+	OpenTargetBtn  *walk.PushButton
+	ResultsBtn     *walk.PushButton
+	StatsBtn       *walk.PushButton
+	RoutesLabel    *walk.Label
+	PlanLabel      *walk.Label
+	ProgressBar    *walk.ProgressBar
+	CancelBtn      *walk.PushButton
+	ListBtn        *walk.PushButton
+	ClearBtn       *walk.PushButton
+	RateCombo      *walk.ComboBox
+	
+	cancelFunc     context.CancelFunc
+	updatingTargets bool
+	lastRun        *engine.Journal
+	lastRejected   error
+	resultsView    *resultModel
+	queued         *queue.Set
+	scanCache      *scan.Cache
+	history        *history.History
+	tray           *walk.NotifyIcon
+	stopSchedule   context.CancelFunc
+	mutex          sync.Mutex
+	isProcessing   bool
+	// planSeq numbers the plans showPlan works out, so only the latest is shown.
+	planSeq        int
+	// shifts are the date shifts set for the queued files, shiftExif whether they are written to the files.
+	shifts         []metadata.Shift
+	shiftExif      bool
+	// cardMemory remembers when each memory card was last imported; cardRoots maps the DCIM folders
+	// of the queued cards to their serials. stopCards ends the card watch.
+	cardMemory     *cards.Memory
+	cardRoots      map[string]string
+	stopCards      context.CancelFunc
+	// imports fingerprints the files runs left on their source volumes, so the next import skips them.
+	imports        *imports.Store
+	// limiter paces the copies of every run to the rate picked in the window, also while one goes on.
+	limiter        *ratelimit.Limiter
+	// runs records the window runs while they go; recovered is the run a crash cut short shown in
+	// lastRun, until it is resumed or dismissed.
+	runs           *runstate.Store
+	recovered      *recoveredRun
+	// staging holds the copies of files dropped from places that do not last, such as a phone over MTP.
+	staging        *staging.Area
+}
+
+func (ui *LumeUI) T(k string) string { return i18n.T(ui.Config.Language, k) }
+
+// layout is the folder layout from the config; month names follow the app language unless the config maps all twelve.
+func (ui *LumeUI) layout() organizer.Layout {
+	names := ui.Config.MonthNames
+	if len(names) != 12 { names = strings.Split(ui.T("month_names"), ",") }
+	bursts := organizer.BurstOptions{MinCount: ui.Config.BurstMinCount, Gap: time.Duration(ui.Config.BurstGap) * time.Second}
+	layout := organizer.Layout{Template: ui.Config.FolderTemplate, NameTemplate: ui.Config.NameTemplate, MonthStyle: ui.Config.MonthStyle, MonthNames: names, Burst: bursts, Devices: metadata.NewDeviceNames(ui.Config.DeviceAliases), SourceDepth: ui.Config.SourceDepth}
+	if ui.Config.PoolDevices { layout.MinDeviceFiles = ui.Config.DeviceMinFiles }
+	return layout
+}
+
+// mainMinSize is the smallest the main window gets, in 1/96".
+var mainMinSize = Size{420, 450}
+
+func main() {
+	if err := logger.Init(); err != nil { fmt.Printf("Fatal: %v\n", err) }
+	
+	defer func() {
+		if r := recover(); r != nil { reportCrash(config.LoadConfig(), r, debug.Stack(), !isHeadless(os.Args[1:]), nil); logger.Close(); os.Exit(engine.ExitOther) }
+		logger.Close()
+	}()
+
+	// Files passed on the command line (Explorer "Send to") go to an already open window if there is one.
+	args := os.Args[1:]
+	if len(args) == 1 && (args[0] == "--version" || args[0] == "-version") { attachConsole(); fmt.Println(version.String("lume")); return }
+	if isHeadless(args) { code := runHeadless(args); logger.Close(); os.Exit(code) }
+	if ok, err := instance.Forward(args); ok { logger.Info("Forwarded %d paths to the running instance", len(args)); return } else if err != nil { logger.Error("Instance forward failed: %v", err) }
+
+	ui := &LumeUI{Config: config.LoadConfig(), queued: queue.New()}; ui.scanCache = openScanCache(ui.Config); ui.history = loadHistory(); ui.cardMemory, ui.cardRoots = loadCardMemory(), map[string]string{}; ui.imports = loadImports(); ui.limiter = ratelimit.New(int64(ui.Config.RateLimit)); ui.runs = openRuns(); ui.sweepStaging(); ui.setDateWindow(); logger.SetDebug(ui.Config.DebugLog)
+
+	// Elite Signal Handler Fixed (Audit 2.1 Point 3)
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sc
+		logger.Info("Shutdown signal received. Shutting down gracefully...")
+		ui.mutex.Lock()
+		if ui.cancelFunc != nil { ui.cancelFunc() }
+		ui.mutex.Unlock()
+		logger.Close() // Ensure log is closed
+		os.Exit(0)
+	}()
+
+	if err := (MainWindow{
+		AssignTo: &ui.MainWindow, RightToLeftLayout: ui.rtl(), Title: ui.T("title"), MinSize: mainMinSize, Layout: VBox{}, OnDropFiles: ui.HandleDrop,
+		Children: []Widget{
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{HSpacer{}, PushButton{AssignTo: &ui.StatsBtn, Text: ui.T("stats_btn"), OnClicked: ui.ShowStats}, PushButton{AssignTo: &ui.SettingsBtn, Text: ui.T("settings_btn"), OnClicked: ui.ShowSettings}, ComboBox{AssignTo: &ui.LangCombo, Model: ui.languageNames(), CurrentIndex: ui.languageIndex(), OnCurrentIndexChanged: ui.LanguageChosen}, PushButton{AssignTo: &ui.ThemeBtn, Text: ui.GetThemeBtnText(), OnClicked: ui.ToggleTheme}}},
+			Label{AssignTo: &ui.ArchiveHeader, Text: ui.T("archive_ops"), Font: Font{PointSize: 10, Bold: true}},
+			GroupBox{AssignTo: &ui.GroupBox, Layout: VBox{}, Children: []Widget{
+				Composite{Layout: HBox{}, Children: []Widget{Label{AssignTo: &ui.TargetHeader, Text: ui.T("target_folder")}, ComboBox{AssignTo: &ui.TargetCombo, Model: ui.Config.RecentTargets, MinSize: Size{Width: 200}, OnCurrentIndexChanged: ui.TargetChosen}, PushButton{AssignTo: &ui.SelectBtn, Text: ui.T("select_btn"), OnClicked: ui.SelectFolder}, PushButton{AssignTo: &ui.OpenTargetBtn, Text: ui.T("open_target_btn"), Enabled: false, OnClicked: ui.OpenTarget}, PushButton{AssignTo: &ui.ReorgBtn, Text: ui.T("reorg_btn"), OnClicked: ui.Reorganize}}},
+				Label{AssignTo: &ui.RoutesLabel, Text: ui.routesText(), Visible: len(ui.Config.Routes) > 0},
+				Label{AssignTo: &ui.SelectionLabel, Text: ui.T("drag_drop"), Font: Font{PointSize: 12, Bold: true}},
+				Label{AssignTo: &ui.StatusLabel, Text: ui.GetStatusText()},
+				ProgressBar{AssignTo: &ui.ProgressBar, MinValue: 0, MaxValue: 100, Visible: false},
+			}},
+			Label{AssignTo: &ui.PlanLabel, Visible: false},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{PushButton{AssignTo: &ui.StartBtn, Text: ui.T("start_btn"), OnClicked: ui.StartOrganizing}, PushButton{AssignTo: &ui.CancelBtn, Text: ui.T("cancel_btn"), Visible: false, OnClicked: ui.CancelOrganizing}, PushButton{AssignTo: &ui.ListBtn, Text: ui.T("list_btn"), Enabled: false, OnClicked: ui.ShowPending}, PushButton{AssignTo: &ui.ClearBtn, Text: ui.T("clear_btn"), Enabled: false, OnClicked: ui.ClearPending}, PushButton{AssignTo: &ui.ResultsBtn, Text: ui.T("results_btn"), Enabled: false, OnClicked: ui.ShowResults}, HSpacer{}, ComboBox{AssignTo: &ui.RateCombo, Model: ui.rateLabels(), CurrentIndex: ui.rateIndex(), ToolTipText: ui.T("rate_tip"), OnCurrentIndexChanged: ui.RateChosen}}},
+		},
+	}.Create()); err != nil { panic(err) }
+	
+	if ui.Config.TargetFolder != "" { ui.TargetFolder = ui.Config.TargetFolder; ui.Config.RememberTarget(ui.TargetFolder) }
+	ui.refreshTargets(); ui.registerShortcuts()
+	if icon, err := walk.NewIconFromFile("lume.ico"); err == nil { ui.MainWindow.SetIcon(icon) }
+	placeForm(ui.MainWindow, ui.Config.MainWindow, walk.Size(mainMinSize)); trackPlacement(ui.MainWindow, &ui.Config.MainWindow)
+	ui.MainWindow.Closing().Attach(func(*bool, walk.CloseReason) { if err := config.SaveConfig(ui.Config); err != nil { logger.Error("Config save failed: %v", err) } })
+	if srv, err := instance.Listen(func(paths []string) { ui.MainWindow.Synchronize(func() { ui.receiveForwarded(paths) }) }); err != nil { logger.Error("Single instance pipe unavailable: %v", err) } else { defer srv.Close() }
+	if len(args) > 0 { ui.HandleDrop(args) }
+	ui.startSchedule(); defer ui.closeSchedule()
+	ui.startCardWatch(); defer ui.stopCardWatch()
+	ui.MainWindow.Synchronize(ui.recoverRun)
+	ui.ApplyTheme(); ui.MainWindow.Run()
+	if err := ui.scanCache.Save(); err != nil { logger.Error("Scan cache save failed: %v", err) }
+	if ui.staging != nil { if err := ui.staging.Clean(); err != nil { logger.Error("Staging area not removed: %v", err) } }
+}
+
+// GetStatusText describes the queue and, while Start is disabled, what is missing to start.
+func (ui *LumeUI) GetStatusText() string {
+	if hint := ui.startHint(); hint != "" { return ui.queueText() + " " + hint }
+	return ui.queueText()
+}
+
+func (ui *LumeUI) queueText() string {
+	filtered := ""
+	if ui.SizeFiltered > 0 { filtered = fmt.Sprintf(ui.T("size_filtered"), ui.count(ui.SizeFiltered)) }
+	if ui.MPFiltered > 0 { filtered += fmt.Sprintf(ui.T("mp_filtered"), ui.count(ui.MPFiltered)) }
+	if ui.LinksSkipped > 0 { filtered += fmt.Sprintf(ui.T("links_skipped"), ui.count(ui.LinksSkipped)) }
+	if ui.HiddenSkipped > 0 { filtered += fmt.Sprintf(ui.T("hidden_skipped"), ui.count(ui.HiddenSkipped)) }
+	if ui.PlaceholdersSkipped > 0 { filtered += fmt.Sprintf(ui.T("placeholders_skipped"), ui.count(ui.PlaceholdersSkipped)) }
+	if ui.PathsRejected > 0 { filtered += fmt.Sprintf(ui.T("paths_rejected"), ui.count(ui.PathsRejected), ui.pathReason(ui.lastRejected)) }
+	if lim := ui.Config.MaxFilesLimit; ui.FileCount > lim {
+		return fmt.Sprintf(ui.T("files_batched"), ui.count(ui.FileCount), (ui.FileCount+lim-1)/lim) + filtered
+	}
+	if ui.FileCount > 0 || filtered != "" {
+		return fmt.Sprintf(ui.T("files_ready"), ui.count(ui.FileCount)) + filtered
+	}
+	// Display Stats when idle (Audit 2.1 Point 5)
+	if ui.Config.Stats.TotalFiles > 0 {
+		return fmt.Sprintf(ui.T("stats_info"), ui.count(ui.Config.Stats.TotalFiles), ui.bytes(ui.Config.Stats.TotalSize), ui.count(ui.Config.Stats.TotalOrganized))
+	}
+	return fmt.Sprintf(ui.T("files_ready"), ui.count(0))
+}
+
+func (ui *LumeUI) ToggleTheme() { ui.Config.DarkMode = !ui.Config.DarkMode; config.SaveConfig(ui.Config); ui.ThemeBtn.SetText(ui.GetThemeBtnText()); ui.ApplyTheme() }
+func (ui *LumeUI) GetThemeBtnText() string { if ui.Config.DarkMode { return ui.T("theme_light") }; return ui.T("theme_dark") }
+func (ui *LumeUI) RefreshLocalization() { ui.MainWindow.SetTitle(ui.T("title")); ui.MainWindow.SetRightToLeftLayout(ui.rtl()); ui.SettingsBtn.SetText(ui.T("settings_btn")); ui.StatsBtn.SetText(ui.T("stats_btn")); ui.ThemeBtn.SetText(ui.GetThemeBtnText()); ui.ArchiveHeader.SetText(ui.T("archive_ops")); ui.TargetHeader.SetText(ui.T("target_folder")); ui.SelectBtn.SetText(ui.T("select_btn")); ui.ReorgBtn.SetText(ui.T("reorg_btn")); ui.OpenTargetBtn.SetText(ui.T("open_target_btn")); ui.ResultsBtn.SetText(ui.T("results_btn")); ui.ListBtn.SetText(ui.T("list_btn")); ui.ClearBtn.SetText(ui.T("clear_btn")); ui.RoutesLabel.SetText(ui.routesText()); ui.SelectionLabel.SetText(ui.T("drag_drop")); ui.StatusLabel.SetText(ui.GetStatusText()); ui.StartBtn.SetText(ui.T("start_btn")); ui.CancelBtn.SetText(ui.T("cancel_btn")); ui.RateCombo.SetModel(ui.rateLabels()); ui.RateCombo.SetCurrentIndex(ui.rateIndex()); ui.refreshTips(); ui.showPlan() }
+func (ui *LumeUI) ApplyTheme() { bg, tx := walk.Color(walk.RGB(240, 240, 240)), walk.Color(walk.RGB(0, 0, 0)); if ui.Config.DarkMode { bg, tx = walk.Color(walk.RGB(35, 35, 35)), walk.Color(walk.RGB(255, 255, 255)) }; br, _ := walk.NewSolidColorBrush(bg); ui.MainWindow.SetBackground(br); for i := 0; i < ui.MainWindow.Children().Len(); i++ { ui.recursiveStyle(ui.MainWindow.Children().At(i), br, tx) }; ui.MainWindow.Invalidate() }
+func (ui *LumeUI) recursiveStyle(w walk.Widget, b walk.Brush, t walk.Color) { w.SetBackground(b); if l, ok := w.(*walk.Label); ok { l.SetTextColor(t) }; if c, ok := w.(walk.Container); ok { for i := 0; i < c.Children().Len(); i++ { ui.recursiveStyle(c.Children().At(i), b, t) } } }
+func (ui *LumeUI) SelectFolder() { ui.mutex.Lock(); if ui.isProcessing { ui.mutex.Unlock(); return }; ui.mutex.Unlock(); dlg := new(walk.FileDialog); if ok, _ := dlg.ShowBrowseFolder(ui.MainWindow); ok { if err := validator.CheckWritability(dlg.FilePath); err != nil { walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), ui.errText(err)), walk.MsgBoxIconError); return }; if err := ui.checkNesting(ui.runTargets(dlg.FilePath)); err != nil { walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.errText(err), walk.MsgBoxIconError); return }; ui.setTarget(dlg.FilePath) } }
+func (ui *LumeUI) HandleDrop(ps []string) {
+	ui.mutex.Lock(); if ui.isProcessing { ui.mutex.Unlock(); return }
+	// Files a phone lends over MTP vanish before a run comes to them; they are staged first.
+	if lasting, eph := ui.ephemeralDrop(ps); len(eph) > 0 { ui.mutex.Unlock(); ui.stageDrop(eph, lasting); return }
+	toScan := ui.queueDrop(ps)
+	ui.mutex.Unlock()
+	if len(toScan) > 0 { ui.scanDrop(toScan); return }
+	ui.StatusLabel.SetText(ui.GetStatusText()); ui.updateQueueButtons(); ui.showPlan()
+}
+
+// queueDrop queues the dropped paths that pass the filters and are not queued yet, directly, through
+// their folder or by another path to the same file, and returns those whose metadata is to be read.
+// The caller holds ui.mutex.
+func (ui *LumeUI) queueDrop(ps []string) []string {
+	var toScan []string
+	for _, p := range ui.expandDrop(ps) {
+		if rp, err := metadata.ResolveLink(p, ui.Config.LinkPolicy); errors.Is(err, metadata.ErrLink) { ui.LinksSkipped++; continue } else if err == nil { p = rp }
+		if err := validator.CheckPath(p); err != nil { logger.Info("Drop refused: %v", err); ui.PathsRejected++; ui.lastRejected = err; continue }
+		if validator.IsSubPath(ui.TargetFolder, p) || ui.queued.Has(p) { continue }
+		if st, err := os.Stat(p); err == nil {
+			if !ui.Config.IncludeHidden && metadata.IsHidden(p, st) { ui.HiddenSkipped++; continue }
+			if !ui.sizeAllowed(st.Size()) { ui.SizeFiltered++; continue }
+			// Reading an online-only cloud file would download it; only the hydrate policy queues placeholders.
+			if metadata.IsPlaceholder(st) && ui.Config.CloudPolicy != metadata.CloudHydrate { ui.PlaceholdersSkipped++; continue }
+			if !metadata.IsPlaceholder(st) && !ui.megapixelsAllowed(p) { ui.MPFiltered++; continue }
+		}
+		// Beyond the limit only the path is queued; metadata is read when its batch comes up.
+		if len(ui.FilesToMove)+len(toScan) >= ui.Config.MaxFilesLimit {
+			if metadata.SupportedExtensions[strings.ToLower(filepath.Ext(p))] { ui.PendingPaths = append(ui.PendingPaths, p); ui.queued.Add(p); ui.FileCount++ }
+			continue
+		}
+		toScan = append(toScan, p); ui.queued.Add(p)
+	}
+	return toScan
+}
+
+// sizeAllowed applies the MinFileSize/MaxFileSize filter; a zero bound is off.
+func (ui *LumeUI) sizeAllowed(n int64) bool { return ui.filters().Size(n) }
+
+// megapixelsAllowed applies the MinMegapixels filter from the image header. Files whose dimensions
+// cannot be read pass.
+func (ui *LumeUI) megapixelsAllowed(path string) bool { return ui.filters().Megapixels(path) }
+
+// filters are the drop filters of the config. Online-only cloud files follow the cloud policy.
+func (ui *LumeUI) filters() engine.Filters {
+	return engine.Filters{MinSize: int64(ui.Config.MinFileSize), MaxSize: int64(ui.Config.MaxFileSize), MinMegapixels: ui.Config.MinMegapixels, Links: ui.Config.LinkPolicy, IncludeHidden: ui.Config.IncludeHidden, Hydrate: ui.Config.CloudPolicy == metadata.CloudHydrate}
+}
+
+// expandDrop flattens dropped folders into their supported files and remembers them as source roots.
+// Links inside the folders follow the link policy; dropped file links are resolved by HandleDrop.
+func (ui *LumeUI) expandDrop(ps []string) []string {
+	var files []string
+	var targets []string
+	if ui.TargetFolder != "" { targets = ui.runTargets(ui.TargetFolder) }
+	scan := metadata.ScanOptions{Links: ui.Config.LinkPolicy, OnLink: func(string) { ui.LinksSkipped++ },
+		SkipDir: func(dir string) bool { return engine.IsTarget(dir, targets) },
+		SkipHidden: !ui.Config.IncludeHidden, OnHidden: func(string) { ui.HiddenSkipped++ }}
+	for _, p := range ps {
+		st, err := os.Stat(p)
+		if err != nil || !st.IsDir() { files = append(files, p); continue }
+		// A folder inside the target would archive the archive; a folder containing a target is scanned around it.
+		if validator.IsSubPath(ui.TargetFolder, p) { continue }
+		ui.SourceRoots = append(ui.SourceRoots, p)
+		metadata.ScanDir(p, scan, func(path string) { files = append(files, path) })
+	}
+	return files
+}
+
+// pruneSources removes the source folders emptied by this run when the prune option is on. Copies left
+// in place as duplicates do not empty their folders.
+func (ui *LumeUI) pruneSources(roots []string, res iter.Seq[OrganizeResult]) {
+	if !ui.Config.PruneEmpty || len(roots) == 0 { return }
+	var moved []string
+	for r := range res { if r.MovedFrom(fsys.OS) { moved = append(moved, r.Path) } }
+	for _, root := range roots {
+		if _, err := organizer.PruneEmptyDirs(root, moved, false); err != nil { logger.Error("Prune error for %s: %v", root, err) }
+	}
+}
+func (ui *LumeUI) StartOrganizing() { ui.mutex.Lock(); if ui.TargetFolder == "" { ui.mutex.Unlock(); walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("warn_select"), walk.MsgBoxIconWarning); return }; if ui.FileCount == 0 || ui.isProcessing { ui.mutex.Unlock(); return }; gone := ui.pruneMissing(); left := ui.FileCount; ui.mutex.Unlock(); note := ""; if len(gone) > 0 { note = fmt.Sprintf(ui.T("missing_pruned"), ui.count(len(gone))) }
+	// With every queued file gone there is nothing to check: the run only reports the missing ones.
+	if left > 0 { ui.StatusLabel.SetText(ui.T("checking_space") + note); if msg, err := ui.checkTargets(); err != nil { walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxIconError); ui.StatusLabel.SetText(ui.GetStatusText()); return }; if !ui.confirmHydrate() || !ui.confirmProtected() || !ui.confirmTooLarge() { ui.StatusLabel.SetText(ui.GetStatusText()); return } }
+	release := ui.lockRun(ui.TargetFolder); if release == nil { ui.StatusLabel.SetText(ui.GetStatusText()); return }; ui.mutex.Lock(); ui.isProcessing = true; ui.mutex.Unlock(); ui.showPlan(); ui.StartBtn.SetEnabled(false); ui.ListBtn.SetEnabled(false); ui.ClearBtn.SetEnabled(false); ui.CancelBtn.SetVisible(true); ui.ProgressBar.SetVisible(true); ui.ProgressBar.SetValue(0); ctx, cancel := context.WithCancel(context.Background()); ui.cancelFunc = cancel; go func() { defer ui.guard(); defer cancel(); defer release()
+		ui.mutex.Lock(); wl, pending, target, roots := ui.FilesToMove, ui.PendingPaths, ui.TargetFolder, ui.SourceRoots; ui.FilesToMove, ui.PendingPaths = nil, nil; ui.mutex.Unlock()
+		ui.organize(ctx, wl, pending, target, roots, gone, nil)
+	}()
+}
+
+// organize moves wl and then the pending paths, batch by batch, into target and reports the run. Manual runs
+// end in message boxes and clear the queue; a scheduled run passes notify instead, told whether to warn, leaves
+// the queue alone and is judged against the earlier runs of its source.
+func (ui *LumeUI) organize(ctx context.Context, wl []metadata.FileInfo, pending []string, target string, roots []string, gone []OrganizeResult, notify func(summary string, warn bool)) {
+	defer ui.backgroundPriority()()
+	journal := openJournal(resultsJournal)
+	progress, started := ui.runProgress(journal), time.Now()
+	opts := ui.runOptions(target); opts.Roots = roots; if notify == nil { opts.InUse = ui.askInUse }
+	endRun := ui.beginRun(journal, wl, pending, target, roots)
+	sum, err := engine.Run(ctx, engine.RunSpec{Files: wl, Pending: pending, Prior: gone, Options: opts, Progress: progress, Journal: journal}); progress.Close(); endRun()
+	spaceStop := errors.Is(err, validator.ErrInsufficientSpace)
+	if err != nil && !spaceStop { ui.MainWindow.Synchronize(func() { ui.StatusLabel.SetText(ui.T("cancelled")) }) }
+	res, successCount, ec := sum.All(), sum.Moved, sum.Errors
+	nearPairs := ui.finishRun(roots, sum)
+	var found []history.Anomaly; if notify != nil && ctx.Err() == nil { found = ui.recordRun(runSource(roots), len(wl)+len(pending), sum.Errors) }
+	go func() {
+		defer ui.guard()
+		if warn := ui.runHook(started, target, sum, err); warn != "" {
+			ui.MainWindow.Synchronize(func() { if notify != nil { ui.toast(true, warn) } else { walk.MsgBox(ui.MainWindow, ui.T("warn_title"), warn, walk.MsgBoxIconWarning) } })
+		}
+	}()
+
+	ui.MainWindow.Synchronize(func() {
+		missing := missingReport(res)
+		ui.ResultsBtn.SetEnabled(journal.Len() > 0)
+		sm := fmt.Sprintf(ui.T("success_msg"), ui.count(successCount), ui.count(ec)) + ui.routeSummary(res) + ui.dateSummary(res) + ui.shiftSummary(res) + ui.missingSummary(missing) + ui.inPlaceSummary(sum) + ui.previouslySummary(sum) + ui.reconcileSummary(sum) + ui.unverifiedSummary(sum) + ui.orphanSummary(sum) + ui.derivativeSummary(res) + ui.anomalySummary(found)
+		if spaceStop { sm += "\n\n" + fmt.Sprintf(ui.T("space_stopped"), ui.count(len(sum.Unattempted)), ui.errText(err)); if notify == nil { sm += " " + ui.T("space_requeued") } }
+		if len(sum.TimedOut) > 0 && notify == nil { sm += "\n\n" + fmt.Sprintf(ui.T("timeout_requeued"), ui.count(len(sum.TimedOut))) }
+		sm += ui.inUseSummary(sum.InUse, notify == nil)
+		if notify != nil { r := ui.runReport(sum, sm, journal.Path()); go func() { defer ui.guard(); ui.deliverReport(r) }(); notify(sm, ec > 0 || len(found) > 0) } else if ec > 0 {
+			var report string; lim := 0; for r := range res { if r.Failed() { report += fmt.Sprintf("- %s: %s\n", r.File, ui.errorText(r.Error)); lim++; if lim > MaxErrorsDisplay { report += ui.T("see_log"); break } } }; walk.MsgBox(ui.MainWindow, ui.T("success_title"), sm+"\n\n"+fmt.Sprintf(ui.T("err_report"), report), walk.MsgBoxIconWarning)
+		} else if len(sum.Discrepancies) > 0 || len(sum.Unverified) > 0 { walk.MsgBox(ui.MainWindow, ui.T("success_title"), sm, walk.MsgBoxIconWarning) } else if successCount > 0 || len(missing) > 0 || sum.InPlace > 0 || sum.Previously > 0 || sum.Duplicates > 0 { walk.MsgBox(ui.MainWindow, ui.T("success_title"), sm, walk.MsgBoxIconInformation) }
+		if len(nearPairs) > 0 && notify == nil { ui.ShowNearDuplicates(nearPairs) } else if len(nearPairs) > 0 { logger.Info("Scheduled run found %d near-duplicate pairs", len(nearPairs)) }
+		ui.mutex.Lock(); if notify == nil { ui.clearQueue(); ui.requeue(slices.Concat(sum.Unattempted, sum.TimedOut, sum.InUse), roots) }; ui.cleanStaging(); ui.isProcessing = false; ui.updateQueueButtons(); ui.mutex.Unlock(); ui.showPlan(); ui.CancelBtn.SetVisible(false); ui.ProgressBar.SetVisible(false); ui.StatusLabel.SetText(ui.GetStatusText())
+	})
+}
+
+// runOptions are the run settings of the config for a run into target.
+func (ui *LumeUI) runOptions(target string) engine.Options {
+	return engine.Options{Target: target, Layout: ui.layout(), Routes: ui.Config.Routes, HardDelete: ui.Config.HardDelete, Conflicts: ui.Config.ConflictStyle, Catalog: ui.Config.Catalog, TargetIndex: ui.Config.TargetIndex, NoDirIndex: ui.Config.NoDirIndex, Checksums: ui.Config.Checksums, MonthIndex: ui.Config.MonthIndex,
+		NearDuplicates: ui.Config.NearDuplicates, PHashMaxSize: ui.Config.PHashMaxSize, Links: ui.Config.LinkPolicy, BatchSize: ui.Config.MaxFilesLimit, Cache: ui.scanCache, FileTimeout: time.Duration(ui.Config.FileTimeout) * time.Second, MoveWorkers: ui.Config.MoveWorkers, Limit: ui.limiter,
+		Reconcile: !ui.Config.NoReconcile, ReconcileMax: ui.Config.ReconcileMaxFiles, PreserveArchived: ui.Config.PreserveArchived, PreserveStructure: ui.Config.PreserveStructure, Protected: ui.Config.ProtectedPaths,
+		Clocks: ui.clocks(), SuspectAfter: ui.suspectAfter(), Shifts: ui.shifts, ShiftExif: ui.shiftExif, Imports: ui.imports, Recheck: ui.Config.RecheckImports, Derivatives: ui.derivatives(), Order: ui.Config.ProcessingOrder, InUse: retryInUse}
+}
+
+// finishRun does the bookkeeping after every run, with or without a window: emptied sources, the scan
+// cache, statistics and history. It returns the near-duplicate pairs to review.
+func (ui *LumeUI) finishRun(roots []string, sum engine.RunSummary) []metadata.NearPair {
+	res, successCount := sum.All(), sum.Moved
+	ui.pruneSources(roots, res)
+	ui.markCards(roots, res)
+	if err := ui.scanCache.Save(); err != nil { logger.Error("Scan cache save failed: %v", err) }
+	if err := ui.imports.Save(); err != nil { logger.Error("Import fingerprints save failed: %v", err) }
+	nearPairs := ui.findNearDuplicates(res, sum.Index)
+
+	// Enhanced Stats Logic (Audit 2.1 Points 1 & 2)
+	ui.mutex.Lock()
+	if successCount > 0 {
+		ui.Config.Stats.TotalFiles += successCount
+		ui.Config.Stats.TotalOrganized++
+		ui.Config.Stats.TotalSize += sum.Bytes
+		config.SaveConfig(ui.Config)
+	}
+	ui.mutex.Unlock()
+	ui.recordHistory(res)
+	return nearPairs
+}
+
+func (ui *LumeUI) CancelOrganizing() { ui.mutex.Lock(); defer ui.mutex.Unlock(); if ui.cancelFunc != nil { ui.cancelFunc() } }
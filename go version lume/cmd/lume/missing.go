@@ -0,0 +1,64 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"lume-go/internal/organizer"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pruneMissing drops queued files that were deleted or moved away since they were dropped, e.g. by
+// OneDrive, and returns them as missing results for the report. The caller holds ui.mutex.
+func (ui *LumeUI) pruneMissing() []OrganizeResult {
+	var gone []OrganizeResult
+	var paths []string
+	for _, f := range ui.FilesToMove {
+		if _, err := os.Lstat(f.Path); errors.Is(err, os.ErrNotExist) {
+			gone = append(gone, OrganizeResult{Missing: true, File: f.Filename, Path: f.Path, Size: f.Size, Error: organizer.ErrSourceMissing})
+			paths = append(paths, f.Path)
+		}
+	}
+	for _, p := range ui.PendingPaths {
+		if _, err := os.Lstat(p); errors.Is(err, os.ErrNotExist) {
+			gone = append(gone, OrganizeResult{Missing: true, File: filepath.Base(p), Path: p, Error: organizer.ErrSourceMissing})
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) > 0 {
+		ui.removePending(paths)
+	}
+	return gone
+}
+
+// missingReport picks the results whose source vanished before processing.
+func missingReport(res iter.Seq[OrganizeResult]) []OrganizeResult {
+	var out []OrganizeResult
+	for r := range res {
+		if r.Missing {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// missingSummary lists the vanished sources under the run summary; they are not counted as errors.
+func (ui *LumeUI) missingSummary(missing []OrganizeResult) string {
+	if len(missing) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n"+ui.T("missing_header"), ui.count(len(missing)))
+	for i, r := range missing {
+		if i == MaxErrorsDisplay {
+			b.WriteString("\n" + ui.T("see_log"))
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", r.Path)
+	}
+	return b.String()
+}
@@ -0,0 +1,229 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/queue"
+	"lume-go/internal/validator"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// pendingRow is one queued file in the pending list dialog.
+type pendingRow struct {
+	File, Size, Dims, Note, Folder, Path string
+}
+
+// pathReason tells why a dropped path was refused.
+func (ui *LumeUI) pathReason(err error) string {
+	var pe *validator.PathError
+	if !errors.As(err, &pe) {
+		return fmt.Sprint(err)
+	}
+	return fmt.Sprintf(ui.T("path_"+pe.Reason), pe.Component)
+}
+
+// dimensions renders an image size with its megapixels, e.g. "4032×3024 (12.2 MP)"; empty when unknown.
+func dimensions(w, h int) string {
+	if w <= 0 || h <= 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d×%d (%.1f MP)", w, h, metadata.FileInfo{Width: w, Height: h}.Megapixels())
+}
+
+// clearQueue empties the pending list. The caller holds ui.mutex.
+func (ui *LumeUI) clearQueue() {
+	ui.FilesToMove, ui.PendingPaths, ui.SourceRoots = nil, nil, nil
+	ui.FileCount, ui.SizeFiltered, ui.MPFiltered, ui.LinksSkipped, ui.HiddenSkipped, ui.PlaceholdersSkipped, ui.PathsRejected = 0, 0, 0, 0, 0, 0, 0
+	ui.queued = queue.New()
+	ui.shifts, ui.shiftExif = nil, false
+	ui.cardRoots = map[string]string{}
+}
+
+// requeue puts the files a run stopped before, or that timed out, back in the emptied queue, so the
+// run can be started again once there is room or the share answers. The caller holds ui.mutex.
+func (ui *LumeUI) requeue(paths, roots []string) {
+	if len(paths) == 0 {
+		return
+	}
+	for _, p := range paths {
+		ui.queued.Add(p)
+	}
+	ui.PendingPaths, ui.SourceRoots, ui.FileCount = paths, roots, len(paths)
+}
+
+// updateQueueButtons enables Start, List and Clear for the queue and whether a run is active.
+func (ui *LumeUI) updateQueueButtons() {
+	ui.updateStart()
+	has := ui.FileCount > 0 && !ui.isProcessing
+	ui.ListBtn.SetEnabled(has)
+	ui.ClearBtn.SetEnabled(has || ui.SizeFiltered+ui.MPFiltered+ui.LinksSkipped+ui.HiddenSkipped+ui.PlaceholdersSkipped+ui.PathsRejected > 0)
+}
+
+// updateStart enables Start once a target is chosen and files are queued, and no task is running.
+func (ui *LumeUI) updateStart() {
+	ui.StartBtn.SetEnabled(queue.CanStart(ui.TargetFolder != "", ui.FileCount, ui.isProcessing) == queue.Ready)
+}
+
+// startHint tells what is missing before Start can be used; it is empty when nothing is, or while a
+// task runs.
+func (ui *LumeUI) startHint() string {
+	switch queue.CanStart(ui.TargetFolder != "", ui.FileCount, ui.isProcessing) {
+	case queue.NoTargetOrFiles:
+		return ui.T("hint_no_target_files")
+	case queue.NoTarget:
+		return ui.T("hint_no_target")
+	case queue.NoFiles:
+		return ui.T("hint_no_files")
+	}
+	return ""
+}
+
+// ClearPending drops every queued file, e.g. after an accidental drop.
+func (ui *LumeUI) ClearPending() {
+	defer ui.showPlan()
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	if ui.isProcessing {
+		return
+	}
+	ui.clearQueue()
+	ui.cleanStaging()
+	ui.updateQueueButtons()
+	ui.StatusLabel.SetText(ui.GetStatusText())
+}
+
+// removePending takes the given paths out of the queue. The caller holds ui.mutex.
+func (ui *LumeUI) removePending(paths []string) {
+	drop := queue.New()
+	for _, p := range paths {
+		drop.Add(p)
+		ui.queued.Remove(p)
+	}
+	files := ui.FilesToMove[:0]
+	for _, f := range ui.FilesToMove {
+		if !drop.Has(f.Path) {
+			files = append(files, f)
+		}
+	}
+	pending := ui.PendingPaths[:0]
+	for _, p := range ui.PendingPaths {
+		if !drop.Has(p) {
+			pending = append(pending, p)
+		}
+	}
+	ui.FilesToMove, ui.PendingPaths = files, pending
+	ui.FileCount = len(files) + len(pending)
+}
+
+// ShowPending lists the queued files so individual ones can be taken out before the run.
+func (ui *LumeUI) ShowPending() {
+	ui.mutex.Lock()
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	rows := make([]*pendingRow, 0, ui.FileCount)
+	for _, f := range ui.FilesToMove {
+		rows = append(rows, &pendingRow{File: f.Filename, Size: ui.bytes(f.Size), Dims: dimensions(f.Width, f.Height), Note: ui.metadataNote(f.MetadataNote, f.DateSource), Folder: filepath.Dir(f.Path), Path: f.Path})
+	}
+	// Paths beyond the batch limit have no metadata loaded yet, so their size is left blank.
+	for _, p := range ui.PendingPaths {
+		rows = append(rows, &pendingRow{File: filepath.Base(p), Folder: filepath.Dir(p), Path: p})
+	}
+	ui.mutex.Unlock()
+
+	var dlg *walk.Dialog
+	var tv *walk.TableView
+	model := &pendingModel{rows: rows}
+	remove := func() {
+		idx := tv.SelectedIndexes()
+		if len(idx) == 0 {
+			return
+		}
+		sort.Ints(idx)
+		var paths []string
+		for _, i := range idx {
+			paths = append(paths, rows[i].Path)
+		}
+		ui.mutex.Lock()
+		ui.removePending(paths)
+		ui.updateQueueButtons()
+		ui.StatusLabel.SetText(ui.GetStatusText())
+		ui.mutex.Unlock()
+		ui.showPlan()
+		rows = model.remove(idx)
+	}
+	// shift drafts a date shift for the selected files that have their metadata loaded.
+	shift := func() {
+		sel := queue.New()
+		for _, i := range tv.SelectedIndexes() {
+			sel.Add(rows[i].Path)
+		}
+		var infos []metadata.FileInfo
+		ui.mutex.Lock()
+		for _, f := range ui.FilesToMove {
+			if sel.Has(f.Path) {
+				infos = append(infos, f)
+			}
+		}
+		ui.mutex.Unlock()
+		ui.ShowShifts(infos)
+	}
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("pending_title"), MinSize: Size{Width: 720, Height: 420}, Layout: VBox{},
+		Children: []Widget{
+			TableView{AssignTo: &tv, Model: model, MultiSelection: true,
+				Columns: []TableViewColumn{
+					{DataMember: "File", Title: ui.T("col_file"), Width: 200}, {DataMember: "Size", Title: ui.T("col_size"), Width: 80},
+					{DataMember: "Dims", Title: ui.T("col_dims"), Width: 130}, {DataMember: "Note", Title: ui.T("col_note"), Width: 220},
+					{DataMember: "Folder", Title: ui.T("col_folder"), Width: 300},
+				},
+				ContextMenuItems: []MenuItem{Action{Text: ui.T("remove_btn"), OnTriggered: remove}, Action{Text: ui.T("shift_btn"), OnTriggered: shift}},
+				OnKeyDown: func(key walk.Key) {
+					if key == walk.KeyDelete {
+						remove()
+					}
+				},
+			},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				PushButton{Text: ui.T("remove_btn"), ToolTipText: fmt.Sprintf("%s (%s)", ui.T("tip_remove"), walk.Shortcut{Key: walk.KeyDelete}), OnClicked: remove},
+				PushButton{Text: ui.T("shift_btn"), OnClicked: shift}, HSpacer{},
+				PushButton{Text: ui.T("ok_btn"), OnClicked: func() { dlg.Accept() }},
+			}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Pending list dialog failed: %v", err)
+	}
+}
+
+// pendingModel backs the pending list table so removed rows disappear without rebuilding the dialog.
+type pendingModel struct {
+	walk.ReflectTableModelBase
+	rows []*pendingRow
+}
+
+func (m *pendingModel) Items() interface{} { return m.rows }
+
+// remove deletes the rows at idx (ascending) and returns what is left.
+func (m *pendingModel) remove(idx []int) []*pendingRow {
+	kept := m.rows[:0]
+	for i, j := 0, 0; i < len(m.rows); i++ {
+		if j < len(idx) && idx[j] == i {
+			j++
+			continue
+		}
+		kept = append(kept, m.rows[i])
+	}
+	m.rows = kept
+	m.PublishRowsReset()
+	return kept
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package main
+
+import (
+	"lume-go/internal/engine"
+	"lume-go/internal/metadata"
+	"lume-go/internal/plan"
+)
+
+// makePlan works out the plan of files, looking duplicates up in the target index when the config keeps one.
+func (ui *LumeUI) makePlan(files []plan.File, target string) plan.Plan {
+	return engine.MakePlan(files, target, ui.Config.TargetIndex, ui.history, ui.protection())
+}
+
+// showPlan works out the plan of the queue in the background and shows it above Start; it is hidden
+// while the queue is empty, no target is chosen or a task runs. Call it without holding ui.mutex.
+func (ui *LumeUI) showPlan() {
+	ui.mutex.Lock()
+	ui.planSeq++
+	seq, target, router, layout, shifts, roots := ui.planSeq, ui.TargetFolder, ui.router(), ui.layout(), ui.shifts, ui.SourceRoots
+	ready := !ui.isProcessing && target != "" && ui.FileCount > 0
+	infos, pending := append([]metadata.FileInfo(nil), ui.FilesToMove...), append([]string(nil), ui.PendingPaths...)
+	ui.mutex.Unlock()
+	if !ready {
+		ui.PlanLabel.SetVisible(false)
+		return
+	}
+	// The preview shows the shifted dates; the run writes them, if at all.
+	for i := range infos {
+		metadata.ShiftDate(&infos[i], shifts, false)
+	}
+	metadata.MarkDateSuspects(infos, ui.suspectAfter())
+	go func() {
+		defer ui.guard()
+		p := ui.makePlan(engine.PlanFiles(router, layout, infos, pending, roots, ui.Config.PreserveStructure), target)
+		ui.MainWindow.Synchronize(func() {
+			ui.mutex.Lock()
+			current := seq == ui.planSeq
+			ui.mutex.Unlock()
+			if current {
+				ui.PlanLabel.SetText(p.Text(ui.Config.Language))
+				ui.PlanLabel.SetVisible(true)
+			}
+		})
+	}()
+}
@@ -0,0 +1,107 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/imports"
+	"lume-go/internal/logger"
+	"strings"
+
+	"github.com/lxn/walk"
+)
+
+// categoryPreviously files the results skipped as imported before for the results filter.
+const categoryPreviously = "previously"
+
+// loadImports opens the fingerprints of the files earlier runs left on their cards. A corrupt file is
+// logged and started over.
+func loadImports() *imports.Store {
+	path, err := config.DataPath(imports.FileName)
+	if err != nil {
+		logger.Error("Import fingerprints unavailable: %v", err)
+		path = imports.FileName
+	}
+	s, err := imports.Load(path)
+	if err != nil {
+		logger.Error("Import fingerprints unreadable, starting over: %v", err)
+	}
+	return s
+}
+
+// previouslySummary notes under the run summary the files skipped as archived by an earlier import;
+// they are neither moved nor errors.
+func (ui *LumeUI) previouslySummary(sum engine.RunSummary) string {
+	if sum.Previously == 0 {
+		return ""
+	}
+	return "\n\n" + fmt.Sprintf(ui.T("previously_summary"), ui.count(sum.Previously))
+}
+
+// verifyPrevious reads the files of results skipped as imported before against their archived copies
+// in the background and reports those that differ, which the next run organizes again.
+func (ui *LumeUI) verifyPrevious(results []OrganizeResult) {
+	ui.mutex.Lock()
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	ui.isProcessing = true
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(ui.T("previously_verifying"))
+	go func() {
+		defer ui.guard()
+		bad, err := engine.VerifyPrevious(ctx, ui.imports, results, ui.busyProgress)
+		if err := ui.imports.Save(); err != nil {
+			logger.Error("Import fingerprints save failed: %v", err)
+		}
+		ui.MainWindow.Synchronize(func() {
+			if err != nil {
+				ui.finishBusy(ui.T("cancelled"))
+				return
+			}
+			ui.finishBusy("")
+			ui.reportVerified(results, bad)
+		})
+	}()
+}
+
+// reportVerified tells how the files skipped as imported before compared with their archived copies,
+// naming a few of those that did not match.
+func (ui *LumeUI) reportVerified(results []OrganizeResult, bad []engine.Mismatch) {
+	total := 0
+	for _, r := range results {
+		if r.Previously {
+			total++
+		}
+	}
+	if len(bad) == 0 {
+		walk.MsgBox(ui.MainWindow, ui.T("success_title"), fmt.Sprintf(ui.T("previously_verified"), ui.count(total)), walk.MsgBoxIconInformation)
+		return
+	}
+	var lines []string
+	for i, m := range bad {
+		logger.Info("Imported before but not matching its archived copy %s: %s (%v)", m.Dest, m.Path, m.Err)
+		if i < auditExamples {
+			line := m.Path + " → " + m.Dest
+			if m.Err != nil {
+				line += " (" + ui.errText(m.Err) + ")"
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(bad) > auditExamples {
+		lines = append(lines, "…")
+	}
+	msg := fmt.Sprintf(ui.T("previously_mismatch"), ui.count(len(bad)), ui.count(total), strings.Join(lines, "\n"))
+	walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxIconWarning)
+}
@@ -0,0 +1,44 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"time"
+)
+
+// progressInterval spaces the window's progress updates; faster than this only floods the message loop.
+const progressInterval = time.Second / 10
+
+// runProgress starts the progress of a run in the window: the journal of the previous run is closed
+// for the run's own, whose results are shown in an open results list as batches of files are done.
+func (ui *LumeUI) runProgress(journal *engine.Journal) *engine.Batcher {
+	ui.MainWindow.Synchronize(func() {
+		if err := ui.lastRun.Close(); err != nil {
+			logger.Error("Journal close failed: %v", err)
+		}
+		ui.lastRun, ui.recovered = journal, nil
+		ui.ResultsBtn.SetEnabled(false)
+	})
+	return engine.NewBatcher(progressInterval, func(u engine.Update) {
+		ui.MainWindow.Synchronize(func() { ui.showProgress(u) })
+	})
+}
+
+func (ui *LumeUI) showProgress(u engine.Update) {
+	if u.Phase != "" {
+		ui.StatusLabel.SetText(ui.T(u.Phase))
+	} else if u.Total > 0 {
+		ui.ProgressBar.SetValue(u.Done * 100 / u.Total)
+		ui.StatusLabel.SetText(fmt.Sprintf(ui.T("proc_count"), ui.count(u.Done), ui.count(u.Total)))
+	}
+	if len(u.Results) == 0 {
+		return
+	}
+	ui.ResultsBtn.SetEnabled(true)
+	if ui.resultsView != nil {
+		ui.resultsView.add(u.Results)
+	}
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"lume-go/internal/plan"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+
+	"github.com/lxn/walk"
+)
+
+// protection finds the protected folders: the configured ones and those holding a marker.
+func (ui *LumeUI) protection() *validator.Protection {
+	return validator.NewProtection(ui.Config.ProtectedPaths)
+}
+
+// confirmProtected tells how many queued files would go into protected folders, which the run skips,
+// and lets the user back out before anything moves.
+func (ui *LumeUI) confirmProtected() bool {
+	ui.mutex.Lock()
+	files := engine.PlanFiles(ui.router(), ui.layout(), ui.FilesToMove, ui.PendingPaths, ui.SourceRoots, ui.Config.PreserveStructure)
+	ui.mutex.Unlock()
+	p := plan.Make(files, plan.Options{Protect: ui.protection()})
+	if p.Protected == 0 {
+		return true
+	}
+	logger.Info("%d queued files would go into protected %s", p.Protected, p.ProtectedRoot)
+	msg := fmt.Sprintf(ui.T("protected_confirm"), ui.count(p.Protected), p.ProtectedRoot)
+	return walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes
+}
+
+// ProtectFolder puts a protection marker into a folder the user picks, e.g. a finished year of the
+// archive. Deleting the marker lifts the protection again.
+func (ui *LumeUI) ProtectFolder() {
+	dlg := &walk.FileDialog{Title: ui.T("protect_btn"), InitialDirPath: ui.TargetFolder}
+	if ok, _ := dlg.ShowBrowseFolder(ui.MainWindow); !ok {
+		return
+	}
+	marker := filepath.Join(dlg.FilePath, validator.ProtectMarker)
+	f, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = f.WriteString("Lume does not write into this folder while this file is here.\r\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		logger.Error("Protect marker failed: %v", err)
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+		return
+	}
+	logger.Info("Protected %s", dlg.FilePath)
+	ui.showPlan()
+	walk.MsgBox(ui.MainWindow, ui.T("settings_title"), fmt.Sprintf(ui.T("protect_done"), dlg.FilePath, validator.ProtectMarker), walk.MsgBoxIconInformation)
+}
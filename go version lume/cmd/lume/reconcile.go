@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/engine"
+	"strings"
+)
+
+// categoryReconcile files archived results the reconciliation found changed for the results filter.
+const categoryReconcile = "reconcile"
+
+// reconcileSummary reports the reconciliation under the run summary: the archived files that are not
+// as the run left them, or that all of them are.
+func (ui *LumeUI) reconcileSummary(sum engine.RunSummary) string {
+	if sum.Reconciled == 0 {
+		return ""
+	}
+	if len(sum.Discrepancies) == 0 {
+		return "\n\n" + fmt.Sprintf(ui.T("reconcile_ok"), ui.count(sum.Reconciled))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n"+ui.T("reconcile_header"), ui.count(sum.Reconciled), ui.count(len(sum.Discrepancies)))
+	for i, p := range sum.Discrepancies {
+		if i == MaxErrorsDisplay {
+			b.WriteString("\n" + ui.T("see_log"))
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s: %s", p.Dest, ui.T("reconcile_"+p.Kind))
+	}
+	return b.String()
+}
+
+// unverifiedSummary lists the files archived without being read back to verify them.
+func (ui *LumeUI) unverifiedSummary(sum engine.RunSummary) string {
+	if len(sum.Unverified) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n"+ui.T("unverified_summary"), ui.count(len(sum.Unverified)))
+	for i, p := range sum.Unverified {
+		if i == MaxErrorsDisplay {
+			b.WriteString("\n" + ui.T("see_log"))
+			break
+		}
+		b.WriteString("\n- " + p)
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/runstate"
+	"slices"
+	"time"
+)
+
+// recoveredRun is a window run a crash or a reboot cut short, found on start: its record, and the
+// queued files it left without a result.
+type recoveredRun struct {
+	state runstate.State
+	left  []string
+}
+
+// openRuns is the store of the window's run records under the config directory; without one, runs go
+// unrecorded.
+func openRuns() *runstate.Store {
+	dir, err := config.DataPath("runs")
+	if err != nil {
+		logger.Error("Run records unavailable: %v", err)
+		return nil
+	}
+	return runstate.New(dir)
+}
+
+// beginRun records a run of wl and the pending paths into target, streaming its results to journal,
+// and returns what removes the record once the run ends.
+func (ui *LumeUI) beginRun(journal *engine.Journal, wl []metadata.FileInfo, pending []string, target string, roots []string) func() {
+	if ui.runs == nil {
+		return func() {}
+	}
+	started := time.Now()
+	st := runstate.State{ID: runstate.NewID(started), Started: started, Target: target, Roots: roots, Journal: journal.Path()}
+	st.Queued = make([]string, 0, len(wl)+len(pending))
+	for _, f := range wl {
+		st.Queued = append(st.Queued, f.Path)
+	}
+	st.Queued = append(st.Queued, pending...)
+	if err := ui.runs.Begin(st); err != nil {
+		logger.Error("Run record not written: %v", err)
+		return func() {}
+	}
+	return func() {
+		if err := ui.runs.End(st.ID); err != nil {
+			logger.Error("Run record not removed: %v", err)
+		}
+	}
+}
+
+// keepInterrupted is how long the records of interrupted runs are kept.
+func (ui *LumeUI) keepInterrupted() time.Duration {
+	days := ui.Config.InterruptedKeepDays
+	if days <= 0 {
+		days = runstate.DefaultKeepDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// recoverRun runs on start: it prunes the old records of interrupted runs and, when the last window
+// run was cut short, shows what it did in the results window, marked interrupted, to be resumed or
+// dismissed there. Only the latest is offered; older ones are left to pruning.
+func (ui *LumeUI) recoverRun() {
+	if ui.runs == nil {
+		return
+	}
+	if n, err := ui.runs.Prune(ui.keepInterrupted(), time.Now()); err != nil {
+		logger.Error("Run records not pruned: %v", err)
+	} else if n > 0 {
+		logger.Info("Pruned %d records of interrupted runs", n)
+	}
+	found, err := ui.runs.Interrupted()
+	if err != nil {
+		logger.Error("Run records unreadable: %v", err)
+		return
+	}
+	if len(found) == 0 {
+		return
+	}
+	for _, st := range found[1:] {
+		if err := ui.runs.Settle(st, runstate.Dismissed); err != nil {
+			logger.Error("Run record not updated: %v", err)
+		}
+	}
+	st := found[0]
+	var journal *engine.Journal
+	if st.Journal != "" {
+		if journal, err = engine.OpenJournal(st.Journal, engine.JournalRecent); err != nil {
+			logger.Error("Results of the interrupted run unreadable: %v", err)
+		}
+	}
+	if journal == nil {
+		journal, _ = engine.CreateJournal("", engine.JournalRecent)
+	}
+	left := st.Remaining(func(yield func(string) bool) {
+		for r := range journal.All() {
+			if !yield(r.Path) {
+				return
+			}
+		}
+	})
+	logger.Info("Run %s into %s was interrupted after %d of %d files", st.ID, st.Target, len(st.Queued)-len(left), len(st.Queued))
+	ui.lastRun, ui.recovered = journal, &recoveredRun{state: st, left: left}
+	ui.ResultsBtn.SetEnabled(true)
+	ui.ShowResults()
+}
+
+// recoveredText says where the recovered run stopped, for the results window.
+func (ui *LumeUI) recoveredText() string {
+	rec := ui.recovered
+	total := len(rec.state.Queued)
+	return fmt.Sprintf(ui.T("run_interrupted"), rec.state.Started.Local().Format("2006-01-02 15:04"), rec.state.Target, ui.count(total-len(rec.left)), ui.count(total))
+}
+
+// resumeRun queues the files the recovered run did not reach, beside any already queued, into its
+// target. Start runs them.
+func (ui *LumeUI) resumeRun() {
+	rec := ui.recovered
+	ui.mutex.Lock()
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	for _, p := range rec.left {
+		if ui.queued.Add(p) {
+			ui.PendingPaths = append(ui.PendingPaths, p)
+			ui.FileCount++
+		}
+	}
+	for _, r := range rec.state.Roots {
+		if !slices.Contains(ui.SourceRoots, r) {
+			ui.SourceRoots = append(ui.SourceRoots, r)
+		}
+	}
+	ui.updateQueueButtons()
+	ui.mutex.Unlock()
+	logger.Info("Resumed run %s: %d files queued again", rec.state.ID, len(rec.left))
+	ui.settleRun(runstate.Resumed)
+	ui.setTarget(rec.state.Target)
+}
+
+// settleRun records the choice made for the recovered run, which is then shown as any last run.
+func (ui *LumeUI) settleRun(status string) {
+	if err := ui.runs.Settle(ui.recovered.state, status); err != nil {
+		logger.Error("Run record not updated: %v", err)
+	}
+	ui.recovered = nil
+}
@@ -0,0 +1,157 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/logger"
+	"lume-go/internal/organizer"
+	"path/filepath"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// reorgRow is one line of the reorganization preview.
+type reorgRow struct {
+	From, To string
+}
+
+// Reorganize re-lays out the target folder with the current folder template. The plan is always
+// previewed first; nothing moves until the user applies it, and the applied moves can be undone.
+func (ui *LumeUI) Reorganize() {
+	ui.mutex.Lock()
+	if ui.TargetFolder == "" {
+		ui.mutex.Unlock()
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("warn_select"), walk.MsgBoxIconWarning)
+		return
+	}
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	ui.isProcessing = true
+	target := ui.TargetFolder
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.StatusLabel.SetText(ui.T("reorg_scanning"))
+	go func() {
+		defer ui.guard()
+		plan, err := organizer.PlanReorganize(ctx, target, ui.layout(), ui.protection())
+		ui.MainWindow.Synchronize(func() {
+			switch {
+			case ctx.Err() != nil:
+				ui.finishBusy(ui.T("cancelled"))
+			case err != nil:
+				walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), ui.errText(err)), walk.MsgBoxIconError)
+				ui.finishBusy("")
+			case len(plan.Moves) == 0:
+				walk.MsgBox(ui.MainWindow, ui.T("reorg_title"), fmt.Sprintf(ui.T("reorg_nothing"), plan.Unchanged, len(plan.Skipped)), walk.MsgBoxIconInformation)
+				ui.finishBusy("")
+			case !ui.ShowReorgPreview(plan):
+				ui.finishBusy("")
+			default:
+				ui.applyReorg(ctx, plan)
+			}
+		})
+	}()
+}
+
+func (ui *LumeUI) applyReorg(ctx context.Context, plan organizer.ReorgPlan) {
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	go func() {
+		defer ui.guard()
+		res, err := organizer.ApplyReorganize(ctx, plan, func(done, total int) {
+			ui.MainWindow.Synchronize(func() {
+				ui.ProgressBar.SetValue(done * 100 / total)
+				ui.StatusLabel.SetText(fmt.Sprintf(ui.T("proc_count"), ui.count(done), ui.count(total)))
+			})
+		})
+		if err != nil {
+			logger.Error("Reorganize stopped: %v", err)
+		}
+		ui.MainWindow.Synchronize(func() {
+			msg := fmt.Sprintf(ui.T("reorg_done"), res.Moved, len(res.Failed), res.Journal)
+			if res.Moved > 0 && walk.MsgBox(ui.MainWindow, ui.T("reorg_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes {
+				n, err := organizer.UndoReorganize(res.Journal)
+				if err != nil {
+					logger.Error("Reorganize undo: %v", err)
+				}
+				walk.MsgBox(ui.MainWindow, ui.T("reorg_title"), fmt.Sprintf(ui.T("reorg_undone"), n), walk.MsgBoxIconInformation)
+			}
+			ui.finishBusy("")
+		})
+	}()
+}
+
+func (ui *LumeUI) setBusy(busy bool) {
+	if busy {
+		ui.StartBtn.SetEnabled(false)
+	} else {
+		ui.updateStart()
+	}
+	ui.SelectBtn.SetEnabled(!busy)
+	ui.ReorgBtn.SetEnabled(!busy)
+	ui.CancelBtn.SetVisible(busy)
+}
+
+// finishBusy releases the context of a background task and re-enables the window.
+func (ui *LumeUI) finishBusy(status string) {
+	ui.mutex.Lock()
+	if ui.cancelFunc != nil {
+		ui.cancelFunc()
+	}
+	ui.isProcessing = false
+	ui.mutex.Unlock()
+	ui.setBusy(false)
+	ui.ProgressBar.SetVisible(false)
+	if status == "" {
+		status = ui.GetStatusText()
+	}
+	ui.StatusLabel.SetText(status)
+	ui.showPlan()
+}
+
+// ShowReorgPreview lists every planned move and every file left in place; it reports whether the user applied the plan.
+func (ui *LumeUI) ShowReorgPreview(plan organizer.ReorgPlan) bool {
+	rel := func(p string) string {
+		if r, err := filepath.Rel(plan.Root, p); err == nil {
+			return r
+		}
+		return p
+	}
+	moves := make([]*reorgRow, 0, len(plan.Moves))
+	for _, m := range plan.Moves {
+		moves = append(moves, &reorgRow{From: rel(m.From), To: rel(m.To)})
+	}
+	skipped := make([]*reorgRow, 0, len(plan.Skipped))
+	for _, s := range plan.Skipped {
+		skipped = append(skipped, &reorgRow{From: rel(s.Path), To: s.Reason})
+	}
+
+	var dlg *walk.Dialog
+	children := []Widget{
+		Label{Text: fmt.Sprintf(ui.T("reorg_preview"), len(plan.Moves), plan.Unchanged, len(plan.Skipped))},
+		TableView{Model: moves, Columns: []TableViewColumn{{DataMember: "From", Title: ui.T("col_from"), Width: 330}, {DataMember: "To", Title: ui.T("col_to"), Width: 330}}},
+	}
+	if len(skipped) > 0 {
+		children = append(children, TableView{Model: skipped, MaxSize: Size{Height: 120}, Columns: []TableViewColumn{{DataMember: "From", Title: ui.T("col_from"), Width: 330}, {DataMember: "To", Title: ui.T("col_reason"), Width: 330}}})
+	}
+	children = append(children, Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+		HSpacer{},
+		PushButton{Text: ui.T("reorg_apply"), OnClicked: func() { dlg.Accept() }},
+		PushButton{Text: ui.T("cancel_btn"), OnClicked: func() { dlg.Cancel() }},
+	}})
+
+	cmd, err := (Dialog{AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("reorg_title"), MinSize: Size{Width: 720, Height: 440}, Layout: VBox{}, Children: children}).Run(ui.MainWindow)
+	if err != nil {
+		logger.Error("Reorganize preview failed: %v", err)
+		return false
+	}
+	return cmd == walk.DlgCmdOK
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"lume-go/internal/report"
+	"strings"
+	"time"
+)
+
+// runReport is the report of an unattended run: its summary, every failed file and where the journal
+// holding all of its results is.
+func (ui *LumeUI) runReport(sum engine.RunSummary, summary, journal string) report.Report {
+	var b strings.Builder
+	b.WriteString(summary)
+	var failed strings.Builder
+	for r := range sum.All() {
+		if r.Failed() {
+			fmt.Fprintf(&failed, "- %s: %s\n", r.File, ui.errorText(r.Error))
+		}
+	}
+	if failed.Len() > 0 {
+		b.WriteString("\n\n" + fmt.Sprintf(ui.T("err_report"), failed.String()))
+	}
+	if journal != "" {
+		b.WriteString("\n\n" + fmt.Sprintf(ui.T("report_results"), journal))
+	}
+	return report.Report{Time: time.Now(), Subject: fmt.Sprintf(ui.T("report_subject"), ui.count(sum.Moved), ui.count(sum.Errors)), Body: b.String()}
+}
+
+// deliverReport sends r where the config asks. A delivery that fails, even after its retry, is only
+// logged; it never changes the outcome of the run.
+func (ui *LumeUI) deliverReport(r report.Report) {
+	c := ui.Config.Report
+	if !c.Enabled() {
+		return
+	}
+	if err := report.Deliver(context.Background(), c, r); err != nil {
+		logger.Error("Run summary not delivered: %v", err)
+		return
+	}
+	logger.Info("Run summary delivered: %s", r.Subject)
+}
@@ -0,0 +1,292 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/i18n"
+	"lume-go/internal/logger"
+	"lume-go/internal/organizer"
+	"lume-go/internal/runstate"
+	"lume-go/internal/shell"
+	"lume-go/internal/validator"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// resultRow is one line of the results table; Dest is the archive path, or the source for failures.
+type resultRow struct {
+	File, Size, Dims, Result, Dest string
+	category                 string
+}
+
+// categoryOK files successful results for the results filter.
+const categoryOK = "ok"
+
+// errorText renders a failure as its localized category with the error for detail.
+func (ui *LumeUI) errorText(err error) string {
+	return fmt.Sprintf("%s (%s)", ui.T("cat_"+organizer.Category(err)), ui.errText(err))
+}
+
+// errText is err for the user: space and writability failures in the app language with readable
+// sizes, anything else as it is.
+func (ui *LumeUI) errText(err error) string {
+	var space *validator.SpaceError
+	var write *validator.WriteError
+	var nest *validator.NestError
+	var protected *validator.ProtectedError
+	var tooLarge *validator.SizeLimitError
+	switch {
+	case errors.As(err, &nest):
+		return fmt.Sprintf(ui.T("err_target_in_source"), nest.Target, nest.Source)
+	case errors.As(err, &protected):
+		return fmt.Sprintf(ui.T("err_protected"), protected.Path, protected.Root)
+	case errors.As(err, &tooLarge):
+		return fmt.Sprintf(ui.T("err_too_large"), ui.bytes(tooLarge.Size), tooLarge.FileSystem, tooLarge.Path, ui.bytes(tooLarge.Limit))
+	case errors.As(err, &space):
+		return i18n.FormatSpace(ui.Config.Language, space.Path, space.Need, space.Have)
+	case errors.As(err, &write):
+		switch write.Cause {
+		case validator.WriteMissing:
+			return fmt.Sprintf(ui.T("write_missing"), write.Path)
+		case validator.WriteNotDir:
+			return fmt.Sprintf(ui.T("write_notdir"), write.Path)
+		case validator.WriteReadOnly:
+			return fmt.Sprintf(ui.T("write_readonly"), write.Path)
+		case validator.WriteFull:
+			return fmt.Sprintf(ui.T("write_full"), write.Path)
+		}
+		return fmt.Sprintf(ui.T("write_denied"), write.Path, write.Err)
+	}
+	return err.Error()
+}
+
+// nameNote names the original of a file archived under a name sanitized for Windows.
+func (ui *LumeUI) nameNote(r OrganizeResult) string {
+	if organizer.SanitizeFileName(r.File) == r.File {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("name_sanitized"), r.File)
+}
+
+// queuedNote names the queued file a file was a copy of, archived in its stead.
+func (ui *LumeUI) queuedNote(r OrganizeResult) string {
+	if r.DuplicateOf == "" {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("dup_queued"), r.DuplicateOf)
+}
+
+func (ui *LumeUI) resultRow(r OrganizeResult) *resultRow {
+	row := &resultRow{File: r.File, Size: ui.bytes(r.Size), Dims: dimensions(r.Width, r.Height), Result: r.Dest + ui.dateNote(r) + ui.clockNote(r) + ui.shiftNote(r) + ui.exifNote(r) + ui.nameNote(r) + ui.queuedNote(r) + ui.derivativeNote(r) + ui.retriedNote(r), Dest: r.Dest, category: categoryOK}
+	if r.InPlace {
+		row.Result, row.Dest, row.category = ui.T("in_place_row"), r.Path, categoryInPlace
+	} else if r.Previously {
+		row.Result, row.category = fmt.Sprintf(ui.T("previously_row"), r.Dest), categoryPreviously
+	} else if !r.Success {
+		row.Result, row.Dest, row.category = ui.errorText(r.Error)+ui.retriedNote(r), r.Path, organizer.Category(r.Error)
+	} else if r.Unverified {
+		row.Result, row.category = ui.errorText(r.Error)+": "+row.Result, organizer.CategoryUnverified
+	} else if r.Discrepancy != "" {
+		row.Result, row.category = ui.T("reconcile_"+r.Discrepancy)+": "+row.Result, categoryReconcile
+	} else if r.DateSuspect {
+		row.Result, row.category = fmt.Sprintf(ui.T("date_suspect_row"), i18n.FormatDuration(ui.Config.Language, r.Skew.Abs()))+": "+row.Result, categorySuspect
+	}
+	return row
+}
+
+// ShowResults lists the files of the last run, filterable by outcome. Opened during a run, the list
+// grows as files are done. "Reveal in Explorer" opens the archive folder of the selected file with the
+// file highlighted, or the source folder for files that were not moved. For a run found interrupted
+// on start, it says where the run stopped and offers to resume or dismiss it.
+func (ui *LumeUI) ShowResults() {
+	if ui.lastRun == nil {
+		return
+	}
+	model := &resultModel{run: ui.lastRun, build: ui.resultRow, cache: make(map[int]*resultRow)}
+	present := map[string]bool{}
+	for r := range ui.lastRun.All() {
+		c := ui.resultRow(r).category
+		present[c] = true
+		model.cats = append(model.cats, c)
+	}
+	if err := ui.lastRun.Err(); err != nil {
+		logger.Error("Results of the last run unreadable: %v", err)
+	}
+	model.filter("")
+	ui.mutex.Lock()
+	running := ui.isProcessing
+	ui.mutex.Unlock()
+	// The filter offers only the outcomes this run actually had, or all of them while it is still going.
+	filters, labels := []string{""}, []string{ui.T("cat_all")}
+	for _, c := range append([]string{categoryOK, categoryInPlace, categoryPreviously, categorySuspect, categoryReconcile}, organizer.Categories...) {
+		if present[c] || running {
+			filters, labels = append(filters, c), append(labels, ui.T("cat_"+c))
+		}
+	}
+
+	var dlg *walk.Dialog
+	var tv *walk.TableView
+	var filter *walk.ComboBox
+	applyFilter := func() {
+		if i := filter.CurrentIndex(); i > 0 && i < len(filters) {
+			model.filter(filters[i])
+		} else {
+			model.filter("")
+		}
+	}
+	reveal := func() {
+		i := tv.CurrentIndex()
+		if i < 0 || i >= len(model.rows) {
+			return
+		}
+		if err := shell.Reveal(model.row(i).Dest); err != nil {
+			walk.MsgBox(dlg, ui.T("warn_title"), fmt.Sprintf(ui.T("open_failed"), err), walk.MsgBoxIconWarning)
+		}
+	}
+	// Files skipped as imported before are read against their archived copies on demand, the selected
+	// one or all of the run's; the dialog closes while the main window shows the check's progress.
+	verifyRow := func() {
+		i := tv.CurrentIndex()
+		if i < 0 || i >= len(model.rows) {
+			return
+		}
+		r, err := model.run.At(model.rows[i])
+		if err != nil || !r.Previously {
+			return
+		}
+		dlg.Accept()
+		ui.verifyPrevious([]OrganizeResult{r})
+	}
+	verifyRun := func() {
+		var prev []OrganizeResult
+		for r := range ui.lastRun.All() {
+			if r.Previously {
+				prev = append(prev, r)
+			}
+		}
+		dlg.Accept()
+		ui.verifyPrevious(prev)
+	}
+	title, interrupted, resumable := ui.T("results_title"), "", false
+	if ui.recovered != nil {
+		title, interrupted, resumable = ui.T("results_interrupted_title"), ui.recoveredText(), len(ui.recovered.left) > 0
+	}
+	settle := func(resume bool) {
+		if resume {
+			ui.resumeRun()
+		} else {
+			ui.settleRun(runstate.Dismissed)
+		}
+		dlg.Accept()
+	}
+	ui.resultsView = model
+	defer func() { ui.resultsView = nil }()
+	minSize := Size{Width: 720, Height: 380}
+	if err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: title, MinSize: minSize, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: interrupted, Visible: interrupted != ""},
+			ComboBox{AssignTo: &filter, Model: labels, CurrentIndex: 0, OnCurrentIndexChanged: applyFilter},
+			TableView{AssignTo: &tv, Model: model, OnItemActivated: reveal,
+				Columns:          []TableViewColumn{{DataMember: "File", Title: ui.T("col_file"), Width: 200}, {DataMember: "Size", Title: ui.T("col_size"), Width: 80}, {DataMember: "Dims", Title: ui.T("col_dims"), Width: 130}, {DataMember: "Result", Title: ui.T("col_result"), Width: 480}},
+				ContextMenuItems: []MenuItem{Action{Text: ui.T("reveal_action"), OnTriggered: reveal}, Action{Text: ui.T("verify_action"), Visible: present[categoryPreviously], OnTriggered: verifyRow}},
+			},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				PushButton{Text: ui.T("resume_btn"), Visible: resumable, OnClicked: func() { settle(true) }},
+				PushButton{Text: ui.T("dismiss_btn"), Visible: interrupted != "", OnClicked: func() { settle(false) }},
+				PushButton{Text: ui.T("verify_previous_btn"), Visible: present[categoryPreviously] && !running, OnClicked: verifyRun},
+				HSpacer{}, PushButton{Text: ui.T("ok_btn"), OnClicked: func() { dlg.Accept() }},
+			}},
+		},
+	}).Create(ui.MainWindow); err != nil {
+		logger.Error("Results dialog failed: %v", err)
+		return
+	}
+	placeForm(dlg, ui.Config.ResultsWindow, walk.Size(minSize))
+	trackPlacement(dlg, &ui.Config.ResultsWindow)
+	dlg.Run()
+	if err := config.SaveConfig(ui.Config); err != nil {
+		logger.Error("Config save failed: %v", err)
+	}
+}
+
+// resultCache bounds the rows the results table keeps built; scrolling further reads them again.
+const resultCache = 2000
+
+// resultModel backs the results table with the journal of the run, so files done by a running run
+// are appended in place and a list of any length only keeps the category of each file: the rows
+// are read from the journal as they are shown.
+type resultModel struct {
+	walk.TableModelBase
+	run      *engine.Journal
+	build    func(OrganizeResult) *resultRow
+	cats     []string // the category of every result, in the journal's order
+	rows     []int    // the results shown, by their index in the journal
+	category string
+	cache    map[int]*resultRow
+}
+
+func (m *resultModel) RowCount() int { return len(m.rows) }
+
+func (m *resultModel) Value(row, col int) interface{} {
+	r := m.row(row)
+	switch col {
+	case 0:
+		return r.File
+	case 1:
+		return r.Size
+	case 2:
+		return r.Dims
+	}
+	return r.Result
+}
+
+// row builds the i-th row shown, reading its result from the journal unless it is cached.
+func (m *resultModel) row(i int) *resultRow {
+	idx := m.rows[i]
+	if r, ok := m.cache[idx]; ok {
+		return r
+	}
+	res, err := m.run.At(idx)
+	if err != nil {
+		logger.Error("Result %d unreadable: %v", idx, err)
+		return &resultRow{Result: err.Error()}
+	}
+	if len(m.cache) >= resultCache {
+		clear(m.cache)
+	}
+	r := m.build(res)
+	m.cache[idx] = r
+	return r
+}
+
+// filter shows the rows of one category, or all of them for "".
+func (m *resultModel) filter(category string) {
+	m.category, m.rows = category, nil
+	for i, c := range m.cats {
+		if category == "" || c == category {
+			m.rows = append(m.rows, i)
+		}
+	}
+	m.PublishRowsReset()
+}
+
+// add appends the results a running run just did, which its journal holds after those before.
+func (m *resultModel) add(res []OrganizeResult) {
+	from := len(m.rows)
+	for _, r := range res {
+		c := m.build(r).category
+		if m.category == "" || c == m.category {
+			m.rows = append(m.rows, len(m.cats))
+		}
+		m.cats = append(m.cats, c)
+	}
+	if len(m.rows) > from {
+		m.PublishRowsInserted(from, len(m.rows)-1)
+	}
+}
@@ -0,0 +1,69 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"lume-go/internal/index"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// nearDupRow is one line of the near-duplicate review table.
+type nearDupRow struct {
+	PathA, SizeA, DateA string
+	PathB, SizeB, DateB string
+	Distance            int
+}
+
+// findNearDuplicates pairs the archived files of this run with each other and with the indexed archive.
+// Nothing is deleted; the pairs are only shown for review.
+func (ui *LumeUI) findNearDuplicates(res iter.Seq[OrganizeResult], ix *index.Index) []metadata.NearPair {
+	if !ui.Config.NearDuplicates {
+		return nil
+	}
+	var fresh, existing []metadata.HashedImage
+	for r := range res {
+		if r.Success && r.PHash != 0 {
+			fresh = append(fresh, metadata.HashedImage{Path: r.Dest, Size: r.Size, Date: r.Date, PHash: r.PHash})
+		}
+	}
+	if ix != nil {
+		existing = ix.Images()
+	}
+	pairs := metadata.FindNearDuplicates(fresh, existing, ui.Config.NearDupDistance)
+	logger.Info("Near-duplicate pass: %d candidates, %d pairs flagged", len(fresh), len(pairs))
+	return pairs
+}
+
+// ShowNearDuplicates lists the flagged pairs side by side so the user can decide what to keep.
+func (ui *LumeUI) ShowNearDuplicates(pairs []metadata.NearPair) {
+	rows := make([]*nearDupRow, 0, len(pairs))
+	for _, p := range pairs {
+		rows = append(rows, &nearDupRow{
+			PathA: p.A.Path, SizeA: ui.bytes(p.A.Size), DateA: p.A.Date.Format("2006-01-02 15:04"),
+			PathB: p.B.Path, SizeB: ui.bytes(p.B.Size), DateB: p.B.Date.Format("2006-01-02 15:04"),
+			Distance: p.Distance,
+		})
+	}
+
+	var dlg *walk.Dialog
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("neardup_title"), MinSize: Size{Width: 820, Height: 380}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: fmt.Sprintf(ui.T("neardup_info"), len(pairs))},
+			TableView{Model: rows, Columns: []TableViewColumn{
+				{DataMember: "PathA", Title: ui.T("col_file_a"), Width: 220}, {DataMember: "SizeA", Title: ui.T("col_size"), Width: 70}, {DataMember: "DateA", Title: ui.T("col_date"), Width: 110},
+				{DataMember: "PathB", Title: ui.T("col_file_b"), Width: 220}, {DataMember: "SizeB", Title: ui.T("col_size"), Width: 70}, {DataMember: "DateB", Title: ui.T("col_date"), Width: 110},
+				{DataMember: "Distance", Title: ui.T("col_distance"), Width: 60},
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{HSpacer{}, PushButton{Text: ui.T("ok_btn"), OnClicked: func() { dlg.Accept() }}}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Near-duplicate dialog failed: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"lume-go/internal/engine"
+	"lume-go/internal/logger"
+	"lume-go/internal/routing"
+	"lume-go/internal/validator"
+	"strings"
+)
+
+func (ui *LumeUI) router() routing.Router {
+	return routing.Router{Rules: ui.Config.Routes, Default: ui.TargetFolder}
+}
+
+// routesText lists the active routing rules for the read-only display under the target folder.
+func (ui *LumeUI) routesText() string {
+	if len(ui.Config.Routes) == 0 {
+		return ""
+	}
+	lines := []string{ui.T("routes_header")}
+	for _, r := range ui.Config.Routes {
+		lines = append(lines, "  "+r.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkTargets validates every routed target, refusing protected ones, and checks free space per
+// target for the queued files.
+// Only files coming from another volume count, since the rest are renamed in place.
+// It returns the message to show along with the error.
+func (ui *LumeUI) checkTargets() (string, error) {
+	rt := ui.router()
+	if err := ui.checkNesting(rt.Targets()); err != nil {
+		return ui.errText(err), err
+	}
+	for _, t := range rt.Targets()[1:] {
+		if err := validator.CheckWritability(t); err != nil {
+			return fmt.Sprintf(ui.T("err_val"), ui.errText(err)), err
+		}
+	}
+	protect := ui.protection()
+	for _, t := range rt.Targets() {
+		if err := protect.Check(t); err != nil {
+			return ui.errText(err), err
+		}
+	}
+	ui.mutex.Lock()
+	files := engine.PlanFiles(rt, ui.layout(), ui.FilesToMove, ui.PendingPaths, ui.SourceRoots, ui.Config.PreserveStructure)
+	ui.mutex.Unlock()
+	byTarget := make(map[string]map[string]int64)
+	for _, f := range files {
+		if byTarget[f.Target] == nil {
+			byTarget[f.Target] = make(map[string]int64)
+		}
+		byTarget[f.Target][f.Path] = f.Size
+	}
+	for target, sizes := range byTarget {
+		req := validator.SpaceNeeded(target, sizes, validator.VolumeSerial)
+		logger.Info("Space needed on %s: %d bytes to copy, %d files renamed in place", target, req.Copy, req.Renamed)
+		if err := validator.CheckDiskSpace(target, req.Need); errors.Is(err, validator.ErrInsufficientSpace) {
+			need := fmt.Sprintf(ui.T("space_need"), ui.bytes(req.Copy), ui.bytes(validator.SpaceMargin), ui.count(req.Renamed))
+			return fmt.Sprintf("%s %s (%s)", ui.T("err_disk"), ui.errText(err), need), fmt.Errorf("%s: %w", target, err)
+		} else if err != nil {
+			err = fmt.Errorf("%s: %w", target, err)
+			return fmt.Sprintf(ui.T("err_val"), err), err
+		}
+	}
+	return "", nil
+}
+
+// routeSummary counts the archived files per target when routing rules are active.
+func (ui *LumeUI) routeSummary(res iter.Seq[OrganizeResult]) string {
+	if len(ui.Config.Routes) == 0 {
+		return ""
+	}
+	counts := make(map[string]int)
+	var order []string
+	for r := range res {
+		if !r.Success {
+			continue
+		}
+		if _, ok := counts[r.Target]; !ok {
+			order = append(order, r.Target)
+		}
+		counts[r.Target]++
+	}
+	var b strings.Builder
+	for _, t := range order {
+		fmt.Fprintf(&b, "\n- %s: %s", t, ui.count(counts[t]))
+	}
+	return b.String()
+}
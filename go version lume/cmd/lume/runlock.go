@@ -0,0 +1,48 @@
+//go:build windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"lume-go/internal/lock"
+	"lume-go/internal/routing"
+	"lume-go/pkg/lume"
+
+	"github.com/lxn/walk"
+)
+
+// runTargets lists every folder a run into target writes to, routed targets included.
+func (ui *LumeUI) runTargets(target string) []string {
+	return routing.Router{Rules: ui.Config.Routes, Default: target}.Targets()
+}
+
+// lockTargets locks each of targets against other Lume and lume-lite runs. force takes over locks
+// held by others. On failure nothing stays locked.
+func (ui *LumeUI) lockTargets(targets []string, force bool) (func(), error) {
+	return lock.AcquireAll(targets, "Lume "+lume.Version(), force)
+}
+
+// lockRun locks the targets of a manual run. When another run holds one, the user is told who and
+// may take the lock over; it returns nil when the run must not start.
+func (ui *LumeUI) lockRun(target string) func() {
+	targets := ui.runTargets(target)
+	release, err := ui.lockTargets(targets, false)
+	var locked *lock.LockedError
+	if errors.As(err, &locked) {
+		msg := ui.lockedText(locked) + "\n\n" + ui.T("lock_override")
+		if walk.MsgBox(ui.MainWindow, ui.T("warn_title"), msg, walk.MsgBoxYesNo|walk.MsgBoxIconWarning|walk.MsgBoxDefButton2) != walk.DlgCmdYes {
+			return nil
+		}
+		release, err = ui.lockTargets(targets, true)
+	}
+	if err != nil {
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+		return nil
+	}
+	return release
+}
+
+func (ui *LumeUI) lockedText(e *lock.LockedError) string {
+	return fmt.Sprintf(ui.T("err_locked"), e.Path, e.Owner.App, e.Owner.PID, e.Owner.Host, e.Owner.Started.Local().Format("02.01.2006 15:04"))
+}
@@ -0,0 +1,188 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/engine"
+	"lume-go/internal/lock"
+	"lume-go/internal/logger"
+	"lume-go/internal/schedule"
+	"lume-go/internal/validator"
+	"os"
+	"time"
+
+	"github.com/lxn/walk"
+)
+
+// startSchedule puts Lume in the tray and starts the timer of the configured job. A job that cannot
+// run (no folders, bad time) is logged and left off.
+func (ui *LumeUI) startSchedule() {
+	job := ui.Config.Schedule
+	if !job.Enabled {
+		return
+	}
+	if err := job.Check(); err != nil {
+		logger.Error("Schedule disabled: %v", err)
+		return
+	}
+	tray, err := walk.NewNotifyIcon(ui.MainWindow)
+	if err != nil {
+		logger.Error("Tray icon unavailable, schedule disabled: %v", err)
+		return
+	}
+	ui.tray = tray
+	if icon := ui.MainWindow.Icon(); icon != nil {
+		tray.SetIcon(icon)
+	}
+	open, run := walk.NewAction(), walk.NewAction()
+	open.SetText(ui.T("tray_open"))
+	open.Triggered().Attach(ui.restoreWindow)
+	run.SetText(ui.T("tray_run"))
+	run.Triggered().Attach(func() { ui.runScheduled(time.Now()) })
+	tray.ContextMenu().Actions().Add(open)
+	tray.ContextMenu().Actions().Add(run)
+	tray.MouseDown().Attach(func(x, y int, button walk.MouseButton) {
+		if button == walk.LeftButton {
+			ui.restoreWindow()
+		}
+	})
+	tray.SetVisible(true)
+
+	anchor := job.Anchor(time.Now())
+	ui.showNextRun(job.Next(anchor))
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.stopSchedule = cancel
+	go job.Run(ctx, anchor, func(due time.Time) {
+		ui.MainWindow.Synchronize(func() {
+			ui.runScheduled(due)
+			ui.showNextRun(job.Next(time.Now()))
+		})
+	})
+}
+
+// closeSchedule stops the timer and removes the tray icon.
+func (ui *LumeUI) closeSchedule() {
+	if ui.stopSchedule != nil {
+		ui.stopSchedule()
+	}
+	if ui.tray != nil {
+		ui.tray.Dispose()
+	}
+}
+
+func (ui *LumeUI) restoreWindow() {
+	ui.MainWindow.Show()
+	ui.MainWindow.SetFocus()
+}
+
+func (ui *LumeUI) showNextRun(next time.Time) {
+	ui.tray.SetToolTip(fmt.Sprintf(ui.T("sched_next"), next.Format("02.01 15:04")))
+}
+
+// toast reports a scheduled run from the tray.
+func (ui *LumeUI) toast(warn bool, msg string) {
+	if ui.tray == nil {
+		return
+	}
+	show := ui.tray.ShowInfo
+	if warn {
+		show = ui.tray.ShowWarning
+	}
+	if err := show(ui.T("sched_title"), msg); err != nil {
+		logger.Error("Tray message failed: %v", err)
+	}
+}
+
+// runScheduled organizes the job's source folder into its target without asking anything. It is
+// skipped while another run, drop scan or reorganization is going on; the queue of a manual run
+// being prepared is left alone.
+func (ui *LumeUI) runScheduled(due time.Time) {
+	ui.mutex.Lock()
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		logger.Info("Scheduled run due %s skipped: another task is in progress", due.Format(time.RFC3339))
+		ui.toast(false, ui.T("sched_skipped"))
+		return
+	}
+	ui.isProcessing = true
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.Config.Schedule.LastRun = time.Now()
+	job := ui.Config.Schedule
+	if err := config.SaveConfig(ui.Config); err != nil {
+		logger.Error("Schedule state not saved: %v", err)
+	}
+	ui.updateQueueButtons()
+	ui.mutex.Unlock()
+
+	logger.Info("Scheduled run due %s: %s -> %s", due.Format(time.RFC3339), job.Source, job.Target)
+	ui.StartBtn.SetEnabled(false)
+	ui.CancelBtn.SetVisible(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(fmt.Sprintf(ui.T("sched_running"), job.Source))
+	go func() {
+		defer ui.guard()
+		defer cancel()
+		paths, err := ui.scheduledFiles(job)
+		release := func() {}
+		if err == nil && len(paths) > 0 {
+			release, err = ui.lockTargets(ui.runTargets(job.Target), false)
+		}
+		if err != nil || len(paths) == 0 {
+			msg, warn := fmt.Sprintf(ui.T("sched_nothing"), job.Source), err != nil
+			var locked *lock.LockedError
+			if errors.As(err, &locked) {
+				logger.Info("Scheduled run skipped: %v", err)
+				msg = ui.lockedText(locked)
+			} else if err != nil {
+				logger.Error("Scheduled run failed: %v", err)
+				msg = fmt.Sprintf(ui.T("sched_failed"), ui.errText(err))
+			} else if found := ui.recordRun(runSource([]string{job.Source}), 0, 0); len(found) > 0 {
+				msg, warn = msg+ui.anomalySummary(found), true
+			}
+			ui.MainWindow.Synchronize(func() {
+				ui.toast(warn, msg)
+				ui.finishBusy("")
+				ui.mutex.Lock()
+				ui.updateQueueButtons()
+				ui.mutex.Unlock()
+			})
+			return
+		}
+		defer release()
+		ui.organize(ctx, nil, paths, job.Target, []string{job.Source}, nil, func(summary string, warn bool) {
+			ui.toast(warn, summary)
+		})
+	}()
+}
+
+// scheduledFiles lists the files of the job's source that pass the drop filters and checks the target
+// has room for those it has to copy.
+func (ui *LumeUI) scheduledFiles(job schedule.Job) ([]string, error) {
+	if _, err := os.Stat(job.Source); err != nil {
+		return nil, err
+	}
+	if validator.IsSubPath(job.Target, job.Source) {
+		return nil, fmt.Errorf("source %s is inside the target %s", job.Source, job.Target)
+	}
+	if err := validator.CheckWritability(job.Target); err != nil {
+		return nil, err
+	}
+	// Nobody is there to confirm downloading online-only cloud files, so they are always left out.
+	f := ui.filters()
+	f.Hydrate = false
+	files, total := engine.Collect([]string{job.Source}, ui.runTargets(job.Target), f)
+	if len(files) == 0 {
+		return nil, nil
+	}
+	// A source on the target's volume is renamed into place and needs no space.
+	if validator.SameVolume(job.Source, job.Target, validator.VolumeSerial) {
+		return files, nil
+	}
+	return files, validator.CheckDiskSpace(job.Target, total+validator.SpaceMargin)
+}
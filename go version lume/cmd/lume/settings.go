@@ -0,0 +1,221 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/shell"
+	"os"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+	"github.com/lxn/win"
+)
+
+// ShowSettings opens the settings dialog.
+func (ui *LumeUI) ShowSettings() {
+	var dlg *walk.Dialog
+	var links, conflicts *walk.ComboBox
+	var hidden, hard, checksums, months, verify, preserve, structure, pool, watchCards, recheck, background *walk.CheckBox
+	var cloud *walk.ComboBox
+	cloudLabels, cloudIndex := make([]string, len(metadata.CloudPolicies)), 0
+	for i, p := range metadata.CloudPolicies {
+		cloudLabels[i] = ui.T("cloud_" + p)
+		if p == ui.Config.CloudPolicy {
+			cloudIndex = i
+		}
+	}
+	linkLabels, linkIndex := make([]string, len(metadata.LinkPolicies)), 0
+	for i, p := range metadata.LinkPolicies {
+		linkLabels[i] = ui.T("links_" + p)
+		if p == ui.Config.LinkPolicy {
+			linkIndex = i
+		}
+	}
+	conflictLabels, conflictIndex := make([]string, len(organizer.ConflictStrategies)), 0
+	for i, s := range organizer.ConflictStrategies {
+		conflictLabels[i] = ui.T("conflict_" + s)
+		if s == ui.Config.ConflictStyle {
+			conflictIndex = i
+		}
+	}
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("settings_title"), MinSize: Size{Width: 320, Height: 140}, Layout: VBox{},
+		Children: []Widget{
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				Label{Text: ui.T("links_label")},
+				ComboBox{AssignTo: &links, Model: linkLabels, CurrentIndex: linkIndex, OnCurrentIndexChanged: func() {
+					if i := links.CurrentIndex(); i >= 0 {
+						ui.Config.LinkPolicy = metadata.LinkPolicies[i]
+						if err := config.SaveConfig(ui.Config); err != nil {
+							logger.Error("Config save failed: %v", err)
+						}
+					}
+				}},
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				Label{Text: ui.T("cloud_label")},
+				ComboBox{AssignTo: &cloud, Model: cloudLabels, CurrentIndex: cloudIndex, OnCurrentIndexChanged: func() {
+					if i := cloud.CurrentIndex(); i >= 0 {
+						ui.Config.CloudPolicy = metadata.CloudPolicies[i]
+						if err := config.SaveConfig(ui.Config); err != nil {
+							logger.Error("Config save failed: %v", err)
+						}
+					}
+				}},
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				Label{Text: ui.T("conflict_label")},
+				ComboBox{AssignTo: &conflicts, Model: conflictLabels, CurrentIndex: conflictIndex, OnCurrentIndexChanged: func() {
+					if i := conflicts.CurrentIndex(); i >= 0 {
+						ui.Config.ConflictStyle = organizer.ConflictStrategies[i]
+						if err := config.SaveConfig(ui.Config); err != nil {
+							logger.Error("Config save failed: %v", err)
+						}
+					}
+				}},
+			}},
+			CheckBox{AssignTo: &hidden, Text: ui.T("include_hidden"), Checked: ui.Config.IncludeHidden, OnCheckedChanged: func() {
+				ui.Config.IncludeHidden = hidden.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			CheckBox{AssignTo: &hard, Text: ui.T("hard_delete"), Checked: ui.Config.HardDelete, OnCheckedChanged: func() {
+				ui.Config.HardDelete = hard.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				CheckBox{AssignTo: &checksums, Text: ui.T("checksums"), Checked: ui.Config.Checksums, OnCheckedChanged: func() {
+					ui.Config.Checksums = checksums.Checked()
+					if err := config.SaveConfig(ui.Config); err != nil {
+						logger.Error("Config save failed: %v", err)
+					}
+				}},
+				PushButton{Text: ui.T("sums_rebuild_btn"), OnClicked: func() {
+					dlg.Accept()
+					ui.RebuildSums()
+				}},
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				CheckBox{AssignTo: &months, Text: ui.T("month_index"), Checked: ui.Config.MonthIndex, OnCheckedChanged: func() {
+					ui.Config.MonthIndex = months.Checked()
+					if err := config.SaveConfig(ui.Config); err != nil {
+						logger.Error("Config save failed: %v", err)
+					}
+				}},
+				PushButton{Text: ui.T("sums_rebuild_btn"), OnClicked: func() {
+					dlg.Accept()
+					ui.RebuildMonthIndexes()
+				}},
+			}},
+			CheckBox{AssignTo: &verify, Text: ui.T("reconcile"), Checked: !ui.Config.NoReconcile, OnCheckedChanged: func() {
+				ui.Config.NoReconcile = !verify.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			CheckBox{AssignTo: &preserve, Text: ui.T("preserve_archived"), Checked: ui.Config.PreserveArchived, OnCheckedChanged: func() {
+				ui.Config.PreserveArchived = preserve.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			CheckBox{AssignTo: &structure, Text: ui.T("preserve_structure"), Checked: ui.Config.PreserveStructure, OnCheckedChanged: func() {
+				ui.Config.PreserveStructure = structure.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+				ui.showPlan()
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				CheckBox{AssignTo: &pool, Text: fmt.Sprintf(ui.T("pool_devices"), ui.Config.DeviceMinFiles), Checked: ui.Config.PoolDevices, OnCheckedChanged: func() {
+					ui.Config.PoolDevices = pool.Checked()
+					if err := config.SaveConfig(ui.Config); err != nil {
+						logger.Error("Config save failed: %v", err)
+					}
+				}},
+				PushButton{Text: ui.T("device_aliases_btn"), OnClicked: ui.ShowDeviceAliases},
+				PushButton{Text: ui.T("clock_offsets_btn"), OnClicked: ui.ShowClockOffsets},
+			}},
+			CheckBox{AssignTo: &watchCards, Text: ui.T("watch_cards"), Checked: ui.Config.WatchCards, OnCheckedChanged: func() {
+				ui.Config.WatchCards = watchCards.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+				if ui.Config.WatchCards {
+					ui.startCardWatch()
+				} else {
+					ui.stopCardWatch()
+				}
+			}},
+			CheckBox{AssignTo: &recheck, Text: ui.T("recheck_imports"), Checked: ui.Config.RecheckImports, OnCheckedChanged: func() {
+				ui.Config.RecheckImports = recheck.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			CheckBox{AssignTo: &background, Text: ui.T("background_priority"), Checked: ui.Config.BackgroundPriority, OnCheckedChanged: func() {
+				ui.Config.BackgroundPriority = background.Checked()
+				if err := config.SaveConfig(ui.Config); err != nil {
+					logger.Error("Config save failed: %v", err)
+				}
+			}},
+			PushButton{Text: ui.T("sendto_btn"), OnClicked: ui.RegisterSendTo},
+			PushButton{Text: ui.T("protect_btn"), OnClicked: func() {
+				dlg.Accept()
+				ui.ProtectFolder()
+			}},
+			PushButton{Text: ui.T("audit_btn"), OnClicked: func() {
+				dlg.Accept()
+				ui.AuditArchive()
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				PushButton{Text: ui.T("settings_export_btn"), OnClicked: func() {
+					dlg.Accept()
+					ui.ExportSettings()
+				}},
+				PushButton{Text: ui.T("settings_import_btn"), OnClicked: func() {
+					dlg.Accept()
+					ui.ImportSettings()
+				}},
+			}},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{HSpacer{}, PushButton{Text: ui.T("ok_btn"), OnClicked: func() { dlg.Accept() }}}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Settings dialog failed: %v", err)
+	}
+}
+
+// RegisterSendTo adds Lume to the Explorer "Send to" menu.
+func (ui *LumeUI) RegisterSendTo() {
+	exe, err := os.Executable()
+	if err == nil {
+		var lnk string
+		if lnk, err = shell.CreateSendToShortcut(exe); err == nil {
+			logger.Info("Send To shortcut created: %s", lnk)
+			walk.MsgBox(ui.MainWindow, ui.T("settings_title"), fmt.Sprintf(ui.T("sendto_done"), lnk), walk.MsgBoxIconInformation)
+			return
+		}
+	}
+	logger.Error("Send To shortcut failed: %v", err)
+	walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+}
+
+// receiveForwarded takes the paths of a second launch (e.g. "Send to") as if they were dropped.
+func (ui *LumeUI) receiveForwarded(paths []string) {
+	h := ui.MainWindow.Handle()
+	if win.IsIconic(h) {
+		win.ShowWindow(h, win.SW_RESTORE)
+	}
+	win.SetForegroundWindow(h)
+	if len(paths) > 0 {
+		ui.HandleDrop(paths)
+	}
+}
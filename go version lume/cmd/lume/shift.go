@@ -0,0 +1,112 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// shiftNote tells from which date a date shift moved a file's, and whether it was written into the file.
+func (ui *LumeUI) shiftNote(r OrganizeResult) string {
+	if r.ShiftedFrom.IsZero() {
+		return ""
+	}
+	s := fmt.Sprintf(ui.T("date_shifted"), r.ShiftedFrom.Format("2006-01-02 15:04"))
+	if r.ExifWritten {
+		s += ui.T("date_shift_written")
+	}
+	return s
+}
+
+// shiftSummary counts the archived files whose date was shifted.
+func (ui *LumeUI) shiftSummary(res iter.Seq[OrganizeResult]) string {
+	n := 0
+	for r := range res {
+		if r.Success && !r.ShiftedFrom.IsZero() {
+			n++
+		}
+	}
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(ui.T("date_shift_summary"), ui.count(n))
+}
+
+// suggestShift drafts a shift for the selected files: the device of the first one and the days they
+// span, the offset left for the user to fill in.
+func suggestShift(sel []metadata.FileInfo) string {
+	var s metadata.Shift
+	for _, f := range sel {
+		if f.DateSource == metadata.DateUndated || f.Date.IsZero() {
+			continue
+		}
+		day := f.Date.Format("2006-01-02")
+		if s.Device == "" {
+			s.Device, s.From, s.To = f.Device, day, day
+		}
+		if day < s.From {
+			s.From = day
+		}
+		if day > s.To {
+			s.To = day
+		}
+	}
+	if s.Device == "" {
+		return ""
+	}
+	return s.String() // ends in an empty offset=
+}
+
+// ShowShifts edits the date shifts of the queued files, one "device=…;from=…;to=…;offset=…" per line,
+// a line for sel drafted below those already set. The shifts apply to the next run only and are
+// dropped with the queue.
+func (ui *LumeUI) ShowShifts(sel []metadata.FileInfo) {
+	ui.mutex.Lock()
+	lines := make([]string, 0, len(ui.shifts)+1)
+	for _, s := range ui.shifts {
+		lines = append(lines, s.String())
+	}
+	writeExif := ui.shiftExif
+	ui.mutex.Unlock()
+	if draft := suggestShift(sel); draft != "" {
+		lines = append(lines, draft)
+	}
+
+	var dlg *walk.Dialog
+	var edit *walk.TextEdit
+	var exif *walk.CheckBox
+	save := func() {
+		shifts, err := metadata.ParseShifts(edit.Text())
+		if err != nil {
+			walk.MsgBox(dlg, ui.T("warn_title"), fmt.Sprintf(ui.T("shift_invalid"), err), walk.MsgBoxIconWarning)
+			return
+		}
+		ui.mutex.Lock()
+		ui.shifts, ui.shiftExif = shifts, exif.Checked()
+		ui.mutex.Unlock()
+		dlg.Accept()
+		ui.showPlan()
+	}
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("shift_title"), MinSize: Size{Width: 520, Height: 300}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: ui.T("shift_hint")},
+			TextEdit{AssignTo: &edit, Text: strings.Join(lines, "\r\n"), VScroll: true},
+			CheckBox{AssignTo: &exif, Text: ui.T("shift_exif"), Checked: writeExif},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{
+				HSpacer{},
+				PushButton{Text: ui.T("ok_btn"), OnClicked: save},
+				PushButton{Text: ui.T("cancel_btn"), OnClicked: func() { dlg.Cancel() }},
+			}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Date shift dialog failed: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/i18n"
+	"lume-go/internal/logger"
+
+	"github.com/lxn/walk"
+)
+
+// shortcut is a key binding of the main window. Its key is shown in the tooltip of widget.
+type shortcut struct {
+	key    walk.Shortcut
+	widget walk.Widget
+	tip    string // i18n key of the tooltip
+	run    func()
+}
+
+// shortcuts lists the key bindings of the main window. Commands that must wait for a run check
+// isProcessing themselves, as they do when clicked.
+func (ui *LumeUI) shortcuts() []shortcut {
+	return []shortcut{
+		{walk.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyO}, ui.SelectBtn, "tip_select", ui.SelectFolder},
+		{walk.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyReturn}, ui.StartBtn, "tip_start", ui.StartOrganizing},
+		{walk.Shortcut{Key: walk.KeyEscape}, ui.CancelBtn, "tip_cancel", ui.confirmCancel},
+		{walk.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyL}, ui.LangCombo, "tip_language", ui.nextLanguage},
+		{walk.Shortcut{Modifiers: walk.ModControl, Key: walk.KeyD}, ui.ThemeBtn, "tip_theme", ui.ToggleTheme},
+	}
+}
+
+// registerShortcuts adds the key bindings to the main window's shortcut actions. Walk only looks
+// them up from the window that has the focus, so they are inert while a modal dialog is open.
+func (ui *LumeUI) registerShortcuts() {
+	for _, s := range ui.shortcuts() {
+		a := walk.NewAction()
+		if err := a.SetShortcut(s.key); err != nil {
+			continue
+		}
+		a.Triggered().Attach(s.run)
+		ui.MainWindow.ShortcutActions().Add(a)
+	}
+	ui.setTabOrder()
+	ui.refreshTips()
+}
+
+// tabOrder lists the controls of the main window in the order Tab moves through them: the target
+// first, then the buttons of a run, then the window's own settings.
+func (ui *LumeUI) tabOrder() []walk.Widget {
+	return []walk.Widget{
+		ui.TargetCombo, ui.SelectBtn, ui.OpenTargetBtn, ui.ReorgBtn,
+		ui.StartBtn, ui.CancelBtn, ui.ListBtn, ui.ClearBtn, ui.ResultsBtn, ui.RateCombo,
+		ui.StatsBtn, ui.SettingsBtn, ui.LangCombo, ui.ThemeBtn,
+	}
+}
+
+// setTabOrder applies tabOrder. Walk moves a widget, and its parents with it, to the front of the
+// focus order, so the list is applied from its end.
+func (ui *LumeUI) setTabOrder() {
+	order := ui.tabOrder()
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := order[i].BringToTop(); err != nil {
+			logger.Error("Tab order not set: %v", err)
+			return
+		}
+	}
+}
+
+// refreshTips sets the tooltips of the main window in the current language; those of controls with
+// a shortcut end in its key.
+func (ui *LumeUI) refreshTips() {
+	for _, s := range ui.shortcuts() {
+		s.widget.SetToolTipText(fmt.Sprintf("%s (%s)", ui.T(s.tip), s.key))
+	}
+	for _, t := range []struct {
+		widget walk.Widget
+		tip    string
+	}{
+		{ui.StatsBtn, "tip_stats"}, {ui.SettingsBtn, "tip_settings"}, {ui.TargetCombo, "tip_target_combo"},
+		{ui.OpenTargetBtn, "tip_open_target"}, {ui.ReorgBtn, "tip_reorg"}, {ui.RoutesLabel, "tip_routes"},
+		{ui.SelectionLabel, "tip_drop"}, {ui.StatusLabel, "tip_status"}, {ui.ProgressBar, "tip_progress"},
+		{ui.ListBtn, "tip_list"}, {ui.ClearBtn, "tip_clear"}, {ui.ResultsBtn, "tip_results"},
+	} {
+		t.widget.SetToolTipText(ui.T(t.tip))
+	}
+}
+
+// confirmCancel asks before Esc cancels the running task.
+func (ui *LumeUI) confirmCancel() {
+	ui.mutex.Lock()
+	busy := ui.isProcessing && ui.cancelFunc != nil
+	ui.mutex.Unlock()
+	if busy && walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("cancel_confirm"), walk.MsgBoxYesNo|walk.MsgBoxIconQuestion) == walk.DlgCmdYes {
+		ui.CancelOrganizing()
+	}
+}
+
+// nextLanguage switches to the language after the current one in the dropdown.
+func (ui *LumeUI) nextLanguage() {
+	if n := len(i18n.Languages()); n > 0 {
+		ui.LangCombo.SetCurrentIndex((ui.LangCombo.CurrentIndex() + 1) % n)
+	}
+}
@@ -0,0 +1,27 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/engine"
+	"strings"
+)
+
+// orphanSummary lists the camcorder sidecars a run found without their video and could not place
+// beside an archived copy of it.
+func (ui *LumeUI) orphanSummary(sum engine.RunSummary) string {
+	if len(sum.Orphans) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n\n"+ui.T("orphan_sidecars"), ui.count(len(sum.Orphans)))
+	for i, p := range sum.Orphans {
+		if i == MaxErrorsDisplay {
+			b.WriteString("\n" + ui.T("see_log"))
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", p)
+	}
+	return b.String()
+}
@@ -0,0 +1,74 @@
+//go:build windows
+
+package main
+
+import (
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/priority"
+	"lume-go/internal/units"
+	"slices"
+)
+
+// ratePresets are the copy rates the main window offers, in bytes per second; 0 is no limit.
+var ratePresets = []int64{0, 1 << 20, 5 << 20, 10 << 20, 20 << 20, 50 << 20, 100 << 20}
+
+// rates are the presets plus the rate of the config when it is none of them, in order.
+func (ui *LumeUI) rates() []int64 {
+	rates := slices.Clone(ratePresets)
+	if r := int64(ui.Config.RateLimit); r > 0 && !slices.Contains(rates, r) {
+		rates = append(rates, r)
+		slices.Sort(rates)
+	}
+	return rates
+}
+
+// rateLabels name the rates for the combo box.
+func (ui *LumeUI) rateLabels() []string {
+	var labels []string
+	for _, r := range ui.rates() {
+		if r <= 0 {
+			labels = append(labels, ui.T("rate_unlimited"))
+		} else {
+			labels = append(labels, ui.bytes(r)+"/s")
+		}
+	}
+	return labels
+}
+
+func (ui *LumeUI) rateIndex() int {
+	return max(slices.Index(ui.rates(), max(int64(ui.Config.RateLimit), 0)), 0)
+}
+
+// RateChosen applies the rate picked in the main window at once, also to a run going on, and keeps
+// it for the next runs.
+func (ui *LumeUI) RateChosen() {
+	i := ui.RateCombo.CurrentIndex()
+	rates := ui.rates()
+	if i < 0 || i >= len(rates) {
+		return
+	}
+	ui.limiter.SetRate(rates[i])
+	ui.Config.RateLimit = units.Size(rates[i])
+	logger.Info("Copy rate limit set to %d bytes/s", rates[i])
+	if err := config.SaveConfig(ui.Config); err != nil {
+		logger.Error("Config save failed: %v", err)
+	}
+}
+
+// backgroundPriority lowers the IO priority of Lume for a run when the config asks for it and returns
+// what restores it.
+func (ui *LumeUI) backgroundPriority() func() {
+	if !ui.Config.BackgroundPriority {
+		return func() {}
+	}
+	if err := priority.Background(true); err != nil {
+		logger.Error("Background priority not set: %v", err)
+		return func() {}
+	}
+	return func() {
+		if err := priority.Background(false); err != nil {
+			logger.Error("Background priority not ended: %v", err)
+		}
+	}
+}
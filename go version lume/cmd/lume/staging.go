@@ -0,0 +1,139 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/staging"
+
+	"github.com/lxn/walk"
+)
+
+// stagingRoot is the folder the staging areas of window sessions are kept in.
+func (ui *LumeUI) stagingRoot() (string, error) {
+	if ui.Config.StagingDir != "" {
+		return ui.Config.StagingDir, nil
+	}
+	return config.DataPath("staging")
+}
+
+// sweepStaging removes the staging areas of sessions a crash ended before they cleaned up.
+func (ui *LumeUI) sweepStaging() {
+	root, err := ui.stagingRoot()
+	if err != nil {
+		return
+	}
+	if n, err := staging.Sweep(root); err != nil {
+		logger.Error("Staging sweep failed: %v", err)
+	} else if n > 0 {
+		logger.Info("Removed %d staging areas left by earlier sessions", n)
+	}
+}
+
+// ephemeralDrop splits a drop into the paths that last and those Explorer only lends for the drop,
+// such as the files of a phone connected over MTP, which are staged first. Staged copies last.
+func (ui *LumeUI) ephemeralDrop(ps []string) (lasting, ephemeral []string) {
+	for _, p := range ps {
+		if staging.Ephemeral(p) && (ui.staging == nil || !ui.staging.Contains(p)) {
+			ephemeral = append(ephemeral, p)
+		} else {
+			lasting = append(lasting, p)
+		}
+	}
+	return lasting, ephemeral
+}
+
+// stageDrop copies the ephemeral paths of a drop into the session's staging area in the background,
+// with progress, then drops the copies with the rest. Copies that do not fit are not made, and the
+// user is told so before anything runs; the rest of the drop is queued all the same. Cancelling
+// leaves nothing staged.
+func (ui *LumeUI) stageDrop(ephemeral, rest []string) {
+	if ui.staging == nil {
+		root, err := ui.stagingRoot()
+		if err != nil {
+			logger.Error("Staging area unavailable: %v", err)
+			walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("staging_failed"), err), walk.MsgBoxIconError)
+			ui.HandleDrop(rest)
+			return
+		}
+		ui.staging = staging.Open(root, int64(ui.Config.StagingMax))
+	}
+	ui.mutex.Lock()
+	ui.isProcessing = true
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.updateQueueButtons()
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(ui.T("staging_start"))
+	area := ui.staging
+	go func() {
+		defer ui.guard()
+		shown := -1
+		staged, err := area.Stage(ctx, ephemeral, func(done, total int64) {
+			// Only a new percentage is worth a trip to the window.
+			if pct := int(done * 100 / max(total, 1)); pct != shown {
+				shown = pct
+				ui.MainWindow.Synchronize(func() {
+					ui.ProgressBar.SetValue(pct)
+					ui.StatusLabel.SetText(fmt.Sprintf(ui.T("staging_copying"), ui.bytes(done), ui.bytes(total)))
+				})
+			}
+		})
+		if err == nil {
+			logger.Info("Staged %d dropped paths in %s", len(staged), area.Dir())
+		}
+		ui.MainWindow.Synchronize(func() {
+			status := ""
+			if err != nil && ctx.Err() != nil {
+				status = ui.T("cancelled")
+			}
+			ui.finishBusy(status)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("Staging failed: %v", err)
+				walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.stagingError(err), walk.MsgBoxIconError)
+			}
+			ui.HandleDrop(append(rest, staged...))
+		})
+	}()
+}
+
+// stagingError says why files could not be staged.
+func (ui *LumeUI) stagingError(err error) string {
+	var full *staging.FullError
+	switch {
+	case errors.As(err, &full) && full.Disk:
+		return fmt.Sprintf(ui.T("staging_full_disk"), ui.bytes(full.Need), ui.bytes(full.Have))
+	case errors.As(err, &full):
+		return fmt.Sprintf(ui.T("staging_full"), ui.bytes(full.Need), ui.bytes(full.Have))
+	}
+	return fmt.Sprintf(ui.T("staging_failed"), err)
+}
+
+// cleanStaging removes the staging area once no queued file is a staged copy. The caller holds
+// ui.mutex.
+func (ui *LumeUI) cleanStaging() {
+	if ui.staging == nil || ui.staging.Used() == 0 {
+		return
+	}
+	for _, f := range ui.FilesToMove {
+		if ui.staging.Contains(f.Path) {
+			return
+		}
+	}
+	for _, p := range ui.PendingPaths {
+		if ui.staging.Contains(p) {
+			return
+		}
+	}
+	if err := ui.staging.Clean(); err != nil {
+		logger.Error("Staging area not removed: %v", err)
+	}
+}
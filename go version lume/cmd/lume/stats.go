@@ -0,0 +1,94 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"iter"
+	"lume-go/internal/config"
+	"lume-go/internal/history"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/plan"
+	"lume-go/internal/validator"
+	"strings"
+
+	"github.com/lxn/walk"
+	. "github.com/lxn/walk/declarative"
+)
+
+// statsRow is one line of a statistics breakdown.
+type statsRow struct {
+	Name, Files, Size, Share string
+}
+
+// loadHistory opens the lifetime statistics. A corrupt file is logged and started over.
+func loadHistory() *history.History {
+	path, err := config.DataPath(history.FileName)
+	if err != nil {
+		logger.Error("History unavailable: %v", err)
+		path = history.FileName
+	}
+	h, err := history.Load(path)
+	if err != nil {
+		logger.Error("History unreadable, starting over: %v", err)
+	}
+	return h
+}
+
+// recordHistory adds the archived files of a run to the history, and how fast they moved to the
+// rates the plan estimates from.
+func (ui *LumeUI) recordHistory(res iter.Seq[OrganizeResult]) {
+	n, meter := 0, plan.NewMeter(validator.VolumeSerial)
+	for r := range res {
+		if r.Success && r.DuplicateOf == "" {
+			ui.history.Add(metadata.SourceLevels(r.Source, 1), r.Device, r.Size)
+			meter.Add(r.Path, r.Target, r.Size, r.Started, r.Elapsed)
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+	meter.Record(ui.history)
+	if err := ui.history.Save(); err != nil {
+		logger.Error("History save failed: %v", err)
+	}
+}
+
+func (ui *LumeUI) statsRows(entries []history.Entry, total int64) []*statsRow {
+	rows := make([]*statsRow, 0, len(entries))
+	for _, e := range entries {
+		share := "-"
+		if total > 0 {
+			share = fmt.Sprintf("%.1f%%", float64(e.Bytes)*100/float64(total))
+		}
+		rows = append(rows, &statsRow{Name: e.Key, Files: ui.count(e.Files), Size: ui.bytes(e.Bytes), Share: share})
+	}
+	return rows
+}
+
+// ShowStats shows how the archive splits by source and by device, largest first.
+func (ui *LumeUI) ShowStats() {
+	total := ui.history.Totals()
+	columns := func(first string) []TableViewColumn {
+		return []TableViewColumn{{DataMember: "Name", Title: ui.T(first), Width: 200}, {DataMember: "Files", Title: ui.T("col_files"), Width: 80}, {DataMember: "Size", Title: ui.T("col_size"), Width: 90}, {DataMember: "Share", Title: ui.T("col_share"), Width: 60}}
+	}
+	var warnings []string
+	for _, a := range ui.history.Anomalies(ui.Config.Anomalies) {
+		warnings = append(warnings, "⚠ "+ui.anomalyText(a))
+	}
+	var dlg *walk.Dialog
+	if _, err := (Dialog{
+		AssignTo: &dlg, RightToLeftLayout: ui.rtl(), Title: ui.T("stats_title"), MinSize: Size{Width: 480, Height: 480}, Layout: VBox{},
+		Children: []Widget{
+			Label{Text: fmt.Sprintf(ui.T("stats_total"), ui.count(total.Files), ui.bytes(total.Bytes)), Font: Font{Bold: true}},
+			Label{Text: strings.Join(warnings, "\n"), Visible: len(warnings) > 0},
+			TableView{Model: ui.statsRows(ui.history.BySource(), total.Bytes), Columns: columns("col_source")},
+			TableView{Model: ui.statsRows(ui.history.ByDevice(), total.Bytes), Columns: columns("col_device")},
+			Composite{Layout: HBox{MarginsZero: true}, Children: []Widget{HSpacer{}, PushButton{Text: ui.T("ok_btn"), OnClicked: func() { dlg.Accept() }}}},
+		},
+	}).Run(ui.MainWindow); err != nil {
+		logger.Error("Statistics dialog failed: %v", err)
+	}
+}
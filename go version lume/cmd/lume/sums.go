@@ -0,0 +1,84 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/logger"
+	"lume-go/internal/monthindex"
+	"lume-go/internal/sums"
+
+	"github.com/lxn/walk"
+)
+
+// RebuildSums regenerates the SHA256SUMS of every year folder of the target from the files as they
+// are now, e.g. after the option was turned on for an existing archive.
+func (ui *LumeUI) RebuildSums() {
+	ui.rebuildTarget("sums_scanning", "sums_done", "checksums", sums.Rebuild)
+}
+
+// RebuildMonthIndexes regenerates the index.json of every month folder of the target from the files
+// as they are now, like RebuildSums. The month folders are those of the layout of the settings.
+func (ui *LumeUI) RebuildMonthIndexes() {
+	depth := ui.layout().MonthDepth()
+	ui.rebuildTarget("month_index_scanning", "month_index_done", "month indexes", func(ctx context.Context, root string, progress func(done, total int)) (int, error) {
+		return monthindex.Rebuild(ctx, root, depth, progress)
+	})
+}
+
+// rebuildTarget runs rebuild over the target in the background with progress, telling scanning while
+// it looks through the archive and finished with the files it listed. The target is locked like a run
+// into it. what names the lists in the log.
+func (ui *LumeUI) rebuildTarget(scanning, finished, what string, rebuild func(context.Context, string, func(done, total int)) (int, error)) {
+	ui.mutex.Lock()
+	if ui.TargetFolder == "" {
+		ui.mutex.Unlock()
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.T("warn_select"), walk.MsgBoxIconWarning)
+		return
+	}
+	if ui.isProcessing {
+		ui.mutex.Unlock()
+		return
+	}
+	target := ui.TargetFolder
+	ui.mutex.Unlock()
+	// The lists are written into the archive, so no run may move files there meanwhile.
+	release := ui.lockRun(target)
+	if release == nil {
+		return
+	}
+	ui.mutex.Lock()
+	ui.isProcessing = true
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancelFunc = cancel
+	ui.mutex.Unlock()
+
+	ui.setBusy(true)
+	ui.ProgressBar.SetVisible(true)
+	ui.ProgressBar.SetValue(0)
+	ui.StatusLabel.SetText(ui.T(scanning))
+	go func() {
+		defer ui.guard()
+		defer release()
+		n, err := rebuild(ctx, target, func(done, total int) {
+			ui.MainWindow.Synchronize(func() {
+				ui.ProgressBar.SetValue(done * 100 / total)
+				ui.StatusLabel.SetText(fmt.Sprintf(ui.T("proc_count"), ui.count(done), ui.count(total)))
+			})
+		})
+		ui.MainWindow.Synchronize(func() {
+			switch {
+			case ctx.Err() != nil:
+				ui.finishBusy(ui.T("cancelled"))
+			case err != nil:
+				logger.Error("Rebuild of %s failed: %v", what, err)
+				walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("err_val"), err), walk.MsgBoxIconError)
+				ui.finishBusy("")
+			default:
+				logger.Info("Rebuilt %s for %s: %d files", what, target, n)
+				ui.finishBusy(fmt.Sprintf(ui.T(finished), ui.count(n)))
+			}
+		})
+	}()
+}
@@ -0,0 +1,101 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"lume-go/internal/shell"
+	"lume-go/internal/validator"
+	"strings"
+
+	"github.com/lxn/walk"
+)
+
+// setTarget makes path the target folder, moves it to the top of the recent list and saves the config.
+func (ui *LumeUI) setTarget(path string) {
+	ui.TargetFolder = path
+	ui.Config.TargetFolder = path
+	ui.Config.RememberTarget(path)
+	if err := config.SaveConfig(ui.Config); err != nil {
+		logger.Error("Config save failed: %v", err)
+	}
+	ui.refreshTargets()
+	ui.StatusLabel.SetText(ui.GetStatusText())
+	ui.showPlan()
+}
+
+// refreshTargets reloads the dropdown from the recent list and selects the current target.
+func (ui *LumeUI) refreshTargets() {
+	ui.updatingTargets = true
+	defer func() { ui.updatingTargets = false }()
+	ui.TargetCombo.SetModel(ui.Config.RecentTargets)
+	idx := -1
+	for i, p := range ui.Config.RecentTargets {
+		if strings.EqualFold(p, ui.TargetFolder) {
+			idx = i
+			break
+		}
+	}
+	ui.TargetCombo.SetCurrentIndex(idx)
+	ui.OpenTargetBtn.SetEnabled(ui.TargetFolder != "")
+	ui.updateStart()
+}
+
+// OpenTarget shows the target folder in Explorer.
+func (ui *LumeUI) OpenTarget() {
+	if ui.TargetFolder == "" {
+		return
+	}
+	if err := shell.OpenFolder(ui.TargetFolder); err != nil {
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("open_failed"), err), walk.MsgBoxIconWarning)
+	}
+}
+
+// TargetChosen switches to a recent folder picked from the dropdown. Folders that vanished or turned
+// read-only since they were used are dropped from the list with a warning instead of failing later.
+func (ui *LumeUI) TargetChosen() {
+	if ui.updatingTargets {
+		return
+	}
+	i := ui.TargetCombo.CurrentIndex()
+	ui.mutex.Lock()
+	busy := ui.isProcessing
+	ui.mutex.Unlock()
+	if busy || i < 0 || i >= len(ui.Config.RecentTargets) {
+		ui.refreshTargets()
+		return
+	}
+	path := ui.Config.RecentTargets[i]
+	if err := validator.CheckWritability(path); err != nil {
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), fmt.Sprintf(ui.T("target_gone"), path, ui.errText(err)), walk.MsgBoxIconWarning)
+		ui.Config.ForgetTarget(path)
+		if strings.EqualFold(ui.TargetFolder, path) {
+			ui.TargetFolder, ui.Config.TargetFolder = "", ""
+		}
+		if err := config.SaveConfig(ui.Config); err != nil {
+			logger.Error("Config save failed: %v", err)
+		}
+		ui.refreshTargets()
+		return
+	}
+	if err := ui.checkNesting(ui.runTargets(path)); err != nil {
+		walk.MsgBox(ui.MainWindow, ui.T("warn_title"), ui.errText(err), walk.MsgBoxIconError)
+		ui.refreshTargets()
+		return
+	}
+	ui.setTarget(path)
+}
+
+// checkNesting refuses targets inside a queued source folder or holding a queued file; archiving there
+// would move files into their own source. See validator.CheckNesting.
+func (ui *LumeUI) checkNesting(targets []string) error {
+	ui.mutex.Lock()
+	defer ui.mutex.Unlock()
+	files := append([]string(nil), ui.PendingPaths...)
+	for _, f := range ui.FilesToMove {
+		files = append(files, f.Path)
+	}
+	return validator.CheckNesting(ui.SourceRoots, files, targets)
+}
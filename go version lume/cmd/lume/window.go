@@ -0,0 +1,54 @@
+//go:build windows
+
+package main
+
+import (
+	"lume-go/internal/config"
+	"lume-go/internal/logger"
+	"unsafe"
+
+	"github.com/lxn/walk"
+	"github.com/lxn/win"
+)
+
+// placeForm puts form back where it was last closed, fitted to the work area of the nearest monitor so
+// a window saved on a disconnected screen still opens in view. minSize is in 1/96" like MinSize. A
+// maximized window is maximized again once the message loop runs.
+func placeForm(form walk.Form, saved config.Window, minSize walk.Size) {
+	if !saved.Saved() {
+		return
+	}
+	r := saved.Rect
+	setBounds(form, r)
+	mi := win.MONITORINFO{CbSize: uint32(unsafe.Sizeof(win.MONITORINFO{}))}
+	if win.GetMonitorInfo(win.MonitorFromWindow(form.Handle(), win.MONITOR_DEFAULTTONEAREST), &mi) {
+		w := mi.RcWork
+		area := config.Rect{X: int(w.Left), Y: int(w.Top), Width: int(w.Right - w.Left), Height: int(w.Bottom - w.Top)}
+		fb := form.AsFormBase()
+		setBounds(form, r.Fit(area, fb.IntFrom96DPI(minSize.Width), fb.IntFrom96DPI(minSize.Height)))
+	}
+	if saved.Maximized {
+		form.Synchronize(func() { win.ShowWindow(form.Handle(), win.SW_MAXIMIZE) })
+	}
+}
+
+func setBounds(form walk.Form, r config.Rect) {
+	if err := form.SetBoundsPixels(walk.Rectangle{X: r.X, Y: r.Y, Width: r.Width, Height: r.Height}); err != nil {
+		logger.Error("Window placement not restored: %v", err)
+	}
+}
+
+// trackPlacement keeps *w up to date with the restored bounds of form and whether it is maximized,
+// to be saved when it closes. Minimizing changes nothing.
+func trackPlacement(form walk.Form, w *config.Window) {
+	form.BoundsChanged().Attach(func() {
+		h := form.Handle()
+		if win.IsIconic(h) {
+			return
+		}
+		if w.Maximized = win.IsZoomed(h); !w.Maximized {
+			b := form.BoundsPixels()
+			w.Rect = config.Rect{X: b.X, Y: b.Y, Width: b.Width, Height: b.Height}
+		}
+	})
+}
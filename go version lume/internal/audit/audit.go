@@ -0,0 +1,470 @@
+// Package audit checks the structure of an archive before it is trusted, e.g. on an old external
+// drive: files lying above the date folders, folders the layout does not make, empty folders, files
+// whose own date disagrees with the year or month folder they are in, and sets of identical files. An
+// audit only reads; Fix repairs the kinds that are safe to repair.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"lume-go/internal/engine"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Kinds of Finding, in the order a Report lists them.
+const (
+	Stray     = "stray"     // a file above the date folders, e.g. directly in the root
+	Folder    = "folder"    // a folder where the layout puts a date folder, not named like one
+	Empty     = "empty"     // a folder without a single file, the topmost of such a tree
+	Misdated  = "misdated"  // a file whose own date belongs in another date folder
+	Duplicate = "duplicate" // a file identical to another one of the archive
+)
+
+// Kinds lists the kinds of Finding in the order a Report lists them.
+var Kinds = []string{Stray, Folder, Empty, Misdated, Duplicate}
+
+// Fixable reports whether Fix repairs findings of kind: strays are filed like any new file and empty
+// folders removed. The others need a decision only the user can make.
+func Fixable(kind string) bool { return kind == Stray || kind == Empty }
+
+// Finding is one problem of the archive.
+type Finding struct {
+	Kind string
+	Path string
+	// Detail is, for Misdated, the date folder the file's own date belongs in, relative to the root,
+	// and for Duplicate, the file it is a copy of.
+	Detail string
+}
+
+// Report is the outcome of Audit.
+type Report struct {
+	Root string
+	// Files counts the media files looked at.
+	Files    int
+	Findings []Finding
+}
+
+// Of returns the findings of kind.
+func (r Report) Of(kind string) []Finding {
+	var out []Finding
+	for _, f := range r.Findings {
+		if f.Kind == kind {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Count counts the findings of kind.
+func (r Report) Count(kind string) int {
+	n := 0
+	for _, f := range r.Findings {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// Options tunes Audit.
+type Options struct {
+	// Layout is that of the archive; the leading folders of its template made only of date tokens
+	// and text are the date folders checked. A layout with Folders set has none.
+	Layout organizer.Layout
+	// Workers bounds the files read at once; zero means one per CPU.
+	Workers int
+	// Progress, when set, is told of the files read for their date or content.
+	Progress func(done, total int)
+}
+
+// dateLevel is a date folder level of the layout: the pattern of its name and the tokens its groups
+// capture, in order.
+type dateLevel struct {
+	re     *regexp.Regexp
+	tokens []string
+}
+
+var tokenRE = regexp.MustCompile(`\{[a-z]+\}`)
+
+// dateLevels compiles the leading folders of the layout's template that hold no token but the date
+// ones.
+func dateLevels(l organizer.Layout) []dateLevel {
+	if l.Folders != "" {
+		return nil
+	}
+	template := l.Template
+	if strings.TrimSpace(template) == "" {
+		template = organizer.DefaultTemplate
+	}
+	months := `(?:0[1-9]|1[0-2])(?:-.+)?`
+	for _, name := range l.MonthNames {
+		if name = strings.TrimSpace(name); name != "" {
+			months += "|" + regexp.QuoteMeta(name)
+		}
+	}
+	patterns := map[string]string{
+		"{year}":     `\d{4}`,
+		"{weekyear}": `\d{4}`,
+		"{month}":    months,
+		"{day}":      `0[1-9]|[12]\d|3[01]`,
+		"{week}":     `W\d{2}`,
+	}
+	var levels []dateLevel
+	for _, seg := range strings.Split(filepath.ToSlash(template), "/") {
+		var lv dateLevel
+		expr, last := "(?i)^", 0
+		for _, m := range tokenRE.FindAllStringIndex(seg, -1) {
+			tok := seg[m[0]:m[1]]
+			p, ok := patterns[tok]
+			if !ok {
+				return levels
+			}
+			expr += regexp.QuoteMeta(seg[last:m[0]]) + "(" + p + ")"
+			lv.tokens = append(lv.tokens, tok)
+			last = m[1]
+		}
+		lv.re = regexp.MustCompile(expr + regexp.QuoteMeta(seg[last:]) + "$")
+		levels = append(levels, lv)
+	}
+	return levels
+}
+
+// folderDate is the date a path below the date folders was filed under, by token.
+type folderDate map[string]string
+
+// month returns the month number of a {month} folder label, "" when it names none.
+func month(label string, names []string) string {
+	if len(label) >= 2 {
+		if n, err := strconv.Atoi(label[:2]); err == nil && n >= 1 && n <= 12 {
+			return label[:2]
+		}
+	}
+	for i, name := range names {
+		if strings.EqualFold(strings.TrimSpace(name), label) {
+			return fmt.Sprintf("%02d", i+1)
+		}
+	}
+	return ""
+}
+
+// Audit walks the archive at root and reports what it finds. Lume's own files and hidden entries are
+// left out. The files are read with a pool of workers, for their dates and, where another file has
+// the same size, their content; cancelling ctx stops it with what was found so far and ctx's error.
+func Audit(ctx context.Context, root string, opts Options) (Report, error) {
+	r := Report{Root: root}
+	levels := dateLevels(opts.Layout)
+	var dated []string
+	filed := make(map[string]folderDate)
+	bySize := make(map[int64][]string)
+	files := make(map[string]int) // folder -> files in its tree
+	var dirs []string
+	bad := make(map[string]bool) // folders reported as Folder, whose tree is not checked for dates
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || path == root {
+			return nil
+		}
+		parent := filepath.Dir(path)
+		fi, err := d.Info()
+		// Whatever is left out still keeps its folder from being empty.
+		if err != nil || fi.Mode()&os.ModeSymlink != 0 || metadata.OwnFile(d.Name()) || metadata.IsHidden(path, fi) {
+			count(files, root, parent)
+			if d.IsDir() && err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		parts := strings.Split(rel, string(filepath.Separator))
+		if d.IsDir() {
+			dirs = append(dirs, path)
+			depth := len(parts) - 1
+			if bad[parent] || parts[0] == metadata.UndatedFolder || depth >= len(levels) {
+				bad[path] = bad[parent]
+				return nil
+			}
+			if !levels[depth].re.MatchString(d.Name()) {
+				r.Findings = append(r.Findings, Finding{Kind: Folder, Path: path})
+				bad[path] = true
+			}
+			return nil
+		}
+		count(files, root, parent)
+		if !metadata.SupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		r.Files++
+		bySize[fi.Size()] = append(bySize[fi.Size()], path)
+		switch {
+		case bad[parent] || parts[0] == metadata.UndatedFolder:
+		case len(parts)-1 < len(levels):
+			r.Findings = append(r.Findings, Finding{Kind: Stray, Path: path})
+		case len(levels) > 0:
+			date := make(folderDate)
+			for i, lv := range levels {
+				m := lv.re.FindStringSubmatch(parts[i])
+				for j, tok := range lv.tokens {
+					date[tok] = m[j+1]
+				}
+			}
+			if date["{month}"] != "" {
+				date["{month}"] = month(date["{month}"], opts.Layout.MonthNames)
+			}
+			filed[path] = date
+			dated = append(dated, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return r, err
+	}
+	for _, dir := range dirs {
+		if files[dir] == 0 && (filepath.Dir(dir) == root || files[filepath.Dir(dir)] > 0) {
+			r.Findings = append(r.Findings, Finding{Kind: Empty, Path: dir})
+		}
+	}
+
+	var hashed []string
+	for _, paths := range bySize {
+		if len(paths) > 1 {
+			hashed = append(hashed, paths...)
+		}
+	}
+	total, done := len(dated)+len(hashed), 0
+	var mu sync.Mutex
+	step := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if opts.Progress != nil {
+			opts.Progress(done, total)
+		}
+	}
+	dates := make([]metadata.FileInfo, len(dated))
+	each(ctx, opts.Workers, len(dated), func(i int) {
+		if info, err := metadata.GetFileInfo(dated[i]); err == nil {
+			dates[i] = info
+		}
+		step()
+	})
+	hashes := make([]string, len(hashed))
+	each(ctx, opts.Workers, len(hashed), func(i int) {
+		hashes[i], _ = metadata.GetFileHashContext(ctx, hashed[i])
+		step()
+	})
+	if err := ctx.Err(); err != nil {
+		return r, err
+	}
+	for i, info := range dates {
+		if info.DateSource != metadata.DateFromExif && info.DateSource != metadata.DateFromVideo {
+			continue
+		}
+		own := folderDate{"{year}": info.Year, "{weekyear}": info.WeekYear, "{month}": info.Month, "{day}": info.Day, "{week}": info.Week}
+		for tok, v := range filed[dated[i]] {
+			if v != own[tok] {
+				info.Path = dated[i]
+				rel, _ := filepath.Rel(root, organizer.TargetDir(info, root, organizer.Layout{Template: datePrefix(levels, opts.Layout), MonthStyle: opts.Layout.MonthStyle, MonthNames: opts.Layout.MonthNames}))
+				r.Findings = append(r.Findings, Finding{Kind: Misdated, Path: dated[i], Detail: rel})
+				break
+			}
+		}
+	}
+	first := make(map[string]string)
+	order := make([]int, len(hashed))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return hashed[order[a]] < hashed[order[b]] })
+	for _, i := range order {
+		if hashes[i] == "" {
+			continue
+		}
+		if orig, ok := first[hashes[i]]; ok {
+			r.Findings = append(r.Findings, Finding{Kind: Duplicate, Path: hashed[i], Detail: orig})
+		} else {
+			first[hashes[i]] = hashed[i]
+		}
+	}
+
+	rank := make(map[string]int)
+	for i, k := range Kinds {
+		rank[k] = i
+	}
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Kind != b.Kind {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		return a.Path < b.Path
+	})
+	return r, nil
+}
+
+// count counts an entry of dir in dir and every folder above it up to root.
+func count(files map[string]int, root, dir string) {
+	for ; dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		files[dir]++
+	}
+}
+
+// datePrefix is the date folders of the layout's template.
+func datePrefix(levels []dateLevel, l organizer.Layout) string {
+	template := l.Template
+	if strings.TrimSpace(template) == "" {
+		template = organizer.DefaultTemplate
+	}
+	return strings.Join(strings.Split(filepath.ToSlash(template), "/")[:len(levels)], "/")
+}
+
+// each runs do for 0..n-1 on at most workers goroutines, stopping early when ctx is done.
+func each(ctx context.Context, workers, n int, do func(i int)) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				do(i)
+			}
+		}()
+	}
+feed:
+	for i := range n {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// FixResult reports what Fix repaired.
+type FixResult struct {
+	// Moved counts the strays filed; Duplicates those left where they are, being copies of a file
+	// already archived or of another stray; Removed the empty folders removed, their subfolders included.
+	Moved, Duplicates, Removed int
+	// Failed are the findings left as they were, with the error in Detail.
+	Failed []Finding
+	// Run is the run that filed the strays, for its history, checksums and the like.
+	Run engine.RunSummary
+}
+
+// Fix repairs the fixable findings of r: the strays are filed into the archive by a run of the
+// engine with opts, its target being the root, so they are recorded like any file a run archives;
+// each empty folder is then removed, from the bottom up, unless a file has turned up in it since.
+// Protected folders are left alone. Cancelling ctx stops it after the current file.
+func Fix(ctx context.Context, r Report, opts engine.Options, progress func(done, total int)) (FixResult, error) {
+	var res FixResult
+	var strays []string
+	for _, f := range r.Of(Stray) {
+		strays = append(strays, f.Path)
+	}
+	empty := r.Of(Empty)
+	total := len(strays) + len(empty)
+	if len(strays) > 0 {
+		// Imported files are skipped only where they were found; a stray is to be filed regardless.
+		opts.Target, opts.Imports = r.Root, nil
+		sum, err := engine.Run(ctx, engine.RunSpec{Pending: strays, Options: opts, Progress: stepper{total, progress}})
+		res.Run = sum
+		for o := range sum.All() {
+			switch {
+			case o.Failed() || o.Missing:
+				res.Failed = append(res.Failed, Finding{Kind: Stray, Path: o.Path, Detail: fmt.Sprint(o.Error)})
+			case o.DuplicateOf != "" || o.Success && leftInPlace(o):
+				res.Duplicates++
+			case o.Success:
+				res.Moved++
+			}
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+	protect := validator.NewProtection(opts.Protected)
+	for i, f := range empty {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if progress != nil {
+			progress(len(strays)+i, total)
+		}
+		n, err := removeEmpty(f.Path, protect)
+		res.Removed += n
+		if err != nil {
+			res.Failed = append(res.Failed, Finding{Kind: f.Kind, Path: f.Path, Detail: err.Error()})
+		}
+	}
+	if progress != nil {
+		progress(total, total)
+	}
+	return res, nil
+}
+
+// leftInPlace reports whether a file the run archived is still where it was: the organizer leaves a
+// copy of a file already in the archive and gives the path of that file.
+func leftInPlace(o engine.OrganizeResult) bool {
+	if o.Dest == o.Path {
+		return false
+	}
+	_, err := os.Lstat(o.Path)
+	return err == nil
+}
+
+// stepper tells progress of the files a Fix run is done with, out of all the findings fixed.
+type stepper struct {
+	total    int
+	progress func(done, total int)
+}
+
+func (s stepper) Phase(string) {}
+
+func (s stepper) Step(done, _ int, _ engine.OrganizeResult) {
+	if s.progress != nil {
+		s.progress(done, s.total)
+	}
+}
+
+// removeEmpty removes dir and the folders below it, deepest first, and counts those removed. os.Remove
+// leaves any folder that is no longer empty.
+func removeEmpty(dir string, protect *validator.Protection) (int, error) {
+	if err := protect.Check(dir); err != nil {
+		return 0, err
+	}
+	var dirs []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Remove(dirs[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"context"
+	"lume-go/internal/engine"
+	"lume-go/internal/organizer"
+	"lume-go/internal/sums"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// archive builds an archive of the default layout with one finding of each kind.
+func archive(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	write := func(rel, content string) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fixture := func(rel, name string) {
+		b, err := os.ReadFile(filepath.Join("..", "metadata", "testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		write(rel, string(b))
+	}
+	write("stray.jpg", "stray")
+	write("2020/05/Camera/a.jpg", "same")
+	write("2020/05-May/Phone/b.jpg", "same")
+	write("2020/05/Camera/notes.txt", "not media")
+	write("Vacation/c.jpg", "c")
+	write("2021/13/d.jpg", "d")
+	write("Undated/Other_Sorted/e.jpg", "e")
+	write(".lume_catalog.jsonl", "{}")
+	fixture("2020/05/Phone/late.webp", "exif.webp") // taken 2023-07-01
+	fixture("2024/02/Phone/leap.png", "exif.png")   // taken 2024-02-29
+	os.MkdirAll(filepath.Join(root, "2019", "06", "Camera"), 0755)
+	os.MkdirAll(filepath.Join(root, "2020", "07"), 0755)
+	return root
+}
+
+func TestAudit(t *testing.T) {
+	root := archive(t)
+	calls := 0
+	r, err := Audit(context.Background(), root, Options{Layout: organizer.Layout{}, Workers: 2, Progress: func(done, total int) { calls++ }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []Finding
+	for _, f := range r.Findings {
+		f.Path, _ = filepath.Rel(root, f.Path)
+		if f.Kind == Duplicate {
+			f.Detail, _ = filepath.Rel(root, f.Detail)
+		}
+		got = append(got, f)
+	}
+	p := filepath.FromSlash
+	want := []Finding{
+		{Kind: Stray, Path: "stray.jpg"},
+		{Kind: Folder, Path: p("2021/13")},
+		{Kind: Folder, Path: "Vacation"},
+		{Kind: Empty, Path: "2019"},
+		{Kind: Empty, Path: p("2020/07")},
+		{Kind: Misdated, Path: p("2020/05/Phone/late.webp"), Detail: p("2023/07")},
+		{Kind: Duplicate, Path: p("2020/05/Camera/a.jpg"), Detail: p("2020/05-May/Phone/b.jpg")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings:\n%v\nwant\n%v", got, want)
+	}
+	if r.Files != 8 || r.Count(Folder) != 2 || len(r.Of(Empty)) != 2 {
+		t.Errorf("files %d, counts %d %d", r.Files, r.Count(Folder), len(r.Of(Empty)))
+	}
+	if calls == 0 {
+		t.Error("no progress reported")
+	}
+}
+
+func TestAuditCancelled(t *testing.T) {
+	root := archive(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Audit(ctx, root, Options{}); err != context.Canceled {
+		t.Errorf("Audit = %v; want context.Canceled", err)
+	}
+}
+
+func TestFix(t *testing.T) {
+	root := archive(t)
+	r, err := Audit(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Fix(context.Background(), r, engine.Options{}, nil)
+	if err != nil || res.Moved != 1 || res.Removed != 4 || res.Failed != nil {
+		t.Fatalf("Fix = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "stray.jpg")); !os.IsNotExist(err) {
+		t.Error("stray left in the root")
+	}
+	again, err := Audit(context.Background(), root, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range again.Findings {
+		if Fixable(f.Kind) {
+			t.Errorf("left after Fix: %+v", f)
+		}
+	}
+	if again.Count(Folder) != 2 || again.Count(Duplicate) != 1 {
+		t.Errorf("Fix touched what it leaves alone: %+v", again.Findings)
+	}
+}
+
+func TestFixFilesStraysAsARun(t *testing.T) {
+	root := t.TempDir()
+	write := func(rel, content string) string {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	write("2020/05/Camera/a.jpg", "same")
+	copied := write("a copy.jpg", "same")
+	stray := write("new.jpg", "new")
+	mod := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	os.Chtimes(stray, mod, mod)
+	r, err := Audit(context.Background(), root, Options{})
+	if err != nil || r.Count(Stray) != 2 {
+		t.Fatalf("Audit = %+v, %v", r.Findings, err)
+	}
+	res, err := Fix(context.Background(), r, engine.Options{TargetIndex: true, Checksums: true}, nil)
+	if err != nil || res.Moved != 1 || res.Duplicates != 1 || res.Failed != nil {
+		t.Fatalf("Fix = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(copied); err != nil {
+		t.Error("copy of an archived file not left where it was")
+	}
+	for o := range res.Run.All() {
+		if o.Path == stray && filepath.Dir(filepath.Dir(o.Dest)) != filepath.Join(root, "2021", "03") {
+			t.Errorf("stray filed as %s", o.Dest)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "2021", sums.FileName)); err != nil {
+		t.Error("stray not recorded in the checksums of its year")
+	}
+}
@@ -0,0 +1,203 @@
+// Package cards notices memory cards and other removable drives with photos on them, so they can be
+// offered for import, and remembers when each card was last imported.
+package cards
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"lume-go/internal/metadata"
+)
+
+// Volume is a removable drive: its root (E:\), its label and its serial number, which tells one card
+// from another in the same slot.
+type Volume struct {
+	Root, Label, Serial string
+}
+
+// DCIM is the folder cameras keep their photos in.
+func (v Volume) DCIM() string { return filepath.Join(v.Root, "DCIM") }
+
+// Name is the label and drive of v for a prompt, e.g. "EOS_DIGITAL (E:)".
+func (v Volume) Name() string {
+	drive := strings.TrimRight(v.Root, `\/`)
+	if v.Label == "" {
+		return drive
+	}
+	return fmt.Sprintf("%s (%s)", v.Label, drive)
+}
+
+// Defaults of the watch.
+const (
+	// PollInterval is how often the drives are listed.
+	PollInterval = 2 * time.Second
+	// Settle is how long a drive must stay inserted before it is looked at, so a card pushed in and
+	// out again, or a reader still mounting it, is not probed.
+	Settle = 3 * time.Second
+)
+
+// Tracker debounces the drives listed by successive polls: a drive is reported once it has been
+// listed for Settle, then not again until it was removed and inserted anew.
+type Tracker struct {
+	Settle time.Duration
+	seen   map[Volume]sighting
+}
+
+type sighting struct {
+	since    time.Time
+	reported bool
+}
+
+// Update takes the drives listed at now and returns those that have just settled.
+func (t *Tracker) Update(now time.Time, vols []Volume) []Volume {
+	if t.seen == nil {
+		t.seen = make(map[Volume]sighting)
+	}
+	present := make(map[Volume]bool, len(vols))
+	var settled []Volume
+	for _, v := range vols {
+		present[v] = true
+		s, ok := t.seen[v]
+		if !ok {
+			s = sighting{since: now}
+		}
+		if !s.reported && now.Sub(s.since) >= t.Settle {
+			s.reported = true
+			settled = append(settled, v)
+		}
+		t.seen[v] = s
+	}
+	for v := range t.seen {
+		if !present[v] {
+			delete(t.seen, v)
+		}
+	}
+	return settled
+}
+
+// Watch lists the removable drives with list every interval until ctx is done, and calls found with
+// each drive that settled (see Tracker) and has a DCIM folder. Listing and probing happen in the
+// calling goroutine, as does found; run it in one of its own. A listing error goes to failed, if
+// set, and the watch goes on.
+func Watch(ctx context.Context, interval, settle time.Duration, list func() ([]Volume, error), found func(Volume), failed func(error)) {
+	t := &Tracker{Settle: settle}
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		vols, err := list()
+		if err != nil && failed != nil {
+			failed(err)
+		}
+		for _, v := range t.Update(time.Now(), vols) {
+			if st, err := os.Stat(v.DCIM()); err == nil && st.IsDir() {
+				found(v)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+// HasNew reports whether dir holds a photo or video modified after since; with a zero since, whether
+// it holds any. It stops at the first one found.
+func HasNew(dir string, since time.Time) bool {
+	found := false
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !metadata.SupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(since) {
+			found = true
+			return fs.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// MemoryFile is the card memory kept in the user's config directory.
+const MemoryFile = "cards.json"
+
+// MaxCards bounds the cards remembered; the ones imported longest ago are forgotten first.
+const MaxCards = 200
+
+// Memory remembers when each card, by its volume serial, was last imported. It is safe for concurrent use.
+type Memory struct {
+	mu    sync.Mutex
+	path  string
+	Cards map[string]time.Time `json:"cards"`
+}
+
+// LoadMemory reads the memory at path. A missing file remembers nothing; an unreadable one is
+// returned empty together with the error, and is replaced on the next Save.
+func LoadMemory(path string) (*Memory, error) {
+	m := &Memory{path: path, Cards: make(map[string]time.Time)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	} else if err != nil {
+		return m, err
+	}
+	var loaded Memory
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return m, err
+	}
+	for serial, t := range loaded.Cards {
+		m.Cards[serial] = t
+	}
+	return m, nil
+}
+
+// Imported is when the card with serial was last imported, zero if never.
+func (m *Memory) Imported(serial string) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Cards[serial]
+}
+
+// Mark records that the card with serial was imported at t.
+func (m *Memory) Mark(serial string, t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Cards[serial] = t
+	if len(m.Cards) <= MaxCards {
+		return
+	}
+	serials := make([]string, 0, len(m.Cards))
+	for s := range m.Cards {
+		serials = append(serials, s)
+	}
+	sort.Slice(serials, func(i, j int) bool { return m.Cards[serials[i]].Before(m.Cards[serials[j]]) })
+	for _, s := range serials[:len(serials)-MaxCards] {
+		delete(m.Cards, s)
+	}
+}
+
+// Save writes the memory back to its file.
+func (m *Memory) Save() error {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(m.path+".tmp", m.path)
+}
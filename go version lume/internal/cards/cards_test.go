@@ -0,0 +1,126 @@
+package cards
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTracker(t *testing.T) {
+	card, other := Volume{Root: `E:\`, Serial: "1234ABCD"}, Volume{Root: `E:\`, Serial: "5678EF01"}
+	start := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	at := func(s int) time.Time { return start.Add(time.Duration(s) * time.Second) }
+	tr := &Tracker{Settle: 3 * time.Second}
+
+	steps := []struct {
+		sec  int
+		vols []Volume
+		want []Volume
+	}{
+		{0, []Volume{card}, nil},
+		{2, []Volume{card}, nil},
+		{3, []Volume{card}, []Volume{card}},
+		{5, []Volume{card}, nil}, // reported once
+		{6, nil, nil},
+		// Pushed in and out again: the new insertion settles from scratch.
+		{7, []Volume{card}, nil},
+		{8, nil, nil},
+		{9, []Volume{card}, nil},
+		{12, []Volume{card}, []Volume{card}},
+		// Another card in the same slot is another volume.
+		{13, []Volume{other}, nil},
+		{16, []Volume{other}, []Volume{other}},
+	}
+	for _, s := range steps {
+		if got := tr.Update(at(s.sec), s.vols); !reflect.DeepEqual(got, s.want) {
+			t.Errorf("at %ds: %v; want %v", s.sec, got, s.want)
+		}
+	}
+}
+
+func TestHasNew(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "100CANON"), 0755)
+	old := time.Date(2024, 7, 1, 12, 0, 0, 0, time.Local)
+	for _, name := range []string{"100CANON/IMG_0001.JPG", "MISC/AUTPRINT.MRK"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, old, old)
+	}
+	if !HasNew(dir, time.Time{}) {
+		t.Error("a card never imported has nothing new")
+	}
+	if HasNew(dir, old) {
+		t.Error("a photo imported before counts as new")
+	}
+	// Only photos and videos count.
+	os.Chtimes(filepath.Join(dir, "MISC", "AUTPRINT.MRK"), old.Add(time.Hour), old.Add(time.Hour))
+	if HasNew(dir, old) {
+		t.Error("a print order counts as a new photo")
+	}
+	p := filepath.Join(dir, "100CANON", "IMG_0002.JPG")
+	os.WriteFile(p, []byte("y"), 0644)
+	os.Chtimes(p, old.Add(time.Hour), old.Add(time.Hour))
+	if !HasNew(dir, old) {
+		t.Error("a photo taken since is not new")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Lume", MemoryFile)
+	m, err := LoadMemory(path)
+	if err != nil || !m.Imported("1234ABCD").IsZero() {
+		t.Fatalf("empty memory: %v, %v", m.Cards, err)
+	}
+	when := time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+	m.Mark("1234ABCD", when)
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+	m, err = LoadMemory(path)
+	if err != nil || !m.Imported("1234ABCD").Equal(when) {
+		t.Errorf("read back %v, %v", m.Cards, err)
+	}
+
+	for i := 0; i < MaxCards; i++ {
+		m.Mark(string(rune('a'+i%26))+string(rune('a'+i/26)), when.Add(time.Duration(i+1)*time.Minute))
+	}
+	if len(m.Cards) != MaxCards || !m.Imported("1234ABCD").IsZero() {
+		t.Errorf("%d cards kept, oldest %v; want %d and the oldest forgotten", len(m.Cards), m.Imported("1234ABCD"), MaxCards)
+	}
+
+	os.WriteFile(path, []byte("{"), 0644)
+	if m, err := LoadMemory(path); err == nil || len(m.Cards) != 0 {
+		t.Errorf("corrupt memory: %v, %v", m.Cards, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	withPhotos, without := t.TempDir(), t.TempDir()
+	os.Mkdir(filepath.Join(withPhotos, "DCIM"), 0755)
+	vols := []Volume{{Root: withPhotos, Serial: "1"}, {Root: without, Serial: "2"}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var mu sync.Mutex
+	var found []Volume
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Watch(ctx, 5*time.Millisecond, 10*time.Millisecond, func() ([]Volume, error) { return vols, nil }, func(v Volume) {
+			mu.Lock()
+			found = append(found, v)
+			mu.Unlock()
+		}, nil)
+	}()
+	time.Sleep(80 * time.Millisecond)
+	cancel()
+	<-done
+	if !reflect.DeepEqual(found, vols[:1]) {
+		t.Errorf("found %v; want the drive with a DCIM folder, once", found)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package cards
+
+// Removable lists no drives: removable drives are only told apart on Windows.
+func Removable() ([]Volume, error) { return nil, nil }
@@ -0,0 +1,42 @@
+package cards
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// driveRemovable is the GetDriveType of memory cards and USB sticks.
+const driveRemovable = 2
+
+// Removable lists the removable drives with a medium in them. An empty card reader slot has no
+// volume information and is left out.
+func Removable() ([]Volume, error) {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	mask, _, err := kernel32.NewProc("GetLogicalDrives").Call()
+	if mask == 0 {
+		return nil, fmt.Errorf("list drives: %v", err)
+	}
+	getType, getInfo := kernel32.NewProc("GetDriveTypeW"), kernel32.NewProc("GetVolumeInformationW")
+	var vols []Volume
+	for i := 0; i < 26; i++ {
+		if mask&(1<<i) == 0 {
+			continue
+		}
+		root := string(rune('A'+i)) + `:\`
+		rootPtr, err := syscall.UTF16PtrFromString(root)
+		if err != nil {
+			continue
+		}
+		if t, _, _ := getType.Call(uintptr(unsafe.Pointer(rootPtr))); t != driveRemovable {
+			continue
+		}
+		label := make([]uint16, 261)
+		var serial uint32
+		if ok, _, _ := getInfo.Call(uintptr(unsafe.Pointer(rootPtr)), uintptr(unsafe.Pointer(&label[0])), uintptr(len(label)), uintptr(unsafe.Pointer(&serial)), 0, 0, 0, 0); ok == 0 {
+			continue
+		}
+		vols = append(vols, Volume{Root: root, Label: syscall.UTF16ToString(label), Serial: fmt.Sprintf("%08X", serial)})
+	}
+	return vols, nil
+}
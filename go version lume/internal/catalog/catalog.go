@@ -0,0 +1,336 @@
+package catalog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the catalog kept at the root of the target folder: a schema header line
+// followed by one JSON record per archived file.
+const FileName = ".lume_catalog.jsonl"
+
+// SchemaVersion is the record layout written by this build.
+const SchemaVersion = 1
+
+// migrations[v] upgrades a record from schema v to v+1. Version 0 files predate the
+// header line; their records already have the v1 shape.
+var migrations = []func(*Record){
+	0: func(*Record) {},
+}
+
+// Record describes one archived file.
+type Record struct {
+	Hash     string    `json:"hash"`
+	Original string    `json:"original"`
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Date     time.Time `json:"date"`
+	Device   string    `json:"device,omitempty"`
+	Source   string    `json:"source,omitempty"`
+	RunID    string    `json:"run_id"`
+	Archived time.Time `json:"archived"`
+}
+
+type header struct {
+	Schema int `json:"schema"`
+}
+
+// Filter selects records; zero fields match everything. Device and Source match case-insensitively
+// as substrings, so "pixel 7" finds "Pixel 7 Pro".
+type Filter struct {
+	From, To time.Time
+	Device   string
+	Source   string
+	RunID    string
+}
+
+// Stats summarises the catalog.
+type Stats struct {
+	Files    int
+	Bytes    int64
+	Runs     int
+	ByDevice map[string]int
+	BySource map[string]int
+}
+
+// Catalog appends records to the catalog file. Writes that fail (e.g. the file is momentarily
+// locked by another process) stay queued and are retried on the next Put, Flush or Close.
+type Catalog struct {
+	path    string
+	mu      sync.Mutex
+	pending []Record
+}
+
+// Open prepares the catalog under root, creating it or migrating an older schema in place.
+func Open(root string) (*Catalog, error) {
+	c := &Catalog{path: filepath.Join(root, FileName)}
+	if _, err := os.Stat(c.path); os.IsNotExist(err) {
+		return c, writeAll(c.path, nil)
+	}
+	version, records, err := readAll(c.path)
+	if err != nil {
+		return nil, err
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("catalog schema %d is newer than supported %d", version, SchemaVersion)
+	}
+	if version < SchemaVersion {
+		for i := range records {
+			for v := version; v < SchemaVersion; v++ {
+				migrations[v](&records[i])
+			}
+		}
+		if err := writeAll(c.path, records); err != nil {
+			return nil, fmt.Errorf("catalog migration: %w", err)
+		}
+		logger.Info("Catalog migrated from schema %d to %d (%d records)", version, SchemaVersion, len(records))
+	}
+	return c, nil
+}
+
+// Put queues a record and tries to write the queue. It never fails the caller's move;
+// write errors are logged and the records are kept for the next attempt.
+func (c *Catalog) Put(r Record) {
+	if r.Archived.IsZero() {
+		r.Archived = time.Now()
+	}
+	c.mu.Lock()
+	c.pending = append(c.pending, r)
+	c.mu.Unlock()
+	if err := c.Flush(); err != nil {
+		logger.Error("Catalog write deferred (%d queued): %v", c.Queued(), err)
+	}
+}
+
+// Queued reports how many records are still waiting to be written.
+func (c *Catalog) Queued() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Flush appends the queued records to the catalog file.
+func (c *Catalog) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pending) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, r := range c.pending {
+		line, _ := json.Marshal(r)
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	c.pending = c.pending[:0]
+	return nil
+}
+
+// Close flushes the queue, retrying briefly while the file stays locked.
+func (c *Catalog) Close() error {
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = c.Flush(); err == nil {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("catalog: %d records not written: %w", c.Queued(), err)
+}
+
+// Query returns the records matching f, oldest first, including records still queued.
+func (c *Catalog) Query(f Filter) ([]Record, error) {
+	_, records, err := readAll(c.path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	records = append(records, c.pending...)
+	c.mu.Unlock()
+
+	out := records[:0]
+	for _, r := range records {
+		if f.Match(r) {
+			out = append(out, r)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out, nil
+}
+
+// Match reports whether r passes the filter. To is inclusive up to the end of that day when it has no time part.
+func (f Filter) Match(r Record) bool {
+	if !f.From.IsZero() && r.Date.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() {
+		to := f.To
+		if to.Hour() == 0 && to.Minute() == 0 && to.Second() == 0 {
+			to = to.AddDate(0, 0, 1)
+		}
+		if !r.Date.Before(to) {
+			return false
+		}
+	}
+	if f.Device != "" && !strings.Contains(strings.ToLower(r.Device), strings.ToLower(f.Device)) {
+		return false
+	}
+	if f.Source != "" && !strings.Contains(strings.ToLower(r.Source), strings.ToLower(f.Source)) {
+		return false
+	}
+	return f.RunID == "" || r.RunID == f.RunID
+}
+
+// Stats summarises every record in the catalog.
+func (c *Catalog) Stats() (Stats, error) {
+	records, err := c.Query(Filter{})
+	if err != nil {
+		return Stats{}, err
+	}
+	s := Stats{ByDevice: map[string]int{}, BySource: map[string]int{}}
+	runs := map[string]bool{}
+	for _, r := range records {
+		s.Files++
+		s.Bytes += r.Size
+		s.ByDevice[r.Device]++
+		s.BySource[r.Source]++
+		runs[r.RunID] = true
+	}
+	s.Runs = len(runs)
+	return s, nil
+}
+
+// Rebuild recreates the catalog from the files under root for recovery. Dates and devices are taken
+// from the Year/Month/Device folder layout where present (numeric or number-name months), falling
+// back to the file time.
+func Rebuild(ctx context.Context, root string) (int, error) {
+	var records []Record
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || fi.IsDir() || metadata.OwnFile(fi.Name()) {
+			return nil
+		}
+		hash, err := metadata.GetFileHash(path)
+		if err != nil {
+			logger.Error("Catalog rebuild skipped %s: %v", path, err)
+			return nil
+		}
+		r := Record{Hash: hash, Path: path, Size: fi.Size(), Date: fi.ModTime(), RunID: "rebuild", Archived: fi.ModTime()}
+		if rel, err := filepath.Rel(root, path); err == nil {
+			parts := strings.Split(rel, string(filepath.Separator))
+			if len(parts) >= 3 {
+				// Month folders may carry a label after the number (01-Ocak).
+				month, _, _ := strings.Cut(parts[1], "-")
+				if t, err := time.ParseInLocation("2006/01", parts[0]+"/"+month, time.Local); err == nil {
+					r.Date = t
+				}
+			}
+			if len(parts) >= 4 {
+				r.Device = parts[2]
+			}
+		}
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(records), writeAll(filepath.Join(root, FileName), records)
+}
+
+// Remap points the records of files moved inside the archive at their new paths. A missing catalog is not an error.
+func Remap(root string, moves map[string]string) error {
+	path := filepath.Join(root, FileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) || len(moves) == 0 {
+		return nil
+	}
+	_, records, err := readAll(path)
+	if err != nil {
+		return err
+	}
+	for i, r := range records {
+		if to, ok := moves[filepath.Clean(r.Path)]; ok {
+			records[i].Path = to
+		}
+	}
+	return writeAll(path, records)
+}
+
+func readAll(path string) (int, []Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+
+	version := 0
+	var records []Record
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for first := true; sc.Scan(); first = false {
+		line := sc.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if first {
+			var h header
+			if json.Unmarshal(line, &h) == nil && h.Schema > 0 {
+				version = h.Schema
+				continue
+			}
+		}
+		var r Record
+		if err := json.Unmarshal(line, &r); err != nil {
+			logger.Error("Catalog: skipping unreadable record: %v", err)
+			continue
+		}
+		records = append(records, r)
+	}
+	return version, records, sc.Err()
+}
+
+// writeAll replaces the catalog atomically with a header and the given records.
+func writeAll(path string, records []Record) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	h, _ := json.Marshal(header{Schema: SchemaVersion})
+	w.Write(append(h, '\n'))
+	for _, r := range records {
+		line, _ := json.Marshal(r)
+		w.Write(append(line, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,134 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestPutAndQuery(t *testing.T) {
+	c, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Put(Record{Path: "a", Date: day("2023-03-03").Add(10 * time.Hour), Device: "Pixel 7", Source: "Camera", RunID: "r1", Size: 10})
+	c.Put(Record{Path: "b", Date: day("2023-03-04"), Device: "SM-G991B", Source: "WhatsApp", RunID: "r1", Size: 20})
+	c.Put(Record{Path: "c", Date: day("2023-03-02"), Device: "Pixel 7 Pro", RunID: "r2", Size: 30})
+
+	tests := []struct {
+		name string
+		f    Filter
+		want string
+	}{
+		{"all", Filter{}, "cab"},
+		{"single day", Filter{From: day("2023-03-03"), To: day("2023-03-03")}, "a"},
+		{"device substring", Filter{Device: "pixel 7"}, "ca"},
+		{"source", Filter{Source: "whatsapp"}, "b"},
+		{"run", Filter{RunID: "r2"}, "c"},
+	}
+	for _, tt := range tests {
+		got, err := c.Query(tt.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths string
+		for _, r := range got {
+			paths += r.Path
+		}
+		if paths != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, paths, tt.want)
+		}
+	}
+
+	s, err := c.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Files != 3 || s.Bytes != 60 || s.Runs != 2 || s.ByDevice["Pixel 7"] != 1 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestPutQueuesWhileUnwritable(t *testing.T) {
+	root := t.TempDir()
+	c, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replacing the file with a directory makes every append fail, like a lock held elsewhere.
+	os.Remove(c.path)
+	os.Mkdir(c.path, 0755)
+	c.Put(Record{Path: "queued"})
+	if c.Queued() != 1 {
+		t.Fatalf("queued = %d, want 1", c.Queued())
+	}
+
+	os.Remove(c.path)
+	if err := writeAll(c.path, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_, records, _ := readAll(c.path)
+	if len(records) != 1 || records[0].Path != "queued" {
+		t.Fatalf("records after flush = %+v", records)
+	}
+}
+
+func TestOpenMigratesHeaderlessCatalog(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, FileName)
+	os.WriteFile(path, []byte(`{"hash":"h","path":"x","run_id":"old"}`+"\n"), 0644)
+
+	c, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), `{"schema":1}`) {
+		t.Fatalf("catalog not migrated: %s", data)
+	}
+	got, _ := c.Query(Filter{RunID: "old"})
+	if len(got) != 1 {
+		t.Fatalf("migrated records = %+v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"schema":99}`+"\n"), 0644)
+	if _, err := Open(root); err == nil {
+		t.Fatal("newer schema opened without error")
+	}
+}
+
+func TestRebuildFromLayout(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "2021", "07", "Camera_Pixel 7")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "IMG_1.jpg"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(root, "stray.jpg"), []byte("y"), 0644)
+	labelled := filepath.Join(root, "2021", "08-Ağustos", "Camera")
+	os.MkdirAll(labelled, 0755)
+	os.WriteFile(filepath.Join(labelled, "IMG_2.jpg"), []byte("z"), 0644)
+
+	n, err := Rebuild(context.Background(), root)
+	if err != nil || n != 3 {
+		t.Fatalf("Rebuild = %d, %v", n, err)
+	}
+	c, _ := Open(root)
+	got, _ := c.Query(Filter{Device: "pixel"})
+	if len(got) != 1 || got[0].Date.Year() != 2021 || got[0].Date.Month() != 7 || got[0].Hash == "" {
+		t.Fatalf("rebuilt records = %+v", got)
+	}
+	got, _ = c.Query(Filter{Device: "camera"})
+	if len(got) != 2 || got[1].Date.Month() != 8 {
+		t.Fatalf("labelled month not parsed: %+v", got)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// BundleVersion is the version of the settings bundles Export writes. ReadBundle reads every
+// version up to it and refuses newer ones, whose settings this Lume may not know how to apply.
+const BundleVersion = 1
+
+// bundleApp marks a file as a Lume settings bundle.
+const bundleApp = "lume-settings"
+
+var (
+	// ErrNotBundle reports a file that is not a settings bundle.
+	ErrNotBundle = errors.New("not a Lume settings bundle")
+	// ErrNewerBundle reports a bundle a newer Lume wrote; see BundleError.
+	ErrNewerBundle = errors.New("settings bundle from a newer Lume")
+)
+
+// BundleError is a bundle of a version this Lume cannot read. It matches ErrNewerBundle.
+type BundleError struct {
+	Version int
+}
+
+func (e *BundleError) Error() string {
+	return fmt.Sprintf("%v: version %d, this Lume reads up to %d", ErrNewerBundle, e.Version, BundleVersion)
+}
+
+func (e *BundleError) Is(target error) bool { return target == ErrNewerBundle }
+
+// localKeys are the settings that stay on their machine: the window places and statistics, the hook,
+// whose URL or command may hold a token, and the report delivery with its mail account. A bundle never
+// carries them, so it holds no secrets.
+var localKeys = map[string]bool{"main_window": true, "results_window": true, "stats": true, "hook": true, "report": true}
+
+// pathKeys are the settings that name folders of one machine; a bundle carries them only when asked.
+var pathKeys = map[string]bool{"target_folder": true, "recent_targets": true, "schedule": true, "protected_paths": true}
+
+// Bundle is a portable copy of the settings, as Export writes it: the template, the device aliases,
+// the filters, the routing rules and the like, keyed as in the config file.
+type Bundle struct {
+	App      string                     `json:"app"`
+	Version  int                        `json:"version"`
+	Exported time.Time                  `json:"exported"`
+	Settings map[string]json.RawMessage `json:"settings"`
+}
+
+// Export writes the portable settings of c as a bundle. The folders of this machine are left out
+// unless paths is set.
+func Export(c Config, paths bool) ([]byte, error) {
+	settings, err := configKeys(c)
+	if err != nil {
+		return nil, err
+	}
+	for k := range settings {
+		if localKeys[k] || pathKeys[k] && !paths {
+			delete(settings, k)
+		}
+	}
+	data, err := json.MarshalIndent(Bundle{App: bundleApp, Version: BundleVersion, Exported: time.Now(), Settings: settings}, "", "  ")
+	return append(data, '\n'), err
+}
+
+// ReadBundle reads a bundle Export wrote, refusing other files with ErrNotBundle and bundles of newer
+// versions with a BundleError.
+func ReadBundle(data []byte) (Bundle, error) {
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil || b.App != bundleApp || b.Version < 1 {
+		return Bundle{}, ErrNotBundle
+	}
+	if b.Version > BundleVersion {
+		return Bundle{}, &BundleError{Version: b.Version}
+	}
+	return b, nil
+}
+
+// Apply returns c with the settings of the bundle in place of its own; the local ones of c are
+// kept whatever the bundle holds, and a setting the bundle does not name keeps its value. A value of
+// the wrong type fails the whole bundle, leaving c as it was.
+func (b Bundle) Apply(c Config) (Config, error) {
+	settings, err := configKeys(c)
+	if err != nil {
+		return c, err
+	}
+	for k, v := range b.Settings {
+		if !localKeys[k] {
+			settings[k] = v
+		}
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return c, err
+	}
+	var next Config
+	if err := json.Unmarshal(data, &next); err != nil {
+		return c, fmt.Errorf("%w: %v", ErrNotBundle, err)
+	}
+	next.applyDefaults()
+	return next, nil
+}
+
+// Change is a setting that differs between two configs, its values as JSON.
+type Change struct {
+	Key      string
+	Old, New string
+}
+
+// Diff lists the settings that differ between old and new, by key.
+func Diff(old, new Config) ([]Change, error) {
+	a, err := configKeys(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := configKeys(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []Change
+	for k, v := range b {
+		if !bytes.Equal(a[k], v) {
+			changes = append(changes, Change{Key: k, Old: string(a[k]), New: string(v)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes, nil
+}
+
+// configKeys is c as its config file keys it.
+func configKeys(c Config) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]json.RawMessage
+	return keys, json.Unmarshal(data, &keys)
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"lume-go/internal/hook"
+	"lume-go/internal/report"
+	"lume-go/internal/routing"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	desktop := Config{Language: "en", TargetFolder: `D:\Archive`, RecentTargets: []string{`D:\Archive`}, FolderTemplate: "{year}/{month}/{source}",
+		DeviceAliases: map[string]string{"SM-G991B": "Galaxy S21"}, Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: `E:\Video`}},
+		Hook: hook.Config{URL: "http://home/api/webhook/secret"}, Report: report.Config{SMTP: report.SMTP{Host: "smtp.example.com", User: "me"}}, Stats: Stats{TotalFiles: 10}}
+	data, err := Export(desktop, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, leak := range []string{"secret", `D:\\Archive`, "total_files", "smtp.example.com"} {
+		if strings.Contains(string(data), leak) {
+			t.Errorf("bundle carries %s:\n%s", leak, data)
+		}
+	}
+
+	b, err := ReadBundle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	laptop := Config{Language: "tr", TargetFolder: `C:\Photos`, DeviceAliases: map[string]string{"Pixel 7": "Phone"}, Hook: hook.Config{Command: "notify.exe"}}
+	laptop.applyDefaults()
+	next, err := b.Apply(laptop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.TargetFolder != `C:\Photos` || next.Hook.Command != "notify.exe" || next.Language != "en" || next.FolderTemplate != desktop.FolderTemplate ||
+		!reflect.DeepEqual(next.DeviceAliases, desktop.DeviceAliases) || !reflect.DeepEqual(next.Routes, desktop.Routes) {
+		t.Errorf("applied: %+v", next)
+	}
+	changes, _ := Diff(laptop, next)
+	var keys []string
+	for _, c := range changes {
+		keys = append(keys, c.Key)
+	}
+	if want := []string{"device_aliases", "folder_template", "language", "routes"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("diff keys = %v; want %v", keys, want)
+	}
+
+	// Asked to, the bundle carries the folders too.
+	data, _ = Export(desktop, true)
+	b, _ = ReadBundle(data)
+	if next, _ := b.Apply(laptop); next.TargetFolder != `D:\Archive` {
+		t.Errorf("target folder = %q", next.TargetFolder)
+	}
+}
+
+func TestReadBundleRefuses(t *testing.T) {
+	var newer *BundleError
+	if _, err := ReadBundle([]byte(`{"app": "lume-settings", "version": 99, "settings": {}}`)); !errors.Is(err, ErrNewerBundle) || !errors.As(err, &newer) || newer.Version != 99 {
+		t.Errorf("newer bundle: %v", err)
+	}
+	for _, data := range []string{`{"language": "en"}`, `not json`, `{"app": "lume-settings"}`} {
+		if _, err := ReadBundle([]byte(data)); !errors.Is(err, ErrNotBundle) {
+			t.Errorf("ReadBundle(%s) = %v", data, err)
+		}
+	}
+	b, _ := ReadBundle([]byte(`{"app": "lume-settings", "version": 1, "settings": {"max_files_limit": "many"}}`))
+	c := Config{MaxFilesLimit: 5}
+	if got, err := b.Apply(c); err == nil || got.MaxFilesLimit != 5 {
+		t.Errorf("mistyped setting applied: %v, %+v", err, got)
+	}
+}
@@ -2,8 +2,28 @@ package config
 
 import (
 	"encoding/json"
+	"lume-go/internal/history"
+	"lume-go/internal/hook"
+	"lume-go/internal/i18n"
+	"lume-go/internal/report"
+	"lume-go/internal/routing"
+	"lume-go/internal/schedule"
+	"lume-go/internal/units"
 	"os"
 	"path/filepath"
+	"strings"
+)
+
+// Defaults applied when the config file leaves a value unset.
+const (
+	DefaultMaxFilesLimit    = 10000
+	DefaultNearDupDistance  = 10
+	DefaultPHashMaxSize     = 25 * 1024 * 1024
+	DefaultBurstGap         = 1 // seconds
+	DefaultDeviceMinFiles   = 5
+	DefaultFileTimeout      = 300 // seconds
+	DefaultDateSuspectHours = 6
+	MaxRecentTargets        = 10
 )
 
 type Stats struct {
@@ -16,7 +36,182 @@ type Config struct {
 	DarkMode     bool   `json:"dark_mode"`
 	Language     string `json:"language"`
 	TargetFolder string `json:"target_folder"`
+	// RecentTargets lists the last used target folders, most recent first.
+	RecentTargets []string `json:"recent_targets"`
+	// MainWindow and ResultsWindow are where those windows were last closed.
+	MainWindow    Window `json:"main_window"`
+	ResultsWindow Window `json:"results_window"`
+	PruneEmpty   bool   `json:"prune_empty"`
+	TargetIndex  bool   `json:"target_index"`
+	Catalog      bool   `json:"catalog"`
+	// Checksums keeps a SHA256SUMS file in each year folder of the archive, checkable with sha256sum -c.
+	Checksums bool `json:"checksums"`
+	// MonthIndex keeps an index.json in each month folder of the archive listing its files with their
+	// date, device, source, size and MD5, for browsing and for other tools.
+	MonthIndex bool `json:"month_index"`
 	Stats        Stats  `json:"stats"`
+
+	// FolderTemplate lays out the archive, e.g. "{year}/{month}/{device}" or "{weekyear}/{week}"; empty means the default layout.
+	FolderTemplate string `json:"folder_template"`
+	// NameTemplate renames archived files, e.g. "{year}{month}{day}_{time}_{subsec}" or "{time}_{seq}"; empty keeps their names.
+	NameTemplate string `json:"name_template"`
+	// SourceDepth is how many levels of a nested source such as WhatsApp/Images/Sent the {source}
+	// token renders as folders; 0 renders the first.
+	SourceDepth int `json:"source_depth"`
+	// MonthStyle names month folders: "numeric" (01), "name" (Ocak) or "number_name" (01-Ocak).
+	// MonthNames, when it holds twelve entries, replaces the names of the app language.
+	MonthStyle string   `json:"month_style"`
+	MonthNames []string `json:"month_names"`
+	// DeviceAliases renames camera models for the device folders, e.g. "SM-G991B": "Galaxy S21". Keys
+	// ignore case and spacing and win over the built-in names.
+	DeviceAliases map[string]string `json:"device_aliases"`
+	// PoolDevices sends devices with fewer than DeviceMinFiles files in a month to that month's shared
+	// Other folder, e.g. the odd photo from a friend's phone.
+	PoolDevices    bool `json:"pool_devices"`
+	DeviceMinFiles int  `json:"device_min_files"`
+	// BurstMinCount photos of one device at most BurstGap seconds apart form a burst, rendered by the
+	// {burst} template token (e.g. "{year}/{month}/{burst}"); 0 turns burst detection off, as does
+	// any count below 2, which no burst can have.
+	BurstMinCount int `json:"burst_min_count"`
+	BurstGap      int `json:"burst_gap"`
+
+	// Routes send matching files to their own target folders, checked in order; unmatched files go to TargetFolder.
+	Routes []routing.Rule `json:"routes"`
+
+	// MinFileSize skips tiny files such as thumbnails, MaxFileSize skips huge ones; 0 turns a bound off.
+	// Both accept bytes or human units ("50KB", "2GB").
+	MinFileSize units.Size `json:"min_file_size"`
+	MaxFileSize units.Size `json:"max_file_size"`
+	// MinMegapixels skips images smaller than this many megapixels (memes, thumbnails); 0 is off.
+	// Images whose dimensions cannot be read are always kept.
+	MinMegapixels float64 `json:"min_megapixels"`
+
+	// LinkPolicy handles symbolic links and junctions in drops: "skip" (default), "follow_files" or "dereference".
+	LinkPolicy string `json:"link_policy"`
+
+	// ConflictStyle names a different file arriving under a taken name: "number" (default, _1, _2…) or
+	// "hash", which adds the first 8 hex digits of its content hash and so gives the same name every run.
+	ConflictStyle string `json:"conflict_style"`
+
+	// DateMinYear is the earliest capture year Lume believes (default 1990); earlier dates and dates in
+	// the future fall back to the next date source, and files without any plausible date go to Undated.
+	DateMinYear int `json:"date_min_year"`
+	// ClockOffsets correct the clock of a device over some days, one "device = ±hh:mm [from..to]" each,
+	// e.g. "Canon EOS 70D = +01:00 2024-07-01..2024-07-14" for a camera an hour behind on a trip.
+	ClockOffsets []string `json:"clock_offsets"`
+	// DateSuspectHours flags the files whose own date lies more than this many hours from their file
+	// times while their device's other files agree with theirs (default 6); negative turns it off.
+	DateSuspectHours int `json:"date_suspect_hours"`
+
+	// IncludeHidden also archives hidden and system files (Thumbs.db, dot-files); they are skipped by default.
+	IncludeHidden bool `json:"include_hidden"`
+
+	// CloudPolicy handles online-only OneDrive/Dropbox files: "skip" (default) or "hydrate", which downloads
+	// the ones that pass the filters after the pre-flight confirmation.
+	CloudPolicy string `json:"cloud_policy"`
+
+	// HardDelete removes files Lume deletes permanently; by default they go to the Recycle Bin.
+	HardDelete bool `json:"hard_delete"`
+
+	// MaxFilesLimit caps how many files are held in memory at once; larger drops run in batches.
+	MaxFilesLimit int `json:"max_files_limit"`
+
+	// NoCache stops the scan cache (metadata and hashes of unchanged source files) from being kept
+	// between sessions; ScanCacheMax caps its entries, 0 meaning the default.
+	NoCache      bool `json:"no_cache"`
+	ScanCacheMax int  `json:"scan_cache_max"`
+
+	// Schedule organizes a folder automatically while Lume runs, e.g. Downloads every night; the tray
+	// icon shows when the next run is due and reports each run.
+	Schedule schedule.Job `json:"schedule"`
+
+	// WatchCards offers the DCIM folder of a memory card or other removable drive for import when it is
+	// inserted while Lume runs.
+	WatchCards bool `json:"watch_cards"`
+	// RecheckImports organizes every file again, even those an earlier import archived and left on their
+	// card unchanged since.
+	RecheckImports bool `json:"recheck_imports"`
+
+	// Hook reports every finished run, manual, scheduled or headless, to a URL or a command, e.g.
+	// {"url": "http://homeassistant.local:8123/api/webhook/lume"}.
+	Hook hook.Config `json:"hook"`
+
+	// Report delivers the summary of every scheduled or headless run, with its failed files, to a drop
+	// folder, standard output or mail, e.g. {"drop": "C:\\Users\\me\\OneDrive\\Notes"}. The mail
+	// password is read from the environment variable smtp.password_env names.
+	Report report.Config `json:"report"`
+
+	// Anomalies tunes when a scheduled or command-line run is flagged as out of line with the earlier
+	// runs of its source, e.g. {"no_files_runs": 7} to warn after a week of nightly runs finding nothing.
+	Anomalies history.Thresholds `json:"anomalies"`
+
+	// NearDuplicates flags visually similar photos (perceptual hash within NearDupDistance bits) for review.
+	NearDuplicates  bool  `json:"near_duplicates"`
+	NearDupDistance int   `json:"near_dup_distance"`
+	PHashMaxSize    int64 `json:"phash_max_size"`
+
+	// FileTimeout bounds organizing one file, in seconds, plus a few seconds per megabyte. A file that
+	// takes longer, e.g. on a share that stopped answering, fails as timed out and the run goes on.
+	FileTimeout int `json:"file_timeout"`
+
+	// MoveWorkers is how many files are moved into each target at once; 0 tunes it per target by its
+	// drive, one stream to a removable drive, two to a network share and more to a fixed disk.
+	MoveWorkers int `json:"move_workers"`
+
+	// ProcessingOrder is the order runs process their files in, so a run cancelled halfway has archived
+	// the files that matter most: "input" (default, as dropped), "newest" or "oldest" by date, or
+	// "smallest" first. A run of several batches is put in order as a whole before any of them moves,
+	// so the archive comes out the same in every order, batched or not.
+	ProcessingOrder string `json:"processing_order"`
+
+	// NoDirIndex stops a file identical to one already in its target folder under another name, such
+	// as IMG_0001 (1).JPG beside IMG_0001.JPG, from counting as a duplicate of it.
+	NoDirIndex bool `json:"no_dir_index"`
+
+	// RateLimit caps the bytes per second runs copy, shared by all the files moving at once, e.g. "20MB"
+	// to leave room on the link to a NAS; 0 is no limit. BackgroundPriority runs them at the low IO
+	// priority of background work.
+	RateLimit          units.Size `json:"rate_limit"`
+	BackgroundPriority bool       `json:"background_priority"`
+
+	// InterruptedKeepDays is how long the results of a window run cut short by a crash or a reboot are
+	// kept once found, resumed or not; 0 keeps them runstate.DefaultKeepDays.
+	InterruptedKeepDays int `json:"interrupted_keep_days"`
+
+	// StagingDir is where files dropped from a phone over MTP, which Explorer only lends for the drop,
+	// are copied before they are queued; "" is a folder of the config directory. StagingMax caps the
+	// bytes staged at once, e.g. "20GB"; 0 is only bounded by the free space of its disk.
+	StagingDir string     `json:"staging_dir"`
+	StagingMax units.Size `json:"staging_max"`
+
+	// NoReconcile skips the check after every run that each archived file is at its destination with
+	// its size, a sample of them hashed again; ReconcileMaxFiles skips it for runs that archived more
+	// files than that, 0 meaning no limit.
+	NoReconcile       bool `json:"no_reconcile"`
+	ReconcileMaxFiles int  `json:"reconcile_max_files"`
+
+	// PreserveArchived keeps the folders of files dropped from another archive laid out like this one,
+	// e.g. an old backup, instead of laying them out again from their metadata.
+	PreserveArchived bool `json:"preserve_archived"`
+
+	// PreserveStructure files the dropped folders as they are laid out below the target rather than by
+	// date, files dropped on their own still by the template; a route with the template {structure}
+	// does so for its files alone.
+	PreserveStructure bool `json:"preserve_structure"`
+
+	// NoDerivatives files the edited copies phone editors save, such as IMG_E1234.JPG or IMG_1234~2.jpg,
+	// by their own date rather than beside their originals. DerivativePatterns add to the built-in
+	// names of such copies (see metadata.DerivativePatterns), each a regular expression whose group marks
+	// what the editor added to the original's name, e.g. "^.+(_bearbeitet)$".
+	NoDerivatives      bool     `json:"no_derivatives"`
+	DerivativePatterns []string `json:"derivative_patterns"`
+
+	// ProtectedPaths are folders Lume never writes into, nor anything below them, like folders holding
+	// a .lume_protected file: no run archives into them and reorganizing leaves them as they are.
+	ProtectedPaths []string `json:"protected_paths"`
+
+	// DebugLog also writes debug lines to the log, such as why an image's EXIF date was not used.
+	DebugLog bool `json:"debug_log"`
 }
 
 func getConfigPath() string {
@@ -27,23 +222,61 @@ func getConfigPath() string {
 	return filepath.Join(filepath.Dir(exe), "lume_config.json")
 }
 
+// DataPath names a file Lume keeps in the user's config directory (%AppData%\Lume on Windows), such
+// as the scan cache and the history.
+func DataPath(name string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Lume", name), nil
+}
+
 func LoadConfig() Config {
 	path := getConfigPath()
 	file, err := os.ReadFile(path)
 	if err != nil {
-		return Config{Language: "tr"}
+		return Config{Language: "tr", MaxFilesLimit: DefaultMaxFilesLimit, NearDupDistance: DefaultNearDupDistance, PHashMaxSize: DefaultPHashMaxSize, BurstGap: DefaultBurstGap, DeviceMinFiles: DefaultDeviceMinFiles, FileTimeout: DefaultFileTimeout}
 	}
 	
 	var conf Config
 	json.Unmarshal(file, &conf)
-	
-	if conf.Language != "tr" && conf.Language != "en" {
+	conf.applyDefaults()
+	return conf
+}
+
+// applyDefaults fills in the values a config file left unset or invalid.
+func (conf *Config) applyDefaults() {
+	if !i18n.Has(conf.Language) {
 		conf.Language = "tr"
 	}
-	
-	return conf
+	if conf.MaxFilesLimit <= 0 {
+		conf.MaxFilesLimit = DefaultMaxFilesLimit
+	}
+	if conf.NearDupDistance <= 0 {
+		conf.NearDupDistance = DefaultNearDupDistance
+	}
+	if conf.PHashMaxSize <= 0 {
+		conf.PHashMaxSize = DefaultPHashMaxSize
+	}
+	if conf.BurstGap <= 0 {
+		conf.BurstGap = DefaultBurstGap
+	}
+	if conf.BurstMinCount < 2 {
+		conf.BurstMinCount = 0
+	}
+	if conf.DeviceMinFiles <= 0 {
+		conf.DeviceMinFiles = DefaultDeviceMinFiles
+	}
+	if conf.FileTimeout <= 0 {
+		conf.FileTimeout = DefaultFileTimeout
+	}
+	if conf.DateSuspectHours == 0 {
+		conf.DateSuspectHours = DefaultDateSuspectHours
+	}
 }
 
+// SaveConfig writes the config atomically, so a crash mid-write never leaves a truncated file behind.
 func SaveConfig(conf Config) error {
 	path := getConfigPath()
 	data, err := json.MarshalIndent(conf, "", "  ")
@@ -51,5 +284,31 @@ func SaveConfig(conf Config) error {
 		return err
 	}
 	
-	return os.WriteFile(path, data, 0644)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// RememberTarget moves path to the front of RecentTargets, keeping at most MaxRecentTargets entries.
+func (c *Config) RememberTarget(path string) {
+	if path == "" {
+		return
+	}
+	c.ForgetTarget(path)
+	c.RecentTargets = append([]string{path}, c.RecentTargets...)
+	if len(c.RecentTargets) > MaxRecentTargets {
+		c.RecentTargets = c.RecentTargets[:MaxRecentTargets]
+	}
+}
+
+// ForgetTarget removes path from RecentTargets. Windows paths compare case-insensitively.
+func (c *Config) ForgetTarget(path string) {
+	out := c.RecentTargets[:0]
+	for _, p := range c.RecentTargets {
+		if !strings.EqualFold(filepath.Clean(p), filepath.Clean(path)) {
+			out = append(out, p)
+		}
+	}
+	c.RecentTargets = out
 }
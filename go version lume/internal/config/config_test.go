@@ -0,0 +1,29 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestRecentTargets(t *testing.T) {
+	var c Config
+	c.RememberTarget(`D:\Archive`)
+	c.RememberTarget(`E:\Video`)
+	c.RememberTarget(`d:\archive`) // same folder, different case
+	if want := []string{`d:\archive`, `E:\Video`}; !reflect.DeepEqual(c.RecentTargets, want) {
+		t.Fatalf("RecentTargets = %q, want %q", c.RecentTargets, want)
+	}
+
+	for i := 0; i < 15; i++ {
+		c.RememberTarget(fmt.Sprintf(`F:\T%d`, i))
+	}
+	if len(c.RecentTargets) != MaxRecentTargets || c.RecentTargets[0] != `F:\T14` {
+		t.Fatalf("RecentTargets = %q", c.RecentTargets)
+	}
+
+	c.ForgetTarget(`F:\T14`)
+	if c.RecentTargets[0] != `F:\T13` || len(c.RecentTargets) != MaxRecentTargets-1 {
+		t.Errorf("after ForgetTarget: %q", c.RecentTargets)
+	}
+}
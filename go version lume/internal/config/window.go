@@ -0,0 +1,43 @@
+package config
+
+// Rect is a rectangle on the screen, in pixels.
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Window is where a window was when it was last closed: its restored bounds and whether it was
+// maximized. The zero value means nothing was saved.
+type Window struct {
+	Rect
+	Maximized bool `json:"maximized"`
+}
+
+// Saved reports whether w holds a placement.
+func (w Window) Saved() bool {
+	return w.Width > 0 && w.Height > 0
+}
+
+// Fit moves and shrinks r to lie within area, e.g. the work area of the monitor nearest to where a
+// window was saved, which may since have been disconnected or changed resolution. r keeps at least
+// minW×minH unless area itself is smaller.
+func (r Rect) Fit(area Rect, minW, minH int) Rect {
+	r.Width = clamp(r.Width, minW, area.Width)
+	r.Height = clamp(r.Height, minH, area.Height)
+	r.X = clamp(r.X, area.X, area.X+area.Width-r.Width)
+	r.Y = clamp(r.Y, area.Y, area.Y+area.Height-r.Height)
+	return r
+}
+
+// clamp limits v to [lo, hi]; when the range is empty, hi wins.
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		v = lo
+	}
+	if v > hi {
+		v = hi
+	}
+	return v
+}
@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestRectFit(t *testing.T) {
+	primary := Rect{0, 0, 1920, 1040}
+	second := Rect{1920, -200, 2560, 1400} // right of the primary, taller and higher up
+	tests := []struct {
+		name  string
+		saved Rect
+		area  Rect
+		want  Rect
+	}{
+		{"inside", Rect{100, 100, 800, 600}, primary, Rect{100, 100, 800, 600}},
+		{"on the second monitor", Rect{2500, -100, 900, 700}, second, Rect{2500, -100, 900, 700}},
+		{"monitor gone, nearest is left of it", Rect{2500, 300, 900, 700}, primary, Rect{1020, 300, 900, 700}},
+		{"above the top", Rect{100, -500, 800, 600}, primary, Rect{100, 0, 800, 600}},
+		{"left of the desktop", Rect{-3000, 200, 800, 600}, primary, Rect{0, 200, 800, 600}},
+		{"larger than the screen", Rect{-50, -50, 4000, 3000}, primary, Rect{0, 0, 1920, 1040}},
+		{"below the minimum", Rect{100, 100, 100, 80}, primary, Rect{100, 100, 420, 450}},
+		{"screen below the minimum", Rect{0, 0, 800, 600}, Rect{0, 0, 400, 300}, Rect{0, 0, 400, 300}},
+		{"off the bottom right", Rect{1800, 1000, 800, 600}, primary, Rect{1120, 440, 800, 600}},
+	}
+	for _, tt := range tests {
+		if got := tt.saved.Fit(tt.area, 420, 450); got != tt.want {
+			t.Errorf("%s: Fit = %+v; want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWindowSaved(t *testing.T) {
+	if (Window{}).Saved() || (Window{Rect: Rect{X: 10, Width: 500}}).Saved() {
+		t.Error("an empty placement counts as saved")
+	}
+	if !(Window{Rect: Rect{Width: 500, Height: 400}}).Saved() {
+		t.Error("a placement was not recognized")
+	}
+}
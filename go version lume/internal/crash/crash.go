@@ -0,0 +1,85 @@
+// Package crash writes the report of a panic that would otherwise end Lume without a trace the user
+// can send in.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// Info describes the running program for a report.
+type Info struct {
+	App     string
+	Version string
+	OS      string
+	// Config is the settings in effect; Write redacts the paths and addresses in it.
+	Config any
+}
+
+// Write saves a report of the panic value and its stack as crash-<time>.txt in dir and returns its
+// path.
+func Write(dir string, info Info, value any, stack []byte, now time.Time) (string, error) {
+	conf, err := json.MarshalIndent(Redact(info.Config), "", "  ")
+	if err != nil {
+		conf = []byte(fmt.Sprintf("unavailable: %v", err))
+	}
+	report := fmt.Sprintf("%s %s crash report\nTime: %s\nOS: %s\nPanic: %v\n\nStack:\n%s\nConfig:\n%s\n",
+		info.App, info.Version, now.Format(time.RFC3339), info.OS, value, stack, conf)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+".txt")
+	if err := os.WriteFile(path, []byte(report), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Redacted replaces the strings Redact leaves out.
+const Redacted = "<redacted>"
+
+// sensitive matches strings that may name the user's folders or carry a token: paths (C:\…, \\server,
+// /home/…, anything with a separator) and URLs.
+var sensitive = regexp.MustCompile(`^[A-Za-z]:|[\\/]|://`)
+
+// Redact returns v, as JSON would encode it, with every sensitive string value and map key replaced
+// by Redacted. Numbers, booleans and other strings are kept, as they are what a crash usually
+// depends on.
+func Redact(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+	return redact(generic)
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case string:
+		if sensitive.MatchString(v) {
+			return Redacted
+		}
+	case []any:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, x := range v {
+			if sensitive.MatchString(k) {
+				k = Redacted
+			}
+			out[k] = redact(x)
+		}
+		return out
+	}
+	return v
+}
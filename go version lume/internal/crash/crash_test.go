@@ -0,0 +1,44 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type conf struct {
+	Language string            `json:"language"`
+	Target   string            `json:"target"`
+	Recent   []string          `json:"recent"`
+	Limit    int               `json:"limit"`
+	Hook     string            `json:"hook"`
+	Aliases  map[string]string `json:"aliases"`
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crash")
+	c := conf{Language: "en", Target: `D:\Photos\Family`, Recent: []string{`\\nas\share`, "/home/ayse/pics"}, Limit: 10000,
+		Hook: "https://example.org/hook?token=secret", Aliases: map[string]string{"SM-G991B": "Galaxy S21", `C:\odd`: "x"}}
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	path, err := Write(dir, Info{App: "Lume", Version: "2.1", OS: "windows 10.0", Config: c}, "index out of range", []byte("goroutine 1 [running]:\nmain.main()"), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "crash-20240506-070809.txt" {
+		t.Errorf("report written to %s", path)
+	}
+	data, _ := os.ReadFile(path)
+	report := string(data)
+	for _, want := range []string{"Lume 2.1", "windows 10.0", "index out of range", "main.main()", `"language": "en"`, `"limit": 10000`, `"SM-G991B": "Galaxy S21"`} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report lacks %q:\n%s", want, report)
+		}
+	}
+	for _, leak := range []string{"Photos", "nas", "ayse", "secret", "odd"} {
+		if strings.Contains(report, leak) {
+			t.Errorf("report leaks %q:\n%s", leak, report)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// Update is what a Batcher hands on: the latest phase or count, and the results since the last update.
+type Update struct {
+	Phase       string
+	Done, Total int
+	Results     []OrganizeResult
+}
+
+// Batcher is a Progress that coalesces a run's steps into at most one Update per interval, so a
+// window is not flooded with thousands of small files a minute. An update is always delivered when the
+// phase changes, when the last file is done and on Close, so the final state is never lost.
+type Batcher struct {
+	interval time.Duration
+	flush    func(Update)
+	now      func() time.Time
+
+	mu      sync.Mutex
+	pending Update
+	dirty   bool
+	last    time.Time
+}
+
+// NewBatcher returns a Batcher that passes updates to flush at most once per interval.
+func NewBatcher(interval time.Duration, flush func(Update)) *Batcher {
+	return &Batcher{interval: interval, flush: flush, now: time.Now}
+}
+
+func (b *Batcher) Phase(name string) {
+	b.mu.Lock()
+	b.pending.Phase = name
+	b.dirty = true
+	b.mu.Unlock()
+	b.send(true)
+}
+
+func (b *Batcher) Step(done, total int, res OrganizeResult) {
+	b.mu.Lock()
+	b.pending.Phase = ""
+	b.pending.Done, b.pending.Total = done, total
+	b.pending.Results = append(b.pending.Results, res)
+	b.dirty = true
+	b.mu.Unlock()
+	b.send(done >= total)
+}
+
+// Close delivers whatever is still held back. Call it once the run has returned.
+func (b *Batcher) Close() { b.send(true) }
+
+func (b *Batcher) send(force bool) {
+	b.mu.Lock()
+	now := b.now()
+	if !b.dirty || (!force && now.Sub(b.last) < b.interval) {
+		b.mu.Unlock()
+		return
+	}
+	u := b.pending
+	b.pending.Results, b.dirty, b.last = nil, false, now
+	b.mu.Unlock()
+	b.flush(u)
+}
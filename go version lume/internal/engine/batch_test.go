@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func TestBatcher(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	var got []Update
+	b := NewBatcher(100*time.Millisecond, func(u Update) { got = append(got, u) })
+	b.now = clock.now
+
+	// 50 files 10ms apart: one update at the first file, then one every 100ms.
+	for i := 1; i <= 50; i++ {
+		b.Step(i, 60, OrganizeResult{File: "f"})
+		clock.t = clock.t.Add(10 * time.Millisecond)
+	}
+	if len(got) != 5 {
+		t.Fatalf("%d updates for 50 files over 500ms, want 5", len(got))
+	}
+	results := 0
+	for _, u := range got {
+		results += len(u.Results)
+	}
+	if last := got[len(got)-1]; last.Done != 41 || results != 41 {
+		t.Fatalf("last update at %d with %d results, want 41", last.Done, results)
+	}
+
+	// A phase change goes out at once; Close delivers the files held back since.
+	b.Phase(PhaseIndexing)
+	if got[len(got)-1].Phase != PhaseIndexing || len(got[len(got)-1].Results) != 9 {
+		t.Fatalf("phase update = %+v", got[len(got)-1])
+	}
+	b.Step(51, 60, OrganizeResult{})
+	b.Close()
+	b.Close()
+	if last := got[len(got)-1]; len(got) != 7 || last.Done != 51 || last.Phase != "" || len(last.Results) != 1 {
+		t.Fatalf("after Close: %d updates, last %+v", len(got), last)
+	}
+
+	// The last file of the run is delivered without waiting for the interval.
+	b.Step(60, 60, OrganizeResult{})
+	if last := got[len(got)-1]; len(got) != 8 || last.Done != 60 {
+		t.Fatalf("final step: %d updates, last %+v", len(got), last)
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Filters pick the files a run takes from its sources, as the config sets them.
+type Filters struct {
+	// MinSize and MaxSize bound the file size; 0 turns a bound off.
+	MinSize, MaxSize int64
+	// MinMegapixels leaves out smaller images; images whose dimensions cannot be read pass.
+	MinMegapixels float64
+	Links         string
+	IncludeHidden bool
+	// Hydrate also takes online-only cloud files, which downloads them when they are read.
+	Hydrate bool
+	// OnSkip, when set, is told about every file the filters leave out, with one of the Skip reasons.
+	OnSkip func(path, reason string)
+}
+
+// Reasons a file is left out for, as Filters.OnSkip is told.
+const (
+	SkipSize       = "size"
+	SkipMegapixels = "megapixels"
+	SkipCloud      = "cloud"
+	SkipLink       = "link"
+	SkipHidden     = "hidden"
+)
+
+func (f Filters) skip(path, reason string) {
+	if f.OnSkip != nil {
+		f.OnSkip(path, reason)
+	}
+}
+
+// Size applies the size bounds.
+func (f Filters) Size(n int64) bool {
+	return n >= f.MinSize && (f.MaxSize <= 0 || n <= f.MaxSize)
+}
+
+// Megapixels applies MinMegapixels from the image header.
+func (f Filters) Megapixels(path string) bool {
+	if f.MinMegapixels <= 0 {
+		return true
+	}
+	w, h, err := metadata.ImageSize(path)
+	return err != nil || (metadata.FileInfo{Width: w, Height: h}).Megapixels() >= f.MinMegapixels
+}
+
+// IsTarget reports whether dir is one of targets. A source folder holding a target is scanned around
+// it, so archived files are not picked up again.
+func IsTarget(dir string, targets []string) bool {
+	for _, t := range targets {
+		if validator.SameFile(dir, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Collect lists the supported files under each source (a folder or a single file) that pass the
+// filters, staying out of the targets, and adds up their size. Files the filters leave out go to
+// OnSkip.
+func Collect(sources, targets []string, f Filters) ([]string, int64) {
+	var files []string
+	var total int64
+	add := func(path string) {
+		if err := validator.CheckPath(path); err != nil {
+			logger.Info("Skipped: %v", err)
+			return
+		}
+		st, err := os.Stat(path)
+		switch {
+		case err != nil:
+			return
+		case metadata.IsPlaceholder(st) && !f.Hydrate:
+			f.skip(path, SkipCloud)
+			return
+		case !f.Size(st.Size()):
+			f.skip(path, SkipSize)
+			return
+		case !metadata.IsPlaceholder(st) && !f.Megapixels(path):
+			f.skip(path, SkipMegapixels)
+			return
+		}
+		files = append(files, path)
+		total += st.Size()
+	}
+	opts := metadata.ScanOptions{Links: f.Links, SkipHidden: !f.IncludeHidden,
+		SkipDir:  func(dir string) bool { return IsTarget(dir, targets) },
+		OnLink:   func(path string) { f.skip(path, SkipLink) },
+		OnHidden: func(path string) { f.skip(path, SkipHidden) }}
+	for _, src := range sources {
+		if st, err := os.Stat(src); err == nil && !st.IsDir() {
+			switch {
+			case !metadata.SupportedExtensions[strings.ToLower(filepath.Ext(src))]:
+			case !f.IncludeHidden && metadata.IsHidden(src, st):
+				f.skip(src, SkipHidden)
+			default:
+				add(src)
+			}
+			continue
+		}
+		metadata.ScanDir(src, opts, add)
+	}
+	return files, total
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lume-go/internal/fsys"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+)
+
+// destination is where moveOne files info before any conflict is resolved: the target base and the
+// move options for it. A file already organized stays in place; otherwise, when opts.PreserveArchived
+// keeps the archive's own folders, kept names them. A file keeping the structure of its source (see
+// Options.PreserveStructure) is filed by it alone; an edited copy found beside its original (see
+// placeDerivatives) goes where the original is filed.
+func destination(info metadata.FileInfo, rt *routes, mo organizer.Options, opts Options) (base string, o organizer.Options, inPlace bool, kept string) {
+	base, o = rt.options(info, mo)
+	if _, template := rt.router.Route(info); template == organizer.StructureTemplate || template == "" && opts.PreserveStructure {
+		if template != "" {
+			o.Layout.Template = mo.Layout.Template
+		}
+		if l, ok := organizer.Structure(info.Path, opts.Roots, o.Layout); ok {
+			o.Layout = l
+			return base, o, organizer.InPlace(info, base, o.Layout), ""
+		}
+	}
+	if info.Beside != "" {
+		o.Layout.Folders = info.Beside
+	}
+	if organizer.InPlace(info, base, o.Layout) {
+		return base, o, true, ""
+	}
+	if opts.PreserveArchived && info.Beside == "" {
+		// Like a route's template, the archive's own folders replace the layout's.
+		if rel, ok := organizer.ArchivedLayout(info, o.Layout); ok {
+			kept = filepath.ToSlash(rel)
+			o.Layout.Template = kept
+		}
+	}
+	return base, o, false, kept
+}
+
+// twins are the files of a run that would take the same name in the archive.
+type twins struct {
+	// content keys each colliding file by its name in the archive and its content; files of one
+	// content are copies of each other. moved holds the result of the first of each content moved.
+	content map[string]string
+	moved   map[string]OrganizeResult
+}
+
+// settleCollisions orders the files of a run that would take the same archive name by their source
+// path, so which of them gets the name and which a suffix (see organizer.Options.Conflicts) no longer
+// depends on the order they were dropped or read in. Each group moves together, where its first file
+// was in the run. Copies with the same content are found here too: only the first is moved, the
+// others are reported as its duplicates (see twins.landed). Files already in place are left out, as
+// they do not move. The files are reordered in place.
+func settleCollisions(ctx context.Context, files []metadata.FileInfo, rt *routes, mo organizer.Options, opts Options) twins {
+	groups := make(map[string][]int)
+	var keys []string
+	for i, info := range files {
+		base, o, inPlace, _ := destination(info, rt, mo, opts)
+		if inPlace {
+			continue
+		}
+		name := organizer.SanitizeFileName(organizer.FileName(info, o.Layout))
+		// Windows names are not case sensitive.
+		key := strings.ToLower(filepath.Join(organizer.TargetDir(info, base, o.Layout), name))
+		if groups[key] == nil {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], i)
+	}
+	tw := twins{content: make(map[string]string), moved: make(map[string]OrganizeResult)}
+	first := make(map[int]string) // the index of each colliding group's first file
+	for _, key := range keys {
+		g := groups[key]
+		if len(g) < 2 {
+			continue
+		}
+		first[g[0]] = key
+		for _, i := range g {
+			tw.content[files[i].Path] = key + "\x00" + contentKey(ctx, &files[i], opts.FS)
+		}
+		logger.Info("%d files would be archived as %s; filed in the order of their paths", len(g), key)
+	}
+	if len(first) == 0 {
+		return tw
+	}
+	ordered := make([]metadata.FileInfo, 0, len(files))
+	for i, info := range files {
+		key, lead := first[i]
+		if _, collides := tw.content[info.Path]; collides && !lead {
+			continue
+		}
+		if !lead {
+			ordered = append(ordered, info)
+			continue
+		}
+		g := groups[key]
+		sort.Slice(g, func(a, b int) bool { return files[g[a]].Path < files[g[b]].Path })
+		for _, j := range g {
+			ordered = append(ordered, files[j])
+		}
+	}
+	copy(files, ordered)
+	return tw
+}
+
+// contentKey tells the contents of colliding files apart: by their hash, or by their size for cloud
+// placeholders, which must not be read. A file that cannot be hashed gets its path, matching none.
+func contentKey(ctx context.Context, info *metadata.FileInfo, fs fsys.FS) string {
+	if info.Placeholder {
+		return "size:" + strconv.FormatInt(info.Size, 10)
+	}
+	if info.MD5 != "" {
+		return info.MD5
+	}
+	h, err := info.EnsureHash(ctx, fsys.Or(fs), metadata.HashMD5)
+	if err != nil {
+		logger.Error("Hash for the name collision of %s failed: %v", info.Path, err)
+		return "path:" + info.Path
+	}
+	return h
+}
+
+// landed returns the result of a copy of info moved earlier in the run, if one was.
+func (tw twins) landed(info metadata.FileInfo) (OrganizeResult, bool) {
+	key, ok := tw.content[info.Path]
+	if !ok {
+		return OrganizeResult{}, false
+	}
+	r, ok := tw.moved[key]
+	return r, ok
+}
+
+// land records r, the result of info, so the copies of info that follow are its duplicates. A copy
+// that failed does not count: the next one is tried instead.
+func (tw twins) land(info metadata.FileInfo, r OrganizeResult) {
+	if key, ok := tw.content[info.Path]; ok && r.Success {
+		if _, done := tw.moved[key]; !done {
+			tw.moved[key] = r
+		}
+	}
+}
+
+// duplicateOf is the result of info, a copy of the file r moved: archived where r is, its source kept.
+func duplicateOf(info metadata.FileInfo, r OrganizeResult) OrganizeResult {
+	logger.Info("%s is a copy of the queued %s, archived as %s; left in place", info.Path, r.Path, r.Dest)
+	return OrganizeResult{Success: true, File: info.Filename, Path: info.Path, Dest: r.Dest, Target: r.Target, Size: info.Size, Width: info.Width, Height: info.Height, Date: r.Date,
+		Source: info.Source, Device: r.Device, DateSource: r.DateSource, MD5: r.MD5, DuplicateOf: r.Path}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"lume-go/internal/organizer"
+)
+
+// tree lists the files under dir with their content.
+func tree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			rel, _ := filepath.Rel(dir, path)
+			b, _ := os.ReadFile(path)
+			files[filepath.ToSlash(rel)] = string(b)
+		}
+		return nil
+	})
+	return files
+}
+
+func TestRunCollisionsDeterministic(t *testing.T) {
+	// Four files of one month that would all be archived as IMG_0001.JPG, two of them copies.
+	sources := []struct{ path, content string }{
+		{"DCIM/IMG_0001.JPG", "first photo"},
+		{"Backup/IMG_0001.JPG", "another photo"},
+		{"Copy/IMG_0001.JPG", "first photo"},
+		{"Other/IMG_0001.JPG", "a third photo"},
+	}
+	run := func(conflicts string, workers int, reverse bool) (map[string]string, RunSummary, string) {
+		src, target := t.TempDir(), t.TempDir()
+		var paths []string
+		for _, s := range sources {
+			p := filepath.Join(src, filepath.FromSlash(s.path))
+			os.MkdirAll(filepath.Dir(p), 0755)
+			os.WriteFile(p, []byte(s.content), 0644)
+			mtime := time.Date(2023, 5, 6, 12, 0, 0, 0, time.Local)
+			os.Chtimes(p, mtime, mtime)
+			paths = append(paths, p)
+		}
+		if reverse {
+			for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+				paths[i], paths[j] = paths[j], paths[i]
+			}
+		}
+		sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Conflicts: conflicts, MoveWorkers: workers}})
+		if err != nil || sum.Errors != 0 {
+			t.Fatalf("%s, %d workers: err %v, summary %+v", conflicts, workers, err, sum)
+		}
+		return tree(t, target), sum, src
+	}
+
+	for _, conflicts := range []string{organizer.ConflictNumber, organizer.ConflictHash} {
+		one, _, _ := run(conflicts, 1, false)
+		eight, sum, src := run(conflicts, 8, true)
+		if !reflect.DeepEqual(one, eight) {
+			t.Errorf("%s: 1 worker archived %v, 8 workers %v", conflicts, one, eight)
+		}
+		if len(eight) != 3 {
+			t.Errorf("%s: archived %v; want the three contents once each", conflicts, eight)
+		}
+		// The name goes to the first source path.
+		if got := eight["2023/05/Camera/IMG_0001.JPG"]; got != "another photo" {
+			t.Errorf("%s: IMG_0001.JPG holds %q; want the file of Backup", conflicts, got)
+		}
+		var dups int
+		for _, r := range sum.Results {
+			if r.DuplicateOf == "" {
+				continue
+			}
+			dups++
+			if r.Path != filepath.Join(src, "DCIM", "IMG_0001.JPG") || r.DuplicateOf != filepath.Join(src, "Copy", "IMG_0001.JPG") || !r.Success {
+				t.Errorf("%s: duplicate %+v", conflicts, r)
+			}
+			if _, err := os.Stat(r.Path); err != nil {
+				t.Errorf("%s: the duplicate left its source: %v", conflicts, err)
+			}
+		}
+		if dups != 1 || sum.Duplicates != 1 || sum.Moved != 3 {
+			t.Errorf("%s: %d duplicates of queued files, summary moved %d, duplicates %d; want 1, 3 and 1", conflicts, dups, sum.Moved, sum.Duplicates)
+		}
+	}
+}
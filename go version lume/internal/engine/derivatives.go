@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"lume-go/internal/catalog"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// placeDerivatives files the edited copies of a run (see Options.Derivatives) beside their
+// originals, rather than by the date of the edit: an original in the run, preferably from the same
+// folder, or else one the catalog of the copy's target records. Camera counters start over every
+// 10,000 shots, so an original must also fit the copy (see original.fits), the latest of those that
+// do winning. An original bound for another target than its copy does not count. A copy whose
+// original is found nowhere is laid out as any file.
+func placeDerivatives(files []metadata.FileInfo, rt *routes, mo organizer.Options, opts Options) {
+	for i := range files {
+		files[i].DerivativeOf, files[i].Beside = "", ""
+	}
+	if opts.Derivatives == nil {
+		return
+	}
+	wanted := make(map[string]bool) // the originals looked for, by the lowercased name without extension
+	var copies []int
+	for i, f := range files {
+		if stem, ok := opts.Derivatives.Original(f.Filename); ok {
+			wanted[strings.ToLower(stem)] = true
+			copies = append(copies, i)
+		}
+	}
+	if len(copies) == 0 {
+		return
+	}
+	queued := make(map[string][]original)
+	for _, f := range files {
+		key := strings.ToLower(strings.TrimSuffix(f.Filename, filepath.Ext(f.Filename)))
+		if _, ok := opts.Derivatives.Original(f.Filename); ok || !wanted[key] {
+			continue
+		}
+		base, o, _, _ := destination(f, rt, mo, opts)
+		queued[key] = append(queued[key], original{f.Path, base, organizer.TargetDir(f, base, o.Layout), f.Date, f.Device})
+	}
+	archived := make(map[*catalog.Catalog]map[string][]original)
+	for _, i := range copies {
+		info := &files[i]
+		stem, _ := opts.Derivatives.Original(info.Filename)
+		base, o := rt.options(*info, mo)
+		var found original
+		for _, c := range queued[strings.ToLower(stem)] {
+			if c.base != base || !c.fits(*info) {
+				continue
+			}
+			if found.path == "" || strings.EqualFold(filepath.Dir(c.path), filepath.Dir(info.Path)) || !strings.EqualFold(filepath.Dir(found.path), filepath.Dir(info.Path)) && c.date.After(found.date) {
+				found = c
+			}
+		}
+		if found.path == "" && o.Catalog != nil {
+			if archived[o.Catalog] == nil {
+				archived[o.Catalog] = archivedOriginals(o.Catalog, base)
+			}
+			for _, c := range archived[o.Catalog][strings.ToLower(stem)] {
+				if c.fits(*info) && (found.path == "" || c.date.After(found.date)) {
+					found = c
+				}
+			}
+		}
+		if found.path == "" {
+			continue
+		}
+		rel, err := filepath.Rel(base, found.dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		info.DerivativeOf, info.Beside = found.path, rel
+		logger.Info("%s is an edited copy of %s; filed beside it", info.Path, found.path)
+	}
+}
+
+// original is a file an edited copy may have been made from: where it is, or goes, in which target
+// and folder, and when and with what it was shot.
+type original struct {
+	path, base, dir string
+	date            time.Time
+	device          string
+}
+
+// fits reports whether o may be the original of the edited copy info: shot with the same device,
+// when both tell theirs, and not after the copy, give or take a day for clocks set apart. An edit is
+// dated when it was made, or keeps the date of its original.
+func (o original) fits(info metadata.FileInfo) bool {
+	if o.device != "" && info.Device != "" && !strings.EqualFold(o.device, info.Device) {
+		return false
+	}
+	return o.date.IsZero() || info.Date.IsZero() || !o.date.After(info.Date.Add(24*time.Hour))
+}
+
+// archivedOriginals maps the lowercased names without extension of the files cat, the catalog of
+// base, records to where they were archived.
+func archivedOriginals(cat *catalog.Catalog, base string) map[string][]original {
+	records, err := cat.Query(catalog.Filter{})
+	if err != nil {
+		logger.Error("Catalog unreadable, edited copies not matched to archived originals: %v", err)
+	}
+	m := make(map[string][]original, len(records))
+	for _, r := range records {
+		name := filepath.Base(r.Original)
+		key := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+		m[key] = append(m[key], original{r.Path, base, filepath.Dir(r.Path), r.Date, r.Device})
+	}
+	return m
+}
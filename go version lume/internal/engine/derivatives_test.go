@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+)
+
+func TestRunFilesDerivativesBesideOriginals(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	d, err := metadata.NewDerivatives(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files without EXIF are dated by their modification time: the originals by the shot, the
+	// edited copies by the edit, months later.
+	write := func(name string, mod time.Time) string {
+		t.Helper()
+		p := filepath.Join(src, name)
+		if err := os.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mod, mod)
+		return p
+	}
+	shot, edited := time.Date(2022, 1, 10, 12, 0, 0, 0, time.Local), time.Date(2023, 9, 1, 12, 0, 0, 0, time.Local)
+	run := func(pending ...string) RunSummary {
+		t.Helper()
+		sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, Layout: organizer.Layout{Template: "{year}/{month}"},
+			Catalog: true, Derivatives: d}})
+		if err != nil || sum.Moved != len(pending) {
+			t.Fatalf("err %v, summary %+v", err, sum)
+		}
+		return sum
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(target, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	// An earlier run archived the original of IMG_5678~2.jpg.
+	run(write("IMG_5678.jpg", shot))
+
+	original := write("IMG_1234.HEIC", shot)
+	sum := run(original, write("IMG_E1234.JPG", edited), write("IMG_5678~2.jpg", edited), write("IMG_9999_edited.jpg", edited))
+	for _, rel := range []string{"2022/01/IMG_1234.HEIC", "2022/01/IMG_E1234.JPG", "2022/01/IMG_5678~2.jpg", "2023/09/IMG_9999_edited.jpg"} {
+		if !exists(rel) {
+			t.Errorf("%s not archived", rel)
+		}
+	}
+	of := make(map[string]string)
+	for r := range sum.All() {
+		of[r.File] = r.DerivativeOf
+	}
+	if of["IMG_E1234.JPG"] != original || of["IMG_5678~2.jpg"] != filepath.Join(target, "2022", "01", "IMG_5678.jpg") {
+		t.Errorf("edited copies not flagged with their originals: %v", of)
+	}
+	// Without its original, an edited copy is dated as any file.
+	if of["IMG_9999_edited.jpg"] != "" || of["IMG_1234.HEIC"] != "" {
+		t.Errorf("flagged without an original: %v", of)
+	}
+}
+
+func TestRunDerivativesMatchOriginalsShotBefore(t *testing.T) {
+	target := t.TempDir()
+	d, err := metadata.NewDerivatives(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string, mod time.Time) string {
+		t.Helper()
+		p := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(p, []byte(name+mod.String()), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(p, mod, mod)
+		return p
+	}
+	run := func(p string) RunSummary {
+		t.Helper()
+		sum, err := Run(context.Background(), RunSpec{Pending: []string{p}, Options: Options{Target: target, Layout: organizer.Layout{Template: "{year}/{month}"},
+			Catalog: true, Derivatives: d}})
+		if err != nil || sum.Moved != 1 {
+			t.Fatalf("err %v, summary %+v", err, sum)
+		}
+		return sum
+	}
+
+	// The camera's counter came round again: two originals share a name, years apart.
+	run(write("IMG_4321.jpg", time.Date(2019, 3, 5, 12, 0, 0, 0, time.Local)))
+	run(write("IMG_4321.jpg", time.Date(2024, 2, 5, 12, 0, 0, 0, time.Local)))
+	sum := run(write("IMG_4321~2.jpg", time.Date(2023, 9, 1, 12, 0, 0, 0, time.Local)))
+	want := filepath.Join(target, "2019", "03", "IMG_4321.jpg")
+	for r := range sum.All() {
+		if r.DerivativeOf != want {
+			t.Errorf("edited copy flagged with %q, want %q", r.DerivativeOf, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "2019", "03", "IMG_4321~2.jpg")); err != nil {
+		t.Error("edited copy not filed beside the original shot before it")
+	}
+}
@@ -0,0 +1,623 @@
+// Package engine is the organizing pipeline shared by the window, scheduled runs and headless mode.
+// It moves loaded files and then pending paths, batch by batch, into the target and reports its
+// progress through the Progress interface, so callers decide how to show it.
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"lume-go/internal/catalog"
+	"lume-go/internal/fsys"
+	"lume-go/internal/imports"
+	"lume-go/internal/index"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/ratelimit"
+	"lume-go/internal/reconcile"
+	"lume-go/internal/routing"
+	"lume-go/internal/scan"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// OrganizeResult is the outcome of one file.
+type OrganizeResult struct {
+	Success bool
+	// Missing marks a source that vanished before its turn; it is reported but not counted as an error.
+	Missing bool
+	// InPlace marks a file already where the layout files it, left as it is; like Missing it is not an error.
+	InPlace bool
+	// Previously marks a file an earlier run archived as Dest and left in its source, unchanged since;
+	// it is skipped and not an error either.
+	Previously bool
+	// RolledBack marks the file a cancel interrupted: its partial copy was removed and the source kept.
+	RolledBack    bool
+	File          string
+	Path          string
+	Dest          string
+	Target        string
+	Size          int64
+	Width, Height int
+	Date          time.Time
+	// Source and Device are metadata.FileInfo's, which the statistics break the archive down by.
+	Source string
+	Device string
+	// DateSource is where Date came from; Rejected is the implausible date the file carried, if any.
+	DateSource     string
+	Rejected       time.Time
+	RejectedSource string
+	// MetadataNote is metadata.FileInfo.MetadataNote: why the file was not dated by its EXIF date.
+	MetadataNote string
+	// ClockOffset, DateSuspect and Skew are metadata.FileInfo's: the correction of the device's clock,
+	// and an own date that disagrees with the file times.
+	ClockOffset time.Duration
+	DateSuspect bool
+	Skew        time.Duration
+	// ShiftedFrom is the date a date shift moved Date from, zero when none did; ExifWritten marks a
+	// shifted date written into the archived copy.
+	ShiftedFrom time.Time
+	ExifWritten bool
+	// DerivativeOf is metadata.FileInfo's: the original of an edited copy, filed beside it.
+	DerivativeOf string
+	// DuplicateOf is the source of a queued file this one is a copy of: it was left in place and Dest
+	// is where its twin was archived. It succeeds but is counted as a duplicate, not as moved.
+	DuplicateOf string
+	// PHash is the perceptual hash of an archived image for the near-duplicate pass; zero when none
+	// was taken.
+	PHash uint64
+	// Started is when the move of an archived file began and Elapsed how long it took; MD5 is the
+	// hash it verified, if any.
+	Started time.Time
+	Elapsed time.Duration
+	MD5     string
+	// Discrepancy is the reconcile problem kind found at Dest after the run; empty when there was
+	// none or the run was not reconciled.
+	Discrepancy string
+	// Retried marks a file tried again at the end of the run, having been open in another program
+	// (organizer.ErrInUse) on its turn; the result is that of the second attempt.
+	Retried bool
+	// Unverified marks a file archived as Dest, its source gone, that could not be read back to
+	// verify it; Error says why. It counts as moved.
+	Unverified bool
+	Error      error
+}
+
+// Failed reports whether r is an error: neither archived, nor missing, nor already in place, nor
+// imported before.
+func (r OrganizeResult) Failed() bool {
+	return !r.Success && !r.Missing && !r.InPlace && !r.Previously
+}
+
+// MovedFrom reports whether the source of r left its folder: r was archived, unverified or not,
+// rather than found a copy of a file archived before or of another queued one, whose source stays.
+// fs is the file system of the run, on which a dry run's moves are done.
+func (r OrganizeResult) MovedFrom(fs fsys.FS) bool {
+	return r.Success && r.DuplicateOf == "" && r.Dest != r.Path && !fsys.Exists(fs, r.Path)
+}
+
+// Phases reported through Progress.Phase: while the target index is built, while a run of several
+// batches is planned as one, while the files that were open in another program are tried again, and
+// while the archived files are reconciled after the run.
+const (
+	PhaseIndexing    = "indexing"
+	PhasePlanning    = "planning"
+	PhaseRetrying    = "retrying_in_use"
+	PhaseReconciling = "reconciling"
+)
+
+// Progress follows a run. Its methods are called from the run's goroutine.
+type Progress interface {
+	// Phase is called when the run enters a phase without per-file progress.
+	Phase(name string)
+	// Step is called after every file with the files done so far of the run's total. Every result of
+	// the summary passes through it, the prior ones and those that failed to load included.
+	Step(done, total int, res OrganizeResult)
+}
+
+// Options are the settings of a run, as the config holds them.
+type Options struct {
+	Target     string
+	Layout     organizer.Layout
+	Routes     []routing.Rule
+	HardDelete bool
+	// Conflicts is the organizer.Options.Conflicts strategy.
+	Conflicts string
+	// Catalog keeps a catalog in every target; TargetIndex treats copies anywhere in the default
+	// target as duplicates.
+	Catalog     bool
+	TargetIndex bool
+	// NoDirIndex leaves out the check that a file is not already in its target folder under another
+	// name (see organizer.DirIndex), so only a file of the same name is a duplicate there.
+	NoDirIndex bool
+	// Checksums keeps a SHA256SUMS file in every year folder of every target.
+	Checksums bool
+	// MonthIndex keeps an index.json in every month folder of every target (see monthindex).
+	MonthIndex bool
+	// NearDuplicates hashes images up to PHashMaxSize bytes for the near-duplicate pass.
+	NearDuplicates bool
+	PHashMaxSize   int64
+	// Links and BatchSize apply to pending paths, which are read BatchSize at a time through Cache by
+	// Workers readers (zero means one per CPU). A run of more than BatchSize files is planned as one
+	// before its batches move (see wholePlan), so its files are read twice: keep a Cache for them.
+	Links     string
+	BatchSize int
+	Cache     *scan.Cache
+	Workers   int
+	// FileTimeout is the organizer.FileTimeout base of every file; zero means none.
+	FileTimeout time.Duration
+	// FS is the organizer.Options.FS of every move; nil is the real file system.
+	FS fsys.FS
+	// Reconcile checks the archived files once the run is done, unless it archived more than
+	// ReconcileMax of them; zero means no limit.
+	Reconcile    bool
+	ReconcileMax int
+	// PreserveArchived keeps the folders of a file that already lies in an archive laid out as its
+	// layout would, under another root, rather than rendering them again from its metadata.
+	PreserveArchived bool
+	// PreserveStructure files every file in the folders it lies in below the root of Roots it was
+	// found under (see organizer.Structure) instead of laying it out by its metadata, unless its
+	// route has a template of its own; a route with organizer.StructureTemplate does so for its files
+	// alone. Files under none of the Roots, dropped on their own, are laid out as ever. Roots are
+	// also searched for orphaned camcorder sidecars once the run is done (see adoptOrphans).
+	PreserveStructure bool
+	Roots             []string
+	// Protected lists folders no file is archived into, besides those holding a validator.ProtectMarker.
+	Protected []string
+	// Clocks corrects the dates of pending paths as they are read; SuspectAfter is the
+	// metadata.MarkDateSuspects threshold of the run, zero or less marking none.
+	Clocks       *metadata.Clocks
+	SuspectAfter time.Duration
+	// Shifts move the dates of the files they select once they are read, before anything is planned
+	// from them; ShiftExif writes the shifted dates into the archived copies of the JPEGs as well (see
+	// metadata.ShiftDate).
+	Shifts    []metadata.Shift
+	ShiftExif bool
+	// MoveWorkers is how many files are moved into each target at once; zero tunes it per target by
+	// the kind of its drive (see TuneWorkers). Files bound for one folder always move one at a time.
+	MoveWorkers int
+	// Limit paces the bytes every move of the run copies, together; nil copies at full speed. Its
+	// rate may be changed while the run goes on.
+	Limit *ratelimit.Limiter
+	// Imports skips the files an earlier run archived and left in their source, unchanged since, and
+	// records those this run leaves; nil keeps no record. Recheck organizes every file regardless,
+	// still recording.
+	Imports *imports.Store
+	Recheck bool
+	// Derivatives tells the edited copies of photos, filed beside their originals where those are
+	// found (see placeDerivatives); nil files them as any file.
+	Derivatives *metadata.Derivatives
+	// Order is the order the files are processed in, one of Orders; "" is OrderInput. A run of
+	// several batches is put in it as a whole, so the batches are drawn in it too.
+	Order string
+	// InUse picks, once every other file is done, which of the files that were open in another
+	// program on their turn (organizer.ErrInUse) are tried once more: all of them, or those the user
+	// closed. nil tries none again, reporting them as they failed.
+	InUse func(paths []string) []string
+}
+
+// RunSpec is one run: the files loaded already, then the pending paths loaded batch by batch.
+// Results already known (e.g. files that vanished from the queue) come in as Prior and count
+// towards the total. With a Journal the results are streamed to it rather than kept in the summary;
+// the run flushes it but leaves it open.
+type RunSpec struct {
+	Files    []metadata.FileInfo
+	Pending  []string
+	Prior    []OrganizeResult
+	Options  Options
+	Progress Progress
+	Journal  *Journal
+}
+
+// RunSummary is a finished run with the figures the statistics are kept from. Its figures are
+// counted as the results come, so they hold for a run whose results went to a journal too.
+type RunSummary struct {
+	// Results are the outcome of every file, unless Journal holds them; All reads either.
+	Results []OrganizeResult
+	Journal *Journal
+	Total   int
+	// Moved files and their bytes; Missing sources; InPlace files already archived where they
+	// belong; Previously files skipped as imported before; Duplicates copies of another queued file,
+	// left in place; Errors counts every other file of the total, the ones a cancelled run never
+	// reached included.
+	Moved      int
+	Bytes      int64
+	Missing    int
+	InPlace    int
+	Previously int
+	Duplicates int
+	Errors     int
+	// Failures counts the failed files (see OrganizeResult.Failed) by organizer.Category.
+	Failures map[string]int
+	// done counts the results added.
+	done int
+	// Unattempted lists the paths a run stopped for lack of space never tried to move.
+	Unattempted []string
+	// TimedOut lists the paths that failed for taking longer than their timeout; InUse those that were
+	// open in another program, when tried again too. Their sources are untouched, so they can simply be
+	// run again.
+	TimedOut []string
+	InUse    []string
+	// Unverified lists the archived files that could not be read back to verify them.
+	Unverified []string
+	// Index is the target index when one was built, for the near-duplicate pass.
+	Index *index.Index
+	// Reconciled counts the archived files checked after the run, zero when it was not reconciled;
+	// Discrepancies are the ones the archive does not match, also flagged in their results.
+	Reconciled    int
+	Discrepancies []reconcile.Problem
+	// Throttles are the targets moved into with how many files at once, in the order first seen.
+	Throttles []Throttle
+	// Orphans lists the camcorder sidecars found in the roots without their video whose archived
+	// copy no catalog knows of, left where they are.
+	Orphans []string
+}
+
+func (s *RunSummary) add(r OrganizeResult) {
+	s.done++
+	if s.Journal == nil {
+		s.Results = append(s.Results, r)
+	} else if err := s.Journal.Add(r); err != nil {
+		logger.Error("Result of %s not journaled: %v", r.Path, err)
+	}
+	if r.Failed() {
+		if s.Failures == nil {
+			s.Failures = make(map[string]int)
+		}
+		s.Failures[organizer.Category(r.Error)]++
+	}
+	switch {
+	case r.DuplicateOf != "":
+		s.Duplicates++
+	case r.Success:
+		s.Moved++
+		s.Bytes += r.Size
+	case r.Missing:
+		s.Missing++
+	case r.InPlace:
+		s.InPlace++
+	case r.Previously:
+		s.Previously++
+	case errors.Is(r.Error, organizer.ErrTimeout):
+		s.TimedOut = append(s.TimedOut, r.Path)
+	case errors.Is(r.Error, organizer.ErrInUse):
+		s.InUse = append(s.InUse, r.Path)
+	}
+	if r.Unverified {
+		s.Unverified = append(s.Unverified, r.Dest)
+	}
+}
+
+// All returns the results in the order they came, from Results or the journal.
+func (s RunSummary) All() iter.Seq[OrganizeResult] {
+	if s.Journal != nil {
+		return s.Journal.All()
+	}
+	return slices.Values(s.Results)
+}
+
+func (s *RunSummary) close() *RunSummary {
+	if err := s.Journal.Flush(); err != nil {
+		logger.Error("Journal flush failed: %v", err)
+	}
+	if s.Errors = s.Total - s.Moved - s.Missing - s.InPlace - s.Previously - s.Duplicates; s.Errors < 0 {
+		s.Errors = 0
+	}
+	return s
+}
+
+type noProgress struct{}
+
+func (noProgress) Phase(string)                  {}
+func (noProgress) Step(int, int, OrganizeResult) {}
+
+// Run organizes spec into its target. Cancelling ctx stops the run, rolling back a copy in flight, and
+// returns the context's error with a summary of everything done until then. When a target runs out of
+// space, the run stops before the file that does not fit with an error matching
+// validator.ErrInsufficientSpace; the files left are listed in the summary as unattempted. A run
+// that was not cancelled is then reconciled as its options say.
+func Run(ctx context.Context, spec RunSpec) (RunSummary, error) {
+	if spec.Progress == nil {
+		spec.Progress = noProgress{}
+	}
+	sum, err := run(ctx, spec)
+	if ctx.Err() == nil {
+		reconcileRun(ctx, &sum, spec.Options, spec.Progress)
+	}
+	return sum, err
+}
+
+// reconcileRun checks the files sum reports archived and flags the results the archive does not match.
+func reconcileRun(ctx context.Context, sum *RunSummary, opts Options, progress Progress) {
+	if !opts.Reconcile || sum.Moved == 0 || opts.ReconcileMax > 0 && sum.Moved > opts.ReconcileMax {
+		return
+	}
+	progress.Phase(PhaseReconciling)
+	var entries []reconcile.Entry
+	for r := range sum.All() {
+		if r.Success && r.Dest != "" {
+			entries = append(entries, reconcile.Entry{Dest: r.Dest, Size: r.Size, MD5: r.MD5})
+		}
+	}
+	problems, err := reconcile.Check(ctx, entries, reconcile.Options{Sample: reconcile.DefaultSample, FS: opts.FS})
+	if err != nil {
+		logger.Error("Reconciliation stopped: %v", err)
+		return
+	}
+	sum.Reconciled, sum.Discrepancies = len(entries), problems
+	// Duplicates of one file share its destination, and so its problem.
+	kinds := make(map[string]string, len(problems))
+	for _, p := range problems {
+		logger.Error("Reconciliation: %s is %s (size %d, found %d): %v", p.Dest, p.Kind, p.Size, p.Got, p.Err)
+		kinds[p.Dest] = p.Kind
+	}
+	sum.Journal.Flag(kinds)
+	for i, r := range sum.Results {
+		if r.Success {
+			sum.Results[i].Discrepancy = kinds[r.Dest]
+		}
+	}
+	logger.Info("Reconciled %d archived files: %d discrepancies", len(entries), len(problems))
+}
+
+// within lists the paths of files and pending that lie under target.
+func within(target string, files []metadata.FileInfo, pending []string) []string {
+	var in []string
+	for _, f := range files {
+		if validator.IsSubPath(target, f.Path) {
+			in = append(in, f.Path)
+		}
+	}
+	for _, p := range pending {
+		if validator.IsSubPath(target, p) {
+			in = append(in, p)
+		}
+	}
+	return in
+}
+
+// run is Run without the reconciliation.
+func run(ctx context.Context, spec RunSpec) (RunSummary, error) {
+	opts, progress := spec.Options, spec.Progress
+	// Batches run back-to-back against one combined total, so progress and the summary cover the whole drop.
+	sum := RunSummary{Total: len(spec.Files) + len(spec.Pending) + len(spec.Prior), Journal: spec.Journal}
+	report := func(r OrganizeResult) {
+		sum.add(r)
+		progress.Step(sum.done, sum.Total, r)
+	}
+	for _, r := range spec.Prior {
+		report(r)
+	}
+	mo := organizer.Options{RunID: time.Now().Format("20060102-150405"), Layout: opts.Layout, HardDelete: opts.HardDelete, Conflicts: opts.Conflicts, FS: opts.FS,
+		Protect: validator.NewProtection(opts.Protected), Limit: opts.Limit}
+	if !opts.NoDirIndex {
+		mo.Dirs = organizer.NewDirIndex()
+	}
+	if opts.Cache != nil {
+		mo.Hashed = opts.Cache.Remember
+	}
+	if opts.Catalog {
+		if cat, err := catalog.Open(opts.Target); err != nil {
+			logger.Error("Catalog unavailable: %v", err)
+		} else {
+			mo.Catalog = cat
+			defer func() {
+				if err := cat.Close(); err != nil {
+					logger.Error("Catalog close: %v", err)
+				}
+			}()
+		}
+	}
+	rt := newRoutes(opts)
+	defer rt.close()
+	if opts.TargetIndex {
+		progress.Phase(PhaseIndexing)
+		ix, err := index.Build(ctx, opts.Target, index.BuildOptions{Workers: runtime.NumCPU(), Perceptual: opts.NearDuplicates, PerceptualMaxSize: opts.PHashMaxSize,
+			Exclude: within(opts.Target, spec.Files, spec.Pending)})
+		if err != nil && ctx.Err() != nil {
+			return *sum.close(), ctx.Err()
+		}
+		if err != nil {
+			logger.Error("Target index unavailable: %v", err)
+		} else {
+			mo.Index, sum.Index = ix, ix
+			defer func() {
+				if err := ix.Save(); err != nil {
+					logger.Error("Target index save failed: %v", err)
+				}
+			}()
+		}
+	}
+
+	prev := newPrevious(opts)
+	files, pending, skipped := prev.skip(spec.Files, spec.Pending)
+	for _, r := range skipped {
+		report(r)
+	}
+	// Targets are tuned while a batch is laid out, before any of its files move.
+	th := newThrottles(opts, func(t Throttle) { sum.Throttles = append(sum.Throttles, t) })
+	m := &mover{report: report, rt: rt, mo: mo, opts: opts, free: newSpace(), prev: prev, th: th}
+	stop := func(left []metadata.FileInfo, err error) (RunSummary, error) {
+		m.retryInUse(ctx, false, progress)
+		for _, info := range left {
+			sum.Unattempted = append(sum.Unattempted, info.Path)
+		}
+		sum.Unattempted = append(sum.Unattempted, pending...)
+		logger.Error("Run stopped, %d files not attempted: %v", len(sum.Unattempted), err)
+		return *sum.close(), err
+	}
+	// A run of several batches is prepared as one first; the batches then follow its order.
+	var w *wholePlan
+	var tw twins
+	if opts.BatchSize > 0 && len(files)+len(pending) > opts.BatchSize {
+		progress.Phase(PhasePlanning)
+		var failed []OrganizeResult
+		w, failed = planWhole(ctx, files, pending, rt, mo, opts)
+		for _, r := range failed {
+			report(r)
+		}
+		if ctx.Err() != nil {
+			return *sum.close(), ctx.Err()
+		}
+		files, pending, failed = w.next(ctx, w.order, opts)
+		for _, r := range failed {
+			report(r)
+		}
+		tw = w.tw
+	} else {
+		if len(pending) > 0 {
+			more, _, failed := nextBatch(ctx, pending, opts)
+			for _, r := range failed {
+				report(r)
+			}
+			files, pending = slices.Concat(files, more), nil
+		}
+		tw = prepare(ctx, files, rt, mo, opts)
+	}
+	for {
+		if left, err := m.batch(ctx, files, tw); ctx.Err() != nil {
+			m.retryInUse(ctx, false, progress)
+			return *sum.close(), ctx.Err()
+		} else if err != nil {
+			return stop(left, err)
+		}
+		rt.flush()
+		if len(pending) == 0 {
+			if left, err := m.retryInUse(ctx, true, progress); ctx.Err() != nil {
+				return *sum.close(), ctx.Err()
+			} else if err != nil {
+				return stop(left, err)
+			}
+			sum.Orphans = adoptOrphans(ctx, opts, mo, rt)
+			return *sum.close(), nil
+		}
+		var failed []OrganizeResult
+		files, pending, failed = w.next(ctx, pending, opts)
+		for _, r := range failed {
+			report(r)
+		}
+		logger.Info("Starting next batch: %d files, %d still queued", len(files), len(pending))
+	}
+}
+
+// ErrPanic marks a file whose organizing panicked; the run goes on with the next file.
+var ErrPanic = errors.New("crashed while organizing")
+
+// moveFile moves one file into place; tests replace it.
+var moveFile = organizer.MoveFileContext
+
+// moveOne organizes one file. A panic on it, say in an image decoder, fails that file only, as does
+// taking longer than its timeout.
+func moveOne(ctx context.Context, info metadata.FileInfo, rt *routes, mo organizer.Options, opts Options) (res OrganizeResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Organizing %s panicked: %v\n%s", info.Path, r, debug.Stack())
+			res = OrganizeResult{File: info.Filename, Path: info.Path, Size: info.Size, Error: fmt.Errorf("%w: %v", ErrPanic, r)}
+		}
+	}()
+	base, o, inPlace, kept := destination(info, rt, mo, opts)
+	if inPlace {
+		logger.Info("Already organized, left in place: %s", info.Path)
+		return OrganizeResult{InPlace: true, File: info.Filename, Path: info.Path, Dest: info.Path, Target: base, Size: info.Size, Date: info.Date, Source: info.Source, Device: info.Device, DateSource: info.DateSource, DerivativeOf: info.DerivativeOf}
+	}
+	if kept != "" {
+		logger.Info("Keeping the archive folders %s of %s", kept, info.Path)
+	}
+	ph := perceptualHash(info, opts)
+	// The hash the move verifies is kept for the reconciliation, or that of the archived copy once
+	// a shifted date is written into it.
+	var md5 string
+	written := info.ExifWritten
+	o.Hashed = func(path, sum string) {
+		md5 = sum
+		if mo.Hashed != nil {
+			mo.Hashed(path, sum)
+		}
+	}
+	o.Written = func(_, sum string) { md5, written = sum, true }
+	limit, start := organizer.FileTimeout(opts.FileTimeout, info.Size), time.Now()
+	if limit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limit)
+		defer cancel()
+	}
+	dest, err := moveFile(ctx, info, base, o)
+	switch {
+	case err == nil:
+		if info.RejectedSource != "" {
+			logger.Info("Implausible %s date %s for %s; filed by %s", info.RejectedSource, info.RejectedDate.Format(time.RFC3339), info.Filename, info.DateSource)
+		}
+		if info.DateSuspect {
+			logger.Info("Suspect %s date %s for %s: %s from its file times", info.DateSource, info.Date.Format(time.RFC3339), info.Filename, info.Skew)
+		}
+		return OrganizeResult{Success: true, File: info.Filename, Path: info.Path, Dest: dest, Target: base, Size: info.Size, Width: info.Width, Height: info.Height, Date: info.Date,
+			Source: info.Source, Device: info.Device, DateSource: info.DateSource, Rejected: info.RejectedDate, RejectedSource: info.RejectedSource, MetadataNote: info.MetadataNote, PHash: ph, Started: start, Elapsed: time.Since(start),
+			MD5: md5, ClockOffset: info.ClockOffset, DateSuspect: info.DateSuspect, Skew: info.Skew, ShiftedFrom: info.ShiftedFrom, ExifWritten: written, DerivativeOf: info.DerivativeOf}
+	case errors.Is(err, organizer.ErrUnverified) && dest != "":
+		return OrganizeResult{Success: true, Unverified: true, File: info.Filename, Path: info.Path, Dest: dest, Target: base, Size: info.Size, Date: info.Date,
+			Source: info.Source, Device: info.Device, DateSource: info.DateSource, Started: start, Elapsed: time.Since(start), DerivativeOf: info.DerivativeOf, Error: err}
+	case errors.Is(err, organizer.ErrTimeout):
+		logger.Error("%s did not finish within %s; skipped, the source is untouched", info.Path, limit)
+		return OrganizeResult{File: info.Filename, Path: info.Path, Size: info.Size, Error: fmt.Errorf("%w (limit %s)", err, limit)}
+	case errors.Is(err, organizer.ErrSourceMissing):
+		return OrganizeResult{Missing: true, File: info.Filename, Path: info.Path, Size: info.Size, Error: err}
+	case errors.Is(err, organizer.ErrRolledBack):
+		return OrganizeResult{RolledBack: true, File: info.Filename, Path: info.Path, Size: info.Size, Error: err}
+	}
+	return OrganizeResult{File: info.Filename, Path: info.Path, Size: info.Size, Error: err}
+}
+
+// shiftDates is the date shift stage between the scan and the plan: the files opts.Shifts select are
+// dated anew, and set to be written into their archived copies when opts.ShiftExif says. A file whose
+// date cannot be written keeps the shifted date for this run.
+func shiftDates(files []metadata.FileInfo, opts Options) {
+	for i := range files {
+		ok, err := metadata.ShiftDate(&files[i], opts.Shifts, opts.ShiftExif)
+		if err != nil {
+			logger.Error("Shifted date of %s not written to the file: %v", files[i].Path, err)
+		}
+		if ok {
+			logger.Info("Date of %s shifted from %s to %s (written: %v, to write: %v)", files[i].Path, files[i].ShiftedFrom.Format(time.RFC3339), files[i].Date.Format(time.RFC3339), files[i].ExifWritten, !files[i].ExifDate.IsZero())
+		}
+	}
+}
+
+// nextBatch loads the metadata for up to BatchSize pending paths. Paths that can no longer be read
+// are returned as failures.
+func nextBatch(ctx context.Context, pending []string, opts Options) ([]metadata.FileInfo, []string, []OrganizeResult) {
+	n := opts.BatchSize
+	if n <= 0 || n > len(pending) {
+		n = len(pending)
+	}
+	batch, failed := make([]metadata.FileInfo, 0, n), []OrganizeResult(nil)
+	res, _ := scan.All(ctx, pending[:n], scan.Options{Workers: opts.Workers, Links: opts.Links, Cache: opts.Cache, Devices: opts.Layout.Devices, Clocks: opts.Clocks})
+	for _, r := range res {
+		if r.Err != nil {
+			failed = append(failed, OrganizeResult{Missing: errors.Is(r.Err, os.ErrNotExist), File: filepath.Base(r.Path), Path: r.Path, Error: r.Err})
+			continue
+		}
+		batch = append(batch, r.Info)
+	}
+	return batch, pending[n:], failed
+}
+
+// perceptualHash hashes an image for the near-duplicate pass; 0 means not hashed.
+func perceptualHash(info metadata.FileInfo, opts Options) uint64 {
+	if !opts.NearDuplicates || !metadata.PerceptualExtensions[strings.ToLower(filepath.Ext(info.Path))] {
+		return 0
+	}
+	ph, err := metadata.PerceptualHash(info.Path, opts.PHashMaxSize)
+	if err != nil {
+		logger.Error("Perceptual hash skipped for %s: %v", info.Filename, err)
+		return 0
+	}
+	return ph
+}
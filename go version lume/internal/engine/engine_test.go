@@ -0,0 +1,502 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/catalog"
+	"lume-go/internal/fsys"
+	"lume-go/internal/metadata"
+	"lume-go/internal/monthindex"
+	"lume-go/internal/organizer"
+	"lume-go/internal/routing"
+	"lume-go/internal/sums"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, dir string, names ...string) []string {
+	t.Helper()
+	var paths []string
+	for _, n := range names {
+		p := filepath.Join(dir, n)
+		if err := os.WriteFile(p, []byte(n), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := time.Date(2023, 5, 6, 12, 0, 0, 0, time.Local)
+		os.Chtimes(p, mtime, mtime)
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// steps records the progress of a run.
+type steps struct {
+	phases []string
+	done   []int
+	cancel func(done int)
+}
+
+func (s *steps) Phase(name string) { s.phases = append(s.phases, name) }
+
+func (s *steps) Step(done, total int, _ OrganizeResult) {
+	s.done = append(s.done, done)
+	if s.cancel != nil {
+		s.cancel(done)
+	}
+}
+
+func TestRunBatchesAndRoutes(t *testing.T) {
+	src, target, videos := t.TempDir(), t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg", "c.mp4", "d.jpg")
+	first, err := metadata.GetFileInfo(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(src, "gone.jpg")
+
+	progress := &steps{}
+	sum, err := Run(context.Background(), RunSpec{
+		Files:    []metadata.FileInfo{first},
+		Pending:  append(paths[1:], gone),
+		Options:  Options{Target: target, Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: videos}}, BatchSize: 2},
+		Progress: progress,
+	})
+	if err != nil || sum.Total != 5 || sum.Moved != 4 || len(sum.Results) != 5 {
+		t.Fatalf("summary: err %v, total %d, moved %d, results %d", err, sum.Total, sum.Moved, len(sum.Results))
+	}
+	for _, r := range sum.Results {
+		switch {
+		case r.Path == gone:
+			if !r.Missing || r.Success {
+				t.Errorf("vanished file: %+v", r)
+			}
+		case filepath.Ext(r.File) == ".mp4":
+			if r.Target != videos {
+				t.Errorf("%s routed to %s", r.File, r.Target)
+			}
+		default:
+			if want := filepath.Join(target, "2023", "05", "Other_Sorted", r.File); r.Dest != want {
+				t.Errorf("%s archived at %s, want %s", r.File, r.Dest, want)
+			}
+		}
+	}
+	if last := progress.done[len(progress.done)-1]; last != sum.Total {
+		t.Errorf("progress ended at %d of %v", last, progress.done)
+	}
+}
+
+func TestRunChecksums(t *testing.T) {
+	src, target, videos := t.TempDir(), t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg", "c.mp4")
+	_, err := Run(context.Background(), RunSpec{Pending: paths,
+		Options: Options{Target: target, Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: videos}}, BatchSize: 2, Checksums: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for dir, want := range map[string][]string{
+		filepath.Join(target, "2023"): {"05/Other_Sorted/a.jpg", "05/Other_Sorted/b.jpg"},
+		filepath.Join(videos, "2023"): {"05/Other_Sorted/c.mp4"},
+	} {
+		entries, err := sums.Read(filepath.Join(dir, sums.FileName))
+		if err != nil || len(entries) != len(want) {
+			t.Fatalf("%s: %+v, %v", dir, entries, err)
+		}
+		for i, e := range entries {
+			if hash, _ := sums.HashFile(filepath.Join(dir, e.Name)); e.Name != want[i] || e.Hash != hash {
+				t.Errorf("%s: entry %+v, want %s with %s", dir, e, want[i], hash)
+			}
+		}
+	}
+}
+
+func TestRunMonthIndex(t *testing.T) {
+	// Each case archives a.jpg and b.jpg of 2023-05-06, in week 18, by one layout; the index of
+	// their month folder, or of the folder for a layout without months, lists them.
+	for _, tt := range []struct {
+		name     string
+		layout   organizer.Layout
+		preserve bool
+		dir      string
+		want     []string
+	}{
+		{"default", organizer.Layout{}, false, "2023/05", []string{"Other_Sorted/a.jpg", "Other_Sorted/b.jpg"}},
+		{"days", organizer.Layout{Template: "{year}/{month}/{day}"}, false, "2023/05", []string{"06/a.jpg", "06/b.jpg"}},
+		{"weeks", organizer.Layout{Template: "{weekyear}/{week}"}, false, "2023/W18", []string{"a.jpg", "b.jpg"}},
+		{"years", organizer.Layout{Template: "{year}/{device}"}, false, "2023", []string{"Other_Sorted/a.jpg", "Other_Sorted/b.jpg"}},
+		{"structure", organizer.Layout{}, true, "Trips/Rome", []string{"a.jpg", "b.jpg"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			src, target := t.TempDir(), t.TempDir()
+			os.MkdirAll(filepath.Join(src, "Trips", "Rome"), 0755)
+			paths := writeFiles(t, filepath.Join(src, "Trips", "Rome"), "a.jpg", "b.jpg")
+			_, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Layout: tt.layout, BatchSize: 1, Checksums: true, MonthIndex: true,
+				PreserveStructure: tt.preserve, Roots: []string{src}}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Join(target, filepath.FromSlash(tt.dir))
+			entries, err := monthindex.Read(filepath.Join(dir, monthindex.FileName))
+			if err != nil || len(entries) != len(tt.want) {
+				t.Fatalf("%s: %+v, %v", dir, entries, err)
+			}
+			for i, e := range entries {
+				if hash, _ := metadata.GetFileHash(filepath.Join(dir, filepath.FromSlash(e.Name))); e.Name != tt.want[i] || e.Hash != hash || e.Date.IsZero() {
+					t.Errorf("%s: entry %+v, want %s with %s", dir, e, tt.want[i], hash)
+				}
+			}
+			// No other folder has an index, and walks of the archive leave the indexes and lists out.
+			filepath.WalkDir(target, func(p string, d os.DirEntry, err error) error {
+				if err == nil && d.Name() == monthindex.FileName && filepath.Dir(p) != dir {
+					t.Errorf("index in %s", filepath.Dir(p))
+				}
+				return nil
+			})
+			if n, err := catalog.Rebuild(context.Background(), target); err != nil || n != 2 {
+				t.Errorf("catalog rebuilt with %d files, %v", n, err)
+			}
+		})
+	}
+}
+
+func TestRunPartialFailure(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.mp4", "c.jpg")
+	// A route into a regular file cannot be created, so its files fail while the others move.
+	blocked := filepath.Join(t.TempDir(), "videos")
+	os.WriteFile(blocked, nil, 0644)
+
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: blocked}}}})
+	if err != nil || sum.Moved != 2 || sum.Errors != 1 || sum.Missing != 0 {
+		t.Fatalf("summary: err %v, moved %d, errors %d, missing %d", err, sum.Moved, sum.Errors, sum.Missing)
+	}
+	for _, r := range sum.Results {
+		if failed := filepath.Ext(r.File) == ".mp4"; failed != (r.Error != nil) || failed == r.Success {
+			t.Errorf("%s: success %v, error %v", r.File, r.Success, r.Error)
+		}
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("failed file left its source: %v", err)
+	}
+}
+
+// The summary of a run with failures injected mid-move counts what is on disk afterwards.
+func TestRunInjectedFailures(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg", "c.jpg", "d.jpg")
+	faulty := fsys.NewFaulty(nil,
+		fsys.Fault{Op: fsys.OpRename, Path: ".jpg"}, // every move copies
+		fsys.Fault{Op: fsys.OpWrite, Path: "b.jpg", After: 2},
+		fsys.Fault{Op: fsys.OpWrite, Path: "c.jpg", After: 2, Corrupt: true},
+		fsys.Fault{Op: fsys.OpRemove, Path: paths[3]},
+	)
+
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, HardDelete: true, FS: faulty}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archived []string
+	filepath.WalkDir(target, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			archived = append(archived, filepath.Base(path))
+		}
+		return err
+	})
+	if sum.Moved != len(archived) || sum.Moved != 2 || sum.Errors != 2 {
+		t.Fatalf("summary: moved %d, errors %d; archived %v", sum.Moved, sum.Errors, archived)
+	}
+	for _, r := range sum.Results {
+		if _, err := os.Stat(r.Path); (err == nil) != (r.File != "a.jpg") {
+			t.Errorf("%s: source left %v", r.File, err == nil)
+		}
+	}
+}
+
+// A file renamed into the archive that cannot be read back is archived, unverified, not failed.
+func TestRunUnverifiedRename(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg")
+	var infos []metadata.FileInfo
+	for _, p := range paths {
+		info, _ := metadata.GetFileInfo(p)
+		infos = append(infos, info)
+	}
+	// b.jpg is opened to hash it before the move, then to verify it after.
+	faulty := fsys.NewFaulty(nil, fsys.Fault{Op: fsys.OpOpen, Path: "b.jpg", Nth: 2})
+
+	sum, err := Run(context.Background(), RunSpec{Files: infos, Options: Options{Target: target, FS: faulty}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Moved != 2 || sum.Errors != 0 || len(sum.Unverified) != 1 {
+		t.Fatalf("summary: moved %d, errors %d, unverified %v", sum.Moved, sum.Errors, sum.Unverified)
+	}
+	for _, r := range sum.Results {
+		if r.Unverified != (r.File == "b.jpg") || r.Unverified != errors.Is(r.Error, organizer.ErrUnverified) {
+			t.Errorf("%s: unverified %v, error %v", r.File, r.Unverified, r.Error)
+		}
+		if _, err := os.Stat(r.Dest); err != nil {
+			t.Errorf("%s not archived as %s: %v", r.File, r.Dest, err)
+		}
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	var infos []metadata.FileInfo
+	for i := 0; i < 5; i++ {
+		p := writeFiles(t, src, fmt.Sprintf("%d.jpg", i))[0]
+		info, _ := metadata.GetFileInfo(p)
+		infos = append(infos, info)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := &steps{cancel: func(done int) {
+		if done == 2 {
+			cancel()
+		}
+	}}
+	sum, err := Run(ctx, RunSpec{Files: infos, Options: Options{Target: target}, Progress: progress})
+	if !errors.Is(err, context.Canceled) || sum.Moved != 2 || len(sum.Results) != 2 || sum.Errors != 3 {
+		t.Fatalf("cancelled run: err %v, moved %d, results %d, errors %d", err, sum.Moved, len(sum.Results), sum.Errors)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "bb.jpg", "ccc.mp4")
+	prior := []OrganizeResult{{File: "gone.jpg", Missing: true}}
+	blocked := filepath.Join(t.TempDir(), "videos")
+	os.WriteFile(blocked, nil, 0644)
+
+	progress := &steps{}
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Prior: prior, Progress: progress,
+		Options: Options{Target: target, TargetIndex: true, Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: blocked}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != 4 || sum.Moved != 2 || sum.Bytes != int64(len("a.jpg")+len("bb.jpg")) || sum.Missing != 1 || sum.Errors != 1 {
+		t.Fatalf("summary: total %d, moved %d, bytes %d, missing %d, errors %d", sum.Total, sum.Moved, sum.Bytes, sum.Missing, sum.Errors)
+	}
+	if sum.Index == nil || len(progress.phases) != 1 || progress.phases[0] != PhaseIndexing {
+		t.Errorf("target index: %v, phases %v", sum.Index, progress.phases)
+	}
+}
+
+func TestRunStopsWhenTargetFills(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg", "c.jpg", "d.jpg")
+	calls := 0
+	defer func(f func(string) (int64, error), v func(string) string) { freeSpace, volumeOf = f, v }(freeSpace, volumeOf)
+	freeSpace = func(string) (int64, error) { calls++; return validator.SpaceMargin + 2*clusterSize + 100, nil }
+	volumeOf = func(p string) string {
+		if strings.HasPrefix(p, src) {
+			return "C:"
+		}
+		return "D:"
+	}
+
+	sum, err := Run(context.Background(), RunSpec{Files: loadAll(t, paths[:2]), Pending: paths[2:], Options: Options{Target: target, BatchSize: 1}})
+	if !errors.Is(err, validator.ErrInsufficientSpace) || sum.Moved != 2 || sum.Errors != 2 {
+		t.Fatalf("full target: err %v, moved %d, errors %d", err, sum.Moved, sum.Errors)
+	}
+	// Two 5-byte files take a cluster each and fit beside the margin; the third was never tried and
+	// the last never loaded.
+	if len(sum.Unattempted) != 2 || sum.Unattempted[0] != paths[2] || sum.Unattempted[1] != paths[3] || calls != 1 {
+		t.Fatalf("unattempted %v after %d free space lookups", sum.Unattempted, calls)
+	}
+	if _, err := os.Stat(paths[2]); err != nil {
+		t.Errorf("unattempted file touched: %v", err)
+	}
+
+	// Moves within the volume are renames and need no space.
+	volumeOf = func(string) string { return "C:" }
+	if sum, err := Run(context.Background(), RunSpec{Pending: paths[2:], Options: Options{Target: target}}); err != nil || sum.Moved != 2 {
+		t.Fatalf("same volume: err %v, moved %d", err, sum.Moved)
+	}
+}
+
+func loadAll(t *testing.T, paths []string) []metadata.FileInfo {
+	t.Helper()
+	var infos []metadata.FileInfo
+	for _, p := range paths {
+		info, err := metadata.GetFileInfo(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func TestCollect(t *testing.T) {
+	src := t.TempDir()
+	target := filepath.Join(src, "archive")
+	os.Mkdir(target, 0755)
+	writeFiles(t, src, "keeper.jpg", "tiny.jpg", "notes.txt")
+	writeFiles(t, target, "archived.jpg")
+	os.WriteFile(filepath.Join(src, "big.jpg"), make([]byte, 100), 0644)
+
+	files, total := Collect([]string{src}, []string{target}, Filters{MinSize: 9})
+	if len(files) != 2 || total != 110 {
+		t.Fatalf("Collect = %v (%d bytes)", files, total)
+	}
+	if files, _ := Collect([]string{filepath.Join(src, "keeper.jpg"), filepath.Join(src, "notes.txt")}, []string{target}, Filters{}); len(files) != 1 {
+		t.Fatalf("Collect of single files = %v", files)
+	}
+}
+
+func TestRunSurvivesPanic(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "b.jpg", "c.jpg")
+	move := moveFile
+	defer func() { moveFile = move }()
+	moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+		if info.Filename == "b.jpg" {
+			panic("malformed EXIF block")
+		}
+		return organizer.MoveFileContext(ctx, info, base, o)
+	}
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target}})
+	if err != nil || sum.Moved != 2 || sum.Errors != 1 {
+		t.Fatalf("summary: err %v, moved %d, errors %d", err, sum.Moved, sum.Errors)
+	}
+	for _, r := range sum.Results {
+		if crashed := r.File == "b.jpg"; crashed != errors.Is(r.Error, ErrPanic) || crashed == r.Success {
+			t.Errorf("%s: success %v, error %v", r.File, r.Success, r.Error)
+		}
+	}
+	if _, err := os.Stat(paths[1]); err != nil {
+		t.Errorf("crashed file left its source: %v", err)
+	}
+}
+
+func TestRunMovesOnAfterTimeout(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "stuck.mp4", "c.jpg")
+	move := moveFile
+	defer func() { moveFile = move }()
+	moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+		if info.Filename == "stuck.mp4" {
+			<-ctx.Done()
+			return "", fmt.Errorf("%s: %w: %w", info.Filename, organizer.ErrTimeout, ctx.Err())
+		}
+		return organizer.MoveFileContext(ctx, info, base, o)
+	}
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, FileTimeout: 50 * time.Millisecond}})
+	if err != nil || sum.Moved != 2 || sum.Errors != 1 {
+		t.Fatalf("summary: err %v, moved %d, errors %d", err, sum.Moved, sum.Errors)
+	}
+	if len(sum.TimedOut) != 1 || sum.TimedOut[0] != paths[1] {
+		t.Errorf("TimedOut = %v", sum.TimedOut)
+	}
+	for _, r := range sum.Results {
+		if r.File == "stuck.mp4" && (organizer.Category(r.Error) != organizer.CategoryTimeout || !strings.Contains(r.Error.Error(), "limit")) {
+			t.Errorf("stuck file reported as %v", r.Error)
+		}
+	}
+}
+
+// A file removed right after its move, as an antivirus quarantine would, is flagged by the reconciliation.
+func TestRunReconciles(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "a.jpg", "quarantined.jpg", "c.jpg")
+	move := moveFile
+	defer func() { moveFile = move }()
+	moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+		dest, err := organizer.MoveFileContext(ctx, info, base, o)
+		if err == nil && info.Filename == "quarantined.jpg" {
+			os.Remove(dest)
+		}
+		return dest, err
+	}
+	progress := &steps{}
+	sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Reconcile: true}, Progress: progress})
+	if err != nil || sum.Moved != 3 || sum.Reconciled != 3 || len(sum.Discrepancies) != 1 {
+		t.Fatalf("summary: err %v, moved %d, reconciled %d, discrepancies %+v", err, sum.Moved, sum.Reconciled, sum.Discrepancies)
+	}
+	for _, r := range sum.Results {
+		if want := map[bool]string{true: "missing"}[r.File == "quarantined.jpg"]; r.Discrepancy != want || r.MD5 == "" {
+			t.Errorf("%s: discrepancy %q, MD5 %q", r.File, r.Discrepancy, r.MD5)
+		}
+	}
+	if last := progress.phases[len(progress.phases)-1]; last != PhaseReconciling {
+		t.Errorf("phases = %v", progress.phases)
+	}
+
+	// Over the limit the run is not reconciled.
+	paths = writeFiles(t, src, "d.jpg", "e.jpg")
+	if sum, _ := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Reconcile: true, ReconcileMax: 1}}); sum.Reconciled != 0 {
+		t.Errorf("reconciled %d files over the limit", sum.Reconciled)
+	}
+}
+
+func TestRunLeavesOrganizedFiles(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	first, err := Run(context.Background(), RunSpec{Pending: writeFiles(t, src, "a.jpg", "b.jpg"), Options: Options{Target: target}})
+	if err != nil || first.Moved != 2 {
+		t.Fatalf("first run: err %v, moved %d", err, first.Moved)
+	}
+	a, b := first.Results[0].Dest, first.Results[1].Dest
+
+	// Dropped again from the archive, a file is left where it is.
+	sum, err := Run(context.Background(), RunSpec{Pending: []string{a}, Options: Options{Target: target}})
+	if err != nil || sum.InPlace != 1 || sum.Moved != 0 || sum.Errors != 0 || sum.Results[0].Failed() {
+		t.Fatalf("in place: err %v, summary %+v", err, sum)
+	}
+	if _, err := os.Stat(a); err != nil {
+		t.Errorf("the file left its place: %v", err)
+	}
+
+	// From another archive of the same shape, its folders are kept when asked to.
+	rel, _ := filepath.Rel(target, filepath.Dir(b))
+	old := filepath.Join(t.TempDir(), filepath.Dir(rel), "Phone of Deniz", "b.jpg")
+	os.MkdirAll(filepath.Dir(old), 0755)
+	if err := os.Rename(b, old); err != nil {
+		t.Fatal(err)
+	}
+	sum, err = Run(context.Background(), RunSpec{Pending: []string{old}, Options: Options{Target: target, PreserveArchived: true}})
+	if want := filepath.Join(target, filepath.Dir(rel), "Phone of Deniz", "b.jpg"); err != nil || sum.Moved != 1 || sum.Results[0].Dest != want {
+		t.Errorf("preserved: err %v, dest %q; want %q", err, sum.Results[0].Dest, want)
+	}
+}
+
+// Only the sources that left count as moved for pruning: a copy of a file archived before stays in
+// its folder, which a dry run must not list as emptied.
+func TestRunMovedFrom(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	for _, d := range []string{"a", "b"} {
+		os.MkdirAll(filepath.Join(src, d), 0755)
+	}
+	first, err := Run(context.Background(), RunSpec{Pending: writeFiles(t, filepath.Join(src, "a"), "x.jpg"), Options: Options{Target: target}})
+	if err != nil || first.Moved != 1 {
+		t.Fatalf("first run: err %v, moved %d", err, first.Moved)
+	}
+	// The folder b holds another copy of x.jpg, already archived, and a new photo.
+	writeFiles(t, filepath.Join(src, "a"), "x.jpg")
+	os.Rename(filepath.Join(src, "a", "x.jpg"), filepath.Join(src, "b", "x.jpg"))
+	fresh := writeFiles(t, filepath.Join(src, "a"), "y.jpg")
+	pending := append(fresh, filepath.Join(src, "b", "x.jpg"))
+
+	rec := fsys.NewRecorder(nil)
+	sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, FS: rec}})
+	if err != nil || sum.Errors != 0 {
+		t.Fatalf("dry run: err %v, summary %+v", err, sum)
+	}
+	var moved []string
+	for r := range sum.All() {
+		if r.MovedFrom(rec) {
+			moved = append(moved, r.Path)
+		}
+	}
+	if len(moved) != 1 || moved[0] != fresh[0] {
+		t.Errorf("moved %v; want only %s", moved, fresh[0])
+	}
+	pruned, err := organizer.PruneEmptyDirs(src, moved, true)
+	if err != nil || len(pruned) != 1 || pruned[0] != filepath.Join(src, "a") {
+		t.Errorf("PruneEmptyDirs = %v, %v; want only %s", pruned, err, filepath.Join(src, "a"))
+	}
+}
@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"errors"
+	"lume-go/internal/hook"
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+	"maps"
+	"time"
+)
+
+// Exit codes of the command-line runs, lume --headless and lume-lite, besides those of the failure
+// categories. ExitAnomaly is that of a run flagged as out of line with its earlier ones when
+// --anomaly-exit asks for it and nothing failed.
+const (
+	ExitUsage   = 1
+	ExitOther   = 2
+	ExitBusy    = 9
+	ExitAnomaly = 11
+)
+
+// exitCodes lists the failure categories in priority order: the first one with failures picks the code.
+var exitCodes = []struct {
+	category string
+	code     int
+}{
+	{organizer.CategorySpace, 3},
+	{organizer.CategoryWritable, 4},
+	{organizer.CategoryProtected, 10},
+	{organizer.CategoryIntegrity, 5},
+	{organizer.CategoryUnverified, 5},
+	{organizer.CategoryMissing, 6},
+	{organizer.CategoryExists, 7},
+	{organizer.CategoryUnsupported, 8},
+	{organizer.CategoryRolledBack, ExitOther},
+	{organizer.CategoryTimeout, ExitOther},
+	{organizer.CategoryInUse, ExitOther},
+	{organizer.CategoryOther, ExitOther},
+}
+
+// FailureExitCode is the exit code of the failed files counted by category, 0 when there are none.
+func FailureExitCode(failed map[string]int) int {
+	for _, c := range exitCodes {
+		if failed[c.category] > 0 {
+			return c.code
+		}
+	}
+	return 0
+}
+
+// ExitCode is the exit code of a finished run: the space code when the target filled up, else that
+// of its failures, and ExitOther for a run cancelled without any.
+func ExitCode(sum RunSummary, runErr error) int {
+	if errors.Is(runErr, validator.ErrInsufficientSpace) {
+		return FailureExitCode(map[string]int{organizer.CategorySpace: 1})
+	}
+	// The counts leave the vanished sources out, which fail a command-line run all the same.
+	failed := maps.Clone(sum.Failures)
+	if sum.Missing > 0 {
+		if failed == nil {
+			failed = make(map[string]int)
+		}
+		failed[organizer.CategoryMissing] += sum.Missing
+	}
+	if code := FailureExitCode(failed); code != 0 || runErr == nil {
+		return code
+	}
+	return ExitOther
+}
+
+// HookSummary is the hook payload of a finished run into targets, started at started.
+func HookSummary(app, version string, started time.Time, targets []string, sum RunSummary, runErr error) hook.Summary {
+	s := hook.Summary{App: app, Version: version, Started: started, Finished: time.Now(), Targets: targets,
+		ExitCode: ExitCode(sum, runErr), Total: sum.Total, Moved: sum.Moved, Bytes: sum.Bytes, Missing: sum.Missing, Errors: sum.Errors,
+		Unattempted: len(sum.Unattempted), Failures: maps.Clone(sum.Failures)}
+	switch {
+	case errors.Is(runErr, validator.ErrInsufficientSpace):
+		s.Status = hook.StatusStopped
+	case runErr != nil:
+		s.Status = hook.StatusCancelled
+	case sum.Errors > 0:
+		s.Status = hook.StatusErrors
+	default:
+		s.Status = hook.StatusOK
+	}
+	return s
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		sum    RunSummary
+		runErr error
+		want   int
+	}{
+		{"clean", RunSummary{Moved: 3}, nil, 0},
+		{"priority", RunSummary{Failures: map[string]int{organizer.CategoryExists: 1, organizer.CategoryWritable: 2}}, nil, 4},
+		{"missing", RunSummary{Missing: 1}, nil, 6},
+		{"space", RunSummary{}, fmt.Errorf("stopped: %w", validator.ErrInsufficientSpace), 3},
+		{"cancelled", RunSummary{Moved: 1}, context.Canceled, ExitOther},
+		{"cancelled with failures", RunSummary{Failures: map[string]int{organizer.CategoryProtected: 1}}, context.Canceled, 10},
+	} {
+		if got := ExitCode(tc.sum, tc.runErr); got != tc.want {
+			t.Errorf("%s: ExitCode = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+}
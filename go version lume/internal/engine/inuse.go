@@ -0,0 +1,53 @@
+package engine
+
+import (
+	"context"
+
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+)
+
+// heldFile is a file that was open in another program on its turn, with the result of that attempt.
+type heldFile struct {
+	info metadata.FileInfo
+	res  OrganizeResult
+}
+
+// retryInUse tries the files that were open in another program once more, those Options.InUse
+// picks, now that every other file is done and they may have been closed; the others are reported
+// with their first result, as all of them are without retry or once the run is cancelled. A retry
+// that stops returns the files it did not attempt and why, as batch does.
+func (m *mover) retryInUse(ctx context.Context, retry bool, progress Progress) ([]metadata.FileInfo, error) {
+	held := m.held
+	m.held = nil
+	if len(held) == 0 {
+		return nil, nil
+	}
+	again := make(map[string]bool)
+	if retry && ctx.Err() == nil {
+		paths := make([]string, len(held))
+		for i, h := range held {
+			paths[i] = h.info.Path
+		}
+		for _, p := range m.opts.InUse(paths) {
+			again[p] = true
+		}
+	}
+	var files []metadata.FileInfo
+	for _, h := range held {
+		if again[h.info.Path] {
+			files = append(files, h.info)
+		} else {
+			m.report(h.res)
+		}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+	progress.Phase(PhaseRetrying)
+	logger.Info("Trying %d files again that were open in another program", len(files))
+	m.retrying = true
+	defer func() { m.retrying = false }()
+	// The collisions of their batches are settled: a name taken since goes to the next free one.
+	return m.batch(ctx, files, twins{})
+}
@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+)
+
+func TestRunRetriesInUse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		// held is how many attempts find clip.mp4 open; pick answers Options.InUse, nil leaving it unset.
+		held  int
+		pick  func([]string) []string
+		moved bool
+		// attempts is how often clip.mp4 is tried; last whether its result comes after the others.
+		attempts int
+		last     bool
+	}{
+		{name: "closed by the retry", held: 1, pick: func(p []string) []string { return p }, moved: true, attempts: 2, last: true},
+		{name: "still open", held: 2, pick: func(p []string) []string { return p }, attempts: 2, last: true},
+		{name: "skipped", held: 1, pick: func([]string) []string { return nil }, attempts: 1, last: true},
+		{name: "no retry", held: 1, attempts: 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			src, target := t.TempDir(), t.TempDir()
+			paths := writeFiles(t, src, "a.jpg", "clip.mp4", "c.jpg")
+			attempts := 0
+			move := moveFile
+			defer func() { moveFile = move }()
+			moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+				if info.Filename == "clip.mp4" {
+					if attempts++; attempts <= tc.held {
+						return "", fmt.Errorf("archive move error for %s: %w", info.Filename, organizer.ErrInUse)
+					}
+				}
+				return organizer.MoveFileContext(ctx, info, base, o)
+			}
+			var asked []string
+			opts := Options{Target: target}
+			if tc.pick != nil {
+				opts.InUse = func(p []string) []string {
+					asked = p
+					return tc.pick(p)
+				}
+			}
+			progress := &steps{}
+			sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: opts, Progress: progress})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if attempts != tc.attempts {
+				t.Errorf("clip.mp4 tried %d times; want %d", attempts, tc.attempts)
+			}
+			if tc.pick != nil && !reflect.DeepEqual(asked, paths[1:2]) {
+				t.Errorf("asked about %v; want %v", asked, paths[1:2])
+			}
+			// Each file is reported once, so the progress stays within the total.
+			if !reflect.DeepEqual(progress.done, []int{1, 2, 3}) || sum.Total != 3 || len(sum.Results) != 3 {
+				t.Fatalf("progress %v, total %d, results %d", progress.done, sum.Total, len(sum.Results))
+			}
+			retried := slices.Contains(progress.phases, PhaseRetrying)
+			if retried != (tc.attempts == 2) {
+				t.Errorf("phases %v", progress.phases)
+			}
+			i := slices.IndexFunc(sum.Results, func(r OrganizeResult) bool { return r.File == "clip.mp4" })
+			r := sum.Results[i]
+			if (i == 2) != tc.last || r.Success != tc.moved || r.Retried != (tc.attempts == 2) {
+				t.Errorf("clip.mp4 reported %d. as %+v", i+1, r)
+			}
+			wantMoved, wantInUse := 3, []string(nil)
+			if !tc.moved {
+				wantMoved, wantInUse = 2, paths[1:2]
+				if organizer.Category(r.Error) != organizer.CategoryInUse {
+					t.Errorf("clip.mp4 failed as %v", r.Error)
+				}
+			}
+			if !reflect.DeepEqual(sum.InUse, wantInUse) || sum.Moved != wantMoved {
+				t.Errorf("summary: moved %d, in use %v", sum.Moved, sum.InUse)
+			}
+		})
+	}
+}
+
+// A cancelled run reports the files held for a retry as they failed, without asking about them.
+func TestRunCancelledReportsInUse(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	paths := writeFiles(t, src, "clip.mp4", "b.jpg", "c.jpg")
+	move := moveFile
+	defer func() { moveFile = move }()
+	moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+		if info.Filename == "clip.mp4" {
+			return "", fmt.Errorf("%s: %w", info.Filename, organizer.ErrInUse)
+		}
+		return organizer.MoveFileContext(ctx, info, base, o)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	progress := &steps{cancel: func(done int) { cancel() }}
+	asked := false
+	sum, err := Run(ctx, RunSpec{Pending: paths, Options: Options{Target: target, InUse: func(p []string) []string { asked = true; return p }}, Progress: progress})
+	if err == nil || asked {
+		t.Fatalf("err %v, asked %v", err, asked)
+	}
+	if sum.Moved != 1 || len(sum.InUse) != 1 || sum.InUse[0] != paths[0] || len(sum.Results) != 2 {
+		t.Errorf("summary: moved %d, in use %v, results %d", sum.Moved, sum.InUse, len(sum.Results))
+	}
+}
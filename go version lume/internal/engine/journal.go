@@ -0,0 +1,338 @@
+package engine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"iter"
+	"os"
+	"sync"
+	"time"
+
+	"lume-go/internal/organizer"
+)
+
+// Journal defaults: the results a journal keeps decoded, and how often it flushes its file.
+const (
+	JournalRecent        = 1000
+	JournalFlushEvery    = 256
+	JournalFlushInterval = time.Second
+)
+
+// journalBuffer holds the lines between two flushes, so they reach the file together.
+const journalBuffer = 512 << 10
+
+// Journal is the results of a run streamed to a file as they come, one JSON line each, instead of
+// being held in memory: a run of any size keeps only the offset of every line and the last few
+// results, and a crash loses at most the lines of the last flush. Results read back from the file
+// keep their error's message and category (see organizer.Category) but not its type. It is safe
+// for concurrent use, so a window can read a journal its run is still writing.
+type Journal struct {
+	mu        sync.Mutex
+	f         *os.File // nil keeps every result in memory
+	w         *bufio.Writer
+	offsets   []int64
+	size      int64
+	unflushed int // lines written since the last flush
+	flushed   time.Time
+	now       func() time.Time
+
+	// recent holds the last results in a ring, the way they were reported, at index i%len(recent).
+	recent []OrganizeResult
+	all    []OrganizeResult // the results of a journal without a file
+	// discrepancies are the reconcile kinds found after the run, by destination; the lines written
+	// before are not rewritten.
+	discrepancies map[string]string
+	err           error // the read error that ended All
+}
+
+// CreateJournal starts a journal at path, replacing any file there, that keeps the last recent
+// results decoded. An empty path keeps the results in memory, as a run without a journal does.
+func CreateJournal(path string, recent int) (*Journal, error) {
+	j := &Journal{recent: make([]OrganizeResult, max(recent, 1)), now: time.Now}
+	if path == "" {
+		return j, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j.f, j.w, j.flushed = f, bufio.NewWriterSize(f, journalBuffer), j.now()
+	return j, nil
+}
+
+// OpenJournal reads back the journal a run left at path, such as one cut short by a crash, keeping
+// the last recent results decoded. A last line the crash left half written is dropped from the file,
+// so results added after follow the complete ones.
+func OpenJournal(path string, recent int) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{f: f, recent: make([]OrganizeResult, max(recent, 1)), now: time.Now}
+	rd := bufio.NewReaderSize(f, journalBuffer)
+	for {
+		line, err := rd.ReadBytes('\n')
+		if err != nil || len(line) == 0 || line[len(line)-1] != '\n' {
+			break
+		}
+		r, derr := decodeLine(line)
+		if derr != nil {
+			break
+		}
+		j.recent[len(j.offsets)%len(j.recent)] = r
+		j.offsets = append(j.offsets, j.size)
+		j.size += int64(len(line))
+	}
+	if err := f.Truncate(j.size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, err := f.Seek(j.size, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	j.w, j.flushed = bufio.NewWriterSize(f, journalBuffer), j.now()
+	return j, nil
+}
+
+// Path is the file of the journal, "" for one in memory.
+func (j *Journal) Path() string {
+	if j.f == nil {
+		return ""
+	}
+	return j.f.Name()
+}
+
+// journalLine is a result as a line of the file.
+type journalLine struct {
+	OrganizeResult
+	Error    string `json:",omitempty"`
+	Category string `json:",omitempty"`
+}
+
+// journalError is an error read back from a journal: its message, and the category it was filed
+// under so errors.Is still tells it apart.
+type journalError struct {
+	msg   string
+	cause error
+}
+
+func (e *journalError) Error() string { return e.msg }
+func (e *journalError) Unwrap() error { return e.cause }
+
+// categoryErrors are the errors organizer.Category files under each category.
+var categoryErrors = map[string]error{
+	organizer.CategorySpace:       organizer.ErrInsufficientSpace,
+	organizer.CategoryWritable:    organizer.ErrNotWritable,
+	organizer.CategoryProtected:   organizer.ErrProtected,
+	organizer.CategoryIntegrity:   organizer.ErrIntegrityMismatch,
+	organizer.CategoryUnverified:  organizer.ErrUnverified,
+	organizer.CategoryUnsupported: organizer.ErrUnsupportedType,
+	organizer.CategoryMissing:     organizer.ErrSourceMissing,
+	organizer.CategoryExists:      organizer.ErrDestinationExists,
+	organizer.CategoryRolledBack:  organizer.ErrRolledBack,
+	organizer.CategoryTimeout:     organizer.ErrTimeout,
+	organizer.CategoryInUse:       organizer.ErrInUse,
+}
+
+// Add appends r. The file is flushed every JournalFlushEvery lines and JournalFlushInterval; a result
+// that cannot be written is still counted and kept among the recent ones.
+func (j *Journal) Add(r OrganizeResult) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	n := len(j.offsets)
+	j.recent[n%len(j.recent)] = r
+	if j.f == nil {
+		j.all = append(j.all, r)
+		j.offsets = append(j.offsets, 0)
+		return nil
+	}
+	line := journalLine{OrganizeResult: r}
+	if r.Error != nil {
+		line.Error, line.Category = r.Error.Error(), organizer.Category(r.Error)
+	}
+	data, err := json.Marshal(line)
+	j.offsets = append(j.offsets, j.size)
+	if err != nil {
+		data = []byte("{}")
+	}
+	data = append(data, '\n')
+	if _, werr := j.w.Write(data); werr != nil && err == nil {
+		err = werr
+	}
+	j.size += int64(len(data))
+	if j.unflushed++; j.unflushed >= JournalFlushEvery || j.now().Sub(j.flushed) >= JournalFlushInterval {
+		if ferr := j.flush(); ferr != nil && err == nil {
+			err = ferr
+		}
+	}
+	return err
+}
+
+// Flush writes the buffered lines to the file. A nil journal has none.
+func (j *Journal) Flush() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flush()
+}
+
+func (j *Journal) flush() error {
+	if j.f == nil || j.unflushed == 0 {
+		return nil
+	}
+	j.unflushed, j.flushed = 0, j.now()
+	return j.w.Flush()
+}
+
+// Close flushes the journal and closes its file; it cannot be read after. A nil journal has none.
+func (j *Journal) Close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	err := j.flush()
+	if cerr := j.f.Close(); err == nil {
+		err = cerr
+	}
+	j.f = nil
+	j.offsets = nil
+	return err
+}
+
+// Len is the number of results added.
+func (j *Journal) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.offsets)
+}
+
+// At returns the i-th result: from the recent ones while it is among them, else read from the file.
+func (j *Journal) At(i int) (OrganizeResult, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if i < 0 || i >= len(j.offsets) {
+		return OrganizeResult{}, io.EOF
+	}
+	if j.f == nil {
+		return j.flag(j.all[i]), nil
+	}
+	if i >= len(j.offsets)-len(j.recent) {
+		return j.flag(j.recent[i%len(j.recent)]), nil
+	}
+	if err := j.flush(); err != nil {
+		return OrganizeResult{}, err
+	}
+	end := j.size
+	if i+1 < len(j.offsets) {
+		end = j.offsets[i+1]
+	}
+	buf := make([]byte, end-j.offsets[i])
+	if _, err := j.f.ReadAt(buf, j.offsets[i]); err != nil {
+		return OrganizeResult{}, err
+	}
+	r, err := decodeLine(buf)
+	return j.flag(r), err
+}
+
+// All returns every result in the order they were added, reading the file from the start. Results
+// added while it runs are not included. A read error ends it early; it is returned by Err.
+func (j *Journal) All() iter.Seq[OrganizeResult] {
+	return func(yield func(OrganizeResult) bool) {
+		j.mu.Lock()
+		n, mem := len(j.offsets), j.f == nil
+		if mem {
+			all := j.all[:n:n]
+			j.mu.Unlock()
+			for _, r := range all {
+				if !yield(j.flagged(r)) {
+					return
+				}
+			}
+			return
+		}
+		err := j.flush()
+		size := j.size
+		j.mu.Unlock()
+		if err != nil {
+			j.fail(err)
+			return
+		}
+		sc := bufio.NewScanner(io.NewSectionReader(j.f, 0, size))
+		sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for i := 0; i < n && sc.Scan(); i++ {
+			r, err := decodeLine(sc.Bytes())
+			if err != nil {
+				j.fail(err)
+				return
+			}
+			if !yield(j.flagged(r)) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			j.fail(err)
+		}
+	}
+}
+
+// fail keeps err for Err.
+func (j *Journal) fail(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.err = err
+}
+
+// Err is the error that ended the last All early, if any.
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Flag records the reconcile kinds found after the run by destination; the results archived there
+// read back with theirs. A nil journal ignores them.
+func (j *Journal) Flag(kinds map[string]string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.discrepancies = kinds
+}
+
+// flag sets the discrepancy of r. The caller holds j.mu.
+func (j *Journal) flag(r OrganizeResult) OrganizeResult {
+	if r.Success {
+		if kind, ok := j.discrepancies[r.Dest]; ok {
+			r.Discrepancy = kind
+		}
+	}
+	return r
+}
+
+func (j *Journal) flagged(r OrganizeResult) OrganizeResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.flag(r)
+}
+
+func decodeLine(b []byte) (OrganizeResult, error) {
+	var line journalLine
+	if err := json.Unmarshal(bytes.TrimSpace(b), &line); err != nil {
+		return OrganizeResult{}, err
+	}
+	r := line.OrganizeResult
+	if line.Error != "" || line.Category != "" {
+		r.Error = &journalError{msg: line.Error, cause: categoryErrors[line.Category]}
+	}
+	return r, nil
+}
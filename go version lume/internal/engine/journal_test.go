@@ -0,0 +1,200 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"lume-go/internal/fsys"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	j, err := CreateJournal(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	want := []OrganizeResult{
+		{Success: true, File: "a.jpg", Path: "/card/a.jpg", Dest: "/archive/a.jpg", Size: 1, Date: time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)},
+		{File: "b.jpg", Path: "/card/b.jpg", Error: fmt.Errorf("copy of b.jpg: %w", organizer.ErrIntegrityMismatch)},
+		{Missing: true, File: "c.jpg", Path: "/card/c.jpg", Error: organizer.ErrSourceMissing},
+		{Success: true, File: "d.jpg", Path: "/card/d.jpg", Dest: "/archive/d.jpg"},
+	}
+	for _, r := range want {
+		if err := j.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	j.Flag(map[string]string{"/archive/a.jpg": "missing"})
+
+	if j.Len() != len(want) {
+		t.Fatalf("Len = %d", j.Len())
+	}
+	// The first two are read back from the file, the last two are the recent ones.
+	for i, w := range want {
+		got, err := j.At(i)
+		if err != nil {
+			t.Fatalf("At(%d): %v", i, err)
+		}
+		if got.Path != w.Path || got.Dest != w.Dest || got.Success != w.Success || !got.Date.Equal(w.Date) {
+			t.Errorf("At(%d) = %+v; want %+v", i, got, w)
+		}
+		if (got.Error == nil) != (w.Error == nil) || w.Error != nil && (got.Error.Error() != w.Error.Error() || organizer.Category(got.Error) != organizer.Category(w.Error)) {
+			t.Errorf("At(%d) error %v; want %v", i, got.Error, w.Error)
+		}
+	}
+	if r, _ := j.At(0); r.Discrepancy != "missing" {
+		t.Errorf("the flagged result reads back with discrepancy %q", r.Discrepancy)
+	}
+	var paths []string
+	for r := range j.All() {
+		paths = append(paths, r.Path)
+		if r.File == "b.jpg" && !errors.Is(r.Error, organizer.ErrIntegrityMismatch) {
+			t.Errorf("the error of b.jpg lost its category: %v", r.Error)
+		}
+	}
+	if len(paths) != len(want) || j.Err() != nil {
+		t.Errorf("All read %v, err %v", paths, j.Err())
+	}
+
+	// Every line is on disk, as a crash would leave it, without a Close.
+	data, _ := os.ReadFile(path)
+	if n := strings.Count(string(data), "\n"); n != len(want) {
+		t.Errorf("%d lines on disk; want %d", n, len(want))
+	}
+}
+
+func TestJournalFlushes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	j, _ := CreateJournal(path, JournalRecent)
+	defer j.Close()
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	j.now, j.flushed = clock.now, clock.t
+	lines := func() int {
+		data, _ := os.ReadFile(path)
+		return strings.Count(string(data), "\n")
+	}
+	for i := 0; i < JournalFlushEvery-1; i++ {
+		j.Add(OrganizeResult{File: "a.jpg"})
+	}
+	if n := lines(); n != 0 {
+		t.Errorf("%d lines flushed before the batch was full", n)
+	}
+	j.Add(OrganizeResult{File: "a.jpg"})
+	if n := lines(); n != JournalFlushEvery {
+		t.Errorf("%d lines flushed with the batch full; want %d", n, JournalFlushEvery)
+	}
+	j.Add(OrganizeResult{File: "a.jpg"})
+	clock.t = clock.t.Add(JournalFlushInterval)
+	j.Add(OrganizeResult{File: "a.jpg"})
+	if n := lines(); n != JournalFlushEvery+2 {
+		t.Errorf("%d lines flushed after the interval; want %d", n, JournalFlushEvery+2)
+	}
+}
+
+func TestOpenJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	j, _ := CreateJournal(path, JournalRecent)
+	for _, name := range []string{"a.jpg", "b.jpg", "c.jpg"} {
+		j.Add(OrganizeResult{Success: true, File: name, Path: "/card/" + name})
+	}
+	j.Add(OrganizeResult{File: "d.jpg", Path: "/card/d.jpg", Error: organizer.ErrTimeout})
+	j.Close()
+	// The crash came in the middle of the next line.
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"Success":true,"File":"e.j`)
+	f.Close()
+
+	j, err := OpenJournal(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	if j.Len() != 4 {
+		t.Fatalf("Len = %d; want the 4 complete lines", j.Len())
+	}
+	if r, err := j.At(0); err != nil || r.File != "a.jpg" {
+		t.Errorf("At(0) = %+v, %v", r, err)
+	}
+	if r, _ := j.At(3); !errors.Is(r.Error, organizer.ErrTimeout) {
+		t.Errorf("the last result reads back with error %v", r.Error)
+	}
+	// Results added after follow the complete ones, the half line gone.
+	if err := j.Add(OrganizeResult{File: "f.jpg", Path: "/card/f.jpg"}); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for r := range j.All() {
+		files = append(files, r.File)
+	}
+	if strings.Join(files, " ") != "a.jpg b.jpg c.jpg d.jpg f.jpg" || j.Err() != nil {
+		t.Errorf("All read %v, err %v", files, j.Err())
+	}
+	if _, err := OpenJournal(filepath.Join(t.TempDir(), "none.jsonl"), 2); err == nil {
+		t.Error("a missing journal opened")
+	}
+}
+
+// A run streamed to a journal holds its results on disk, not in memory, however many files it has.
+func TestRunJournalMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("moves 50,000 files")
+	}
+	const n = 50000
+	m := fsys.NewMem()
+	root, src := filepath.Join(string(filepath.Separator), "archive"), filepath.Join(string(filepath.Separator), "card")
+	m.MkdirAll(root, 0755)
+	files := make([]metadata.FileInfo, n)
+	for i := range files {
+		name := fmt.Sprintf("IMG_%05d.JPG", i)
+		path := filepath.Join(src, fmt.Sprintf("%03d", i/500), name)
+		m.WriteFile(path, []byte{byte(i)})
+		files[i] = metadata.FileInfo{Path: path, Filename: name, Size: 1, Year: "2024", Month: "05", Source: "Camera", DateSource: metadata.DateFromExif}
+	}
+	heap := func() uint64 {
+		runtime.GC()
+		var ms runtime.MemStats
+		runtime.ReadMemStats(&ms)
+		return ms.HeapAlloc
+	}
+
+	j, err := CreateJournal(filepath.Join(t.TempDir(), "results.jsonl"), JournalRecent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer j.Close()
+	before := heap()
+	sum, err := Run(context.Background(), RunSpec{Files: files, Journal: j, Options: Options{Target: root, FS: m}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := heap()
+	if sum.Moved != n || sum.Errors != 0 || len(sum.Results) != 0 || j.Len() != n {
+		t.Fatalf("moved %d, errors %d, %d results kept, %d journaled", sum.Moved, sum.Errors, len(sum.Results), j.Len())
+	}
+	// The offsets and the recent results take well under a megabyte; the results themselves would
+	// take tens.
+	const ceiling = 4 << 20
+	if after > before && after-before > ceiling {
+		t.Errorf("the run kept %d bytes; want at most %d", after-before, ceiling)
+	}
+	count := 0
+	for r := range sum.All() {
+		if r.Success {
+			count++
+		}
+	}
+	if count != n {
+		t.Errorf("the journal reads back %d archived files; want %d", count, n)
+	}
+	runtime.KeepAlive(files)
+}
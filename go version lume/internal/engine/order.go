@@ -0,0 +1,58 @@
+package engine
+
+import (
+	"sort"
+	"time"
+
+	"lume-go/internal/metadata"
+)
+
+// The orders a run processes its files in (see Options.Order), so that a run cancelled halfway has
+// archived the files that matter most rather than whichever came first.
+const (
+	OrderInput    = "input"    // as they were dropped or found, the default
+	OrderNewest   = "newest"   // latest date first
+	OrderOldest   = "oldest"   // earliest date first
+	OrderSmallest = "smallest" // smallest file first, the quick wins
+)
+
+// Orders lists the orders in display order.
+var Orders = []string{OrderInput, OrderNewest, OrderOldest, OrderSmallest}
+
+// ValidOrder reports whether order names one of Orders; "" is OrderInput.
+func ValidOrder(order string) bool {
+	if order == "" {
+		return true
+	}
+	for _, o := range Orders {
+		if o == order {
+			return true
+		}
+	}
+	return false
+}
+
+// orderBefore reports whether a file of date a and size as goes before one of date b and size bs in
+// order, or false for ties and for OrderInput.
+func orderBefore(order string, a, b time.Time, as, bs int64) bool {
+	switch order {
+	case OrderNewest:
+		return a.After(b)
+	case OrderOldest:
+		return a.Before(b)
+	case OrderSmallest:
+		return as < bs
+	}
+	return false
+}
+
+// sortFiles puts files in order by their resolved date or their size. Ties keep the
+// order they came in, and so does OrderInput.
+func sortFiles(files []metadata.FileInfo, order string) {
+	if order == "" || order == OrderInput {
+		return
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return orderBefore(order, files[i].Date, files[j].Date, files[i].Size, files[j].Size)
+	})
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"lume-go/internal/metadata"
+)
+
+func TestSortFiles(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 5, d, 12, 0, 0, 0, time.UTC) }
+	files := []metadata.FileInfo{
+		{Path: "a", Date: day(2), Size: 300},
+		{Path: "b", Date: day(3), Size: 100},
+		{Path: "c", Date: day(1), Size: 200},
+		{Path: "d", Date: day(3), Size: 100},
+	}
+	for _, tc := range []struct {
+		order, want string
+	}{
+		{"", "abcd"},
+		{OrderInput, "abcd"},
+		{OrderNewest, "bdac"},
+		{OrderOldest, "cabd"},
+		{OrderSmallest, "bdca"},
+	} {
+		got := slices.Clone(files)
+		sortFiles(got, tc.order)
+		var paths string
+		for _, f := range got {
+			paths += f.Path
+		}
+		if paths != tc.want {
+			t.Errorf("%q: order %s; want %s", tc.order, paths, tc.want)
+		}
+	}
+}
+
+func TestValidOrder(t *testing.T) {
+	for _, o := range append([]string{""}, Orders...) {
+		if !ValidOrder(o) {
+			t.Errorf("ValidOrder(%q) = false", o)
+		}
+	}
+	if ValidOrder("largest") {
+		t.Error("ValidOrder(largest) = true")
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	// Three days of photos, two of them taking the same name on the same day, the one of the later
+	// path shot later: batches drawn newest first would move it first.
+	sources := []struct {
+		path, content string
+		day, hour     int
+	}{
+		{"A/IMG_0001.JPG", "first of the fifth", 5, 10},
+		{"B/IMG_0001.JPG", "second of the fifth, larger", 5, 15},
+		{"A/IMG_0002.JPG", "the third, the largest of them all", 3, 12},
+		{"A/IMG_0003.JPG", "the ninth", 9, 12},
+	}
+	run := func(order string, batch int) (map[string]string, []string) {
+		src, target := t.TempDir(), t.TempDir()
+		var paths []string
+		for _, s := range sources {
+			p := filepath.Join(src, filepath.FromSlash(s.path))
+			os.MkdirAll(filepath.Dir(p), 0755)
+			os.WriteFile(p, []byte(s.content), 0644)
+			mtime := time.Date(2023, 5, s.day, s.hour, 0, 0, 0, time.Local)
+			os.Chtimes(p, mtime, mtime)
+			paths = append(paths, p)
+		}
+		sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Order: order, BatchSize: batch}})
+		if err != nil || sum.Errors != 0 || sum.Total != len(sources) {
+			t.Fatalf("%s: err %v, summary %+v", order, err, sum)
+		}
+		var done []string
+		for _, r := range sum.Results {
+			rel, _ := filepath.Rel(src, r.Path)
+			done = append(done, filepath.ToSlash(rel))
+		}
+		return tree(t, target), done
+	}
+
+	want, _ := run(OrderInput, 0)
+	for _, tc := range []struct {
+		order string
+		batch int
+		done  []string
+	}{
+		{OrderNewest, 0, []string{"A/IMG_0003.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0002.JPG"}},
+		{OrderOldest, 0, []string{"A/IMG_0002.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0003.JPG"}},
+		{OrderSmallest, 0, []string{"A/IMG_0003.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0002.JPG"}},
+		{OrderNewest, 1, []string{"A/IMG_0003.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0002.JPG"}},
+		{OrderOldest, 1, []string{"A/IMG_0002.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0003.JPG"}},
+		{OrderSmallest, 3, []string{"A/IMG_0003.JPG", "A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0002.JPG"}},
+		{OrderInput, 1, []string{"A/IMG_0001.JPG", "B/IMG_0001.JPG", "A/IMG_0002.JPG", "A/IMG_0003.JPG"}},
+	} {
+		got, done := run(tc.order, tc.batch)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s, batches of %d: archived %v; in input order %v", tc.order, tc.batch, got, want)
+		}
+		if !reflect.DeepEqual(done, tc.done) {
+			t.Errorf("%s, batches of %d: processed %v; want %v", tc.order, tc.batch, done, tc.done)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"lume-go/internal/history"
+	"lume-go/internal/index"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/plan"
+	"lume-go/internal/routing"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+)
+
+// PlanFiles routes the files of a run to their targets and, going by layout, their folders, for
+// plan.Make. Pending paths have no metadata yet, so they are routed by name, their size read from the
+// disk and their folder left unknown. Files found under roots keep the folders of their source where
+// preserve or their route says so, as the run files them (see Options.PreserveStructure).
+func PlanFiles(router routing.Router, layout organizer.Layout, infos []metadata.FileInfo, pending []string, roots []string, preserve bool) []plan.File {
+	files := make([]plan.File, 0, len(infos)+len(pending))
+	structure := func(path, template string, l organizer.Layout) (organizer.Layout, bool) {
+		if template != organizer.StructureTemplate && (template != "" || !preserve) {
+			return l, false
+		}
+		return organizer.Structure(path, roots, l)
+	}
+	for _, f := range infos {
+		target, template := router.Route(f)
+		l := layout
+		if template != "" && template != organizer.StructureTemplate {
+			l.Template = template
+		}
+		l, kept := structure(f.Path, template, l)
+		files = append(files, plan.File{Path: f.Path, Size: f.Size, Target: target, Dir: organizer.TargetDir(f, target, l), Suspect: f.DateSuspect, Structure: kept})
+	}
+	for _, p := range pending {
+		st, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		f := metadata.FileInfo{Path: p, Filename: filepath.Base(p), Size: st.Size(), Source: metadata.DetectSource(filepath.Base(p), filepath.Dir(p))}
+		target, template := router.Route(f)
+		_, kept := structure(p, template, layout)
+		files = append(files, plan.File{Path: p, Size: f.Size, Target: target, Structure: kept})
+	}
+	return files
+}
+
+// MakePlan works out the plan of files going to target. Duplicates are looked up in the target index
+// as last saved when useIndex is set; nothing is read from the files themselves.
+func MakePlan(files []plan.File, target string, useIndex bool, rates *history.History, protect *validator.Protection) plan.Plan {
+	opts := plan.Options{Volume: validator.VolumeSerial, Rates: rates, Protect: protect}
+	if ix, ok := index.Saved(target); ok && useIndex {
+		opts.Duplicate = func(f plan.File) bool {
+			_, dup := ix.LookupName(filepath.Base(f.Path), f.Size)
+			return f.Target == target && dup
+		}
+	}
+	return plan.Make(files, opts)
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lume-go/internal/imports"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+)
+
+// previous skips the files earlier runs archived and left in their source, unchanged since, and
+// stamps the ones this run archives and leaves (see imports). A nil *previous does neither.
+type previous struct {
+	store   *imports.Store
+	recheck bool
+	serials map[string]string        // the volume serial of each folder, "" when unknown
+	grains  map[string]time.Duration // the time granularity of the file system of each folder
+	now     time.Time
+}
+
+func newPrevious(opts Options) *previous {
+	if opts.Imports == nil {
+		return nil
+	}
+	return &previous{store: opts.Imports, recheck: opts.Recheck, serials: make(map[string]string), grains: make(map[string]time.Duration), now: time.Now()}
+}
+
+// serial names the volume holding path, asked once per folder.
+func (p *previous) serial(path string) string {
+	dir := filepath.Dir(path)
+	s, ok := p.serials[dir]
+	if !ok {
+		s, _ = validator.VolumeSerial(dir)
+		p.serials[dir] = s
+	}
+	return s
+}
+
+// granularity is how coarsely the file system holding path keeps modification times, asked once per
+// folder.
+func (p *previous) granularity(path string) time.Duration {
+	dir := filepath.Dir(path)
+	g, ok := p.grains[dir]
+	if !ok {
+		g = validator.TimeGranularity(fileSystem(dir))
+		p.grains[dir] = g
+	}
+	return g
+}
+
+// imported returns the result of a file of size modified at mtime that an earlier run archived, when
+// it is unchanged since and its archived copy is still there with its size. The stamp of a file that
+// changed, or whose copy did, is dropped: the file is organized again.
+func (p *previous) imported(path string, size int64, mtime time.Time) (OrganizeResult, bool) {
+	serial := p.serial(path)
+	if serial == "" {
+		return OrganizeResult{}, false
+	}
+	st, ok := p.store.Lookup(serial, path)
+	if !ok {
+		return OrganizeResult{}, false
+	}
+	if !st.Matches(size, mtime, p.granularity(path)) || !st.Archived() {
+		logger.Info("%s or its archived copy %s changed since it was imported; organized again", path, st.Dest)
+		p.store.Forget(serial, path)
+		return OrganizeResult{}, false
+	}
+	return OrganizeResult{Previously: true, File: filepath.Base(path), Path: path, Dest: st.Dest, Size: size}, true
+}
+
+// skip takes the files and pending paths imported before out of a run and returns their results.
+// With Recheck nothing is skipped.
+func (p *previous) skip(files []metadata.FileInfo, pending []string) ([]metadata.FileInfo, []string, []OrganizeResult) {
+	if p == nil || p.recheck {
+		return files, pending, nil
+	}
+	var done []OrganizeResult
+	keptFiles := make([]metadata.FileInfo, 0, len(files))
+	for _, info := range files {
+		if r, ok := p.imported(info.Path, info.Size, info.ModTime); ok {
+			r.File = info.Filename
+			done = append(done, r)
+			continue
+		}
+		keptFiles = append(keptFiles, info)
+	}
+	keptPending := make([]string, 0, len(pending))
+	for _, path := range pending {
+		if st, err := os.Stat(path); err == nil {
+			if r, ok := p.imported(path, st.Size(), st.ModTime()); ok {
+				done = append(done, r)
+				continue
+			}
+		}
+		keptPending = append(keptPending, path)
+	}
+	if len(done) > 0 {
+		logger.Info("%d files were imported before and are unchanged since; skipped", len(done))
+	}
+	return keptFiles, keptPending, done
+}
+
+// stamp fingerprints the source of an archived file that is still there, as a duplicate or a copy
+// from a write-protected card is, so the next import skips it.
+func (p *previous) stamp(info metadata.FileInfo, r OrganizeResult) {
+	if p == nil || !r.Success || r.Dest == "" {
+		return
+	}
+	st, err := os.Stat(info.Path)
+	if err != nil || !st.Mode().IsRegular() {
+		return
+	}
+	if serial := p.serial(info.Path); serial != "" {
+		p.store.Put(serial, info.Path, imports.NewStamp(st.Size(), st.ModTime(), r.Dest), p.now)
+	}
+}
+
+// Mismatch is a file skipped as imported before that did not pass VerifyPrevious: it differs from its
+// archived copy, or Err tells why the two could not be compared.
+type Mismatch struct {
+	Path, Dest string
+	Err        error
+}
+
+// VerifyPrevious reads the files skipped as imported before together with their archived copies, the
+// check the skip leaves out, and returns those that do not match. A file whose copy differs or is gone
+// loses its stamp, so the next run organizes it again; one that could not be read keeps it. Results
+// that were not skipped are ignored. progress, if not nil, is told of each file done.
+func VerifyPrevious(ctx context.Context, store *imports.Store, results []OrganizeResult, progress func(done, total int)) ([]Mismatch, error) {
+	var bad []Mismatch
+	for i, r := range results {
+		if err := ctx.Err(); err != nil {
+			return bad, err
+		}
+		if r.Previously {
+			if m, ok := verifyPrevious(store, r); !ok {
+				bad = append(bad, m)
+			}
+		}
+		if progress != nil {
+			progress(i+1, len(results))
+		}
+	}
+	return bad, nil
+}
+
+// verifyPrevious compares one skipped file with its archived copy.
+func verifyPrevious(store *imports.Store, r OrganizeResult) (Mismatch, bool) {
+	same, err := organizer.IsDuplicate(r.Path, r.Dest)
+	if err == nil && same {
+		return Mismatch{}, true
+	}
+	if _, statErr := os.Stat(r.Dest); err == nil || errors.Is(statErr, os.ErrNotExist) {
+		if serial, _ := validator.VolumeSerial(filepath.Dir(r.Path)); serial != "" && store != nil {
+			logger.Info("%s does not match its archived copy %s; organized again next time", r.Path, r.Dest)
+			store.Forget(serial, r.Path)
+		}
+	}
+	return Mismatch{Path: r.Path, Dest: r.Dest, Err: err}, false
+}
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lume-go/internal/imports"
+	"lume-go/internal/validator"
+)
+
+func TestRunSkipsPreviousImports(t *testing.T) {
+	// A card holding each photo twice: the second copies are left on it as duplicates and stamped.
+	card, target := t.TempDir(), t.TempDir()
+	mtime := time.Date(2023, 5, 6, 12, 0, 0, 0, time.Local)
+	write := func(rel, content string) string {
+		p := filepath.Join(card, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+		os.Chtimes(p, mtime, mtime)
+		return p
+	}
+	var paths, kept []string
+	for _, name := range []string{"IMG_0001.JPG", "IMG_0002.JPG", "IMG_0003.JPG"} {
+		paths = append(paths, write("100CANON/"+name, name), write("101CANON/"+name, name))
+		kept = append(kept, filepath.Join(card, "101CANON", name))
+	}
+	store, err := imports.Load(filepath.Join(t.TempDir(), imports.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(pending []string, recheck bool) RunSummary {
+		t.Helper()
+		sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, Imports: store, Recheck: recheck}})
+		if err != nil || sum.Errors != 0 {
+			t.Fatalf("err %v, summary %+v", err, sum)
+		}
+		return sum
+	}
+	previously := func(sum RunSummary) map[string]bool {
+		got := make(map[string]bool)
+		for _, r := range sum.Results {
+			if r.Previously {
+				got[r.Path] = true
+			}
+		}
+		return got
+	}
+
+	first := run(paths, false)
+	dest := make(map[string]string)
+	for _, r := range first.Results {
+		dest[r.Path] = r.Dest
+	}
+	for _, p := range kept {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("duplicate %s not left on the card: %v", p, err)
+		}
+	}
+
+	// Between the imports the second photo is edited on the card, the archived copy of the third is
+	// removed and a new photo is taken.
+	later := mtime.Add(time.Hour)
+	os.Chtimes(kept[1], later, later)
+	os.Remove(dest[kept[2]])
+	fresh := write("101CANON/IMG_0004.JPG", "IMG_0004.JPG")
+
+	second := run(append(append([]string{}, kept...), fresh), false)
+	got := previously(second)
+	if len(got) != 1 || !got[kept[0]] || second.Previously != 1 {
+		t.Errorf("skipped %v (%d); want only %s", got, second.Previously, kept[0])
+	}
+	for _, r := range second.Results {
+		if r.Previously && r.Dest != dest[kept[0]] {
+			t.Errorf("%s skipped as archived at %s; want %s", r.Path, r.Dest, dest[kept[0]])
+		}
+	}
+	// Forcing a full re-check organizes it again.
+	if got := previously(run([]string{kept[0]}, true)); len(got) != 0 {
+		t.Errorf("re-check skipped %v", got)
+	}
+}
+
+func TestVerifyPrevious(t *testing.T) {
+	card, target := t.TempDir(), t.TempDir()
+	store, err := imports.Load(filepath.Join(t.TempDir(), imports.FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := validator.VolumeSerial(card)
+	if err != nil {
+		t.Skip("no volume serial:", err)
+	}
+	// Three files skipped as imported before: one matches its copy, one's copy was altered in place,
+	// keeping its size, and one's copy is gone.
+	var results []OrganizeResult
+	for _, name := range []string{"same.jpg", "altered.jpg", "gone.jpg"} {
+		src, dest := filepath.Join(card, name), filepath.Join(target, name)
+		os.WriteFile(src, []byte("photo "+name), 0644)
+		os.WriteFile(dest, []byte("photo "+name), 0644)
+		st, _ := os.Stat(src)
+		store.Put(serial, src, imports.NewStamp(st.Size(), st.ModTime(), dest), time.Now())
+		results = append(results, OrganizeResult{Previously: true, Path: src, Dest: dest, Size: st.Size()})
+	}
+	os.WriteFile(results[1].Dest, []byte("PHOTO altered.jpg"), 0644)
+	os.Remove(results[2].Dest)
+	results = append(results, OrganizeResult{Success: true, Path: filepath.Join(card, "moved.jpg")})
+
+	var done int
+	bad, err := VerifyPrevious(context.Background(), store, results, func(d, total int) { done = d })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bad) != 2 || bad[0].Path != results[1].Path || bad[0].Err != nil || bad[1].Path != results[2].Path || bad[1].Err == nil {
+		t.Errorf("mismatches %+v; want the altered copy, then the missing one", bad)
+	}
+	if done != len(results) {
+		t.Errorf("progress reached %d of %d", done, len(results))
+	}
+	for i, want := range []bool{true, false, false} {
+		if _, ok := store.Lookup(serial, results[i].Path); ok != want {
+			t.Errorf("stamp of %s kept %v; want %v", results[i].Path, ok, want)
+		}
+	}
+}
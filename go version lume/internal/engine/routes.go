@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"lume-go/internal/catalog"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/monthindex"
+	"lume-go/internal/organizer"
+	"lume-go/internal/routing"
+	"lume-go/internal/sums"
+	"lume-go/internal/validator"
+	"sync"
+)
+
+// fileSystem names the file system of a target; tests replace it.
+var fileSystem = validator.FileSystem
+
+// routes resolves the target of each file during a run. Routed targets get their own catalog,
+// checksum lists and month indexes; the target index only covers the default target, so it is not consulted for
+// routed files. mu guards the lists opened as files move side by side.
+type routes struct {
+	mu       sync.Mutex
+	router   routing.Router
+	catalog  bool
+	catalogs map[string]*catalog.Catalog
+	sums     map[string]*sums.Sums
+	months   map[string]*monthindex.Index
+	// fileSystems caches the file system of each target, asked once per run.
+	fileSystems map[string]string
+}
+
+func newRoutes(opts Options) *routes {
+	rt := &routes{router: routing.Router{Rules: opts.Routes, Default: opts.Target}, catalog: opts.Catalog, catalogs: make(map[string]*catalog.Catalog), fileSystems: make(map[string]string)}
+	if opts.Checksums {
+		rt.sums = make(map[string]*sums.Sums)
+	}
+	if opts.MonthIndex {
+		rt.months = make(map[string]*monthindex.Index)
+	}
+	return rt
+}
+
+// options returns the target base for info and the move options adjusted for it.
+func (rt *routes) options(info metadata.FileInfo, opts organizer.Options) (string, organizer.Options) {
+	base, template := rt.router.Route(info)
+	if template != "" {
+		opts.Layout.Template = template
+	}
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	fs, ok := rt.fileSystems[base]
+	if !ok {
+		fs = fileSystem(base)
+		rt.fileSystems[base] = fs
+		if limit := validator.MaxFileSize(fs); limit > 0 {
+			logger.Info("%s is on %s: files over %d bytes cannot be archived there", base, fs, limit)
+		}
+	}
+	opts.FileSystem = fs
+	if rt.sums != nil {
+		if rt.sums[base] == nil {
+			rt.sums[base] = sums.New(base)
+		}
+		opts.Sums = rt.sums[base]
+	}
+	if rt.months != nil {
+		if rt.months[base] == nil {
+			rt.months[base] = monthindex.New(base)
+		}
+		opts.MonthIndex = rt.months[base]
+	}
+	if base == rt.router.Default {
+		return base, opts
+	}
+	opts.Index, opts.Catalog = nil, nil
+	if rt.catalog {
+		cat, ok := rt.catalogs[base]
+		if !ok {
+			var err error
+			if cat, err = catalog.Open(base); err != nil {
+				logger.Error("Catalog unavailable for %s: %v", base, err)
+			}
+			rt.catalogs[base] = cat
+		}
+		opts.Catalog = cat
+	}
+	return base, opts
+}
+
+// markBursts groups the continuous shots of a run for the {burst} token, joining bursts that an
+// earlier run started in the same target.
+func (rt *routes) markBursts(files []metadata.FileInfo, opts organizer.Options) {
+	if opts.Layout.Burst.MinCount < 2 {
+		return
+	}
+	organizer.MarkBursts(files, opts.Layout.Burst)
+	organizer.JoinBursts(files, func(info metadata.FileInfo) (string, organizer.Layout) {
+		base, o := rt.options(info, opts)
+		return base, o.Layout
+	})
+}
+
+// poolDevices sends the devices with few files in a month of a run to the month's shared folder.
+func (rt *routes) poolDevices(files []metadata.FileInfo, opts organizer.Options) {
+	organizer.PoolSmallDevices(files, func(info metadata.FileInfo) (string, organizer.Layout) {
+		base, o := rt.options(info, opts)
+		return base, o.Layout
+	})
+}
+
+// flush writes the checksums and month indexes of a finished batch; lists that stay locked are
+// retried after the next one.
+func (rt *routes) flush() {
+	for base, s := range rt.sums {
+		if err := s.Flush(); err != nil {
+			logger.Error("Checksums deferred for %s (%d queued): %v", base, s.Queued(), err)
+		}
+	}
+	for base, x := range rt.months {
+		if err := x.Flush(); err != nil {
+			logger.Error("Month indexes deferred for %s (%d queued): %v", base, x.Queued(), err)
+		}
+	}
+}
+
+func (rt *routes) close() {
+	for base, s := range rt.sums {
+		if err := s.Close(); err != nil {
+			logger.Error("Checksums close for %s: %v", base, err)
+		}
+	}
+	for base, x := range rt.months {
+		if err := x.Close(); err != nil {
+			logger.Error("Month indexes close for %s: %v", base, err)
+		}
+	}
+	for base, cat := range rt.catalogs {
+		if cat == nil {
+			continue
+		}
+		if err := cat.Close(); err != nil {
+			logger.Error("Catalog close for %s: %v", base, err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lume-go/internal/metadata"
+)
+
+// The shifted date goes into the archived copy once it is verified, and what is recorded of the copy
+// is its content after that.
+func TestRunWritesShiftIntoArchivedCopy(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	data, err := os.ReadFile(filepath.Join("testdata", "shift.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(src, "IMG_0001.JPG")
+	os.WriteFile(path, data, 0644)
+	opts := Options{Target: target, Shifts: []metadata.Shift{{Years: 1}}, ShiftExif: true}
+	sum, err := Run(context.Background(), RunSpec{Pending: []string{path}, Options: opts})
+	if err != nil || len(sum.Results) != 1 {
+		t.Fatalf("Run = %+v, %v", sum, err)
+	}
+	r := sum.Results[0]
+	if !r.Success || !r.ExifWritten || r.ShiftedFrom.Year() != 2013 {
+		t.Fatalf("result %+v", r)
+	}
+	info, err := metadata.GetFileInfo(r.Dest)
+	if err != nil || info.Date.Year() != 2014 || info.DateSource != metadata.DateFromExif {
+		t.Errorf("archived copy dated %s (%s), %v; want 2014", info.Date, info.DateSource, err)
+	}
+	if hashed, _ := metadata.GetFileHash(r.Dest); r.MD5 != hashed {
+		t.Errorf("MD5 %s; want the archived copy's %s", r.MD5, hashed)
+	}
+	if copied, _ := os.ReadFile(r.Dest); bytes.Equal(copied, data) || len(copied) != len(data) {
+		t.Error("the archived copy is not the source with its date rewritten")
+	}
+}
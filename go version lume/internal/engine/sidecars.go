@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"io/fs"
+	"lume-go/internal/catalog"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+)
+
+// adoptOrphans looks through the roots of a run for camcorder sidecars left without their video,
+// such as those of videos an earlier run archived before sidecars went along, and moves each beside
+// the archived copy of its video that a catalog of the run's targets records. The sidecars it cannot
+// place are returned for the report. Target folders are not searched.
+func adoptOrphans(ctx context.Context, opts Options, mo organizer.Options, rt *routes) []string {
+	targets := []string{opts.Target}
+	for _, r := range opts.Routes {
+		targets = append(targets, r.Target)
+	}
+	seen := make(map[string]bool)
+	var orphans []string
+	for _, root := range opts.Roots {
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				for _, t := range targets {
+					if validator.IsSubPath(t, path) {
+						return filepath.SkipDir
+					}
+				}
+				return nil
+			}
+			if _, _, ok := metadata.SidecarSuffix(path); !ok || seen[path] {
+				return nil
+			}
+			seen[path] = true
+			if _, ok := metadata.SidecarVideo(path); !ok {
+				orphans = append(orphans, path)
+			}
+			return nil
+		})
+	}
+	if len(orphans) == 0 {
+		return nil
+	}
+
+	cats := []*catalog.Catalog{mo.Catalog}
+	for _, cat := range rt.catalogs {
+		cats = append(cats, cat)
+	}
+	var records []catalog.Record
+	for _, cat := range cats {
+		if cat == nil {
+			continue
+		}
+		rs, err := cat.Query(catalog.Filter{})
+		if err != nil {
+			logger.Error("Catalog unreadable, orphaned sidecars not matched: %v", err)
+			continue
+		}
+		records = append(records, rs...)
+	}
+	var left []string
+	for _, sc := range orphans {
+		// The latest record of the video wins, should it have been archived more than once.
+		video := ""
+		for _, r := range records {
+			if metadata.OwnsSidecar(r.Original, sc) {
+				video = r.Path
+			}
+		}
+		if video != "" {
+			if _, err := os.Stat(video); err == nil && len(organizer.MoveSidecars(ctx, []string{sc}, video, mo)) > 0 {
+				continue
+			}
+		}
+		logger.Info("Orphaned sidecar: %s", sc)
+		left = append(left, sc)
+	}
+	return left
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lume-go/internal/organizer"
+)
+
+func TestRunMovesSidecars(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	fixture := func(from, to string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join("..", "metadata", "testdata", "camcorder", from))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := filepath.Join(src, to)
+		if err := os.WriteFile(p, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	run := func(pending ...string) RunSummary {
+		t.Helper()
+		sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, Layout: organizer.Layout{Template: "{year}/{month}"},
+			Catalog: true, Roots: []string{src}}})
+		if err != nil || sum.Moved != len(pending) {
+			t.Fatalf("err %v, summary %+v", err, sum)
+		}
+		return sum
+	}
+	exists := func(rel string) bool {
+		_, err := os.Stat(filepath.Join(target, filepath.FromSlash(rel)))
+		return err == nil
+	}
+
+	// An earlier run archived C0003.MP4 before its sidecar was there to go along.
+	run(fixture("C0001.MP4", "C0003.MP4"))
+	fixture("C0002M01.XML", "C0003M01.XML")
+	fixture("C0002M01.XML", "C0009M01.XML")
+
+	// The sidecar's date files the video, and the sidecar goes with it.
+	fixture("C0001M01.XML", "C0001M01.XML")
+	sum := run(fixture("C0001.MP4", "C0001.MP4"))
+	if !exists("2020/07/C0001.MP4") || !exists("2020/07/C0001M01.XML") {
+		t.Errorf("C0001 and its sidecar not archived together in 2020/07")
+	}
+	// The orphan of C0003 joins its archived video; that of C0009, whose video the catalog does not
+	// know, stays and is reported.
+	if !exists("2019/03/C0003M01.XML") {
+		t.Errorf("orphaned C0003M01.XML not moved beside its video")
+	}
+	if len(sum.Orphans) != 1 || filepath.Base(sum.Orphans[0]) != "C0009M01.XML" {
+		t.Errorf("Orphans = %v; want C0009M01.XML", sum.Orphans)
+	}
+	if _, err := os.Stat(filepath.Join(src, "C0009M01.XML")); err != nil {
+		t.Errorf("unknown orphan moved: %v", err)
+	}
+}
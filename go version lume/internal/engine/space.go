@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"lume-go/internal/logger"
+	"lume-go/internal/validator"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SpaceRecheck is how long a reading of a target's free space is trusted. In between, the bytes the
+// run copies are subtracted from it, so asking the volume before every file is not needed.
+const SpaceRecheck = 5 * time.Second
+
+// The volume lookups of the space check, replaced in tests.
+var (
+	freeSpace = validator.FreeSpace
+	volumeOf  = func(path string) string {
+		if serial, err := validator.VolumeSerial(path); err == nil {
+			return serial
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		return strings.ToUpper(filepath.VolumeName(path))
+	}
+)
+
+// space re-checks the free space of the run's targets while it goes, since other programs can fill a
+// disk during a long run.
+type space struct {
+	now     func() time.Time
+	targets map[string]*targetSpace
+}
+
+type targetSpace struct {
+	free int64
+	at   time.Time
+	// off is set when the volume cannot tell its free space; the run then relies on copy errors.
+	off bool
+}
+
+func newSpace() *space {
+	return &space{now: time.Now, targets: make(map[string]*targetSpace)}
+}
+
+// clusterSize is the allocation unit a copy is counted in, the NTFS default: a small file takes a
+// whole one.
+const clusterSize = 4 << 10
+
+// reserve accounts for moving size bytes from src into target, or returns a SpaceError when they do
+// not fit with validator.SpaceMargin left free, as the check before the run leaves it for the file
+// system's own writes. A move within the volume is a rename that needs no space.
+func (s *space) reserve(src, target string, size int64) error {
+	if volumeOf(src) == volumeOf(target) {
+		return nil
+	}
+	t := s.targets[target]
+	if t == nil {
+		t = &targetSpace{}
+		s.targets[target] = t
+	}
+	if t.off {
+		return nil
+	}
+	if now := s.now(); t.at.IsZero() || now.Sub(t.at) >= SpaceRecheck {
+		free, err := freeSpace(target)
+		if err != nil {
+			logger.Error("Free space of %s unknown, not re-checked during the run: %v", target, err)
+			t.off = true
+			return nil
+		}
+		t.free, t.at = free, now
+	}
+	alloc := (size + clusterSize - 1) / clusterSize * clusterSize
+	if alloc+validator.SpaceMargin > t.free {
+		return &validator.SpaceError{Path: target, Need: alloc + validator.SpaceMargin, Have: t.free}
+	}
+	t.free -= alloc
+	return nil
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"lume-go/internal/organizer"
+	"lume-go/internal/routing"
+)
+
+func TestRunPreservesStructure(t *testing.T) {
+	src, loose, target, videos := t.TempDir(), t.TempDir(), t.TempDir(), t.TempDir()
+	mtime := time.Date(2023, 5, 6, 12, 0, 0, 0, time.Local)
+	nested, top := filepath.Join(src, "Trip", "Day 1", "IMG_0001.JPG"), filepath.Join(src, "IMG_0002.JPG")
+	video, single := filepath.Join(src, "Trip", "MOV_0003.MP4"), filepath.Join(loose, "IMG_0004.JPG")
+	pending := []string{nested, top, video, single}
+
+	// Each run puts the files back in the source first.
+	run := func(preserve bool, routes []routing.Rule) map[string]string {
+		t.Helper()
+		for _, p := range pending {
+			os.MkdirAll(filepath.Dir(p), 0755)
+			os.WriteFile(p, []byte(p), 0644)
+			os.Chtimes(p, mtime, mtime)
+		}
+		os.RemoveAll(filepath.Join(target, "Trip"))
+		sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, Layout: organizer.Layout{Template: "{year}/{month}"},
+			Routes: routes, PreserveStructure: preserve, Roots: []string{src}}})
+		if err != nil || sum.Moved != len(pending) {
+			t.Fatalf("err %v, summary %+v", err, sum)
+		}
+		dest := make(map[string]string)
+		for _, r := range sum.Results {
+			dest[r.Path] = r.Dest
+		}
+		return dest
+	}
+	check := func(dest map[string]string, path, want string) {
+		t.Helper()
+		if want = filepath.FromSlash(want); dest[path] != want {
+			t.Errorf("%s archived as %s; want %s", path, dest[path], want)
+		}
+	}
+
+	// Videos are routed to their own target by date, the rest mirror the source; the file dropped on
+	// its own has no structure to keep.
+	dest := run(true, []routing.Rule{{Extensions: []string{".mp4"}, Target: videos, Template: "{year}"}})
+	check(dest, nested, target+"/Trip/Day 1/IMG_0001.JPG")
+	check(dest, top, target+"/IMG_0002.JPG")
+	check(dest, video, videos+"/2023/MOV_0003.MP4")
+	check(dest, single, target+"/2023/05/IMG_0004.JPG")
+
+	// The other way round: only the route keeps the structure.
+	dest = run(false, []routing.Rule{{Extensions: []string{".mp4"}, Target: videos, Template: organizer.StructureTemplate}})
+	check(dest, nested, target+"/2023/05/IMG_0001.JPG")
+	check(dest, video, videos+"/Trip/MOV_0003.MP4")
+}
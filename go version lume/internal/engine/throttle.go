@@ -0,0 +1,234 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/validator"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// MaxMoveWorkers bounds the files moved into one target at once, however many CPUs there are.
+const MaxMoveWorkers = 8
+
+// The lookups of the tuning, replaced in tests.
+var (
+	driveType = validator.DriveType
+	numCPU    = runtime.NumCPU
+)
+
+// TuneWorkers is how many files are moved into a target on a drive of kind at once. A fixed disk
+// takes half the CPUs, up to MaxMoveWorkers; a network share two, to hide its latency; a removable
+// drive one, as a spinning USB disk only slows down with more streams. A drive of unknown kind gets
+// the one stream runs always had.
+func TuneWorkers(kind string, cpus int) int {
+	switch kind {
+	case validator.DriveFixed:
+		return max(1, min(cpus/2, MaxMoveWorkers))
+	case validator.DriveRemote:
+		return 2
+	}
+	return 1
+}
+
+// Throttle is how many files a run moved into one target at once, and the kind of its drive.
+type Throttle struct {
+	Target  string
+	Kind    string
+	Workers int
+}
+
+// throttles hands out the move slots of the targets of a run, each target tuned by the kind of its
+// drive when first seen unless Options.MoveWorkers sets them all. tuned is told each choice.
+type throttles struct {
+	mu      sync.Mutex
+	workers int
+	slots   map[string]chan struct{}
+	tuned   func(Throttle)
+}
+
+func newThrottles(opts Options, tuned func(Throttle)) *throttles {
+	return &throttles{workers: opts.MoveWorkers, slots: make(map[string]chan struct{}), tuned: tuned}
+}
+
+// slot returns the semaphore of target, its capacity the files moved into it at once.
+func (t *throttles) slot(target string) chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if s, ok := t.slots[target]; ok {
+		return s
+	}
+	kind, n := driveType(target), t.workers
+	if n <= 0 {
+		n = TuneWorkers(kind, numCPU())
+	}
+	logger.Info("Moving up to %d files at once into %s (%s drive)", n, target, kind)
+	t.slots[target] = make(chan struct{}, n)
+	t.tuned(Throttle{Target: target, Kind: kind, Workers: n})
+	return t.slots[target]
+}
+
+// mover moves the files of a run's batches, reporting each result. mu guards the run's bookkeeping
+// the files of a batch share while they move side by side.
+type mover struct {
+	mu     sync.Mutex
+	report func(OrganizeResult)
+	rt     *routes
+	mo     organizer.Options
+	opts   Options
+	free   *space
+	prev   *previous
+	th     *throttles
+	// held are the files that were open in another program, reported once tried again (see
+	// retryInUse); retrying is set while they are.
+	held     []heldFile
+	retrying bool
+}
+
+// one moves a file and reports its result, unless an earlier copy of it in the batch already landed.
+// attempted is false when the file was not tried for lack of space, which stop is then set to; a
+// move that found the target full is attempted and stops the batch all the same.
+func (m *mover) one(ctx context.Context, info metadata.FileInfo, tw twins) (attempted bool, stop error) {
+	m.mu.Lock()
+	if r, ok := tw.landed(info); ok {
+		dup := duplicateOf(info, r)
+		m.report(dup)
+		m.prev.stamp(info, dup)
+		m.mu.Unlock()
+		return true, nil
+	}
+	base, _ := m.rt.router.Route(info)
+	err := m.free.reserve(info.Path, base, info.Size)
+	m.mu.Unlock()
+	if err != nil {
+		return false, err
+	}
+	r := moveOne(ctx, info, m.rt, m.mo, m.opts)
+	m.mu.Lock()
+	if m.retrying {
+		r.Retried = true
+	} else if m.opts.InUse != nil && errors.Is(r.Error, organizer.ErrInUse) {
+		logger.Info("%s is open in another program; tried again at the end of the run", info.Path)
+		m.held = append(m.held, heldFile{info, r})
+		m.mu.Unlock()
+		return true, nil
+	}
+	m.report(r)
+	tw.land(info, r)
+	m.prev.stamp(info, r)
+	m.mu.Unlock()
+	// The disk filled faster than the re-check saw: the next copies would fail the same way.
+	if errors.Is(r.Error, organizer.ErrInsufficientSpace) {
+		return true, fmt.Errorf("%s: %w", base, r.Error)
+	}
+	return true, nil
+}
+
+// batch moves the files of a batch and returns those it did not attempt, with the error that
+// stopped it: a cancelled run or a full target. Files bound for one folder form a lane and move one
+// after the other, in the order settleCollisions left them, so names are given out as when moving
+// one file at a time; the lanes of a target move side by side up to its throttle, and every target
+// independently of the others. When no target takes more than one file at once, the files simply
+// move in order.
+func (m *mover) batch(ctx context.Context, files []metadata.FileInfo, tw twins) ([]metadata.FileInfo, error) {
+	type lane struct {
+		base  string
+		files []int
+	}
+	var lanes []*lane
+	byKey := make(map[string]*lane)
+	parallel := false
+	for i, info := range files {
+		base, o, inPlace, _ := destination(info, m.rt, m.mo, m.opts)
+		// Windows names are not case sensitive; a file left in place goes nowhere to share.
+		key := strings.ToLower(organizer.TargetDir(info, base, o.Layout))
+		if inPlace {
+			key = "\x00" + info.Path
+		}
+		l := byKey[key]
+		if l == nil {
+			l = &lane{base: base}
+			byKey[key] = l
+			lanes = append(lanes, l)
+		}
+		l.files = append(l.files, i)
+		parallel = parallel || cap(m.th.slot(base)) > 1
+	}
+
+	attempted := make([]bool, len(files))
+	var stopErr error
+	if !parallel {
+		for i, info := range files {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			if attempted[i], stopErr = m.one(ctx, info, tw); stopErr != nil {
+				break
+			}
+		}
+	} else {
+		stopCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var once sync.Once
+		var wg sync.WaitGroup
+		byBase := make(map[string][]*lane)
+		var bases []string
+		for _, l := range lanes {
+			if byBase[l.base] == nil {
+				bases = append(bases, l.base)
+			}
+			byBase[l.base] = append(byBase[l.base], l)
+		}
+		for _, base := range bases {
+			slots := m.th.slot(base)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for _, l := range byBase[base] {
+					select {
+					case slots <- struct{}{}:
+					case <-stopCtx.Done():
+						return
+					}
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						defer func() { <-slots }()
+						for _, i := range l.files {
+							// A move in flight is rolled back by ctx alone; stopCtx only holds back the next.
+							if stopCtx.Err() != nil {
+								return
+							}
+							ok, err := m.one(ctx, files[i], tw)
+							attempted[i] = ok
+							if err != nil {
+								once.Do(func() { stopErr = err })
+								cancel()
+								return
+							}
+						}
+					}()
+				}
+			}()
+		}
+		wg.Wait()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if stopErr == nil {
+		return nil, nil
+	}
+	var left []metadata.FileInfo
+	for i, info := range files {
+		if !attempted[i] {
+			left = append(left, info)
+		}
+	}
+	return left, stopErr
+}
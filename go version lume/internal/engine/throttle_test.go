@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/routing"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTuneWorkers(t *testing.T) {
+	tests := []struct {
+		kind string
+		cpus int
+		want int
+	}{
+		{validator.DriveFixed, 32, MaxMoveWorkers},
+		{validator.DriveFixed, 8, 4},
+		{validator.DriveFixed, 1, 1},
+		{validator.DriveRemote, 16, 2},
+		{validator.DriveRemovable, 16, 1},
+		{validator.DriveUnknown, 16, 1},
+	}
+	for _, tt := range tests {
+		if got := TuneWorkers(tt.kind, tt.cpus); got != tt.want {
+			t.Errorf("TuneWorkers(%s, %d) = %d; want %d", tt.kind, tt.cpus, got, tt.want)
+		}
+	}
+}
+
+func TestRunThrottlesPerTarget(t *testing.T) {
+	src, target, videos := t.TempDir(), t.TempDir(), t.TempDir()
+	var pending []string
+	for day := 1; day <= 8; day++ {
+		mtime := time.Date(2023, 5, day, 12, 0, 0, 0, time.Local)
+		for _, name := range []string{fmt.Sprintf("IMG_%04d.JPG", day), fmt.Sprintf("MOV_%04d.MP4", day)} {
+			p := filepath.Join(src, name)
+			os.WriteFile(p, []byte(name), 0644)
+			os.Chtimes(p, mtime, mtime)
+			pending = append(pending, p)
+		}
+	}
+	// Two different photos of one day under one name: the folder's lane gives out the names in order.
+	mtime := time.Date(2023, 5, 1, 12, 0, 0, 0, time.Local)
+	for _, dir := range []string{"a", "b"} {
+		p := filepath.Join(src, dir, "DSC_0001.JPG")
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(dir), 0644)
+		os.Chtimes(p, mtime, mtime)
+		pending = append(pending, p)
+	}
+
+	defer func(d func(string) string, n func() int, m func(context.Context, metadata.FileInfo, string, organizer.Options) (string, error)) {
+		driveType, numCPU, moveFile = d, n, m
+	}(driveType, numCPU, moveFile)
+	driveType = func(p string) string {
+		if p == videos {
+			return validator.DriveRemovable
+		}
+		return validator.DriveFixed
+	}
+	numCPU = func() int { return 8 }
+	var mu sync.Mutex
+	inFlight, peak := make(map[string]int), make(map[string]int)
+	moveFile = func(ctx context.Context, info metadata.FileInfo, base string, o organizer.Options) (string, error) {
+		mu.Lock()
+		inFlight[base]++
+		peak[base] = max(peak[base], inFlight[base])
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		defer func() {
+			mu.Lock()
+			inFlight[base]--
+			mu.Unlock()
+		}()
+		return organizer.MoveFileContext(ctx, info, base, o)
+	}
+
+	sum, err := Run(context.Background(), RunSpec{Pending: pending, Options: Options{Target: target, Layout: organizer.Layout{Template: "{year}/{month}/{day}"},
+		Routes: []routing.Rule{{Extensions: []string{".mp4"}, Target: videos}}}})
+	if err != nil || sum.Moved != len(pending) {
+		t.Fatalf("err %v, summary %+v", err, sum)
+	}
+	if peak[target] < 2 || peak[target] > 4 || peak[videos] != 1 {
+		t.Errorf("peak moves at once: %d into the fixed drive, %d into the removable one; want 2 to 4 and 1", peak[target], peak[videos])
+	}
+	want := []Throttle{{Target: target, Kind: validator.DriveFixed, Workers: 4}, {Target: videos, Kind: validator.DriveRemovable, Workers: 1}}
+	if len(sum.Throttles) != 2 || sum.Throttles[0] != want[0] || sum.Throttles[1] != want[1] {
+		t.Errorf("Throttles = %+v; want %+v", sum.Throttles, want)
+	}
+	for _, r := range sum.Results {
+		if filepath.Base(r.Path) != "DSC_0001.JPG" {
+			continue
+		}
+		name := "DSC_0001.JPG"
+		if filepath.Base(filepath.Dir(r.Path)) == "b" {
+			name = "DSC_0001_1.JPG"
+		}
+		if want := filepath.Join(target, "2023", "05", "01", name); r.Dest != want {
+			t.Errorf("%s archived as %s; want %s", r.Path, r.Dest, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"time"
+
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+)
+
+// prepare runs the stages between the scan and the moves over files: the date shift, the marks that
+// depend on the other files (shots within a second, suspect dates, bursts, pooled devices, edited
+// copies), the order of processing and the settling of name collisions, which reorders files in place.
+// It returns the copies among colliding files.
+func prepare(ctx context.Context, files []metadata.FileInfo, rt *routes, mo organizer.Options, opts Options) twins {
+	shiftDates(files, opts)
+	organizer.MarkSequences(files)
+	if n := metadata.MarkDateSuspects(files, opts.SuspectAfter); n > 0 {
+		logger.Info("%d files have a date far from their file times", n)
+	}
+	rt.markBursts(files, mo)
+	rt.poolDevices(files, mo)
+	placeDerivatives(files, rt, mo, opts)
+	// Colliding files are settled by their paths after this, so the order never changes a name.
+	sortFiles(files, opts.Order)
+	return settleCollisions(ctx, files, rt, mo, opts)
+}
+
+// marks are what prepare settles for a file from the other files of the run.
+type marks struct {
+	seq          int
+	burst        string
+	pooled       bool
+	suspect      bool
+	skew         time.Duration
+	derivativeOf string
+	beside       string
+}
+
+func marksOf(info metadata.FileInfo) marks {
+	return marks{info.Seq, info.Burst, info.Pooled, info.DateSuspect, info.Skew, info.DerivativeOf, info.Beside}
+}
+
+func (m marks) apply(info *metadata.FileInfo) {
+	info.Seq, info.Burst, info.Pooled, info.DateSuspect, info.Skew, info.DerivativeOf, info.Beside = m.seq, m.burst, m.pooled, m.suspect, m.skew, m.derivativeOf, m.beside
+}
+
+// wholePlan is a run of several batches prepared as one before any of its files moves, so that no
+// name, mark or order depends on where the batches break. Between the planning pass and the batches
+// only the order of the paths, their marks and the copies among colliding files are kept; each batch
+// reads its metadata again, from the scan cache when the run has one.
+type wholePlan struct {
+	order []string
+	marks map[string]marks
+	tw    twins
+	// loaded are the files the run was given with their metadata, not read again.
+	loaded map[string]metadata.FileInfo
+}
+
+// planWhole reads the metadata of files and the pending paths, batch by batch, and prepares them as
+// one. Paths that cannot be read are returned as failures.
+func planWhole(ctx context.Context, files []metadata.FileInfo, pending []string, rt *routes, mo organizer.Options, opts Options) (*wholePlan, []OrganizeResult) {
+	w := &wholePlan{loaded: make(map[string]metadata.FileInfo, len(files))}
+	all := make([]metadata.FileInfo, 0, len(files)+len(pending))
+	for _, f := range files {
+		w.loaded[f.Path] = f
+		all = append(all, f)
+	}
+	var failed []OrganizeResult
+	for len(pending) > 0 && ctx.Err() == nil {
+		var batch []metadata.FileInfo
+		var bad []OrganizeResult
+		batch, pending, bad = nextBatch(ctx, pending, opts)
+		all, failed = append(all, batch...), append(failed, bad...)
+	}
+	w.tw = prepare(ctx, all, rt, mo, opts)
+	w.order, w.marks = make([]string, len(all)), make(map[string]marks, len(all))
+	for i, f := range all {
+		w.order[i], w.marks[f.Path] = f.Path, marksOf(f)
+	}
+	logger.Info("Planned %d files to move in batches of %d", len(all), opts.BatchSize)
+	return w, failed
+}
+
+// next loads the next batch of the plan from pending, in the order of the plan, and gives its files
+// the marks the plan settled. Paths that can no longer be read are returned as failures.
+func (w *wholePlan) next(ctx context.Context, pending []string, opts Options) ([]metadata.FileInfo, []string, []OrganizeResult) {
+	n := opts.BatchSize
+	if n <= 0 || n > len(pending) {
+		n = len(pending)
+	}
+	var read []string
+	for _, p := range pending[:n] {
+		if _, ok := w.loaded[p]; !ok {
+			read = append(read, p)
+		}
+	}
+	batch, _, failed := nextBatch(ctx, read, opts)
+	byPath := make(map[string]metadata.FileInfo, len(batch))
+	for _, f := range batch {
+		byPath[f.Path] = f
+	}
+	files := make([]metadata.FileInfo, 0, n)
+	for _, p := range pending[:n] {
+		f, ok := w.loaded[p]
+		if ok {
+			delete(w.loaded, p)
+		} else if f, ok = byPath[p]; !ok {
+			continue
+		}
+		files = append(files, f)
+	}
+	shiftDates(files, opts)
+	for i := range files {
+		w.marks[files[i].Path].apply(&files[i])
+		delete(w.marks, files[i].Path)
+	}
+	return files, pending[n:], failed
+}
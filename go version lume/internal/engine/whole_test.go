@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"lume-go/internal/organizer"
+)
+
+// A run of several batches names its files as the same run in one batch would: the shots of one
+// second are numbered across the batches.
+func TestRunBatchesPlannedAsOne(t *testing.T) {
+	names := []string{"IMG_0003.JPG", "IMG_0001.JPG", "IMG_0004.JPG", "IMG_0002.JPG"}
+	run := func(batch int) (map[string]string, RunSummary) {
+		src, target := t.TempDir(), t.TempDir()
+		var paths []string
+		for _, name := range names {
+			p := filepath.Join(src, name)
+			os.WriteFile(p, []byte("shot "+name), 0644)
+			mtime := time.Date(2023, 5, 6, 12, 30, 15, 0, time.Local)
+			os.Chtimes(p, mtime, mtime)
+			paths = append(paths, p)
+		}
+		layout := organizer.Layout{NameTemplate: "{time}_{seq}"}
+		sum, err := Run(context.Background(), RunSpec{Pending: paths, Options: Options{Target: target, Layout: layout, BatchSize: batch}})
+		if err != nil || sum.Errors != 0 || sum.Moved != len(names) {
+			t.Fatalf("batches of %d: err %v, summary %+v", batch, err, sum)
+		}
+		return tree(t, target), sum
+	}
+
+	one, _ := run(0)
+	several, _ := run(1)
+	if !reflect.DeepEqual(one, several) {
+		t.Errorf("one batch archived %v; batches of one %v", one, several)
+	}
+	for i, name := range []string{"IMG_0001.JPG", "IMG_0002.JPG", "IMG_0003.JPG", "IMG_0004.JPG"} {
+		archived := "2023/05/Camera/123015_00" + string(rune('1'+i)) + ".JPG"
+		if got := several[archived]; got != "shot "+name {
+			t.Errorf("%s holds %q; want %s", archived, got, name)
+		}
+	}
+}
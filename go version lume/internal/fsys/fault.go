@@ -0,0 +1,211 @@
+package fsys
+
+import (
+	"errors"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrInjected is the error of a failure a Faulty injected.
+var ErrInjected = errors.New("injected failure")
+
+// Operations a Fault can break. OpRead and OpWrite break the files opened, after Fault.After bytes.
+const (
+	OpOpen   = "open"
+	OpCreate = "create"
+	OpRename = "rename"
+	OpRemove = "remove"
+	OpMkdir  = "mkdir"
+	OpRead   = "read"
+	OpWrite  = "write"
+)
+
+// Fault is a failure a Faulty injects into the calls it matches.
+type Fault struct {
+	Op string
+	// Path matches the calls on paths ending in it (the old path of a rename); empty matches all.
+	Path string
+	// Nth fails only the Nth matching call, counting from 1; 0 fails every one.
+	Nth int
+	// After is how many bytes a read or write passes before it fails.
+	After int64
+	// Corrupt makes a write from After on change the bytes instead of failing, as a bad disk would.
+	Corrupt bool
+	// Err is the error returned; nil is ErrInjected.
+	Err error
+}
+
+func (f Fault) err(op, path string) error {
+	if f.Err != nil {
+		return f.Err
+	}
+	return &fs.PathError{Op: op, Path: path, Err: ErrInjected}
+}
+
+// Faulty is an FS that fails where it is told to, for tests of what a failure in the middle of an
+// operation leaves behind. After Chaos it also fails calls at random, for soak tests.
+type Faulty struct {
+	FS
+	mu     sync.Mutex
+	faults []Fault
+	seen   []int
+	rate   float64
+	rnd    *rand.Rand
+}
+
+// NewFaulty returns base (nil is OS) failing as the faults say.
+func NewFaulty(base FS, faults ...Fault) *Faulty {
+	return &Faulty{FS: Or(base), faults: faults, seen: make([]int, len(faults))}
+}
+
+// Chaos makes every open, create, rename, remove and mkdir fail with probability rate, and copies
+// fail or corrupt part-way as often; seed makes a run repeatable.
+func (f *Faulty) Chaos(rate float64, seed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rate, f.rnd = rate, rand.New(rand.NewPCG(seed, seed))
+}
+
+// match returns the fault hit by an op call on path, if any.
+func (f *Faulty) match(op, path string) (Fault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, ft := range f.faults {
+		if ft.Op != op || !strings.HasSuffix(path, ft.Path) {
+			continue
+		}
+		f.seen[i]++
+		if ft.Nth == 0 || ft.Nth == f.seen[i] {
+			return ft, true
+		}
+	}
+	if f.rnd != nil && f.rnd.Float64() < f.rate {
+		switch op {
+		case OpRead, OpWrite:
+			return Fault{Op: op, After: f.rnd.Int64N(1 << 16), Corrupt: op == OpWrite && f.rnd.IntN(2) == 0}, true
+		}
+		return Fault{Op: op}, true
+	}
+	return Fault{}, false
+}
+
+func (f *Faulty) Open(name string) (File, error) {
+	if ft, ok := f.match(OpOpen, name); ok {
+		return nil, ft.err("open", name)
+	}
+	file, err := f.FS.Open(name)
+	return f.wrap(file, err)
+}
+
+func (f *Faulty) Create(name string) (File, error) {
+	if ft, ok := f.match(OpCreate, name); ok {
+		return nil, ft.err("open", name)
+	}
+	file, err := f.FS.Create(name)
+	return f.wrap(file, err)
+}
+
+// OpenFile fails as an open, or as a create when flag has O_CREATE.
+func (f *Faulty) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	op := OpOpen
+	if flag&os.O_CREATE != 0 {
+		op = OpCreate
+	}
+	if ft, ok := f.match(op, name); ok {
+		return nil, ft.err("open", name)
+	}
+	file, err := f.FS.OpenFile(name, flag, perm)
+	return f.wrap(file, err)
+}
+
+func (f *Faulty) Rename(oldpath, newpath string) error {
+	if ft, ok := f.match(OpRename, oldpath); ok {
+		return ft.err("rename", oldpath)
+	}
+	return f.FS.Rename(oldpath, newpath)
+}
+
+func (f *Faulty) Remove(name string) error {
+	if ft, ok := f.match(OpRemove, name); ok {
+		return ft.err("remove", name)
+	}
+	return f.FS.Remove(name)
+}
+
+// Trash fails as a remove.
+func (f *Faulty) Trash(name string) error {
+	if ft, ok := f.match(OpRemove, name); ok {
+		return ft.err("remove", name)
+	}
+	return f.FS.Trash(name)
+}
+
+func (f *Faulty) MkdirAll(path string, perm fs.FileMode) error {
+	if ft, ok := f.match(OpMkdir, path); ok {
+		return ft.err("mkdir", path)
+	}
+	return f.FS.MkdirAll(path, perm)
+}
+
+// wrap arms the read and write faults of an opened file.
+func (f *Faulty) wrap(file File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+	ff := &faultyFile{File: file}
+	if ft, ok := f.match(OpRead, file.Name()); ok {
+		ff.read = &ft
+	}
+	if ft, ok := f.match(OpWrite, file.Name()); ok {
+		ff.write = &ft
+	}
+	return ff, nil
+}
+
+// faultyFile is a file of a Faulty with its read and write faults.
+type faultyFile struct {
+	File
+	read, write *Fault
+	rn, wn      int64
+}
+
+func (f *faultyFile) Read(p []byte) (int, error) {
+	if f.read != nil {
+		if f.rn >= f.read.After {
+			return 0, f.read.err("read", f.Name())
+		}
+		if rest := f.read.After - f.rn; int64(len(p)) > rest {
+			p = p[:rest]
+		}
+	}
+	n, err := f.File.Read(p)
+	f.rn += int64(n)
+	return n, err
+}
+
+func (f *faultyFile) Write(p []byte) (int, error) {
+	if f.write == nil || f.wn+int64(len(p)) <= f.write.After {
+		n, err := f.File.Write(p)
+		f.wn += int64(n)
+		return n, err
+	}
+	if f.write.Corrupt {
+		q := append([]byte(nil), p...)
+		for i := max(f.write.After-f.wn, 0); i < int64(len(q)); i++ {
+			q[i] ^= 0xff
+		}
+		n, err := f.File.Write(q)
+		f.wn += int64(n)
+		return n, err
+	}
+	keep := max(f.write.After-f.wn, 0)
+	n, err := f.File.Write(p[:keep])
+	f.wn += int64(n)
+	if err == nil {
+		err = f.write.err("write", f.Name())
+	}
+	return n, err
+}
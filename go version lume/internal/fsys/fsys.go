@@ -0,0 +1,101 @@
+// Package fsys puts the file system calls of the organizer and validator behind an interface, so a
+// run can be previewed without touching the disk and tests can use a file system in memory. OS is the
+// real one and what every caller gets by default.
+package fsys
+
+import (
+	"io"
+	"io/fs"
+	"lume-go/internal/trash"
+	"os"
+	"time"
+)
+
+// File is an open file of an FS; *os.File is one.
+type File interface {
+	io.Reader
+	io.Writer
+	io.Closer
+	Name() string
+	Stat() (fs.FileInfo, error)
+	Sync() error
+}
+
+// FS is the part of the file system Lume changes archives through. Paths are OS paths, and errors
+// match fs.ErrNotExist, fs.ErrExist and the like as those of package os do.
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Open(name string) (File, error)
+	Create(name string) (File, error)
+	OpenFile(name string, flag int, perm fs.FileMode) (File, error)
+	Rename(oldpath, newpath string) error
+	Remove(name string) error
+	MkdirAll(path string, perm fs.FileMode) error
+	Chtimes(name string, atime, mtime time.Time) error
+	// Trash deletes name recoverably, as package trash does on the real file system.
+	Trash(name string) error
+	// SameFile reports whether a and b are one file reached by two paths, such as hardlinks or a
+	// subst drive and its folder; paths that cannot be read never are.
+	SameFile(a, b string) bool
+}
+
+// OS is the real file system.
+var OS FS = osFS{}
+
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) Lstat(name string) (fs.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Rename(oldpath, newpath string) error       { return os.Rename(oldpath, newpath) }
+func (osFS) Remove(name string) error                   { return os.Remove(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+func (osFS) Chtimes(name string, atime, mtime time.Time) error {
+	return os.Chtimes(name, atime, mtime)
+}
+func (osFS) Trash(name string) error { return trash.Remove(name, false) }
+
+func (osFS) SameFile(a, b string) bool {
+	sa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	sb, err := os.Stat(b)
+	return err == nil && os.SameFile(sa, sb)
+}
+
+// The *os.File results are returned through checks so a failed call yields a nil File, not a File
+// holding a nil *os.File.
+
+func (osFS) Open(name string) (File, error) { return file(os.Open(name)) }
+
+func (osFS) Create(name string) (File, error) { return file(os.Create(name)) }
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	return file(os.OpenFile(name, flag, perm))
+}
+
+func file(f *os.File, err error) (File, error) {
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Or returns fsys, or OS when it is nil.
+func Or(fsys FS) FS {
+	if fsys == nil {
+		return OS
+	}
+	return fsys
+}
+
+// Exists reports whether name exists, without following a final symlink.
+func Exists(fsys FS, name string) bool {
+	_, err := fsys.Lstat(name)
+	return err == nil
+}
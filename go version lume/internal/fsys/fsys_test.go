@@ -0,0 +1,167 @@
+package fsys
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMem(t *testing.T) {
+	m := NewMem()
+	root := filepath.Join(string(filepath.Separator), "archive")
+	a := filepath.Join(root, "2024", "a.jpg")
+
+	if _, err := m.Create(a); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Create without the folder = %v; want ErrNotExist", err)
+	}
+	if err := m.MkdirAll(filepath.Dir(a), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := m.Create(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(f, "hello")
+	f.Close()
+	if _, err := m.OpenFile(a, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("exclusive create of a taken name = %v; want ErrExist", err)
+	}
+
+	r, err := m.Open(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(r)
+	st, _ := r.Stat()
+	if string(data) != "hello" || st.Size() != 5 || st.IsDir() {
+		t.Errorf("read back %q, size %d, dir %v", data, st.Size(), st.IsDir())
+	}
+
+	b := filepath.Join(root, "2025", "b.jpg")
+	if err := m.Rename(a, b); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("rename into a missing folder = %v; want ErrNotExist", err)
+	}
+	m.MkdirAll(filepath.Dir(b), 0755)
+	if err := m.Rename(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if Exists(m, a) || !Exists(m, b) {
+		t.Errorf("after rename: %s exists %v, %s exists %v", a, Exists(m, a), b, Exists(m, b))
+	}
+	if err := m.Remove(filepath.Dir(b)); err == nil {
+		t.Error("removed a folder that is not empty")
+	}
+	entries, _ := m.ReadDir(root)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if !reflect.DeepEqual(names, []string{"2024", "2025"}) {
+		t.Errorf("ReadDir = %v", names)
+	}
+	old := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	m.Chtimes(b, old, old)
+	if st, _ := m.Stat(b); !st.ModTime().Equal(old) {
+		t.Errorf("ModTime after Chtimes = %v", st.ModTime())
+	}
+	if err := m.Remove(b); err != nil || Exists(m, b) {
+		t.Errorf("Remove = %v", err)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	keep := filepath.Join(dir, "keep.txt")
+	os.WriteFile(keep, []byte("x"), 0644)
+
+	r := NewRecorder(nil)
+	r.MkdirAll(dir, 0755) // exists: nothing to record
+	r.MkdirAll(filepath.Join(dir, "new"), 0755)
+	f, _ := r.Create(filepath.Join(dir, "new", "c.txt"))
+	f.Write([]byte("abc"))
+	f.Close()
+	moved := filepath.Join(dir, "moved.txt")
+	r.Rename(keep, moved)
+	if err := r.Remove(keep); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Remove of the renamed file = %v", err)
+	}
+	r.Trash(filepath.Join(dir, "new", "c.txt"))
+
+	want := []Op{
+		{Kind: "mkdir", Path: filepath.Join(dir, "new")},
+		{Kind: "create", Path: filepath.Join(dir, "new", "c.txt")},
+		{Kind: "rename", Path: keep, To: moved},
+		{Kind: "trash", Path: filepath.Join(dir, "new", "c.txt")},
+	}
+	if got := r.Ops(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Ops = %v; want %v", got, want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "keep.txt" {
+		t.Errorf("the disk changed: %v", entries)
+	}
+
+	// Its reads show the changes: the renamed file reads from where it was.
+	if f, err := r.Open(moved); err != nil {
+		t.Errorf("Open of the renamed file: %v", err)
+	} else if data, _ := io.ReadAll(f); string(data) != "x" {
+		t.Errorf("the renamed file reads %q", data)
+	}
+	if Exists(r, keep) || !r.SameFile(moved, moved) || r.SameFile(moved, keep) {
+		t.Error("the renamed file is still at its old path")
+	}
+	var names []string
+	if list, err := r.ReadDir(dir); err == nil {
+		for _, e := range list {
+			names = append(names, e.Name())
+		}
+	}
+	if !reflect.DeepEqual(names, []string{"moved.txt", "new"}) {
+		t.Errorf("ReadDir = %v", names)
+	}
+	f, _ = r.Create(filepath.Join(dir, "new", "d.txt"))
+	f.Write([]byte("def"))
+	if g, err := r.Open(filepath.Join(dir, "new", "d.txt")); err != nil {
+		t.Errorf("Open of a created file: %v", err)
+	} else if data, _ := io.ReadAll(g); string(data) != "def" {
+		t.Errorf("the created file reads %q", data)
+	}
+}
+
+func TestFaulty(t *testing.T) {
+	m := NewMem()
+	dir := filepath.Join(string(filepath.Separator), "card")
+	m.WriteFile(filepath.Join(dir, "a.jpg"), []byte("0123456789"))
+	f := NewFaulty(m,
+		Fault{Op: OpOpen, Path: "a.jpg", Nth: 2},
+		Fault{Op: OpRead, Path: "a.jpg", After: 4},
+		Fault{Op: OpWrite, Path: "b.jpg", After: 3, Corrupt: true},
+	)
+
+	r, err := f.Open(filepath.Join(dir, "a.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(r); string(data) != "0123" || !errors.Is(err, ErrInjected) {
+		t.Errorf("read %q, %v; want 4 bytes, then the failure", data, err)
+	}
+	if _, err := f.Open(filepath.Join(dir, "a.jpg")); !errors.Is(err, ErrInjected) {
+		t.Errorf("second open = %v; want the failure", err)
+	}
+	if _, err := f.Open(filepath.Join(dir, "a.jpg")); err != nil {
+		t.Errorf("third open = %v", err)
+	}
+
+	w, _ := f.Create(filepath.Join(dir, "b.jpg"))
+	if n, err := io.WriteString(w, "abcdef"); n != 6 || err != nil {
+		t.Errorf("corrupting write = %d, %v; want it to look fine", n, err)
+	}
+	if data, _ := m.ReadFile(filepath.Join(dir, "b.jpg")); string(data[:3]) != "abc" || string(data[3:]) == "def" {
+		t.Errorf("wrote %q; want the bytes after the third changed", data)
+	}
+}
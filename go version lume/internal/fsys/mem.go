@@ -0,0 +1,319 @@
+package fsys
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errIsDir    = errors.New("is a directory")
+	errNotEmpty = errors.New("directory not empty")
+)
+
+// Mem is a file system held in memory, for tests. It knows files and directories, no links, and
+// is safe for concurrent use. Paths are cleaned, so "a/./b" and "a/b" are one file.
+type Mem struct {
+	mu    sync.Mutex
+	nodes map[string]*memNode
+}
+
+type memNode struct {
+	dir   bool
+	data  []byte
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+// NewMem returns an empty file system whose roots, such as "/" or "C:\", exist.
+func NewMem() *Mem {
+	return &Mem{nodes: make(map[string]*memNode)}
+}
+
+// WriteFile creates or replaces name with data, creating its parent directories, like a test
+// fixture needs.
+func (m *Mem) WriteFile(name string, data []byte) error {
+	if err := m.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nodes[filepath.Clean(name)] = &memNode{data: append([]byte(nil), data...), mode: 0644, mtime: time.Now()}
+	return nil
+}
+
+// ReadFile returns the content of name.
+func (m *Mem) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if n.dir {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errIsDir}
+	}
+	return append([]byte(nil), n.data...), nil
+}
+
+// node returns the node of name; callers hold mu.
+func (m *Mem) node(op, name string) (*memNode, error) {
+	name = filepath.Clean(name)
+	if n, ok := m.nodes[name]; ok {
+		return n, nil
+	}
+	if isRoot(name) {
+		return &memNode{dir: true, mode: fs.ModeDir | 0755}, nil
+	}
+	return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+func isRoot(name string) bool {
+	return filepath.Dir(name) == name
+}
+
+// parentDir fails unless the parent of name is a directory; callers hold mu.
+func (m *Mem) parentDir(op, name string) error {
+	p, err := m.node(op, filepath.Dir(name))
+	if err != nil {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	if !p.dir {
+		return &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return nil
+}
+
+func (m *Mem) Stat(name string) (fs.FileInfo, error) { return m.Lstat(name) }
+
+func (m *Mem) Lstat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return n.info(filepath.Base(filepath.Clean(name))), nil
+}
+
+func (m *Mem) ReadDir(name string) ([]fs.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	n, err := m.node("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !n.dir {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	var out []fs.DirEntry
+	for p, c := range m.nodes {
+		if filepath.Dir(p) == name && p != name {
+			out = append(out, fs.FileInfoToDirEntry(c.info(filepath.Base(p))))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+func (m *Mem) Open(name string) (File, error) { return m.OpenFile(name, os.O_RDONLY, 0) }
+
+func (m *Mem) Create(name string) (File, error) {
+	return m.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+// OpenFile honours O_CREATE, O_EXCL and O_TRUNC. Writes always append and reach the file at once; a
+// reader sees the content as it was when it opened the file.
+func (m *Mem) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	n, err := m.node("open", name)
+	switch {
+	case err != nil && flag&os.O_CREATE == 0:
+		return nil, err
+	case err != nil:
+		if err := m.parentDir("open", name); err != nil {
+			return nil, err
+		}
+		n = &memNode{mode: perm & fs.ModePerm, mtime: time.Now()}
+		m.nodes[name] = n
+	case flag&(os.O_CREATE|os.O_EXCL) == os.O_CREATE|os.O_EXCL:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrExist}
+	case n.dir && flag&(os.O_WRONLY|os.O_RDWR) != 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+	if flag&os.O_TRUNC != 0 {
+		n.data, n.mtime = nil, time.Now()
+	}
+	h := &memFile{m: m, n: n, name: name, write: flag&(os.O_WRONLY|os.O_RDWR) != 0}
+	h.r = bytes.NewReader(append([]byte(nil), n.data...))
+	return h, nil
+}
+
+func (m *Mem) Rename(oldpath, newpath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	n, err := m.node("rename", oldpath)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if err := m.parentDir("rename", newpath); err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	if t, ok := m.nodes[newpath]; ok && t.dir != n.dir {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrExist}
+	}
+	delete(m.nodes, oldpath)
+	m.nodes[newpath] = n
+	if n.dir {
+		prefix := oldpath + string(filepath.Separator)
+		moved := make(map[string]*memNode)
+		for p, c := range m.nodes {
+			if strings.HasPrefix(p, prefix) {
+				moved[filepath.Join(newpath, strings.TrimPrefix(p, prefix))] = c
+				delete(m.nodes, p)
+			}
+		}
+		for p, c := range moved {
+			m.nodes[p] = c
+		}
+	}
+	return nil
+}
+
+func (m *Mem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = filepath.Clean(name)
+	n, err := m.node("remove", name)
+	if err != nil {
+		return err
+	}
+	if n.dir {
+		for p := range m.nodes {
+			if filepath.Dir(p) == name && p != name {
+				return &fs.PathError{Op: "remove", Path: name, Err: errNotEmpty}
+			}
+		}
+	}
+	delete(m.nodes, name)
+	return nil
+}
+
+func (m *Mem) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	path = filepath.Clean(path)
+	var missing []string
+	for p := path; ; p = filepath.Dir(p) {
+		n, err := m.node("mkdir", p)
+		if err == nil {
+			if !n.dir {
+				return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+			}
+			break
+		}
+		missing = append(missing, p)
+	}
+	for _, p := range missing {
+		m.nodes[p] = &memNode{dir: true, mode: fs.ModeDir | perm&fs.ModePerm, mtime: time.Now()}
+	}
+	return nil
+}
+
+func (m *Mem) Chtimes(name string, atime, mtime time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n, err := m.node("chtimes", name)
+	if err != nil {
+		return err
+	}
+	n.mtime = mtime
+	return nil
+}
+
+// Trash removes name: a file system in memory keeps no trash.
+func (m *Mem) Trash(name string) error { return m.Remove(name) }
+
+// SameFile holds for one path written two ways only, Mem having no links.
+func (m *Mem) SameFile(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b) && Exists(m, a)
+}
+
+func (n *memNode) info(name string) fs.FileInfo {
+	return memInfo{name: name, size: int64(len(n.data)), mode: n.mode, mtime: n.mtime}
+}
+
+type memInfo struct {
+	name  string
+	size  int64
+	mode  fs.FileMode
+	mtime time.Time
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() fs.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return i.mtime }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+// memFile is an open Mem file.
+type memFile struct {
+	m      *Mem
+	n      *memNode
+	name   string
+	r      *bytes.Reader
+	write  bool
+	closed bool
+}
+
+func (f *memFile) Name() string { return f.name }
+
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	return f.r.Read(p)
+}
+
+func (f *memFile) Write(p []byte) (int, error) {
+	if f.closed {
+		return 0, fs.ErrClosed
+	}
+	if !f.write {
+		return 0, &fs.PathError{Op: "write", Path: f.name, Err: fs.ErrPermission}
+	}
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	f.n.data = append(f.n.data, p...)
+	f.n.mtime = time.Now()
+	return len(p), nil
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	f.m.mu.Lock()
+	defer f.m.mu.Unlock()
+	return f.n.info(filepath.Base(f.name)), nil
+}
+
+func (f *memFile) Sync() error { return nil }
+
+func (f *memFile) Close() error {
+	if f.closed {
+		return fs.ErrClosed
+	}
+	f.closed = true
+	return nil
+}
+
+var _ FS = (*Mem)(nil)
@@ -0,0 +1,371 @@
+package fsys
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"lume-go/internal/logger"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Op is a change a Recorder was asked to make.
+type Op struct {
+	Kind string // "create", "write", "rename", "remove", "trash", "mkdir" or "chtimes"
+	Path string
+	To   string // the new path of a rename
+}
+
+func (o Op) String() string {
+	if o.To != "" {
+		return fmt.Sprintf("%s %s -> %s", o.Kind, o.Path, o.To)
+	}
+	return o.Kind + " " + o.Path
+}
+
+// Recorder is the file system of a dry run: it reads from Base but only logs and records the changes
+// it is asked for. It shows them to its own reads as they would be, so an operation made of several
+// calls, such as a move that renames a file and reads it back to verify it, goes through: a renamed
+// file reads from where it was, a removed one is gone, a created one holds what was written to it
+// (in memory). A renamed folder is recorded, its files are not followed.
+type Recorder struct {
+	Base  FS
+	mu    sync.Mutex
+	ops   []Op
+	files map[string]*recorded // files created
+	dirs  map[string]bool      // folders created
+	from  map[string]string    // the Base path a renamed file reads from
+	gone  map[string]bool      // Base paths removed or renamed away
+}
+
+// NewRecorder returns a Recorder reading from base (nil is OS).
+func NewRecorder(base FS) *Recorder {
+	return &Recorder{Base: Or(base), files: make(map[string]*recorded), dirs: make(map[string]bool), from: make(map[string]string), gone: make(map[string]bool)}
+}
+
+// Ops returns the changes recorded so far, in order.
+func (r *Recorder) Ops() []Op {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Op(nil), r.ops...)
+}
+
+// record logs and records op; callers hold mu.
+func (r *Recorder) record(op Op) {
+	logger.Info("Dry run, would %s", op)
+	r.ops = append(r.ops, op)
+}
+
+// target is what name is in the dry run: a file it created, a folder it created, or the Base path it
+// reads from. Callers hold mu.
+func (r *Recorder) target(op, name string) (f *recorded, dir bool, base string, err error) {
+	name = filepath.Clean(name)
+	if f, ok := r.files[name]; ok {
+		return f, false, "", nil
+	}
+	if r.dirs[name] {
+		return nil, true, "", nil
+	}
+	if p, ok := r.from[name]; ok {
+		return nil, false, p, nil
+	}
+	if r.gone[name] {
+		return nil, false, "", &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return nil, false, name, nil
+}
+
+func (r *Recorder) Stat(name string) (fs.FileInfo, error) { return r.stat("stat", name, r.Base.Stat) }
+func (r *Recorder) Lstat(name string) (fs.FileInfo, error) {
+	return r.stat("lstat", name, r.Base.Lstat)
+}
+
+func (r *Recorder) stat(op, name string, stat func(string) (fs.FileInfo, error)) (fs.FileInfo, error) {
+	r.mu.Lock()
+	f, dir, base, err := r.target(op, name)
+	r.mu.Unlock()
+	switch {
+	case err != nil:
+		return nil, err
+	case f != nil:
+		return f.info(), nil
+	case dir:
+		return memInfo{name: filepath.Base(name), mode: fs.ModeDir | 0755, mtime: time.Now()}, nil
+	}
+	st, err := stat(base)
+	if err != nil || base == filepath.Clean(name) {
+		return st, err
+	}
+	return renamed{st, filepath.Base(name)}, nil
+}
+
+// ReadDir lists name as the dry run left it.
+func (r *Recorder) ReadDir(name string) ([]fs.DirEntry, error) {
+	name = filepath.Clean(name)
+	r.mu.Lock()
+	_, dir, base, err := r.target("readdir", name)
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]fs.DirEntry)
+	if !dir {
+		entries, err := r.Base.ReadDir(base)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			byName[e.Name()] = e
+		}
+	}
+	r.mu.Lock()
+	for p := range r.gone {
+		if filepath.Dir(p) == name {
+			delete(byName, filepath.Base(p))
+		}
+	}
+	var added []string
+	for p := range r.files {
+		added = append(added, p)
+	}
+	for p := range r.dirs {
+		added = append(added, p)
+	}
+	for p := range r.from {
+		added = append(added, p)
+	}
+	r.mu.Unlock()
+	for _, p := range added {
+		if filepath.Dir(p) != name || p == name {
+			continue
+		}
+		if st, err := r.Lstat(p); err == nil {
+			byName[st.Name()] = fs.FileInfoToDirEntry(st)
+		}
+	}
+	out := make([]fs.DirEntry, 0, len(byName))
+	for _, e := range byName {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+func (r *Recorder) Open(name string) (File, error) {
+	r.mu.Lock()
+	f, dir, base, err := r.target("open", name)
+	r.mu.Unlock()
+	switch {
+	case err != nil:
+		return nil, err
+	case f != nil:
+		return f.open(), nil
+	case dir:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: errIsDir}
+	}
+	return r.Base.Open(base)
+}
+
+func (r *Recorder) Create(name string) (File, error) {
+	name = filepath.Clean(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(Op{Kind: "create", Path: name})
+	f := &recorded{name: name, mtime: time.Now()}
+	r.files[name] = f
+	delete(r.from, name)
+	delete(r.gone, name)
+	return &recordedFile{f: f, write: true}, nil
+}
+
+// OpenFile opens for reading as Open does. An existing file opened for writing in place is recorded
+// as written, the writes discarded; otherwise opened for writing, the file is recorded as created.
+func (r *Recorder) OpenFile(name string, flag int, perm fs.FileMode) (File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) == 0 {
+		return r.Open(name)
+	}
+	if flag&(os.O_CREATE|os.O_TRUNC) == 0 {
+		f, err := r.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		r.record(Op{Kind: "write", Path: filepath.Clean(name)})
+		r.mu.Unlock()
+		return discardWrites{f}, nil
+	}
+	return r.Create(name)
+}
+
+func (r *Recorder) Rename(oldpath, newpath string) error {
+	oldpath, newpath = filepath.Clean(oldpath), filepath.Clean(newpath)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, dir, base, err := r.target("rename", oldpath)
+	if err == nil && f == nil && !dir {
+		_, err = r.Base.Lstat(base)
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldpath, New: newpath, Err: fs.ErrNotExist}
+	}
+	r.record(Op{Kind: "rename", Path: oldpath, To: newpath})
+	r.drop(oldpath)
+	r.drop(newpath)
+	delete(r.gone, newpath)
+	switch {
+	case f != nil:
+		f.name = newpath
+		r.files[newpath] = f
+	case dir:
+		r.dirs[newpath] = true
+	case base != newpath:
+		r.from[newpath] = base
+	}
+	return nil
+}
+
+func (r *Recorder) Remove(name string) error { return r.remove("remove", name) }
+
+// Trash is Remove recorded as a deletion into the trash.
+func (r *Recorder) Trash(name string) error { return r.remove("trash", name) }
+
+func (r *Recorder) remove(kind, name string) error {
+	name = filepath.Clean(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, dir, base, err := r.target(kind, name)
+	if err == nil && f == nil && !dir {
+		_, err = r.Base.Lstat(base)
+	}
+	if err != nil {
+		return err
+	}
+	r.record(Op{Kind: kind, Path: name})
+	r.drop(name)
+	return nil
+}
+
+// drop takes name out of the dry run's view; callers hold mu.
+func (r *Recorder) drop(name string) {
+	delete(r.files, name)
+	delete(r.dirs, name)
+	delete(r.from, name)
+	r.gone[name] = true
+}
+
+func (r *Recorder) MkdirAll(path string, perm fs.FileMode) error {
+	path = filepath.Clean(path)
+	if st, err := r.Stat(path); err == nil && st.IsDir() {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(Op{Kind: "mkdir", Path: path})
+	for p := path; !isRoot(p); p = filepath.Dir(p) {
+		f, dir, base, err := r.target("mkdir", p)
+		if dir {
+			break
+		}
+		if err == nil && f == nil {
+			if st, err := r.Base.Stat(base); err == nil && st.IsDir() {
+				break
+			}
+		}
+		r.dirs[p] = true
+		delete(r.gone, p)
+	}
+	return nil
+}
+
+func (r *Recorder) Chtimes(name string, atime, mtime time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.record(Op{Kind: "chtimes", Path: name})
+	if f, ok := r.files[filepath.Clean(name)]; ok {
+		f.mtime = mtime
+	}
+	return nil
+}
+
+// SameFile compares the files a and b read from: a created file is only itself.
+func (r *Recorder) SameFile(a, b string) bool {
+	r.mu.Lock()
+	fa, da, pa, erra := r.target("stat", a)
+	fb, db, pb, errb := r.target("stat", b)
+	r.mu.Unlock()
+	switch {
+	case erra != nil || errb != nil:
+		return false
+	case fa != nil || fb != nil || da || db:
+		return fa == fb && da == db && filepath.Clean(a) == filepath.Clean(b)
+	}
+	return r.Base.SameFile(pa, pb)
+}
+
+// recorded is a file a Recorder pretends to create, with what was written to it.
+type recorded struct {
+	mu    sync.Mutex
+	name  string
+	data  []byte
+	mtime time.Time
+}
+
+func (f *recorded) info() fs.FileInfo {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return memInfo{name: filepath.Base(f.name), size: int64(len(f.data)), mode: 0644, mtime: f.mtime}
+}
+
+func (f *recorded) open() File {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &recordedFile{f: f, r: bytes.NewReader(append([]byte(nil), f.data...))}
+}
+
+// recordedFile is an open recorded file: writes append to it, reads see it as it was when opened.
+type recordedFile struct {
+	f     *recorded
+	r     *bytes.Reader
+	write bool
+}
+
+func (h *recordedFile) Name() string { return h.f.name }
+
+func (h *recordedFile) Read(p []byte) (int, error) {
+	if h.r == nil {
+		return 0, fs.ErrPermission
+	}
+	return h.r.Read(p)
+}
+
+func (h *recordedFile) Write(p []byte) (int, error) {
+	if !h.write {
+		return 0, &fs.PathError{Op: "write", Path: h.f.name, Err: fs.ErrPermission}
+	}
+	h.f.mu.Lock()
+	defer h.f.mu.Unlock()
+	h.f.data = append(h.f.data, p...)
+	return len(p), nil
+}
+
+func (h *recordedFile) Stat() (fs.FileInfo, error) { return h.f.info(), nil }
+func (h *recordedFile) Sync() error                { return nil }
+func (h *recordedFile) Close() error               { return nil }
+
+// discardWrites is a file a Recorder pretends to write in place.
+type discardWrites struct{ File }
+
+func (d discardWrites) Write(p []byte) (int, error) { return len(p), nil }
+
+// renamed is the FileInfo of a file read under the name it was renamed to.
+type renamed struct {
+	fs.FileInfo
+	name string
+}
+
+func (i renamed) Name() string { return i.name }
+
+var _ FS = (*Recorder)(nil)
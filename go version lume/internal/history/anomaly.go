@@ -0,0 +1,177 @@
+package history
+
+import (
+	"slices"
+	"time"
+)
+
+// Run is one automated run of a source, scheduled or from the command line: when it ended, the files
+// it found and how many of them failed.
+type Run struct {
+	At     time.Time `json:"at"`
+	Source string    `json:"source"`
+	Files  int       `json:"files"`
+	Errors int       `json:"errors"`
+}
+
+// MaxRuns bounds the runs kept, the oldest going first.
+const MaxRuns = 1000
+
+// BaselineRuns is how many of the latest earlier runs of a source make its baseline.
+const BaselineRuns = 30
+
+// The kinds of Anomaly.
+const (
+	AnomalyNoFiles  = "no_files"
+	AnomalyFewFiles = "few_files"
+	AnomalyErrors   = "errors"
+)
+
+// Thresholds tune when a run is flagged. A zero field takes the value of DefaultThresholds, a
+// negative one turns its check off.
+type Thresholds struct {
+	// NoFilesRuns is how many runs in a row must find nothing, of a source that usually has files.
+	NoFilesRuns int `json:"no_files_runs"`
+	// FewFiles flags a run finding fewer files than this share of the median run.
+	FewFiles float64 `json:"few_files"`
+	// ErrorRise flags a run whose share of failed files is this much above the typical one.
+	ErrorRise float64 `json:"error_rise"`
+	// MinRuns is how many earlier runs a baseline needs; before that nothing is flagged.
+	MinRuns int `json:"min_runs"`
+}
+
+// DefaultThresholds flag three empty runs in a row, a run with a tenth of the usual files, and a
+// quarter more of the files failing than usual, once five runs make a baseline.
+var DefaultThresholds = Thresholds{NoFilesRuns: 3, FewFiles: 0.1, ErrorRise: 0.25, MinRuns: 5}
+
+func (t Thresholds) withDefaults() Thresholds {
+	d := DefaultThresholds
+	if t.NoFilesRuns != 0 {
+		d.NoFilesRuns = t.NoFilesRuns
+	}
+	if t.FewFiles != 0 {
+		d.FewFiles = t.FewFiles
+	}
+	if t.ErrorRise != 0 {
+		d.ErrorRise = t.ErrorRise
+	}
+	if t.MinRuns > 0 {
+		d.MinRuns = t.MinRuns
+	}
+	return d
+}
+
+// Anomaly is a run out of line with the earlier runs of its source.
+type Anomaly struct {
+	Kind   string
+	Source string
+	// Runs is the empty runs in a row, the flagged one included, and Since when the first of them ended.
+	Runs  int
+	Since time.Time
+	// Files and Errors are those of the flagged run, Rate its share of failed files.
+	Files  int
+	Errors int
+	Rate   float64
+	// Typical is the median files per run for the file counts, the median failed share for errors.
+	Typical float64
+}
+
+// Evaluate compares run with the earlier runs of its source, oldest first, and returns what is out of
+// line: a source that usually has files finding none several runs in a row (a sync that broke), far
+// fewer files than usual, or many more failing. The baseline is the runs before the current streak
+// of empty ones, so a long streak does not become the norm. A single failed file is never flagged.
+func Evaluate(past []Run, run Run, th Thresholds) []Anomaly {
+	th = th.withDefaults()
+	streak := 0
+	if run.Files == 0 {
+		streak = 1
+		for i := len(past) - 1; i >= 0 && past[i].Files == 0; i-- {
+			streak++
+		}
+	}
+	base := past[:len(past)-max(streak-1, 0)]
+	base = base[max(len(base)-BaselineRuns, 0):]
+	if len(base) < th.MinRuns {
+		return nil
+	}
+	var files, rates []float64
+	for _, r := range base {
+		files = append(files, float64(r.Files))
+		if r.Files > 0 {
+			rates = append(rates, float64(r.Errors)/float64(r.Files))
+		}
+	}
+	typical := median(files)
+	a := Anomaly{Source: run.Source, Files: run.Files, Errors: run.Errors, Typical: typical}
+	var out []Anomaly
+	switch {
+	case run.Files == 0:
+		if th.NoFilesRuns > 0 && streak >= th.NoFilesRuns && typical > 0 {
+			a.Kind, a.Runs, a.Since = AnomalyNoFiles, streak, run.At
+			if streak > 1 {
+				a.Since = past[len(past)-streak+1].At
+			}
+			out = append(out, a)
+		}
+		return out
+	case th.FewFiles > 0 && float64(run.Files) < th.FewFiles*typical:
+		a.Kind = AnomalyFewFiles
+		out = append(out, a)
+	}
+	a.Rate = float64(run.Errors) / float64(run.Files)
+	if th.ErrorRise > 0 && run.Errors > 1 && len(rates) > 0 {
+		if usual := median(rates); a.Rate >= usual+th.ErrorRise {
+			a.Kind, a.Typical = AnomalyErrors, usual
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func median(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	s := slices.Clone(v)
+	slices.Sort(s)
+	if n := len(s); n%2 == 0 {
+		return (s[n/2-1] + s[n/2]) / 2
+	}
+	return s[len(s)/2]
+}
+
+// AddRun records an automated run and returns what Evaluate finds out of line in it.
+func (h *History) AddRun(run Run, th Thresholds) []Anomaly {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	found := Evaluate(h.runsOf(run.Source), run, th)
+	h.Runs = append(h.Runs, run)
+	if n := len(h.Runs) - MaxRuns; n > 0 {
+		h.Runs = slices.Delete(h.Runs, 0, n)
+	}
+	return found
+}
+
+// runsOf lists the runs of source, oldest first. h.mu is held.
+func (h *History) runsOf(source string) []Run {
+	var runs []Run
+	for _, r := range h.Runs {
+		if r.Source == source {
+			runs = append(runs, r)
+		}
+	}
+	return runs
+}
+
+// Anomalies evaluates the latest run against the earlier runs of its source. Each run replaces what
+// the run before it flagged, so a resolved anomaly is not shown past the next run.
+func (h *History) Anomalies(th Thresholds) []Anomaly {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.Runs) == 0 {
+		return nil
+	}
+	latest := h.Runs[len(h.Runs)-1]
+	runs := h.runsOf(latest.Source)
+	return Evaluate(runs[:len(runs)-1], latest, th)
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// nightly makes runs of src a day apart from 2024-03-01, one per file count, each with errs[i] failed
+// files when errs has that many.
+func nightly(src string, files []int, errs ...int) []Run {
+	start := time.Date(2024, 3, 1, 2, 0, 0, 0, time.UTC)
+	runs := make([]Run, len(files))
+	for i, n := range files {
+		runs[i] = Run{At: start.AddDate(0, 0, i), Source: src, Files: n}
+		if i < len(errs) {
+			runs[i].Errors = errs[i]
+		}
+	}
+	return runs
+}
+
+func TestEvaluate(t *testing.T) {
+	usual := []int{40, 55, 38, 61, 47, 52}
+	tests := []struct {
+		name  string
+		files []int
+		errs  []int
+		th    Thresholds
+		want  []string
+		runs  int
+	}{
+		{"usual run", append(usual, 44), nil, Thresholds{}, nil, 0},
+		{"too few runs for a baseline", []int{40, 55, 0, 0, 0}, nil, Thresholds{}, nil, 0},
+		{"empty run after a full one", append(usual, 0), nil, Thresholds{}, nil, 0},
+		{"empty runs below the streak", append(usual, 0, 0), nil, Thresholds{}, nil, 0},
+		{"third empty run", append(usual, 0, 0, 0), nil, Thresholds{}, []string{AnomalyNoFiles}, 3},
+		{"streak never becomes the norm", append(usual, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0), nil, Thresholds{}, []string{AnomalyNoFiles}, 10},
+		{"streak set to a week", append(usual, 0, 0, 0, 0, 0, 0), nil, Thresholds{NoFilesRuns: 7}, nil, 0},
+		{"week reached", append(usual, 0, 0, 0, 0, 0, 0, 0), nil, Thresholds{NoFilesRuns: 7}, []string{AnomalyNoFiles}, 7},
+		{"empty check off", append(usual, 0, 0, 0), nil, Thresholds{NoFilesRuns: -1}, nil, 0},
+		{"source that is usually empty", []int{0, 3, 0, 0, 1, 0, 0, 0, 0}, nil, Thresholds{}, nil, 0},
+		{"far fewer files", append(usual, 2), nil, Thresholds{}, []string{AnomalyFewFiles}, 0},
+		{"fewer files check off", append(usual, 2), nil, Thresholds{FewFiles: -1}, nil, 0},
+		{"error rate jumps", append(usual, 50), []int{0, 1, 0, 0, 2, 0, 20}, Thresholds{}, []string{AnomalyErrors}, 0},
+		{"error rate as usual", append(usual, 50), []int{8, 12, 9, 10, 11, 10, 12}, Thresholds{}, nil, 0},
+		{"error rise set higher", append(usual, 50), []int{0, 1, 0, 0, 2, 0, 20}, Thresholds{ErrorRise: 0.5}, nil, 0},
+		{"one failed file of two", append(usual, 2), []int{0, 0, 0, 0, 0, 0, 1}, Thresholds{FewFiles: -1}, nil, 0},
+		{"few files, most failing", append(usual, 3), []int{0, 0, 0, 0, 0, 0, 3}, Thresholds{}, []string{AnomalyFewFiles, AnomalyErrors}, 0},
+		{"min runs raised", append(usual, 0, 0, 0), nil, Thresholds{MinRuns: 10}, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runs := nightly("D:\\Sync", tt.files, tt.errs...)
+			got := Evaluate(runs[:len(runs)-1], runs[len(runs)-1], tt.th)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Evaluate = %+v; want kinds %v", got, tt.want)
+			}
+			for i, a := range got {
+				if a.Kind != tt.want[i] || a.Source != "D:\\Sync" {
+					t.Errorf("anomaly %d = %+v; want kind %s", i, a, tt.want[i])
+				}
+				if a.Kind == AnomalyNoFiles {
+					since := runs[len(runs)-tt.runs].At
+					if a.Runs != tt.runs || !a.Since.Equal(since) || a.Typical != 49.5 {
+						t.Errorf("streak %d since %s, typical %g; want %d since %s, 49.5", a.Runs, a.Since, a.Typical, tt.runs, since)
+					}
+				}
+			}
+		})
+	}
+
+	// The error baseline is the median failed share of the runs that found files.
+	runs := nightly("D:\\Sync", append(usual, 0, 40), 2, 2, 2, 3, 2, 2, 0, 30)
+	got := Evaluate(runs[:len(runs)-1], runs[len(runs)-1], Thresholds{})
+	if len(got) != 1 || got[0].Rate != 0.75 || got[0].Typical < 0.04 || got[0].Typical > 0.05 {
+		t.Errorf("Evaluate = %+v; want the rate 0.75 against about 4%%", got)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	h, _ := Load(path)
+	var found []Anomaly
+	for _, r := range nightly("D:\\Sync", []int{40, 55, 38, 61, 47, 52, 0, 0, 0}) {
+		h.AddRun(Run{At: r.At, Source: "E:\\Camera", Files: 10}, Thresholds{})
+		found = h.AddRun(r, Thresholds{})
+	}
+	if len(found) != 1 || found[0].Kind != AnomalyNoFiles || found[0].Runs != 3 {
+		t.Errorf("AddRun = %+v; want the third empty run flagged", found)
+	}
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h.Runs) != 18 {
+		t.Fatalf("%d runs kept; want 18", len(h.Runs))
+	}
+	if a := h.Anomalies(Thresholds{}); len(a) != 1 || a[0].Source != "D:\\Sync" {
+		t.Errorf("Anomalies = %+v; want D:\\Sync alone", a)
+	}
+	// The next run, of any source, replaces them.
+	h.AddRun(Run{Source: "E:\\Camera", Files: 10}, Thresholds{})
+	if a := h.Anomalies(Thresholds{}); len(a) != 0 {
+		t.Errorf("Anomalies after a sound run = %+v; want none", a)
+	}
+
+	for i := 0; i < MaxRuns; i++ {
+		h.AddRun(Run{Source: "E:\\Camera", Files: 1}, Thresholds{})
+	}
+	if len(h.Runs) != MaxRuns || h.Runs[0].Source != "E:\\Camera" {
+		t.Errorf("%d runs kept, the oldest of %s; want %d", len(h.Runs), h.Runs[0].Source, MaxRuns)
+	}
+}
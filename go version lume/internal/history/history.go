@@ -0,0 +1,227 @@
+// Package history keeps lifetime statistics of archived files, broken down by source and device, and
+// how fast earlier runs moved files between volumes.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the history file kept in the user's config directory.
+const FileName = "history.json"
+
+const historyVersion = 1
+
+// MaxKeys bounds the distinct sources and devices each kept; later newcomers are counted under
+// OtherKey so the file cannot grow without limit.
+const (
+	MaxKeys  = 100
+	OtherKey = "Other"
+)
+
+// Bucket counts archived files and their bytes.
+type Bucket struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+func (b *Bucket) add(size int64) {
+	b.Files++
+	b.Bytes += size
+}
+
+// Rate is the moving average of how fast runs moved files from one volume to another.
+type Rate struct {
+	BytesPerSec float64 `json:"bytes_per_sec"`
+	FilesPerSec float64 `json:"files_per_sec"`
+	Runs        int     `json:"runs"`
+}
+
+// RateWeight is the share of a run's own speed in the new average; the rest is the earlier average.
+const RateWeight = 0.3
+
+// MinRateSample is the least time a run must have moved files between two volumes to count, so a few
+// quick files do not swing the average.
+const MinRateSample = time.Second
+
+// Entry is one line of a breakdown.
+type Entry struct {
+	Key string
+	Bucket
+}
+
+// History is the persisted statistics. It is safe for concurrent use.
+type History struct {
+	mu      sync.Mutex
+	path    string
+	Total   Bucket            `json:"total"`
+	Sources map[string]Bucket `json:"sources"`
+	Devices map[string]Bucket `json:"devices"`
+	// Rates are keyed by RateKey.
+	Rates map[string]Rate `json:"rates,omitempty"`
+	// Runs are the latest automated runs, oldest first, that anomalies are judged against.
+	Runs []Run `json:"runs,omitempty"`
+}
+
+type historyFile struct {
+	Version int `json:"version"`
+	*History
+}
+
+// Load reads the history at path. A missing file is an empty history; an unreadable one is returned
+// empty together with the error, and is replaced on the next Save.
+func Load(path string) (*History, error) {
+	h := &History{path: path, Sources: make(map[string]Bucket), Devices: make(map[string]Bucket), Rates: make(map[string]Rate)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	} else if err != nil {
+		return h, err
+	}
+	loaded := &History{}
+	f := historyFile{History: loaded}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return h, err
+	}
+	if f.Version != historyVersion {
+		return h, fmt.Errorf("history version %d", f.Version)
+	}
+	h.Total = loaded.Total
+	for k, b := range loaded.Sources {
+		h.Sources[k] = b
+	}
+	for k, b := range loaded.Devices {
+		h.Devices[k] = b
+	}
+	for k, r := range loaded.Rates {
+		h.Rates[k] = r
+	}
+	h.Runs = loaded.Runs
+	return h, nil
+}
+
+// Add records one archived file.
+func (h *History) Add(source, device string, size int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Total.add(size)
+	addKey(h.Sources, source, size)
+	addKey(h.Devices, device, size)
+}
+
+func addKey(m map[string]Bucket, key string, size int64) {
+	if key == "" {
+		key = "Unknown"
+	}
+	if _, ok := m[key]; !ok && len(m) >= MaxKeys {
+		key = OtherKey
+	}
+	b := m[key]
+	b.add(size)
+	m[key] = b
+}
+
+// RateKey names the pair of volumes files moved between, from one volume to another or within one.
+func RateKey(from, to string) string { return from + ">" + to }
+
+// AddRate folds a run that moved files (bytes in total) from volume from to volume to in elapsed into
+// the pair's average. Samples shorter than MinRateSample are left out. Past MaxKeys pairs, new ones
+// are not kept.
+func (h *History) AddRate(from, to string, files int, bytes int64, elapsed time.Duration) {
+	if files == 0 || elapsed < MinRateSample {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := RateKey(from, to)
+	r, ok := h.Rates[key]
+	if !ok && len(h.Rates) >= MaxKeys {
+		return
+	}
+	bps, fps := float64(bytes)/elapsed.Seconds(), float64(files)/elapsed.Seconds()
+	if r.Runs == 0 {
+		r.BytesPerSec, r.FilesPerSec = bps, fps
+	} else {
+		r.BytesPerSec += RateWeight * (bps - r.BytesPerSec)
+		r.FilesPerSec += RateWeight * (fps - r.FilesPerSec)
+	}
+	r.Runs++
+	h.Rates[key] = r
+}
+
+// Rate returns the average of moving files from volume from to volume to. Without one for the pair,
+// it falls back to the mean of the pairs of the same kind, moves within a volume or across volumes;
+// ok is false when there is none of either.
+func (h *History) Rate(from, to string) (r Rate, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r, ok := h.Rates[RateKey(from, to)]; ok {
+		return r, true
+	}
+	n := 0
+	for key, pr := range h.Rates {
+		f, t, _ := strings.Cut(key, ">")
+		if (f == t) != (from == to) {
+			continue
+		}
+		r.BytesPerSec += pr.BytesPerSec
+		r.FilesPerSec += pr.FilesPerSec
+		n++
+	}
+	if n == 0 {
+		return Rate{}, false
+	}
+	r.BytesPerSec /= float64(n)
+	r.FilesPerSec /= float64(n)
+	return r, true
+}
+
+// Save writes the history atomically.
+func (h *History) Save() error {
+	h.mu.Lock()
+	data, err := json.MarshalIndent(historyFile{Version: historyVersion, History: h}, "", "  ")
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(h.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(h.path+".tmp", h.path)
+}
+
+// Totals is the bucket of every archived file, read under the lock Add holds.
+func (h *History) Totals() Bucket {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.Total
+}
+
+// BySource and ByDevice list the breakdowns, largest first.
+func (h *History) BySource() []Entry { return h.sorted(h.Sources) }
+func (h *History) ByDevice() []Entry { return h.sorted(h.Devices) }
+
+func (h *History) sorted(m map[string]Bucket) []Entry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]Entry, 0, len(m))
+	for k, b := range m {
+		out = append(out, Entry{k, b})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Bytes != out[j].Bytes {
+			return out[i].Bytes > out[j].Bytes
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
@@ -0,0 +1,88 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Lume", FileName)
+	h, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Add("WhatsApp", "Unknown", 100)
+	h.Add("WhatsApp", "Unknown", 50)
+	h.Add("Camera", "Pixel 8", 4000)
+	h.Add("", "", 1)
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	h, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := h.Totals(); got != (Bucket{4, 4151}) {
+		t.Errorf("Totals = %+v", got)
+	}
+	src := h.BySource()
+	if len(src) != 3 || src[0].Key != "Camera" || src[1] != (Entry{"WhatsApp", Bucket{2, 150}}) || src[2].Key != "Unknown" {
+		t.Errorf("BySource = %+v", src)
+	}
+
+	// Past MaxKeys, new devices fold into Other; known ones keep counting. Unknown and Pixel 8 are
+	// already there, so 98 of the new models get their own key.
+	for i := 0; i < MaxKeys+5; i++ {
+		h.Add("Camera", fmt.Sprintf("Model %d", i), 1)
+	}
+	h.Add("Camera", "Pixel 8", 1)
+	if len(h.Devices) != MaxKeys+1 || h.Devices[OtherKey].Files != 7 || h.Devices["Pixel 8"].Files != 2 {
+		t.Errorf("%d devices, Other %+v, Pixel 8 %+v", len(h.Devices), h.Devices[OtherKey], h.Devices["Pixel 8"])
+	}
+}
+
+func TestHistoryCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	os.WriteFile(path, []byte("{not json"), 0644)
+	h, err := Load(path)
+	if err == nil || h == nil || h.Total.Files != 0 {
+		t.Fatalf("Load = %+v, %v", h, err)
+	}
+	h.Add("Camera", "X", 1)
+	if err := h.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if h, err = Load(path); err != nil || h.Total.Files != 1 {
+		t.Errorf("after rewrite: %+v, %v", h, err)
+	}
+}
+
+func TestRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	h, _ := Load(path)
+	if _, ok := h.Rate("C", "D"); ok {
+		t.Fatal("rate without any runs")
+	}
+	h.AddRate("C", "D", 10, 100<<20, 10*time.Second)
+	h.AddRate("C", "D", 10, 200<<20, 10*time.Second)
+	h.AddRate("C", "D", 1, 1<<20, time.Millisecond) // too short to count
+	h.AddRate("C", "C", 1000, 4<<30, 2*time.Second)
+	h.Save()
+
+	h, _ = Load(path)
+	r, ok := h.Rate("C", "D")
+	if want := float64(13 << 20); !ok || r.Runs != 2 || r.BytesPerSec != want || r.FilesPerSec != 1 {
+		t.Errorf("Rate(C, D) = %+v; want %.0f bytes/s over 2 runs", r, want)
+	}
+	// A pair never seen borrows the average of its kind.
+	if r, ok := h.Rate("E", "F"); !ok || r.BytesPerSec != float64(13<<20) {
+		t.Errorf("Rate(E, F) = %+v, %v", r, ok)
+	}
+	if r, ok := h.Rate("E", "E"); !ok || r.FilesPerSec != 500 {
+		t.Errorf("Rate(E, E) = %+v, %v", r, ok)
+	}
+}
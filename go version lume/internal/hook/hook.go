@@ -0,0 +1,138 @@
+// Package hook tells another program that a run has finished: an HTTP POST of the run summary to a
+// URL, or a command started with the summary. lume-lite sends the same payload.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"time"
+)
+
+// DefaultTimeout bounds one delivery attempt when the config sets none.
+const DefaultTimeout = 10 * time.Second
+
+// retryDelay is the pause before the single retry of a failed POST.
+var retryDelay = 2 * time.Second
+
+// Run outcomes reported in Summary.Status.
+const (
+	StatusOK        = "ok"        // every file archived
+	StatusErrors    = "errors"    // finished, some files failed
+	StatusStopped   = "stopped"   // the target filled up; the rest was not attempted
+	StatusCancelled = "cancelled" // cancelled by the user
+)
+
+// Summary is the payload of a hook: the figures of one finished run.
+type Summary struct {
+	App      string    `json:"app"`
+	Version  string    `json:"version"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+	Targets  []string  `json:"targets"`
+	Status   string    `json:"status"`
+	// ExitCode is the code lume-lite exits with for this outcome, 0 on success.
+	ExitCode    int   `json:"exit_code"`
+	Total       int   `json:"total"`
+	Moved       int   `json:"moved"`
+	Bytes       int64 `json:"bytes"`
+	Missing     int   `json:"missing"`
+	Errors      int   `json:"errors"`
+	Unattempted int   `json:"unattempted"`
+	// Failures counts the failed files by category ("space", "integrity", ...).
+	Failures map[string]int `json:"failures,omitempty"`
+	// Layout is the --layout the paths of the run were laid out by, "template" for a --template of
+	// its own. Only lume-lite sends it.
+	Layout string `json:"layout,omitempty"`
+}
+
+// Config selects the hook: URL receives a POST, or Command is started with Args, the summary JSON
+// as its last argument and on its standard input. Setting neither turns the hook off.
+type Config struct {
+	URL     string   `json:"url,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"`
+	// Timeout bounds each attempt in seconds; 0 means DefaultTimeout.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Enabled reports whether a hook is configured.
+func (c Config) Enabled() bool { return c.URL != "" || c.Command != "" }
+
+// Check rejects a config that sets both a URL and a command.
+func (c Config) Check() error {
+	if c.URL != "" && c.Command != "" {
+		return errors.New("hook: set either a url or a command, not both")
+	}
+	return nil
+}
+
+func (c Config) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Send delivers s through the configured hook. A POST that fails or gets no 2xx answer is retried
+// once; a command is run once and must exit with 0.
+func Send(ctx context.Context, c Config, s Summary) error {
+	if err := c.Check(); err != nil || !c.Enabled() {
+		return err
+	}
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if c.Command != "" {
+		return command(ctx, c, payload)
+	}
+	if err = post(ctx, c, payload); err == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return err
+	case <-time.After(retryDelay):
+	}
+	if err2 := post(ctx, c, payload); err2 != nil {
+		return fmt.Errorf("hook %s failed twice: %w", c.URL, err2)
+	}
+	return nil
+}
+
+func post(ctx context.Context, c Config, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("hook %s answered %s", c.URL, resp.Status)
+	}
+	return nil
+}
+
+func command(ctx context.Context, c Config, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.Command, append(append([]string(nil), c.Args...), string(payload))...)
+	cmd.Stdin = bytes.NewReader(payload)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("hook %s: %w %s", c.Command, err, bytes.TrimSpace(out))
+	}
+	return nil
+}
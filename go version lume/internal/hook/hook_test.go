@@ -0,0 +1,103 @@
+package hook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var summary = Summary{App: "lume", Version: "test", Status: StatusErrors, ExitCode: 5, Total: 3, Moved: 2, Bytes: 2048, Errors: 1,
+	Targets: []string{`D:\Arşiv`}, Failures: map[string]int{"integrity": 1}}
+
+func TestPostRetriesOnce(t *testing.T) {
+	retryDelay = time.Millisecond
+	for _, tt := range []struct {
+		fail  int
+		calls int
+		ok    bool
+	}{{0, 1, true}, {1, 2, true}, {2, 2, false}} {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			var got Summary
+			body, _ := io.ReadAll(r.Body)
+			if err := json.Unmarshal(body, &got); err != nil || got.Moved != 2 || got.Failures["integrity"] != 1 || r.Header.Get("Content-Type") != "application/json" {
+				t.Errorf("payload %s: %v", body, err)
+			}
+			if calls <= tt.fail {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		err := Send(context.Background(), Config{URL: srv.URL}, summary)
+		srv.Close()
+		if (err == nil) != tt.ok || calls != tt.calls {
+			t.Errorf("%d failures: err %v after %d calls, want ok=%v after %d", tt.fail, err, calls, tt.ok, tt.calls)
+		}
+	}
+}
+
+func TestPostTimeout(t *testing.T) {
+	retryDelay = time.Millisecond
+	hang := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+	defer close(hang)
+	start := time.Now()
+	if err := Send(context.Background(), Config{URL: srv.URL, Timeout: 1}, summary); err == nil {
+		t.Fatal("hung server accepted")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("two attempts took %v", d)
+	}
+}
+
+// TestHelperProcess is the command started by TestCommand; it copies its input and last argument
+// to the file LUME_HOOK_OUT names.
+func TestHelperProcess(t *testing.T) {
+	out := os.Getenv("LUME_HOOK_OUT")
+	if out == "" {
+		return
+	}
+	stdin, _ := io.ReadAll(os.Stdin)
+	os.WriteFile(out, []byte(string(stdin)+"\n"+os.Args[len(os.Args)-1]), 0644)
+	os.Exit(0)
+}
+
+func TestCommand(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "hook.txt")
+	t.Setenv("LUME_HOOK_OUT", out)
+	if err := Send(context.Background(), Config{Command: os.Args[0], Args: []string{"-test.run=^TestHelperProcess$"}}, summary); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, arg, _ := strings.Cut(string(data), "\n")
+	var got Summary
+	if err := json.Unmarshal([]byte(stdin), &got); err != nil || got.Targets[0] != `D:\Arşiv` || got.ExitCode != 5 || stdin != arg {
+		t.Errorf("command got %q and %q: %v", stdin, arg, err)
+	}
+
+	if err := Send(context.Background(), Config{Command: filepath.Join(t.TempDir(), "missing")}, summary); err == nil {
+		t.Error("missing command reported as delivered")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if (Config{}).Enabled() || Send(context.Background(), Config{}, summary) != nil {
+		t.Error("empty config is not off")
+	}
+	if err := (Config{URL: "http://x", Command: "y"}).Check(); err == nil {
+		t.Error("url and command both accepted")
+	}
+}
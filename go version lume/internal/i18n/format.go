@@ -0,0 +1,81 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var byteUnits = []string{"B", "KB", "MB", "GB", "TB", "PB"}
+
+// FormatCount renders n with the digit grouping of lang, e.g. 12,345 in English and 12.345 in Turkish.
+func FormatCount(lang string, n int64) string {
+	return group(strconv.FormatInt(n, 10), locale(lang).Group)
+}
+
+// FormatBytes renders a byte size in binary units with one decimal (1.5 MB), switching to the next
+// unit once the rounded value reaches 1024 so sizes never show as "1024.0 MB" or "0 MB".
+func FormatBytes(lang string, n int64) string {
+	l := locale(lang)
+	if n < 1024 && n > -1024 {
+		return FormatCount(lang, n) + " B"
+	}
+	v, u := float64(n), 0
+	// 1023.95 and up would round to "1024.0", so those already move on to the next unit.
+	for u < len(byteUnits)-1 && math.Abs(v) >= 1023.95 {
+		v /= 1024
+		u++
+	}
+	whole, frac, _ := strings.Cut(strconv.FormatFloat(v, 'f', 1, 64), ".")
+	return group(whole, l.Group) + l.Decimal + frac + " " + byteUnits[u]
+}
+
+// FormatDuration renders a rough duration in lang, rounded to minutes: "under a minute", "6 min",
+// "2 h 5 min".
+func FormatDuration(lang string, d time.Duration) string {
+	m := int64(d.Round(time.Minute) / time.Minute)
+	switch {
+	case m < 1:
+		return T(lang, "dur_under_minute")
+	case m < 60:
+		return fmt.Sprintf(T(lang, "dur_minutes"), FormatCount(lang, m))
+	}
+	return fmt.Sprintf(T(lang, "dur_hours"), FormatCount(lang, m/60), m%60)
+}
+
+// group inserts sep between every three digits of an integer string.
+func group(digits, sep string) string {
+	sign := ""
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= 3 || sep == "" {
+		return sign + digits
+	}
+	var b strings.Builder
+	b.WriteString(sign)
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > len(sign) {
+			b.WriteString(sep)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
+
+// FormatSpace describes a drive short of space in lang: "D: needs 5.0 GB, only 1.0 GB free". path is
+// the drive or a folder on it; folders without a drive letter (e.g. on a share) are named in full.
+func FormatSpace(lang, path string, need, have int64) string {
+	vol := filepath.VolumeName(path)
+	if vol == "" {
+		vol = path
+	}
+	return fmt.Sprintf(T(lang, "space_detail"), vol, FormatBytes(lang, need), FormatBytes(lang, have))
+}
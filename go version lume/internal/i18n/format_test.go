@@ -0,0 +1,87 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int64
+		want string
+	}{
+		{"en", 0, "0 B"},
+		{"en", 1023, "1,023 B"},
+		{"en", 1024, "1.0 KB"},
+		{"en", 900 * 1024, "900.0 KB"},
+		{"en", 1536 * 1024, "1.5 MB"},
+		{"en", 1<<20 - 1, "1.0 MB"},
+		{"en", 1<<30 - 1, "1.0 GB"},
+		{"en", 1 << 30, "1.0 GB"},
+		{"en", 1500 << 30, "1.5 TB"},
+		{"tr", 1536 * 1024, "1,5 MB"},
+		{"de", 1023 << 50, "1.023,0 PB"},
+		{"xx", 2048, "2.0 KB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.lang, tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%q, %d) = %q; want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	tests := []struct {
+		lang string
+		n    int64
+		want string
+	}{
+		{"en", 0, "0"},
+		{"en", 999, "999"},
+		{"en", 1000, "1,000"},
+		{"en", 1234567, "1,234,567"},
+		{"en", -12345, "-12,345"},
+		{"tr", 12345, "12.345"},
+		{"ru", 12345, "12 345"},
+	}
+	for _, tt := range tests {
+		if got := FormatCount(tt.lang, tt.n); got != tt.want {
+			t.Errorf("FormatCount(%q, %d) = %q; want %q", tt.lang, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSpace(t *testing.T) {
+	tests := []struct {
+		lang, path string
+		want       string
+	}{
+		{"tr", "D:", "D: sürücüsünde 5,0 GB gerekli, 1,0 GB boş"},
+		{"en", "D:", "D: needs 5.0 GB, only 1.0 GB free"},
+		{"en", `\\nas\photos`, `\\nas\photos needs 5.0 GB, only 1.0 GB free`},
+	}
+	for _, tt := range tests {
+		if got := FormatSpace(tt.lang, tt.path, 5<<30, 1<<30); got != tt.want {
+			t.Errorf("FormatSpace(%q, %q) = %q; want %q", tt.lang, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		lang string
+		d    time.Duration
+		want string
+	}{
+		{"en", 20 * time.Second, "under a minute"},
+		{"en", 6*time.Minute + 10*time.Second, "6 min"},
+		{"en", 125 * time.Minute, "2 h 5 min"},
+		{"tr", 1500 * time.Hour, "1.500 sa 0 dk"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.lang, tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%q, %v) = %q; want %q", tt.lang, tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package i18n holds the UI strings. Each language is one JSON file under locales/, embedded into the
+// binary, so adding a language means adding a file rather than touching the code.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Fallback is the language used for keys a translation does not define yet.
+const Fallback = "en"
+
+// Locale is one embedded language file.
+type Locale struct {
+	Code string `json:"-"`
+	// Name is the language's own name, shown in the language dropdown.
+	Name string `json:"name"`
+	// RTL marks right-to-left scripts such as Arabic.
+	RTL bool `json:"rtl"`
+	// Group and Decimal are the digit grouping and decimal separators used by FormatCount and FormatBytes.
+	Group   string            `json:"group"`
+	Decimal string            `json:"decimal"`
+	Strings map[string]string `json:"strings"`
+}
+
+//go:embed locales/*.json
+var files embed.FS
+
+var locales = mustLoad()
+
+func mustLoad() map[string]*Locale {
+	out, err := load()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+func load() (map[string]*Locale, error) {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]*Locale)
+	for _, e := range entries {
+		data, err := files.ReadFile(path.Join("locales", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var l Locale
+		if err := json.Unmarshal(data, &l); err != nil {
+			return nil, fmt.Errorf("locale %s: %w", e.Name(), err)
+		}
+		l.Code = strings.TrimSuffix(e.Name(), ".json")
+		out[l.Code] = &l
+	}
+	if out[Fallback] == nil {
+		return nil, fmt.Errorf("fallback locale %s.json is missing", Fallback)
+	}
+	return out, nil
+}
+
+// Languages lists the available languages ordered by code.
+func Languages() []Locale {
+	out := make([]Locale, 0, len(locales))
+	for _, l := range locales {
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// Has reports whether lang is an available language.
+func Has(lang string) bool {
+	return locales[lang] != nil
+}
+
+// IsRTL reports whether lang is written right to left.
+func IsRTL(lang string) bool {
+	l := locales[lang]
+	return l != nil && l.RTL
+}
+
+func locale(lang string) *Locale {
+	if l := locales[lang]; l != nil {
+		return l
+	}
+	return locales[Fallback]
+}
+
+// T returns the string for key in lang, falling back to English and then to the key itself.
+func T(lang, key string) string {
+	if l := locales[lang]; l != nil {
+		if s, ok := l.Strings[key]; ok {
+			return s
+		}
+	}
+	if s, ok := locales[Fallback].Strings[key]; ok {
+		return s
+	}
+	return key
+}
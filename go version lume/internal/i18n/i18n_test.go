@@ -0,0 +1,61 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var verbs = regexp.MustCompile(`%[-+# 0-9.]*[a-zA-Z%]`)
+
+// TestKeySets makes sure every language translates exactly the English keys, with the same format
+// verbs in the same order so fmt.Sprintf arguments line up.
+func TestKeySets(t *testing.T) {
+	en := locales[Fallback].Strings
+	for _, l := range Languages() {
+		if l.Name == "" || l.Decimal == "" || l.Group == "" {
+			t.Errorf("%s: name or number separators missing", l.Code)
+		}
+		for k, ref := range en {
+			s, ok := l.Strings[k]
+			if !ok {
+				t.Errorf("%s: missing key %q", l.Code, k)
+				continue
+			}
+			if got, want := verbs.FindAllString(s, -1), verbs.FindAllString(ref, -1); strings.Join(got, " ") != strings.Join(want, " ") {
+				t.Errorf("%s: %q has verbs %v, English has %v", l.Code, k, got, want)
+			}
+		}
+		for k := range l.Strings {
+			if _, ok := en[k]; !ok {
+				t.Errorf("%s: key %q is not in %s.json", l.Code, k, Fallback)
+			}
+		}
+		if names := strings.Split(l.Strings["month_names"], ","); len(names) != 12 {
+			t.Errorf("%s: month_names has %d entries", l.Code, len(names))
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	codes := make([]string, 0)
+	for _, l := range Languages() {
+		codes = append(codes, l.Code)
+	}
+	if !sort.StringsAreSorted(codes) || len(codes) < 5 {
+		t.Errorf("Languages = %v", codes)
+	}
+	if !IsRTL("ar") || IsRTL("de") || IsRTL("xx") {
+		t.Error("IsRTL wrong")
+	}
+	if got := T("xx", "ok_btn"); got != "OK" {
+		t.Errorf("unknown language: %q", got)
+	}
+	if got := T("de", "no_such_key"); got != "no_such_key" {
+		t.Errorf("unknown key: %q", got)
+	}
+	if got := T("tr", "cancel_btn"); got != "İptal" {
+		t.Errorf("tr cancel_btn = %q", got)
+	}
+}
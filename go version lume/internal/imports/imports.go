@@ -0,0 +1,171 @@
+// Package imports remembers the source files earlier runs archived but left in place, such as the
+// duplicates on a memory card or the photos of a write-protected one, by their size and modification
+// time. Importing the same card again then skips them without reading them.
+package imports
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileName is the fingerprint store kept in the user's config directory.
+const FileName = "imports.json"
+
+const storeVersion = 1
+
+// MaxVolumes bounds the volumes remembered; the ones imported from longest ago are forgotten first.
+const MaxVolumes = 50
+
+// Stamp is the fingerprint of one imported file: its size and modification time when it was archived,
+// and where it was archived to.
+type Stamp struct {
+	Size  int64  `json:"size"`
+	MTime int64  `json:"mtime"` // Unix nanoseconds
+	Dest  string `json:"dest"`
+}
+
+// NewStamp fingerprints a file of size modified at mtime, archived as dest.
+func NewStamp(size int64, mtime time.Time, dest string) Stamp {
+	return Stamp{Size: size, MTime: mtime.UnixNano(), Dest: dest}
+}
+
+// Matches reports whether a file of size modified at mtime is unchanged since it was stamped, on a
+// volume keeping times to granularity g (see validator.TimeGranularity), as a card formatted FAT does.
+func (s Stamp) Matches(size int64, mtime time.Time, g time.Duration) bool {
+	return s.Size == size && validator.SameModTime(time.Unix(0, s.MTime), mtime, g)
+}
+
+// Archived reports whether the archived copy is still where it was put, with its size. It does not
+// read the copy.
+func (s Stamp) Archived() bool {
+	st, err := os.Stat(s.Dest)
+	return err == nil && st.Mode().IsRegular() && st.Size() == s.Size
+}
+
+// Volume is what one volume, by its serial number, gave to earlier runs.
+type Volume struct {
+	Imported time.Time `json:"imported"`
+	// Files are keyed by their path on the volume, without its drive letter, so a card keeps its
+	// fingerprints under another letter.
+	Files map[string]Stamp `json:"files"`
+}
+
+// Store holds the fingerprints of every volume. It is safe for concurrent use.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	Volumes map[string]*Volume `json:"volumes"`
+}
+
+type storeFile struct {
+	Version int `json:"version"`
+	*Store
+}
+
+// Key is the path of a file on its volume, as Files keys it.
+func Key(path string) string {
+	return filepath.ToSlash(path[len(filepath.VolumeName(path)):])
+}
+
+// Load reads the store at path. A missing file is an empty store; an unreadable one is returned empty
+// together with the error, and is replaced on the next Save.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path, Volumes: make(map[string]*Volume)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return s, err
+	}
+	loaded := &Store{}
+	f := storeFile{Store: loaded}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return s, err
+	}
+	if f.Version != storeVersion {
+		return s, fmt.Errorf("import fingerprints version %d", f.Version)
+	}
+	for serial, v := range loaded.Volumes {
+		if v != nil && v.Files != nil {
+			s.Volumes[serial] = v
+		}
+	}
+	return s, nil
+}
+
+// Lookup returns the stamp of the file at path on the volume with serial, if it was stamped.
+func (s *Store) Lookup(serial, path string) (Stamp, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.Volumes[serial]
+	if !ok {
+		return Stamp{}, false
+	}
+	st, ok := v.Files[Key(path)]
+	return st, ok
+}
+
+// Put stamps the file at path on the volume with serial, imported at now.
+func (s *Store) Put(serial, path string, st Stamp, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.Volumes[serial]
+	if !ok {
+		v = &Volume{Files: make(map[string]Stamp)}
+		s.Volumes[serial] = v
+	}
+	v.Files[Key(path)] = st
+	v.Imported = now
+	s.prune()
+}
+
+// Forget drops the stamp of a file that changed or whose archived copy is gone.
+func (s *Store) Forget(serial, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.Volumes[serial]; ok {
+		delete(v.Files, Key(path))
+		if len(v.Files) == 0 {
+			delete(s.Volumes, serial)
+		}
+	}
+}
+
+// prune forgets the volumes imported from longest ago beyond MaxVolumes. The caller holds s.mu.
+func (s *Store) prune() {
+	if len(s.Volumes) <= MaxVolumes {
+		return
+	}
+	serials := make([]string, 0, len(s.Volumes))
+	for serial := range s.Volumes {
+		serials = append(serials, serial)
+	}
+	sort.Slice(serials, func(i, j int) bool { return s.Volumes[serials[i]].Imported.Before(s.Volumes[serials[j]].Imported) })
+	for _, serial := range serials[:len(serials)-MaxVolumes] {
+		delete(s.Volumes, serial)
+	}
+}
+
+// Save writes the store back to its file.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	data, err := json.MarshalIndent(storeFile{Version: storeVersion, Store: s}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.path+".tmp", s.path)
+}
@@ -0,0 +1,92 @@
+package imports
+
+import (
+	"fmt"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "archive", "IMG_0001.JPG")
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	os.WriteFile(dest, []byte("photo"), 0644)
+	mtime := time.Date(2024, 7, 1, 12, 0, 0, 123, time.UTC)
+	path := filepath.Join("DCIM", "100CANON", "IMG_0001.JPG")
+
+	s, err := Load(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Put("1234ABCD", path, NewStamp(5, mtime, dest), mtime)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s, err = Load(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, ok := s.Lookup("1234ABCD", path)
+	if !ok {
+		t.Fatal("stamp lost in the round trip")
+	}
+	if !st.Matches(5, mtime, 0) || !st.Archived() {
+		t.Errorf("%+v does not match the file it stamped", st)
+	}
+	if st.Matches(6, mtime, 0) || st.Matches(5, mtime.Add(time.Second), 0) {
+		t.Error("a file changed in size or time still matches")
+	}
+	// FAT keeps times to two seconds, so a driver may give them back rounded.
+	if !st.Matches(5, mtime.Add(time.Second), validator.FATTimeGranularity) || st.Matches(5, mtime.Add(2*time.Second), validator.FATTimeGranularity) {
+		t.Error("FAT time granularity not tolerated, or too much")
+	}
+	if _, ok := s.Lookup("5678EF01", path); ok {
+		t.Error("the stamp is found on another volume")
+	}
+	os.WriteFile(dest, []byte("edited photo"), 0644)
+	if st.Archived() {
+		t.Error("a copy of another size counts as archived")
+	}
+	s.Forget("1234ABCD", path)
+	if _, ok := s.Lookup("1234ABCD", path); ok || len(s.Volumes) != 0 {
+		t.Error("Forget kept the stamp or its empty volume")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s, _ := Load(filepath.Join(t.TempDir(), FileName))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < MaxVolumes+2; i++ {
+		s.Put(fmt.Sprintf("%08X", i), "a.jpg", Stamp{}, start.Add(time.Duration(i)*time.Hour))
+	}
+	if len(s.Volumes) != MaxVolumes {
+		t.Fatalf("%d volumes kept; want %d", len(s.Volumes), MaxVolumes)
+	}
+	for _, serial := range []string{"00000000", "00000001"} {
+		if _, ok := s.Volumes[serial]; ok {
+			t.Errorf("the oldest volume %s was kept", serial)
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	os.WriteFile(path, []byte("{not json"), 0644)
+	s, err := Load(path)
+	if err == nil {
+		t.Error("a corrupt file loads without an error")
+	}
+	if s == nil || len(s.Volumes) != 0 {
+		t.Error("a corrupt file does not start over empty")
+	}
+	s.Put("1234ABCD", "a.jpg", Stamp{}, time.Now())
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err != nil {
+		t.Errorf("the replaced file does not load: %v", err)
+	}
+}
@@ -0,0 +1,336 @@
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the index file kept at the root of the target folder.
+const FileName = ".lume_index.json"
+
+const indexVersion = 1
+
+// fileSystem names the file system of the target; tests replace it.
+var fileSystem = validator.FileSystem
+
+// Entry describes one archived file. Path is relative to the target root.
+type Entry struct {
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash,omitempty"`
+	PHash   uint64 `json:"phash,omitempty"`
+}
+
+type indexFile struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// BuildOptions tunes how the index is built.
+type BuildOptions struct {
+	Workers int
+	// Perceptual also records a perceptual hash for decodable images up to PerceptualMaxSize bytes.
+	Perceptual        bool
+	PerceptualMaxSize int64
+	// Exclude lists files under the root that are not archived, e.g. those a run is about to file
+	// from within its target, so that none of them is a copy of itself or of another.
+	Exclude []string
+}
+
+// Index maps file sizes to archived files so content-identical files are found anywhere in the target tree.
+type Index struct {
+	root    string
+	opts    BuildOptions
+	mu      sync.Mutex
+	entries map[string]*Entry
+	bySize  map[int64][]string
+}
+
+// Build loads the persisted index under root, walks the tree and re-hashes only new or changed files
+// (by size and mtime) using opts.Workers goroutines. On FAT, whose times come back rounded, a time
+// within its granularity is unchanged. Cancelling ctx aborts the build.
+func Build(ctx context.Context, root string, opts BuildOptions) (*Index, error) {
+	workers := opts.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	prev := load(root)
+	grain := validator.TimeGranularity(fileSystem(root))
+	ix := &Index{root: root, opts: opts, entries: make(map[string]*Entry), bySize: make(map[int64][]string)}
+	excluded := make(map[string]bool, len(opts.Exclude))
+	for _, p := range opts.Exclude {
+		excluded[strings.ToLower(filepath.Clean(p))] = true
+	}
+
+	var todo []*Entry
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil || fi.IsDir() || fi.Mode()&os.ModeSymlink != 0 || metadata.OwnFile(fi.Name()) || excluded[strings.ToLower(path)] {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		e := &Entry{Path: rel, Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+		if old, ok := prev[rel]; ok && old.Size == e.Size && validator.SameModTime(time.Unix(0, old.ModTime), time.Unix(0, e.ModTime), grain) {
+			e.Hash, e.PHash = old.Hash, old.PHash
+		}
+		if e.Hash == "" || ix.wantsPHash(e) {
+			todo = append(todo, e)
+		}
+		ix.put(e)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(chan *Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				ix.fill(e)
+			}
+		}()
+	}
+feed:
+	for _, e := range todo {
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- e:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	logger.Info("Target index ready: %d files, %d re-hashed", len(ix.entries), len(todo))
+	return ix, nil
+}
+
+// Saved returns the index last saved under root without walking or hashing anything, for a quick
+// look before a run. Its entries may be stale; ok is false when root has none.
+func Saved(root string) (ix *Index, ok bool) {
+	prev := load(root)
+	if len(prev) == 0 {
+		return nil, false
+	}
+	ix = &Index{root: root, entries: make(map[string]*Entry), bySize: make(map[int64][]string)}
+	for _, e := range prev {
+		ix.put(&e)
+	}
+	return ix, true
+}
+
+func (ix *Index) wantsPHash(e *Entry) bool {
+	return ix.opts.Perceptual && e.PHash == 0 && metadata.PerceptualExtensions[strings.ToLower(filepath.Ext(e.Path))] &&
+		(ix.opts.PerceptualMaxSize <= 0 || e.Size <= ix.opts.PerceptualMaxSize)
+}
+
+// fill computes the missing hashes of an entry.
+func (ix *Index) fill(e *Entry) {
+	abs := filepath.Join(ix.root, e.Path)
+	ix.mu.Lock()
+	needHash, needPHash := e.Hash == "", ix.wantsPHash(e)
+	ix.mu.Unlock()
+
+	var h string
+	var ph uint64
+	var err error
+	if needHash {
+		if h, err = metadata.GetFileHash(abs); err != nil {
+			logger.Error("Index hash failed for %s: %v", e.Path, err)
+		}
+	}
+	if needPHash {
+		if ph, err = metadata.PerceptualHash(abs, ix.opts.PerceptualMaxSize); err != nil {
+			logger.Error("Index perceptual hash failed for %s: %v", e.Path, err)
+		}
+	}
+	ix.mu.Lock()
+	if h != "" {
+		e.Hash = h
+	}
+	if ph != 0 {
+		e.PHash = ph
+	}
+	ix.mu.Unlock()
+}
+
+func load(root string) map[string]Entry {
+	out := make(map[string]Entry)
+	data, err := os.ReadFile(filepath.Join(root, FileName))
+	if err != nil {
+		return out
+	}
+	var f indexFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != indexVersion {
+		logger.Error("Ignoring unreadable target index: %v", err)
+		return out
+	}
+	for _, e := range f.Entries {
+		out[e.Path] = e
+	}
+	return out
+}
+
+func (ix *Index) put(e *Entry) {
+	if old, ok := ix.entries[e.Path]; ok {
+		ix.dropSize(old.Size, old.Path)
+	}
+	ix.entries[e.Path] = e
+	ix.bySize[e.Size] = append(ix.bySize[e.Size], e.Path)
+}
+
+func (ix *Index) drop(rel string) {
+	if e, ok := ix.entries[rel]; ok {
+		ix.dropSize(e.Size, rel)
+		delete(ix.entries, rel)
+	}
+}
+
+func (ix *Index) dropSize(size int64, rel string) {
+	paths := ix.bySize[size]
+	for i, p := range paths {
+		if p == rel {
+			ix.bySize[size] = append(paths[:i], paths[i+1:]...)
+			break
+		}
+	}
+	if len(ix.bySize[size]) == 0 {
+		delete(ix.bySize, size)
+	}
+}
+
+// LookupName returns an archived file with the given base name and size without reading any
+// content, for sources such as cloud placeholders that must not be downloaded just to compare them.
+func (ix *Index) LookupName(name string, size int64) (string, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for _, rel := range ix.bySize[size] {
+		if strings.EqualFold(filepath.Base(rel), name) {
+			return filepath.Join(ix.root, rel), true
+		}
+	}
+	return "", false
+}
+
+// Lookup returns the archived file whose content matches src, if any. Every hit is verified by
+// hashing the indexed file, so entries for deleted or modified files never cause a false skip.
+func (ix *Index) Lookup(src string, size int64) (string, bool) {
+	return ix.LookupHash(func() (string, error) { return metadata.GetFileHash(src) }, size)
+}
+
+// LookupHash is Lookup with the source hash supplied by the caller, e.g. from a scan cache. srcHash
+// is only called when an archived file has the same size.
+func (ix *Index) LookupHash(srcHash func() (string, error), size int64) (string, bool) {
+	ix.mu.Lock()
+	cands := append([]string(nil), ix.bySize[size]...)
+	ix.mu.Unlock()
+	if len(cands) == 0 {
+		return "", false
+	}
+
+	hash, err := srcHash()
+	if err != nil {
+		return "", false
+	}
+	for _, rel := range cands {
+		ix.mu.Lock()
+		e, ok := ix.entries[rel]
+		known := ok && e.Hash != "" && e.Hash != hash
+		ix.mu.Unlock()
+		if !ok || known {
+			continue
+		}
+
+		abs := filepath.Join(ix.root, rel)
+		h, err := metadata.GetFileHash(abs)
+		ix.mu.Lock()
+		if err != nil {
+			ix.drop(rel)
+		} else {
+			e.Hash = h
+		}
+		ix.mu.Unlock()
+		if err == nil && h == hash {
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+// Add records a file that was just archived under the target root.
+func (ix *Index) Add(path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	rel, err := filepath.Rel(ix.root, path)
+	if err != nil {
+		return
+	}
+	e := &Entry{Path: rel, Size: fi.Size(), ModTime: fi.ModTime().UnixNano()}
+	ix.mu.Lock()
+	ix.put(e)
+	want := ix.wantsPHash(e)
+	ix.mu.Unlock()
+	if want {
+		if ph, err := metadata.PerceptualHash(path, ix.opts.PerceptualMaxSize); err == nil {
+			ix.mu.Lock()
+			e.PHash = ph
+			ix.mu.Unlock()
+		}
+	}
+}
+
+// Images returns the indexed files that carry a perceptual hash, dated by modification time.
+func (ix *Index) Images() []metadata.HashedImage {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var out []metadata.HashedImage
+	for _, e := range ix.entries {
+		if e.PHash != 0 {
+			out = append(out, metadata.HashedImage{Path: filepath.Join(ix.root, e.Path), Size: e.Size, Date: time.Unix(0, e.ModTime), PHash: e.PHash})
+		}
+	}
+	return out
+}
+
+// Save persists the index next to the archive, replacing the previous file atomically.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	f := indexFile{Version: indexVersion, Entries: make([]Entry, 0, len(ix.entries))}
+	for _, e := range ix.entries {
+		f.Entries = append(f.Entries, *e)
+	}
+	ix.mu.Unlock()
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(ix.root, FileName)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
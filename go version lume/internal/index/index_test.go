@@ -0,0 +1,173 @@
+package index
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func write(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupFindsRenamedCopy(t *testing.T) {
+	target, src := t.TempDir(), t.TempDir()
+	archived := write(t, filepath.Join(target, "2023", "05", "Camera", "IMG_0001.JPG"), "same bytes")
+	write(t, filepath.Join(target, "2023", "05", "Camera", "IMG_0002.JPG"), "other byte")
+
+	ix, err := Build(context.Background(), target, BuildOptions{Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := write(t, filepath.Join(src, "holiday.jpg"), "same bytes")
+	if got, ok := ix.Lookup(renamed, int64(len("same bytes"))); !ok || got != archived {
+		t.Fatalf("Lookup = %q, %v; want %q", got, ok, archived)
+	}
+	decoy := write(t, filepath.Join(src, "decoy.jpg"), "diff bytes")
+	if _, ok := ix.Lookup(decoy, int64(len("diff bytes"))); ok {
+		t.Fatal("same-size file with different content reported as duplicate")
+	}
+}
+
+func TestLookupName(t *testing.T) {
+	target := t.TempDir()
+	archived := write(t, filepath.Join(target, "2023", "IMG_0001.JPG"), "same bytes")
+	ix, err := Build(context.Background(), target, BuildOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The source is never opened: it may be an online-only file.
+	if got, ok := ix.LookupName("img_0001.jpg", int64(len("same bytes"))); !ok || got != archived {
+		t.Fatalf("LookupName = %q, %v; want %q", got, ok, archived)
+	}
+	if _, ok := ix.LookupName("IMG_0002.JPG", int64(len("same bytes"))); ok {
+		t.Fatal("a different name matched")
+	}
+}
+
+func TestSaved(t *testing.T) {
+	target := t.TempDir()
+	if _, ok := Saved(target); ok {
+		t.Fatal("Saved found an index in an empty folder")
+	}
+	archived := write(t, filepath.Join(target, "2023", "IMG_0001.JPG"), "same bytes")
+	ix, err := Build(context.Background(), target, BuildOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Save()
+	// Saved does not walk: a file added since is not in it.
+	write(t, filepath.Join(target, "2023", "IMG_0002.JPG"), "new")
+	saved, ok := Saved(target)
+	if !ok {
+		t.Fatal("Saved found no index")
+	}
+	if got, ok := saved.LookupName("IMG_0001.JPG", int64(len("same bytes"))); !ok || got != archived {
+		t.Errorf("LookupName = %q, %v; want %q", got, ok, archived)
+	}
+	if _, ok := saved.LookupName("IMG_0002.JPG", 3); ok {
+		t.Error("a file added after the save is in the saved index")
+	}
+}
+
+func TestStaleEntryIsNotADuplicate(t *testing.T) {
+	target, src := t.TempDir(), t.TempDir()
+	archived := write(t, filepath.Join(target, "2023", "a.jpg"), "content")
+	ix, err := Build(context.Background(), target, BuildOptions{Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(archived)
+
+	// The persisted entry still claims the hash; only verification can tell it is gone.
+	ix2 := &Index{root: target, entries: map[string]*Entry{}, bySize: map[int64][]string{}}
+	for _, e := range load(target) {
+		e := e
+		ix2.put(&e)
+	}
+	f := write(t, filepath.Join(src, "a.jpg"), "content")
+	if _, ok := ix2.Lookup(f, int64(len("content"))); ok {
+		t.Fatal("deleted archive file caused a duplicate skip")
+	}
+	if len(ix2.entries) != 0 {
+		t.Fatalf("stale entry kept: %v", ix2.entries)
+	}
+}
+
+func TestBuildReusesPersistedHashes(t *testing.T) {
+	target := t.TempDir()
+	write(t, filepath.Join(target, "2022", "x.jpg"), "xx")
+	ix, err := Build(context.Background(), target, BuildOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved := load(target)
+	e, ok := saved[filepath.Join("2022", "x.jpg")]
+	if !ok || e.Hash == "" {
+		t.Fatalf("persisted entry = %+v", e)
+	}
+	if _, ok := saved[FileName]; ok {
+		t.Fatal("index file indexed itself")
+	}
+
+	ix2, err := Build(context.Background(), target, BuildOptions{Workers: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ix2.entries[e.Path].Hash; got != e.Hash {
+		t.Fatalf("hash = %q, want %q", got, e.Hash)
+	}
+}
+
+func TestBuildToleratesFATTimes(t *testing.T) {
+	target := t.TempDir()
+	rel := filepath.Join("2022", "x.jpg")
+	write(t, filepath.Join(target, rel), "xx")
+	// Save an entry whose time is a second off, as FAT hands back a time set to the nanosecond, and
+	// whose hash tells whether it was reused.
+	ix, err := Build(context.Background(), target, BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.entries[rel].ModTime -= int64(time.Second)
+	ix.entries[rel].Hash = "persisted"
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+	defer func(orig func(string) string) { fileSystem = orig }(fileSystem)
+	for fs, reused := range map[string]bool{"FAT32": true, "NTFS": false} {
+		fileSystem = func(string) string { return fs }
+		ix, err := Build(context.Background(), target, BuildOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ix.entries[rel].Hash == "persisted"; got != reused {
+			t.Errorf("%s: hash reused = %v; want %v", fs, got, reused)
+		}
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	target := t.TempDir()
+	write(t, filepath.Join(target, "a.jpg"), "a")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Build(ctx, target, BuildOptions{Workers: 2}); err == nil {
+		t.Fatal("cancelled build returned no error")
+	}
+}
@@ -0,0 +1,119 @@
+// Package instance keeps Lume to a single window: a second launch forwards its file arguments
+// to the running instance over a local pipe and exits.
+package instance
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"lume-go/internal/logger"
+	"sync"
+)
+
+// name identifies the pipe (Windows) or socket (elsewhere) of the running instance.
+var name = "lume-single-instance"
+
+// ErrRunning is returned by Listen when another instance already owns the pipe.
+var ErrRunning = errors.New("another instance is running")
+
+// request is one forwarded launch. An empty Paths only asks the window to come to the front.
+type request struct {
+	Paths []string `json:"paths"`
+}
+
+const ack = "ok"
+
+// listener accepts connections on the platform transport.
+type listener interface {
+	Accept() (io.ReadWriteCloser, error)
+	Close() error
+}
+
+// Server is the primary instance's end of the pipe.
+type Server struct {
+	ln     listener
+	mu     sync.Mutex
+	closed bool
+}
+
+// Listen makes this process the primary instance; handle runs on the server goroutine for every
+// forwarded request and must hand the paths to the UI thread itself.
+func Listen(handle func(paths []string)) (*Server, error) {
+	ln, err := listen(name)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{ln: ln}
+	go s.serve(handle)
+	return s, nil
+}
+
+func (s *Server) serve(handle func([]string)) {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if !closed {
+				logger.Error("Instance pipe stopped: %v", err)
+			}
+			return
+		}
+		go func() {
+			defer conn.Close()
+			req, err := readRequest(conn)
+			if err != nil {
+				logger.Error("Instance pipe: bad request: %v", err)
+				return
+			}
+			io.WriteString(conn, ack+"\n")
+			handle(req.Paths)
+		}()
+	}
+}
+
+// Close stops accepting forwarded launches.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	return s.ln.Close()
+}
+
+// Forward hands paths to a running instance. It reports false, without error, when none is listening.
+func Forward(paths []string) (bool, error) {
+	conn, err := dial(name)
+	if err != nil {
+		return false, nil
+	}
+	defer conn.Close()
+	if err := writeRequest(conn, request{Paths: paths}); err != nil {
+		return false, err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || line != ack+"\n" {
+		return false, fmt.Errorf("instance did not acknowledge: %q, %v", line, err)
+	}
+	return true, nil
+}
+
+func writeRequest(w io.Writer, r request) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func readRequest(r io.Reader) (request, error) {
+	var req request
+	line, err := bufio.NewReader(r).ReadBytes('\n')
+	if err != nil {
+		return req, err
+	}
+	return req, json.Unmarshal(line, &req)
+}
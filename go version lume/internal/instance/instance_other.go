@@ -0,0 +1,43 @@
+//go:build !windows
+
+package instance
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// Outside Windows a unix socket in the temp folder plays the role of the named pipe.
+func socketPath(name string) string {
+	return filepath.Join(os.TempDir(), name+".sock")
+}
+
+type unixListener struct{ net.Listener }
+
+func (l unixListener) Accept() (io.ReadWriteCloser, error) { return l.Listener.Accept() }
+
+func listen(name string) (listener, error) {
+	path := socketPath(name)
+	ln, err := net.Listen("unix", path)
+	if err != nil && errors.Is(err, syscall.EADDRINUSE) {
+		// A socket nobody answers on is left over from a crash.
+		if conn, derr := net.Dial("unix", path); derr == nil {
+			conn.Close()
+			return nil, ErrRunning
+		}
+		os.Remove(path)
+		ln, err = net.Listen("unix", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return unixListener{ln}, nil
+}
+
+func dial(name string) (io.ReadWriteCloser, error) {
+	return net.Dial("unix", socketPath(name))
+}
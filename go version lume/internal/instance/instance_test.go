@@ -0,0 +1,62 @@
+package instance
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestForwardToRunningInstance(t *testing.T) {
+	name = fmt.Sprintf("lume-test-%d", os.Getpid())
+
+	if ok, err := Forward([]string{"a.jpg"}); ok || err != nil {
+		t.Fatalf("Forward with no instance = %v, %v", ok, err)
+	}
+
+	got := make(chan []string, 4)
+	srv, err := Listen(func(paths []string) { got <- paths })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(func([]string) {}); err != ErrRunning {
+		t.Fatalf("second Listen = %v, want ErrRunning", err)
+	}
+
+	want := []string{`C:\Fotos\IMG_0001.JPG`, `C:\Fotos\ünïcode ş.jpg`}
+	if ok, err := Forward(want); !ok || err != nil {
+		t.Fatalf("Forward = %v, %v", ok, err)
+	}
+	select {
+	case paths := <-got:
+		if !reflect.DeepEqual(paths, want) {
+			t.Errorf("received %q, want %q", paths, want)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("request not delivered")
+	}
+
+	// Concurrent launches (selecting many files in Explorer) all arrive.
+	done := make(chan error, 2)
+	for _, p := range []string{"x.jpg", "y.jpg"} {
+		go func(p string) { _, err := Forward([]string{p}); done <- err }(p)
+	}
+	var all []string
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, (<-got)...)
+	}
+	sort.Strings(all)
+	if !reflect.DeepEqual(all, []string{"x.jpg", "y.jpg"}) {
+		t.Errorf("concurrent requests = %q", all)
+	}
+
+	srv.Close()
+	if ok, _ := Forward(nil); ok {
+		t.Error("Forward succeeded after Close")
+	}
+}
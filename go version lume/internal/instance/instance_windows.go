@@ -0,0 +1,118 @@
+package instance
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	pipeAccessDuplex       = 0x00000003
+	fileFlagFirstInstance  = 0x00080000
+	pipeTypeByte           = 0x00000000
+	pipeUnlimitedInstances = 255
+	errorPipeConnected     = syscall.Errno(535)
+	errorPipeBusy          = syscall.Errno(231)
+	pipeBufferSize         = 4096
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipe  = kernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = kernel32.NewProc("ConnectNamedPipe")
+)
+
+func pipePath(name string) string { return `\\.\pipe\` + name }
+
+// pipeListener hands out one pipe instance per client, the way named pipe servers do.
+type pipeListener struct {
+	path    string
+	mu      sync.Mutex
+	pending syscall.Handle // the first instance, created by listen to claim the name
+	closed  bool
+}
+
+func createPipe(path string, first bool) (syscall.Handle, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	mode := uintptr(pipeAccessDuplex)
+	if first {
+		mode |= fileFlagFirstInstance
+	}
+	h, _, callErr := procCreateNamedPipe.Call(uintptr(unsafe.Pointer(p)), mode, pipeTypeByte, pipeUnlimitedInstances, pipeBufferSize, pipeBufferSize, 0, 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, callErr
+	}
+	return syscall.Handle(h), nil
+}
+
+func listen(name string) (listener, error) {
+	path := pipePath(name)
+	// The first instance flag fails if any process already serves this pipe name.
+	h, err := createPipe(path, true)
+	if err != nil {
+		if errors.Is(err, syscall.ERROR_ACCESS_DENIED) {
+			return nil, ErrRunning
+		}
+		return nil, err
+	}
+	return &pipeListener{path: path, pending: h}, nil
+}
+
+func (l *pipeListener) Accept() (io.ReadWriteCloser, error) {
+	l.mu.Lock()
+	h := l.pending
+	l.pending = syscall.InvalidHandle
+	l.mu.Unlock()
+	if h == syscall.InvalidHandle {
+		var err error
+		if h, err = createPipe(l.path, false); err != nil {
+			return nil, err
+		}
+	}
+	ok, _, callErr := procConnectNamedPipe.Call(uintptr(h), 0)
+	if ok == 0 && callErr != errorPipeConnected {
+		syscall.CloseHandle(h)
+		return nil, callErr
+	}
+	l.mu.Lock()
+	closed := l.closed
+	l.mu.Unlock()
+	if closed {
+		syscall.CloseHandle(h)
+		return nil, errors.New("pipe closed")
+	}
+	return os.NewFile(uintptr(h), l.path), nil
+}
+
+// Close marks the listener closed and connects once to release the blocked Accept.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	if f, err := os.OpenFile(l.path, os.O_RDWR, 0); err == nil {
+		f.Close()
+	}
+	return nil
+}
+
+func dial(name string) (io.ReadWriteCloser, error) {
+	var err error
+	for i := 0; i < 10; i++ {
+		var f *os.File
+		if f, err = os.OpenFile(pipePath(name), os.O_RDWR, 0); err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, errorPipeBusy) {
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return nil, err
+}
@@ -0,0 +1,165 @@
+// Package ioutil holds the copy and hash loops of Lume. Unlike io.Copy they stop when their context
+// is done: between chunks, and during a read that blocks, as one from a network share that stopped
+// answering does.
+package ioutil
+
+import (
+	"context"
+	"encoding/hex"
+	"hash"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultBufferSize is the chunk size used when no buffer is given.
+const DefaultBufferSize = 1 << 20
+
+// buffers keeps the buffers of DefaultBufferSize copies and hashes are done with, so a run of many
+// small files does not allocate two of them per file.
+var buffers = sync.Pool{New: func() any { b := make([]byte, DefaultBufferSize); return &b }}
+
+// readGrace is how long a read may still finish once the context is done before it is given up.
+const readGrace = 100 * time.Millisecond
+
+// Reader reads from an io.Reader until its context is done, even when a read blocks. The read given
+// up on goes on in its goroutine; its data is handed out by the next Read, so a Reader can be read
+// again under another context (see SetContext) without losing any.
+type Reader struct {
+	ctx     context.Context
+	r       io.Reader
+	pending chan readResult
+	rest    []byte
+	// buf takes the reads; a new one is only made for a larger read.
+	buf []byte
+}
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// NewReader returns a Reader of r bound to ctx.
+func NewReader(ctx context.Context, r io.Reader) *Reader {
+	return &Reader{ctx: ctx, r: r}
+}
+
+// SetContext binds the reader to ctx for the reads that follow.
+func (c *Reader) SetContext(ctx context.Context) { c.ctx = ctx }
+
+func (c *Reader) Read(p []byte) (int, error) {
+	if len(c.rest) > 0 {
+		n := copy(p, c.rest)
+		c.rest = c.rest[n:]
+		return n, nil
+	}
+	if c.pending == nil {
+		if err := c.ctx.Err(); err != nil {
+			return 0, err
+		}
+		if len(c.buf) < len(p) {
+			c.buf = make([]byte, len(p))
+		}
+		ch, buf := make(chan readResult, 1), c.buf[:len(p)]
+		go func() {
+			n, err := c.r.Read(buf)
+			ch <- readResult{buf[:n], err}
+		}()
+		c.pending = ch
+	}
+	select {
+	case res := <-c.pending:
+		return c.take(p, res)
+	case <-c.ctx.Done():
+	}
+	// A read finishing as ctx ends, the usual case, is still taken rather than left pending.
+	select {
+	case res := <-c.pending:
+		return c.take(p, res)
+	case <-time.After(readGrace):
+		return 0, c.ctx.Err()
+	}
+}
+
+func (c *Reader) take(p []byte, res readResult) (int, error) {
+	c.pending = nil
+	n := copy(p, res.data)
+	c.rest = res.data[n:]
+	return n, res.err
+}
+
+// CopyContext copies src to dst a buffer at a time until EOF or until ctx is done, when it returns
+// ctx's error. progress, when set, is called with the bytes copied so far after every chunk. An empty
+// buf means one of DefaultBufferSize. src is read through a Reader bound to ctx; pass a *Reader to
+// keep a read given up on for a later call, which rebinds it to its own ctx.
+//
+// The count is the bytes written to dst, also on failure, so the caller knows whether a partial
+// destination is left to clean up.
+func CopyContext(ctx context.Context, dst io.Writer, src io.Reader, buf []byte, progress func(done int64)) (int64, error) {
+	if len(buf) == 0 {
+		b := buffers.Get().(*[]byte)
+		defer buffers.Put(b)
+		buf = *b
+	}
+	r, ok := src.(*Reader)
+	if ok {
+		r.SetContext(ctx)
+	} else {
+		r = NewReader(ctx, src)
+		b := buffers.Get().(*[]byte)
+		r.buf = *b
+		defer func() {
+			// A read given up on still writes into the buffer; it is left to it.
+			if r.pending == nil {
+				buffers.Put(b)
+			}
+		}()
+	}
+	var written int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			w, werr := dst.Write(buf[:n])
+			written += int64(w)
+			if werr == nil && w < n {
+				werr = io.ErrShortWrite
+			}
+			if werr != nil {
+				return written, werr
+			}
+			if progress != nil {
+				progress(written)
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// HashContext returns the hex digest of the file at path under algo, e.g. md5.New or sha256.New,
+// stopping with ctx's error when ctx is done.
+func HashContext(ctx context.Context, path string, algo func() hash.Hash) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return HashReader(ctx, f, algo)
+}
+
+// HashReader is HashContext for content already opened, e.g. through another file system.
+func HashReader(ctx context.Context, r io.Reader, algo func() hash.Hash) (string, error) {
+	h := algo()
+	if _, err := CopyContext(ctx, h, r, nil, nil); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,146 @@
+package ioutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// stalledReader hands out its data and then blocks until released, like a file on a share that
+// stopped answering.
+type stalledReader struct {
+	data    []byte
+	release chan struct{}
+}
+
+func (r *stalledReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		<-r.release
+		return 0, io.EOF
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestCopyContext(t *testing.T) {
+	data := bytes.Repeat([]byte("lume"), 10000)
+	var dst bytes.Buffer
+	var steps []int64
+	n, err := CopyContext(context.Background(), &dst, bytes.NewReader(data), make([]byte, 4096), func(done int64) { steps = append(steps, done) })
+	if err != nil || n != int64(len(data)) || !bytes.Equal(dst.Bytes(), data) {
+		t.Fatalf("CopyContext = %d, %v", n, err)
+	}
+	// The injected buffer sets the chunk: 40000 bytes in 4096-byte steps.
+	if len(steps) != 10 || steps[0] != 4096 || steps[9] != int64(len(data)) {
+		t.Errorf("progress steps %v", steps)
+	}
+}
+
+func TestCopyContextPreCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var dst bytes.Buffer
+	n, err := CopyContext(ctx, &dst, bytes.NewReader([]byte("data")), nil, nil)
+	if !errors.Is(err, context.Canceled) || n != 0 || dst.Len() != 0 {
+		t.Errorf("CopyContext = %d, %v; wrote %d bytes", n, err, dst.Len())
+	}
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	os.WriteFile(path, []byte("data"), 0644)
+	if _, err := HashContext(ctx, path, md5.New); !errors.Is(err, context.Canceled) {
+		t.Errorf("HashContext = %v", err)
+	}
+}
+
+func TestCopyContextCancelledMidStream(t *testing.T) {
+	data := make([]byte, 64<<10)
+	ctx, cancel := context.WithCancel(context.Background())
+	dst := filepath.Join(t.TempDir(), "video.mp4")
+	out, err := os.Create(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := CopyContext(ctx, out, bytes.NewReader(data), make([]byte, 1024), func(done int64) {
+		if done == 8<<10 {
+			cancel()
+		}
+	})
+	out.Close()
+	if !errors.Is(err, context.Canceled) || n != 8<<10 {
+		t.Fatalf("CopyContext = %d, %v", n, err)
+	}
+	// The count tells the caller a partial destination is there to remove.
+	if st, err := os.Stat(dst); err != nil || st.Size() != n {
+		t.Errorf("partial destination %v, %v; reported %d bytes", st, err, n)
+	}
+}
+
+func TestCopyContextGivesUpBlockedRead(t *testing.T) {
+	r := &stalledReader{data: make([]byte, 3000), release: make(chan struct{})}
+	defer close(r.release)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var dst bytes.Buffer
+	n, err := CopyContext(ctx, &dst, r, make([]byte, 1000), nil)
+	if !errors.Is(err, context.DeadlineExceeded) || n != 3000 {
+		t.Errorf("CopyContext = %d, %v", n, err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("returned after %s", d)
+	}
+}
+
+// A Reader carries a read given up on over to the next context, so nothing is lost.
+func TestReaderKeepsPendingRead(t *testing.T) {
+	release := make(chan struct{})
+	pr, pw := io.Pipe()
+	go func() {
+		<-release
+		pw.Write([]byte("late"))
+		pw.Close()
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := NewReader(context.Background(), pr)
+	r.SetContext(ctx)
+	// Unbound reads start only under a live context.
+	if _, err := r.Read(make([]byte, 8)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Read = %v", err)
+	}
+	short, stop := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer stop()
+	r.SetContext(short)
+	if _, err := r.Read(make([]byte, 8)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("blocked Read = %v", err)
+	}
+	close(release)
+	var dst bytes.Buffer
+	if _, err := CopyContext(context.Background(), &dst, r, nil, nil); err != nil || dst.String() != "late" {
+		t.Errorf("copied %q, %v", dst.String(), err)
+	}
+}
+
+func TestHashContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	data := []byte("not really a jpeg")
+	os.WriteFile(path, data, 0644)
+	md5Sum, shaSum := md5.Sum(data), sha256.Sum256(data)
+	for _, tt := range []struct {
+		algo func() hash.Hash
+		want []byte
+	}{{md5.New, md5Sum[:]}, {sha256.New, shaSum[:]}} {
+		if got, err := HashContext(context.Background(), path, tt.algo); err != nil || got != hex.EncodeToString(tt.want) {
+			t.Errorf("HashContext = %s, %v; want %x", got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Package lock keeps two Lume processes (the app, lume-lite, a scheduled run) from organizing into
+// the same target at once, which would interleave their conflict resolution. The lock is advisory:
+// a file in the target naming its holder, refreshed while the run goes on.
+package lock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lume-go/internal/logger"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileName is the lock file kept at the root of a locked target. lume-lite uses the same name and format.
+const FileName = ".lume.lock"
+
+// A lock not refreshed for StaleAfter is left over from a run that died; holders refresh theirs
+// every refreshEvery.
+var (
+	StaleAfter   = 10 * time.Minute
+	refreshEvery = time.Minute
+)
+
+// Owner is what the lock file records about its holder.
+type Owner struct {
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	App     string    `json:"app"`
+	Started time.Time `json:"started"`
+}
+
+// ErrLocked is matched by every LockedError.
+var ErrLocked = errors.New("target is locked by another run")
+
+// LockedError reports a target held by another live run.
+type LockedError struct {
+	Path  string
+	Owner Owner
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s: held by %s (pid %d on %s) since %s", e.Path, e.Owner.App, e.Owner.PID, e.Owner.Host, e.Owner.Started.Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool { return target == ErrLocked }
+
+// Lock is a held target lock.
+type Lock struct {
+	path string
+	data []byte
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// Acquire locks dir for app. A lock whose holder is gone (its process no longer runs on this host,
+// or it stopped refreshing the lock StaleAfter ago) is taken over; force takes over any lock and
+// must only come from an explicit user choice. A live holder yields a *LockedError.
+func Acquire(dir, app string, force bool) (*Lock, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := json.Marshal(Owner{PID: os.Getpid(), Host: hostname(), App: app, Started: time.Now()})
+	if err != nil {
+		return nil, err
+	}
+	var held Owner
+	// Two attempts: the second follows the removal of a stale lock.
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, err
+			}
+			l := &Lock{path: path, data: data, stop: make(chan struct{})}
+			l.wg.Add(1)
+			go l.refresh()
+			return l, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		raw, owner, age, err := read(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		held = owner
+		if !force && !stale(owner, age) {
+			break
+		}
+		// Only remove the lock that was judged stale, not one another process just took over.
+		if cur, _, _, _ := read(path); !bytes.Equal(cur, raw) {
+			continue
+		}
+		logger.Info("Taking over lock %s from %s (pid %d on %s), force=%v", path, owner.App, owner.PID, owner.Host, force)
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &LockedError{Path: dir, Owner: held}
+}
+
+// AcquireAll locks each of dirs for app as Acquire does and returns the release of them all. On
+// failure nothing stays locked.
+func AcquireAll(dirs []string, app string, force bool) (func(), error) {
+	var held []*Lock
+	release := func() {
+		for _, l := range held {
+			l.Release()
+		}
+	}
+	for _, d := range dirs {
+		l, err := Acquire(d, app, force)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		held = append(held, l)
+	}
+	return release, nil
+}
+
+// read returns the lock file's content, its holder and how long ago it was last refreshed. An
+// unreadable holder is returned zero and only ages out.
+func read(path string) ([]byte, Owner, time.Duration, error) {
+	var owner Owner
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil, owner, 0, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, owner, 0, err
+	}
+	json.Unmarshal(raw, &owner)
+	return raw, owner, time.Since(st.ModTime()), nil
+}
+
+func stale(owner Owner, age time.Duration) bool {
+	if age > StaleAfter {
+		return true
+	}
+	return owner.PID > 0 && owner.Host == hostname() && !processAlive(owner.PID)
+}
+
+// Alive reports whether process pid still runs on this machine.
+func Alive(pid int) bool { return pid > 0 && processAlive(pid) }
+
+// AliveSince is Alive for a process known to have run at t: a process started after t only reuses
+// the pid of the one that ended. Where the start of a process cannot be read, it is Alive.
+func AliveSince(pid int, t time.Time) bool {
+	if !Alive(pid) {
+		return false
+	}
+	start, ok := processStart(pid)
+	return !ok || !start.After(t)
+}
+
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return h
+}
+
+// refresh keeps the lock's modification time current so other processes do not take it for stale.
+func (l *Lock) refresh() {
+	defer l.wg.Done()
+	t := time.NewTicker(refreshEvery)
+	defer t.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+			now := time.Now()
+			if err := os.Chtimes(l.path, now, now); err != nil {
+				logger.Error("Lock refresh failed for %s: %v", l.path, err)
+			}
+		}
+	}
+}
+
+// Release removes the lock unless another process took it over meanwhile. It is safe to call more
+// than once and on a nil Lock.
+func (l *Lock) Release() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() {
+		close(l.stop)
+		l.wg.Wait()
+		if cur, err := os.ReadFile(l.path); err == nil && bytes.Equal(cur, l.data) {
+			if err := os.Remove(l.path); err != nil {
+				logger.Error("Lock release failed for %s: %v", l.path, err)
+			}
+		}
+	})
+}
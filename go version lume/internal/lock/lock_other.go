@@ -0,0 +1,17 @@
+//go:build !windows
+
+package lock
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// processStart is not read outside Windows: Alive alone decides.
+func processStart(int) (time.Time, bool) { return time.Time{}, false }
@@ -0,0 +1,124 @@
+package lock
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeLock(t *testing.T, dir string, owner Owner, age time.Duration) {
+	t.Helper()
+	data, _ := json.Marshal(owner)
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-age)
+	os.Chtimes(path, old, old)
+}
+
+// deadPID returns the pid of a process that has already exited.
+func deadPID(t *testing.T) int {
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestContention(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Acquire(dir, "lume", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Acquire(dir, "lume-lite", false)
+	var locked *LockedError
+	if !errors.As(err, &locked) || !errors.Is(err, ErrLocked) || locked.Owner.App != "lume" || locked.Owner.PID != os.Getpid() {
+		t.Fatalf("second Acquire = %v, want locked by this process", err)
+	}
+	l.Release()
+	l.Release()
+	if _, err := os.Stat(filepath.Join(dir, FileName)); !os.IsNotExist(err) {
+		t.Fatalf("lock left after Release: %v", err)
+	}
+	l2, err := Acquire(dir, "lume-lite", false)
+	if err != nil {
+		t.Fatalf("Acquire after Release: %v", err)
+	}
+	l2.Release()
+}
+
+func TestStaleTakeover(t *testing.T) {
+	host := hostname()
+	tests := []struct {
+		name  string
+		owner Owner
+		age   time.Duration
+		force bool
+		taken bool
+	}{
+		{"dead process on this host", Owner{PID: deadPID(t), Host: host, App: "lume"}, 0, false, true},
+		{"live process elsewhere", Owner{PID: 1, Host: "other-pc", App: "lume"}, time.Minute, false, false},
+		{"not refreshed", Owner{PID: 1, Host: "other-pc", App: "lume"}, StaleAfter + time.Minute, false, true},
+		{"unreadable and old", Owner{}, StaleAfter + time.Minute, false, true},
+		{"forced", Owner{PID: os.Getpid(), Host: host, App: "lume"}, 0, true, true},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		writeLock(t, dir, tt.owner, tt.age)
+		l, err := Acquire(dir, "test", tt.force)
+		if tt.taken != (err == nil) {
+			t.Errorf("%s: Acquire err = %v, want taken=%v", tt.name, err, tt.taken)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		data, _ := os.ReadFile(filepath.Join(dir, FileName))
+		var got Owner
+		json.Unmarshal(data, &got)
+		if got.PID != os.Getpid() || got.App != "test" {
+			t.Errorf("%s: lock holder = %+v", tt.name, got)
+		}
+		l.Release()
+	}
+}
+
+// A lock taken over from this holder must not be removed when it releases.
+func TestReleaseKeepsForeignLock(t *testing.T) {
+	dir := t.TempDir()
+	l, err := Acquire(dir, "lume", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeLock(t, dir, Owner{PID: 1, Host: "other-pc", App: "lume-lite"}, 0)
+	l.Release()
+	if _, err := os.Stat(filepath.Join(dir, FileName)); err != nil {
+		t.Fatalf("foreign lock removed: %v", err)
+	}
+}
+
+func TestAcquireAllReleasesOnFailure(t *testing.T) {
+	free, held := t.TempDir(), t.TempDir()
+	l, err := Acquire(held, "lume", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Release()
+	if _, err := AcquireAll([]string{free, held}, "lume-lite", false); !errors.Is(err, ErrLocked) {
+		t.Fatalf("AcquireAll = %v, want ErrLocked", err)
+	}
+	if _, err := os.Stat(filepath.Join(free, FileName)); !os.IsNotExist(err) {
+		t.Fatalf("lock left on %s after failed AcquireAll: %v", free, err)
+	}
+	release, err := AcquireAll([]string{free}, "lume-lite", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
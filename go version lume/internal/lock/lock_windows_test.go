@@ -0,0 +1,18 @@
+package lock
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestAliveSince(t *testing.T) {
+	pid := os.Getpid()
+	if !AliveSince(pid, time.Now()) {
+		t.Error("this process is not alive since now")
+	}
+	// A run recorded before this process started had another process of the same pid.
+	if AliveSince(pid, time.Now().Add(-24*time.Hour)) {
+		t.Error("this process taken for one running a day ago")
+	}
+}
@@ -9,6 +9,7 @@ import (
 var (
 	logFile *os.File
 	logger  *log.Logger
+	debug   bool
 )
 
 // Init sets up the logger relative to the executable path.
@@ -35,6 +36,18 @@ func Info(format string, v ...interface{}) {
 	}
 }
 
+// SetDebug turns Debug messages on or off; it is set once at startup from the config.
+func SetDebug(on bool) {
+	debug = on
+}
+
+// Debug logs details only worth the space when chasing a problem, such as why a file had no EXIF date.
+func Debug(format string, v ...interface{}) {
+	if logger != nil && debug {
+		logger.Printf("[DEBUG] "+format, v...)
+	}
+}
+
 func Error(format string, v ...interface{}) {
 	if logger != nil {
 		logger.Printf("[ERROR] "+format, v...)
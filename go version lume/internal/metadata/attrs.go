@@ -0,0 +1,52 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Windows file attributes Lume looks at.
+const (
+	fileAttributeHidden             = 0x2
+	fileAttributeSystem             = 0x4
+	fileAttributeOffline            = 0x1000
+	fileAttributeRecallOnOpen       = 0x40000
+	fileAttributeRecallOnDataAccess = 0x400000
+)
+
+// IsHidden reports whether the entry at path, with fi from os.Stat or os.Lstat, is hidden: a
+// dot-prefixed name on any platform, or the Windows hidden or system attribute (Thumbs.db,
+// desktop.ini, camera databases).
+func IsHidden(path string, fi os.FileInfo) bool {
+	if name := filepath.Base(path); strings.HasPrefix(name, ".") && name != "." && name != ".." {
+		return true
+	}
+	attr, ok := winAttributes(fi)
+	return ok && attr&(fileAttributeHidden|fileAttributeSystem) != 0
+}
+
+// OwnFile reports whether name is a file or folder Lume keeps in an archive for itself, never one it
+// archived: its .lume files and folders, the checksum lists (sums.FileName) and the month indexes
+// (monthindex.FileName). Walks of an archive leave them out.
+func OwnFile(name string) bool {
+	return strings.HasPrefix(name, ".lume") || name == "SHA256SUMS" || name == "index.json"
+}
+
+// Policies for online-only cloud files (see IsPlaceholder).
+const (
+	// CloudSkip leaves placeholders alone; nothing is downloaded.
+	CloudSkip = "skip"
+	// CloudHydrate archives placeholders that pass the filters, downloading each as it is moved.
+	CloudHydrate = "hydrate"
+)
+
+// CloudPolicies lists the cloud policies in display order.
+var CloudPolicies = []string{CloudSkip, CloudHydrate}
+
+// IsPlaceholder reports a cloud file (OneDrive, Dropbox) whose content is not on this disk; reading
+// it makes the sync client download the whole file.
+func IsPlaceholder(fi os.FileInfo) bool {
+	attr, ok := winAttributes(fi)
+	return ok && attr&(fileAttributeRecallOnDataAccess|fileAttributeRecallOnOpen|fileAttributeOffline) != 0
+}
@@ -0,0 +1,179 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoContainerExif reports a file whose container holds no Exif data Lume can find by its chunks.
+var ErrNoContainerExif = errors.New("no Exif in container")
+
+// maxChunkSize bounds a metadata chunk read into memory.
+const maxChunkSize = 4 << 20
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// containerMeta reads the Exif blob a JPEG, PNG, WebP, HEIF, AVIF or JPEG XL file keeps in its own
+// structure, sniffing the type from the header rather than trusting the extension (see sniffFormat).
+// PNG files may instead carry a textual "Creation Time", returned as created.
+func containerMeta(path string) (raw []byte, created *time.Time, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	var head [12]byte
+	if _, err := io.ReadFull(f, head[:]); err != nil {
+		return nil, nil, ErrNoContainerExif
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	switch sniffFormat(head[:]) {
+	case formatJPEG:
+		raw, err := jpegExif(f)
+		return raw, nil, err
+	case formatPNG:
+		return pngMeta(f)
+	case formatWebP:
+		raw, err := webpExif(f)
+		return raw, nil, err
+	case formatHEIF, formatAVIF: // AVIF keeps its Exif item as HEIF does
+		raw, err := heifExif(f)
+		return raw, nil, err
+	case formatJXL:
+		raw, err := jxlExif(f)
+		return raw, nil, err
+	}
+	return nil, nil, ErrNoContainerExif
+}
+
+// pngMeta walks the PNG chunks up to IEND for an eXIf chunk and a tEXt/iTXt "Creation Time".
+// Image data is skipped, not read.
+func pngMeta(r io.ReadSeeker) ([]byte, *time.Time, error) {
+	if _, err := r.Seek(int64(len(pngSignature)), io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	var raw []byte
+	var created *time.Time
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			break
+		}
+		size, typ := binary.BigEndian.Uint32(hdr[:4]), string(hdr[4:8])
+		if size > 1<<31-1 {
+			break
+		}
+		wanted := typ == "eXIf" || ((typ == "tEXt" || typ == "iTXt") && created == nil)
+		if !wanted || size > maxChunkSize {
+			if typ == "IEND" {
+				break
+			}
+			if _, err := r.Seek(int64(size)+4, io.SeekCurrent); err != nil {
+				break
+			}
+			continue
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			break
+		}
+		r.Seek(4, io.SeekCurrent) // CRC
+		switch typ {
+		case "eXIf":
+			raw = data
+		case "tEXt":
+			if k, v, ok := strings.Cut(string(data), "\x00"); ok && k == "Creation Time" {
+				created = parseCreationTime(v)
+			}
+		case "iTXt":
+			if k, v, ok := iTXt(data); ok && k == "Creation Time" {
+				created = parseCreationTime(v)
+			}
+		}
+	}
+	if raw == nil && created == nil {
+		return nil, nil, ErrNoContainerExif
+	}
+	return raw, created, nil
+}
+
+// iTXt splits an international text chunk: keyword, compression flag and method, language tag,
+// translated keyword, then the (possibly zlib-compressed) text.
+func iTXt(data []byte) (string, string, bool) {
+	key, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || len(rest) < 2 {
+		return "", "", false
+	}
+	compressed := rest[0] == 1
+	_, rest, ok = bytes.Cut(rest[2:], []byte{0}) // language tag
+	if !ok {
+		return "", "", false
+	}
+	_, text, ok := bytes.Cut(rest, []byte{0}) // translated keyword
+	if !ok {
+		return "", "", false
+	}
+	if compressed {
+		zr, err := zlib.NewReader(bytes.NewReader(text))
+		if err != nil {
+			return "", "", false
+		}
+		defer zr.Close()
+		if text, err = io.ReadAll(io.LimitReader(zr, maxChunkSize)); err != nil {
+			return "", "", false
+		}
+	}
+	return string(key), string(text), true
+}
+
+// creationTimeLayouts are the forms "Creation Time" takes in the wild: RFC 1123 as the PNG spec
+// suggests, Exif style and ISO 8601.
+var creationTimeLayouts = []string{time.RFC1123Z, time.RFC1123, "2006:01:02 15:04:05", "2006-01-02 15:04:05", time.RFC3339, "2006-01-02T15:04:05"}
+
+func parseCreationTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range creationTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// webpExif walks the RIFF chunks of a WebP file for its EXIF chunk. Some writers keep the JPEG
+// "Exif\0\0" marker in front of the TIFF data; it is dropped.
+func webpExif(r io.ReadSeeker) ([]byte, error) {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var hdr [8]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, ErrNoContainerExif
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		padded := size + size&1
+		if string(hdr[:4]) != "EXIF" {
+			if _, err := r.Seek(padded, io.SeekCurrent); err != nil {
+				return nil, ErrNoContainerExif
+			}
+			continue
+		}
+		if size > maxChunkSize {
+			return nil, ErrNoContainerExif
+		}
+		data := make([]byte, size)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, ErrNoContainerExif
+		}
+		return bytes.TrimPrefix(data, []byte("Exif\x00\x00")), nil
+	}
+}
@@ -0,0 +1,39 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContainerExif(t *testing.T) {
+	// Fixtures: exif.png keeps its eXIf chunk after IDAT, created.png and itxt.png only have a
+	// textual Creation Time (the latter zlib-compressed), exif.webp has an odd-sized chunk before
+	// its "Exif\0\0"-prefixed EXIF chunk.
+	tests := []struct {
+		file, date, model string
+	}{
+		{"exif.png", "2024-02-29 23:59:58", "Pixel 8"},
+		{"created.png", "2024-09-14 18:22:10", ""},
+		{"itxt.png", "2022-01-02 03:04:05", ""},
+		{"exif.webp", "2023-07-01 12:00:00", "SM-S918B"},
+	}
+	for _, tt := range tests {
+		date, model, err := ExtractExif(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if date == nil || date.Format("2006-01-02 15:04:05") != tt.date || model != tt.model {
+			t.Errorf("%s: date %v, model %q; want %s, %q", tt.file, date, model, tt.date, tt.model)
+		}
+	}
+
+	// Broken Exif, a bad zlib stream and an absurd chunk length must neither panic nor yield a date.
+	if date, _, err := ExtractExif(filepath.Join("testdata", "malformed.png")); err == nil && date != nil {
+		t.Errorf("malformed.png: date %v", date)
+	}
+	info, err := GetFileInfo(filepath.Join("testdata", "malformed.png"))
+	if err != nil || info.DateSource != DateFromModTime {
+		t.Errorf("malformed.png: GetFileInfo = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClockOffset corrects the clock of one device: its own dates (EXIF, a video's recording date) on the
+// days From to To, both inclusive and as the device's clock had them, are moved by Offset. An empty
+// From or To leaves that end open.
+type ClockOffset struct {
+	Device   string
+	Offset   time.Duration
+	From, To string // YYYY-MM-DD
+}
+
+// ParseClockOffset reads an offset written as "device = +01:00", optionally followed by the days it
+// applies to, e.g. "Canon EOS 70D = +01:00 2024-07-01..2024-07-14"; either day may be left out.
+func ParseClockOffset(s string) (ClockOffset, error) {
+	device, rest, ok := strings.Cut(s, "=")
+	fields := strings.Fields(rest)
+	o := ClockOffset{Device: collapseSpace(device)}
+	if !ok || o.Device == "" || len(fields) == 0 || len(fields) > 2 {
+		return o, fmt.Errorf("want \"device = +hh:mm [from..to]\", got %q", strings.TrimSpace(s))
+	}
+	var err error
+	if o.Offset, err = parseOffset(fields[0]); err != nil {
+		return o, err
+	}
+	if len(fields) == 2 {
+		from, to, ok := strings.Cut(fields[1], "..")
+		if !ok || from == "" && to == "" {
+			return o, fmt.Errorf("want the days as from..to, got %q", fields[1])
+		}
+		for _, day := range []string{from, to} {
+			if _, err := time.Parse("2006-01-02", day); day != "" && err != nil {
+				return o, fmt.Errorf("invalid day %q, want YYYY-MM-DD", day)
+			}
+		}
+		if to != "" && from > to {
+			return o, fmt.Errorf("%s is after %s", from, to)
+		}
+		o.From, o.To = from, to
+	}
+	return o, nil
+}
+
+// parseOffset reads a signed offset as ±hh:mm.
+func parseOffset(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid offset %q, want +hh:mm or -hh:mm", s)
+	if len(s) < 2 || s[0] != '+' && s[0] != '-' {
+		return 0, invalid
+	}
+	hh, mm, ok := strings.Cut(s[1:], ":")
+	h, herr := strconv.Atoi(hh)
+	m, merr := strconv.Atoi(mm)
+	if !ok || herr != nil || merr != nil || h < 0 || m < 0 || m > 59 {
+		return 0, invalid
+	}
+	d := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute
+	if s[0] == '-' {
+		d = -d
+	}
+	return d, nil
+}
+
+// FormatOffset writes d as ±hh:mm.
+func FormatOffset(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// String writes o the way ParseClockOffset reads it.
+func (o ClockOffset) String() string {
+	s := o.Device + " = " + FormatOffset(o.Offset)
+	if o.From != "" || o.To != "" {
+		s += " " + o.From + ".." + o.To
+	}
+	return s
+}
+
+// covers reports whether o applies to a file of device dated t.
+func (o ClockOffset) covers(device string, t time.Time) bool {
+	day := t.Format("2006-01-02")
+	return deviceKey(o.Device) == deviceKey(device) && (o.From == "" || day >= o.From) && (o.To == "" || day <= o.To)
+}
+
+// ParseClockOffsets reads offsets written one per line, as ParseClockOffset reads them; blank lines
+// are skipped.
+func ParseClockOffsets(text string) ([]ClockOffset, error) {
+	var offsets []ClockOffset
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		o, err := ParseClockOffset(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		offsets = append(offsets, o)
+	}
+	return offsets, nil
+}
+
+// Clocks corrects the dates of devices whose clock was off. A nil *Clocks corrects nothing.
+type Clocks struct {
+	offsets []ClockOffset
+}
+
+// NewClocks reads the offsets of the config. Their devices are named as names names models, so an
+// offset given for "SM-G991B" also applies to the files of its "Galaxy S21" folder. Entries that do
+// not parse are left out and reported in the error; the others apply all the same. When several
+// offsets cover a file, the first one applies.
+func NewClocks(entries []string, names *DeviceNames) (*Clocks, error) {
+	c := &Clocks{}
+	var bad []string
+	for _, e := range entries {
+		o, err := ParseClockOffset(e)
+		if err != nil {
+			bad = append(bad, err.Error())
+			continue
+		}
+		o.Device = names.Normalize(o.Device)
+		c.offsets = append(c.offsets, o)
+	}
+	if bad != nil {
+		return c, fmt.Errorf("clock offsets skipped: %s", strings.Join(bad, "; "))
+	}
+	return c, nil
+}
+
+// Apply moves the own date of info, named by its normalized device, by the offset that covers it and
+// records the correction in ClockOffset. Dates from names or file times are left alone: the device's
+// clock did not set them.
+func (c *Clocks) Apply(info *FileInfo) {
+	if c == nil || info.ClockOffset != 0 || info.DateSource != DateFromExif && info.DateSource != DateFromVideo {
+		return
+	}
+	for _, o := range c.offsets {
+		if o.covers(info.Device, info.Date) {
+			info.SetDate(info.Date.Add(o.Offset))
+			info.ClockOffset = o.Offset
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClockOffset(t *testing.T) {
+	tests := []struct {
+		in   string
+		want ClockOffset
+		ok   bool
+	}{
+		{"Canon EOS 70D = +01:00 2024-07-01..2024-07-14", ClockOffset{"Canon EOS 70D", time.Hour, "2024-07-01", "2024-07-14"}, true},
+		{"  Canon  EOS 70D=-00:30", ClockOffset{"Canon EOS 70D", -30 * time.Minute, "", ""}, true},
+		{"Pixel 7 = +12:00 2023-01-01..", ClockOffset{"Pixel 7", 12 * time.Hour, "2023-01-01", ""}, true},
+		{"Pixel 7 = -02:15 ..2023-01-01", ClockOffset{"Pixel 7", -(2*time.Hour + 15*time.Minute), "", "2023-01-01"}, true},
+		{"Pixel 7 = 01:00", ClockOffset{}, false},
+		{"Pixel 7 = +1h", ClockOffset{}, false},
+		{"Pixel 7 = +01:60", ClockOffset{}, false},
+		{"= +01:00", ClockOffset{}, false},
+		{"Pixel 7 +01:00", ClockOffset{}, false},
+		{"Pixel 7 = +01:00 2023-01-01", ClockOffset{}, false},
+		{"Pixel 7 = +01:00 2023-02-30..", ClockOffset{}, false},
+		{"Pixel 7 = +01:00 2023-03-01..2023-02-01", ClockOffset{}, false},
+		{"Pixel 7 = +01:00 ..", ClockOffset{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseClockOffset(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseClockOffset(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParseClockOffset(%q) = %+v; want an error", tt.in, got)
+		}
+		if tt.ok {
+			if again, err := ParseClockOffset(got.String()); err != nil || again != got {
+				t.Errorf("%q does not read back: %+v, %v", got.String(), again, err)
+			}
+		}
+	}
+}
+
+func TestClocksApply(t *testing.T) {
+	c, err := NewClocks([]string{
+		"CANON EOS 70D = +01:00 2024-07-01..2024-07-14",
+		"SM-G991B = -00:30",
+		"broken",
+	}, NewDeviceNames(nil))
+	if err == nil {
+		t.Error("NewClocks did not report the broken entry")
+	}
+	at := func(day, clock string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+clock, time.Local)
+		return t
+	}
+	tests := []struct {
+		device, source string
+		date, want     time.Time
+	}{
+		// The first and last day of the trip are covered, as the camera's clock had them.
+		{"Canon Eos 70d", DateFromExif, at("2024-07-01", "08:00"), at("2024-07-01", "09:00")},
+		{"Canon Eos 70d", DateFromExif, at("2024-07-14", "23:30"), at("2024-07-15", "00:30")},
+		{"Canon Eos 70d", DateFromVideo, at("2024-07-05", "12:00"), at("2024-07-05", "13:00")},
+		{"Canon Eos 70d", DateFromExif, at("2024-07-15", "12:00"), at("2024-07-15", "12:00")},
+		{"Canon Eos 70d", DateFromExif, at("2024-06-30", "23:59"), at("2024-06-30", "23:59")},
+		// The offset names the device by its model, the file by its folder name.
+		{"Galaxy S21", DateFromExif, at("2019-01-01", "00:10"), at("2018-12-31", "23:40")},
+		// Dates the device's clock did not set are left alone.
+		{"Canon Eos 70d", DateFromFilename, at("2024-07-05", "12:00"), at("2024-07-05", "12:00")},
+		{"Canon Eos 70d", DateFromModTime, at("2024-07-05", "12:00"), at("2024-07-05", "12:00")},
+		{"Nikon D750", DateFromExif, at("2024-07-05", "12:00"), at("2024-07-05", "12:00")},
+	}
+	for _, tt := range tests {
+		info := FileInfo{Device: tt.device, DateSource: tt.source}
+		info.SetDate(tt.date)
+		c.Apply(&info)
+		if !info.Date.Equal(tt.want) || info.Day != tt.want.Format("02") || (info.ClockOffset != 0) != !tt.want.Equal(tt.date) {
+			t.Errorf("%s %s %s: Date = %s, Day %s, offset %s; want %s", tt.device, tt.source, tt.date, info.Date, info.Day, info.ClockOffset, tt.want)
+		}
+		// A second pass does not correct the date again.
+		before := info.Date
+		if c.Apply(&info); !info.Date.Equal(before) {
+			t.Errorf("%s %s: corrected twice", tt.device, tt.date)
+		}
+	}
+	var none *Clocks
+	info := FileInfo{Device: "Canon Eos 70d", DateSource: DateFromExif}
+	info.SetDate(at("2024-07-05", "12:00"))
+	if none.Apply(&info); info.ClockOffset != 0 {
+		t.Error("nil Clocks corrected a date")
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package metadata
+
+import (
+	"os"
+	"time"
+)
+
+// GetCreationTime returns the modification time of path: the creation time is not kept portably
+// elsewhere.
+func GetCreationTime(path string) (time.Time, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fileInfo.ModTime(), nil
+}
@@ -0,0 +1,20 @@
+package metadata
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// GetCreationTime reads the creation time the file system keeps for path, or its modification time
+// when there is none.
+func GetCreationTime(path string) (time.Time, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if winAttr, ok := fileInfo.Sys().(*syscall.Win32FileAttributeData); ok {
+		return time.Unix(0, winAttr.CreationTime.Nanoseconds()), nil
+	}
+	return fileInfo.ModTime(), nil
+}
@@ -0,0 +1,89 @@
+package metadata
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Date sources beyond the file's own headers. DateUndated marks a file without any plausible date;
+// its date folders render as UndatedFolder.
+const (
+	DateFromFilename = "filename"
+	DateUndated      = "undated"
+	UndatedFolder    = "Undated"
+)
+
+// DateWindow bounds the capture dates Lume believes. Cameras with a dead clock stamp 1904, 1980 or
+// 2089; such dates are rejected and the next source in the chain is tried.
+type DateWindow struct {
+	MinYear int
+	// Future is how far past the current time a date may lie (time zones, slightly fast clocks).
+	Future time.Duration
+}
+
+// DefaultMinYear is the earliest plausible capture year unless configured otherwise.
+const DefaultMinYear = 1990
+
+// Plausible is the window GetFileInfo validates dates against. It is set once at startup from the
+// configuration.
+var Plausible = DateWindow{MinYear: DefaultMinYear, Future: 24 * time.Hour}
+
+// Contains reports whether t is a plausible capture date.
+func (w DateWindow) Contains(t time.Time) bool {
+	return !t.IsZero() && t.Year() >= w.MinYear && !t.After(time.Now().Add(w.Future))
+}
+
+// filenamePattern matches the dates phones and messengers put in names: IMG_20230514_123456,
+// PXL_20230514_123456789, IMG-20230514-WA0001, Screenshot_2023-05-14-12-34-56, 2023-05-14 12.34.56.
+var filenamePattern = regexp.MustCompile(`(?:^|\D)((?:19|20)\d\d)[-_.]?(\d\d)[-_.]?(\d\d)(?:[-_. T]?(\d\d)[-_.:]?(\d\d)[-_.:]?(\d\d))?`)
+
+// FilenameDate reads a capture date from the file name, in local time. Digits that do not form a
+// real calendar date (month 13, 30 February) are not a date.
+func FilenameDate(name string) (time.Time, bool) {
+	for _, m := range filenamePattern.FindAllStringSubmatch(name, -1) {
+		n := make([]int, 6)
+		for i, s := range m[1:] {
+			n[i], _ = strconv.Atoi(s) // the time is optional; missing parts stay 0
+		}
+		t := time.Date(n[0], time.Month(n[1]), n[2], n[3], n[4], n[5], 0, time.Local)
+		if t.Year() == n[0] && int(t.Month()) == n[1] && t.Day() == n[2] && t.Hour() == n[3] && t.Minute() == n[4] && t.Second() == n[5] {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateCandidate is one step of the date chain.
+type dateCandidate struct {
+	source string
+	date   func() (time.Time, bool)
+}
+
+// resolveDate takes the first plausible date of the chain. The first implausible one is kept in
+// RejectedDate for the report; without any plausible date the file is undated.
+func (info *FileInfo) resolveDate(chain []dateCandidate) {
+	for _, c := range chain {
+		t, ok := c.date()
+		if !ok {
+			continue
+		}
+		if Plausible.Contains(t) {
+			info.SetDate(t)
+			info.DateSource = c.source
+			return
+		}
+		if info.RejectedSource == "" {
+			info.RejectedDate, info.RejectedSource = t, c.source
+		}
+	}
+	info.SetUndated()
+}
+
+// SetUndated files info under UndatedFolder: {year} and {weekyear} render as Undated, the other
+// date tokens as nothing. Date keeps the modification time for ordering.
+func (info *FileInfo) SetUndated() {
+	info.Date, info.DateSource = info.ModTime, DateUndated
+	info.Year, info.WeekYear = UndatedFolder, UndatedFolder
+	info.Month, info.Day, info.Week = "", "", ""
+}
@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFilenameDate(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"IMG_20230514_123456.jpg", "2023-05-14 12:34:56"},
+		{"PXL_20230514_123456789.jpg", "2023-05-14 12:34:56"},
+		{"IMG-20230514-WA0001.jpg", "2023-05-14 00:00:00"},
+		{"Screenshot_2023-05-14-12-34-56.png", "2023-05-14 12:34:56"},
+		{"2023-05-14 12.34.56.jpg", "2023-05-14 12:34:56"},
+		{"IMG_20230230_000000.jpg", ""}, // 30 February
+		{"DSC_1234.jpg", ""},
+		{"IMG_99991231.jpg", ""},
+	}
+	for _, tt := range tests {
+		got, ok := FilenameDate(tt.name)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("FilenameDate(%q) = %v; want none", tt.name, got)
+			}
+			continue
+		}
+		if !ok || got.Format("2006-01-02 15:04:05") != tt.want {
+			t.Errorf("FilenameDate(%q) = %v, %v; want %s", tt.name, got, ok, tt.want)
+		}
+	}
+}
+
+// exifTIFF builds a little-endian TIFF with Model in IFD0 and DateTimeOriginal in the Exif IFD.
+func exifTIFF(model, date string) []byte {
+	le := binary.LittleEndian
+	b := []byte{'I', 'I', 42, 0, 8, 0, 0, 0}
+	entry := func(tag, typ uint16, count, value uint32) {
+		b = le.AppendUint16(b, tag)
+		b = le.AppendUint16(b, typ)
+		b = le.AppendUint32(b, count)
+		b = le.AppendUint32(b, value)
+	}
+	model += "\x00"
+	date += "\x00"
+	ifd0 := uint32(8)
+	exifIFD := ifd0 + 2 + 2*12 + 4
+	modelOff := exifIFD + 2 + 12 + 4
+	dateOff := modelOff + uint32(len(model))
+	b = le.AppendUint16(b, 2)
+	entry(0x0110, 2, uint32(len(model)), modelOff)
+	entry(0x8769, 4, 1, exifIFD)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint16(b, 1)
+	entry(0x9003, 2, uint32(len(date)), dateOff)
+	b = le.AppendUint32(b, 0)
+	return append(append(b, model...), date...)
+}
+
+// mp4WithMvhd builds an ftyp box and a moov holding a version 0 mvhd with the given creation time.
+func mp4WithMvhd(secs uint32) []byte {
+	be := binary.BigEndian
+	b := be.AppendUint32(nil, 16)
+	b = append(b, "ftypisom\x00\x00\x02\x00"...)
+	mvhd := make([]byte, 8+100)
+	be.PutUint32(mvhd, uint32(len(mvhd)))
+	copy(mvhd[4:], "mvhd")
+	be.PutUint32(mvhd[12:], secs)
+	b = be.AppendUint32(b, uint32(8+len(mvhd)))
+	b = append(b, "moov"...)
+	return append(b, mvhd...)
+}
+
+func TestImplausibleDates(t *testing.T) {
+	dir := t.TempDir()
+	mtime := time.Date(2021, 6, 1, 9, 0, 0, 0, time.Local)
+	write := func(name string, data []byte, mod time.Time) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	future := buildJPEG(16, segment(0xe1, append([]byte("Exif\x00\x00"), exifTIFF("DeadClock", "2089:05:01 10:00:00")...)))
+	tests := []struct {
+		name, path             string
+		source, year, rejected string
+		rejectedFrom           string
+	}{
+		{"future EXIF falls back to the name", write("IMG_20200704_101500.jpg", future, mtime), DateFromFilename, "2020", "2089-05-01", DateFromExif},
+		{"future EXIF falls back to mtime", write("DSC_0001.jpg", future, mtime), DateFromModTime, "2021", "2089-05-01", DateFromExif},
+		// One day after the 1904 QuickTime epoch: a camera whose clock was never set. Videos fall back to
+		// the creation time first, which is the time of this test on Windows.
+		{"1904 QuickTime epoch", write("MOV_0001.mp4", mp4WithMvhd(86400), mtime), DateFromCreation, "", "1904-01-02", DateFromVideo},
+		{"nothing plausible", write("DSC_0003.jpg", future, time.Date(1980, 1, 1, 0, 0, 0, 0, time.Local)), DateUndated, UndatedFolder, "2089-05-01", DateFromExif},
+		{"plausible EXIF", write("DSC_0002.jpg", buildJPEG(16, segment(0xe1, append([]byte("Exif\x00\x00"), exifTIFF("Good", "2019:02:03 04:05:06")...))), mtime), DateFromExif, "2019", "", ""},
+	}
+	for _, tt := range tests {
+		info, err := GetFileInfo(tt.path)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		rejected := ""
+		if !info.RejectedDate.IsZero() {
+			rejected = info.RejectedDate.UTC().Format("2006-01-02")
+		}
+		if tt.source == DateFromCreation && info.DateSource == DateFromModTime {
+			tt.source = DateFromModTime // no creation time on this platform
+		}
+		if info.DateSource != tt.source || (tt.year != "" && info.Year != tt.year) || rejected != tt.rejected || info.RejectedSource != tt.rejectedFrom {
+			t.Errorf("%s: source %s, year %s, rejected %s from %q; want %s, %s, %s from %q",
+				tt.name, info.DateSource, info.Year, rejected, info.RejectedSource, tt.source, tt.year, tt.rejected, tt.rejectedFrom)
+		}
+	}
+}
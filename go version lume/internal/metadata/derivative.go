@@ -0,0 +1,66 @@
+package metadata
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DerivativePatterns are the names phone editors give the copies they save of a photo, as regular
+// expressions matched against the name of the copy without its extension, ignoring case. The first
+// group is what the editor added to the name of the original; cut out, the original's is left.
+var DerivativePatterns = []string{
+	`^IMG_(E)\d{4}$`, // iOS Photos: IMG_E1234.JPG is the edited IMG_1234.HEIC
+	`^.+(~\d+)$`,     // Samsung Gallery and Google Photos on Android: IMG_1234~2.jpg
+	`^.+(_edited)$`,  // Samsung Gallery: IMG_1234_edited.jpg
+	`^.+(-edited)$`,  // Google Photos: PXL_20230615_101010123-edited.jpg
+}
+
+// Derivatives tells the edited copies of photos by their names. A nil *Derivatives tells none.
+type Derivatives struct {
+	patterns []*regexp.Regexp
+}
+
+// NewDerivatives compiles DerivativePatterns and the extra patterns of the config after them. Extra
+// patterns that do not compile, or lack a group, are left out and reported in the error; the others
+// apply all the same.
+func NewDerivatives(extra []string) (*Derivatives, error) {
+	d := &Derivatives{}
+	var bad []string
+	for _, p := range append(DerivativePatterns[:len(DerivativePatterns):len(DerivativePatterns)], extra...) {
+		re, err := regexp.Compile("(?i)" + p)
+		if err == nil && re.NumSubexp() == 0 {
+			err = fmt.Errorf("no group marks what the editor added")
+		}
+		if err != nil {
+			bad = append(bad, fmt.Sprintf("%q: %v", p, err))
+			continue
+		}
+		d.patterns = append(d.patterns, re)
+	}
+	if bad != nil {
+		return d, fmt.Errorf("derivative patterns skipped: %s", strings.Join(bad, "; "))
+	}
+	return d, nil
+}
+
+// Original returns the name, without extension, of the photo the file named name is an edited copy
+// of, by the first pattern that matches; ok is false when name is no edited copy. The original may
+// have another extension than its copy.
+func (d *Derivatives) Original(name string) (stem string, ok bool) {
+	if d == nil {
+		return "", false
+	}
+	stem = strings.TrimSuffix(name, filepath.Ext(name))
+	for _, re := range d.patterns {
+		m := re.FindStringSubmatchIndex(stem)
+		if m == nil || m[2] < 0 {
+			continue
+		}
+		if orig := stem[:m[2]] + stem[m[3]:]; orig != "" {
+			return orig, true
+		}
+	}
+	return "", false
+}
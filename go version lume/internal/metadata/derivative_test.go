@@ -0,0 +1,39 @@
+package metadata
+
+import "testing"
+
+func TestDerivativesOriginal(t *testing.T) {
+	d, err := NewDerivatives([]string{`^.+( \(bearbeitet\))$`, `^(.+`, `^plain$`})
+	if err == nil {
+		t.Error("patterns that do not compile, or lack a group, not reported")
+	}
+	for _, tc := range []struct {
+		name, want string
+	}{
+		// iOS Photos
+		{"IMG_E1234.JPG", "IMG_1234"},
+		{"img_e0042.heic", "img_0042"},
+		// Samsung Gallery
+		{"IMG_1234~2.jpg", "IMG_1234"},
+		{"20230615_101010_edited.jpg", "20230615_101010"},
+		{"IMG_1234_EDITED.JPG", "IMG_1234"},
+		// Google Photos
+		{"PXL_20230615_101010123-edited.jpg", "PXL_20230615_101010123"},
+		{"PXL_20230615_101010123~3.jpg", "PXL_20230615_101010123"},
+		// the config's own
+		{"DSC_0001 (bearbeitet).jpg", "DSC_0001"},
+		// originals
+		{"IMG_1234.JPG", ""},
+		{"IMG_EDIT.JPG", ""},
+		{"~2.jpg", ""},
+		{"edited.jpg", ""},
+	} {
+		got, ok := d.Original(tc.name)
+		if got != tc.want || ok != (tc.want != "") {
+			t.Errorf("Original(%s) = %q, %v; want %q", tc.name, got, ok, tc.want)
+		}
+	}
+	if _, ok := (*Derivatives)(nil).Original("IMG_E1234.JPG"); ok {
+		t.Error("nil Derivatives told an edited copy")
+	}
+}
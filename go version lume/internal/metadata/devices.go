@@ -0,0 +1,182 @@
+package metadata
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// appleModels maps the hardware identifiers some apps write as the EXIF Model to the phone's name.
+var appleModels = map[string]string{
+	"iphone10,1": "iPhone 8", "iphone10,4": "iPhone 8", "iphone10,2": "iPhone 8 Plus", "iphone10,5": "iPhone 8 Plus",
+	"iphone10,3": "iPhone X", "iphone10,6": "iPhone X", "iphone11,2": "iPhone XS", "iphone11,4": "iPhone XS Max",
+	"iphone11,6": "iPhone XS Max", "iphone11,8": "iPhone XR", "iphone12,1": "iPhone 11", "iphone12,3": "iPhone 11 Pro",
+	"iphone12,5": "iPhone 11 Pro Max", "iphone12,8": "iPhone SE (2nd generation)", "iphone13,1": "iPhone 12 mini",
+	"iphone13,2": "iPhone 12", "iphone13,3": "iPhone 12 Pro", "iphone13,4": "iPhone 12 Pro Max",
+	"iphone14,4": "iPhone 13 mini", "iphone14,5": "iPhone 13", "iphone14,2": "iPhone 13 Pro",
+	"iphone14,3": "iPhone 13 Pro Max", "iphone14,6": "iPhone SE (3rd generation)", "iphone14,7": "iPhone 14",
+	"iphone14,8": "iPhone 14 Plus", "iphone15,2": "iPhone 14 Pro", "iphone15,3": "iPhone 14 Pro Max",
+	"iphone15,4": "iPhone 15", "iphone15,5": "iPhone 15 Plus", "iphone16,1": "iPhone 15 Pro",
+	"iphone16,2": "iPhone 15 Pro Max", "iphone17,3": "iPhone 16", "iphone17,4": "iPhone 16 Plus",
+	"iphone17,1": "iPhone 16 Pro", "iphone17,2": "iPhone 16 Pro Max",
+}
+
+// samsungModels maps Samsung model numbers, without the region letters that follow them (SM-G991B,
+// SM-G991U and SM-G991N are all a Galaxy S21), to the phone's name.
+var samsungModels = map[string]string{
+	"SM-G970": "Galaxy S10e", "SM-G973": "Galaxy S10", "SM-G975": "Galaxy S10+",
+	"SM-G981": "Galaxy S20", "SM-G986": "Galaxy S20+", "SM-G988": "Galaxy S20 Ultra", "SM-G780": "Galaxy S20 FE",
+	"SM-G991": "Galaxy S21", "SM-G996": "Galaxy S21+", "SM-G998": "Galaxy S21 Ultra", "SM-G990": "Galaxy S21 FE",
+	"SM-S901": "Galaxy S22", "SM-S906": "Galaxy S22+", "SM-S908": "Galaxy S22 Ultra",
+	"SM-S911": "Galaxy S23", "SM-S916": "Galaxy S23+", "SM-S918": "Galaxy S23 Ultra", "SM-S711": "Galaxy S23 FE",
+	"SM-S921": "Galaxy S24", "SM-S926": "Galaxy S24+", "SM-S928": "Galaxy S24 Ultra",
+	"SM-N970": "Galaxy Note10", "SM-N975": "Galaxy Note10+", "SM-N981": "Galaxy Note20", "SM-N986": "Galaxy Note20 Ultra",
+	"SM-A515": "Galaxy A51", "SM-A715": "Galaxy A71", "SM-A525": "Galaxy A52", "SM-A536": "Galaxy A53", "SM-A546": "Galaxy A54",
+	"SM-F711": "Galaxy Z Flip3", "SM-F721": "Galaxy Z Flip4", "SM-F731": "Galaxy Z Flip5",
+	"SM-F926": "Galaxy Z Fold3", "SM-F936": "Galaxy Z Fold4", "SM-F946": "Galaxy Z Fold5",
+}
+
+var samsungModel = regexp.MustCompile(`^(SM-[A-Z]\d{3})`)
+
+// pixelCodenames maps the codenames of Google Pixel phones to their names.
+var pixelCodenames = map[string]string{
+	"sailfish": "Pixel", "marlin": "Pixel XL", "walleye": "Pixel 2", "taimen": "Pixel 2 XL",
+	"blueline": "Pixel 3", "crosshatch": "Pixel 3 XL", "sargo": "Pixel 3a", "bonito": "Pixel 3a XL",
+	"flame": "Pixel 4", "coral": "Pixel 4 XL", "sunfish": "Pixel 4a", "bramble": "Pixel 4a (5G)",
+	"redfin": "Pixel 5", "barbet": "Pixel 5a", "oriole": "Pixel 6", "raven": "Pixel 6 Pro", "bluejay": "Pixel 6a",
+	"panther": "Pixel 7", "cheetah": "Pixel 7 Pro", "lynx": "Pixel 7a", "felix": "Pixel Fold",
+	"shiba": "Pixel 8", "husky": "Pixel 8 Pro", "akita": "Pixel 8a",
+	"tokay": "Pixel 9", "caiman": "Pixel 9 Pro", "komodo": "Pixel 9 Pro XL",
+}
+
+// DeviceNames turns EXIF Model strings into the device names folders are named by, so "iPhone12,3"
+// and "iPhone 11 Pro" end up in one folder. A nil *DeviceNames applies the built-in names only.
+type DeviceNames struct {
+	aliases map[string]string
+}
+
+// NewDeviceNames returns the built-in names overridden by the user's aliases (model → name). Keys
+// match regardless of case and spacing; when several keys match alike, the one sorting first wins.
+func NewDeviceNames(aliases map[string]string) *DeviceNames {
+	keys := make([]string, 0, len(aliases))
+	for k := range aliases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	d := &DeviceNames{aliases: make(map[string]string, len(aliases))}
+	for _, k := range keys {
+		name := collapseSpace(aliases[k])
+		if key := deviceKey(k); key != "" && name != "" {
+			if _, taken := d.aliases[key]; !taken {
+				d.aliases[key] = name
+			}
+		}
+	}
+	return d
+}
+
+// Normalize returns the device name of an EXIF model: a user alias, else a built-in name (which a
+// user alias may rename in turn), else the model with its spacing cleaned up and, when it is all in
+// one case, title-cased. An empty model is "Unknown".
+func (d *DeviceNames) Normalize(model string) string {
+	model = collapseSpace(strings.Trim(model, "\x00"))
+	if model == "" {
+		return "Unknown"
+	}
+	if name, ok := d.alias(model); ok {
+		return name
+	}
+	if name, ok := builtinDevice(model); ok {
+		if alias, ok := d.alias(name); ok {
+			return alias
+		}
+		return name
+	}
+	return titleCase(model)
+}
+
+func (d *DeviceNames) alias(model string) (string, bool) {
+	if d == nil {
+		return "", false
+	}
+	name, ok := d.aliases[deviceKey(model)]
+	return name, ok
+}
+
+func builtinDevice(model string) (string, bool) {
+	key := deviceKey(model)
+	if name, ok := appleModels[key]; ok {
+		return name, true
+	}
+	if name, ok := pixelCodenames[key]; ok {
+		return name, true
+	}
+	if m := samsungModel.FindStringSubmatch(strings.ToUpper(model)); m != nil {
+		if name, ok := samsungModels[m[1]]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// deviceKey is the form models and alias keys are compared in.
+func deviceKey(s string) string {
+	return strings.ToLower(collapseSpace(s))
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// titleCase capitalizes a model written all in upper or all in lower case ("NIKON D750" becomes
+// "Nikon D750"); mixed case such as "iPhone" or "Canon EOS R5" is the maker's own and is kept.
+func titleCase(s string) string {
+	if strings.ToUpper(s) != s && strings.ToLower(s) != s {
+		return s
+	}
+	words := strings.Fields(s)
+	for i, w := range words {
+		r := []rune(w)
+		if strings.IndexFunc(w, func(c rune) bool { return !unicode.IsLetter(c) }) < 0 {
+			r = []rune(strings.ToLower(w))
+		}
+		r[0] = unicode.ToUpper(r[0])
+		words[i] = string(r)
+	}
+	return strings.Join(words, " ")
+}
+
+// ParseDeviceAliases reads aliases written one per line as "model = name"; blank lines are skipped.
+func ParseDeviceAliases(text string) (map[string]string, error) {
+	aliases := make(map[string]string)
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		model, name, ok := strings.Cut(line, "=")
+		model, name = strings.TrimSpace(model), strings.TrimSpace(name)
+		if !ok || model == "" || name == "" {
+			return nil, fmt.Errorf("line %d: want \"model = name\", got %q", i+1, line)
+		}
+		aliases[model] = name
+	}
+	return aliases, nil
+}
+
+// FormatDeviceAliases writes aliases the way ParseDeviceAliases reads them, sorted by model and with
+// the CRLF line ends a Windows edit box needs.
+func FormatDeviceAliases(aliases map[string]string) string {
+	keys := make([]string, 0, len(aliases))
+	for k := range aliases {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\r\n", k, aliases[k])
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package metadata
+
+import "testing"
+
+func TestNormalizeDevice(t *testing.T) {
+	d := NewDeviceNames(map[string]string{
+		"SM-G991B":      "Galaxy S21",
+		"iPhone 11 Pro": "Mom's phone",
+		// Alike keys conflict; the one sorting first ("Canon…" before "canon…") wins.
+		"canon eos r5":  "Second body",
+		"Canon  EOS R5": "Main body",
+		// Aliases are not chained, so two naming each other cannot loop.
+		"A":     "B",
+		"B":     "A",
+		"empty": "  ",
+	})
+	tests := []struct{ model, want string }{
+		{"SM-G991B", "Galaxy S21"},
+		{"sm-g991b", "Galaxy S21"},
+		{"SM-G991U", "Galaxy S21"},
+		{"SM-S918B", "Galaxy S23 Ultra"},
+		{"iPhone12,3", "Mom's phone"},
+		{"iPhone 11 Pro", "Mom's phone"},
+		{"iPhone13,2", "iPhone 12"},
+		{"oriole", "Pixel 6"},
+		{"Canon EOS R5", "Main body"},
+		{"A", "B"},
+		{"B", "A"},
+		{"empty", "Empty"},
+		{"NIKON  D750 ", "Nikon D750"},
+		{"canon eos 5d", "Canon Eos 5d"},
+		{"Canon EOS 5D Mark IV", "Canon EOS 5D Mark IV"},
+		{"SM-X999Z", "SM-X999Z"},
+		{"", "Unknown"},
+		{"\x00", "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := d.Normalize(tt.model); got != tt.want {
+			t.Errorf("Normalize(%q) = %q; want %q", tt.model, got, tt.want)
+		}
+	}
+	var builtin *DeviceNames
+	if got := builtin.Normalize("iPhone12,3"); got != "iPhone 11 Pro" {
+		t.Errorf("nil Normalize = %q; want the built-in name", got)
+	}
+}
+
+func TestParseDeviceAliases(t *testing.T) {
+	in := map[string]string{"SM-G991B": "Galaxy S21", "iPhone12,3": "Work = phone"}
+	out, err := ParseDeviceAliases(FormatDeviceAliases(in) + "\r\n\r\n")
+	if err != nil || len(out) != 2 || out["SM-G991B"] != "Galaxy S21" || out["iPhone12,3"] != "Work = phone" {
+		t.Errorf("round trip = %v, %v; want %v", out, err, in)
+	}
+	for _, bad := range []string{"no separator", "= name", "model ="} {
+		if _, err := ParseDeviceAliases(bad); err == nil {
+			t.Errorf("ParseDeviceAliases(%q) accepted", bad)
+		}
+	}
+}
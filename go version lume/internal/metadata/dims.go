@@ -0,0 +1,109 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoDimensions reports an image whose size cannot be read from its header.
+var ErrNoDimensions = errors.New("image dimensions unavailable")
+
+// ImageSize reads the pixel dimensions of an image from its header without decoding the picture.
+// JPEG, PNG and GIF go through image.DecodeConfig; WebP is read from its VP8, VP8L or VP8X chunk,
+// HEIC/HEIF and AVIF from the image extent property of their meta box, BMP from its info header and
+// JPEG XL from the size header of its codestream.
+func ImageSize(path string) (int, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		cfg, _, err := image.DecodeConfig(f)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%w: %v", ErrNoDimensions, err)
+		}
+		return cfg.Width, cfg.Height, nil
+	case ".webp":
+		return webpSize(f)
+	case ".heic", ".heif", ".hif", ".avif":
+		return heifSize(f)
+	case ".bmp":
+		return bmpSize(f)
+	case ".jxl":
+		return jxlSize(f)
+	}
+	return 0, 0, ErrNoDimensions
+}
+
+// Megapixels is the pixel count in millions, 0 when the dimensions are unknown.
+func (info FileInfo) Megapixels() float64 {
+	return float64(info.Width) * float64(info.Height) / 1e6
+}
+
+// webpSize parses the first chunk of a RIFF WebP file.
+func webpSize(r io.Reader) (int, int, error) {
+	var h [30]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil || string(h[0:4]) != "RIFF" || string(h[8:12]) != "WEBP" {
+		return 0, 0, ErrNoDimensions
+	}
+	data := h[20:]
+	switch string(h[12:16]) {
+	case "VP8 ": // lossy: frame tag, start code, then 14-bit width and height
+		if data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return 0, 0, ErrNoDimensions
+		}
+		return int(binary.LittleEndian.Uint16(data[6:]) & 0x3fff), int(binary.LittleEndian.Uint16(data[8:]) & 0x3fff), nil
+	case "VP8L": // lossless: signature, then width-1 and height-1 in 14 bits each
+		if data[0] != 0x2f {
+			return 0, 0, ErrNoDimensions
+		}
+		b := binary.LittleEndian.Uint32(data[1:])
+		return int(b&0x3fff) + 1, int(b>>14&0x3fff) + 1, nil
+	case "VP8X": // extended: flags, reserved, then 24-bit canvas width-1 and height-1
+		w := int(data[4]) | int(data[5])<<8 | int(data[6])<<16
+		h := int(data[7]) | int(data[8])<<8 | int(data[9])<<16
+		return w + 1, h + 1, nil
+	}
+	return 0, 0, ErrNoDimensions
+}
+
+// screenSizes maps screenshot resolutions, as captured (portrait for phones), to device classes.
+var screenSizes = map[[2]int]string{
+	// iPhone
+	{640, 1136}: "iPhone", {750, 1334}: "iPhone", {1242, 2208}: "iPhone", {1125, 2436}: "iPhone",
+	{828, 1792}: "iPhone", {1242, 2688}: "iPhone", {1170, 2532}: "iPhone", {1284, 2778}: "iPhone",
+	{1179, 2556}: "iPhone", {1290, 2796}: "iPhone", {1206, 2622}: "iPhone", {1320, 2868}: "iPhone",
+	// iPad, portrait and landscape
+	{1536, 2048}: "iPad", {2048, 1536}: "iPad", {1620, 2160}: "iPad", {2160, 1620}: "iPad",
+	{1668, 2388}: "iPad", {2388, 1668}: "iPad", {1640, 2360}: "iPad", {2360, 1640}: "iPad",
+	{2048, 2732}: "iPad", {2732, 2048}: "iPad", {1488, 2266}: "iPad", {2266, 1488}: "iPad",
+	// Android phones
+	{720, 1280}: "Android", {1080, 1920}: "Android", {1080, 2160}: "Android", {1080, 2220}: "Android",
+	{1080, 2280}: "Android", {1080, 2340}: "Android", {1080, 2400}: "Android", {1080, 2412}: "Android",
+	{1440, 2560}: "Android", {1440, 2960}: "Android", {1440, 3040}: "Android", {1440, 3088}: "Android",
+	{1440, 3120}: "Android", {1440, 3200}: "Android", {1344, 2992}: "Android", {1280, 2856}: "Android",
+	// Desktop and laptop displays
+	{1280, 720}: "Desktop", {1280, 800}: "Desktop", {1366, 768}: "Desktop", {1440, 900}: "Desktop",
+	{1536, 864}: "Desktop", {1600, 900}: "Desktop", {1680, 1050}: "Desktop", {1920, 1080}: "Desktop",
+	{1920, 1200}: "Desktop", {2560, 1080}: "Desktop", {2560, 1440}: "Desktop", {2560, 1600}: "Desktop",
+	{2880, 1800}: "Desktop", {3024, 1964}: "Desktop", {3440, 1440}: "Desktop", {3456, 2234}: "Desktop",
+	{3840, 2160}: "Desktop", {5120, 2880}: "Desktop",
+}
+
+// ScreenshotDevice names the device class a screenshot of w×h pixels was taken on, or "WxH" for
+// resolutions it does not know.
+func ScreenshotDevice(w, h int) string {
+	if d, ok := screenSizes[[2]int{w, h}]; ok {
+		return d
+	}
+	return fmt.Sprintf("%dx%d", w, h)
+}
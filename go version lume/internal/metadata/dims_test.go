@@ -0,0 +1,67 @@
+package metadata
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageSize(t *testing.T) {
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewGray(image.Rect(0, 0, 7, 3)))
+	pngPath := filepath.Join(dir, "a.png")
+	os.WriteFile(pngPath, buf.Bytes(), 0644)
+
+	riff := func(chunk string, data ...byte) []byte {
+		b := append([]byte("RIFF\x00\x00\x00\x00WEBP"+chunk+"\x00\x00\x00\x00"), data...)
+		return append(b, make([]byte, 30)...)
+	}
+	files := map[string][]byte{
+		"lossy.webp":    riff("VP8 ", 0, 0, 0, 0x9d, 0x01, 0x2a, 0x80, 0x07, 0x38, 0x04), // 1920x1080
+		"lossless.webp": riff("VP8L", 0x2f, 0x7f, 0xc0, 0x0d, 0x00),                      // 128x56
+		"extended.webp": riff("VP8X", 0, 0, 0, 0, 0x91, 0x04, 0x00, 0xe3, 0x09, 0x00),    // 1170x2532
+		"broken.webp":   []byte("RIFF"),
+	}
+	for name, data := range files {
+		os.WriteFile(filepath.Join(dir, name), data, 0644)
+	}
+
+	tests := []struct {
+		name string
+		w, h int
+		ok   bool
+	}{
+		{"a.png", 7, 3, true},
+		{"lossy.webp", 1920, 1080, true},
+		{"lossless.webp", 128, 56, true},
+		{"extended.webp", 1170, 2532, true},
+		{"broken.webp", 0, 0, false},
+	}
+	for _, tt := range tests {
+		w, h, err := ImageSize(filepath.Join(dir, tt.name))
+		if (err == nil) != tt.ok || w != tt.w || h != tt.h {
+			t.Errorf("ImageSize(%s) = %d, %d, %v; want %d, %d", tt.name, w, h, err, tt.w, tt.h)
+		}
+	}
+}
+
+func TestScreenshotDevice(t *testing.T) {
+	for _, tt := range []struct {
+		w, h int
+		want string
+	}{
+		{1170, 2532, "iPhone"},
+		{1920, 1080, "Desktop"},
+		{1080, 1920, "Android"}, // a portrait 1080p capture is a phone, not a monitor
+		{2048, 1536, "iPad"},
+		{1000, 700, "1000x700"},
+	} {
+		if got := ScreenshotDevice(tt.w, tt.h); got != tt.want {
+			t.Errorf("ScreenshotDevice(%d, %d) = %q; want %q", tt.w, tt.h, got, tt.want)
+		}
+	}
+}
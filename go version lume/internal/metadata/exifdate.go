@@ -0,0 +1,102 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Why ExtractExif found no date. GetFileInfo records the reason in FileInfo.MetadataNote.
+var (
+	ErrNoExif      = errors.New("no EXIF data")
+	ErrCorruptExif = errors.New("corrupt EXIF data")
+	ErrNoDateTag   = errors.New("EXIF date tag missing")
+	ErrBadDate     = errors.New("unparseable EXIF date")
+)
+
+// Values of FileInfo.MetadataNote: why an image was not dated by its EXIF date.
+const (
+	NoteNoExif      = "no_exif"
+	NoteCorruptExif = "corrupt_exif"
+	NoteNoDateTag   = "no_date_tag"
+	NoteBadDate     = "bad_date"
+)
+
+// ExifNote is the MetadataNote for an error of ExtractExif; anything unrecognized, such as a read
+// error, counts as corrupt.
+func ExifNote(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrNoExif):
+		return NoteNoExif
+	case errors.Is(err, ErrNoDateTag):
+		return NoteNoDateTag
+	case errors.Is(err, ErrBadDate):
+		return NoteBadDate
+	}
+	return NoteCorruptExif
+}
+
+// exifLayouts are the date layouts ParseExifDate accepts once separators are normalized. A fraction
+// after the seconds ("15:04:05.123") needs no layout of its own: time.Parse accepts it anyway.
+var exifLayouts = []string{
+	"2006:01:02 15:04:05Z07:00",
+	"2006:01:02 15:04:05-0700",
+	"2006:01:02 15:04:05",
+	"2006:01:02 15:04",
+	"2006:01:02",
+}
+
+// ParseExifDate reads an EXIF date as the wall-clock time it names, in UTC like the rest of the EXIF
+// dates. Besides the standard "2006:01:02 15:04:05" it takes what cameras and apps write in the wild:
+// subseconds, '-' or '/' between the date parts, a 'T' before the time, a zone offset and missing
+// seconds. An empty or all-zero date ("0000:00:00 00:00:00", "    :  :     :  :  ") is ErrNoDateTag,
+// anything else unreadable ErrBadDate.
+func ParseExifDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(strings.Trim(s, "\x00"))
+	if strings.Trim(s, "0: -/T.") == "" {
+		return time.Time{}, ErrNoDateTag
+	}
+	norm := []byte(s)
+	for i := 0; i < len(norm) && i < 10; i++ {
+		if norm[i] == '-' || norm[i] == '/' {
+			norm[i] = ':'
+		}
+	}
+	if len(norm) > 10 && norm[10] == 'T' {
+		norm[10] = ' '
+	}
+	for _, layout := range exifLayouts {
+		if t, err := time.Parse(layout, string(norm)); err == nil {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrBadDate, s)
+}
+
+// WithSubSec adds an EXIF SubSecTime value, the decimal digits of a fraction of a second ("123" is
+// 0.123 s, "5" is 0.5 s), to a date that has no fraction of its own. Anything that is not digits is
+// ignored, as are digits beyond nanoseconds.
+func WithSubSec(t time.Time, subsec string) time.Time {
+	subsec = strings.TrimSpace(strings.Trim(subsec, "\x00"))
+	if subsec == "" || t.Nanosecond() != 0 {
+		return t
+	}
+	if len(subsec) > 9 {
+		subsec = subsec[:9]
+	}
+	ns := 0
+	for i := 0; i < 9; i++ {
+		d := 0
+		if i < len(subsec) {
+			if subsec[i] < '0' || subsec[i] > '9' {
+				return t
+			}
+			d = int(subsec[i] - '0')
+		}
+		ns = ns*10 + d
+	}
+	return t.Add(time.Duration(ns))
+}
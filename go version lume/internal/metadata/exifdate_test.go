@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseExifDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"2023:10:20 15:04:05", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04:05\x00", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04:05.123", "2023-10-20 15:04:05.123", nil},
+		{"2023:10:20 15:04:05.5", "2023-10-20 15:04:05.500", nil},
+		{"2023-10-20 15:04:05", "2023-10-20 15:04:05.000", nil},
+		{"2023/10/20 15:04:05", "2023-10-20 15:04:05.000", nil},
+		{"2023-10-20T15:04:05", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04:05+03:00", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04:05Z", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04:05-0500", "2023-10-20 15:04:05.000", nil},
+		{"2023:10:20 15:04", "2023-10-20 15:04:00.000", nil},
+		{"2023:10:20", "2023-10-20 00:00:00.000", nil},
+		{" 2023:10:20 15:04:05 ", "2023-10-20 15:04:05.000", nil},
+		{"0000:00:00 00:00:00", "", ErrNoDateTag},
+		{"0000-00-00", "", ErrNoDateTag},
+		{"    :  :     :  :  ", "", ErrNoDateTag},
+		{"", "", ErrNoDateTag},
+		{"2023:13:01 00:00:00", "", ErrBadDate},
+		{"2023:02:30 10:00:00", "", ErrBadDate},
+		{"20.10.2023 15:04", "", ErrBadDate},
+		{"yesterday", "", ErrBadDate},
+	}
+	for _, tt := range tests {
+		got, err := ParseExifDate(tt.in)
+		if !errors.Is(err, tt.err) || tt.err == nil && err != nil {
+			t.Errorf("ParseExifDate(%q) error %v; want %v", tt.in, err, tt.err)
+			continue
+		}
+		if tt.err == nil && got.Format("2006-01-02 15:04:05.000") != tt.want {
+			t.Errorf("ParseExifDate(%q) = %s; want %s", tt.in, got.Format("2006-01-02 15:04:05.000"), tt.want)
+		}
+	}
+}
+
+func TestWithSubSec(t *testing.T) {
+	base := time.Date(2023, 10, 20, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		subsec string
+		want   string
+	}{
+		{"123", "15:04:05.123000"},
+		{"5", "15:04:05.500000"},
+		{"05", "15:04:05.050000"},
+		{" 042\x00", "15:04:05.042000"},
+		{"1234567", "15:04:05.123456"},
+		{"", "15:04:05.000000"},
+		{"abc", "15:04:05.000000"},
+	}
+	for _, tt := range tests {
+		if got := WithSubSec(base, tt.subsec).Format("15:04:05.000000"); got != tt.want {
+			t.Errorf("WithSubSec(%q) = %s; want %s", tt.subsec, got, tt.want)
+		}
+	}
+	// A date that already has a fraction keeps it.
+	if got := WithSubSec(base.Add(300*time.Millisecond), "9"); got.Nanosecond() != 300e6 {
+		t.Errorf("WithSubSec on a fractional date = %s; want .300 kept", got.Format("15:04:05.000"))
+	}
+}
+
+func TestMetadataNote(t *testing.T) {
+	tiff := sampleTIFF(t)
+	jfif := segment(0xe0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	withDate := func(date string) []byte {
+		raw := bytes.ReplaceAll(tiff, []byte("2023:07:01 12:00:00"), []byte(date))
+		return buildJPEG(16, jfif, segment(0xe1, append([]byte("Exif\x00\x00"), raw...)))
+	}
+	dir := t.TempDir()
+	tests := []struct {
+		name   string
+		data   []byte
+		note   string
+		source string
+	}{
+		{"standard date", withDate("2023:07:01 12:00:00"), "", DateFromExif},
+		{"dashed date", withDate("2023-07-01 12:00:00"), "", DateFromExif},
+		{"zero date", withDate("0000:00:00 00:00:00"), NoteNoDateTag, DateFromModTime},
+		{"garbled date", withDate("07/01/2023 12:00 PM"), NoteBadDate, DateFromModTime},
+		{"no exif", buildJPEG(16, jfif), NoteNoExif, DateFromModTime},
+		{"corrupt exif", buildJPEG(16, jfif, segment(0xe1, []byte("Exif\x00\x00MM\x00\x2a\xff\xff\xff\xff"))), NoteCorruptExif, DateFromModTime},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".jpg")
+		if err := os.WriteFile(path, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		info, err := GetFileInfo(path)
+		if err != nil || info.MetadataNote != tt.note || info.DateSource != tt.source {
+			t.Errorf("%s: note %q, source %s, err %v; want %q, %s", tt.name, info.MetadataNote, info.DateSource, err, tt.note, tt.source)
+		}
+		// The model is still read when only the date is unusable.
+		if tt.note == NoteNoDateTag && info.Device != "SM-S918B" {
+			t.Errorf("%s: device %q", tt.name, info.Device)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"lume-go/internal/fsys"
+	"os"
+	"time"
+)
+
+// ErrExifNotWritable reports a file whose EXIF date cannot be rewritten in place: not a JPEG, or
+// without both a DateTimeOriginal and a DateTimeDigitized to keep the camera's reading in.
+var ErrExifNotWritable = errors.New("EXIF date cannot be written")
+
+// exifDateLayout is the layout of the EXIF date tags.
+const exifDateLayout = "2006:01:02 15:04:05"
+
+// EXIF tags of the date fields.
+const (
+	tagExifIFD           = 0x8769
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+)
+
+// exifField is an ASCII EXIF value: where its bytes start in the file, how many there are and what
+// they say.
+type exifField struct {
+	off   int64
+	count int
+	text  string
+}
+
+// jpegDateFields finds the DateTimeOriginal and DateTimeDigitized of a JPEG. Only the headers up to
+// jpegHeaderLimit are read.
+func jpegDateFields(r io.ReaderAt) (orig, dig exifField, err error) {
+	head := make([]byte, jpegHeaderLimit)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return orig, dig, err
+	}
+	head = head[:n]
+	start, end, ok := jpegExifBounds(head)
+	if !ok {
+		return orig, dig, ErrExifNotWritable
+	}
+	tiff := head[start:end]
+	if len(tiff) < 8 {
+		return orig, dig, ErrExifNotWritable
+	}
+	var bo binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		bo = binary.LittleEndian
+	case "MM":
+		bo = binary.BigEndian
+	default:
+		return orig, dig, ErrExifNotWritable
+	}
+	// entries calls visit with each entry of the IFD at off: its tag, type, count and value.
+	entries := func(off uint32, visit func(tag, typ uint16, count, value uint32)) {
+		if int64(off)+2 > int64(len(tiff)) {
+			return
+		}
+		k := int(bo.Uint16(tiff[off:]))
+		for i := 0; i < k; i++ {
+			e := int(off) + 2 + 12*i
+			if e+12 > len(tiff) {
+				return
+			}
+			visit(bo.Uint16(tiff[e:]), bo.Uint16(tiff[e+2:]), bo.Uint32(tiff[e+4:]), bo.Uint32(tiff[e+8:]))
+		}
+	}
+	var exifIFD uint32
+	entries(bo.Uint32(tiff[4:]), func(tag, _ uint16, _, value uint32) {
+		if tag == tagExifIFD {
+			exifIFD = value
+		}
+	})
+	if exifIFD == 0 {
+		return orig, dig, ErrExifNotWritable
+	}
+	entries(exifIFD, func(tag, typ uint16, count, value uint32) {
+		// A date is 19 characters and a NUL, too long to sit in the entry itself.
+		if typ != 2 || count < 20 || int64(value)+int64(count) > int64(len(tiff)) {
+			return
+		}
+		f := exifField{off: int64(start) + int64(value), count: int(count), text: string(bytes.TrimSpace(bytes.SplitN(tiff[value:value+count], []byte{0}, 2)[0]))}
+		switch tag {
+		case tagDateTimeOriginal:
+			orig = f
+		case tagDateTimeDigitized:
+			dig = f
+		}
+	})
+	if orig.count == 0 || dig.count == 0 {
+		return orig, dig, ErrExifNotWritable
+	}
+	return orig, dig, nil
+}
+
+// jpegExifBounds finds the TIFF data of the APP1 Exif segment in the head of a JPEG, as jpegExif does.
+func jpegExifBounds(head []byte) (start, end int, ok bool) {
+	if len(head) < 2 || head[0] != 0xff || head[1] != 0xd8 {
+		return 0, 0, false
+	}
+	for pos := 2; pos+4 <= len(head); {
+		if head[pos] != 0xff {
+			return 0, 0, false
+		}
+		marker := head[pos+1]
+		switch {
+		case marker == 0xff: // fill byte
+			pos++
+			continue
+		case marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			pos += 2
+			continue
+		case marker == 0xda || marker == 0xd9:
+			return 0, 0, false
+		}
+		n := int(binary.BigEndian.Uint16(head[pos+2:]))
+		if n < 2 || pos+2+n > len(head) {
+			return 0, 0, false
+		}
+		seg := head[pos+4 : pos+2+n]
+		if marker == 0xe1 && bytes.HasPrefix(seg, exifMarker) {
+			return pos + 4 + len(exifMarker), pos + 2 + n, true
+		}
+		pos += 2 + n
+	}
+	return 0, 0, false
+}
+
+// dateFields reads the EXIF date fields of the JPEG at path.
+func dateFields(path string) (orig, dig exifField, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return orig, dig, err
+	}
+	defer f.Close()
+	return jpegDateFields(f)
+}
+
+// writtenShift tells from the EXIF dates of a JPEG whether s already wrote its date: a
+// DateTimeOriginal that is the DateTimeDigitized shifted by s. It returns the date written, and the
+// camera's own as before.
+func writtenShift(orig, dig exifField, s Shift) (written, before time.Time, ok bool) {
+	if orig.text == dig.text {
+		return written, before, false
+	}
+	written, err1 := ParseExifDate(orig.text)
+	before, err2 := ParseExifDate(dig.text)
+	return written, before, err1 == nil && err2 == nil && s.Apply(before).Equal(written)
+}
+
+// WriteExifDate rewrites the DateTimeOriginal of the JPEG at path on fs (nil is the real file system)
+// to t in place, keeping the size of the file and its modification time. The DateTimeDigitized keeps
+// the camera's reading. A file fs cannot write in place fails as ErrExifNotWritable.
+func WriteExifDate(fs fsys.FS, path string, t time.Time) error {
+	fs = fsys.Or(fs)
+	st, err := fs.Stat(path)
+	if err != nil {
+		return err
+	}
+	f, err := fs.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	rw, ok := f.(interface {
+		io.ReaderAt
+		io.WriterAt
+	})
+	if !ok {
+		f.Close()
+		return ErrExifNotWritable
+	}
+	orig, _, err := jpegDateFields(rw)
+	if err == nil {
+		// The rest of the field is NULs, whatever it held.
+		field := make([]byte, orig.count)
+		copy(field, t.Format(exifDateLayout))
+		_, err = rw.WriteAt(field, orig.off)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return fs.Chtimes(path, st.ModTime(), st.ModTime())
+}
@@ -0,0 +1,323 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+// Image formats as told by the signature their files start with (see sniffFormat).
+const (
+	formatJPEG = "jpeg"
+	formatPNG  = "png"
+	formatWebP = "webp"
+	formatHEIF = "heif"
+	formatAVIF = "avif"
+	formatJXL  = "jxl"
+	formatGIF  = "gif"
+	formatBMP  = "bmp"
+)
+
+// jxlSignature starts a JPEG XL file in the ISO-BMFF container; a bare codestream starts with 0xff0a.
+var jxlSignature = []byte("\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a")
+
+// sniffFormat names the image format of a file by its first 12 bytes, "" for none Lume reads. An
+// ISO-BMFF file is AVIF by an avif or avis major brand, HEIF otherwise.
+func sniffFormat(head []byte) string {
+	switch {
+	case len(head) < 12:
+	case head[0] == 0xff && head[1] == 0xd8:
+		return formatJPEG
+	case bytes.HasPrefix(head, pngSignature):
+		return formatPNG
+	case string(head[0:4]) == "RIFF" && string(head[8:12]) == "WEBP":
+		return formatWebP
+	case string(head[4:8]) == "ftyp":
+		if brand := string(head[8:12]); brand == "avif" || brand == "avis" {
+			return formatAVIF
+		}
+		return formatHEIF
+	case bytes.HasPrefix(head, jxlSignature) || head[0] == 0xff && head[1] == 0x0a:
+		return formatJXL
+	case string(head[0:6]) == "GIF87a" || string(head[0:6]) == "GIF89a":
+		return formatGIF
+	case string(head[0:2]) == "BM":
+		return formatBMP
+	}
+	return ""
+}
+
+// Animated reports whether path is an animated image: a GIF of more than one frame or an AVIF image
+// sequence. Other formats, and files that cannot be read, are not.
+func Animated(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var head [12]byte
+	if _, err := io.ReadFull(f, head[:]); err != nil {
+		return false
+	}
+	switch sniffFormat(head[:]) {
+	case formatGIF:
+		f.Seek(0, io.SeekStart)
+		return gifFrames(bufio.NewReader(f), 2) > 1
+	case formatAVIF:
+		f.Seek(0, io.SeekStart)
+		return hasBrand(f, "avis")
+	}
+	return false
+}
+
+// gifFrames counts the frames of a GIF up to limit, walking its blocks without decoding them.
+func gifFrames(r *bufio.Reader, limit int) int {
+	var lsd [13]byte // header and logical screen descriptor
+	if _, err := io.ReadFull(r, lsd[:]); err != nil {
+		return 0
+	}
+	if lsd[10]&0x80 != 0 { // global color table
+		r.Discard(3 << (lsd[10]&7 + 1))
+	}
+	frames := 0
+	for frames < limit {
+		kind, err := r.ReadByte()
+		if err != nil {
+			return frames
+		}
+		switch kind {
+		case 0x21: // extension: label, then data sub-blocks
+			if _, err := r.ReadByte(); err != nil || !skipSubBlocks(r) {
+				return frames
+			}
+		case 0x2c: // image descriptor, local color table, LZW code size, data sub-blocks
+			var desc [9]byte
+			if _, err := io.ReadFull(r, desc[:]); err != nil {
+				return frames
+			}
+			if desc[8]&0x80 != 0 {
+				r.Discard(3 << (desc[8]&7 + 1))
+			}
+			if _, err := r.ReadByte(); err != nil || !skipSubBlocks(r) {
+				return frames
+			}
+			frames++
+		default: // the trailer, or anything else ends it
+			return frames
+		}
+	}
+	return frames
+}
+
+// skipSubBlocks skips GIF data sub-blocks up to their terminator.
+func skipSubBlocks(r *bufio.Reader) bool {
+	for {
+		n, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		if n == 0 {
+			return true
+		}
+		if _, err := r.Discard(int(n)); err != nil {
+			return false
+		}
+	}
+}
+
+// hasBrand reports whether the ftyp box at the start of an ISO-BMFF file lists brand, as its major
+// brand or a compatible one.
+func hasBrand(r io.Reader, brand string) bool {
+	var hdr [8]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil || string(hdr[4:8]) != "ftyp" {
+		return false
+	}
+	size := binary.BigEndian.Uint32(hdr[:4])
+	if size < 16 || size > 1024 {
+		return false
+	}
+	b := make([]byte, size-8)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return false
+	}
+	for i := 0; i+4 <= len(b); i += 4 {
+		if i != 4 && string(b[i:i+4]) == brand { // b[4:8] is the minor version
+			return true
+		}
+	}
+	return false
+}
+
+// bmpSize reads the dimensions of a BMP from its info header; the height of a top-down bitmap is
+// stored negative. The old OS/2 header keeps them in 16 bits.
+func bmpSize(r io.Reader) (int, int, error) {
+	var h [26]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil || string(h[0:2]) != "BM" {
+		return 0, 0, ErrNoDimensions
+	}
+	if binary.LittleEndian.Uint32(h[14:]) == 12 {
+		return int(binary.LittleEndian.Uint16(h[18:])), int(binary.LittleEndian.Uint16(h[20:])), nil
+	}
+	w, ht := int32(binary.LittleEndian.Uint32(h[18:])), int32(binary.LittleEndian.Uint32(h[22:]))
+	if ht < 0 {
+		ht = -ht
+	}
+	if w <= 0 || ht == 0 {
+		return 0, 0, ErrNoDimensions
+	}
+	return int(w), int(ht), nil
+}
+
+// jxlBoxes walks the top-level boxes of a JPEG XL container after its signature, calling visit with
+// the type and payload size of each, the reader at the payload; visit returns whether to go on.
+// Payloads visit leaves unread are skipped.
+func jxlBoxes(r io.ReadSeeker, visit func(typ string, size int64) bool) error {
+	if _, err := r.Seek(int64(len(jxlSignature)), io.SeekStart); err != nil {
+		return err
+	}
+	var hdr [16]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:8]); err != nil {
+			return ErrNoContainerExif
+		}
+		size, typ, hdrLen := int64(binary.BigEndian.Uint32(hdr[:4])), string(hdr[4:8]), int64(8)
+		if size == 1 {
+			if _, err := io.ReadFull(r, hdr[8:16]); err != nil {
+				return ErrNoContainerExif
+			}
+			size, hdrLen = int64(binary.BigEndian.Uint64(hdr[8:16])), 16
+		}
+		start, _ := r.Seek(0, io.SeekCurrent)
+		if size == 0 { // the last box runs to the end of the file
+			end, err := r.Seek(0, io.SeekEnd)
+			if err != nil {
+				return err
+			}
+			size = end - start + hdrLen
+			r.Seek(start, io.SeekStart)
+		}
+		if size < hdrLen {
+			return ErrNoContainerExif
+		}
+		if !visit(typ, size-hdrLen) {
+			return nil
+		}
+		if _, err := r.Seek(start+size-hdrLen, io.SeekStart); err != nil {
+			return ErrNoContainerExif
+		}
+	}
+}
+
+// jxlExif returns the TIFF blob of the Exif box of a JPEG XL container. A Brotli-compressed one
+// (brob) is not read; a bare codestream has no boxes.
+func jxlExif(r io.ReadSeeker) ([]byte, error) {
+	var item []byte
+	err := jxlBoxes(r, func(typ string, size int64) bool {
+		if typ != "Exif" {
+			return true
+		}
+		if size < 4 || size > maxChunkSize {
+			return false
+		}
+		item = make([]byte, size)
+		if _, err := io.ReadFull(r, item); err != nil {
+			item = nil
+		}
+		return false
+	})
+	if item == nil {
+		if err == nil {
+			err = ErrNoContainerExif
+		}
+		return nil, err
+	}
+	// As in HEIF, the box starts with the offset of the TIFF header past its own four bytes.
+	start := 4 + uint64(binary.BigEndian.Uint32(item))
+	if start+8 > uint64(len(item)) {
+		return nil, ErrNoContainerExif
+	}
+	return item[start:], nil
+}
+
+// jxlSize reads the dimensions of a JPEG XL image from the size header of its codestream: the file
+// itself when bare, else the jxlc box or the first jxlp part of the container.
+func jxlSize(r io.ReadSeeker) (int, int, error) {
+	var head [12]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, 0, ErrNoDimensions
+	}
+	var cs [16]byte
+	switch {
+	case head[0] == 0xff && head[1] == 0x0a:
+		copy(cs[:], head[:])
+		io.ReadFull(r, cs[len(head):])
+	case bytes.HasPrefix(head[:], jxlSignature):
+		found := false
+		jxlBoxes(r, func(typ string, size int64) bool {
+			switch typ {
+			case "jxlp": // parts are numbered first
+				r.Seek(4, io.SeekCurrent)
+			case "jxlc":
+			default:
+				return true
+			}
+			n, _ := io.ReadFull(r, cs[:])
+			found = n >= 4
+			return false
+		})
+		if !found {
+			return 0, 0, ErrNoDimensions
+		}
+	default:
+		return 0, 0, ErrNoDimensions
+	}
+	if cs[0] != 0xff || cs[1] != 0x0a {
+		return 0, 0, ErrNoDimensions
+	}
+	br := bitReader{b: cs[2:]}
+	dim := func(small bool) uint32 {
+		if small {
+			return (br.read(5) + 1) * 8
+		}
+		return br.read([]int{9, 13, 18, 30}[br.read(2)]) + 1
+	}
+	small := br.read(1) == 1
+	h := dim(small)
+	ratio := br.read(3)
+	w := uint64(h)
+	if ratio == 0 {
+		w = uint64(dim(small))
+	} else {
+		// The fixed aspect ratios of the size header: 1:1, 12:10, 4:3, 3:2, 16:9, 5:4 and 2:1.
+		num := []uint64{1, 12, 4, 3, 16, 5, 2}[ratio-1]
+		den := []uint64{1, 10, 3, 2, 9, 4, 1}[ratio-1]
+		w = w * num / den
+	}
+	if br.short {
+		return 0, 0, ErrNoDimensions
+	}
+	return int(w), int(h), nil
+}
+
+// bitReader reads the bits of a JPEG XL header, least significant first. Reading past the end sets
+// short and returns zeros.
+type bitReader struct {
+	b     []byte
+	pos   int
+	short bool
+}
+
+func (br *bitReader) read(n int) uint32 {
+	var v uint32
+	for i := 0; i < n; i++ {
+		if br.pos/8 >= len(br.b) {
+			br.short = true
+			return 0
+		}
+		v |= uint32(br.b[br.pos/8]>>(br.pos%8)&1) << i
+		br.pos++
+	}
+	return v
+}
@@ -0,0 +1,55 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFormats(t *testing.T) {
+	// The fixtures: AVIF stills and sequences keep their Exif item as HEIF does, JPEG XL in an Exif
+	// box beside its codestream; GIF and BMP keep no date, so the name or the file dates tell it.
+	tests := []struct {
+		file, source, date, model string
+		w, h                      int
+		animated                  bool
+	}{
+		{"exif.avif", DateFromExif, "2022-08-14 18:45:10", "SM-S918B", 4032, 3024, false},
+		{"animated.avif", DateFromExif, "2021-12-31 23:59:58", "SM-S918B", 4032, 3024, true},
+		{"exif.jxl", DateFromExif, "2020-02-29 07:08:09", "Pixel 8", 40, 30, false},
+		{"IMG_20190304_101112.gif", DateFromFilename, "2019-03-04 10:11:12", "Unknown", 4, 2, false},
+		{"animated.gif", DateFromModTime, "", "Unknown", 4, 2, true},
+		{"2019-03-04 10.11.12.bmp", DateFromFilename, "2019-03-04 10:11:12", "Unknown", 3, 2, false},
+	}
+	for _, tt := range tests {
+		info, err := GetFileInfo(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Errorf("GetFileInfo(%s): %v", tt.file, err)
+			continue
+		}
+		if info.DateSource != tt.source || tt.date != "" && info.Date.Format("2006-01-02 15:04:05") != tt.date {
+			t.Errorf("%s dated %s by %s; want %s by %s", tt.file, info.Date, info.DateSource, tt.date, tt.source)
+		}
+		if info.Device != tt.model || info.Width != tt.w || info.Height != tt.h || info.Animated != tt.animated {
+			t.Errorf("%s: device %q, %dx%d, animated %v; want %q, %dx%d, %v", tt.file, info.Device, info.Width, info.Height, info.Animated, tt.model, tt.w, tt.h, tt.animated)
+		}
+	}
+}
+
+func TestSniffFormat(t *testing.T) {
+	tests := map[string]string{
+		"\xff\xd8\xff\xe1\x00\x10Exif\x00\x00":             formatJPEG,
+		"\x00\x00\x00\x18ftypheic\x00\x00\x00\x00":         formatHEIF,
+		"\x00\x00\x00\x18ftypavis\x00\x00\x00\x00":         formatAVIF,
+		"\x00\x00\x00\x0cJXL \x0d\x0a\x87\x0a":             formatJXL,
+		"\xff\x0a\xe8\x00\x4e\x00\x00\x00\x00\x00\x00\x00": formatJXL,
+		"GIF89a\x04\x00\x02\x00\x80\x00":                   formatGIF,
+		"BM\x4e\x00\x00\x00\x00\x00\x00\x00\x36\x00":       formatBMP,
+		"ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00":          "",
+		"GIF": "",
+	}
+	for head, want := range tests {
+		if got := sniffFormat([]byte(head)); got != want {
+			t.Errorf("sniffFormat(%q) = %q; want %q", head, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package metadata
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"lume-go/internal/fsys"
+	"lume-go/internal/ioutil"
+	"sync"
+)
+
+// Hash algorithms of EnsureHash. MD5 is the one duplicate checks, the target index and the catalog
+// compare; SHA-256 is the one of the SHA256SUMS lists.
+const (
+	HashMD5    = "md5"
+	HashSHA256 = "sha256"
+)
+
+var hashAlgos = map[string]func() hash.Hash{HashMD5: md5.New, HashSHA256: sha256.New}
+
+// hashes holds the digests EnsureHash computed for one file.
+type hashes struct {
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+// EnsureHash returns the digest of the file under algo ("" is HashMD5), reading it through fs (nil is
+// the real file system) only the first time: later calls reuse it, also on copies of info made after GetFileInfo. The MD5 is stored
+// in MD5 too. A value already in MD5, e.g. from the scan cache of an earlier run, is not trusted here
+// since it was not read now. A cancelled ctx stops the read and nothing is remembered. Calls may run
+// concurrently on an info from GetFileInfo.
+func (info *FileInfo) EnsureHash(ctx context.Context, fs fsys.FS, algo string) (string, error) {
+	if algo == "" {
+		algo = HashMD5
+	}
+	newHash, ok := hashAlgos[algo]
+	if !ok {
+		return "", fmt.Errorf("unknown hash algorithm %q", algo)
+	}
+	if info.hashes == nil {
+		info.hashes = &hashes{}
+	}
+	h := info.hashes
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if sum, ok := h.sums[algo]; ok {
+		return sum, nil
+	}
+	f, err := fsys.Or(fs).Open(info.Path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sum, err := ioutil.HashReader(ctx, f, newHash)
+	if err != nil {
+		return "", err
+	}
+	if h.sums == nil {
+		h.sums = make(map[string]string)
+	}
+	h.sums[algo] = sum
+	if algo == HashMD5 {
+		info.MD5 = sum
+	}
+	return sum, nil
+}
+
+// FreshHashes returns info with a memo of its own for EnsureHash, so no digest read through another
+// copy of it, e.g. the one the scan cache kept from an earlier run, is reused.
+func (info FileInfo) FreshHashes() FileInfo {
+	info.hashes = &hashes{}
+	return info
+}
@@ -0,0 +1,64 @@
+package metadata
+
+import (
+	"context"
+	"lume-go/internal/fsys"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// countingFS counts the files opened for reading.
+type countingFS struct {
+	fsys.FS
+	opens atomic.Int32
+}
+
+func (c *countingFS) Open(name string) (fsys.File, error) {
+	c.opens.Add(1)
+	return c.FS.Open(name)
+}
+
+func TestEnsureHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.jpg")
+	os.WriteFile(path, []byte("hello"), 0644)
+	info, err := GetFileInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := &countingFS{FS: fsys.OS}
+
+	// Copies of one info share the hash, whichever goroutine computes it.
+	var wg sync.WaitGroup
+	sums := make([]string, 8)
+	for i := range sums {
+		wg.Add(1)
+		go func(i int, info FileInfo) {
+			defer wg.Done()
+			sums[i], _ = info.EnsureHash(context.Background(), fs, HashMD5)
+		}(i, info)
+	}
+	wg.Wait()
+	for _, s := range sums {
+		if s != "5d41402abc4b2a76b9719d911017c592" {
+			t.Fatalf("EnsureHash = %q; want the MD5 of hello", s)
+		}
+	}
+	if sha, _ := info.EnsureHash(context.Background(), fs, HashSHA256); sha != "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824" {
+		t.Errorf("EnsureHash(sha256) = %q", sha)
+	}
+	if n := fs.opens.Load(); n != 2 {
+		t.Errorf("file opened %d times; want once per algorithm", n)
+	}
+
+	// A hash kept from elsewhere is not trusted, and an unknown algorithm fails.
+	stale := FileInfo{Path: path, MD5: "stale"}
+	if h, _ := stale.EnsureHash(context.Background(), nil, ""); h != sums[0] || stale.MD5 != h {
+		t.Errorf("EnsureHash over a kept MD5 = %q (MD5 %q); want %q", h, stale.MD5, sums[0])
+	}
+	if _, err := info.EnsureHash(context.Background(), nil, "crc32"); err == nil {
+		t.Error("EnsureHash(crc32) succeeded")
+	}
+}
@@ -0,0 +1,281 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrNotHEIF reports a file that is not an ISO-BMFF HEIF image or whose boxes cannot be parsed.
+var ErrNotHEIF = errors.New("not a HEIF image")
+
+// maxMetaSize bounds the meta box read into memory; real files keep it well under a megabyte.
+const maxMetaSize = 4 << 20
+
+// heifBrands are the ftyp brands of HEIF still images (heic, heif/hif and the generic mif1) and of
+// AVIF, which shares the structure.
+var heifBrands = map[string]bool{"heic": true, "heix": true, "heim": true, "heis": true, "mif1": true, "msf1": true, "avif": true, "avis": true}
+
+// box is one ISO-BMFF box; data is its payload.
+type box struct {
+	typ  string
+	data []byte
+}
+
+// parseBoxes splits b into consecutive boxes, stopping at the first malformed one.
+func parseBoxes(b []byte) []box {
+	var out []box
+	for len(b) >= 8 {
+		size, hdr := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return out
+			}
+			size, hdr = binary.BigEndian.Uint64(b[8:]), 16
+		}
+		if size < hdr || size > uint64(len(b)) {
+			return out
+		}
+		out = append(out, box{typ: string(b[4:8]), data: b[hdr:size]})
+		b = b[size:]
+	}
+	return out
+}
+
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, bx := range boxes {
+		if bx.typ == typ {
+			return bx, true
+		}
+	}
+	return box{}, false
+}
+
+// heifMeta checks the ftyp brands and returns the children of the top-level meta box.
+func heifMeta(r io.ReadSeeker) ([]box, error) {
+	var hdr [16]byte
+	branded := false
+	for {
+		if _, err := io.ReadFull(r, hdr[:8]); err != nil {
+			return nil, ErrNotHEIF
+		}
+		size, typ, hdrLen := int64(binary.BigEndian.Uint32(hdr[:4])), string(hdr[4:8]), int64(8)
+		if size == 1 {
+			if _, err := io.ReadFull(r, hdr[8:16]); err != nil {
+				return nil, ErrNotHEIF
+			}
+			size, hdrLen = int64(binary.BigEndian.Uint64(hdr[8:16])), 16
+		}
+		if size == 0 || size < hdrLen {
+			return nil, ErrNotHEIF // the meta box never runs to the end of the file
+		}
+		switch typ {
+		case "ftyp":
+			if size-hdrLen > 1024 {
+				return nil, ErrNotHEIF
+			}
+			b := make([]byte, size-hdrLen)
+			if _, err := io.ReadFull(r, b); err != nil || len(b) < 8 {
+				return nil, ErrNotHEIF
+			}
+			for i := 0; i+4 <= len(b); i += 4 {
+				if i != 4 && heifBrands[string(b[i:i+4])] { // b[4:8] is the minor version
+					branded = true
+				}
+			}
+		case "meta":
+			if !branded || size-hdrLen > maxMetaSize || size-hdrLen < 4 {
+				return nil, ErrNotHEIF
+			}
+			b := make([]byte, size-hdrLen)
+			if _, err := io.ReadFull(r, b); err != nil {
+				return nil, ErrNotHEIF
+			}
+			return parseBoxes(b[4:]), nil // meta is a full box: version and flags come first
+		default:
+			if _, err := r.Seek(size-hdrLen, io.SeekCurrent); err != nil {
+				return nil, ErrNotHEIF
+			}
+		}
+	}
+}
+
+// heifSize returns the largest image extent (ispe) in the meta box: the full picture rather than
+// its grid tiles or thumbnail.
+func heifSize(r io.ReadSeeker) (int, int, error) {
+	meta, err := heifMeta(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	iprp, ok := findBox(meta, "iprp")
+	if !ok {
+		return 0, 0, ErrNoDimensions
+	}
+	ipco, ok := findBox(parseBoxes(iprp.data), "ipco")
+	if !ok {
+		return 0, 0, ErrNoDimensions
+	}
+	w, h := 0, 0
+	for _, p := range parseBoxes(ipco.data) {
+		if p.typ == "ispe" && len(p.data) >= 12 {
+			pw, ph := int(binary.BigEndian.Uint32(p.data[4:])), int(binary.BigEndian.Uint32(p.data[8:]))
+			if pw*ph > w*h {
+				w, h = pw, ph
+			}
+		}
+	}
+	if w == 0 {
+		return 0, 0, ErrNoDimensions
+	}
+	return w, h, nil
+}
+
+// fields reads big-endian integers of 0, 2, 4 or 8 bytes from a box payload. Reading past the end
+// sets bad and returns 0, so a parse can check once at the end.
+type fields struct {
+	b   []byte
+	bad bool
+}
+
+func (f *fields) uint(n int) uint64 {
+	if n == 0 {
+		return 0
+	}
+	if len(f.b) < n {
+		f.bad = true
+		f.b = nil
+		return 0
+	}
+	var v uint64
+	for _, c := range f.b[:n] {
+		v = v<<8 | uint64(c)
+	}
+	f.b = f.b[n:]
+	return v
+}
+
+// heifExifID finds the item ID of the Exif item in iinf.
+func heifExifID(meta []box) (uint64, bool) {
+	iinf, ok := findBox(meta, "iinf")
+	if !ok || len(iinf.data) < 4 {
+		return 0, false
+	}
+	f := fields{b: iinf.data[4:]}
+	if iinf.data[0] == 0 {
+		f.uint(2)
+	} else {
+		f.uint(4)
+	}
+	for _, infe := range parseBoxes(f.b) {
+		if infe.typ != "infe" || len(infe.data) < 4 || infe.data[0] < 2 {
+			continue
+		}
+		e := fields{b: infe.data[4:]}
+		id := e.uint(2)
+		if infe.data[0] >= 3 {
+			id = id<<16 | e.uint(2)
+		}
+		e.uint(2)                                          // item_protection_index
+		if typ := e.uint(4); !e.bad && typ == 0x45786966 { // "Exif"
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// extent is one piece of an item: in the file, or in the idat box when inIdat is set.
+type extent struct {
+	offset, length uint64
+	inIdat         bool
+}
+
+// heifExtents reads the location of item id from iloc (versions 0 to 2).
+func heifExtents(meta []box, id uint64) ([]extent, bool) {
+	iloc, ok := findBox(meta, "iloc")
+	if !ok || len(iloc.data) < 6 {
+		return nil, false
+	}
+	version := iloc.data[0]
+	f := fields{b: iloc.data[4:]}
+	sizes := f.uint(2)
+	offSize, lenSize, baseSize, idxSize := int(sizes>>12), int(sizes>>8&0xf), int(sizes>>4&0xf), 0
+	if version == 1 || version == 2 {
+		idxSize = int(sizes & 0xf)
+	}
+	idLen := 2
+	if version == 2 {
+		idLen = 4
+	}
+	count := f.uint(idLen)
+	for i := uint64(0); i < count && !f.bad; i++ {
+		itemID, method := f.uint(idLen), uint64(0)
+		if version == 1 || version == 2 {
+			method = f.uint(2) & 0xf
+		}
+		f.uint(2) // data_reference_index
+		base := f.uint(baseSize)
+		n := f.uint(2)
+		var out []extent
+		for j := uint64(0); j < n && !f.bad; j++ {
+			f.uint(idxSize)
+			off, length := f.uint(offSize), f.uint(lenSize)
+			out = append(out, extent{offset: base + off, length: length, inIdat: method == 1})
+		}
+		if itemID == id {
+			// Only file offsets and idat are supported; item references (method 2) are not.
+			return out, !f.bad && method <= 1 && len(out) > 0
+		}
+	}
+	return nil, false
+}
+
+// heifExif returns the TIFF blob of the Exif item of a HEIF file, located through the iinf and
+// iloc tables. Items split into several extents, in the file or in idat, are joined.
+func heifExif(r interface {
+	io.ReadSeeker
+	io.ReaderAt
+}) ([]byte, error) {
+	meta, err := heifMeta(r)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := heifExifID(meta)
+	if !ok {
+		return nil, ErrNotHEIF
+	}
+	extents, ok := heifExtents(meta, id)
+	if !ok {
+		return nil, ErrNotHEIF
+	}
+	idat, _ := findBox(meta, "idat")
+	var item []byte
+	for _, e := range extents {
+		if e.length == 0 || e.length > maxMetaSize || uint64(len(item))+e.length > maxMetaSize {
+			return nil, ErrNotHEIF
+		}
+		if e.inIdat {
+			if n := uint64(len(idat.data)); e.offset > n || e.length > n-e.offset {
+				return nil, ErrNotHEIF
+			}
+			item = append(item, idat.data[e.offset:e.offset+e.length]...)
+			continue
+		}
+		b := make([]byte, e.length)
+		if _, err := r.ReadAt(b, int64(e.offset)); err != nil {
+			return nil, ErrNotHEIF
+		}
+		item = append(item, b...)
+	}
+	// The item starts with the offset of the TIFF header, which usually follows an "Exif\0\0" marker.
+	if len(item) < 4 {
+		return nil, ErrNotHEIF
+	}
+	start := 4 + uint64(binary.BigEndian.Uint32(item))
+	if start+8 > uint64(len(item)) {
+		return nil, ErrNotHEIF
+	}
+	return item[start:], nil
+}
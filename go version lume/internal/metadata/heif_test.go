@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// mkbox builds an ISO-BMFF box from its payload parts.
+func mkbox(typ string, parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	copy(b[4:], typ)
+	return append(b, payload...)
+}
+
+func u32(vs ...uint32) []byte {
+	b := make([]byte, 4*len(vs))
+	for i, v := range vs {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// ispe is an image extent property: version/flags, width, height.
+func ispe(w, h uint32) []byte { return mkbox("ispe", u32(0, w, h)) }
+
+func TestHEIFSize(t *testing.T) {
+	dir := t.TempDir()
+	ftyp := mkbox("ftyp", []byte("heic"), u32(0), []byte("mif1heic"))
+	meta := mkbox("meta", u32(0), mkbox("hdlr", u32(0, 0), []byte("pict")),
+		mkbox("iprp", mkbox("ipco", ispe(512, 512), ispe(4032, 3024), ispe(320, 240))))
+
+	files := map[string][]byte{
+		"grid.heic":      append(append(ftyp, mkbox("free", make([]byte, 100))...), meta...),
+		"brand.hif":      append(mkbox("ftyp", []byte("mif1"), u32(0), []byte("mif1")), meta...),
+		"not-heif.heic":  append(mkbox("ftyp", []byte("isom"), u32(0), []byte("isom")), meta...),
+		"no-extent.heic": append(ftyp, mkbox("meta", u32(0), mkbox("iprp", mkbox("ipco")))...),
+		"truncated.heic": append(ftyp, meta[:20]...),
+	}
+	for name, data := range files {
+		os.WriteFile(filepath.Join(dir, name), data, 0644)
+	}
+	tests := []struct {
+		name string
+		w, h int
+	}{
+		{"grid.heic", 4032, 3024}, // the full picture wins over its tiles and thumbnail
+		{"brand.hif", 4032, 3024},
+		{"not-heif.heic", 0, 0},
+		{"no-extent.heic", 0, 0},
+		{"truncated.heic", 0, 0},
+	}
+	for _, tt := range tests {
+		w, h, err := ImageSize(filepath.Join(dir, tt.name))
+		if w != tt.w || h != tt.h || (err == nil) != (tt.w > 0) {
+			t.Errorf("ImageSize(%s) = %d, %d, %v; want %d, %d", tt.name, w, h, err, tt.w, tt.h)
+		}
+	}
+	if mp := (FileInfo{Width: 4032, Height: 3024}).Megapixels(); mp < 12.19 || mp > 12.2 {
+		t.Errorf("Megapixels = %v; want 12.19", mp)
+	}
+}
+
+func TestHEIFExif(t *testing.T) {
+	// The fixtures are hand-built HEIF headers with a tiny big-endian TIFF Exif item:
+	// exif_extents.heic has the item in mdat split over two extents with junk between them,
+	// exif_idat.hif (mif1 brand, 32-bit iinf count) keeps it in the idat box.
+	tests := []struct {
+		file, date, model string
+	}{
+		{"exif_extents.heic", "2023-10-20 15:04:05", "iPhone 15 Pro"},
+		{"exif_idat.hif", "2024-05-12 09:30:00", "ILCE-7M4"},
+	}
+	for _, tt := range tests {
+		date, model, err := ExtractExif(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Errorf("%s: %v", tt.file, err)
+			continue
+		}
+		if date == nil || date.Format("2006-01-02 15:04:05") != tt.date || model != tt.model {
+			t.Errorf("%s: date %v, model %q; want %s, %q", tt.file, date, model, tt.date, tt.model)
+		}
+	}
+
+	// A damaged item table falls back to go-exif's byte search, which finds the contiguous idat payload.
+	data, _ := os.ReadFile(filepath.Join("testdata", "exif_idat.hif"))
+	p := filepath.Join(t.TempDir(), "broken.hif")
+	os.WriteFile(p, bytes.Replace(data, []byte("iloc"), []byte("xloc"), 1), 0644)
+	f, _ := os.Open(p)
+	defer f.Close()
+	if _, err := heifExif(f); err == nil {
+		t.Error("heifExif accepted a file without iloc")
+	}
+	if date, _, err := ExtractExif(p); err != nil || date == nil || date.Day() != 12 {
+		t.Errorf("fallback: %v, %v", date, err)
+	}
+}
+
+// An idat extent whose offset and length wrap around is refused instead of slicing past idat.
+func TestHEIFExifIdatOverflow(t *testing.T) {
+	infe := mkbox("infe", []byte{2, 0, 0, 0, 0, 7, 0, 0}, []byte("Exif\x00"))
+	iloc := mkbox("iloc", []byte{1, 0, 0, 0, 0x84, 0x00}, []byte{0, 1, 0, 7, 0, 1, 0, 0, 0, 1},
+		u32(0xFFFFFFFF, 0xFFFFFFF0), u32(0x20))
+	meta := mkbox("meta", u32(0), mkbox("hdlr", u32(0, 0), []byte("pict")),
+		mkbox("iinf", u32(0x01000000, 1), infe), iloc, mkbox("idat", make([]byte, 64)))
+	data := append(mkbox("ftyp", []byte("mif1"), u32(0), []byte("mif1")), meta...)
+	if _, err := heifExif(bytes.NewReader(data)); err != ErrNotHEIF {
+		t.Errorf("heifExif = %v; want ErrNotHEIF", err)
+	}
+}
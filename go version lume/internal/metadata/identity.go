@@ -0,0 +1,23 @@
+package metadata
+
+import "fmt"
+
+// FileIdentity names a file independently of the path used to reach it: the volume serial and file
+// index on Windows, device and inode elsewhere. Hardlinks, subst drives and mapped letters of the
+// same file share one identity.
+type FileIdentity struct {
+	Volume uint64
+	Index  uint64
+}
+
+func (id FileIdentity) String() string { return fmt.Sprintf("%x:%x", id.Volume, id.Index) }
+
+// SameIdentity reports whether a and b are the same physical file. Unreadable paths never match.
+func SameIdentity(a, b string) bool {
+	ia, err := GetFileIdentity(a)
+	if err != nil {
+		return false
+	}
+	ib, err := GetFileIdentity(b)
+	return err == nil && ia == ib
+}
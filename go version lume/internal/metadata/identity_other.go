@@ -0,0 +1,22 @@
+//go:build !windows
+
+package metadata
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// GetFileIdentity reads the device and inode of path.
+func GetFileIdentity(path string) (FileIdentity, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return FileIdentity{}, fmt.Errorf("no file identity for %s", path)
+	}
+	return FileIdentity{Volume: uint64(st.Dev), Index: uint64(st.Ino)}, nil
+}
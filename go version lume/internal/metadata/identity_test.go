@@ -0,0 +1,29 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileIdentity(t *testing.T) {
+	dir := t.TempDir()
+	a, b, c := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg"), filepath.Join(dir, "c.jpg")
+	os.WriteFile(a, []byte("same"), 0644)
+	os.WriteFile(c, []byte("same"), 0644)
+	if err := os.Link(a, b); err != nil {
+		t.Skipf("hardlinks unavailable: %v", err)
+	}
+	if !SameIdentity(a, b) {
+		t.Error("a hardlink is not the same file")
+	}
+	if !SameIdentity(a, filepath.Join(dir, ".", "sub", "..", "a.jpg")) {
+		t.Error("another spelling of the path is not the same file")
+	}
+	if SameIdentity(a, c) {
+		t.Error("an identical copy counts as the same file")
+	}
+	if SameIdentity(a, filepath.Join(dir, "missing.jpg")) {
+		t.Error("a missing file matched")
+	}
+}
@@ -0,0 +1,23 @@
+package metadata
+
+import "syscall"
+
+// GetFileIdentity reads the identity of path from BY_HANDLE_FILE_INFORMATION. The handle is opened
+// without data access, so cloud placeholders are not downloaded.
+func GetFileIdentity(path string) (FileIdentity, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	share := uint32(syscall.FILE_SHARE_READ | syscall.FILE_SHARE_WRITE | syscall.FILE_SHARE_DELETE)
+	h, err := syscall.CreateFile(p, 0, share, nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return FileIdentity{}, err
+	}
+	defer syscall.CloseHandle(h)
+	var d syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(h, &d); err != nil {
+		return FileIdentity{}, err
+	}
+	return FileIdentity{Volume: uint64(d.VolumeSerialNumber), Index: uint64(d.FileIndexHigh)<<32 | uint64(d.FileIndexLow)}, nil
+}
@@ -0,0 +1,68 @@
+package metadata
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// jpegHeaderLimit is how far into a JPEG the segment walker reads; Exif lives in APP1 right after
+// SOI, and cameras keep the whole header well under this.
+var jpegHeaderLimit int64 = 512 << 10
+
+var exifMarker = []byte("Exif\x00\x00")
+
+// jpegExif walks the JPEG marker segments up to the start of scan and returns the TIFF data of the
+// APP1 Exif segment. XMP or other APP1 segments before it are skipped, nothing past jpegHeaderLimit
+// is read.
+func jpegExif(r io.Reader) ([]byte, error) {
+	br := bufio.NewReaderSize(io.LimitReader(r, jpegHeaderLimit), 64<<10)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil || soi[0] != 0xff || soi[1] != 0xd8 {
+		return nil, ErrNoContainerExif
+	}
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, ErrNoContainerExif
+		}
+		if b != 0xff {
+			return nil, ErrNoContainerExif
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xff { // fill bytes
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			return nil, ErrNoContainerExif
+		}
+		switch {
+		case marker == 0xd8 || marker == 0x01 || (marker >= 0xd0 && marker <= 0xd7):
+			continue // no length
+		case marker == 0xda || marker == 0xd9: // start of scan, end of image
+			return nil, ErrNoContainerExif
+		}
+		var lb [2]byte
+		if _, err := io.ReadFull(br, lb[:]); err != nil {
+			return nil, ErrNoContainerExif
+		}
+		n := int(binary.BigEndian.Uint16(lb[:])) - 2
+		if n < 0 {
+			return nil, ErrNoContainerExif
+		}
+		if marker != 0xe1 {
+			if _, err := br.Discard(n); err != nil {
+				return nil, ErrNoContainerExif
+			}
+			continue
+		}
+		seg := make([]byte, n)
+		if _, err := io.ReadFull(br, seg); err != nil {
+			return nil, ErrNoContainerExif
+		}
+		if bytes.HasPrefix(seg, exifMarker) {
+			return seg[len(exifMarker):], nil
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dsoprea/go-exif/v3"
+)
+
+// sampleTIFF borrows the Exif blob of the WebP fixture (SM-S918B, 2023-07-01 12:00:00).
+func sampleTIFF(tb testing.TB) []byte {
+	f, err := os.Open(filepath.Join("testdata", "exif.webp"))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	raw, err := webpExif(f)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return raw
+}
+
+// buildJPEG lays out SOI, the given APPn segments, a start of scan and scan bytes of the given size.
+func buildJPEG(scan int, segments ...[]byte) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0xff, 0xd8})
+	for _, s := range segments {
+		b.Write(s)
+	}
+	b.Write([]byte{0xff, 0xda, 0x00, 0x02})
+	b.Write(make([]byte, scan))
+	b.Write([]byte{0xff, 0xd9})
+	return b.Bytes()
+}
+
+func segment(marker byte, payload []byte) []byte {
+	s := []byte{0xff, marker, 0, 0}
+	binary.BigEndian.PutUint16(s[2:], uint16(len(payload)+2))
+	return append(s, payload...)
+}
+
+func TestJPEGExif(t *testing.T) {
+	tiff := sampleTIFF(t)
+	jfif := segment(0xe0, []byte("JFIF\x00\x01\x02\x00\x00\x01\x00\x01\x00\x00"))
+	xmp := segment(0xe1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta/>"))
+	app1 := segment(0xe1, append([]byte("Exif\x00\x00"), tiff...))
+	dir := t.TempDir()
+	tests := []struct {
+		name  string
+		data  []byte
+		model string
+	}{
+		{"jfif then exif", buildJPEG(16, jfif, app1), "SM-S918B"},
+		{"xmp before exif", buildJPEG(16, xmp, app1), "SM-S918B"},
+		{"fill bytes", buildJPEG(16, jfif, []byte{0xff}, app1), "SM-S918B"},
+		{"no exif", buildJPEG(16, jfif), ""},
+		{"exif after scan", append(buildJPEG(16, jfif), app1...), ""},
+		{"truncated", buildJPEG(0, jfif, app1)[:40], ""},
+	}
+	for _, tt := range tests {
+		raw, err := jpegExif(bytes.NewReader(tt.data))
+		if tt.model == "" {
+			if err == nil {
+				t.Errorf("%s: found %d bytes of Exif", tt.name, len(raw))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		path := filepath.Join(dir, tt.name+".jpg")
+		if err := os.WriteFile(path, tt.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		date, model, err := ExtractExif(path)
+		if err != nil || model != tt.model || date == nil || date.Format("2006-01-02 15:04:05") != "2023-07-01 12:00:00" {
+			t.Errorf("%s: ExtractExif = %v, %q, %v", tt.name, date, model, err)
+		}
+	}
+
+	// Nothing past the limit is read, so an Exif segment beyond it is left to the slow path.
+	defer func(n int64) { jpegHeaderLimit = n }(jpegHeaderLimit)
+	jpegHeaderLimit = 1 << 10
+	if _, err := jpegExif(bytes.NewReader(buildJPEG(16, segment(0xe2, make([]byte, 4<<10)), app1))); err == nil {
+		t.Error("Exif beyond jpegHeaderLimit was read")
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// BenchmarkJPEGExif compares the segment walker with go-exif's byte search over a directory of
+// camera-sized JPEGs (an ICC profile ahead of the Exif segment, 4 MB of scan data), reporting the
+// bytes each reads per file.
+func BenchmarkJPEGExif(b *testing.B) {
+	tiff := sampleTIFF(b)
+	dir := b.TempDir()
+	var paths []string
+	for i := 0; i < 8; i++ {
+		data := buildJPEG(4<<20, segment(0xe0, []byte("JFIF\x00")), segment(0xe2, make([]byte, 60<<10)), segment(0xe1, append([]byte("Exif\x00\x00"), tiff...)))
+		path := filepath.Join(dir, filepath.Base(b.Name())+string(rune('a'+i))+".jpg")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			b.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+	run := func(b *testing.B, extract func(io.Reader) ([]byte, error)) {
+		var read int64
+		for i := 0; i < b.N; i++ {
+			f, err := os.Open(paths[i%len(paths)])
+			if err != nil {
+				b.Fatal(err)
+			}
+			c := &countingReader{r: f}
+			raw, err := extract(c)
+			f.Close()
+			if err != nil || len(raw) == 0 {
+				b.Fatalf("no Exif: %v", err)
+			}
+			read += c.n
+		}
+		b.ReportMetric(float64(read)/float64(b.N), "bytes-read/op")
+	}
+	b.Run("segments", func(b *testing.B) { run(b, jpegExif) })
+	b.Run("search", func(b *testing.B) { run(b, exif.SearchAndExtractExifWithReader) })
+}
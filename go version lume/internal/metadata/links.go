@@ -0,0 +1,160 @@
+package metadata
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Link policies decide what happens to symbolic links, junctions and mount points met in a drop.
+const (
+	// LinkSkip leaves every link alone. It is the default.
+	LinkSkip = "skip"
+	// LinkFollowFiles archives file links like regular files (the link itself moves) but never
+	// descends into linked folders.
+	LinkFollowFiles = "follow_files"
+	// LinkDereference archives the file a link points to and scans linked folders, once each.
+	LinkDereference = "dereference"
+)
+
+// LinkPolicies lists the policies in display order.
+var LinkPolicies = []string{LinkSkip, LinkFollowFiles, LinkDereference}
+
+// ErrLink reports a link left out under the active policy.
+var ErrLink = errors.New("symbolic link or junction")
+
+// Reparse tags of the Windows reparse points that behave like links. Other reparse points, such as
+// OneDrive placeholders or deduplicated files, are ordinary files to Lume.
+const (
+	reparseTagMountPoint = 0xA0000003
+	reparseTagSymlink    = 0xA000000C
+)
+
+// fileAttributeReparsePoint is FILE_ATTRIBUTE_REPARSE_POINT.
+const fileAttributeReparsePoint = 0x400
+
+// IsLink reports whether the entry at path, with fi from os.Lstat, is a symbolic link or a junction.
+func IsLink(path string, fi os.FileInfo) bool {
+	if fi.Mode()&os.ModeSymlink != 0 {
+		return true
+	}
+	// Recent Go versions report junctions as irregular files rather than symlinks, so ask for the tag.
+	if attr, ok := winAttributes(fi); ok && attr&fileAttributeReparsePoint != 0 {
+		switch reparseTag(path) {
+		case reparseTagMountPoint, reparseTagSymlink:
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveLink applies policy to the file at path. It returns the path to archive: path itself for
+// regular files and followed file links, the link target when dereferencing. Links the policy leaves
+// out return an error wrapping ErrLink.
+func ResolveLink(path, policy string) (string, error) {
+	lst, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if !IsLink(path, lst) {
+		return path, nil
+	}
+	switch policy {
+	case LinkFollowFiles:
+		if st, err := os.Stat(path); err == nil && !st.IsDir() {
+			return path, nil
+		}
+	case LinkDereference:
+		if real, err := filepath.EvalSymlinks(path); err == nil {
+			return real, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrLink, path)
+}
+
+// ScanOptions tunes ScanDir.
+type ScanOptions struct {
+	// Links is the link policy; empty or unknown means LinkSkip.
+	Links string
+	// SkipDir, when set, keeps the scan out of the folders it returns true for.
+	SkipDir func(dir string) bool
+	// OnLink is told about every link the policy leaves out.
+	OnLink func(path string)
+	// SkipHidden leaves out hidden files and folders (see IsHidden); OnHidden is told about each.
+	SkipHidden bool
+	OnHidden   func(path string)
+}
+
+// ScanDir calls visit for every supported file under root. Links are handled by opts.Links; every
+// real folder is entered at most once, so links pointing back up the tree cannot loop.
+func ScanDir(root string, opts ScanOptions, visit func(path string)) {
+	visited := make(map[string]bool)
+	skipped := func(p string) {
+		if opts.OnLink != nil {
+			opts.OnLink(p)
+		}
+	}
+	var scan func(dir string)
+	scan = func(dir string) {
+		if opts.SkipDir != nil && opts.SkipDir(dir) {
+			return
+		}
+		key := dir
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			key = real
+		}
+		if runtime.GOOS == "windows" {
+			key = strings.ToLower(key)
+		}
+		if visited[key] {
+			return
+		}
+		visited[key] = true
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			p := filepath.Join(dir, e.Name())
+			lst, err := os.Lstat(p)
+			if err != nil {
+				continue
+			}
+			if opts.SkipHidden && IsHidden(p, lst) {
+				if opts.OnHidden != nil {
+					opts.OnHidden(p)
+				}
+				continue
+			}
+			if IsLink(p, lst) {
+				st, err := os.Stat(p)
+				switch {
+				case err != nil: // dangling
+					skipped(p)
+				case st.IsDir() && opts.Links == LinkDereference:
+					scan(p)
+				case st.IsDir() || (opts.Links != LinkFollowFiles && opts.Links != LinkDereference):
+					skipped(p)
+				case SupportedExtensions[strings.ToLower(filepath.Ext(p))]:
+					visit(p)
+				}
+				continue
+			}
+			if lst.IsDir() {
+				scan(p)
+			} else if SupportedExtensions[strings.ToLower(filepath.Ext(p))] {
+				visit(p)
+			}
+		}
+	}
+
+	if lst, err := os.Lstat(root); err == nil && IsLink(root, lst) && opts.Links != LinkDereference {
+		skipped(root)
+		return
+	}
+	scan(root)
+}
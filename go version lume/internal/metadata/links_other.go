@@ -0,0 +1,10 @@
+//go:build !windows
+
+package metadata
+
+import "os"
+
+// Only Windows has reparse points; elsewhere os.ModeSymlink covers every link.
+func winAttributes(os.FileInfo) (uint32, bool) { return 0, false }
+
+func reparseTag(string) uint32 { return 0 }
@@ -0,0 +1,113 @@
+package metadata
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// linkTree builds root/photos/a.jpg, a link to it, a dangling link and a folder link back to root.
+func linkTree(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	photos := filepath.Join(root, "photos")
+	os.MkdirAll(photos, 0755)
+	os.WriteFile(filepath.Join(photos, "a.jpg"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(photos, "notes.txt"), []byte("n"), 0644)
+	for link, to := range map[string]string{
+		filepath.Join(photos, "b.jpg"):    filepath.Join(photos, "a.jpg"),
+		filepath.Join(photos, "gone.jpg"): filepath.Join(root, "missing.jpg"),
+		filepath.Join(photos, "loop"):     root,
+	} {
+		if err := os.Symlink(to, link); err != nil {
+			t.Skip("symlinks unavailable:", err)
+		}
+	}
+	return root
+}
+
+func TestScanDirLinkPolicies(t *testing.T) {
+	root := linkTree(t)
+	rel := func(paths []string) string {
+		for i, p := range paths {
+			paths[i], _ = filepath.Rel(root, p)
+			paths[i] = filepath.ToSlash(paths[i])
+		}
+		sort.Strings(paths)
+		return strings.Join(paths, " ")
+	}
+	tests := []struct {
+		policy        string
+		files, linked string
+	}{
+		{"", "photos/a.jpg", "photos/b.jpg photos/gone.jpg photos/loop"},
+		{LinkFollowFiles, "photos/a.jpg photos/b.jpg", "photos/gone.jpg photos/loop"},
+		// The folder link leads back to root, which was already scanned, so nothing repeats.
+		{LinkDereference, "photos/a.jpg photos/b.jpg", "photos/gone.jpg"},
+	}
+	for _, tt := range tests {
+		var files, linked []string
+		ScanDir(root, ScanOptions{Links: tt.policy, OnLink: func(p string) { linked = append(linked, p) }}, func(p string) { files = append(files, p) })
+		if got := rel(files); got != tt.files {
+			t.Errorf("%q: files = %s; want %s", tt.policy, got, tt.files)
+		}
+		if got := rel(linked); got != tt.linked {
+			t.Errorf("%q: links = %s; want %s", tt.policy, got, tt.linked)
+		}
+	}
+
+	var files []string
+	ScanDir(root, ScanOptions{SkipDir: func(d string) bool { return filepath.Base(d) == "photos" }}, func(p string) { files = append(files, p) })
+	if len(files) != 0 {
+		t.Errorf("SkipDir ignored: %v", files)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	root := linkTree(t)
+	a, b := filepath.Join(root, "photos", "a.jpg"), filepath.Join(root, "photos", "b.jpg")
+	realA, _ := filepath.EvalSymlinks(a)
+
+	if _, err := GetFileInfo(b); !errors.Is(err, ErrLink) {
+		t.Errorf("GetFileInfo(link) = %v; want ErrLink", err)
+	}
+	if p, err := ResolveLink(b, LinkFollowFiles); err != nil || p != b {
+		t.Errorf("follow: %q, %v", p, err)
+	}
+	if p, err := ResolveLink(b, LinkDereference); err != nil || p != realA {
+		t.Errorf("dereference: %q, %v", p, err)
+	}
+	if _, err := ResolveLink(filepath.Join(root, "photos", "loop"), LinkFollowFiles); !errors.Is(err, ErrLink) {
+		t.Errorf("follow must not accept folder links: %v", err)
+	}
+	if p, err := ResolveLink(a, LinkSkip); err != nil || p != a {
+		t.Errorf("regular file: %q, %v", p, err)
+	}
+}
+
+func TestScanDirHidden(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, ".thumbnails"), 0755)
+	os.WriteFile(filepath.Join(root, ".thumbnails", "t.jpg"), []byte("t"), 0644)
+	os.WriteFile(filepath.Join(root, ".hidden.jpg"), []byte("h"), 0644)
+	os.WriteFile(filepath.Join(root, "a.jpg"), []byte("a"), 0644)
+
+	for _, skip := range []bool{true, false} {
+		var files, hidden []string
+		ScanDir(root, ScanOptions{SkipHidden: skip, OnHidden: func(p string) { hidden = append(hidden, p) }}, func(p string) { files = append(files, p) })
+		// The hidden folder is reported once, not per file inside it.
+		wantFiles, wantHidden := 3, 0
+		if skip {
+			wantFiles, wantHidden = 1, 2
+		}
+		if len(files) != wantFiles || len(hidden) != wantHidden {
+			t.Errorf("SkipHidden=%v: files %v, hidden %v", skip, files, hidden)
+		}
+	}
+	if st, _ := os.Lstat(filepath.Join(root, "a.jpg")); IsHidden(filepath.Join(root, "a.jpg"), st) || IsPlaceholder(st) {
+		t.Error("a regular file is reported hidden or as a placeholder")
+	}
+}
@@ -0,0 +1,28 @@
+package metadata
+
+import (
+	"os"
+	"syscall"
+)
+
+func winAttributes(fi os.FileInfo) (uint32, bool) {
+	if d, ok := fi.Sys().(*syscall.Win32FileAttributeData); ok {
+		return d.FileAttributes, true
+	}
+	return 0, false
+}
+
+// reparseTag reads the reparse tag of path; FindFirstFile reports it in Reserved0.
+func reparseTag(path string) uint32 {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0
+	}
+	var fd syscall.Win32finddata
+	h, err := syscall.FindFirstFile(p, &fd)
+	if err != nil {
+		return 0
+	}
+	syscall.FindClose(h)
+	return fd.Reserved0
+}
@@ -1,14 +1,15 @@
 package metadata
 
 import (
+	"context"
 	"crypto/md5"
-	"encoding/hex"
+	"errors"
 	"fmt"
-	"io"
+	"lume-go/internal/ioutil"
+	"lume-go/internal/logger"
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/dsoprea/go-exif/v3"
@@ -21,44 +22,117 @@ var SupportedExtensions = map[string]bool{
 	".png":  true,
 	".webp": true,
 	".heic": true,
+	".heif": true,
+	".hif":  true,
 	".tiff": true,
+	".gif":  true,
+	".bmp":  true,
+	".avif": true,
+	".jxl":  true,
 	".mp4":  true,
 	".mov":  true,
+	".3gp":  true,
 	".avi":  true,
+	".mkv":  true,
+	".webm": true,
 }
 
 // FileInfo carries the metadata extracted from a file.
 type FileInfo struct {
-	Path     string
-	Filename string
-	Size     int64
-	ModTime  time.Time
-	Date     time.Time
-	Year     string
-	Month    string
-	Device   string
-	Source   string
-	MD5      string
+	Path       string
+	Filename   string
+	Size       int64
+	ModTime    time.Time
+	Date       time.Time
+	DateSource string
+	Year       string
+	Month      string
+	Day        string
+	Week       string // ISO week, W01..W53
+	WeekYear   string // ISO week-numbering year; differs from Year around New Year
+	Device     string
+	Source     string
+	// MD5 is the content hash once EnsureHash or the scan cache computed it; empty until then.
+	MD5        string
+	hashes     *hashes
+	// Placeholder marks an online-only cloud file; reading its content downloads it.
+	Placeholder bool
+	// Width and Height are the pixel dimensions read from the image header; 0 when unknown.
+	Width, Height int
+	// Animated marks an animated GIF or an AVIF image sequence (see Animated), to tell them from stills.
+	Animated bool
+	// Sidecars are the camcorder files written beside a video (see Sidecars), moved along with it.
+	Sidecars []string
+	// Burst names the continuous-shooting group the photo belongs to, e.g. Burst_20240512_143022; empty otherwise.
+	Burst string
+	// Seq numbers the photos of one device shot within the same second, from 1 in shooting order (see
+	// organizer.MarkSequences); 0 until numbered.
+	Seq int
+	// Pooled files go to the shared Other folder of their month instead of a folder of their device.
+	Pooled bool
+	// RejectedDate is an implausible date the file carried (see Plausible) and RejectedSource where it
+	// came from; Date and DateSource then hold the fallback that was used instead.
+	RejectedDate   time.Time
+	RejectedSource string
+	// MetadataNote tells why an image was not dated by its EXIF date (NoteNoExif, NoteCorruptExif,
+	// NoteNoDateTag, NoteBadDate); empty when it was, and for videos and placeholders.
+	MetadataNote string
+	// Created is the creation time the file system keeps, zero when unknown; with ModTime it is what
+	// MarkDateSuspects checks the file's own date against.
+	Created time.Time
+	// ClockOffset is the correction a clock offset moved Date by (see Clocks); zero when none applied.
+	ClockOffset time.Duration
+	// DateSuspect marks an own date Skew away from the file times where its device's other files agree
+	// with theirs (see MarkDateSuspects). The file is filed by it all the same.
+	DateSuspect bool
+	Skew        time.Duration
+	// ShiftedFrom is the date a Shift moved Date from, zero when none did; ExifWritten marks a JPEG
+	// whose DateTimeOriginal holds the shifted date already, ExifDate the date to write into the
+	// archived copy of one whose does not (see ShiftDate).
+	ShiftedFrom time.Time
+	ExifWritten bool
+	ExifDate    time.Time
+	// DerivativeOf is the original an edited copy was made from (see Derivatives): its path in the run,
+	// or where the archive holds it. Beside is the folder below the target the original is filed in,
+	// where the copy goes too, "." for the target itself. Both are empty for other files.
+	DerivativeOf string
+	Beside       string
 }
 
+// Where FileInfo.Date came from. Only an EXIF date or a video's own recording date survives copying
+// the file around; the recording date of a video includes the one its camcorder sidecar holds.
+const (
+	DateFromExif     = "exif"
+	DateFromVideo    = "video"
+	DateFromCreation = "created"
+	DateFromModTime  = "modified"
+)
+
 // GetFileHash calculates the MD5 hash of a file using streaming.
 func GetFileHash(path string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	hasher := md5.New()
-	if _, err := io.Copy(hasher, f); err != nil {
-		return "", err
-	}
+	return GetFileHashContext(context.Background(), path)
+}
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+// GetFileHashContext is GetFileHash stopping with ctx's error when ctx is done.
+func GetFileHashContext(ctx context.Context, path string) (string, error) {
+	return ioutil.HashContext(ctx, path, md5.New)
 }
 
-// GetFileInfo gathers basic file information and extracts EXIF metadata.
+// ErrUnsupportedType reports a file whose extension Lume does not archive.
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// GetFileInfo gathers basic file information and extracts EXIF metadata. Links are skipped.
 func GetFileInfo(path string) (FileInfo, error) {
+	return GetFileInfoLinks(path, LinkSkip)
+}
+
+// GetFileInfoLinks is GetFileInfo under the given link policy (see ResolveLink). When a link is
+// dereferenced, Path is the file it points to.
+func GetFileInfoLinks(path, policy string) (FileInfo, error) {
+	path, err := ResolveLink(path, policy)
+	if err != nil {
+		return FileInfo{}, err
+	}
 	stat, err := os.Stat(path)
 	if err != nil {
 		return FileInfo{}, err
@@ -66,7 +140,7 @@ func GetFileInfo(path string) (FileInfo, error) {
 
 	ext := strings.ToLower(filepath.Ext(path))
 	if !SupportedExtensions[ext] {
-		return FileInfo{}, fmt.Errorf("unsupported extension: %s", ext)
+		return FileInfo{}, fmt.Errorf("%w: %s", ErrUnsupportedType, ext)
 	}
 
 	info := FileInfo{
@@ -74,67 +148,186 @@ func GetFileInfo(path string) (FileInfo, error) {
 		Filename: filepath.Base(path),
 		Size:     stat.Size(),
 		ModTime:  stat.ModTime(),
-		Date:     stat.ModTime(), // Fallback
 		Device:   "Unknown",
-		Source:   DetectSource(filepath.Base(path)),
+		Source:   DetectSource(filepath.Base(path), filepath.Dir(path)),
+		hashes:   &hashes{},
+	}
+	info.Placeholder = IsPlaceholder(stat)
+	if t, err := GetCreationTime(path); err == nil {
+		info.Created = t
 	}
 
-	// Extract EXIF for images
-	isImage := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".heic": true, ".tiff": true}
-	if isImage[ext] {
-		if exifDate, device, err := ExtractExif(path); err == nil {
-			if exifDate != nil {
-				info.Date = *exifDate
-			}
-			if device != "" {
-				info.Device = device
-			}
+	// The date chain: the file's own headers (EXIF for images, the recording date for videos), a date
+	// in the name, the creation time (not for images, where it is only the copy time) and the
+	// modification time. Each step is checked against Plausible before it is used.
+	// A placeholder keeps its file dates: reading its headers would download it.
+	isImage := map[string]bool{".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".heic": true, ".heif": true, ".hif": true, ".tiff": true,
+		".gif": true, ".bmp": true, ".avif": true, ".jxl": true}
+	var chain []dateCandidate
+	// WhatsApp strips the headers of what it sends, so the day in its name leads the chain.
+	byName := dateCandidate{DateFromFilename, func() (time.Time, bool) { return FilenameDate(info.Filename) }}
+	_, _, _, whatsApp := WhatsAppName(info.Filename)
+	if whatsApp {
+		chain = append(chain, byName)
+	}
+	if info.Placeholder {
+		// Nothing to read without hydrating the file.
+	} else if ext == ".gif" || ext == ".bmp" {
+		// No capture date is kept in these; the name and the file dates tell it.
+	} else if isImage[ext] {
+		exifDate, device, err := ExtractExif(path)
+		if device != "" {
+			info.Device = device
 		}
+		if err != nil {
+			info.MetadataNote = ExifNote(err)
+			logger.Debug("No EXIF date for %s: %v", info.Filename, err)
+		}
+		chain = append(chain, dateCandidate{DateFromExif, func() (time.Time, bool) {
+			return derefTime(exifDate), err == nil && exifDate != nil
+		}})
 	} else {
-		// Video or other: Try creation time if available
-		if createTime, err := GetCreationTime(path); err == nil {
-			info.Date = createTime
+		// A camcorder's sidecar holds the recording date in the camera's own clock and the model the
+		// container often lacks, so it goes before the container's date.
+		info.Sidecars = Sidecars(path)
+		for _, sc := range info.Sidecars {
+			scDate, model, err := SidecarMeta(sc)
+			if model != "" && info.Device == "Unknown" {
+				info.Device = model
+			}
+			if err != nil {
+				logger.Debug("No date in sidecar %s: %v", filepath.Base(sc), err)
+				continue
+			}
+			chain = append(chain, dateCandidate{DateFromVideo, func() (time.Time, bool) { return scDate, true }})
 		}
+		chain = append(chain, dateCandidate{DateFromVideo, func() (time.Time, bool) {
+			t, err := VideoDate(path)
+			return t, err == nil
+		}})
+	}
+	if !whatsApp {
+		chain = append(chain, byName)
+	}
+	if !isImage[ext] {
+		chain = append(chain, dateCandidate{DateFromCreation, func() (time.Time, bool) {
+			t, err := GetCreationTime(path)
+			return t, err == nil
+		}})
 	}
+	chain = append(chain, dateCandidate{DateFromModTime, func() (time.Time, bool) { return stat.ModTime(), true }})
+	info.resolveDate(chain)
 
-	info.Year = fmt.Sprintf("%d", info.Date.Year())
-	info.Month = fmt.Sprintf("%02d", info.Date.Month())
+	// Dimensions are best effort and never fail the file. Screenshots carry no camera model, so
+	// their resolution tells phone and desktop captures apart.
+	if !info.Placeholder {
+		if w, h, err := ImageSize(path); err == nil {
+			info.Width, info.Height = w, h
+			info.Animated = (ext == ".gif" || ext == ".avif") && Animated(path)
+			if info.Source == "Screenshots" && info.Device == "Unknown" {
+				info.Device = ScreenshotDevice(w, h)
+			}
+		}
+	}
 
 	return info, nil
 }
 
-// ExtractExif uses go-exif to extract the date and device model.
+func derefTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+// SetDate sets Date and the folder fields derived from it.
+func (info *FileInfo) SetDate(t time.Time) {
+	info.Date = t
+	info.Year = fmt.Sprintf("%d", t.Year())
+	info.Month = fmt.Sprintf("%02d", t.Month())
+	info.Day = fmt.Sprintf("%02d", t.Day())
+	wy, wk := t.ISOWeek()
+	info.WeekYear = fmt.Sprintf("%d", wy)
+	info.Week = fmt.Sprintf("W%02d", wk)
+}
+
+// ExtractExif uses go-exif to extract the date and device model. Files are read through their own
+// structure first (see containerMeta): JPEG segments up to the start of scan, PNG and WebP chunks,
+// HEIF item tables. go-exif's byte search through the whole file is only the fallback when that
+// finds nothing. A PNG "Creation Time" fills in a missing date.
+//
+// Without a date the error tells why: ErrNoExif, ErrCorruptExif, ErrNoDateTag or ErrBadDate. The
+// device is returned all the same when it was read.
 func ExtractExif(path string) (*time.Time, string, error) {
+	var why error
+	if raw, created, err := containerMeta(path); err == nil {
+		var date *time.Time
+		var device string
+		if raw != nil {
+			date, device, why = exifFields(raw)
+		}
+		if date == nil {
+			date = created
+		}
+		if date != nil {
+			return date, device, nil
+		}
+		if device != "" {
+			return nil, device, why
+		}
+	}
 	rawExif, err := exif.SearchFileAndExtractExif(path)
-	if err != nil {
-		return nil, "", err
+	switch {
+	case err != nil && why != nil:
+		return nil, "", why
+	case errors.Is(err, exif.ErrNoExif):
+		return nil, "", ErrNoExif
+	case err != nil:
+		return nil, "", fmt.Errorf("%w: %w", ErrCorruptExif, err)
 	}
+	return exifFields(rawExif)
+}
 
+// exifFields reads DateTimeOriginal and Model from a raw TIFF/Exif blob. Without a date the error
+// tells why, see ExtractExif.
+func exifFields(rawExif []byte) (*time.Time, string, error) {
 	entries, _, err := exif.GetFlatExifData(rawExif, nil)
 	if err != nil {
-		return nil, "", err
+		return nil, "", fmt.Errorf("%w: %w", ErrCorruptExif, err)
 	}
 
 	var date *time.Time
-	var device string
+	var device, subsec string
+	dateErr := ErrNoDateTag
 
 	for _, entry := range entries {
 		if entry.TagName == "DateTimeOriginal" {
 			// Format is usually "2023:10:20 15:04:05"
-			t, err := time.Parse("2006:01:02 15:04:05", entry.FormattedFirst)
+			t, err := ParseExifDate(entry.FormattedFirst)
 			if err == nil {
 				date = &t
 			}
+			dateErr = err
+		} else if entry.TagName == "SubSecTimeOriginal" {
+			subsec = entry.FormattedFirst
 		} else if entry.TagName == "Model" {
 			device = strings.TrimSpace(entry.FormattedFirst)
 		}
 	}
 
-	return date, device, nil
+	if date == nil {
+		return nil, device, dateErr
+	}
+	t := WithSubSec(*date, subsec)
+	return &t, device, nil
 }
 
-// DetectSource identifies the source based on professional patterns.
-func DetectSource(filename string) string {
+// DetectSource identifies the source based on professional patterns. dir is the folder the file is
+// in: WhatsApp's folders nest the source by kind and direction, e.g. WhatsApp/Images/Sent.
+func DetectSource(filename, dir string) string {
+	if source := whatsAppSource(filename, dir); source != "" {
+		return source
+	}
 	lower := strings.ToLower(strings.TrimSpace(filename))
 	
 	patterns := map[string]string{
@@ -161,17 +354,3 @@ func DetectSource(filename string) string {
 	// Better fallback: avoid anemic folder names
 	return "Other_Imports"
 }
-
-// GetCreationTime attempts to get the OS-level creation time (Windows specific)
-func GetCreationTime(path string) (time.Time, error) {
-	fileInfo, err := os.Stat(path)
-	if err != nil {
-		return time.Time{}, err
-	}
-	// On Windows, sys is *syscall.Win32FileAttributeData
-	if winAttr, ok := fileInfo.Sys().(*syscall.Win32FileAttributeData); ok {
-		t := time.Unix(0, winAttr.CreationTime.Nanoseconds())
-		return t, nil
-	}
-	return fileInfo.ModTime(), nil
-}
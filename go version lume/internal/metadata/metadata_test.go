@@ -0,0 +1,27 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetDate(t *testing.T) {
+	tests := []struct {
+		date                          string
+		year, month, day, week, wyear string
+	}{
+		{"2023-06-15", "2023", "06", "15", "W24", "2023"},
+		{"2024-12-31", "2024", "12", "31", "W01", "2025"}, // Tuesday: ISO week 1 of the next year
+		{"2021-01-03", "2021", "01", "03", "W53", "2020"}, // Sunday: still the last week of the old year
+		{"2026-01-01", "2026", "01", "01", "W01", "2026"},
+	}
+	for _, tt := range tests {
+		d, _ := time.Parse("2006-01-02", tt.date)
+		var info FileInfo
+		info.SetDate(d)
+		if info.Year != tt.year || info.Month != tt.month || info.Day != tt.day || info.Week != tt.week || info.WeekYear != tt.wyear {
+			t.Errorf("SetDate(%s) = %s/%s/%s %s-%s; want %s/%s/%s %s-%s", tt.date,
+				info.Year, info.Month, info.Day, info.WeekYear, info.Week, tt.year, tt.month, tt.day, tt.wyear, tt.week)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package metadata
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PerceptualExtensions are the formats the standard library can decode for perceptual hashing.
+var PerceptualExtensions = map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
+
+// PerceptualHash computes a 64-bit difference hash (dHash) of the image: the picture is reduced to a
+// 9x8 grayscale grid and each bit records whether a cell is darker than its right neighbour. Resized
+// or re-compressed copies of a photo end up a few bits apart. Files above maxSize are not decoded.
+func PerceptualHash(path string, maxSize int64) (uint64, error) {
+	if !PerceptualExtensions[strings.ToLower(filepath.Ext(path))] {
+		return 0, fmt.Errorf("perceptual hash: unsupported type %s", filepath.Ext(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if st, err := f.Stat(); err != nil {
+		return 0, err
+	} else if maxSize > 0 && st.Size() > maxSize {
+		return 0, fmt.Errorf("perceptual hash: %s exceeds %d bytes", filepath.Base(path), maxSize)
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, fmt.Errorf("perceptual hash decode: %w", err)
+	}
+	return DHash(img), nil
+}
+
+// DHash returns the difference hash of an already decoded image.
+func DHash(img image.Image) uint64 {
+	const w, h = 9, 8
+	var grid [h][w]float64
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		y0, y1 := b.Min.Y+y*b.Dy()/h, b.Min.Y+(y+1)*b.Dy()/h
+		for x := 0; x < w; x++ {
+			x0, x1 := b.Min.X+x*b.Dx()/w, b.Min.X+(x+1)*b.Dx()/w
+			grid[y][x] = cellLuma(img, x0, y0, x1, y1)
+		}
+	}
+
+	var hash uint64
+	for y := 0; y < h; y++ {
+		for x := 0; x < w-1; x++ {
+			hash <<= 1
+			if grid[y][x] < grid[y][x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// cellLuma averages the luminance of a cell, sampling at most 16x16 points so large photos stay cheap.
+func cellLuma(img image.Image, x0, y0, x1, y1 int) float64 {
+	if x1 <= x0 {
+		x1 = x0 + 1
+	}
+	if y1 <= y0 {
+		y1 = y0 + 1
+	}
+	sx, sy := (x1-x0+15)/16, (y1-y0+15)/16
+	var sum float64
+	var n int
+	for y := y0; y < y1; y += sy {
+		for x := x0; x < x1; x += sx {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+// HammingDistance counts the differing bits of two perceptual hashes.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// HashedImage is a file taking part in near-duplicate detection.
+type HashedImage struct {
+	Path  string
+	Size  int64
+	Date  time.Time
+	PHash uint64
+}
+
+// NearPair is a pair of visually similar files flagged for user review.
+type NearPair struct {
+	A, B     HashedImage
+	Distance int
+}
+
+// FindNearDuplicates pairs every item of fresh with the visually similar items of fresh and existing
+// (within maxDist bits). Identical paths are never paired and existing items that are also fresh are
+// ignored, so each pair is reported once. Pairs are sorted by distance, then path.
+func FindNearDuplicates(fresh, existing []HashedImage, maxDist int) []NearPair {
+	seen := make(map[string]bool, len(fresh))
+	for _, f := range fresh {
+		seen[f.Path] = true
+	}
+	var others []HashedImage
+	for _, e := range existing {
+		if !seen[e.Path] {
+			others = append(others, e)
+		}
+	}
+
+	var pairs []NearPair
+	for i, a := range fresh {
+		for _, b := range fresh[i+1:] {
+			if d := HammingDistance(a.PHash, b.PHash); d <= maxDist && a.Path != b.Path {
+				pairs = append(pairs, NearPair{A: a, B: b, Distance: d})
+			}
+		}
+		for _, b := range others {
+			if d := HammingDistance(a.PHash, b.PHash); d <= maxDist && a.Path != b.Path {
+				pairs = append(pairs, NearPair{A: a, B: b, Distance: d})
+			}
+		}
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Distance != pairs[j].Distance {
+			return pairs[i].Distance < pairs[j].Distance
+		}
+		return pairs[i].A.Path < pairs[j].A.Path
+	})
+	return pairs
+}
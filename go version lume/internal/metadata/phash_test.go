@@ -0,0 +1,96 @@
+package metadata
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// gradient draws a deterministic test picture; flip mirrors it to get a visually different image.
+func gradient(w, h int, flip bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8((x*255/w + y*128/h) % 256)
+			if x > w/3 && x < w/2 && y > h/4 {
+				v = 255 - v
+			}
+			if flip {
+				v = 255 - v
+			}
+			img.Set(x, y, color.RGBA{v, v / 2, 255 - v, 255})
+		}
+	}
+	return img
+}
+
+func shrink(src image.Image, w, h int) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*b.Dx()/w, b.Min.Y+y*b.Dy()/h))
+		}
+	}
+	return dst
+}
+
+func TestPerceptualHashSurvivesRecompression(t *testing.T) {
+	dir := t.TempDir()
+	orig := filepath.Join(dir, "IMG_0001.png")
+	small := filepath.Join(dir, "IMG-20230101-WA0001.jpg")
+	other := filepath.Join(dir, "other.jpg")
+
+	f, _ := os.Create(orig)
+	png.Encode(f, gradient(640, 480, false))
+	f.Close()
+	f, _ = os.Create(small)
+	jpeg.Encode(f, shrink(gradient(640, 480, false), 160, 120), &jpeg.Options{Quality: 40})
+	f.Close()
+	f, _ = os.Create(other)
+	jpeg.Encode(f, gradient(640, 480, true), &jpeg.Options{Quality: 90})
+	f.Close()
+
+	h1, err := PerceptualHash(orig, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := PerceptualHash(small, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3, err := PerceptualHash(other, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := HammingDistance(h1, h2); d > 6 {
+		t.Errorf("re-compressed copy distance = %d, want <= 6", d)
+	}
+	if d := HammingDistance(h1, h3); d < 20 {
+		t.Errorf("different image distance = %d, want >= 20", d)
+	}
+
+	if _, err := PerceptualHash(orig, 10); err == nil {
+		t.Error("file above the size threshold was decoded")
+	}
+}
+
+func TestFindNearDuplicates(t *testing.T) {
+	fresh := []HashedImage{{Path: "a", PHash: 0xFF00}, {Path: "b", PHash: 0xFF01}}
+	existing := []HashedImage{{Path: "b", PHash: 0xFF01}, {Path: "c", PHash: 0x00FF}, {Path: "d", PHash: 0xFF03}}
+	pairs := FindNearDuplicates(fresh, existing, 2)
+
+	want := [][2]string{{"a", "b"}, {"b", "d"}, {"a", "d"}}
+	if len(pairs) != len(want) {
+		t.Fatalf("got %d pairs: %+v", len(pairs), pairs)
+	}
+	for i, w := range want {
+		if pairs[i].A.Path != w[0] || pairs[i].B.Path != w[1] {
+			t.Errorf("pair %d = %s/%s, want %s/%s", i, pairs[i].A.Path, pairs[i].B.Path, w[0], w[1])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package metadata
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Shift moves the dates of a selection of files by a fixed offset, e.g. the photos of a camera that
+// was set to the wrong year. It selects by device and by the days the files are dated on before the
+// shift; an empty Device selects every device, an empty From or To leaves that end open.
+type Shift struct {
+	Device   string
+	From, To string // YYYY-MM-DD
+	// Years and Days are calendar steps, Offset the hours, minutes and seconds on top of them.
+	Years, Days int
+	Offset      time.Duration
+}
+
+// shiftTerm is one step of a shift offset, e.g. -1y or +2h.
+var shiftTerm = regexp.MustCompile(`([+-]?)(\d+)(y|d|h|m|s)`)
+
+// ParseShiftOffset reads an offset such as "+1y", "-2h" or "-1y+2h30m": signed numbers of years
+// (y), days (d), hours (h), minutes (m) and seconds (s); a number without a sign takes the one
+// before it, the first one +.
+func ParseShiftOffset(s string, into *Shift) error {
+	s = strings.ReplaceAll(s, " ", "")
+	terms := shiftTerm.FindAllStringSubmatchIndex(s, -1)
+	sign, pos := 1, 0
+	for _, m := range terms {
+		if m[0] != pos {
+			break
+		}
+		pos = m[1]
+		switch s[m[2]:m[3]] {
+		case "-":
+			sign = -1
+		case "+":
+			sign = 1
+		}
+		n, err := strconv.Atoi(s[m[4]:m[5]])
+		if err != nil {
+			return fmt.Errorf("invalid offset %q", s)
+		}
+		n *= sign
+		switch s[m[6]:m[7]] {
+		case "y":
+			into.Years += n
+		case "d":
+			into.Days += n
+		case "h":
+			into.Offset += time.Duration(n) * time.Hour
+		case "m":
+			into.Offset += time.Duration(n) * time.Minute
+		case "s":
+			into.Offset += time.Duration(n) * time.Second
+		}
+	}
+	if pos != len(s) || len(terms) == 0 {
+		return fmt.Errorf("invalid offset %q, want e.g. +1y, -2h or -1y+2h30m", s)
+	}
+	if into.Years == 0 && into.Days == 0 && into.Offset == 0 {
+		return fmt.Errorf("offset %q shifts nothing", s)
+	}
+	return nil
+}
+
+// ParseShift reads a shift written as "device=EOS70D;offset=-1h", optionally with the days it
+// applies to as from=2013-01-01 and to=2013-12-31. The offset is required.
+func ParseShift(s string) (Shift, error) {
+	var sh Shift
+	offset := false
+	for _, part := range strings.Split(s, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		switch k {
+		case "device":
+			sh.Device = collapseSpace(v)
+		case "from", "to":
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return sh, fmt.Errorf("invalid day %q, want YYYY-MM-DD", v)
+			}
+			if k == "from" {
+				sh.From = v
+			} else {
+				sh.To = v
+			}
+		case "offset":
+			if err := ParseShiftOffset(v, &sh); err != nil {
+				return sh, err
+			}
+			offset = true
+		default:
+			return sh, fmt.Errorf("unknown shift key %q (device, from, to, offset)", k)
+		}
+	}
+	if !offset {
+		return sh, fmt.Errorf("shift %q has no offset", s)
+	}
+	if sh.From != "" && sh.To != "" && sh.From > sh.To {
+		return sh, fmt.Errorf("%s is after %s", sh.From, sh.To)
+	}
+	return sh, nil
+}
+
+// OffsetString writes the offset of s the way ParseShiftOffset reads it.
+func (s Shift) OffsetString() string {
+	var b strings.Builder
+	term := func(n int, unit string) {
+		if n != 0 {
+			fmt.Fprintf(&b, "%+d%s", n, unit)
+		}
+	}
+	term(s.Years, "y")
+	term(s.Days, "d")
+	d := s.Offset
+	term(int(d/time.Hour), "h")
+	term(int(d%time.Hour/time.Minute), "m")
+	term(int(d%time.Minute/time.Second), "s")
+	return b.String()
+}
+
+// String writes s the way ParseShift reads it.
+func (s Shift) String() string {
+	var parts []string
+	if s.Device != "" {
+		parts = append(parts, "device="+s.Device)
+	}
+	if s.From != "" {
+		parts = append(parts, "from="+s.From)
+	}
+	if s.To != "" {
+		parts = append(parts, "to="+s.To)
+	}
+	return strings.Join(append(parts, "offset="+s.OffsetString()), ";")
+}
+
+// Apply returns t shifted.
+func (s Shift) Apply(t time.Time) time.Time {
+	return t.AddDate(s.Years, 0, s.Days).Add(s.Offset)
+}
+
+// Matches reports whether s selects info: a dated file of its device, on its days. The device
+// matches when the file's device name holds it, ignoring case and spaces, so "EOS70D" selects the
+// files of a "Canon EOS 70D".
+func (s Shift) Matches(info FileInfo) bool {
+	if info.DateSource == DateUndated || info.Date.IsZero() {
+		return false
+	}
+	squash := func(v string) string { return strings.ToLower(strings.Join(strings.Fields(v), "")) }
+	if s.Device != "" && !strings.Contains(squash(info.Device), squash(s.Device)) {
+		return false
+	}
+	day := info.Date.Format("2006-01-02")
+	return (s.From == "" || day >= s.From) && (s.To == "" || day <= s.To)
+}
+
+// ShiftDate moves the date of info by the first of shifts that selects it and keeps the date it had
+// in ShiftedFrom. With writeExif the new date of a JPEG dated by its EXIF is set in ExifDate, less a
+// clock offset (see Clocks), which applies when the file is read: the move writes it into the
+// DateTimeOriginal of the archived copy once that is verified, its DateTimeDigitized keeping the
+// camera's reading (see WriteExifDate). The source is never written, so running the shift again on
+// it gives the same date. A JPEG whose two dates already differ by the shift, such as an archived
+// copy, had it written before: it is dated by what it holds and written no further. A file the date
+// cannot be written into keeps its shifted date and returns ErrExifNotWritable or the I/O error. It
+// reports whether a shift selected info.
+func ShiftDate(info *FileInfo, shifts []Shift, writeExif bool) (bool, error) {
+	if !info.ShiftedFrom.IsZero() {
+		return false, nil
+	}
+	for _, s := range shifts {
+		if !s.Matches(*info) {
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(info.Path))
+		jpeg := (ext == ".jpg" || ext == ".jpeg") && !info.Placeholder
+		fields := ErrExifNotWritable
+		if jpeg {
+			orig, dig, err := dateFields(info.Path)
+			if written, before, ok := writtenShift(orig, dig, s); err == nil && ok {
+				info.SetDate(written.Add(time.Duration(info.Date.Nanosecond()) + info.ClockOffset))
+				info.ShiftedFrom, info.ExifWritten = before.Add(info.ClockOffset), true
+				return true, nil
+			}
+			fields = err
+		}
+		from, to := info.Date, s.Apply(info.Date)
+		info.SetDate(to)
+		info.ShiftedFrom = from
+		if !writeExif {
+			return true, nil
+		}
+		if !jpeg || info.DateSource != DateFromExif {
+			return true, ErrExifNotWritable
+		}
+		if fields != nil {
+			return true, fields
+		}
+		info.ExifDate = to.Add(-info.ClockOffset)
+		return true, nil
+	}
+	return false, nil
+}
+
+// ParseShifts reads shifts written one per line, as ParseShift reads them; blank lines are skipped.
+func ParseShifts(text string) ([]Shift, error) {
+	var shifts []Shift
+	for i, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		s, err := ParseShift(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		shifts = append(shifts, s)
+	}
+	return shifts, nil
+}
@@ -0,0 +1,151 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseShift(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Shift
+		ok   bool
+	}{
+		{"device=EOS70D;offset=-1h", Shift{Device: "EOS70D", Offset: -time.Hour}, true},
+		{"offset=+1y; device = Canon EOS 70D ;from=2013-01-01;to=2013-12-31", Shift{Device: "Canon EOS 70D", From: "2013-01-01", To: "2013-12-31", Years: 1}, true},
+		{"offset=-1y2h30m", Shift{Years: -1, Offset: -(2*time.Hour + 30*time.Minute)}, true},
+		{"offset=1d-12h", Shift{Days: 1, Offset: -12 * time.Hour}, true},
+		{"device=EOS70D", Shift{}, false},
+		{"offset=+1w", Shift{}, false},
+		{"offset=+1h junk", Shift{}, false},
+		{"offset=+1h-1h", Shift{}, false},
+		{"offset=+1h;color=red", Shift{}, false},
+		{"offset=+1h;from=2013-13-01", Shift{}, false},
+		{"offset=+1h;from=2014-01-01;to=2013-01-01", Shift{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseShift(tt.in)
+		if tt.ok && (err != nil || got != tt.want) {
+			t.Errorf("ParseShift(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("ParseShift(%q) = %+v; want an error", tt.in, got)
+		}
+		if tt.ok {
+			if again, err := ParseShift(got.String()); err != nil || again != got {
+				t.Errorf("%q does not read back: %+v, %v", got.String(), again, err)
+			}
+		}
+	}
+
+	if _, err := ParseShifts("offset=+1h\n\ndevice=X"); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ParseShifts: %v; want line 3 reported", err)
+	}
+
+	s := Shift{Years: 1, Offset: -2 * time.Hour}
+	if got, want := s.Apply(time.Date(2012, 2, 29, 1, 0, 0, 0, time.UTC)), time.Date(2013, 2, 28, 23, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Apply = %s; want %s", got, want)
+	}
+}
+
+// dateTIFF is an Exif blob with only a DateTimeOriginal and a DateTimeDigitized.
+func dateTIFF(orig, digitized string) []byte {
+	le := binary.LittleEndian
+	b := make([]byte, 96)
+	copy(b, "II")
+	le.PutUint16(b[2:], 42)
+	le.PutUint32(b[4:], 8)
+	le.PutUint16(b[8:], 1) // IFD0: the Exif IFD pointer
+	le.PutUint16(b[10:], tagExifIFD)
+	le.PutUint16(b[12:], 4)
+	le.PutUint32(b[14:], 1)
+	le.PutUint32(b[18:], 26)
+	le.PutUint16(b[26:], 2) // Exif IFD: the two dates
+	for i, tag := range []uint16{tagDateTimeOriginal, tagDateTimeDigitized} {
+		e := 28 + 12*i
+		le.PutUint16(b[e:], tag)
+		le.PutUint16(b[e+2:], 2)
+		le.PutUint32(b[e+4:], 20)
+		le.PutUint32(b[e+8:], uint32(56+20*i))
+	}
+	copy(b[56:], orig)
+	copy(b[76:], digitized)
+	return b
+}
+
+func TestShiftDate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "IMG_0001.JPG")
+	app1 := segment(0xe1, append([]byte("Exif\x00\x00"), dateTIFF("2013:05:01 10:00:00", "2013:05:01 10:00:00")...))
+	if err := os.WriteFile(path, buildJPEG(64, app1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2014, 5, 1, 10, 0, 0, 0, time.Local)
+	os.Chtimes(path, mtime, mtime)
+	// The camera was set a year behind.
+	shifts := []Shift{{Device: "unknown", From: "2013-01-01", To: "2013-12-31", Years: 1}}
+	want := time.Date(2014, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	info, err := GetFileInfo(path)
+	if err != nil || info.DateSource != DateFromExif {
+		t.Fatalf("GetFileInfo = %+v, %v", info, err)
+	}
+	if ok, err := ShiftDate(&info, shifts, false); !ok || err != nil || !info.Date.Equal(want) || info.Year != "2014" || info.ExifWritten {
+		t.Fatalf("shift = %v, %v: %+v", ok, err, info)
+	}
+	// Shifted once per run, however often it is asked.
+	if ok, _ := ShiftDate(&info, shifts, false); ok || !info.Date.Equal(want) {
+		t.Errorf("shifted twice: %s", info.Date)
+	}
+
+	// Writing is left to the archived copy: the source stays as it was, so every run shifts it alike.
+	source, _ := os.ReadFile(path)
+	for run := 0; run < 2; run++ {
+		info, _ = GetFileInfo(path)
+		if ok, err := ShiftDate(&info, shifts, true); !ok || err != nil || info.ExifWritten || !info.ExifDate.Equal(want) || !info.ShiftedFrom.Equal(want.AddDate(-1, 0, 0)) {
+			t.Fatalf("run %d: write = %v, %v: %+v", run, ok, err, info)
+		}
+	}
+	if data, _ := os.ReadFile(path); !bytes.Equal(data, source) {
+		t.Error("the source was written")
+	}
+	archived := filepath.Join(dir, "archived.jpg")
+	os.WriteFile(archived, source, 0644)
+	os.Chtimes(archived, mtime, mtime)
+	if err := WriteExifDate(nil, archived, info.ExifDate); err != nil {
+		t.Fatal(err)
+	}
+	if st, _ := os.Stat(archived); !st.ModTime().Equal(mtime) {
+		t.Errorf("modification time %s; want it kept", st.ModTime())
+	}
+	// The copy holds the shifted date, and the shift, narrow or wide, leaves it so.
+	wide := []Shift{{Years: 1}}
+	for _, s := range [][]Shift{shifts, wide} {
+		info, err = GetFileInfo(archived)
+		if err != nil || !info.Date.Equal(want) {
+			t.Fatalf("read back %s, %v", info.Date, err)
+		}
+		ok, err := ShiftDate(&info, s, true)
+		if !info.Date.Equal(want) || !info.ExifDate.IsZero() || err != nil || ok != info.ExifWritten {
+			t.Errorf("%v: shifted again to %s (%v, %v): %+v", s, info.Date, ok, err, info)
+		}
+	}
+	if info.ShiftedFrom.IsZero() || !info.ShiftedFrom.Equal(want.AddDate(-1, 0, 0)) {
+		t.Errorf("written shift not recognized: %+v", info)
+	}
+
+	// A file without the dates to write keeps its shifted date for the run.
+	png := filepath.Join(dir, "a.png")
+	os.WriteFile(png, []byte("\x89PNG\r\n\x1a\n"), 0644)
+	info = FileInfo{Path: png, Device: "Unknown", DateSource: DateFromModTime}
+	info.SetDate(time.Date(2013, 5, 1, 10, 0, 0, 0, time.Local))
+	if ok, err := ShiftDate(&info, shifts, true); !ok || !errors.Is(err, ErrExifNotWritable) || info.Year != "2014" {
+		t.Errorf("png: %v, %v, %+v", ok, err, info)
+	}
+}
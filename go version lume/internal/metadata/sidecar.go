@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoSidecarDate reports a sidecar that names no recording date Lume can read.
+var ErrNoSidecarDate = errors.New("no date in sidecar")
+
+// SidecarSuffixes are what camcorders add to the name of a video for the files written beside it:
+// Sony's C0001M01.XML, holding the recording date and camera model, and the .THM thumbnail of
+// Canon and Panasonic, a JPEG whose EXIF holds them.
+var SidecarSuffixes = []string{"M01.XML", ".THM"}
+
+// videoExtensions are the formats whose sidecars are looked for.
+var videoExtensions = map[string]bool{".mp4": true, ".mov": true, ".3gp": true, ".avi": true, ".mkv": true, ".webm": true}
+
+// Sidecars lists the sidecars of the video at path that exist beside it, in the order of
+// SidecarSuffixes. The suffix is matched in upper or lower case.
+func Sidecars(path string) []string {
+	if !videoExtensions[strings.ToLower(filepath.Ext(path))] {
+		return nil
+	}
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	var out []string
+	for _, suffix := range SidecarSuffixes {
+		for _, p := range []string{base + suffix, base + strings.ToLower(suffix)} {
+			if st, err := os.Stat(p); err == nil && st.Mode().IsRegular() {
+				out = append(out, p)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// SidecarSuffix is the suffix of SidecarSuffixes path ends in, matched ignoring case, and the name
+// of the video it belongs to without its extension; ok is false for a file that is no sidecar.
+func SidecarSuffix(path string) (suffix, stem string, ok bool) {
+	name := filepath.Base(path)
+	for _, s := range SidecarSuffixes {
+		if len(name) > len(s) && strings.EqualFold(name[len(name)-len(s):], s) {
+			return name[len(name)-len(s):], name[:len(name)-len(s)], true
+		}
+	}
+	return "", "", false
+}
+
+// OwnsSidecar reports whether sidecar belongs to the video at video: it lies in the same folder
+// under the video's name, told apart ignoring case.
+func OwnsSidecar(video, sidecar string) bool {
+	_, stem, ok := SidecarSuffix(sidecar)
+	ext := filepath.Ext(video)
+	return ok && videoExtensions[strings.ToLower(ext)] && strings.EqualFold(filepath.Dir(video), filepath.Dir(sidecar)) &&
+		strings.EqualFold(strings.TrimSuffix(filepath.Base(video), ext), stem)
+}
+
+// SidecarVideo finds the video a sidecar at path belongs to beside it; ok is false for an orphan,
+// whose video is gone.
+func SidecarVideo(path string) (string, bool) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return "", false
+	}
+	for _, e := range entries {
+		if video := filepath.Join(filepath.Dir(path), e.Name()); OwnsSidecar(video, path) {
+			return video, true
+		}
+	}
+	return "", false
+}
+
+// SidecarMeta reads the recording date and camera model of a sidecar: the CreationDate and Device
+// of a Sony XML, or the EXIF of a THM thumbnail. The date is the camera's wall clock, in local time
+// like an EXIF date. Without a date the model is returned all the same when it was read.
+func SidecarMeta(path string) (time.Time, string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		return sonyXML(path)
+	}
+	date, model, err := ExtractExif(path)
+	if err != nil {
+		return time.Time{}, model, err
+	}
+	return *date, model, nil
+}
+
+// sonyMeta is the part of Sony's NonRealTimeMeta XML Lume reads.
+type sonyMeta struct {
+	XMLName  xml.Name `xml:"NonRealTimeMeta"`
+	Creation struct {
+		Value string `xml:"value,attr"`
+	} `xml:"CreationDate"`
+	Device struct {
+		Manufacturer string `xml:"manufacturer,attr"`
+		Model        string `xml:"modelName,attr"`
+	} `xml:"Device"`
+}
+
+// sonyXML reads a Sony clip's XML. Its CreationDate carries the camera's time zone, which is dropped
+// for the wall clock it was recorded at.
+func sonyXML(path string) (time.Time, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	defer f.Close()
+	var m sonyMeta
+	if err := xml.NewDecoder(io.LimitReader(f, maxChunkSize)).Decode(&m); err != nil {
+		return time.Time{}, "", err
+	}
+	model := strings.TrimSpace(m.Device.Model)
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(m.Creation.Value))
+	if err != nil {
+		return time.Time{}, model, ErrNoSidecarDate
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local), model, nil
+}
@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSidecars(t *testing.T) {
+	// Fixtures: C0001.MP4 with Sony's C0001M01.XML, MVI_0042.MOV with a THM thumbnail holding EXIF,
+	// and C0002M01.XML, whose video is gone. Both videos record 2019-03-01 08:00:00 themselves.
+	dir := filepath.Join("testdata", "camcorder")
+	tests := []struct {
+		video, sidecar, date, device string
+	}{
+		{"C0001.MP4", "C0001M01.XML", "2020-07-14 16:45:12", "ILCE-7SM3"},
+		{"MVI_0042.MOV", "MVI_0042.THM", "2018-05-06 07:08:09", "Canon LEGRIA HF G40"},
+	}
+	for _, tt := range tests {
+		info, err := GetFileInfo(filepath.Join(dir, tt.video))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(info.Sidecars) != 1 || filepath.Base(info.Sidecars[0]) != tt.sidecar {
+			t.Errorf("%s: Sidecars = %v; want %s", tt.video, info.Sidecars, tt.sidecar)
+		}
+		if got := info.Date.Format("2006-01-02 15:04:05"); got != tt.date || info.DateSource != DateFromVideo || info.Device != tt.device {
+			t.Errorf("%s: date %s from %s, device %q; want %s from the video, %q", tt.video, got, info.DateSource, info.Device, tt.date, tt.device)
+		}
+		if v, ok := SidecarVideo(filepath.Join(dir, tt.sidecar)); !ok || filepath.Base(v) != tt.video {
+			t.Errorf("SidecarVideo(%s) = %s, %v; want %s", tt.sidecar, v, ok, tt.video)
+		}
+	}
+	if v, ok := SidecarVideo(filepath.Join(dir, "C0002M01.XML")); ok {
+		t.Errorf("SidecarVideo(C0002M01.XML) = %s; want an orphan", v)
+	}
+	if got := Sidecars(filepath.Join("testdata", "recorded.3gp")); got != nil {
+		t.Errorf("Sidecars(recorded.3gp) = %v; want none", got)
+	}
+}
+
+func TestOwnsSidecar(t *testing.T) {
+	tests := []struct {
+		video, sidecar string
+		want           bool
+	}{
+		{"/card/C0001.MP4", "/card/C0001M01.XML", true},
+		{"/card/c0001.mp4", "/card/C0001m01.xml", true},
+		{"/card/MVI_0042.MOV", "/card/MVI_0042.THM", true},
+		{"/card/C0001.MP4", "/other/C0001M01.XML", false},
+		{"/card/C0001.JPG", "/card/C0001M01.XML", false},
+		{"/card/C0001.MP4", "/card/C0002M01.XML", false},
+		{"/card/C0001.MP4", "/card/C0001.XML", false},
+	}
+	for _, tt := range tests {
+		if got := OwnsSidecar(tt.video, tt.sidecar); got != tt.want {
+			t.Errorf("OwnsSidecar(%s, %s) = %v; want %v", tt.video, tt.sidecar, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package metadata
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultSuspectThreshold is how far a file's own date may lie from its file times before
+// MarkDateSuspects doubts it, unless configured otherwise.
+const DefaultSuspectThreshold = 6 * time.Hour
+
+// skew is how far the own date of info lies from the nearer of its file times, signed; ok is false
+// for a file dated by anything but its own headers.
+func (info FileInfo) skew() (d time.Duration, ok bool) {
+	if info.DateSource != DateFromExif && info.DateSource != DateFromVideo {
+		return 0, false
+	}
+	d = info.Date.Sub(info.ModTime)
+	if !info.Created.IsZero() {
+		if c := info.Date.Sub(info.Created); abs(c) < abs(d) {
+			d = c
+		}
+	}
+	return d, true
+}
+
+func abs(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// MarkDateSuspects flags the files whose own date lies more than threshold from both their file
+// times while the files next to them, of the same device and in the order of their file times, lie
+// within it: a clock that jumped, or a date edited wrongly. A device whose files all disagree with
+// their file times (copies that lost them, say) has nothing to go by, and a file without a
+// neighbour neither; none of them is flagged. Marks from an earlier call are replaced. It returns the
+// number of files flagged; a threshold of zero or less flags none.
+func MarkDateSuspects(files []FileInfo, threshold time.Duration) int {
+	type dated struct {
+		i    int
+		skew time.Duration
+	}
+	byDevice := make(map[string][]dated)
+	for i := range files {
+		files[i].DateSuspect, files[i].Skew = false, 0
+		if d, ok := files[i].skew(); ok && threshold > 0 {
+			byDevice[files[i].Device] = append(byDevice[files[i].Device], dated{i, d})
+		}
+	}
+	n := 0
+	for _, group := range byDevice {
+		sort.SliceStable(group, func(a, b int) bool { return files[group[a].i].ModTime.Before(files[group[b].i].ModTime) })
+		for k, g := range group {
+			if abs(g.skew) <= threshold || len(group) < 2 {
+				continue
+			}
+			agree := true
+			for _, j := range []int{k - 1, k + 1} {
+				if j >= 0 && j < len(group) && abs(group[j].skew) > threshold {
+					agree = false
+				}
+			}
+			if agree {
+				files[g.i].DateSuspect, files[g.i].Skew = true, g.skew
+				n++
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarkDateSuspects(t *testing.T) {
+	base := time.Date(2024, 7, 1, 10, 0, 0, 0, time.Local)
+	file := func(device, source string, date, mod time.Time) FileInfo {
+		info := FileInfo{Filename: date.Format("150405"), Device: device, DateSource: source, ModTime: mod}
+		info.SetDate(date)
+		return info
+	}
+	hour := time.Hour
+	files := []FileInfo{
+		// A camera whose clock jumped a day ahead for one shot, its other files agreeing with their times.
+		file("Canon", DateFromExif, base, base),
+		file("Canon", DateFromExif, base.Add(26*hour), base.Add(2*hour)),
+		file("Canon", DateFromExif, base.Add(4*hour), base.Add(4*hour+time.Minute)),
+		// Within the threshold: only a slightly wrong clock.
+		file("Canon", DateFromExif, base.Add(9*hour), base.Add(6*hour)),
+		// A phone whose copies all lost their file times has nothing to go by.
+		file("Phone", DateFromExif, base, base.Add(200*hour)),
+		file("Phone", DateFromExif, base.Add(hour), base.Add(201*hour)),
+		file("Phone", DateFromExif, base.Add(2*hour), base.Add(202*hour)),
+		// Alone on its device, nothing vouches for the file times either.
+		file("Drone", DateFromExif, base, base.Add(100*hour)),
+		// Dated by its name, not by its own clock.
+		file("Canon", DateFromFilename, base.Add(50*hour), base.Add(3*hour)),
+	}
+	// The creation time counts as a file time as well.
+	created := file("Canon", DateFromExif, base.Add(-30*hour), base.Add(5*hour))
+	created.Created = base.Add(-30*hour + time.Minute)
+	files = append(files, created)
+
+	if n := MarkDateSuspects(files, 6*hour); n != 1 {
+		t.Errorf("MarkDateSuspects = %d; want 1", n)
+	}
+	for i, f := range files {
+		if want := i == 1; f.DateSuspect != want {
+			t.Errorf("file %d (%s, %s): DateSuspect = %v; want %v", i, f.Device, f.Filename, f.DateSuspect, want)
+		}
+	}
+	if files[1].Skew != 24*hour {
+		t.Errorf("Skew = %s; want 24h", files[1].Skew)
+	}
+
+	// A later call replaces the marks; no threshold marks none.
+	if n := MarkDateSuspects(files, 0); n != 0 || files[1].DateSuspect || files[1].Skew != 0 {
+		t.Errorf("without a threshold: %d marked, %+v", n, files[1])
+	}
+}
@@ -0,0 +1,80 @@
+package metadata
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SourceSep separates the levels of a nested source, e.g. WhatsApp/Images/Sent.
+const SourceSep = "/"
+
+// SourceLevels cuts source to its first depth levels; depth below 1 keeps the first.
+func SourceLevels(source string, depth int) string {
+	parts := strings.Split(source, SourceSep)
+	return strings.Join(parts[:min(max(depth, 1), len(parts))], SourceSep)
+}
+
+// SourceIn reports whether source is within want, a source of the same or fewer levels, ignoring
+// case: WhatsApp/Images/Sent is within WhatsApp and WhatsApp/Images.
+func SourceIn(source, want string) bool {
+	source, want = strings.ToLower(source), strings.ToLower(strings.Trim(want, SourceSep))
+	return source == want || strings.HasPrefix(source, want+SourceSep)
+}
+
+// whatsAppPattern matches the names WhatsApp gives media, IMG-20230514-WA0012.jpg: the kind, the day the
+// message came and its counter on that day.
+var whatsAppPattern = regexp.MustCompile(`(?i)^(IMG|VID|STK|DOC)-(\d{4})(\d\d)(\d\d)-WA(\d+)`)
+
+// whatsAppKinds names the kind of each name prefix.
+var whatsAppKinds = map[string]string{"IMG": "Images", "VID": "Video", "STK": "Stickers", "DOC": "Documents"}
+
+// whatsAppFolders names the kind of each media folder, without its "WhatsApp " or "WhatsApp Business "
+// prefix.
+var whatsAppFolders = map[string]string{
+	"images": "Images", "video": "Video", "documents": "Documents", "animated gifs": "GIFs",
+	"stickers": "Stickers", "profile photos": "Profile Photos",
+}
+
+// WhatsAppName reads a name WhatsApp gave, IMG-20230514-WA0012.jpg: the day in local time, the
+// kind of media (Images, Video, Stickers or Documents) and the counter, 12.
+func WhatsAppName(name string) (day time.Time, kind string, n int, ok bool) {
+	m := whatsAppPattern.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, "", 0, false
+	}
+	day, ok = FilenameDate(m[2] + m[3] + m[4])
+	if !ok {
+		return time.Time{}, "", 0, false
+	}
+	n, _ = strconv.Atoi(m[5])
+	return day, whatsAppKinds[strings.ToUpper(m[1])], n, true
+}
+
+// whatsAppSource is the nested source of a file WhatsApp saved, or "". Its folder tells the kind and,
+// in Sent, that it was sent; the name only the kind, when the file is elsewhere.
+func whatsAppSource(filename, dir string) string {
+	folder, received := filepath.Base(dir), "Received"
+	switch strings.ToLower(folder) {
+	case "sent":
+		folder, received = filepath.Base(filepath.Dir(dir)), "Sent"
+	case "private":
+		folder = filepath.Base(filepath.Dir(dir))
+	}
+	lower := strings.ToLower(folder)
+	for _, prefix := range []string{"whatsapp business ", "whatsapp "} {
+		if rest, ok := strings.CutPrefix(lower, prefix); ok {
+			kind, known := whatsAppFolders[rest]
+			if !known {
+				kind = folder[len(prefix):]
+			}
+			return strings.Join([]string{"WhatsApp", kind, received}, SourceSep)
+		}
+	}
+	if _, kind, _, ok := WhatsAppName(filename); ok {
+		return "WhatsApp" + SourceSep + kind
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package metadata
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDetectSourceWhatsApp(t *testing.T) {
+	media := filepath.Join("Android", "media", "com.whatsapp", "WhatsApp", "Media")
+	business := filepath.Join("Android", "media", "com.whatsapp.w4b", "WhatsApp Business", "Media")
+	tests := []struct {
+		dir, name, want string
+	}{
+		{filepath.Join(media, "WhatsApp Images"), "IMG-20230514-WA0012.jpg", "WhatsApp/Images/Received"},
+		{filepath.Join(media, "WhatsApp Images", "Sent"), "IMG-20230514-WA0013.jpg", "WhatsApp/Images/Sent"},
+		{filepath.Join(media, "WhatsApp Images", "Private"), "IMG-20230514-WA0014.jpg", "WhatsApp/Images/Received"},
+		{filepath.Join(media, "WhatsApp Video"), "VID-20230514-WA0001.mp4", "WhatsApp/Video/Received"},
+		{filepath.Join(media, "WhatsApp Video", "Sent"), "VID-20230514-WA0002.mp4", "WhatsApp/Video/Sent"},
+		{filepath.Join(media, "WhatsApp Animated Gifs"), "VID-20230514-WA0003.mp4", "WhatsApp/GIFs/Received"},
+		{filepath.Join(media, "WhatsApp Stickers"), "STK-20230514-WA0004.webp", "WhatsApp/Stickers/Received"},
+		// Documents keep the name they were sent with.
+		{filepath.Join(media, "WhatsApp Documents"), "holiday.jpg", "WhatsApp/Documents/Received"},
+		{filepath.Join(media, "WhatsApp Documents", "Sent"), "scan.png", "WhatsApp/Documents/Sent"},
+		{filepath.Join(business, "WhatsApp Business Images"), "IMG-20230514-WA0005.jpg", "WhatsApp/Images/Received"},
+		// Copied out of WhatsApp's folders, the name still tells the kind.
+		{filepath.Join("Desktop", "phone"), "IMG-20230514-WA0006.jpeg", "WhatsApp/Images"},
+		{filepath.Join("Desktop", "phone"), "vid-20230514-wa0007.mp4", "WhatsApp/Video"},
+		{filepath.Join("Desktop", "Sent"), "IMG_20230514_101500.jpg", "Camera"},
+		{filepath.Join("DCIM", "Camera"), "IMG-20231301-WA0001.jpg", "WhatsApp"}, // no such month
+	}
+	for _, tt := range tests {
+		if got := DetectSource(tt.name, tt.dir); got != tt.want {
+			t.Errorf("DetectSource(%q, %q) = %q; want %q", tt.name, tt.dir, got, tt.want)
+		}
+	}
+}
+
+func TestWhatsAppName(t *testing.T) {
+	day, kind, n, ok := WhatsAppName("IMG-20230514-WA0012.jpg")
+	if !ok || day.Format("2006-01-02 15:04") != "2023-05-14 00:00" || kind != "Images" || n != 12 {
+		t.Errorf("WhatsAppName = %v, %q, %d, %v", day, kind, n, ok)
+	}
+	for _, name := range []string{"IMG_20230514_123456.jpg", "IMG-20230230-WA0001.jpg", "PTT-20230514-WA0001.opus", "IMG-20230514.jpg"} {
+		if _, _, _, ok := WhatsAppName(name); ok {
+			t.Errorf("WhatsAppName(%q) matched", name)
+		}
+	}
+}
+
+// A WhatsApp name dates the file even against an EXIF date: the EXIF left is that of an edit or a forward.
+func TestWhatsAppDateLeads(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "WhatsApp Images", "Sent")
+	os.MkdirAll(dir, 0755)
+	path := filepath.Join(dir, "IMG-20230514-WA0012.jpg")
+	os.WriteFile(path, buildJPEG(16, segment(0xe1, append([]byte("Exif\x00\x00"), exifTIFF("Good", "2019:02:03 04:05:06")...))), 0644)
+	mtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	os.Chtimes(path, mtime, mtime)
+
+	info, err := GetFileInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DateSource != DateFromFilename || info.Date.Format("2006-01-02") != "2023-05-14" || info.Source != "WhatsApp/Images/Sent" {
+		t.Errorf("dated %s by %s, source %s", info.Date, info.DateSource, info.Source)
+	}
+}
+
+func TestSourceLevels(t *testing.T) {
+	for _, tt := range []struct {
+		source string
+		depth  int
+		want   string
+	}{
+		{"WhatsApp/Images/Sent", 0, "WhatsApp"},
+		{"WhatsApp/Images/Sent", 2, "WhatsApp/Images"},
+		{"WhatsApp/Images/Sent", 5, "WhatsApp/Images/Sent"},
+		{"Camera", 3, "Camera"},
+		{"", 1, ""},
+	} {
+		if got := SourceLevels(tt.source, tt.depth); got != tt.want {
+			t.Errorf("SourceLevels(%q, %d) = %q; want %q", tt.source, tt.depth, got, tt.want)
+		}
+	}
+	if !SourceIn("WhatsApp/Images/Sent", "whatsapp") || !SourceIn("WhatsApp/Images/Sent", "WhatsApp/Images") || !SourceIn("Camera", "camera") {
+		t.Error("SourceIn misses a source within")
+	}
+	if SourceIn("WhatsApp/Images", "WhatsApp/Images/Sent") || SourceIn("WhatsAppX", "WhatsApp") {
+		t.Error("SourceIn matches a source outside")
+	}
+}
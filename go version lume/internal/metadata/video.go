@@ -0,0 +1,269 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNoVideoDate reports a video whose headers carry no recording date.
+var ErrNoVideoDate = errors.New("no recording date in video headers")
+
+// maxVideoHeader bounds how far into a file the AVI and Matroska readers look; MP4 boxes are
+// skipped by seeking, so moov is found even at the end of the file.
+const maxVideoHeader = 1 << 20
+
+var (
+	mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	mkvEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// VideoDate reads the recording date a video stores in its own headers: mvhd for MP4, MOV and 3GP,
+// the IDIT or ICRD chunk for AVI and DateUTC in the Segment Info for Matroska/WebM. Only headers
+// are read, never the media data.
+func VideoDate(path string) (time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	var head [12]byte
+	if _, err := io.ReadFull(f, head[:]); err != nil {
+		return time.Time{}, ErrNoVideoDate
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return time.Time{}, err
+	}
+	switch {
+	case string(head[0:4]) == "RIFF" && string(head[8:12]) == "AVI ":
+		return aviDate(io.LimitReader(f, maxVideoHeader))
+	case bytes.HasPrefix(head[:], []byte{0x1a, 0x45, 0xdf, 0xa3}):
+		return mkvDate(io.LimitReader(f, maxVideoHeader))
+	default:
+		return mp4Date(f)
+	}
+}
+
+// mp4Date finds moov/mvhd by seeking over the top-level boxes; old QuickTime files without ftyp
+// are walked the same way.
+func mp4Date(r io.ReadSeeker) (time.Time, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for off := int64(0); off+8 <= end; {
+		typ, size, hdr, err := boxHeader(r, off, end)
+		if err != nil {
+			break
+		}
+		if typ == "moov" {
+			for c := off + hdr; c+8 <= off+size; {
+				ctyp, csize, chdr, err := boxHeader(r, c, off+size)
+				if err != nil {
+					break
+				}
+				if ctyp == "mvhd" {
+					return mvhdDate(r, c+chdr, csize-chdr)
+				}
+				c += csize
+			}
+			break
+		}
+		off += size
+	}
+	return time.Time{}, ErrNoVideoDate
+}
+
+// boxHeader reads the box at off, returning its type, total size and header length.
+func boxHeader(r io.ReadSeeker, off, limit int64) (string, int64, int64, error) {
+	var b [16]byte
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return "", 0, 0, err
+	}
+	if _, err := io.ReadFull(r, b[:8]); err != nil {
+		return "", 0, 0, err
+	}
+	size, hdr := int64(binary.BigEndian.Uint32(b[:4])), int64(8)
+	switch size {
+	case 0:
+		size = limit - off
+	case 1:
+		if _, err := io.ReadFull(r, b[8:16]); err != nil {
+			return "", 0, 0, err
+		}
+		size, hdr = int64(binary.BigEndian.Uint64(b[8:16])), 16
+	}
+	if size < hdr || off+size > limit {
+		return "", 0, 0, ErrNoVideoDate
+	}
+	return string(b[4:8]), size, hdr, nil
+}
+
+// mvhdDate reads the creation time of a movie header (version 0 or 1), seconds since 1904 in UTC.
+func mvhdDate(r io.ReadSeeker, off, size int64) (time.Time, error) {
+	var b [12]byte
+	if size < 12 {
+		return time.Time{}, ErrNoVideoDate
+	}
+	if _, err := r.Seek(off, io.SeekStart); err != nil {
+		return time.Time{}, err
+	}
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return time.Time{}, ErrNoVideoDate
+	}
+	secs := uint64(binary.BigEndian.Uint32(b[4:8]))
+	if b[0] == 1 {
+		secs = binary.BigEndian.Uint64(b[4:12])
+	}
+	// Zero means unset; anything beyond 2100 is a broken header rather than a recording date.
+	if secs == 0 || secs > 6317683200 {
+		return time.Time{}, ErrNoVideoDate
+	}
+	return mp4Epoch.Add(time.Duration(secs) * time.Second), nil
+}
+
+// aviDateLayouts covers IDIT (ctime style or Exif style) and ICRD (ISO date) values.
+var aviDateLayouts = []string{"Mon Jan _2 15:04:05 2006", "Mon Jan 02 15:04:05 2006", "2006:01:02 15:04:05", "2006-01-02 15:04:05", "2006/01/02 15:04:05", "2006-01-02T15:04:05", "2006-01-02"}
+
+// aviDate walks the RIFF chunks of an AVI header. IDIT, the camera's recording date in hdrl, wins
+// over the ICRD creation date of the INFO list. The movi list is skipped, not read.
+func aviDate(r io.Reader) (time.Time, error) {
+	var head [12]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return time.Time{}, ErrNoVideoDate
+	}
+	var idit, icrd string
+	var walk func(r io.Reader) bool
+	walk = func(r io.Reader) bool {
+		var hdr [8]byte
+		for {
+			if _, err := io.ReadFull(r, hdr[:]); err != nil {
+				return true
+			}
+			id, size := string(hdr[:4]), int64(binary.LittleEndian.Uint32(hdr[4:]))
+			padded := size + size&1
+			switch {
+			case id == "LIST" && size >= 4:
+				var kind [4]byte
+				if _, err := io.ReadFull(r, kind[:]); err != nil {
+					return false
+				}
+				if k := string(kind[:]); k == "hdrl" || k == "INFO" {
+					if !walk(io.LimitReader(r, padded-4)) {
+						return false
+					}
+				} else if _, err := io.CopyN(io.Discard, r, padded-4); err != nil {
+					return false
+				}
+			case (id == "IDIT" || id == "ICRD") && size <= 256:
+				b := make([]byte, padded)
+				if _, err := io.ReadFull(r, b); err != nil {
+					return false
+				}
+				v := strings.TrimSpace(strings.TrimRight(string(b[:size]), "\x00"))
+				if id == "IDIT" {
+					idit = v
+				} else {
+					icrd = v
+				}
+			default:
+				if _, err := io.CopyN(io.Discard, r, padded); err != nil {
+					return false
+				}
+			}
+			if idit != "" {
+				return false
+			}
+		}
+	}
+	walk(r)
+	for _, v := range []string{idit, icrd} {
+		for _, layout := range aviDateLayouts {
+			if t, err := time.Parse(layout, v); err == nil && v != "" {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, ErrNoVideoDate
+}
+
+// Matroska element IDs, marker bits included.
+const (
+	ebmlHeader  = 0x1a45dfa3
+	mkvSegment  = 0x18538067
+	mkvInfo     = 0x1549a966
+	mkvCluster  = 0x1f43b675
+	mkvDateUTC  = 0x4461
+	ebmlUnknown = -1
+)
+
+// ebmlVint reads an EBML variable-length integer; keepMarker leaves the length marker in place,
+// as element IDs are written with it. An all-ones size is returned as ebmlUnknown.
+func ebmlVint(r io.Reader, keepMarker bool) (int64, error) {
+	var b [8]byte
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return 0, err
+	}
+	n := 1
+	for mask := byte(0x80); n <= 8 && b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 || (keepMarker && n > 4) {
+		return 0, ErrNoVideoDate
+	}
+	if _, err := io.ReadFull(r, b[1:n]); err != nil {
+		return 0, err
+	}
+	v, allOnes := int64(b[0]), b[0]|byte(0xff<<(8-n)) == 0xff
+	if !keepMarker {
+		v &= int64(0xff >> n)
+	}
+	for _, c := range b[1:n] {
+		v = v<<8 | int64(c)
+		allOnes = allOnes && c == 0xff
+	}
+	if !keepMarker && allOnes {
+		return ebmlUnknown, nil
+	}
+	return v, nil
+}
+
+// mkvDate reads DateUTC, nanoseconds since 2001 in UTC, from the Segment Info, stopping at the first
+// Cluster.
+func mkvDate(r io.Reader) (time.Time, error) {
+	for {
+		id, err := ebmlVint(r, true)
+		if err != nil {
+			return time.Time{}, ErrNoVideoDate
+		}
+		size, err := ebmlVint(r, false)
+		if err != nil {
+			return time.Time{}, ErrNoVideoDate
+		}
+		switch id {
+		case mkvSegment, mkvInfo:
+			continue // descend: their children follow directly
+		case mkvCluster:
+			return time.Time{}, ErrNoVideoDate
+		case mkvDateUTC:
+			if size != 8 {
+				return time.Time{}, ErrNoVideoDate
+			}
+			var b [8]byte
+			if _, err := io.ReadFull(r, b[:]); err != nil {
+				return time.Time{}, ErrNoVideoDate
+			}
+			return mkvEpoch.Add(time.Duration(int64(binary.BigEndian.Uint64(b[:])))), nil
+		}
+		if size == ebmlUnknown {
+			return time.Time{}, ErrNoVideoDate
+		}
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return time.Time{}, ErrNoVideoDate
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package metadata
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestVideoDate(t *testing.T) {
+	// Fixtures: recorded.3gp keeps moov after a large mdat, recorded_v1.mov has a version 1 mvhd
+	// behind a 64-bit box, idit.avi has both IDIT and ICRD (IDIT wins), icrd.avi only ICRD after movi,
+	// dated.mkv has an unknown-size Segment with a SeekHead before Info, undated.webm has no DateUTC.
+	tests := []struct {
+		file, want string
+	}{
+		{"recorded.3gp", "2012-08-15 10:20:30"},
+		{"recorded_v1.mov", "2019-03-01 08:00:00"},
+		{"idit.avi", "2005-03-05 14:03:07"},
+		{"icrd.avi", "2007-06-30 00:00:00"},
+		{"dated.mkv", "2021-12-24 18:30:00"},
+		{"undated.webm", ""},
+		{"exif.png", ""},
+	}
+	for _, tt := range tests {
+		got, err := VideoDate(filepath.Join("testdata", tt.file))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: VideoDate = %v; want an error", tt.file, got)
+			}
+			continue
+		}
+		if err != nil || got.Format("2006-01-02 15:04:05") != tt.want {
+			t.Errorf("%s: VideoDate = %v, %v; want %s", tt.file, got, err, tt.want)
+		}
+	}
+
+	info, err := GetFileInfo(filepath.Join("testdata", "recorded.3gp"))
+	if err != nil || info.DateSource != DateFromVideo || info.Year != "2012" {
+		t.Errorf("GetFileInfo(recorded.3gp) = %+v, %v", info, err)
+	}
+}
@@ -0,0 +1,315 @@
+// Package monthindex keeps an index.json in each month folder of an archive, listing the files under
+// it with their date, device, source, size and hash, for browsing the archive and for other tools to
+// read without going through its files. A run adds the files it archives as it goes; Rebuild lists an
+// archive from its files as they are.
+package monthindex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the index of a month folder.
+const FileName = "index.json"
+
+// Version is that of the index format written; indexes of another are rebuilt, unless a later Lume
+// wrote them.
+const Version = 1
+
+// Entry is a file of an index.
+type Entry struct {
+	// Name is the path of the file relative to the month folder, with forward slashes.
+	Name   string    `json:"name"`
+	Date   time.Time `json:"date"`
+	Device string    `json:"device,omitempty"`
+	Source string    `json:"source,omitempty"`
+	Size   int64     `json:"size"`
+	// Hash is the lowercase hex MD5 of the file, as the catalog keeps it.
+	Hash string `json:"hash"`
+}
+
+// File is an index as it is written.
+type File struct {
+	Version int     `json:"version"`
+	Files   []Entry `json:"files"`
+}
+
+// ErrNewer reports an index written by a later Lume, which is left as it is.
+var ErrNewer = errors.New("index of a later format version")
+
+// Folder returns the month folder under root that holds path, whose index lists it, and the name of
+// path in that index. depth is the folder level below root of the month folders of the layout the
+// file was filed by (see organizer.Layout.MonthDepth); a file above that level is listed in its top
+// folder, and a depth of zero, for folders taken as they are, lists each file in its own folder.
+// Files directly in root and under folders Lume keeps for itself are not listed.
+func Folder(root, path string, depth int) (dir, name string, ok bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." || skip(parts[0]) || skip(parts[len(parts)-1]) {
+		return "", "", false
+	}
+	if depth <= 0 || depth > len(parts)-1 {
+		depth = len(parts) - 1
+	}
+	return filepath.Join(root, filepath.Join(parts[:depth]...)), strings.Join(parts[depth:], "/"), true
+}
+
+// skip reports the names never listed: the indexes themselves, the checksum lists and Lume's own files
+// and folders.
+func skip(name string) bool { return metadata.OwnFile(name) }
+
+// Read returns the entries of the index at path; a missing index has none. An index that does not
+// parse, or is of an earlier version, is reported as an error; one of a later version as ErrNewer.
+func Read(path string) ([]Entry, error) {
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch {
+	case f.Version > Version:
+		return nil, fmt.Errorf("%s: %w (%d)", path, ErrNewer, f.Version)
+	case f.Version < Version:
+		return nil, fmt.Errorf("%s: format version %d", path, f.Version)
+	}
+	return f.Files, nil
+}
+
+// Write replaces the index at path with entries, sorted by name. The index is written beside it and
+// renamed over it, so a reader or a crash never sees half an index.
+func Write(path string, entries []Entry) error {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	b, err := json.MarshalIndent(File{Version: Version, Files: entries}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lume_monthindex_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(b, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Index queues the files archived into root and merges them into the indexes of their month
+// folders on Flush, one folder at a time, so files moved side by side into one month never write
+// its index at once. Indexes that cannot be written keep their entries queued for the next Flush.
+type Index struct {
+	root    string
+	mu      sync.Mutex
+	pending map[string]map[string]Entry // month folder -> name -> entry
+	depths  map[string]int              // month folder -> its level below root
+}
+
+// New returns an Index for the archive at root.
+func New(root string) *Index {
+	return &Index{root: root, pending: make(map[string]map[string]Entry), depths: make(map[string]int)}
+}
+
+// Add queues the entry of info, archived at path by a layout whose month folders lie depth levels
+// below the root (see Folder), with the MD5 hash; an empty hash is read from the file.
+func (x *Index) Add(path string, depth int, info metadata.FileInfo, hash string) error {
+	dir, name, ok := Folder(x.root, path, depth)
+	if !ok {
+		return nil
+	}
+	if hash == "" {
+		var err error
+		if hash, err = metadata.GetFileHash(path); err != nil {
+			return fmt.Errorf("md5 of %s: %w", path, err)
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.pending[dir] == nil {
+		x.pending[dir] = make(map[string]Entry)
+	}
+	x.depths[dir] = depth
+	x.pending[dir][name] = Entry{Name: name, Date: info.Date, Device: info.Device, Source: info.Source, Size: info.Size, Hash: hash}
+	return nil
+}
+
+// Queued reports how many entries are still waiting to be written.
+func (x *Index) Queued() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n := 0
+	for _, names := range x.pending {
+		n += len(names)
+	}
+	return n
+}
+
+// Flush merges the queued entries into their indexes, replacing older entries of the same name and
+// dropping those of files no longer there. An index that cannot be read is rebuilt from the files
+// of its folder instead, the queued entries kept. One a later Lume wrote is left alone and its queued
+// entries dropped, reported in the error.
+func (x *Index) Flush() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var first error
+	for dir, names := range x.pending {
+		path := filepath.Join(dir, FileName)
+		entries, err := Read(path)
+		if errors.Is(err, ErrNewer) {
+			delete(x.pending, dir)
+			delete(x.depths, dir)
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		if err != nil {
+			entries, err = folderEntries(context.Background(), x.root, dir, x.depths[dir], nil)
+		}
+		if err == nil {
+			kept := entries[:0]
+			for _, e := range entries {
+				if _, ok := names[e.Name]; ok {
+					continue
+				}
+				if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(e.Name))); err == nil {
+					kept = append(kept, e)
+				}
+			}
+			for _, e := range names {
+				kept = append(kept, e)
+			}
+			err = Write(path, kept)
+		}
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		delete(x.pending, dir)
+		delete(x.depths, dir)
+	}
+	return first
+}
+
+// Close flushes the queue, retrying briefly while an index stays locked.
+func (x *Index) Close() error {
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = x.Flush(); err == nil {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("monthindex: %d entries not written: %w", x.Queued(), err)
+}
+
+// folderEntries reads the entries of the files under the month folder dir of root, depth levels below
+// it, from the files themselves, counting each in done.
+func folderEntries(ctx context.Context, root, dir string, depth int, done func()) ([]Entry, error) {
+	var entries []Entry
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && (skip(d.Name()) || depth <= 0) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		mdir, name, ok := Folder(root, path, depth)
+		if !ok || mdir != dir || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := metadata.GetFileInfo(path)
+		if err != nil {
+			return err
+		}
+		hash, err := metadata.GetFileHashContext(ctx, path)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, Entry{Name: name, Date: info.Date, Device: info.Device, Source: info.Source, Size: info.Size, Hash: hash})
+		if done != nil {
+			done()
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// Rebuild lists every file of the archive at root from its metadata and content and replaces the
+// index of each month folder, depth levels below root as for Folder, reporting its progress in files.
+// A cancelled rebuild leaves the folders it has not finished with their old indexes. It returns the
+// number of files listed.
+func Rebuild(ctx context.Context, root string, depth int, progress func(done, total int)) (int, error) {
+	counts := make(map[string]int)
+	var order []string
+	total := 0
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skip(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir, _, ok := Folder(root, path, depth)
+		if !ok || !d.Type().IsRegular() {
+			return nil
+		}
+		if counts[dir] == 0 {
+			order = append(order, dir)
+		}
+		counts[dir]++
+		total++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	done, listed := 0, 0
+	for _, dir := range order {
+		entries, err := folderEntries(ctx, root, dir, depth, func() {
+			done++
+			if progress != nil {
+				progress(done, total)
+			}
+		})
+		if err != nil {
+			return listed, err
+		}
+		if err := Write(filepath.Join(dir, FileName), entries); err != nil {
+			return listed, err
+		}
+		listed += len(entries)
+	}
+	return listed, nil
+}
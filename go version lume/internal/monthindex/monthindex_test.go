@@ -0,0 +1,168 @@
+package monthindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"lume-go/internal/metadata"
+)
+
+func TestFolder(t *testing.T) {
+	root := filepath.Join("archive")
+	tests := []struct {
+		path      string
+		depth     int
+		dir, name string
+		ok        bool
+	}{
+		{filepath.Join(root, "2024", "03", "Apple_iPhone 12", "a b.jpg"), 2, filepath.Join(root, "2024", "03"), "Apple_iPhone 12/a b.jpg", true},
+		{filepath.Join(root, "2024", "03", "a.jpg"), 2, filepath.Join(root, "2024", "03"), "a.jpg", true},
+		{filepath.Join(root, "2024", "a.jpg"), 2, filepath.Join(root, "2024"), "a.jpg", true},
+		{filepath.Join(root, "a.jpg"), 2, "", "", false},
+		{filepath.Join(root, ".lume_trash", "2024", "a.jpg"), 2, "", "", false},
+		{filepath.Join(root, "2024", "03", FileName), 2, "", "", false},
+		{filepath.Join(root, "2024", "SHA256SUMS"), 2, "", "", false},
+		{filepath.Join("elsewhere", "2024", "03", "a.jpg"), 2, "", "", false},
+		// {year} alone, the months of {device}/{year}/{month}, and folders taken as they are.
+		{filepath.Join(root, "2024", "Camera", "a.jpg"), 1, filepath.Join(root, "2024"), "Camera/a.jpg", true},
+		{filepath.Join(root, "Camera", "2024", "03", "a.jpg"), 3, filepath.Join(root, "Camera", "2024", "03"), "a.jpg", true},
+		{filepath.Join(root, "Trips", "Rome", "day 1", "a.jpg"), 0, filepath.Join(root, "Trips", "Rome", "day 1"), "a.jpg", true},
+		{filepath.Join(root, "a.jpg"), 0, "", "", false},
+	}
+	for _, tt := range tests {
+		dir, name, ok := Folder(root, tt.path, tt.depth)
+		if dir != tt.dir || name != tt.name || ok != tt.ok {
+			t.Errorf("Folder(%s, %d) = %q, %q, %v", tt.path, tt.depth, dir, name, ok)
+		}
+	}
+}
+
+// write writes a file of the archive.
+func write(t *testing.T, path, content string) string {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAddAndFlush(t *testing.T) {
+	root := t.TempDir()
+	month := filepath.Join(root, "2024", "03")
+	date := time.Date(2024, 3, 9, 14, 30, 0, 0, time.UTC)
+
+	// Files moved side by side into one month are listed together.
+	x := New(root)
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := write(t, filepath.Join(month, "Pixel 7", fmt.Sprintf("IMG_%d.jpg", i)), "photo")
+			if err := x.Add(p, 2, metadata.FileInfo{Date: date, Device: "Pixel 7", Source: "Camera", Size: 5}, "abc"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := x.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(filepath.Join(month, FileName))
+	if err != nil || len(got) != 8 {
+		t.Fatalf("after Add: %d entries, %v", len(got), err)
+	}
+	if e := got[0]; e.Name != "Pixel 7/IMG_0.jpg" || !e.Date.Equal(date) || e.Device != "Pixel 7" || e.Source != "Camera" || e.Size != 5 || e.Hash != "abc" {
+		t.Errorf("entry %+v", e)
+	}
+
+	// A later run merges into the index, replacing the entry of a file written again and dropping
+	// those of files gone since; the file's own hash is read when the move did not verify one.
+	os.Remove(filepath.Join(month, "Pixel 7", "IMG_7.jpg"))
+	again := write(t, filepath.Join(month, "Pixel 7", "IMG_0.jpg"), "again")
+	x.Add(again, 2, metadata.FileInfo{Date: date, Size: 5}, "")
+	if err := x.Flush(); err != nil || x.Queued() != 0 {
+		t.Fatalf("Flush: %v, %d queued", err, x.Queued())
+	}
+	got, _ = Read(filepath.Join(month, FileName))
+	want, _ := metadata.GetFileHash(again)
+	if len(got) != 7 || got[0].Hash != want {
+		t.Errorf("after merge: %+v", got)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(month, ".lume_monthindex_*")); len(leftovers) != 0 {
+		t.Errorf("temporary indexes left: %v", leftovers)
+	}
+}
+
+func TestFlushRepairsIndex(t *testing.T) {
+	root := t.TempDir()
+	month := filepath.Join(root, "2024", "05")
+	write(t, filepath.Join(month, "old.jpg"), "old")
+	write(t, filepath.Join(month, FileName), `{"version":1,"files":[{"name":"old.jpg"`)
+
+	// A corrupted index is rebuilt from the folder; the move that found it is not failed.
+	x := New(root)
+	x.Add(write(t, filepath.Join(month, "new.jpg"), "new"), 2, metadata.FileInfo{Size: 3}, "abc")
+	if err := x.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(filepath.Join(month, FileName))
+	if err != nil || len(got) != 2 || got[0].Name != "new.jpg" || got[0].Hash != "abc" || got[1].Name != "old.jpg" || got[1].Hash == "" {
+		t.Fatalf("after repair: %+v, %v", got, err)
+	}
+
+	// An index of a later Lume is left as it is.
+	later := `{"version":2,"files":[]}`
+	write(t, filepath.Join(month, FileName), later)
+	x.Add(filepath.Join(month, "new.jpg"), 2, metadata.FileInfo{Size: 3}, "abc")
+	if err := x.Flush(); !errors.Is(err, ErrNewer) || x.Queued() != 0 {
+		t.Errorf("Flush over a later index: %v, %d queued", err, x.Queued())
+	}
+	if b, _ := os.ReadFile(filepath.Join(month, FileName)); string(b) != later {
+		t.Errorf("later index rewritten: %s", b)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "2024", "01", "Canon", "a.jpg"), "a")
+	write(t, filepath.Join(root, "2024", "01", "b.jpg"), "b")
+	write(t, filepath.Join(root, "2024", "02", "c.jpg"), "c")
+	write(t, filepath.Join(root, "2024", "SHA256SUMS"), "")
+	write(t, filepath.Join(root, ".lume_catalog.jsonl"), "{}")
+
+	var steps int
+	n, err := Rebuild(context.Background(), root, 2, func(done, total int) {
+		if steps++; done != steps || total != 3 {
+			t.Errorf("progress %d/%d at step %d", done, total, steps)
+		}
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("Rebuild = %d, %v", n, err)
+	}
+	got, _ := Read(filepath.Join(root, "2024", "01", FileName))
+	if len(got) != 2 || got[0].Name != "Canon/a.jpg" || got[1].Name != "b.jpg" || got[0].Size != 1 || got[0].Hash == "" {
+		t.Errorf("January: %+v", got)
+	}
+	if got, _ := Read(filepath.Join(root, "2024", "02", FileName)); len(got) != 1 {
+		t.Errorf("February: %+v", got)
+	}
+	for _, p := range []string{filepath.Join(root, FileName), filepath.Join(root, "2024", FileName)} {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("index written at %s: %v", p, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Rebuild(ctx, root, 2, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled Rebuild: %v", err)
+	}
+}
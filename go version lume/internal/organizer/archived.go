@@ -0,0 +1,88 @@
+package organizer
+
+import (
+	"lume-go/internal/metadata"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// InPlace reports whether info already is where layout files it under base, in any month style, so
+// that organizing it would only move it onto itself. Paths compare as Windows does, ignoring case.
+func InPlace(info metadata.FileInfo, base string, layout Layout) bool {
+	path := filepath.Clean(info.Path)
+	for _, l := range append([]Layout{layout}, layout.monthVariants()...) {
+		want := filepath.Join(TargetDir(info, base, l), SanitizeFileName(FileName(info, l)))
+		if strings.EqualFold(path, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// ArchivedLayout reports whether the folders above info lay it out as the template of layout would,
+// under whatever root: the file sits in an archive Lume made before, or one shaped like it. The date
+// tokens must render as they do for info, {month} in any month style, while {device}, {source},
+// {model} and {burst} take any folder name, such as a device folder renamed by hand. A template
+// without a date that renders for info matches nothing, as any folder would fit it. rel is the
+// matching folders, from below the root down to the file's own.
+func ArchivedLayout(info metadata.FileInfo, layout Layout) (rel string, ok bool) {
+	pattern, dated := layoutPattern(info, layout)
+	if !dated {
+		return "", false
+	}
+	m := pattern.FindStringSubmatch(filepath.ToSlash(filepath.Dir(filepath.Clean(info.Path))))
+	if m == nil {
+		return "", false
+	}
+	return filepath.FromSlash(m[1]), true
+}
+
+// layoutPattern is the regular expression of the folders layout renders for info, matched at the end
+// of a slash-separated path; dated reports whether a date token rendered into it.
+func layoutPattern(info metadata.FileInfo, layout Layout) (pattern *regexp.Regexp, dated bool) {
+	months := []string{""}
+	if info.Month != "" {
+		months = nil
+		for _, l := range append([]Layout{layout}, layout.monthVariants()...) {
+			months = append(months, regexp.QuoteMeta(SanitizeFolderName(l.MonthLabel(info.Month))))
+		}
+	}
+	values := map[string]string{
+		"{year}": info.Year, "{month}": info.Month, "{day}": info.Day, "{weekyear}": info.WeekYear, "{week}": info.Week,
+		"{device}": DeviceFolder(info), "{source}": info.Source, "{model}": info.Device, "{burst}": info.Burst,
+	}
+	sourceLevels := strings.Count(metadata.SourceLevels(info.Source, layout.SourceDepth), metadata.SourceSep)
+	var segs []string
+	for _, seg := range strings.Split(filepath.ToSlash(layout.template()), "/") {
+		// A segment is dropped where TargetDir drops it.
+		seg = strings.TrimSpace(seg)
+		if strings.TrimSpace(tokenPattern.ReplaceAllStringFunc(seg, func(t string) string { return values[t] })) == "" {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range tokenPattern.FindAllStringIndex(seg, -1) {
+			b.WriteString(regexp.QuoteMeta(seg[last:loc[0]]))
+			last = loc[1]
+			switch t := seg[loc[0]:loc[1]]; t {
+			case "{device}", "{model}", "{burst}":
+				b.WriteString("[^/]*")
+			case "{source}":
+				b.WriteString("[^/]+" + strings.Repeat("/[^/]+", sourceLevels))
+			case "{month}":
+				dated = dated || info.Month != ""
+				b.WriteString("(?:" + strings.Join(months, "|") + ")")
+			default:
+				dated = dated || values[t] != ""
+				b.WriteString(regexp.QuoteMeta(values[t]))
+			}
+		}
+		b.WriteString(regexp.QuoteMeta(seg[last:]))
+		segs = append(segs, b.String())
+	}
+	return regexp.MustCompile(`(?i)(?:^|/)(` + strings.Join(segs, "/") + `)$`), dated
+}
+
+// tokenPattern matches the TemplateTokens.
+var tokenPattern = regexp.MustCompile(`\{(?:year|month|day|week|weekyear|device|source|model|burst)\}`)
@@ -0,0 +1,152 @@
+package organizer
+
+import (
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BurstPrefix starts every burst folder name; the rest is the date and time of the first shot,
+// Burst_20240512_143022, so bursts of other days at the same time never share a folder. Folders of
+// earlier versions carry the time alone, Burst_143022.
+const BurstPrefix = "Burst_"
+
+// burstStamp and burstClock lay out the rest of a burst folder name, as named now and before.
+const (
+	burstStamp = "20060102_150405"
+	burstClock = "150405"
+)
+
+// BurstOptions detects continuous shooting: runs of at least MinCount photos from one device whose
+// EXIF timestamps are at most Gap apart. A MinCount below 2, which no burst can have, turns detection
+// off.
+type BurstOptions struct {
+	MinCount int
+	Gap      time.Duration
+}
+
+// MarkBursts sets Burst on every file that belongs to a burst and clears it on the others. Only
+// EXIF dates count, and the result does not depend on the order of files.
+func MarkBursts(files []metadata.FileInfo, opts BurstOptions) {
+	byDevice := make(map[string][]int)
+	for i := range files {
+		files[i].Burst = ""
+		if opts.MinCount > 1 && files[i].DateSource == metadata.DateFromExif {
+			byDevice[files[i].Device] = append(byDevice[files[i].Device], i)
+		}
+	}
+	for _, idx := range byDevice {
+		sort.SliceStable(idx, func(a, b int) bool { return shotBefore(files[idx[a]], files[idx[b]]) })
+		start := 0
+		for i := 1; i <= len(idx); i++ {
+			if i < len(idx) && files[idx[i]].Date.Sub(files[idx[i-1]].Date) <= opts.Gap {
+				continue
+			}
+			if i-start >= opts.MinCount {
+				id := BurstPrefix + files[idx[start]].Date.Format(burstStamp)
+				for _, j := range idx[start:i] {
+					files[j].Burst = id
+				}
+			}
+			start = i
+		}
+	}
+}
+
+// JoinBursts renames the bursts of files that continue a burst folder archived by an earlier run,
+// so later additions land next to the earlier shots: the folder's last shot is at most Burst.Gap
+// before the first shot of the new burst. place returns the target base and layout of a file. Only
+// templates where {burst} is a folder of its own are looked at.
+func JoinBursts(files []metadata.FileInfo, place func(metadata.FileInfo) (string, Layout)) {
+	first := make(map[string]int) // burst ID -> index of its earliest file
+	for i, f := range files {
+		if j, ok := first[f.Burst]; f.Burst != "" && (!ok || f.Date.Before(files[j].Date)) {
+			first[f.Burst] = i
+		}
+	}
+	rename := make(map[string]string)
+	for id, i := range first {
+		base, layout := place(files[i])
+		parent, ok := burstParent(files[i], base, layout)
+		if !ok {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(parent, id)); err == nil {
+			continue // the burst already has its folder
+		}
+		if prev := precedingBurst(parent, files[i].Date, layout.Burst.Gap); prev != "" {
+			rename[id] = prev
+		}
+	}
+	for i := range files {
+		if to, ok := rename[files[i].Burst]; ok {
+			files[i].Burst = to
+		}
+	}
+}
+
+// burstParent renders the folders above a {burst} segment.
+func burstParent(info metadata.FileInfo, base string, layout Layout) (string, bool) {
+	segs := strings.Split(filepath.ToSlash(layout.template()), "/")
+	for i, seg := range segs {
+		if strings.TrimSpace(seg) == "{burst}" {
+			layout.Template = strings.Join(segs[:i], "/")
+			if layout.Template == "" {
+				return base, true
+			}
+			return TargetDir(info, base, layout), true
+		}
+	}
+	return "", false
+}
+
+// precedingBurst finds the burst folder in parent started the same day before t whose last shot
+// is within gap of t.
+func precedingBurst(parent string, t time.Time, gap time.Duration) string {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() || !strings.HasPrefix(name, BurstPrefix) {
+			continue
+		}
+		start, err := time.ParseInLocation(burstStamp, strings.TrimPrefix(name, BurstPrefix), t.Location())
+		if err != nil {
+			clock, err := time.ParseInLocation(burstClock, strings.TrimPrefix(name, BurstPrefix), t.Location())
+			if err != nil {
+				continue
+			}
+			start = time.Date(t.Year(), t.Month(), t.Day(), clock.Hour(), clock.Minute(), clock.Second(), 0, t.Location())
+		}
+		if start.After(t) || start.YearDay() != t.YearDay() || start.Year() != t.Year() {
+			continue
+		}
+		if last, ok := lastShot(filepath.Join(parent, name)); ok && !last.After(t) && t.Sub(last) <= gap {
+			return name
+		}
+	}
+	return ""
+}
+
+// lastShot is the latest capture date among the files in dir.
+func lastShot(dir string) (time.Time, bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return time.Time{}, false
+	}
+	var last time.Time
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if info, err := metadata.GetFileInfo(filepath.Join(dir, e.Name())); err == nil && info.Date.After(last) {
+			last = info.Date
+		}
+	}
+	return last, !last.IsZero()
+}
@@ -0,0 +1,156 @@
+package organizer
+
+import (
+	"fmt"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// shots builds EXIF-dated photos of one device at the given offsets in seconds from 14:30:00.
+func shots(device string, offsets ...int) []metadata.FileInfo {
+	base := time.Date(2024, 5, 12, 14, 30, 0, 0, time.UTC)
+	var out []metadata.FileInfo
+	for i, s := range offsets {
+		t := base.Add(time.Duration(s) * time.Second)
+		out = append(out, metadata.FileInfo{Path: fmt.Sprintf("%s_%02d.jpg", device, i), Filename: fmt.Sprintf("%s_%02d.jpg", device, i), Date: t, DateSource: metadata.DateFromExif, Device: device})
+	}
+	return out
+}
+
+func bursts(files []metadata.FileInfo) map[string]string {
+	out := make(map[string]string)
+	for _, f := range files {
+		out[f.Path] = f.Burst
+	}
+	return out
+}
+
+func TestMarkBursts(t *testing.T) {
+	opts := BurstOptions{MinCount: 3, Gap: 2 * time.Second}
+	tests := []struct {
+		name    string
+		offsets []int
+		want    []string
+	}{
+		{"gap exactly at the limit", []int{0, 2, 4}, []string{"Burst_20240512_143000", "Burst_20240512_143000", "Burst_20240512_143000"}},
+		{"gap one second over splits", []int{0, 2, 5, 6, 7}, []string{"", "", "Burst_20240512_143005", "Burst_20240512_143005", "Burst_20240512_143005"}},
+		{"one short of MinCount", []int{0, 1}, []string{"", ""}},
+		{"same second", []int{22, 22, 22}, []string{"Burst_20240512_143022", "Burst_20240512_143022", "Burst_20240512_143022"}},
+		{"two bursts", []int{0, 1, 2, 10, 11, 12}, []string{"Burst_20240512_143000", "Burst_20240512_143000", "Burst_20240512_143000", "Burst_20240512_143010", "Burst_20240512_143010", "Burst_20240512_143010"}},
+	}
+	for _, tt := range tests {
+		files := shots("X100", tt.offsets...)
+		MarkBursts(files, opts)
+		for i, f := range files {
+			if f.Burst != tt.want[i] {
+				t.Errorf("%s: shot %d Burst = %q; want %q", tt.name, i, f.Burst, tt.want[i])
+			}
+		}
+	}
+
+	// Another device shooting in between neither joins nor breaks the burst, and order does not matter.
+	files := append(shots("A", 0, 1, 2), shots("B", 1)...)
+	rev := make([]metadata.FileInfo, len(files))
+	for i, f := range files {
+		rev[len(files)-1-i] = f
+	}
+	MarkBursts(files, opts)
+	MarkBursts(rev, opts)
+	a, b := bursts(files), bursts(rev)
+	for p, id := range a {
+		if b[p] != id {
+			t.Errorf("%s: Burst %q depends on input order (%q)", p, id, b[p])
+		}
+	}
+	if a["A_00.jpg"] != "Burst_20240512_143000" || a["B_00.jpg"] != "" {
+		t.Errorf("mixed devices: %v", a)
+	}
+
+	// Without an EXIF date a file cannot be placed in a burst; with detection off nothing is marked.
+	files = shots("X100", 0, 1, 2)
+	files[1].DateSource = metadata.DateFromModTime
+	MarkBursts(files, opts)
+	if files[0].Burst != "" {
+		t.Error("burst formed across a file without EXIF date")
+	}
+	files = shots("X100", 0, 1, 2)
+	MarkBursts(files, BurstOptions{})
+	if files[0].Burst != "" {
+		t.Error("burst marked with detection off")
+	}
+	// A MinCount of one would make a burst of every photo.
+	files = shots("X100", 0, 10)
+	MarkBursts(files, BurstOptions{MinCount: 1, Gap: time.Second})
+	if files[0].Burst != "" || files[1].Burst != "" {
+		t.Error("single shots marked as bursts")
+	}
+}
+
+func TestJoinBursts(t *testing.T) {
+	root := t.TempDir()
+	layout := Layout{Template: "{year}/{month}/{burst}", Burst: BurstOptions{MinCount: 3, Gap: 2 * time.Second}}
+
+	// An earlier run archived the first three shots.
+	earlier := shots("X100", 0, 1, 2)
+	MarkBursts(earlier, layout.Burst)
+	for _, f := range earlier {
+		dir := TargetDir(f, root, layout)
+		os.MkdirAll(dir, 0755)
+		p := filepath.Join(dir, f.Filename)
+		os.WriteFile(p, []byte("x"), 0644)
+		os.Chtimes(p, f.Date, f.Date) // no EXIF in the test files, so the date comes from mtime
+	}
+
+	later := shots("X100", 4, 5, 6)
+	MarkBursts(later, layout.Burst)
+	JoinBursts(later, func(metadata.FileInfo) (string, Layout) { return root, layout })
+	if later[0].Burst != "Burst_20240512_143000" {
+		t.Errorf("continuation got %q; want Burst_20240512_143000", later[0].Burst)
+	}
+
+	apart := shots("X100", 30, 31, 32)
+	MarkBursts(apart, layout.Burst)
+	JoinBursts(apart, func(metadata.FileInfo) (string, Layout) { return root, layout })
+	if apart[0].Burst != "Burst_20240512_143030" {
+		t.Errorf("separate burst got %q; want Burst_20240512_143030", apart[0].Burst)
+	}
+}
+
+func TestJoinBurstsOtherDays(t *testing.T) {
+	root := t.TempDir()
+	layout := Layout{Template: "{year}/{month}/{burst}", Burst: BurstOptions{MinCount: 3, Gap: 2 * time.Second}}
+	archive := func(files []metadata.FileInfo, folder string) {
+		for _, f := range files {
+			dir := filepath.Join(TargetDir(f, root, Layout{Template: "{year}/{month}"}), folder)
+			os.MkdirAll(dir, 0755)
+			p := filepath.Join(dir, f.Filename)
+			os.WriteFile(p, []byte("x"), 0644)
+			os.Chtimes(p, f.Date, f.Date)
+		}
+	}
+	// A burst shot at the same time a day earlier has a folder of its own.
+	earlier := shots("X100", 0, 1, 2)
+	for i := range earlier {
+		earlier[i].Date = earlier[i].Date.AddDate(0, 0, -1)
+	}
+	MarkBursts(earlier, layout.Burst)
+	archive(earlier, earlier[0].Burst)
+	today := shots("X100", 0, 1, 2)
+	MarkBursts(today, layout.Burst)
+	JoinBursts(today, func(metadata.FileInfo) (string, Layout) { return root, layout })
+	if today[0].Burst == earlier[0].Burst || today[0].Burst != "Burst_20240512_143000" {
+		t.Errorf("burst of another day got %q, the earlier one %q", today[0].Burst, earlier[0].Burst)
+	}
+
+	// A folder named by an earlier version, by the time alone, is still continued.
+	archive(shots("Y", 100, 101, 102), "Burst_143140")
+	later := shots("Y", 104, 105, 106)
+	MarkBursts(later, layout.Burst)
+	JoinBursts(later, func(metadata.FileInfo) (string, Layout) { return root, layout })
+	if later[0].Burst != "Burst_143140" {
+		t.Errorf("continuation of an old folder got %q; want Burst_143140", later[0].Burst)
+	}
+}
@@ -0,0 +1,28 @@
+package organizer
+
+import (
+	"context"
+	"time"
+)
+
+// finishing is ctx without its cancellation but with its deadline: the user's cancel no longer
+// stops what it runs, a per-file timeout still does.
+func finishing(ctx context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithDeadline(context.WithoutCancel(ctx), deadline)
+	}
+	return context.WithoutCancel(ctx), func() {}
+}
+
+// timeoutPerMB is how long a file may take per megabyte on top of its base timeout: a move reads it
+// up to three times (hash, copy, verify), so this allows for a share doing well under 2 MB/s.
+const timeoutPerMB = 2 * time.Second
+
+// FileTimeout is how long organizing a file of size bytes may take given a base timeout for any
+// file; a non-positive base means no limit.
+func FileTimeout(base time.Duration, size int64) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	return base + time.Duration(size>>20)*timeoutPerMB
+}
@@ -0,0 +1,57 @@
+package organizer
+
+import (
+	"lume-go/internal/fsys"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DirIndex lists the files of destination folders by size, each folder read once, when a run first
+// files something into it. A file arriving as IMG_0001 (1).JPG then finds the IMG_0001.JPG already
+// there: the two are hashed only when their sizes match, so a folder with no file of the same size
+// costs one directory read per run. It is safe for concurrent use.
+type DirIndex struct {
+	mu   sync.Mutex
+	dirs map[string]map[int64][]string
+}
+
+// NewDirIndex returns an empty index, for one run.
+func NewDirIndex() *DirIndex {
+	return &DirIndex{dirs: make(map[string]map[int64][]string)}
+}
+
+// dirKey is dir as the index keys it; Windows names are not case sensitive.
+func dirKey(dir string) string { return strings.ToLower(filepath.Clean(dir)) }
+
+// sameSize returns the files of dir of size bytes, reading dir from fs the first time. A folder that
+// does not exist yet has none.
+func (d *DirIndex) sameSize(fs fsys.FS, dir string, size int64) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := dirKey(dir)
+	sizes, ok := d.dirs[key]
+	if !ok {
+		sizes = make(map[int64][]string)
+		entries, _ := fs.ReadDir(dir)
+		for _, e := range entries {
+			if !e.Type().IsRegular() {
+				continue
+			}
+			if fi, err := e.Info(); err == nil {
+				sizes[fi.Size()] = append(sizes[fi.Size()], filepath.Join(dir, e.Name()))
+			}
+		}
+		d.dirs[key] = sizes
+	}
+	return append([]string(nil), sizes[size]...)
+}
+
+// add lists a file just archived at path, if its folder is indexed.
+func (d *DirIndex) add(path string, size int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if sizes, ok := d.dirs[dirKey(filepath.Dir(path))]; ok {
+		sizes[size] = append(sizes[size], path)
+	}
+}
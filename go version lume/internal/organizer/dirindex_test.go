@@ -0,0 +1,68 @@
+package organizer
+
+import (
+	"lume-go/internal/fsys"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveFileSameFolderOtherName(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	dir := filepath.Join(root, "2024", "05", "Camera")
+	os.MkdirAll(dir, 0755)
+	// Decoys of the same size as the photo, and one of its content but another size.
+	archived := map[string]string{"IMG_0001.JPG": "photo A", "IMG_0002.JPG": "photo B", "IMG_0003.JPG": "photo C", "IMG_0004.JPG": "photo A, edited"}
+	for name, content := range archived {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	incoming := func(name, content string) metadata.FileInfo {
+		path := filepath.Join(src, name)
+		os.WriteFile(path, []byte(content), 0644)
+		return metadata.FileInfo{Path: path, Filename: name, Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+	}
+
+	fs := &countingFS{FS: fsys.OS, opens: make(map[string]int)}
+	opts := Options{Dirs: NewDirIndex(), FS: fs}
+	for _, tc := range []struct {
+		name, content, want string
+	}{
+		{"IMG_0001 (1).JPG", "photo A", "IMG_0001.JPG"},
+		{"IMG_0001-copy.JPG", "photo D", "IMG_0001-copy.JPG"},
+		// Archived earlier in the run, and found by its content all the same.
+		{"IMG_0001-copy (2).JPG", "photo D", "IMG_0001-copy.JPG"},
+		{"IMG_0005.JPG", "photo E, much larger", "IMG_0005.JPG"},
+	} {
+		info := incoming(tc.name, tc.content)
+		got, err := MoveFile(info, root, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != filepath.Join(dir, tc.want) {
+			t.Errorf("%s archived as %s; want %s", tc.name, filepath.Base(got), tc.want)
+		}
+		if data, _ := os.ReadFile(got); string(data) != tc.content {
+			t.Errorf("%s: %s holds %q", tc.name, filepath.Base(got), data)
+		}
+		// A duplicate is left where it is.
+		if _, err := os.Stat(info.Path); (err == nil) != (tc.want != tc.name) {
+			t.Errorf("%s: source left %v", tc.name, err == nil)
+		}
+	}
+	for name, content := range archived {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != content {
+			t.Errorf("%s changed to %q", name, data)
+		}
+	}
+	// A file of a size no other has reads nothing of the folder.
+	if n := fs.opens[filepath.Join(dir, "IMG_0004.JPG")]; n != 0 {
+		t.Errorf("IMG_0004.JPG, the only file of its size, was opened %d times", n)
+	}
+
+	// Without the index only the name counts, as before.
+	info := incoming("IMG_0002 (1).JPG", "photo B")
+	if got, err := MoveFile(info, root, Options{}); err != nil || filepath.Base(got) != "IMG_0002 (1).JPG" {
+		t.Errorf("without Dirs: %s, %v; want archived under its own name", got, err)
+	}
+}
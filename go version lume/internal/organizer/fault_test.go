@@ -0,0 +1,233 @@
+package organizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"lume-go/internal/fsys"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// faultContent is the content of the n-th test file, large enough for a copy in several writes.
+func faultContent(n, size int) []byte {
+	unit := fmt.Sprintf("file %d;", n)
+	return bytes.Repeat([]byte(unit), size/len(unit)+1)[:size]
+}
+
+// checkArchive fails unless every original is still whole at its source or under root, and every file
+// under root is a whole original. originals maps source paths to their content.
+func checkArchive(t *testing.T, root string, originals map[string][]byte, strict bool) {
+	t.Helper()
+	found := make(map[string]bool)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, _ := os.ReadFile(path)
+		whole := false
+		for src, want := range originals {
+			if bytes.Equal(data, want) {
+				found[src], whole = true, true
+			}
+		}
+		if !whole && strict {
+			t.Errorf("%s is left in the archive but is no original (%d bytes)", path, len(data))
+		}
+		return nil
+	})
+	for src, want := range originals {
+		if data, err := os.ReadFile(src); err == nil && bytes.Equal(data, want) {
+			continue
+		}
+		if !found[src] {
+			t.Errorf("%s is lost: neither the source nor a whole copy is left", src)
+		}
+	}
+}
+
+// A failure at any point of a move leaves the source, or a verified copy, and nothing half written.
+func TestMoveFileFaults(t *testing.T) {
+	defer func(b []time.Duration) { verifyBackoff = b }(verifyBackoff)
+	verifyBackoff = []time.Duration{time.Millisecond, time.Millisecond}
+	dst := filepath.Join("2024", "05", "Camera", "IMG_0001.JPG")
+	noRename := fsys.Fault{Op: fsys.OpRename, Path: "IMG_0001.JPG"}
+	// held is the error of opening a file an antivirus holds open, as it scans a file just written.
+	held := &fs.PathError{Op: "open", Path: dst, Err: errorSharingViolation}
+	// open is the error of a source another program holds open, as a player does a video.
+	open := &fs.PathError{Op: "open", Path: "IMG_0001.JPG", Err: errorSharingViolation}
+	for _, tc := range []struct {
+		name   string
+		faults []fsys.Fault
+		// moved tells whether the move succeeds; kept whether the source is left.
+		moved, kept bool
+		want        error
+	}{
+		{name: "rename fails, copy succeeds", faults: []fsys.Fault{noRename}, moved: true},
+		{name: "mkdir fails", faults: []fsys.Fault{{Op: fsys.OpMkdir}}, kept: true},
+		{name: "create fails", faults: []fsys.Fault{noRename, {Op: fsys.OpCreate}}, kept: true},
+		{name: "copy fails at byte N", faults: []fsys.Fault{noRename, {Op: fsys.OpWrite, After: 70000}}, kept: true},
+		{name: "copy corrupted at byte N", faults: []fsys.Fault{noRename, {Op: fsys.OpWrite, After: 70000, Corrupt: true}}, kept: true, want: ErrIntegrityMismatch},
+		{name: "source hash read fails", faults: []fsys.Fault{{Op: fsys.OpRead, Path: "IMG_0001.JPG", Nth: 1, After: 4096}}, kept: true},
+		{name: "source copy read fails", faults: []fsys.Fault{noRename, {Op: fsys.OpRead, Path: "IMG_0001.JPG", Nth: 2, After: 4096}}, kept: true},
+		{name: "copy hash read fails", faults: []fsys.Fault{noRename, {Op: fsys.OpOpen, Path: dst}}, kept: true, want: ErrUnverified},
+		{name: "copy hash read breaks off", faults: []fsys.Fault{noRename, {Op: fsys.OpRead, Path: dst, After: 4096}}, kept: true, want: ErrUnverified},
+		{name: "renamed file unreadable", faults: []fsys.Fault{{Op: fsys.OpOpen, Path: dst}}, want: ErrUnverified},
+		{name: "copy held for a moment", faults: []fsys.Fault{noRename, {Op: fsys.OpOpen, Path: dst, Nth: 1, Err: held}, {Op: fsys.OpOpen, Path: dst, Nth: 2, Err: held}}, moved: true},
+		{name: "copy held throughout", faults: []fsys.Fault{noRename, {Op: fsys.OpOpen, Path: dst, Err: held}}, kept: true, want: ErrUnverified},
+		{name: "renamed file held throughout", faults: []fsys.Fault{{Op: fsys.OpOpen, Path: dst, Err: held}}, want: ErrUnverified},
+		{name: "source held open", faults: []fsys.Fault{{Op: fsys.OpRename, Path: "IMG_0001.JPG", Err: open}}, kept: true, want: ErrInUse},
+		{name: "source locked against reading", faults: []fsys.Fault{{Op: fsys.OpOpen, Path: "IMG_0001.JPG", Err: open}}, kept: true, want: ErrInUse},
+		{name: "source remove fails", faults: []fsys.Fault{noRename, {Op: fsys.OpRemove, Path: filepath.Join("card", "IMG_0001.JPG")}}, moved: true, kept: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			root, card := t.TempDir(), filepath.Join(t.TempDir(), "card")
+			os.MkdirAll(card, 0755)
+			src := filepath.Join(card, "IMG_0001.JPG")
+			content := faultContent(1, 300000)
+			os.WriteFile(src, content, 0644)
+			info := metadata.FileInfo{Path: src, Filename: "IMG_0001.JPG", Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+
+			got, err := MoveFile(info, root, Options{FS: fsys.NewFaulty(nil, tc.faults...), HardDelete: true})
+			if (err == nil) != tc.moved || tc.want != nil && !errors.Is(err, tc.want) {
+				t.Fatalf("MoveFile = %q, %v; want moved %v (%v)", got, err, tc.moved, tc.want)
+			}
+			if _, serr := os.Stat(src); (serr == nil) != tc.kept {
+				t.Errorf("source left: %v; want %v", serr == nil, tc.kept)
+			}
+			// Renamed but not read back, the file is archived all the same; a copy is not, its source kept.
+			if unverified := errors.Is(err, ErrUnverified) && !tc.kept; (tc.moved || unverified) != (got == filepath.Join(root, dst)) {
+				t.Errorf("archived at %s", got)
+			}
+			// A file that could not be read back is not known to be bad: it is never deleted.
+			if _, derr := os.Stat(filepath.Join(root, dst)); errors.Is(tc.want, ErrUnverified) && derr != nil {
+				t.Errorf("unverified file removed: %v", derr)
+			}
+			checkArchive(t, root, map[string][]byte{src: content}, true)
+		})
+	}
+}
+
+// Soak: with calls failing at random, no file is ever lost, and every move reported is a whole copy.
+// Only a failed removal of a bad copy can leave one behind, so the archive is not checked for those.
+func TestMoveFileChaos(t *testing.T) {
+	for seed := uint64(1); seed <= 5; seed++ {
+		root, card := t.TempDir(), t.TempDir()
+		originals := make(map[string][]byte)
+		var infos []metadata.FileInfo
+		for i := range 12 {
+			name := fmt.Sprintf("IMG_%04d.JPG", i%6) // half the names are taken twice
+			dir := filepath.Join(card, fmt.Sprint(i))
+			os.MkdirAll(dir, 0755)
+			path := filepath.Join(dir, name)
+			content := faultContent(i, 1000+i*17000)
+			os.WriteFile(path, content, 0644)
+			originals[path] = content
+			infos = append(infos, metadata.FileInfo{Path: path, Filename: name, Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"})
+		}
+		faulty := fsys.NewFaulty(nil, fsys.Fault{Op: fsys.OpRename, Path: ".JPG", Nth: 3})
+		faulty.Chaos(0.15, seed)
+		for _, info := range infos {
+			got, err := MoveFile(info, root, Options{FS: faulty, HardDelete: true})
+			if err != nil {
+				continue
+			}
+			if data, _ := os.ReadFile(got); !bytes.Equal(data, originals[info.Path]) {
+				t.Errorf("seed %d: %s reported archived at %s, which does not hold it", seed, info.Path, got)
+			}
+		}
+		checkArchive(t, root, originals, false)
+	}
+}
+
+// slowOpen is an FS whose opens of paths ending in path first wait for delay, as a share that
+// stopped answering would.
+type slowOpen struct {
+	fsys.FS
+	path  string
+	delay time.Duration
+}
+
+func (s slowOpen) Open(name string) (fsys.File, error) {
+	if strings.HasSuffix(name, s.path) {
+		time.Sleep(s.delay)
+	}
+	return s.FS.Open(name)
+}
+
+// A timeout while the archived file is read back rolls a copy back, but leaves a renamed file
+// archived, unverified: its source is gone.
+func TestMoveFileTimeoutVerifying(t *testing.T) {
+	dst := filepath.Join("2024", "05", "Camera", "IMG_0001.JPG")
+	for _, copied := range []bool{false, true} {
+		root, card := t.TempDir(), t.TempDir()
+		src := filepath.Join(card, "IMG_0001.JPG")
+		content := faultContent(1, 5000)
+		os.WriteFile(src, content, 0644)
+		info := metadata.FileInfo{Path: src, Filename: "IMG_0001.JPG", Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+		var fs fsys.FS = slowOpen{fsys.OS, dst, 200 * time.Millisecond}
+		if copied {
+			fs = fsys.NewFaulty(fs, fsys.Fault{Op: fsys.OpRename, Path: "IMG_0001.JPG"})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		got, err := MoveFileContext(ctx, info, root, Options{FS: fs})
+		cancel()
+		_, serr := os.Stat(src)
+		_, derr := os.Stat(filepath.Join(root, dst))
+		if copied && (!errors.Is(err, ErrTimeout) || got != "" || serr != nil || derr == nil) {
+			t.Errorf("copy: MoveFileContext = %q, %v; source left %v, copy left %v", got, err, serr == nil, derr == nil)
+		}
+		if !copied && (!errors.Is(err, ErrUnverified) || errors.Is(err, ErrTimeout) || got != filepath.Join(root, dst) || serr == nil || derr != nil) {
+			t.Errorf("rename: MoveFileContext = %q, %v; source left %v, file archived %v", got, err, serr == nil, derr == nil)
+		}
+	}
+}
+
+// changingRename is an FS on which a file is written to as it is renamed, as a camera application
+// still saving it would.
+type changingRename struct{ fsys.FS }
+
+func (c changingRename) Rename(oldpath, newpath string) error {
+	if err := c.FS.Rename(oldpath, newpath); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(newpath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write([]byte("more"))
+	return err
+}
+
+// A renamed file that no longer hashes like its source is moved back; one that cannot be is archived
+// unverified, with its path, rather than left in the archive as a failure.
+func TestMoveFileChangedWhileRenamed(t *testing.T) {
+	dst := filepath.Join("2024", "05", "Camera", "IMG_0001.JPG")
+	for _, back := range []bool{true, false} {
+		root, card := t.TempDir(), t.TempDir()
+		src := filepath.Join(card, "IMG_0001.JPG")
+		content := faultContent(1, 5000)
+		os.WriteFile(src, content, 0644)
+		info := metadata.FileInfo{Path: src, Filename: "IMG_0001.JPG", Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+		var faults []fsys.Fault
+		if !back {
+			faults = append(faults, fsys.Fault{Op: fsys.OpRename, Path: "IMG_0001.JPG", Nth: 2})
+		}
+		got, err := MoveFile(info, root, Options{FS: changingRename{fsys.NewFaulty(nil, faults...)}})
+		_, serr := os.Stat(src)
+		_, derr := os.Stat(filepath.Join(root, dst))
+		if back && (!errors.Is(err, ErrIntegrityMismatch) || got != "" || serr != nil || derr == nil) {
+			t.Errorf("moved back: MoveFile = %q, %v; source left %v, file archived %v", got, err, serr == nil, derr == nil)
+		}
+		if !back && (!errors.Is(err, ErrUnverified) || errors.Is(err, ErrIntegrityMismatch) || got != filepath.Join(root, dst) || serr == nil || derr != nil) {
+			t.Errorf("left: MoveFile = %q, %v; source left %v, file archived %v", got, err, serr == nil, derr == nil)
+		}
+	}
+}
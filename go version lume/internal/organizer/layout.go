@@ -0,0 +1,246 @@
+package organizer
+
+import (
+	"fmt"
+	"lume-go/internal/i18n"
+	"lume-go/internal/metadata"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultTemplate is the Year/Month/Device layout Lume has always used.
+const DefaultTemplate = "{year}/{month}/{device}"
+
+// TemplateTokens lists the placeholders a folder template may use. {week} is the ISO week (W01);
+// pair it with {weekyear} rather than {year} so the days around New Year land in the right week.
+// {burst} is the burst folder of continuous shots (see MarkBursts) and empty for other files.
+// {source} renders Layout.SourceDepth levels of a nested source as nested folders.
+var TemplateTokens = []string{"{year}", "{month}", "{day}", "{week}", "{weekyear}", "{device}", "{source}", "{model}", "{burst}"}
+
+// NameTokens lists the placeholders a file name template may use besides TemplateTokens: {name} is
+// the original name without its extension, {time} the capture time (HHMMSS), {subsec} its
+// milliseconds and {seq} the number of the shot within its second (see MarkSequences).
+var NameTokens = []string{"{name}", "{time}", "{subsec}", "{seq}"}
+
+// dateTokens are the tokens that need a trustworthy capture date.
+var dateTokens = []string{"{year}", "{month}", "{day}", "{week}", "{weekyear}"}
+
+// Month folder styles. FileInfo.Month stays numeric; the style only changes the rendered folder name.
+const (
+	MonthNumeric    = "numeric"     // 01
+	MonthName       = "name"        // Ocak
+	MonthNumberName = "number_name" // 01-Ocak
+)
+
+// Layout describes how archive folders are named.
+type Layout struct {
+	// Template lays out the folders; empty means DefaultTemplate.
+	Template string
+	// NameTemplate names the archived files, e.g. "{year}{month}{day}_{time}_{seq}"; the original
+	// extension is kept. Empty keeps the original names. Reorganizing moves files but keeps their names.
+	NameTemplate string
+	// MonthStyle picks the {month} label; MonthNames holds the twelve names it uses.
+	MonthStyle string
+	MonthNames []string
+	// Burst detects continuous shooting for the {burst} token.
+	Burst BurstOptions
+	// MinDeviceFiles pools devices with fewer files than this in a month into OtherDevices; 0 is off.
+	MinDeviceFiles int
+	// Devices names the camera models of files PlanReorganize reads; nil applies the built-in names.
+	Devices *metadata.DeviceNames
+	// SourceDepth is how many levels of a nested source such as WhatsApp/Images/Sent {source}
+	// renders; 0 renders the first, as for a source of one level.
+	SourceDepth int
+	// Folders, when set, are the folders below the base taken as they are instead of the template's,
+	// "." for the base itself (see Structure).
+	Folders string
+}
+
+func (l Layout) template() string {
+	if strings.TrimSpace(l.Template) == "" {
+		return DefaultTemplate
+	}
+	return l.Template
+}
+
+// MonthLabel renders a numeric month ("01".."12") in the layout's style. Without a full set of
+// names, or for anything that is not a month number, the number is kept.
+func (l Layout) MonthLabel(month string) string {
+	n, err := strconv.Atoi(month)
+	if err != nil || n < 1 || n > 12 || len(l.MonthNames) != 12 {
+		return month
+	}
+	name := strings.TrimSpace(l.MonthNames[n-1])
+	if name == "" {
+		return month
+	}
+	switch l.MonthStyle {
+	case MonthName:
+		return name
+	case MonthNumberName:
+		return month + "-" + name
+	}
+	return month
+}
+
+// monthVariants lists every label the month may have been archived under, so folders written with
+// another month style, or with the month names of another language, are recognised as the same
+// logical month.
+func (l Layout) monthVariants() []Layout {
+	// Names that are not a full set render the number, as MonthNumeric does.
+	key := func(v Layout) string {
+		if (v.MonthStyle != MonthName && v.MonthStyle != MonthNumberName) || len(v.MonthNames) != 12 {
+			return MonthNumeric
+		}
+		return v.MonthStyle + "|" + strings.Join(v.MonthNames, ",")
+	}
+	seen := map[string]bool{key(l): true}
+	var out []Layout
+	for _, style := range []string{MonthNumeric, MonthName, MonthNumberName} {
+		for _, names := range append([][]string{l.MonthNames}, languageMonths...) {
+			v := l
+			v.MonthStyle, v.MonthNames = style, names
+			if k := key(v); !seen[k] {
+				seen[k] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// languageMonths are the month names of every language of the app, one of which the archive may have
+// been written in before the language was changed.
+var languageMonths = func() [][]string {
+	var out [][]string
+	for _, lang := range i18n.Languages() {
+		out = append(out, strings.Split(i18n.T(lang.Code, "month_names"), ","))
+	}
+	return out
+}()
+
+// DeviceFolder names the device level of the archive: the source and camera model combined,
+// Other_Sorted when neither is known, or OtherDevices for a pooled file.
+func DeviceFolder(info metadata.FileInfo) string {
+	if info.Pooled {
+		return OtherDevices
+	}
+	device := SanitizeFolderName(info.Device)
+	if source := metadata.SourceLevels(info.Source, 1); source != "" && source != "Other_Imports" {
+		if info.Device == "Unknown" || info.Device == "" {
+			device = SanitizeFolderName(source)
+		} else {
+			device = SanitizeFolderName(source + "_" + info.Device)
+		}
+	}
+	if device == "Unknown" || device == "" {
+		device = "Other_Sorted"
+	}
+	return device
+}
+
+// TargetDir renders the layout for info under base. Each path segment is sanitized after
+// substitution and segments that render empty are dropped; the levels of {source} are segments of
+// their own. The Folders of a layout are taken as they are, each sanitized.
+func TargetDir(info metadata.FileInfo, base string, layout Layout) string {
+	r := strings.NewReplacer(
+		"{year}", info.Year,
+		"{month}", layout.MonthLabel(info.Month),
+		"{day}", info.Day,
+		"{weekyear}", info.WeekYear,
+		"{week}", info.Week,
+		"{device}", DeviceFolder(info),
+		"{source}", strings.ReplaceAll(metadata.SourceLevels(info.Source, layout.SourceDepth), metadata.SourceSep, levelSep),
+		"{model}", info.Device,
+		"{burst}", info.Burst,
+	)
+	dir := base
+	if layout.Folders != "" {
+		for _, seg := range strings.Split(filepath.ToSlash(layout.Folders), "/") {
+			if seg = strings.TrimSpace(seg); seg != "" && seg != "." {
+				dir = filepath.Join(dir, SanitizeFolderName(seg))
+			}
+		}
+		return dir
+	}
+	for _, seg := range strings.Split(filepath.ToSlash(layout.template()), "/") {
+		for _, level := range strings.Split(r.Replace(seg), levelSep) {
+			if level = strings.TrimSpace(level); level != "" {
+				dir = filepath.Join(dir, SanitizeFolderName(level))
+			}
+		}
+	}
+	return dir
+}
+
+// FileName is the name info is archived under: the original one, or NameTemplate rendered with the
+// extension of the original. A template that renders empty keeps the original name too.
+func FileName(info metadata.FileInfo, layout Layout) string {
+	if strings.TrimSpace(layout.NameTemplate) == "" {
+		return info.Filename
+	}
+	ext := filepath.Ext(info.Filename)
+	seq := info.Seq
+	if seq < 1 {
+		seq = 1
+	}
+	r := strings.NewReplacer(
+		"{year}", info.Year,
+		"{month}", layout.MonthLabel(info.Month),
+		"{day}", info.Day,
+		"{weekyear}", info.WeekYear,
+		"{week}", info.Week,
+		"{device}", DeviceFolder(info),
+		"{source}", strings.ReplaceAll(metadata.SourceLevels(info.Source, layout.SourceDepth), metadata.SourceSep, "_"),
+		"{model}", info.Device,
+		"{burst}", info.Burst,
+		"{name}", strings.TrimSuffix(info.Filename, ext),
+		"{time}", info.Date.Format("150405"),
+		"{subsec}", info.Date.Format(".000")[1:],
+		"{seq}", fmt.Sprintf("%03d", seq),
+	)
+	name := strings.TrimSpace(r.Replace(filepath.Base(filepath.ToSlash(layout.NameTemplate))))
+	if name == "" {
+		return info.Filename
+	}
+	return name + ext
+}
+
+// MonthDepth is the folder level below the base of the month folders of the layout, each of which
+// keeps a month index (see monthindex): that of {month}, or of {week} in a layout by weeks, or else
+// the deepest of its leading date folders, as the year of "{year}". Folders taken as they are have
+// none, nor a template starting with another token: it is then 0, each folder keeping its own.
+func (l Layout) MonthDepth() int {
+	if l.Folders != "" {
+		return 0
+	}
+	depth, leading := 0, true
+	for i, seg := range strings.Split(filepath.ToSlash(l.template()), "/") {
+		if templateUses(seg, "{month}", "{week}") {
+			return i + 1
+		}
+		rest := seg
+		for _, t := range dateTokens {
+			rest = strings.ReplaceAll(rest, t, "")
+		}
+		if leading = leading && rest != seg && !strings.Contains(rest, "{"); leading {
+			depth = i + 1
+		}
+	}
+	return depth
+}
+
+// levelSep stands in for the level separator of {source} until a segment is split, so that no other
+// value can split one.
+const levelSep = "\x00"
+
+// templateUses reports whether template contains one of the tokens.
+func templateUses(template string, tokens ...string) bool {
+	for _, t := range tokens {
+		if strings.Contains(template, t) {
+			return true
+		}
+	}
+	return false
+}
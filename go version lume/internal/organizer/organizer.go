@@ -1,119 +1,749 @@
 package organizer
 
 import (
+	"context"
+	"crypto/md5"
+	"errors"
 	"fmt"
 	"io"
+	"lume-go/internal/catalog"
+	"lume-go/internal/fsys"
+	"lume-go/internal/index"
+	"lume-go/internal/ioutil"
 	"lume-go/internal/logger"
 	"lume-go/internal/metadata"
+	"lume-go/internal/monthindex"
+	"lume-go/internal/ratelimit"
+	"lume-go/internal/sums"
+	"lume-go/internal/validator"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"unicode/utf16"
 )
 
+// maxFolderName is the longest folder name SanitizeFolderName returns, in characters.
+const maxFolderName = 100
+
 // SanitizeFolderName cleans folder names for OS compatibility. (Audit Point 6 Tested)
+// Characters Windows forbids become an underscore, a run of them a single one. Dots stay inside the
+// name ("Mi 11.5G") but not at its ends, nor do spaces: Explorer cannot delete a folder ending in
+// either. Reserved device names get a suffix, and long names are cut on a character with an ellipsis.
 func SanitizeFolderName(name string) string {
-	name = strings.TrimSpace(name)
-	if name == "" || name == "." || name == ".." {
+	var b strings.Builder
+	for _, r := range name {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			if !strings.HasSuffix(b.String(), "_") {
+				b.WriteByte('_')
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	name = strings.Trim(b.String(), ". ")
+	if name == "" {
 		return "Unknown"
 	}
 
-	invalidChars := `<>:"/\|?*.`
-	for _, char := range invalidChars {
-		name = strings.ReplaceAll(name, string(char), "_")
+	if validator.IsReservedName(name) {
+		stem, ext, dot := strings.Cut(name, ".")
+		if name = strings.TrimRight(stem, " ") + "_safe"; dot {
+			name += "." + ext
+		}
 	}
 
-	reserved := map[string]bool{
-		"CON": true, "PRN": true, "AUX": true, "NUL": true,
-		"COM1": true, "LPT1": true,
+	// Cut on a rune boundary so localized names (e.g. Turkish month labels) stay valid UTF-8.
+	if r := []rune(name); len(r) > maxFolderName {
+		return strings.TrimRight(string(r[:maxFolderName-1]), ". ") + "…"
+	}
+	return name
+}
+
+// SanitizeFileName makes a file name from another system (an Android phone, a Linux share) valid on
+// Windows: forbidden characters become underscores, trailing dots and spaces of the name and of its
+// base go, and a reserved base gets the suffix SanitizeFolderName uses. The extension is kept, and
+// inner dots too; valid names come back unchanged.
+func SanitizeFileName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r < ' ' || strings.ContainsRune(`<>:"/\|?*`, r) {
+			r = '_'
+		}
+		b.WriteRune(r)
 	}
-	if reserved[strings.ToUpper(name)] {
-		return name + "_safe"
+	name = strings.TrimRight(b.String(), ". ")
+	ext := filepath.Ext(name)
+	base := strings.TrimRight(strings.TrimSuffix(name, ext), ". ")
+	if strings.Trim(base, " ") == "" {
+		base = "Unknown"
 	}
+	if validator.IsReservedName(base + ext) {
+		stem, rest, _ := strings.Cut(base, ".")
+		base = strings.TrimRight(stem, " ") + "_safe"
+		if rest != "" {
+			base += "." + rest
+		}
+	}
+	return base + ext
+}
 
-	if len(name) > 100 {
-		return name[:100]
+// Errors MoveFile and the reorganizer wrap, so callers can tell failures apart with errors.Is
+// instead of matching message text.
+var (
+	// ErrSourceMissing reports that a queued file was deleted or moved away before its turn came.
+	ErrSourceMissing = errors.New("source file no longer exists")
+	// ErrIntegrityMismatch reports that the archived copy does not hash like the source; the copy is
+	// removed, or a renamed file, changed while it moved, is moved back to its source.
+	ErrIntegrityMismatch = errors.New("integrity check failed: hash mismatch")
+	// ErrUnverified reports an archived file that could not be read back to verify it, e.g. while an
+	// antivirus held it: the file is at its destination and, when it was copied, the source is kept too.
+	ErrUnverified = errors.New("archived but not verified")
+	// ErrDestinationExists reports that no free name was left for the file in its target folder.
+	ErrDestinationExists = errors.New("destination already exists")
+	// ErrRolledBack marks a move cancelled while copying: the partial copy was removed and the source
+	// is untouched.
+	ErrRolledBack = errors.New("cancelled, partial copy removed")
+	// ErrTimeout marks a file that took longer than its timeout, e.g. on a share that stopped
+	// answering; like a rolled-back move it leaves the source untouched and can simply be retried.
+	ErrTimeout = errors.New("timed out")
+	// ErrInUse marks a source another program holds open without sharing it, such as a video playing
+	// in a player; the source is untouched and the move can be tried again once it is closed.
+	ErrInUse = errors.New("open in another program")
+
+	// The remaining categories are detected by the packages that own the check.
+	ErrUnsupportedType   = metadata.ErrUnsupportedType
+	ErrInsufficientSpace = validator.ErrInsufficientSpace
+	ErrTooLarge          = validator.ErrTooLarge
+	ErrNotWritable       = validator.ErrNotWritable
+	ErrProtected         = validator.ErrProtected
+)
+
+// ioError files an operating system error under ErrNotWritable or ErrInsufficientSpace when it is
+// one of those, keeping the original error in the chain.
+func ioError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrPermission):
+		return fmt.Errorf("%w: %w", ErrNotWritable, err)
+	case isDiskFull(err):
+		return fmt.Errorf("%w: %w", ErrInsufficientSpace, err)
 	}
+	return inUse(err)
+}
 
-	return name
+// inUse files a sharing violation on the source under ErrInUse, keeping the original error in the
+// chain.
+func inUse(err error) error {
+	if err != nil && isSharingViolation(err) {
+		return fmt.Errorf("%w: %w", ErrInUse, err)
+	}
+	return err
 }
 
+// Failure categories group errors for the report, the results filter and CLI exit codes.
+const (
+	CategorySpace       = "space"
+	CategoryWritable    = "writable"
+	CategoryProtected   = "protected"
+	CategoryIntegrity   = "integrity"
+	CategoryUnverified  = "unverified"
+	CategoryUnsupported = "unsupported"
+	CategoryMissing     = "missing"
+	CategoryExists      = "exists"
+	CategoryRolledBack  = "rolledback"
+	CategoryTimeout     = "timeout"
+	CategoryInUse       = "in_use"
+	CategoryOther       = "other"
+)
+
+// Categories lists the failure categories in display order.
+var Categories = []string{CategorySpace, CategoryWritable, CategoryProtected, CategoryIntegrity, CategoryUnverified, CategoryUnsupported, CategoryMissing, CategoryExists, CategoryRolledBack, CategoryTimeout, CategoryInUse, CategoryOther}
+
+// Category files err under one of the failure categories; nil has none.
+func Category(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrInsufficientSpace), errors.Is(err, ErrTooLarge):
+		return CategorySpace
+	case errors.Is(err, ErrNotWritable):
+		return CategoryWritable
+	case errors.Is(err, ErrProtected):
+		return CategoryProtected
+	case errors.Is(err, ErrIntegrityMismatch):
+		return CategoryIntegrity
+	case errors.Is(err, ErrUnverified):
+		return CategoryUnverified
+	case errors.Is(err, ErrUnsupportedType):
+		return CategoryUnsupported
+	case errors.Is(err, ErrSourceMissing), errors.Is(err, os.ErrNotExist):
+		return CategoryMissing
+	case errors.Is(err, ErrDestinationExists):
+		return CategoryExists
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return CategoryTimeout
+	case errors.Is(err, ErrRolledBack):
+		return CategoryRolledBack
+	case errors.Is(err, ErrInUse):
+		return CategoryInUse
+	}
+	return CategoryOther
+}
+
+// Options carries the optional behaviours of a run. The zero value archives exactly as before.
+type Options struct {
+	// Index, when set, makes content-identical files anywhere in the target count as duplicates.
+	Index *index.Index
+	// Catalog, when set, records every archived file under RunID.
+	Catalog *catalog.Catalog
+	RunID   string
+	// Sums, when set, lists the SHA-256 of every file archived in the SHA256SUMS of its year folder.
+	Sums *sums.Sums
+	// MonthIndex, when set, lists every file archived in the index.json of its month folder.
+	MonthIndex *monthindex.Index
+	// Layout names the archive folders; the zero value is DefaultTemplate with numeric months.
+	Layout Layout
+	// HardDelete removes files permanently instead of sending them to the Recycle Bin.
+	HardDelete bool
+	// Hashed, when set, is told the MD5 of every source file read for it, e.g. so a scan cache keeps
+	// it for the duplicate checks of the next run.
+	Hashed func(path, md5 string)
+	// Written, when set, is told the MD5 of an archived copy a shifted date was written into (see
+	// metadata.FileInfo.ExifDate), which no longer matches its source; "" when it could not be read.
+	Written func(path, md5 string)
+	// Conflicts names a different file that takes a taken name: ConflictNumber (the default) or
+	// ConflictHash.
+	Conflicts string
+	// FS is the file system the move works through; nil is the real one. The index, catalog and
+	// checksum lists always use the real one.
+	FS fsys.FS
+	// Protect refuses target folders within protected ones with ErrProtected; a copy already there
+	// still counts as a duplicate.
+	Protect *validator.Protection
+	// Limit, when set, paces the bytes copied across volumes; a rename within one is not paced.
+	Limit *ratelimit.Limiter
+	// Dirs, when set, makes a file identical to one already in its target folder under another name a
+	// duplicate of it; cloud placeholders, which are not hashed, are left to the name check.
+	Dirs *DirIndex
+	// FileSystem names the file system of the target (see validator.FileSystem); a file larger than it
+	// holds fails with ErrTooLarge before anything is copied.
+	FileSystem string
+}
+
+// Conflict strategies of Options.Conflicts.
+const (
+	ConflictNumber = "number" // DJI_0001_1.JPG: the first free _N
+	ConflictHash   = "hash"   // DJI_0001_a1b2c3d4.JPG: the start of the content hash, the same on every run
+)
+
+// ConflictStrategies lists the strategies in display order.
+var ConflictStrategies = []string{ConflictNumber, ConflictHash}
+
 // MoveFile handles the movement of a file with detailed result reporting. (Elite Error Wrapping)
-func MoveFile(info metadata.FileInfo, targetBase string) error {
-	year := SanitizeFolderName(info.Year)
-	month := SanitizeFolderName(info.Month)
-	device := SanitizeFolderName(info.Device)
-	
-	if info.Source != "" && info.Source != "Other_Imports" {
-		if info.Device == "Unknown" || info.Device == "" {
-			device = SanitizeFolderName(info.Source)
-		} else {
-			device = SanitizeFolderName(info.Source + "_" + info.Device)
+// It returns the archive path of the file: the new location, or the existing copy for duplicates.
+// A file renamed into the archive but not read back is archived without verification: its path is
+// returned with an error matching ErrUnverified.
+func MoveFile(info metadata.FileInfo, targetBase string, opts Options) (string, error) {
+	return MoveFileContext(context.Background(), info, targetBase, opts)
+}
+
+// MoveFileContext is MoveFile with a copy across volumes that stops when ctx is cancelled. The
+// partial copy is then removed and ErrRolledBack returned, unless the copy was nearly done: it is
+// finished and verified instead, so a cancel never costs the last seconds of a large file. A deadline
+// on ctx is a timeout: reaching it rolls a copy back, even a read that hangs, with ErrTimeout. A file
+// already renamed when it is reached is archived unverified (ErrUnverified).
+func MoveFileContext(ctx context.Context, info metadata.FileInfo, targetBase string, opts Options) (string, error) {
+	fs := fsys.Or(opts.FS)
+	if _, err := fs.Lstat(info.Path); errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%s: %w", info.Filename, ErrSourceMissing)
+	}
+	// The archived copy gets a name Windows accepts; the report keeps the original.
+	named := FileName(info, opts.Layout)
+	name := SanitizeFileName(named)
+	// The duplicate checks, the hash suffix and the integrity check of the move share one read of the
+	// source, made by the first of them to need it. Duplicate checks also take an MD5 kept from an
+	// earlier run (info.MD5, e.g. from the scan cache); the integrity check only trusts a fresh one.
+	freshHash := func() (string, error) {
+		h, err := info.EnsureHash(ctx, fs, metadata.HashMD5)
+		if err == nil && opts.Hashed != nil {
+			opts.Hashed(info.Path, h)
+		}
+		return h, err
+	}
+	known := info.MD5
+	srcHash := func(string) (string, error) {
+		if known != "" {
+			return known, nil
 		}
+		return freshHash()
 	}
-	if device == "Unknown" || device == "" {
-		device = "Other_Sorted"
+	// Duplicate checks must not hash a cloud placeholder, which would download it; size (and name in
+	// the index) decide instead. A match is only ever left in place, never deleted.
+	isDuplicate := func(p1, p2 string) (bool, error) { return duplicateHash(fs, p1, p2, srcHash) }
+	if info.Placeholder {
+		isDuplicate = func(p1, p2 string) (bool, error) { return sameSize(fs, p1, p2) }
+	}
+	if opts.Index != nil {
+		lookup := func(src string, size int64) (string, bool) {
+			return opts.Index.LookupHash(func() (string, error) { return srcHash(src) }, size)
+		}
+		if info.Placeholder {
+			lookup = func(_ string, size int64) (string, bool) { return opts.Index.LookupName(name, size) }
+		}
+		if existing, ok := lookup(info.Path, info.Size); ok {
+			logger.Info("Already archived elsewhere: %s == %s", info.Filename, existing)
+			return existing, nil
+		}
 	}
 
-	targetDir := filepath.Join(targetBase, year, month, device)
-	if err := os.MkdirAll(targetDir, 0755); err != nil {
-		return fmt.Errorf("mkdir failed for %s: %w", targetDir, err)
+	targetDir := TargetDir(info, targetBase, opts.Layout)
+	if limit := validator.MaxFileSize(opts.FileSystem); limit > 0 && info.Size > limit {
+		return "", fmt.Errorf("%s: %w", info.Filename, &validator.SizeLimitError{Path: targetBase, FileSystem: opts.FileSystem, Size: info.Size, Limit: limit})
+	}
+	// The same month may already be archived under another label (01 vs 01-Ocak).
+	if templateUses(opts.Layout.template(), "{month}") {
+		for _, alt := range opts.Layout.monthVariants() {
+			dir := TargetDir(info, targetBase, alt)
+			if dir == targetDir {
+				continue
+			}
+			if p := filepath.Join(dir, name); fsys.Exists(fs, p) {
+				if isDup, err := isDuplicate(info.Path, p); err == nil && isDup {
+					return p, nil
+				}
+			}
+		}
+	}
+	if err := opts.Protect.Check(targetDir); err != nil {
+		if p := filepath.Join(targetDir, name); fsys.Exists(fs, p) {
+			if isDup, derr := isDuplicate(info.Path, p); derr == nil && isDup {
+				return p, nil
+			}
+		}
+		return "", fmt.Errorf("%s: %w", info.Filename, err)
+	}
+	if err := fs.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("mkdir failed for %s: %w", targetDir, ioError(err))
 	}
 
-	finalPath := filepath.Join(targetDir, info.Filename)
-	if _, err := os.Stat(finalPath); err == nil {
-		isDup, err := IsDuplicate(info.Path, finalPath)
+	finalPath := filepath.Join(targetDir, name)
+	if name != named {
+		logger.Info("Renamed for Windows: %s -> %s", named, name)
+	}
+	if opts.Dirs != nil && !info.Placeholder {
+		for _, p := range opts.Dirs.sameSize(fs, targetDir, info.Size) {
+			// The name check below handles finalPath; a source lying in the folder is no copy of itself.
+			if strings.EqualFold(p, finalPath) || strings.EqualFold(p, info.Path) || fs.SameFile(info.Path, p) {
+				continue
+			}
+			if isDup, err := isDuplicate(info.Path, p); err != nil {
+				logger.Error("Duplicate check fail for %s: %v", info.Filename, err)
+			} else if isDup {
+				logger.Info("Already archived under another name: %s == %s", info.Filename, p)
+				return p, nil
+			}
+		}
+	}
+	if _, err := fs.Stat(finalPath); err == nil {
+		// Another path to the file itself (hardlink, subst drive): it is already where it belongs.
+		if fs.SameFile(info.Path, finalPath) {
+			return finalPath, nil
+		}
+		isDup, err := isDuplicate(info.Path, finalPath)
 		if err != nil {
 			logger.Error("Duplicate check fail for %s: %v", info.Filename, err)
 		} else if isDup {
-			return nil
+			return finalPath, nil
 		}
-		finalPath = ResolveConflict(finalPath)
+		resolved := ""
+		if opts.Conflicts == ConflictHash && !info.Placeholder {
+			var dup bool
+			if resolved, dup = hashConflict(fs, info.Path, finalPath, srcHash, isDuplicate); dup {
+				return resolved, nil
+			}
+		}
+		if resolved == "" {
+			if resolved, err = resolveConflict(fs, finalPath); err != nil {
+				return "", fmt.Errorf("%s: %w", info.Filename, err)
+			}
+		}
+		finalPath = resolved
 	}
 
-	if err := AtomicMove(info.Path, finalPath); err != nil {
-		return fmt.Errorf("archive move error for %s: %w", info.Filename, err)
+	hash, err := atomicMove(ctx, fs, info.Path, finalPath, freshHash, opts.HardDelete, opts.Limit)
+	// A timeout rolls the move back, unless it struck once the file was renamed.
+	if errors.Is(err, context.DeadlineExceeded) && fsys.Exists(fs, info.Path) {
+		logger.Error("Move of %s timed out and was rolled back", info.Filename)
+		return "", fmt.Errorf("%s: %w: %w", info.Filename, ErrTimeout, err)
+	}
+	if errors.Is(err, ErrRolledBack) {
+		logger.Info("Move of %s cancelled and rolled back", info.Filename)
+		return "", fmt.Errorf("%s: %w", info.Filename, err)
+	}
+	if err != nil && !fsys.Exists(fs, info.Path) && !fsys.Exists(fs, finalPath) {
+		return "", fmt.Errorf("%s: %w", info.Filename, ErrSourceMissing)
+	}
+	// A renamed file that could not be read back is archived all the same, its source being gone: it
+	// is listed like any other and returned with the error.
+	unverified := errors.Is(err, ErrUnverified) && !fsys.Exists(fs, info.Path) && fsys.Exists(fs, finalPath)
+	if err != nil && !unverified {
+		return "", fmt.Errorf("archive move error for %s: %w", info.Filename, err)
+	}
+	if !info.ExifDate.IsZero() && !unverified {
+		hash = writeShiftedDate(ctx, fs, info, finalPath, hash, opts)
+	}
+	MoveSidecars(ctx, info.Sidecars, finalPath, opts)
+	if opts.Dirs != nil {
+		opts.Dirs.add(finalPath, info.Size)
 	}
 	
+	if opts.Index != nil {
+		opts.Index.Add(finalPath)
+	}
+	if opts.Catalog != nil {
+		opts.Catalog.Put(catalog.Record{Hash: hash, Original: info.Path, Path: finalPath, Size: info.Size, Date: info.Date, Device: info.Device, Source: info.Source, RunID: opts.RunID})
+	}
+	if opts.Sums != nil {
+		if err := opts.Sums.Add(finalPath); err != nil {
+			logger.Error("Checksum not listed: %v", err)
+		}
+	}
+	if opts.MonthIndex != nil {
+		// A copy filed beside its original lies in a month folder of the layout all the same.
+		l := opts.Layout
+		if info.Beside != "" {
+			l.Folders = ""
+		}
+		if err := opts.MonthIndex.Add(finalPath, l.MonthDepth(), info, hash); err != nil {
+			logger.Error("Month index not updated: %v", err)
+		}
+	}
+	if unverified {
+		logger.Error("Archived without verification: %s -> %s", info.Filename, finalPath)
+		return finalPath, fmt.Errorf("archive move error for %s: %w", info.Filename, err)
+	}
 	logger.Info("Successfully archived: %s -> %s", info.Filename, finalPath)
-	return nil
+	return finalPath, nil
+}
+
+// writeShiftedDate writes the shifted date of info into its archived copy at path, verified by now,
+// before the copy is listed anywhere, and returns the hash of the copy as it then is. The source is
+// left as it was, so the scan cache and the next run of the shift find it unchanged. A copy the date
+// cannot be written into stays archived as it is, with hash.
+func writeShiftedDate(ctx context.Context, fs fsys.FS, info metadata.FileInfo, path, hash string, opts Options) string {
+	if err := metadata.WriteExifDate(fs, path, info.ExifDate); err != nil {
+		logger.Error("Shifted date of %s not written to %s: %v", info.Filename, path, err)
+		return hash
+	}
+	h, err := hashFile(ctx, fs, path)
+	if err != nil {
+		logger.Error("Archived copy %s not hashed again after its date was written: %v", path, err)
+	}
+	if opts.Written != nil {
+		opts.Written(path, h)
+	}
+	return h
 }
 
-func IsDuplicate(p1, p2 string) (bool, error) {
-	s1, err := os.Stat(p1); if err != nil { return false, fmt.Errorf("stat src: %w", err) }
-	s2, err := os.Stat(p2); if err != nil { return false, fmt.Errorf("stat dst: %w", err) }
+func IsDuplicate(p1, p2 string) (bool, error) { return duplicateHash(fsys.OS, p1, p2, metadata.GetFileHash) }
+
+// duplicateHash is IsDuplicate on fs hashing p1 with srcHash.
+func duplicateHash(fs fsys.FS, p1, p2 string, srcHash func(string) (string, error)) (bool, error) {
+	s1, err := fs.Stat(p1); if err != nil { return false, fmt.Errorf("stat src: %w", err) }
+	s2, err := fs.Stat(p2); if err != nil { return false, fmt.Errorf("stat dst: %w", err) }
 	if s1.Size() != s2.Size() { return false, nil }
 
-	h1, err := metadata.GetFileHash(p1); if err != nil { return false, fmt.Errorf("hash src: %w", err) }
-	h2, err := metadata.GetFileHash(p2); if err != nil { return false, fmt.Errorf("hash dst: %w", err) }
+	h1, err := srcHash(p1); if err != nil { return false, fmt.Errorf("hash src: %w", err) }
+	h2, err := hashFile(context.Background(), fs, p2); if err != nil { return false, fmt.Errorf("hash dst: %w", err) }
 	return h1 == h2, nil
 }
 
-func ResolveConflict(path string) string {
-	ext := filepath.Ext(path)
-	base := strings.TrimSuffix(path, ext)
-	for i := 1; i < 10000; i++ {
-		newPath := fmt.Sprintf("%s_%d%s", base, i, ext)
-		if _, err := os.Stat(newPath); os.IsNotExist(err) {
-			return newPath
+// sameSize is the duplicate check for files whose content must not be read.
+func sameSize(fs fsys.FS, p1, p2 string) (bool, error) {
+	s1, err := fs.Stat(p1); if err != nil { return false, fmt.Errorf("stat src: %w", err) }
+	s2, err := fs.Stat(p2); if err != nil { return false, fmt.Errorf("stat dst: %w", err) }
+	return s1.Size() == s2.Size(), nil
+}
+
+// hashFile is metadata.GetFileHashContext reading through fs.
+func hashFile(ctx context.Context, fs fsys.FS, path string) (string, error) { return hashPaced(ctx, fs, path, nil) }
+
+// hashPaced is hashFile with the read paced by lim.
+func hashPaced(ctx context.Context, fs fsys.FS, path string, lim *ratelimit.Limiter) (string, error) {
+	f, err := fs.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return ioutil.HashReader(ctx, lim.Reader(ctx, f), md5.New)
+}
+
+// maxComponent is the longest file name NTFS and exFAT accept, in UTF-16 code units; maxConflicts
+// bounds the numbered names ResolveConflict tries.
+const (
+	maxComponent = 255
+	maxConflicts = 9999
+)
+
+// ResolveConflict returns the first free name path_N.ext for N from 1 to maxConflicts. A long name
+// is cut (on a character) to leave room for the suffix, so the result never exceeds maxComponent.
+// When every N is taken it fails with ErrDestinationExists rather than return a taken path.
+func ResolveConflict(path string) (string, error) { return resolveConflict(fsys.OS, path) }
+
+func resolveConflict(fs fsys.FS, path string) (string, error) {
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; i <= maxConflicts; i++ {
+		newPath := filepath.Join(dir, fitName(base, fmt.Sprintf("_%d%s", i, ext)))
+		if _, err := fs.Lstat(newPath); os.IsNotExist(err) {
+			return newPath, nil
 		}
 	}
-	return path
+	return "", fmt.Errorf("no free name for %s after %d attempts: %w", name, maxConflicts, ErrDestinationExists)
+}
+
+// hashSuffix is how many hex digits of the content hash ConflictHash puts in a name.
+const hashSuffix = 8
+
+// hashConflict names src for the taken path by the start of its content hash. When that name is
+// taken too, it is a duplicate if the content matches (dup is true); otherwise, as when src cannot be
+// hashed, it returns "" and the caller falls back to a numbered name.
+func hashConflict(fs fsys.FS, src, path string, srcHash func(string) (string, error), isDuplicate func(string, string) (bool, error)) (string, bool) {
+	h, err := srcHash(src)
+	if err != nil || len(h) < hashSuffix {
+		logger.Error("Hash suffix unavailable for %s: %v", src, err)
+		return "", false
+	}
+	dir, name := filepath.Split(path)
+	ext := filepath.Ext(name)
+	alt := filepath.Join(dir, fitName(strings.TrimSuffix(name, ext), "_"+h[:hashSuffix]+ext))
+	if _, err := fs.Lstat(alt); os.IsNotExist(err) {
+		return alt, false
+	}
+	if isDup, err := isDuplicate(src, alt); err == nil && isDup {
+		return alt, true
+	}
+	return "", false
+}
+
+// fitName joins base and suffix, cutting base so the name stays within maxComponent.
+func fitName(base, suffix string) string {
+	room := maxComponent - utf16Len(suffix)
+	r := []rune(base)
+	for n := utf16Len(base); n > room && len(r) > 0; r = r[:len(r)-1] {
+		n -= utf16.RuneLen(r[len(r)-1])
+	}
+	return string(r) + suffix
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
 }
 
 func AtomicMove(src, dst string) error {
-	sh, err := metadata.GetFileHash(src); if err != nil { return fmt.Errorf("pre-move hash: %w", err) }
-	if err := os.Rename(src, dst); err != nil {
-		if err := CopyFile(src, dst); err != nil { return fmt.Errorf("copy failed: %w", err) }
-		if err := os.Remove(src); err != nil { logger.Error("Cleanup error: %v", err) }
+	ctx := context.Background()
+	_, err := atomicMove(ctx, fsys.OS, src, dst, func() (string, error) { return metadata.GetFileHashContext(ctx, src) }, false, nil)
+	return err
+}
+
+// atomicMove is AtomicMove on fs returning the verified content hash; srcHash supplies the hash of
+// src before it moves. A copy across volumes is verified before the source is removed, so neither a
+// failed copy nor a failed check ever costs the source. Only a copy that hashes differently is
+// deleted, through the trash package unless hard is set, and a renamed file that does is moved back
+// to its source; one that cannot be read back, after verifyHash's retries, is kept with its source and
+// fails as ErrUnverified, as it may well be the only good copy of a renamed file. Removing the source
+// after a verified copy completes the move rather than deleting anything, so it stays a plain remove.
+// A source another program holds open fails as ErrInUse before anything is copied. lim paces the copy
+// and reading it back; a renamed file is read back at full speed, as nothing crossed a volume.
+func atomicMove(ctx context.Context, fs fsys.FS, src, dst string, srcHash func() (string, error), hard bool, lim *ratelimit.Limiter) (string, error) {
+	sh, err := srcHash(); if ctx.Err() != nil { return "", fmt.Errorf("%w before copying: %w", ErrRolledBack, ctx.Err()) } else if err != nil { return "", fmt.Errorf("pre-move hash: %w", inUse(err)) }
+	copied := false
+	if err := fs.Rename(src, dst); isSharingViolation(err) {
+		// Held open without sharing: the copy would go through, but the source could not be removed.
+		return "", fmt.Errorf("rename: %w", inUse(err))
+	} else if err != nil {
+		if err := copyFileContext(ctx, fs, src, dst, lim); errors.Is(err, ErrRolledBack) { return "", err } else if err != nil { return "", fmt.Errorf("copy failed: %w", ioError(err)) }
+		copied = true
+	}
+	// The copy is done: a cancel no longer stops verifying it, only the timeout does.
+	fin, stop := finishing(ctx); defer stop()
+	var paced *ratelimit.Limiter
+	if copied {
+		paced = lim
+	}
+	th, err := verifyHash(fin, fs, dst, paced)
+	if err != nil && fin.Err() != nil && copied {
+		// Out of time: the copy is rolled back like one cut short, the source being whole.
+		if rerr := fs.Remove(dst); rerr != nil { logger.Error("Unverified copy not removed: %v", rerr) }
+		return "", fmt.Errorf("post-move hash: %w", fin.Err())
+	} else if err != nil {
+		// A renamed file out of time is archived all the same, as one that cannot be read.
+		logger.Error("Archived file %s could not be verified and is kept: %v", dst, err)
+		return "", fmt.Errorf("%s: %w: %w", dst, ErrUnverified, err)
+	}
+	if sh != th {
+		if copied {
+			if err := removeCopy(fs, dst, hard); err != nil { logger.Error("Corrupt copy not removed: %v", err) }
+		} else if err := fs.Rename(dst, src); err != nil {
+			// A renamed file is the source itself, changed since it was hashed. Left in the archive, it
+			// is archived as it is now, unverified.
+			logger.Error("Changed file %s not moved back to %s: %v", dst, src, err)
+			return "", fmt.Errorf("%s: %w: changed while moved, not moved back: %w", dst, ErrUnverified, err)
+		}
+		return "", ErrIntegrityMismatch
 	}
-	th, err := metadata.GetFileHash(dst); if err != nil { return fmt.Errorf("post-move hash: %w", err) }
-	if sh != th { os.Remove(dst); return fmt.Errorf("integrity failed: hash mismatch") }
-	return nil
+	if copied {
+		if err := fs.Remove(src); err != nil { logger.Error("Cleanup error: %v", err) }
+	}
+	return th, nil
+}
+
+// removeCopy deletes a copy that failed verification: into the trash of fs, or for good when hard is
+// set.
+func removeCopy(fs fsys.FS, path string, hard bool) error {
+	if hard {
+		return fs.Remove(path)
+	}
+	return fs.Trash(path)
 }
 
-func CopyFile(src, dst string) error {
-	in, err := os.Open(src); if err != nil { return err }; defer in.Close()
-	out, err := os.Create(dst); if err != nil { return err }; defer out.Close()
-	if _, err := io.Copy(out, in); err != nil { return err }
+// PruneEmptyDirs removes the source directories under root that were emptied by the given moved files.
+// Candidates come only from the parents of moved files; root itself is never removed and a directory
+// holding anything else (unsupported, hidden or unmoved files) is kept. With dryRun nothing is deleted,
+// the moved files are treated as gone and the directories that would be removed are returned.
+func PruneEmptyDirs(root string, moved []string, dryRun bool) ([]string, error) {
+	var fs fsys.FS = fsys.OS
+	if dryRun {
+		fs = fsys.NewRecorder(fs)
+	}
+	return pruneEmptyDirs(fs, root, moved)
+}
+
+func pruneEmptyDirs(fs fsys.FS, root string, moved []string) ([]string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("prune root: %w", err)
+	}
+
+	gone := make(map[string]bool)
+	candidates := make(map[string]bool)
+	for _, p := range moved {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			continue
+		}
+		gone[abs] = true
+		for d := filepath.Dir(abs); isStrictlyUnder(root, d); d = filepath.Dir(d) {
+			candidates[d] = true
+		}
+	}
+
+	dirs := make([]string, 0, len(candidates))
+	for d := range candidates {
+		dirs = append(dirs, d)
+	}
+	// Deepest first, so a parent is only judged after its children were pruned.
+	sort.Slice(dirs, func(i, j int) bool {
+		di, dj := strings.Count(dirs[i], string(filepath.Separator)), strings.Count(dirs[j], string(filepath.Separator))
+		if di != dj {
+			return di > dj
+		}
+		return dirs[i] < dirs[j]
+	})
+
+	var pruned []string
+	for _, d := range dirs {
+		entries, err := fs.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		empty := true
+		for _, e := range entries {
+			if !gone[filepath.Join(d, e.Name())] {
+				empty = false
+				break
+			}
+		}
+		if !empty {
+			continue
+		}
+		if err := fs.Remove(d); err != nil {
+			logger.Error("Prune failed for %s: %v", d, err)
+			continue
+		}
+		if _, dry := fs.(*fsys.Recorder); !dry {
+			logger.Info("Pruned empty folder: %s", d)
+		}
+		gone[d] = true
+		pruned = append(pruned, d)
+	}
+	return pruned, nil
+}
+
+func isStrictlyUnder(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func CopyFile(src, dst string) error { return copyFileContext(context.Background(), fsys.OS, src, dst, nil) }
+
+// copyChunk is how much is copied between two looks at the context.
+const copyChunk = 1 << 20
+
+// finishAbove is the share of a file past which a cancelled copy is finished rather than rolled back.
+const finishAbove = 0.95
+
+func copyFileContext(ctx context.Context, fs fsys.FS, src, dst string, lim *ratelimit.Limiter) error {
+	in, err := fs.Open(src); if err != nil { return err }; defer in.Close()
+	st, err := in.Stat(); if err != nil { return err }
+	return copyTo(ctx, fs, dst, in, st.Size(), lim)
+}
+
+// copyTo writes size bytes from r to the new file dst in chunks, checking ctx between them and
+// giving up on a read that blocks past it. On cancellation the partial file is removed, or finished
+// when more than finishAbove of it is written; a deadline reached always removes it. lim paces the
+// writes, finishing ones included.
+func copyTo(ctx context.Context, fs fsys.FS, dst string, r io.Reader, size int64, lim *ratelimit.Limiter) (err error) {
+	out, err := fs.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			if rerr := fs.Remove(dst); rerr != nil && !os.IsNotExist(rerr) {
+				logger.Error("Partial copy %s not removed: %v", dst, rerr)
+			}
+		}
+	}()
+	src, buf := ioutil.NewReader(ctx, r), make([]byte, copyChunk)
+	written, err := ioutil.CopyContext(ctx, lim.Writer(ctx, out), src, buf, nil)
+	if cerr := ctx.Err(); cerr != nil && errors.Is(err, cerr) {
+		if errors.Is(cerr, context.DeadlineExceeded) || float64(written) <= finishAbove*float64(size) {
+			return fmt.Errorf("%w after %d of %d bytes: %w", ErrRolledBack, written, size, cerr)
+		}
+		fin, stop := finishing(ctx)
+		defer stop()
+		n, ferr := ioutil.CopyContext(fin, lim.Writer(fin, out), src, buf, nil)
+		if written += n; ferr != nil && errors.Is(ferr, fin.Err()) {
+			return fmt.Errorf("%w after %d of %d bytes: %w", ErrRolledBack, written, size, ferr)
+		}
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
 	return out.Sync()
 }
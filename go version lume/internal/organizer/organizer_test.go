@@ -1,8 +1,22 @@
 package organizer
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"lume-go/internal/fsys"
+	"lume-go/internal/index"
+	"lume-go/internal/metadata"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestSanitizeFolderName(t *testing.T) {
@@ -16,14 +30,34 @@ func TestSanitizeFolderName(t *testing.T) {
 		{"NUL", "NUL_safe"},
 		{"COM1", "COM1_safe"},
 		{"LPT1", "LPT1_safe"},
+		{"COM2", "COM2_safe"},
+		{"com9", "com9_safe"},
+		{"LPT5", "LPT5_safe"},
+		{"NUL.txt", "NUL_safe.txt"},
+		{"Con .backup", "Con_safe.backup"},
+		{"COM10", "COM10"},
+		{"Console", "Console"},
 		{"my<file>", "my_file_"},
 		{"folder/path", "folder_path"},
 		{"file:name", "file_name"},
+		{`a<>:"b`, "a_b"},
+		{"tab\tname", "tab_name"},
 		{"  trim  ", "trim"},
 		{"", "Unknown"},
 		{".", "Unknown"},
 		{"..", "Unknown"},
-		{"a" + strings.Repeat("b", 150), "a" + strings.Repeat("b", 99)}, // Length limit test
+		{" . . ", "Unknown"},
+		{"Trip.", "Trip"},
+		{"Trip. . ", "Trip"},
+		{".hidden", "hidden"},
+		{"Mi 11.5G", "Mi 11.5G"},
+		{"a" + strings.Repeat("b", 150), "a" + strings.Repeat("b", 98) + "…"}, // Length limit test
+		{strings.Repeat("b", 100), strings.Repeat("b", 100)},
+		{strings.Repeat("b", 98) + ". x", strings.Repeat("b", 98) + "…"},
+		{"  01-Şubat ", "01-Şubat"},
+		{"Ağustos", "Ağustos"},
+		{strings.Repeat("ğ", 120), strings.Repeat("ğ", 99) + "…"}, // Cut on runes, not bytes
+		{strings.Repeat("📷", 120), strings.Repeat("📷", 99) + "…"},
 	}
 	for _, tt := range tests {
 		got := SanitizeFolderName(tt.input)
@@ -32,3 +66,825 @@ func TestSanitizeFolderName(t *testing.T) {
 		}
 	}
 }
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"IMG_0001.jpg", "IMG_0001.jpg"},
+		{"aux.mp4", "aux_safe.mp4"},
+		{"NUL.tar.jpg", "NUL_safe.tar.jpg"},
+		{"com1 .png", "com1_safe.png"},
+		{"auxiliary.mp4", "auxiliary.mp4"},
+		{"photo .jpg", "photo.jpg"},
+		{"photo. .jpg", "photo.jpg"},
+		{"photo.jpg.", "photo.jpg"},
+		{"photo.jpg ", "photo.jpg"},
+		{"a<b>.png", "a_b_.png"},
+		{`a:b|c?"d"*.heic`, "a_b_c__d__.heic"},
+		{"tab\tname.jpg", "tab_name.jpg"},
+		{"Mi 11.5G shot.jpg", "Mi 11.5G shot.jpg"},
+		{" .jpg", "Unknown.jpg"},
+		{"...", "Unknown"},
+		{" leading.jpg", " leading.jpg"},
+	}
+	for _, tt := range tests {
+		got := SanitizeFileName(tt.input)
+		if got != tt.want {
+			t.Errorf("SanitizeFileName(%q) = %q; want %q", tt.input, got, tt.want)
+		}
+		if again := SanitizeFileName(got); again != got {
+			t.Errorf("SanitizeFileName(%q) = %q; not stable", got, again)
+		}
+		if filepath.Ext(got) != filepath.Ext(strings.TrimRight(tt.input, ". ")) && strings.Trim(tt.input, ". ") != "" {
+			t.Errorf("SanitizeFileName(%q) = %q; extension changed", tt.input, got)
+		}
+	}
+}
+
+// Names valid on the source system but not on Windows are archived under their sanitized form; two
+// originals that sanitize alike both keep their content.
+func TestMoveFileSanitizesName(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	for i, name := range []string{"aux.mp4", "photo .jpg", "a<b>.png", "a>b<.png"} {
+		path := filepath.Join(src, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Skipf("%q cannot be created here: %v", name, err)
+		}
+		info := metadata.FileInfo{Path: path, Filename: name, Size: int64(len(name)), Year: "2024", Month: "05", Source: "Camera"}
+		got, err := MoveFile(info, root, Options{})
+		if err != nil {
+			t.Fatalf("MoveFile(%q): %v", name, err)
+		}
+		want := []string{"aux_safe.mp4", "photo.jpg", "a_b_.png", "a_b__1.png"}[i]
+		if filepath.Base(got) != want {
+			t.Errorf("MoveFile(%q) archived as %q; want %q", name, filepath.Base(got), want)
+		}
+		if data, _ := os.ReadFile(got); string(data) != name {
+			t.Errorf("%s holds %q; want the content of %q", got, data, name)
+		}
+	}
+}
+
+// With ConflictHash a different file under a taken name gets the start of its hash, and importing it
+// again finds that copy instead of adding another.
+func TestMoveFileHashConflict(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	dir := filepath.Join(root, "2024", "05", "Camera")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "DJI_0001.JPG"), []byte("first flight"), 0644)
+	opts := Options{Conflicts: ConflictHash}
+
+	move := func() string {
+		path := filepath.Join(src, "DJI_0001.JPG")
+		os.WriteFile(path, []byte("second flight"), 0644)
+		info := metadata.FileInfo{Path: path, Filename: "DJI_0001.JPG", Size: 13, Year: "2024", Month: "05", Source: "Camera"}
+		got, err := MoveFile(info, root, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	first := move()
+	h, _ := metadata.GetFileHash(first)
+	if want := filepath.Join(dir, "DJI_0001_"+h[:hashSuffix]+".JPG"); first != want {
+		t.Fatalf("MoveFile = %q; want %q", first, want)
+	}
+	if again := move(); again != first {
+		t.Errorf("second import = %q; want the existing %q", again, first)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("%d files in the folder; want 2", len(entries))
+	}
+}
+
+func TestResolveConflictLongNames(t *testing.T) {
+	dir := t.TempDir()
+	long := strings.Repeat("a", 251) + ".jpg"
+	if err := os.WriteFile(filepath.Join(dir, long), nil, 0644); err != nil {
+		t.Skipf("255-character names unavailable: %v", err)
+	}
+	for i, want := range []string{strings.Repeat("a", 249) + "_1.jpg", strings.Repeat("a", 249) + "_2.jpg"} {
+		got, err := ResolveConflict(filepath.Join(dir, long))
+		if err != nil || got != filepath.Join(dir, want) {
+			t.Fatalf("attempt %d: ResolveConflict = %q, %v; want %q", i+1, filepath.Base(got), err, want)
+		}
+		if err := os.WriteFile(got, nil, 0644); err != nil {
+			t.Fatalf("resolved name cannot be created: %v", err)
+		}
+	}
+
+	// Cuts fall on characters and count UTF-16 units: ğ takes one, 📷 two.
+	tests := []struct {
+		base, suffix, want string
+	}{
+		{"short", "_1.jpg", "short_1.jpg"},
+		{strings.Repeat("ğ", 260), "_12.jpg", strings.Repeat("ğ", 248) + "_12.jpg"},
+		{strings.Repeat("📷", 130), "_1.jpg", strings.Repeat("📷", 124) + "_1.jpg"},
+		{"x" + strings.Repeat("📷", 125), "_1.jpg", "x" + strings.Repeat("📷", 124) + "_1.jpg"},
+	}
+	for _, tt := range tests {
+		got := fitName(tt.base, tt.suffix)
+		if got != tt.want || utf16Len(got) > maxComponent {
+			t.Errorf("fitName(%d runes, %q) = %d runes, %d units", len([]rune(tt.base)), tt.suffix, len([]rune(got)), utf16Len(got))
+		}
+	}
+}
+
+func TestResolveConflictExhausted(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "IMG.jpg")
+	os.WriteFile(path, nil, 0644)
+	for i := 1; i <= maxConflicts; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("IMG_%d.jpg", i)), nil, 0644)
+	}
+	got, err := ResolveConflict(path)
+	if !errors.Is(err, ErrDestinationExists) || got != "" {
+		t.Fatalf("ResolveConflict = %q, %v; want ErrDestinationExists", got, err)
+	}
+}
+
+func TestPruneEmptyDirs(t *testing.T) {
+	root := t.TempDir()
+	mk := func(rel string) string {
+		p := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	moved := []string{
+		mk("DCIM/100APPLE/IMG_0001.JPG"),
+		mk("DCIM/100APPLE/IMG_0002.JPG"),
+		mk("DCIM/101APPLE/IMG_0003.JPG"),
+		mk("top.jpg"),
+	}
+	mk("DCIM/101APPLE/.hidden")
+
+	// Dry-run: nothing is touched, the moved files count as gone.
+	got, err := PruneEmptyDirs(root, moved, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != filepath.Join(root, "DCIM", "100APPLE") {
+		t.Fatalf("dry-run pruned %v", got)
+	}
+	if _, err := os.Stat(moved[0]); err != nil {
+		t.Fatalf("dry-run touched files: %v", err)
+	}
+
+	for _, p := range moved {
+		os.Remove(p)
+	}
+	got, err = PruneEmptyDirs(root, moved, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("pruned %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "DCIM", "100APPLE")); !os.IsNotExist(err) {
+		t.Error("emptied folder was kept")
+	}
+	if _, err := os.Stat(filepath.Join(root, "DCIM", "101APPLE")); err != nil {
+		t.Error("folder with a hidden file was removed")
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Error("root was removed")
+	}
+
+	// Once the hidden file is gone the whole chain collapses, still sparing root.
+	os.Remove(filepath.Join(root, "DCIM", "101APPLE", ".hidden"))
+	got, _ = PruneEmptyDirs(root, moved, false)
+	if len(got) != 2 || got[1] != filepath.Join(root, "DCIM") {
+		t.Fatalf("pruned %v", got)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Error("root was removed")
+	}
+}
+
+func TestTargetDir(t *testing.T) {
+	info := metadata.FileInfo{Device: "Pixel 7", Source: "Camera"}
+	info.SetDate(time.Date(2023, 5, 20, 12, 0, 0, 0, time.UTC))
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"", "base/2023/05/Camera_Pixel 7"},
+		{"{year}/{month}", "base/2023/05"},
+		{"{year}-{month}/{model}", "base/2023-05/Pixel 7"},
+		{"{source}//{year}", "base/Camera/2023"},
+		{"{year}/{month}/{day}", "base/2023/05/20"},
+		{"{weekyear}/{week}/{device}", "base/2023/W20/Camera_Pixel 7"},
+	}
+	for _, tt := range tests {
+		if got := TargetDir(info, "base", Layout{Template: tt.template}); got != filepath.FromSlash(tt.want) {
+			t.Errorf("TargetDir(%q) = %q; want %q", tt.template, got, tt.want)
+		}
+	}
+
+	// New Year's Eve 2024 belongs to ISO week 1 of 2025.
+	info.SetDate(time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+	if got, want := TargetDir(info, "base", Layout{Template: "{weekyear}/{week}"}), filepath.Join("base", "2025", "W01"); got != want {
+		t.Errorf("week boundary: got %q; want %q", got, want)
+	}
+
+	// A nested source renders as deep as the layout asks, as folders in a template and joined in a name.
+	chat := metadata.FileInfo{Filename: "IMG-20230520-WA0003.jpg", Device: "Unknown", Source: "WhatsApp/Images/Sent"}
+	chat.SetDate(time.Date(2023, 5, 20, 0, 0, 0, 0, time.UTC))
+	for depth, want := range map[int]string{0: "base/WhatsApp/2023", 2: "base/WhatsApp/Images/2023", 3: "base/WhatsApp/Images/Sent/2023"} {
+		if got := TargetDir(chat, "base", Layout{Template: "{source}/{year}", SourceDepth: depth}); got != filepath.FromSlash(want) {
+			t.Errorf("source depth %d: got %q; want %q", depth, got, want)
+		}
+	}
+	if got := TargetDir(chat, "base", Layout{SourceDepth: 3}); got != filepath.Join("base", "2023", "05", "WhatsApp") {
+		t.Errorf("device folder of a nested source: %q", got)
+	}
+	if got := FileName(chat, Layout{NameTemplate: "{source}_{name}", SourceDepth: 3}); got != "WhatsApp_Images_Sent_IMG-20230520-WA0003.jpg" {
+		t.Errorf("nested source in a name: %q", got)
+	}
+
+	// Without a plausible date the file goes to one Undated folder instead of 2089/05.
+	info.SetUndated()
+	if got, want := TargetDir(info, "base", Layout{}), filepath.Join("base", "Undated", "Camera_Pixel 7"); got != want {
+		t.Errorf("undated: got %q; want %q", got, want)
+	}
+}
+
+var turkishMonths = []string{"Ocak", "Şubat", "Mart", "Nisan", "Mayıs", "Haziran", "Temmuz", "Ağustos", "Eylül", "Ekim", "Kasım", "Aralık"}
+
+func TestMonthLabel(t *testing.T) {
+	tests := []struct {
+		layout Layout
+		month  string
+		want   string
+	}{
+		{Layout{}, "02", "02"},
+		{Layout{MonthStyle: MonthName, MonthNames: turkishMonths}, "02", "Şubat"},
+		{Layout{MonthStyle: MonthNumberName, MonthNames: turkishMonths}, "08", "08-Ağustos"},
+		{Layout{MonthStyle: MonthNumberName, MonthNames: []string{"Jan"}}, "01", "01"}, // incomplete mapping
+		{Layout{MonthStyle: MonthName, MonthNames: turkishMonths}, "13", "13"},
+	}
+	for _, tt := range tests {
+		if got := tt.layout.MonthLabel(tt.month); got != tt.want {
+			t.Errorf("MonthLabel(%q, %s) = %q; want %q", tt.month, tt.layout.MonthStyle, got, tt.want)
+		}
+	}
+
+	info := metadata.FileInfo{Year: "2024", Month: "05", Source: "Camera"}
+	got := TargetDir(info, "base", Layout{MonthStyle: MonthNumberName, MonthNames: turkishMonths})
+	if want := filepath.Join("base", "2024", "05-Mayıs", "Camera"); got != want {
+		t.Errorf("TargetDir = %q; want %q", got, want)
+	}
+}
+
+func TestInPlace(t *testing.T) {
+	layout := Layout{MonthStyle: MonthNumberName, MonthNames: turkishMonths}
+	base := filepath.Join(string(filepath.Separator), "Archive")
+	at := func(rel string) metadata.FileInfo {
+		info := metadata.FileInfo{Filename: "IMG_1.jpg", Path: filepath.Join(base, filepath.FromSlash(rel), "IMG_1.jpg"), Source: "Camera", Device: "Pixel 7"}
+		info.SetDate(time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC))
+		return info
+	}
+	for rel, want := range map[string]bool{
+		"2024/05-Mayıs/Camera_Pixel 7":   true,
+		"2024/05/Camera_Pixel 7":         true, // another month style
+		"2024/05-mayıs/camera_pixel 7":   true, // Windows ignores case
+		"2024/05-Mai/Camera_Pixel 7":     true, // the month names of another language
+		"2024/May/Camera_Pixel 7":        true,
+		"2024/06-Haziran/Camera_Pixel 7": false,
+		"2024/05-Mayıs":                  false,
+		"2024/05-Mayıs/Pixel":            false,
+		"Import/2024/05/Camera_Pixel 7":  false,
+	} {
+		if got := InPlace(at(rel), base, layout); got != want {
+			t.Errorf("InPlace(%s) = %v; want %v", rel, got, want)
+		}
+	}
+	renamed := at("2024/05-Mayıs/Camera_Pixel 7")
+	if InPlace(renamed, base, Layout{NameTemplate: "{year}{month}{day}_{time}", MonthStyle: MonthNumberName, MonthNames: turkishMonths}) {
+		t.Error("in place under a name the layout does not give")
+	}
+}
+
+func TestArchivedLayout(t *testing.T) {
+	at := func(rel string) metadata.FileInfo {
+		info := metadata.FileInfo{Filename: "IMG_1.jpg", Path: filepath.Join(string(filepath.Separator), filepath.FromSlash(rel), "IMG_1.jpg"), Source: "Camera", Device: "Pixel 7"}
+		info.SetDate(time.Date(2024, 5, 20, 12, 0, 0, 0, time.UTC))
+		return info
+	}
+	layout := Layout{MonthNames: turkishMonths}
+	tests := []struct {
+		template, path, rel string
+	}{
+		{"", "Old Archive/2024/05/Camera_Pixel 7", "2024/05/Camera_Pixel 7"},
+		{"", "USB/Backup/2024/05-Mayıs/Phone of Ayşe", "2024/05-Mayıs/Phone of Ayşe"}, // renamed device folder
+		{"", "USB/2024/Mayıs/x", "2024/Mayıs/x"},
+		{"", "2024/05/Camera_Pixel 7", "2024/05/Camera_Pixel 7"},
+		{"{year}/{month}", "Old/2024/05", "2024/05"},
+		{"Photos {year}/{month}/{day}", "D/photos 2024/05/20", "photos 2024/05/20"},
+		{"{weekyear}/{week}", "D/2024/W21", "2024/W21"},
+		{"", "Old/2024/06/Camera_Pixel 7", ""},   // wrong month
+		{"", "Old/2023/05/Camera_Pixel 7", ""},   // wrong year
+		{"", "Old/05/Camera_Pixel 7", ""},        // a level missing
+		{"", "Old/2024/05", ""},                  // the device level missing
+		{"", "Old/2024/05/a/Camera_Pixel 7", ""}, // a level too many
+		{"{year}/{month}/{day}", "Old/2024/05/21", ""},
+		{"{device}", "Anything/At all", ""}, // no date to tell an archive by
+	}
+	for _, tt := range tests {
+		l := layout
+		l.Template = tt.template
+		rel, ok := ArchivedLayout(at(tt.path), l)
+		if want := filepath.FromSlash(tt.rel); rel != want || ok != (tt.rel != "") {
+			t.Errorf("ArchivedLayout(%q, %q) = %q, %v; want %q", tt.template, tt.path, rel, ok, want)
+		}
+	}
+
+	// A nested source spans as many folders as the layout renders of it.
+	chat := metadata.FileInfo{Filename: "IMG-20240520-WA0003.jpg", Path: filepath.FromSlash("/WhatsApp/Images/2024/IMG-20240520-WA0003.jpg"), Source: "WhatsApp/Images/Sent"}
+	chat.SetDate(time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC))
+	if rel, ok := ArchivedLayout(chat, Layout{Template: "{source}/{year}", SourceDepth: 2}); !ok || rel != filepath.FromSlash("WhatsApp/Images/2024") {
+		t.Errorf("nested source: %q, %v", rel, ok)
+	}
+	if _, ok := ArchivedLayout(chat, Layout{Template: "{source}/{year}", SourceDepth: 3}); ok {
+		t.Error("matched a nested source a level short")
+	}
+}
+
+func TestMoveFileFindsCopyUnderOtherMonthLabel(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	old := filepath.Join(root, "2024", "05", "Camera")
+	os.MkdirAll(old, 0755)
+	os.WriteFile(filepath.Join(old, "IMG_1.jpg"), []byte("same"), 0644)
+	os.WriteFile(filepath.Join(src, "IMG_1.jpg"), []byte("same"), 0644)
+
+	info := metadata.FileInfo{Path: filepath.Join(src, "IMG_1.jpg"), Filename: "IMG_1.jpg", Size: 4, Year: "2024", Month: "05", Source: "Camera", Device: "Unknown"}
+	got, err := MoveFile(info, root, Options{Layout: Layout{MonthStyle: MonthNumberName, MonthNames: turkishMonths}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != filepath.Join(old, "IMG_1.jpg") {
+		t.Errorf("MoveFile = %q; want the copy in the numeric month folder", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2024", "05-Mayıs")); !os.IsNotExist(err) {
+		t.Error("a second month folder was created for a duplicate")
+	}
+}
+
+func TestReorganizeAndUndo(t *testing.T) {
+	root := t.TempDir()
+	old := filepath.Join(root, "2023", "05", "Camera")
+	os.MkdirAll(old, 0755)
+	for _, name := range []string{"IMG_0001.jpg", "IMG-20230501-WA0001.jpg"} {
+		os.WriteFile(filepath.Join(old, name), []byte(name), 0644)
+	}
+	os.MkdirAll(filepath.Join(root, "Camera"), 0755)
+	os.WriteFile(filepath.Join(root, "Camera", "IMG_0001.jpg"), []byte("already here"), 0644)
+
+	// Without EXIF the default template cannot place anything, so every file stays put.
+	plan, err := PlanReorganize(context.Background(), root, Layout{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 0 || len(plan.Skipped) != 3 || plan.Skipped[0].Reason != "no EXIF date" {
+		t.Fatalf("default template plan = %+v", plan)
+	}
+
+	plan, err = PlanReorganize(context.Background(), root, Layout{Template: "{source}"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 2 || plan.Unchanged != 1 {
+		t.Fatalf("source template plan = %+v", plan)
+	}
+	res, err := ApplyReorganize(context.Background(), plan, nil)
+	if err != nil || res.Moved != 2 {
+		t.Fatalf("apply = %+v, %v", res, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "Camera", "IMG_0001_1.jpg")); err != nil {
+		t.Error("conflicting name was not suffixed")
+	}
+	if _, err := os.Stat(filepath.Join(root, "WhatsApp", "IMG-20230501-WA0001.jpg")); err != nil {
+		t.Error("file was not moved to its source folder")
+	}
+	if _, err := os.Stat(filepath.Join(root, "2023")); !os.IsNotExist(err) {
+		t.Error("emptied layout folders were kept")
+	}
+
+	n, err := UndoReorganize(res.Journal)
+	if err != nil || n != 2 {
+		t.Fatalf("undo = %d, %v", n, err)
+	}
+	for _, name := range []string{"IMG_0001.jpg", "IMG-20230501-WA0001.jpg"} {
+		if data, err := os.ReadFile(filepath.Join(old, name)); err != nil || string(data) != name {
+			t.Errorf("%s not restored: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "WhatsApp")); !os.IsNotExist(err) {
+		t.Error("folder created by the reorganization was kept after undo")
+	}
+}
+
+func TestReorganizeProtected(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"2015/05/WhatsApp", "2015/05/Unsorted", "2023/05/Camera"} {
+		os.MkdirAll(filepath.Join(root, filepath.FromSlash(dir)), 0755)
+	}
+	os.WriteFile(filepath.Join(root, "2015", validator.ProtectMarker), nil, 0644)
+	os.WriteFile(filepath.Join(root, "2015", "05", "WhatsApp", "IMG-20150501-WA0001.jpg"), []byte("a"), 0644)
+	os.WriteFile(filepath.Join(root, "2023", "05", "Camera", "IMG_0001.jpg"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(root, "2023", "05", "Camera", "IMG-20230501-WA0002.jpg"), []byte("c"), 0644)
+	// A listed folder is protected as well, and a file would move into it.
+	protect := validator.NewProtection([]string{filepath.Join(root, "WhatsApp")})
+
+	plan, err := PlanReorganize(context.Background(), root, Layout{Template: "{source}"}, protect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 1 || filepath.Base(plan.Moves[0].From) != "IMG_0001.jpg" {
+		t.Errorf("moves = %+v; want IMG_0001.jpg only", plan.Moves)
+	}
+	reasons := make(map[string]string)
+	for _, s := range plan.Skipped {
+		reasons[filepath.Base(s.Path)] = s.Reason
+	}
+	if reasons["2015"] != "protected" || !strings.Contains(reasons["IMG-20230501-WA0002.jpg"], ErrProtected.Error()) || len(reasons) != 2 {
+		t.Errorf("skipped = %+v", plan.Skipped)
+	}
+
+	var pe *validator.ProtectedError
+	if _, err := PlanReorganize(context.Background(), filepath.Join(root, "2015", "05"), Layout{}, protect); !errors.As(err, &pe) {
+		t.Errorf("protected root: %v", err)
+	}
+}
+
+func TestMoveFileProtected(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	os.MkdirAll(filepath.Join(root, "2015", "06", "Camera"), 0755)
+	os.WriteFile(filepath.Join(root, "2015", validator.ProtectMarker), nil, 0644)
+	os.WriteFile(filepath.Join(root, "2015", "06", "Camera", "old.jpg"), []byte("old"), 0644)
+	opts := Options{Protect: validator.NewProtection(nil)}
+
+	// A copy in the protected folder is still found.
+	os.WriteFile(filepath.Join(src, "old.jpg"), []byte("old"), 0644)
+	info := metadata.FileInfo{Path: filepath.Join(src, "old.jpg"), Filename: "old.jpg", Size: 3, Year: "2015", Month: "06", Source: "Camera"}
+	if got, err := MoveFile(info, root, opts); err != nil || got != filepath.Join(root, "2015", "06", "Camera", "old.jpg") {
+		t.Errorf("duplicate = %q, %v", got, err)
+	}
+
+	// Nothing new goes in, not even into a folder yet to be made.
+	os.WriteFile(filepath.Join(src, "new.jpg"), []byte("new"), 0644)
+	info = metadata.FileInfo{Path: filepath.Join(src, "new.jpg"), Filename: "new.jpg", Size: 3, Year: "2015", Month: "07", Source: "Camera"}
+	if _, err := MoveFile(info, root, opts); !errors.Is(err, ErrProtected) || Category(err) != CategoryProtected {
+		t.Errorf("MoveFile = %v; want ErrProtected", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2015", "07")); !os.IsNotExist(err) {
+		t.Error("a folder was created in the protected tree")
+	}
+	if _, err := os.Stat(info.Path); err != nil {
+		t.Errorf("source not kept: %v", err)
+	}
+
+	// Next to the protected year the archive takes files as before.
+	info.Year = "2016"
+	if _, err := MoveFile(info, root, opts); err != nil {
+		t.Errorf("unprotected year: %v", err)
+	}
+}
+
+func TestMoveFileSourceMissing(t *testing.T) {
+	root := t.TempDir()
+	info := metadata.FileInfo{Path: filepath.Join(t.TempDir(), "gone.jpg"), Filename: "gone.jpg", Year: "2024", Month: "05", Source: "Camera"}
+	_, err := MoveFile(info, root, Options{})
+	if !errors.Is(err, ErrSourceMissing) {
+		t.Fatalf("MoveFile = %v; want ErrSourceMissing", err)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Error("folders were created for a missing source")
+	}
+}
+
+func TestMoveFileTooLarge(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(src, "a.mp4"), []byte("video"), 0644)
+	// The size reported by the scan decides, so no 4 GB file need be written.
+	info := metadata.FileInfo{Path: filepath.Join(src, "a.mp4"), Filename: "a.mp4", Size: validator.FATMaxFile + 1, Year: "2024", Month: "05", Source: "Camera"}
+	var tooLarge *validator.SizeLimitError
+	if _, err := MoveFile(info, root, Options{FileSystem: validator.FileSystemFAT32}); !errors.As(err, &tooLarge) || Category(err) != CategorySpace {
+		t.Fatalf("MoveFile = %v; want a SizeLimitError", err)
+	}
+	if tooLarge.Path != root || tooLarge.Limit != validator.FATMaxFile {
+		t.Errorf("error = %+v", tooLarge)
+	}
+	if entries, _ := os.ReadDir(root); len(entries) != 0 {
+		t.Error("folders were created for a file too large")
+	}
+	if _, err := MoveFile(info, root, Options{FileSystem: validator.FileSystemExFAT}); err != nil {
+		t.Errorf("exFAT: %v", err)
+	}
+}
+
+func TestMoveFileSameFileElsewhere(t *testing.T) {
+	root := t.TempDir()
+	info := metadata.FileInfo{Filename: "a.jpg", Year: "2024", Month: "05", Source: "Camera"}
+	dir := TargetDir(info, root, Layout{})
+	os.MkdirAll(dir, 0755)
+	archived := filepath.Join(dir, "a.jpg")
+	os.WriteFile(archived, []byte("a"), 0644)
+	info.Path = filepath.Join(t.TempDir(), "a.jpg")
+	if err := os.Link(archived, info.Path); err != nil {
+		t.Skipf("hardlinks unavailable: %v", err)
+	}
+
+	got, err := MoveFile(info, root, Options{})
+	if err != nil || got != archived {
+		t.Fatalf("MoveFile = %q, %v; want %q", got, err, archived)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("target holds %d files; want the one archived copy", len(entries))
+	}
+}
+
+func TestMoveFileKnownHash(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	info := metadata.FileInfo{Path: filepath.Join(src, "a.jpg"), Filename: "a.jpg", Size: 4, Year: "2024", Month: "05", Source: "Camera"}
+	dir := TargetDir(info, root, Layout{})
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "a.jpg"), []byte("same"), 0644)
+	os.WriteFile(info.Path, []byte("same"), 0644)
+
+	// A kept hash that disagrees with the content proves the duplicate check used it; the integrity
+	// check reads the file itself and reports what it read.
+	info.MD5 = "cached"
+	var hashed []string
+	opts := Options{Hashed: func(path, md5 string) { hashed = append(hashed, md5) }}
+	got, err := MoveFile(info, root, opts)
+	if err != nil || got == filepath.Join(dir, "a.jpg") {
+		t.Fatalf("MoveFile = %q, %v; want a renamed copy", got, err)
+	}
+	if h, _ := metadata.GetFileHash(got); len(hashed) != 1 || hashed[0] != h {
+		t.Errorf("Hashed got %v; want the one fresh hash %s", hashed, h)
+	}
+}
+
+// A move through another file system never touches the disk.
+func TestMoveFileInMemory(t *testing.T) {
+	m := fsys.NewMem()
+	root, src := filepath.Join(string(filepath.Separator), "archive"), filepath.Join(string(filepath.Separator), "card")
+	m.MkdirAll(root, 0755)
+	var got []string
+	for i, content := range []string{"first", "second", "first"} {
+		path := filepath.Join(src, fmt.Sprintf("%d", i), "IMG_0001.JPG")
+		m.WriteFile(path, []byte(content))
+		info := metadata.FileInfo{Path: path, Filename: "IMG_0001.JPG", Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+		dest, err := MoveFile(info, root, Options{FS: m})
+		if err != nil {
+			t.Fatalf("MoveFile %d: %v", i, err)
+		}
+		got = append(got, dest)
+	}
+	dir := filepath.Join(root, "2024", "05", "Camera")
+	want := []string{filepath.Join(dir, "IMG_0001.JPG"), filepath.Join(dir, "IMG_0001_1.JPG"), filepath.Join(dir, "IMG_0001.JPG")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("archived as %v; want %v", got, want)
+	}
+	if data, _ := m.ReadFile(want[1]); string(data) != "second" {
+		t.Errorf("%s holds %q", want[1], data)
+	}
+	if fsys.Exists(m, filepath.Join(src, "1", "IMG_0001.JPG")) || !fsys.Exists(m, filepath.Join(src, "2", "IMG_0001.JPG")) {
+		t.Error("the moved source is left, or the duplicate was removed")
+	}
+	if _, err := os.Stat(root); !os.IsNotExist(err) {
+		t.Errorf("the disk changed: %v", err)
+	}
+}
+
+// A move through a Recorder goes through as it would, verifying included, and leaves the disk as it was.
+func TestMoveFileDryRun(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	taken := filepath.Join(root, "2024", "05", "Camera", "IMG_0001.JPG")
+	os.MkdirAll(filepath.Dir(taken), 0755)
+	os.WriteFile(taken, []byte("another photo"), 0644)
+	path := filepath.Join(src, "IMG_0001.JPG")
+	os.WriteFile(path, []byte("the photo"), 0644)
+	info := metadata.FileInfo{Path: path, Filename: "IMG_0001.JPG", Size: 9, Year: "2024", Month: "05", Source: "Camera"}
+
+	rec := fsys.NewRecorder(nil)
+	got, err := MoveFile(info, root, Options{FS: rec})
+	want := filepath.Join(filepath.Dir(taken), "IMG_0001_1.JPG")
+	if err != nil || got != want {
+		t.Fatalf("MoveFile = %q, %v; want %q", got, err, want)
+	}
+	if ops := rec.Ops(); !reflect.DeepEqual(ops, []fsys.Op{{Kind: "rename", Path: path, To: want}}) {
+		t.Errorf("recorded %v", ops)
+	}
+	if !fsys.Exists(rec, want) || fsys.Exists(rec, path) {
+		t.Error("the dry run does not show the file moved")
+	}
+	if _, err := os.Stat(path); err != nil || fsys.Exists(fsys.OS, want) {
+		t.Errorf("the disk changed: %v", err)
+	}
+	// A second file of the same name sees the first one's place taken.
+	os.WriteFile(path+"2", []byte("a third photo"), 0644)
+	info = metadata.FileInfo{Path: path + "2", Filename: "IMG_0001.JPG", Size: 13, Year: "2024", Month: "05", Source: "Camera"}
+	if got, err := MoveFile(info, root, Options{FS: rec}); err != nil || got != filepath.Join(filepath.Dir(taken), "IMG_0001_2.JPG") {
+		t.Errorf("second MoveFile = %q, %v", got, err)
+	}
+}
+
+// countingFS counts the files opened for reading.
+type countingFS struct {
+	fsys.FS
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fsys.File, error) {
+	c.mu.Lock()
+	c.opens[name]++
+	c.mu.Unlock()
+	return c.FS.Open(name)
+}
+
+// However many checks need the hash of a source (the index, the taken name, the hash suffix and the
+// integrity check here), it is read for hashing once.
+func TestMoveFileHashesSourceOnce(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	dir := filepath.Join(root, "2024", "05", "Camera")
+	os.MkdirAll(dir, 0755)
+	os.WriteFile(filepath.Join(dir, "DJI_0001.JPG"), []byte("first flight"), 0644)
+	ix, err := index.Build(context.Background(), root, index.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(src, "DJI_0001.JPG")
+	os.WriteFile(path, []byte("other flight"), 0644)
+	info := metadata.FileInfo{Path: path, Filename: "DJI_0001.JPG", Size: 12, Year: "2024", Month: "05", Source: "Camera"}
+
+	fs := &countingFS{FS: fsys.OS, opens: make(map[string]int)}
+	got, err := MoveFile(info, root, Options{Index: ix, Conflicts: ConflictHash, FS: fs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.opens[path] != 1 || fs.opens[got] != 1 {
+		t.Errorf("hashing opened the source %d times and the copy %d times; want once each", fs.opens[path], fs.opens[got])
+	}
+}
+
+func TestErrorCategories(t *testing.T) {
+	perm := &os.PathError{Op: "open", Path: `D:\Archive\a.jpg`, Err: os.ErrPermission}
+	tests := []struct {
+		name string
+		err  error
+		is   error
+		want string
+	}{
+		{"permission through copy and move wrapping", fmt.Errorf("archive move error for a.jpg: %w", fmt.Errorf("copy failed: %w", ioError(perm))), ErrNotWritable, CategoryWritable},
+		{"space check", fmt.Errorf("D:: %w", &validator.SpaceError{Path: "D:", Need: 10, Have: 1}), ErrInsufficientSpace, CategorySpace},
+		{"disk full while copying", fmt.Errorf("copy failed: %w", ioError(syscall.ENOSPC)), ErrInsufficientSpace, CategorySpace},
+		{"too large for FAT32", fmt.Errorf("a.mp4: %w", &validator.SizeLimitError{Path: "E:", FileSystem: "FAT32", Size: 5 << 30, Limit: validator.FATMaxFile}), ErrTooLarge, CategorySpace},
+		{"integrity", fmt.Errorf("archive move error for a.jpg: %w", ErrIntegrityMismatch), ErrIntegrityMismatch, CategoryIntegrity},
+		{"unverified", fmt.Errorf(`D:\Archive\a.jpg: %w: %w`, ErrUnverified, &os.PathError{Op: "open", Path: `D:\Archive\a.jpg`, Err: errorSharingViolation}), ErrUnverified, CategoryUnverified},
+		{"unsupported", fmt.Errorf("%w: .txt", metadata.ErrUnsupportedType), ErrUnsupportedType, CategoryUnsupported},
+		{"missing", fmt.Errorf("a.jpg: %w", ErrSourceMissing), ErrSourceMissing, CategoryMissing},
+		{"exists", fmt.Errorf("a.jpg: %w", ErrDestinationExists), ErrDestinationExists, CategoryExists},
+		{"rolled back", fmt.Errorf("a.mp4: %w after 1 of 9 bytes: %w", ErrRolledBack, context.Canceled), ErrRolledBack, CategoryRolledBack},
+		{"timed out", fmt.Errorf("a.mp4: %w: %w", ErrTimeout, fmt.Errorf("%w after 1 of 9 bytes: %w", ErrRolledBack, context.DeadlineExceeded)), ErrTimeout, CategoryTimeout},
+		{"in use", fmt.Errorf("archive move error for a.mp4: %w", fmt.Errorf("rename: %w", inUse(&os.LinkError{Op: "rename", Old: "a.mp4", New: "b.mp4", Err: errorSharingViolation}))), ErrInUse, CategoryInUse},
+		{"other", errors.New("boom"), nil, CategoryOther},
+	}
+	for _, tt := range tests {
+		if tt.is != nil && !errors.Is(tt.err, tt.is) {
+			t.Errorf("%s: errors.Is(%v) = false", tt.name, tt.err)
+		}
+		if got := Category(tt.err); got != tt.want {
+			t.Errorf("%s: Category = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+
+	var se *validator.SpaceError
+	if err := fmt.Errorf("wrapped: %w", &validator.SpaceError{Need: 10, Have: 1}); !errors.As(err, &se) || se.Need != 10 {
+		t.Error("errors.As does not reach SpaceError")
+	}
+	if Category(nil) != "" {
+		t.Error("nil has a category")
+	}
+}
+
+// slowReader hands out data a chunk at a time with a pause, and calls at after each chunk.
+type slowReader struct {
+	data []byte
+	read int
+	at   func(read int)
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if r.read == len(r.data) {
+		return 0, io.EOF
+	}
+	time.Sleep(time.Millisecond)
+	n := copy(p[:min(len(p), copyChunk/4)], r.data[r.read:])
+	r.read += n
+	r.at(r.read)
+	return n, nil
+}
+
+func TestCopyCancelled(t *testing.T) {
+	data := make([]byte, 20*copyChunk)
+	tests := []struct {
+		name     string
+		cancelAt int
+		finished bool
+	}{
+		{"early cancel rolls back", 3 * copyChunk, false},
+		{"cancel near the end finishes", 19*copyChunk + 1, true},
+	}
+	for _, tt := range tests {
+		dst := filepath.Join(t.TempDir(), "video.mp4")
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &slowReader{data: data, at: func(read int) {
+			if read >= tt.cancelAt {
+				cancel()
+			}
+		}}
+		err := copyTo(ctx, fsys.OS, dst, r, int64(len(data)), nil)
+		st, statErr := os.Stat(dst)
+		if tt.finished {
+			if err != nil || statErr != nil || st.Size() != int64(len(data)) {
+				t.Errorf("%s: err %v, copy %v", tt.name, err, statErr)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrRolledBack) || !errors.Is(err, context.Canceled) || !os.IsNotExist(statErr) {
+			t.Errorf("%s: err %v, partial copy %v", tt.name, err, statErr)
+		}
+		// The cancel is noticed within a chunk, not at the end of the file.
+		if r.read > tt.cancelAt+copyChunk {
+			t.Errorf("%s: read %d bytes after cancelling at %d", tt.name, r.read, tt.cancelAt)
+		}
+	}
+}
+
+// stalledReader hands out its data and then blocks until released, like a file on a share that
+// stopped answering.
+type stalledReader struct {
+	data    []byte
+	read    int
+	release chan struct{}
+	stalled func()
+}
+
+func (r *stalledReader) Read(p []byte) (int, error) {
+	if r.read == len(r.data) {
+		r.stalled()
+		<-r.release
+		return 0, io.EOF
+	}
+	n := copy(p[:min(len(p), copyChunk/4)], r.data[r.read:])
+	r.read += n
+	return n, nil
+}
+
+func TestCopyTimeout(t *testing.T) {
+	const size = 20 * copyChunk
+	tests := []struct {
+		name   string
+		served int
+		cancel bool
+	}{
+		{"stall halfway", size / 2, false},
+		// A cancel near the end would finish the copy, but a timeout still ends it.
+		{"stall after a cancel near the end", size - copyChunk/4, true},
+	}
+	for _, tt := range tests {
+		dst := filepath.Join(t.TempDir(), "video.mp4")
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, stop := context.WithTimeout(parent, 100*time.Millisecond)
+		r := &stalledReader{data: make([]byte, tt.served), release: make(chan struct{}), stalled: func() {
+			if tt.cancel {
+				cancel()
+			}
+		}}
+		start := time.Now()
+		err := copyTo(ctx, fsys.OS, dst, r, size, nil)
+		if !errors.Is(err, ErrRolledBack) || !errors.Is(err, context.DeadlineExceeded) || Category(err) != CategoryTimeout {
+			t.Errorf("%s: err %v", tt.name, err)
+		}
+		if _, statErr := os.Stat(dst); !os.IsNotExist(statErr) {
+			t.Errorf("%s: partial copy left: %v", tt.name, statErr)
+		}
+		if d := time.Since(start); d > 5*time.Second {
+			t.Errorf("%s: copy returned after %s", tt.name, d)
+		}
+		close(r.release)
+		stop()
+		cancel()
+	}
+}
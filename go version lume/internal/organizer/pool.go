@@ -0,0 +1,96 @@
+package organizer
+
+import (
+	"io/fs"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OtherDevices is the folder a month's small devices share.
+const OtherDevices = "Other"
+
+// PoolSmallDevices sets Pooled on the files of devices with fewer than Layout.MinDeviceFiles files in
+// their folder's month (more exactly, under the same folders above {device}) and clears it on the
+// others. A device that already has its own folder there keeps it, and one already in the Other folder
+// stays in it, so later runs never split a device across both. place returns the target base and layout
+// of a file; only templates with {device} are pooled.
+func PoolSmallDevices(files []metadata.FileInfo, place func(metadata.FileInfo) (string, Layout)) {
+	type group struct {
+		own, other string
+		layout     Layout
+		files      []int
+	}
+	groups := make(map[string]*group)
+	for i := range files {
+		files[i].Pooled = false
+		base, layout := place(files[i])
+		if layout.MinDeviceFiles <= 0 {
+			continue
+		}
+		own, ok := deviceDir(files[i], base, layout)
+		if !ok {
+			continue
+		}
+		g := groups[own]
+		if g == nil {
+			pooled := files[i]
+			pooled.Pooled = true
+			other, _ := deviceDir(pooled, base, layout)
+			g = &group{own: own, other: other, layout: layout}
+			groups[own] = g
+		}
+		g.files = append(g.files, i)
+	}
+	pooledDevices := make(map[string]map[string]bool) // Other folder -> device folders found in it
+	for _, g := range groups {
+		pool := len(g.files) < g.layout.MinDeviceFiles
+		if isDir(g.own) {
+			pool = false
+		} else if !pool {
+			found, ok := pooledDevices[g.other]
+			if !ok {
+				found = devicesIn(g.other, g.layout.Devices)
+				pooledDevices[g.other] = found
+			}
+			pool = found[DeviceFolder(files[g.files[0]])]
+		}
+		for _, i := range g.files {
+			files[i].Pooled = pool
+		}
+	}
+}
+
+// deviceDir renders the folders down to the {device} segment.
+func deviceDir(info metadata.FileInfo, base string, layout Layout) (string, bool) {
+	segs := strings.Split(filepath.ToSlash(layout.template()), "/")
+	for i, seg := range segs {
+		if strings.Contains(seg, "{device}") {
+			layout.Template = strings.Join(segs[:i+1], "/")
+			return TargetDir(info, base, layout), true
+		}
+	}
+	return "", false
+}
+
+// devicesIn lists the device folders the files under an Other folder would have of their own.
+func devicesIn(dir string, names *metadata.DeviceNames) map[string]bool {
+	found := make(map[string]bool)
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || metadata.OwnFile(d.Name()) {
+			return nil
+		}
+		if info, err := metadata.GetFileInfo(path); err == nil {
+			info.Device = names.Normalize(info.Device)
+			found[DeviceFolder(info)] = true
+		}
+		return nil
+	})
+	return found
+}
+
+func isDir(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && st.IsDir()
+}
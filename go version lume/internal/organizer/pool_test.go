@@ -0,0 +1,54 @@
+package organizer
+
+import (
+	"fmt"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPoolSmallDevices(t *testing.T) {
+	root := t.TempDir()
+	layout := Layout{Template: "{year}/{month}/{device}", MinDeviceFiles: 5}
+	// A device with a folder from an earlier run keeps it; one pooled earlier stays pooled.
+	os.MkdirAll(filepath.Join(root, "2024", "05", "Camera_Kept"), 0755)
+	other := filepath.Join(root, "2024", "05", OtherDevices)
+	os.MkdirAll(other, 0755)
+	os.WriteFile(filepath.Join(other, "IMG-20240501-WA0001.jpg"), []byte("x"), 0644)
+
+	var files []metadata.FileInfo
+	add := func(source, device, month string, n int) {
+		for i := 0; i < n; i++ {
+			files = append(files, metadata.FileInfo{Path: fmt.Sprintf("%s_%s_%s_%d.jpg", source, device, month, i), Source: source, Device: device, Year: "2024", Month: month})
+		}
+	}
+	add("Camera", "Big", "05", 5)
+	add("Camera", "Small", "05", 4)
+	add("Camera", "Small", "06", 1)
+	add("Camera", "Kept", "05", 1)
+	add("WhatsApp", "Unknown", "05", 7)
+	add("WhatsApp", "Unknown", "06", 7)
+
+	PoolSmallDevices(files, func(metadata.FileInfo) (string, Layout) { return root, layout })
+	got := make(map[string]int)
+	for _, f := range files {
+		dir, _ := filepath.Rel(root, TargetDir(f, root, layout))
+		got[filepath.ToSlash(dir)]++
+	}
+	want := map[string]int{"2024/05/Camera_Big": 5, "2024/05/Other": 4 + 7, "2024/06/Other": 1, "2024/05/Camera_Kept": 1, "2024/06/WhatsApp": 7}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("folders = %v; want %v", got, want)
+	}
+
+	// Without {device} in the template, or with pooling off, nothing is pooled.
+	for _, l := range []Layout{{Template: "{year}/{month}", MinDeviceFiles: 5}, {Template: layout.Template}} {
+		PoolSmallDevices(files, func(metadata.FileInfo) (string, Layout) { return root, l })
+		for _, f := range files {
+			if f.Pooled {
+				t.Errorf("%+v: %s pooled", l, f.Path)
+				break
+			}
+		}
+	}
+}
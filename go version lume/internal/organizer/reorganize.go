@@ -0,0 +1,262 @@
+package organizer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"lume-go/internal/catalog"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// JournalPrefix names the undo journals a reorganization leaves at the archive root.
+const JournalPrefix = ".lume_reorg_"
+
+// ReorgMove is one file a reorganization relocates.
+type ReorgMove struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ReorgSkip is a file left where it is, with the reason.
+type ReorgSkip struct {
+	Path   string
+	Reason string
+}
+
+// ReorgPlan previews a reorganization. Nothing on disk changes until it is applied.
+type ReorgPlan struct {
+	Root      string
+	Layout    Layout
+	Moves     []ReorgMove
+	Skipped   []ReorgSkip
+	Unchanged int
+}
+
+// ReorgResult reports an applied plan.
+type ReorgResult struct {
+	Journal string
+	Moved   int
+	Failed  []ReorgSkip
+	Pruned  []string
+}
+
+// PlanReorganize re-reads the metadata of every file under root and works out where the template
+// puts it now. Files already in place are counted, files whose metadata can no longer be recovered
+// for the template (e.g. no EXIF date once copied around) are skipped with a reason. Protected
+// folders are left as they are: nothing moves out of them or into them, and a protected root fails
+// with a validator.ProtectedError.
+func PlanReorganize(ctx context.Context, root string, layout Layout, protect *validator.Protection) (ReorgPlan, error) {
+	template := layout.template()
+	plan := ReorgPlan{Root: root, Layout: layout}
+	claimed := make(map[string]bool)
+	if err := protect.Check(root); err != nil {
+		return plan, err
+	}
+
+	var infos []metadata.FileInfo
+	err := filepath.Walk(root, func(path string, fi os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err == nil && fi.IsDir() && protect.Check(path) != nil {
+			plan.Skipped = append(plan.Skipped, ReorgSkip{path, "protected"})
+			return filepath.SkipDir
+		}
+		if err != nil || fi.IsDir() || fi.Mode()&os.ModeSymlink != 0 || metadata.OwnFile(fi.Name()) {
+			return nil
+		}
+		if !metadata.SupportedExtensions[strings.ToLower(filepath.Ext(path))] {
+			return nil
+		}
+		info, err := metadata.GetFileInfo(path)
+		if err != nil {
+			plan.Skipped = append(plan.Skipped, ReorgSkip{path, err.Error()})
+			return nil
+		}
+		info.Device = layout.Devices.Normalize(info.Device)
+		if reason := unrecoverable(info, template); reason != "" {
+			plan.Skipped = append(plan.Skipped, ReorgSkip{path, reason})
+			return nil
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return plan, err
+	}
+	// Bursts need every file of the archive at once; the names only depend on the shots themselves.
+	if templateUses(template, "{burst}") {
+		MarkBursts(infos, layout.Burst)
+	}
+
+	for _, info := range infos {
+		path := info.Path
+		dir := TargetDir(info, root, layout)
+		if strings.EqualFold(filepath.Clean(dir), filepath.Dir(path)) {
+			plan.Unchanged++
+			continue
+		}
+		if err := protect.Check(dir); err != nil {
+			plan.Skipped = append(plan.Skipped, ReorgSkip{path, err.Error()})
+			continue
+		}
+		dst := filepath.Join(dir, info.Filename)
+		// A copy already at the new place (e.g. 01/ and 01-Ocak/ both exist) is reported, not duplicated.
+		if exists(dst) {
+			if isDup, err := IsDuplicate(path, dst); err == nil && isDup {
+				plan.Skipped = append(plan.Skipped, ReorgSkip{path, "duplicate of " + dst})
+				continue
+			}
+		}
+		for i := 1; claimed[strings.ToLower(dst)] || exists(dst); i++ {
+			ext := filepath.Ext(info.Filename)
+			dst = filepath.Join(dir, fmt.Sprintf("%s_%d%s", strings.TrimSuffix(info.Filename, ext), i, ext))
+		}
+		claimed[strings.ToLower(dst)] = true
+		plan.Moves = append(plan.Moves, ReorgMove{From: path, To: dst})
+	}
+	return plan, nil
+}
+
+// unrecoverable names the template field that cannot be filled from the file's own metadata.
+func unrecoverable(info metadata.FileInfo, template string) string {
+	if templateUses(template, dateTokens...) && info.DateSource != metadata.DateFromExif && info.DateSource != metadata.DateFromVideo {
+		return "no EXIF date"
+	}
+	if templateUses(template, "{model}") && (info.Device == "" || info.Device == "Unknown") {
+		return "no camera model"
+	}
+	if templateUses(template, "{device}") && DeviceFolder(info) == "Other_Sorted" {
+		return "no device or source"
+	}
+	return ""
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// ApplyReorganize performs the planned moves as renames inside the archive, journaling each one
+// before going on so UndoReorganize can put everything back. Folders emptied by the moves are pruned
+// and catalog records follow their files. Cancelling ctx stops after the current file; the journal
+// still covers every completed move.
+func ApplyReorganize(ctx context.Context, plan ReorgPlan, progress func(done, total int)) (ReorgResult, error) {
+	res := ReorgResult{Journal: filepath.Join(plan.Root, JournalPrefix+time.Now().Format("20060102-150405")+".jsonl")}
+	journal, err := os.OpenFile(res.Journal, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return res, fmt.Errorf("reorg journal: %w", err)
+	}
+	defer journal.Close()
+
+	var moved []string
+	remap := make(map[string]string)
+	for i, m := range plan.Moves {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if progress != nil {
+			progress(i, len(plan.Moves))
+		}
+		to := m.To
+		if exists(to) {
+			free, err := ResolveConflict(to)
+			if err != nil {
+				res.Failed = append(res.Failed, ReorgSkip{m.From, err.Error()})
+				continue
+			}
+			to = free
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			res.Failed = append(res.Failed, ReorgSkip{m.From, err.Error()})
+			continue
+		}
+		if err := os.Rename(m.From, to); err != nil {
+			res.Failed = append(res.Failed, ReorgSkip{m.From, err.Error()})
+			continue
+		}
+		line, _ := json.Marshal(ReorgMove{From: m.From, To: to})
+		if _, err := journal.Write(append(line, '\n')); err != nil {
+			// Without a journal entry the move could not be undone, so take it back and stop here.
+			os.Rename(to, m.From)
+			return res, fmt.Errorf("reorg journal write: %w", err)
+		}
+		moved = append(moved, m.From)
+		remap[filepath.Clean(m.From)] = to
+		res.Moved++
+	}
+	if progress != nil {
+		progress(res.Moved+len(res.Failed), len(plan.Moves))
+	}
+
+	res.Pruned, _ = PruneEmptyDirs(plan.Root, moved, false)
+	if err := catalog.Remap(plan.Root, remap); err != nil {
+		logger.Error("Catalog not updated after reorganize: %v", err)
+	}
+	logger.Info("Reorganized %s: %d moved, %d failed, journal %s", plan.Root, res.Moved, len(res.Failed), res.Journal)
+	return res, err
+}
+
+// UndoReorganize replays a journal backwards, moving every file back where it was. A file whose
+// old place is taken again is left at its new location and reported. The journal is renamed to
+// *.undone afterwards so it cannot be replayed twice.
+func UndoReorganize(journalPath string) (int, error) {
+	f, err := os.Open(journalPath)
+	if err != nil {
+		return 0, err
+	}
+	var moves []ReorgMove
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var m ReorgMove
+		if err := json.Unmarshal(sc.Bytes(), &m); err == nil && m.From != "" && m.To != "" {
+			moves = append(moves, m)
+		}
+	}
+	f.Close()
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+
+	restored, failed := 0, 0
+	var emptied []string
+	remap := make(map[string]string)
+	for i := len(moves) - 1; i >= 0; i-- {
+		m := moves[i]
+		if exists(m.From) {
+			logger.Error("Undo skipped %s: %v", m.To, ErrDestinationExists)
+			failed++
+			continue
+		}
+		err := os.MkdirAll(filepath.Dir(m.From), 0755)
+		if err == nil {
+			err = os.Rename(m.To, m.From)
+		}
+		if err != nil {
+			logger.Error("Undo failed for %s: %v", m.To, err)
+			failed++
+			continue
+		}
+		emptied = append(emptied, m.To)
+		remap[filepath.Clean(m.To)] = m.From
+		restored++
+	}
+	PruneEmptyDirs(filepath.Dir(journalPath), emptied, false)
+	if err := catalog.Remap(filepath.Dir(journalPath), remap); err != nil {
+		logger.Error("Catalog not updated after undo: %v", err)
+	}
+	if err := os.Rename(journalPath, journalPath+".undone"); err != nil {
+		logger.Error("Could not retire journal %s: %v", journalPath, err)
+	}
+	if failed > 0 {
+		return restored, fmt.Errorf("%d files could not be moved back", failed)
+	}
+	return restored, nil
+}
@@ -0,0 +1,65 @@
+package organizer
+
+import (
+	"lume-go/internal/metadata"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileNumber is the counter a camera puts at the end of a file name, 42 for IMG_0042.JPG or
+// DSC00042.ARW. Names without digits before the extension have none.
+func FileNumber(name string) (int, bool) {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	end := len(base)
+	start := end
+	for start > 0 && base[start-1] >= '0' && base[start-1] <= '9' {
+		start--
+	}
+	if start == end {
+		return 0, false
+	}
+	n, err := strconv.Atoi(base[max(start, end-9):end])
+	return n, err == nil
+}
+
+// shotBefore orders photos as they were shot: by date, which includes the EXIF subseconds, then by the
+// camera's file counter for shots the date cannot tell apart, then by path so the order never depends
+// on the order files were listed in.
+func shotBefore(a, b metadata.FileInfo) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.Before(b.Date)
+	}
+	na, oka := FileNumber(a.Filename)
+	nb, okb := FileNumber(b.Filename)
+	if oka != okb {
+		return oka
+	}
+	if na != nb {
+		return na < nb
+	}
+	return a.Path < b.Path
+}
+
+// MarkSequences numbers the photos each device shot within the same second, from 1 in shooting order,
+// for the {seq} token. A photo alone in its second is 1. Like MarkBursts, the result does not depend
+// on the order of files.
+func MarkSequences(files []metadata.FileInfo) {
+	type second struct {
+		device string
+		at     time.Time
+	}
+	groups := make(map[second][]int)
+	for i := range files {
+		k := second{files[i].Device, files[i].Date.Truncate(time.Second)}
+		groups[k] = append(groups[k], i)
+	}
+	for _, idx := range groups {
+		sort.Slice(idx, func(a, b int) bool { return shotBefore(files[idx[a]], files[idx[b]]) })
+		for n, i := range idx {
+			files[i].Seq = n + 1
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package organizer
+
+import (
+	"fmt"
+	"lume-go/internal/metadata"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+		ok   bool
+	}{
+		{"IMG_0042.JPG", 42, true},
+		{"DSC00042.ARW", 42, true},
+		{"PXL_20240512_143022123.jpg", 143022123, true},
+		{"photo.jpg", 0, false},
+		{"IMG_0042 copy.jpg", 0, false},
+		{"12345678901234.jpg", 678901234, true},
+	}
+	for _, tt := range tests {
+		if got, ok := FileNumber(tt.name); got != tt.want || ok != tt.ok {
+			t.Errorf("FileNumber(%q) = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Ten shots of one second, without subseconds, are named in the camera's counter order whatever
+// order they arrive in, and none of them needs a conflict suffix.
+func TestSameSecondNames(t *testing.T) {
+	at := time.Date(2024, 5, 12, 14, 30, 22, 0, time.UTC)
+	layout := Layout{Template: "{year}", NameTemplate: "{year}{month}{day}_{time}_{seq}"}
+	for run, seed := range []int64{1, 2, 3} {
+		root, src := t.TempDir(), t.TempDir()
+		var files []metadata.FileInfo
+		for n := 98; n < 108; n++ {
+			name := fmt.Sprintf("IMG_%04d.JPG", n)
+			path := filepath.Join(src, name)
+			if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, metadata.FileInfo{Path: path, Filename: name, Size: int64(len(name)), Date: at, DateSource: metadata.DateFromExif,
+				Year: "2024", Month: "05", Day: "12", Device: "X100"})
+		}
+		rand.New(rand.NewSource(seed)).Shuffle(len(files), func(i, j int) { files[i], files[j] = files[j], files[i] })
+		MarkSequences(files)
+		for _, f := range files {
+			got, err := MoveFile(f, root, Options{Layout: layout})
+			if err != nil {
+				t.Fatalf("run %d: MoveFile(%s): %v", run, f.Filename, err)
+			}
+			n, _ := FileNumber(f.Filename)
+			if want := fmt.Sprintf("20240512_143022_%03d.JPG", n-97); filepath.Base(got) != want {
+				t.Errorf("run %d: %s archived as %s; want %s", run, f.Filename, filepath.Base(got), want)
+			}
+		}
+		if entries, _ := os.ReadDir(filepath.Join(root, "2024")); len(entries) != 10 {
+			t.Errorf("run %d: %d files archived; want 10", run, len(entries))
+		}
+	}
+}
+
+// Subseconds order the shots before the file counter does, and {subsec} shows them.
+func TestSubsecOrdersBeforeCounter(t *testing.T) {
+	at := time.Date(2024, 5, 12, 14, 30, 22, 0, time.UTC)
+	files := []metadata.FileInfo{
+		{Path: "a/IMG_0001.JPG", Filename: "IMG_0001.JPG", Date: at.Add(600 * time.Millisecond), Device: "X100"},
+		{Path: "a/IMG_0002.JPG", Filename: "IMG_0002.JPG", Date: at.Add(100 * time.Millisecond), Device: "X100"},
+		{Path: "a/IMG_0003.JPG", Filename: "IMG_0003.JPG", Date: at.Add(time.Second), Device: "X100"},
+		{Path: "b/IMG_0004.JPG", Filename: "IMG_0004.JPG", Date: at, Device: "Pixel 8"},
+	}
+	MarkSequences(files)
+	layout := Layout{NameTemplate: "{time}_{subsec}_{seq}"}
+	want := []string{"143022_600_002.JPG", "143022_100_001.JPG", "143023_000_001.JPG", "143022_000_001.JPG"}
+	for i, f := range files {
+		if got := FileName(f, layout); got != want[i] {
+			t.Errorf("FileName(%s) = %s; want %s", f.Filename, got, want[i])
+		}
+	}
+	if got := FileName(files[0], Layout{}); got != "IMG_0001.JPG" {
+		t.Errorf("FileName without a template = %s; want the original name", got)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package organizer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// errorSharingViolation is what stands for a file held by another process where files are not
+// locked on open.
+const errorSharingViolation = syscall.EBUSY
+
+func isSharingViolation(err error) bool {
+	return errors.Is(err, errorSharingViolation)
+}
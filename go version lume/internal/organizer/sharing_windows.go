@@ -0,0 +1,16 @@
+package organizer
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Win32 codes for a file another process opened without sharing it, or locked part of.
+const (
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+func isSharingViolation(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
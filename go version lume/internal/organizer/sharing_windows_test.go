@@ -0,0 +1,34 @@
+package organizer
+
+import (
+	"errors"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestMoveFileHeldSource(t *testing.T) {
+	root, card := t.TempDir(), t.TempDir()
+	src := filepath.Join(card, "MVI_0001.MP4")
+	content := faultContent(1, 5000)
+	os.WriteFile(src, content, 0644)
+	info := metadata.FileInfo{Path: src, Filename: "MVI_0001.MP4", Size: int64(len(content)), Year: "2024", Month: "05", Source: "Camera"}
+
+	h := holdExclusive(t, src)
+	_, err := MoveFile(info, root, Options{HardDelete: true})
+	syscall.CloseHandle(h)
+	if !errors.Is(err, ErrInUse) || Category(err) != CategoryInUse {
+		t.Fatalf("MoveFile of a held file = %v; want ErrInUse", err)
+	}
+	if _, err := os.Stat(src); err != nil {
+		t.Errorf("held source gone: %v", err)
+	}
+	checkArchive(t, root, map[string][]byte{src: content}, true)
+
+	// Closed again, it moves.
+	if got, err := MoveFile(info, root, Options{HardDelete: true}); err != nil {
+		t.Errorf("MoveFile once released = %q, %v", got, err)
+	}
+}
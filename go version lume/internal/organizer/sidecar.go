@@ -0,0 +1,49 @@
+package organizer
+
+import (
+	"context"
+	"lume-go/internal/fsys"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"path/filepath"
+	"strings"
+)
+
+// SidecarPath is where a sidecar of the video archived at videoPath goes: beside it, named after it
+// with the sidecar's own suffix, so C0001.MP4 archived as C0001_1.MP4 takes C0001M01.XML along as
+// C0001_1M01.XML and the camera software still pairs them.
+func SidecarPath(sidecar, videoPath string) string {
+	suffix, _, ok := metadata.SidecarSuffix(sidecar)
+	if !ok {
+		suffix = filepath.Ext(sidecar)
+	}
+	return strings.TrimSuffix(videoPath, filepath.Ext(videoPath)) + suffix
+}
+
+// MoveSidecars moves the sidecars of a video to where its archived copy at videoPath is (see
+// SidecarPath) and returns the paths they were moved to. A sidecar whose place is taken, or that fails
+// to move, is logged and left where it is; the video stays archived either way.
+func MoveSidecars(ctx context.Context, sidecars []string, videoPath string, opts Options) []string {
+	fs := fsys.Or(opts.FS)
+	var moved []string
+	for _, sc := range sidecars {
+		dst := SidecarPath(sc, videoPath)
+		if fsys.Exists(fs, dst) {
+			logger.Info("Sidecar %s left in place: %s is taken", filepath.Base(sc), dst)
+			continue
+		}
+		hash := func() (string, error) { return hashFile(ctx, fs, sc) }
+		if _, err := atomicMove(ctx, fs, sc, dst, hash, opts.HardDelete, opts.Limit); err != nil {
+			logger.Error("Sidecar %s not moved: %v", filepath.Base(sc), err)
+			continue
+		}
+		if opts.Sums != nil {
+			if err := opts.Sums.Add(dst); err != nil {
+				logger.Error("Checksum not listed: %v", err)
+			}
+		}
+		logger.Info("Sidecar moved with its video: %s -> %s", filepath.Base(sc), dst)
+		moved = append(moved, dst)
+	}
+	return moved
+}
@@ -0,0 +1,41 @@
+package organizer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// StructureTemplate is the template of a route whose files keep the folders of their source rather
+// than being laid out by date (see Structure); files the source structure does not apply to take
+// the default layout.
+const StructureTemplate = "{structure}"
+
+// SourceFolders returns the folders path lies in below the deepest of roots holding it, "." for a
+// file right in its root. ok is false for a path under none of them, such as a file dropped on its
+// own.
+func SourceFolders(path string, roots []string) (rel string, ok bool) {
+	dir, best := filepath.Dir(filepath.Clean(path)), -1
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		r, err := filepath.Rel(root, dir)
+		if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > best {
+			rel, best = r, len(root)
+		}
+	}
+	return rel, best >= 0
+}
+
+// Structure returns layout mirroring the folders path lies in below its root among roots, so the
+// file keeps its place in the source under the target, and its original name. A path under none
+// of the roots keeps layout, and ok is false.
+func Structure(path string, roots []string, layout Layout) (l Layout, ok bool) {
+	rel, ok := SourceFolders(path, roots)
+	if !ok {
+		return layout, false
+	}
+	layout.Folders, layout.NameTemplate = filepath.ToSlash(rel), ""
+	return layout, true
+}
@@ -0,0 +1,48 @@
+package organizer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"lume-go/internal/metadata"
+)
+
+func TestStructure(t *testing.T) {
+	root := filepath.FromSlash("/card/DCIM")
+	roots := []string{filepath.FromSlash("/card"), root}
+	for _, tt := range []struct {
+		path, rel string
+		ok        bool
+	}{
+		{"/card/DCIM/100CANON/IMG_1.JPG", "100CANON", true},
+		{"/card/DCIM/IMG_2.JPG", ".", true},
+		{"/card/MISC/IMG_3.JPG", "MISC", true},
+		{"/cardigan/IMG_4.JPG", "", false},
+		{"/desk/IMG_5.JPG", "", false},
+	} {
+		rel, ok := SourceFolders(filepath.FromSlash(tt.path), roots)
+		if rel != filepath.FromSlash(tt.rel) || ok != tt.ok {
+			t.Errorf("SourceFolders(%s) = %q, %v; want %q, %v", tt.path, rel, ok, tt.rel, tt.ok)
+		}
+	}
+
+	info := metadata.FileInfo{Path: filepath.FromSlash("/card/DCIM/100CANON/IMG_1.JPG"), Filename: "IMG_1.JPG", Year: "2023", Month: "05", Device: "Canon"}
+	layout := Layout{Template: "{year}/{month}", NameTemplate: "{year}{month}_{name}"}
+	l, ok := Structure(info.Path, roots, layout)
+	if !ok {
+		t.Fatal("Structure found no root")
+	}
+	if dir := TargetDir(info, "/archive", l); dir != filepath.FromSlash("/archive/100CANON") {
+		t.Errorf("TargetDir = %s; want /archive/100CANON", dir)
+	}
+	if name := FileName(info, l); name != "IMG_1.JPG" {
+		t.Errorf("FileName = %s; want the original name", name)
+	}
+	info.Path = filepath.FromSlash("/card/DCIM/IMG_1.JPG")
+	if l, _ := Structure(info.Path, roots, layout); TargetDir(info, "/archive", l) != filepath.FromSlash("/archive") {
+		t.Errorf("a file right in its root goes to %s; want the target itself", TargetDir(info, "/archive", l))
+	}
+	if l, ok := Structure(filepath.FromSlash("/desk/IMG_1.JPG"), roots, layout); ok || l.Folders != "" || l.NameTemplate != layout.NameTemplate {
+		t.Errorf("a file under no root gets %+v, %v; want the layout", l, ok)
+	}
+}
@@ -0,0 +1,32 @@
+package organizer
+
+import (
+	"context"
+	"lume-go/internal/fsys"
+	"lume-go/internal/logger"
+	"lume-go/internal/ratelimit"
+	"path/filepath"
+	"time"
+)
+
+// verifyBackoff are the waits between attempts to read back a fresh copy that another program holds
+// open, as an antivirus does to scan a file just written; replaced in tests.
+var verifyBackoff = []time.Duration{100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2 * time.Second, 4 * time.Second}
+
+// verifyHash hashes the file at path to verify it, trying again after each of verifyBackoff while a
+// sharing violation keeps it from being read. Any other error, or the last violation, is returned.
+// lim paces the read like the copy it checks; nil reads at full speed.
+func verifyHash(ctx context.Context, fs fsys.FS, path string, lim *ratelimit.Limiter) (string, error) {
+	for i := 0; ; i++ {
+		h, err := hashPaced(ctx, fs, path, lim)
+		if err == nil || !isSharingViolation(err) || i == len(verifyBackoff) {
+			return h, err
+		}
+		logger.Info("%s is held by another program, verifying again in %s", filepath.Base(path), verifyBackoff[i])
+		select {
+		case <-time.After(verifyBackoff[i]):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
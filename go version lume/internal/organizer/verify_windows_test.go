@@ -0,0 +1,52 @@
+package organizer
+
+import (
+	"context"
+	"lume-go/internal/fsys"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// holdExclusive opens path without sharing it, as an antivirus scanning a new file does.
+func holdExclusive(t *testing.T, path string) syscall.Handle {
+	t.Helper()
+	name, _ := syscall.UTF16PtrFromString(path)
+	h, err := syscall.CreateFile(name, syscall.GENERIC_READ, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func TestVerifyHashHeldFile(t *testing.T) {
+	defer func(b []time.Duration) { verifyBackoff = b }(verifyBackoff)
+	verifyBackoff = []time.Duration{50 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}
+	path := filepath.Join(t.TempDir(), "IMG_0001.JPG")
+	os.WriteFile(path, faultContent(1, 5000), 0644)
+	want, err := hashFile(context.Background(), fsys.OS, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Released while verifyHash waits: the retry reads it.
+	h := holdExclusive(t, path)
+	time.AfterFunc(120*time.Millisecond, func() { syscall.CloseHandle(h) })
+	if got, err := verifyHash(context.Background(), fsys.OS, path, nil); err != nil || got != want {
+		t.Errorf("verifyHash of a file released in time = %q, %v; want %q", got, err, want)
+	}
+
+	// Held past every retry: a sharing violation, and the file is left alone.
+	verifyBackoff = []time.Duration{time.Millisecond, time.Millisecond}
+	h = holdExclusive(t, path)
+	_, err = verifyHash(context.Background(), fsys.OS, path, nil)
+	syscall.CloseHandle(h)
+	if !isSharingViolation(err) {
+		t.Errorf("verifyHash of a held file = %v; want a sharing violation", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("held file gone: %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package plan works out what a run is about to do before it starts: how much it moves, how much of
+// that crosses to another volume and is copied rather than renamed, how many files the target holds
+// already, and roughly how long it takes going by earlier runs.
+package plan
+
+import (
+	"errors"
+	"fmt"
+	"lume-go/internal/history"
+	"lume-go/internal/i18n"
+	"lume-go/internal/validator"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File is one file of a run: where it is, its size and the target it is routed to. Dir is the folder
+// of the target it goes into, when known; the protection check falls back to the target. Suspect
+// marks a file whose date metadata.MarkDateSuspects doubts, Structure one that keeps the folders of
+// its source rather than being laid out by date (see organizer.Structure).
+type File struct {
+	Path      string
+	Size      int64
+	Target    string
+	Dir       string
+	Suspect   bool
+	Structure bool
+}
+
+// Plan is the outcome of Make.
+type Plan struct {
+	Files int
+	Bytes int64
+	// Renamed files stay on their volume; Copied ones go to another and take the time.
+	Renamed, Copied           int
+	RenamedBytes, CopiedBytes int64
+	// Duplicates counts the files the target already holds by name and size, as far as Options.
+	// Duplicate can tell.
+	Duplicates int
+	// Protected counts the files whose folder is protected, which the run will skip; ProtectedRoot
+	// is the first protected folder they meet.
+	Protected     int
+	ProtectedRoot string
+	// Suspect counts the files whose date is suspect; they are filed by it all the same.
+	Suspect int
+	// Structure counts the files keeping the folders of their source.
+	Structure int
+	// Estimate is the expected duration going by the history; zero when it has no rate to go by.
+	Estimate time.Duration
+}
+
+// Text renders p in lang, e.g. "4,213 files, 18.7 GB · 2.1 GB copied from other drives (310 files) ·
+// about 6 min".
+func (p Plan) Text(lang string) string {
+	count := func(n int) string { return i18n.FormatCount(lang, int64(n)) }
+	parts := []string{fmt.Sprintf(i18n.T(lang, "plan_total"), count(p.Files), i18n.FormatBytes(lang, p.Bytes))}
+	if p.Copied > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_copied"), i18n.FormatBytes(lang, p.CopiedBytes), count(p.Copied)))
+	}
+	if p.Renamed > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_renamed"), count(p.Renamed)))
+	}
+	if p.Duplicates > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_duplicates"), count(p.Duplicates)))
+	}
+	if p.Protected > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_protected"), count(p.Protected), p.ProtectedRoot))
+	}
+	if p.Suspect > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_suspect"), count(p.Suspect)))
+	}
+	if p.Structure > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_structure"), count(p.Structure)))
+	}
+	if p.Estimate > 0 {
+		parts = append(parts, fmt.Sprintf(i18n.T(lang, "plan_estimate"), i18n.FormatDuration(lang, p.Estimate)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Options are what Make looks things up with.
+type Options struct {
+	// Volume names the volume of a path, e.g. validator.VolumeSerial.
+	Volume validator.VolumeFunc
+	// Duplicate reports whether the target of f already holds it; nil finds none.
+	Duplicate func(f File) bool
+	// Rates are the speeds of earlier runs; nil gives no estimate.
+	Rates *history.History
+	// Protect finds the files a run will refuse to write into a protected folder; nil finds none.
+	Protect *validator.Protection
+}
+
+// Make works out the plan of moving files. Volumes are looked up once per folder. The files going
+// into protected folders are counted in Files but in nothing else.
+func Make(files []File, opts Options) Plan {
+	var p Plan
+	vol := newVolumes(opts.Volume)
+	type pair struct{ from, to string }
+	type load struct {
+		files int
+		bytes int64
+	}
+	pairs := make(map[pair]*load)
+	for _, f := range files {
+		p.Files++
+		p.Bytes += f.Size
+		if f.Suspect {
+			p.Suspect++
+		}
+		if f.Structure {
+			p.Structure++
+		}
+		dir := f.Dir
+		if dir == "" {
+			dir = f.Target
+		}
+		var protected *validator.ProtectedError
+		if errors.As(opts.Protect.Check(dir), &protected) {
+			if p.Protected++; p.ProtectedRoot == "" {
+				p.ProtectedRoot = protected.Root
+			}
+			continue
+		}
+		if opts.Duplicate != nil && opts.Duplicate(f) {
+			p.Duplicates++
+		}
+		from, to := vol.of(filepath.Dir(f.Path)), vol.of(f.Target)
+		if from != "" && from == to {
+			p.Renamed++
+			p.RenamedBytes += f.Size
+		} else {
+			p.Copied++
+			p.CopiedBytes += f.Size
+		}
+		k := pair{from, to}
+		if pairs[k] == nil {
+			pairs[k] = &load{}
+		}
+		pairs[k].files++
+		pairs[k].bytes += f.Size
+	}
+	if opts.Rates == nil {
+		return p
+	}
+	var secs float64
+	for k, l := range pairs {
+		r, ok := opts.Rates.Rate(k.from, k.to)
+		if !ok || r.BytesPerSec <= 0 || r.FilesPerSec <= 0 {
+			return p
+		}
+		// A rename takes about as long whatever the size, a copy about as long as its bytes.
+		if k.from != "" && k.from == k.to {
+			secs += float64(l.files) / r.FilesPerSec
+		} else {
+			secs += float64(l.bytes) / r.BytesPerSec
+		}
+	}
+	p.Estimate = time.Duration(secs * float64(time.Second))
+	return p
+}
+
+// Meter adds up what a run moved between each pair of volumes and how long it took, for the rates
+// of the history. The time of a pair is the wall clock from its first move starting to its last
+// ending, so files moved at once are not counted as though they had moved one after another.
+type Meter struct {
+	vol   *volumes
+	pairs map[[2]string]*sample
+}
+
+type sample struct {
+	files       int
+	bytes       int64
+	first, last time.Time
+}
+
+// NewMeter returns a Meter looking volumes up with volume.
+func NewMeter(volume validator.VolumeFunc) *Meter {
+	return &Meter{vol: newVolumes(volume), pairs: make(map[[2]string]*sample)}
+}
+
+// Add counts a file of size bytes moved from path into target, its move started at start and taking
+// elapsed.
+func (m *Meter) Add(path, target string, size int64, start time.Time, elapsed time.Duration) {
+	k := [2]string{m.vol.of(filepath.Dir(path)), m.vol.of(target)}
+	if k[0] == "" || k[1] == "" {
+		return
+	}
+	s := m.pairs[k]
+	if s == nil {
+		s = &sample{first: start}
+		m.pairs[k] = s
+	}
+	s.files++
+	s.bytes += size
+	if start.Before(s.first) {
+		s.first = start
+	}
+	if end := start.Add(elapsed); end.After(s.last) {
+		s.last = end
+	}
+}
+
+// Record folds the pairs into the rates of h.
+func (m *Meter) Record(h *history.History) {
+	for k, s := range m.pairs {
+		h.AddRate(k[0], k[1], s.files, s.bytes, s.last.Sub(s.first))
+	}
+}
+
+// volumes caches the volume of each folder; an unknown volume is "".
+type volumes struct {
+	volume validator.VolumeFunc
+	dirs   map[string]string
+}
+
+func newVolumes(volume validator.VolumeFunc) *volumes {
+	return &volumes{volume: volume, dirs: make(map[string]string)}
+}
+
+func (v *volumes) of(dir string) string {
+	name, ok := v.dirs[dir]
+	if !ok {
+		if v.volume != nil {
+			name, _ = v.volume(dir)
+		}
+		v.dirs[dir] = name
+	}
+	return name
+}
@@ -0,0 +1,99 @@
+package plan
+
+import (
+	"errors"
+	"lume-go/internal/history"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// driveOf names the volume by the first folder, so /c/... and /d/... are two volumes.
+func driveOf(path string) (string, error) {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	if len(parts) < 2 || parts[1] == "x" {
+		return "", errors.New("unknown volume")
+	}
+	return parts[1], nil
+}
+
+func TestMake(t *testing.T) {
+	files := []File{
+		{Path: "/c/card/a.jpg", Size: 100 << 20, Target: "/d/archive"},
+		{Path: "/c/card/b.jpg", Size: 300 << 20, Target: "/d/archive"},
+		{Path: "/d/inbox/c.jpg", Size: 50, Target: "/d/archive"},
+		{Path: "/x/share/d.jpg", Size: 10, Target: "/d/archive"},
+	}
+	dup := func(f File) bool { return filepath.Base(f.Path) == "b.jpg" }
+
+	p := Make(files, Options{Volume: driveOf, Duplicate: dup})
+	want := Plan{Files: 4, Bytes: 400<<20 + 60, Renamed: 1, RenamedBytes: 50, Copied: 3, CopiedBytes: 400<<20 + 10, Duplicates: 1}
+	if p != want {
+		t.Errorf("Make = %+v; want %+v", p, want)
+	}
+
+	h, _ := history.Load(filepath.Join(t.TempDir(), history.FileName))
+	h.AddRate("c", "d", 4, 100<<20, 10*time.Second)
+	if p := Make(files, Options{Volume: driveOf, Rates: h}); p.Estimate != 0 {
+		t.Errorf("estimate %v without a rate for renames", p.Estimate)
+	}
+	h.AddRate("d", "d", 100, 1<<30, 2*time.Second)
+	// 400 MB at 10 MB/s, the unknown share at the same, and one rename at 50 files/s.
+	if p := Make(files, Options{Volume: driveOf, Rates: h}); p.Estimate.Round(time.Millisecond) != 40*time.Second+20*time.Millisecond {
+		t.Errorf("Estimate = %v", p.Estimate)
+	}
+}
+
+func TestMakeProtected(t *testing.T) {
+	archive := t.TempDir()
+	os.MkdirAll(filepath.Join(archive, "2015"), 0755)
+	os.WriteFile(filepath.Join(archive, "2015", validator.ProtectMarker), nil, 0644)
+	files := []File{
+		{Path: "/c/card/a.jpg", Size: 10, Target: archive, Dir: filepath.Join(archive, "2015", "06")},
+		{Path: "/c/card/b.jpg", Size: 20, Target: archive, Dir: filepath.Join(archive, "2024", "05")},
+		{Path: "/c/card/c.jpg", Size: 30, Target: archive},
+	}
+	p := Make(files, Options{Volume: driveOf, Protect: validator.NewProtection(nil)})
+	if p.Files != 3 || p.Protected != 1 || p.ProtectedRoot != validator.Canonical(filepath.Join(archive, "2015")) || p.Copied != 2 {
+		t.Errorf("Make = %+v", p)
+	}
+	// A protected target protects every file routed to it.
+	if p := Make(files, Options{Protect: validator.NewProtection([]string{archive})}); p.Protected != 3 {
+		t.Errorf("protected target: %d of 3 files protected", p.Protected)
+	}
+}
+
+func TestMeter(t *testing.T) {
+	h, _ := history.Load(filepath.Join(t.TempDir(), history.FileName))
+	m := NewMeter(driveOf)
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// Two files moved at once over the first two seconds, a third over the next two: four seconds in all.
+	m.Add("/c/card/a.jpg", "/d/archive", 30<<20, start, 2*time.Second)
+	m.Add("/c/card/b.jpg", "/d/archive", 5<<20, start, 2*time.Second)
+	m.Add("/c/card/c.jpg", "/d/archive", 5<<20, start.Add(2*time.Second), 2*time.Second)
+	m.Add("/x/share/d.jpg", "/d/archive", 1, start, time.Hour) // unknown volume: left out
+	m.Record(h)
+	if r, ok := h.Rate("c", "d"); !ok || r.BytesPerSec != 10<<20 || r.FilesPerSec != 0.75 {
+		t.Errorf("Rate(c, d) = %+v, %v", r, ok)
+	}
+	if len(h.Rates) != 1 {
+		t.Errorf("rates kept: %v", h.Rates)
+	}
+}
+
+func TestText(t *testing.T) {
+	p := Plan{Files: 4213, Bytes: 18 << 30, Copied: 310, CopiedBytes: 2 << 30, Renamed: 3903, Duplicates: 12, Estimate: 6 * time.Minute}
+	want := "4,213 files, 18.0 GB · 2.0 GB copied from other drives (310 files) · 3,903 files renamed in place · about 12 already in the archive · about 6 min"
+	if got := p.Text("en"); got != want {
+		t.Errorf("Text = %q; want %q", got, want)
+	}
+	if got := (Plan{Files: 1, Bytes: 10, Renamed: 1}).Text("en"); got != "1 files, 10 B · 1 files renamed in place" {
+		t.Errorf("Text = %q", got)
+	}
+	if got := (Plan{Files: 2, Bytes: 10, Renamed: 2, Structure: 2}).Text("en"); got != "2 files, 10 B · 2 files renamed in place · 2 keep their source folders" {
+		t.Errorf("Text = %q", got)
+	}
+}
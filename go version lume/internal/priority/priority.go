@@ -0,0 +1,3 @@
+// Package priority lowers the priority of Lume's own disk and network traffic while a run goes on,
+// so other programs, a video call say, keep the upper hand.
+package priority
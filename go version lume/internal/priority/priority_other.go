@@ -0,0 +1,8 @@
+//go:build !windows
+
+package priority
+
+// Background does nothing outside Windows, where the I/O priority is left to the user (ionice).
+func Background(on bool) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+package priority
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// The background processing mode of SetPriorityClass: low I/O and memory priority for the process.
+const (
+	modeBackgroundBegin = 0x00100000
+	modeBackgroundEnd   = 0x00200000
+)
+
+// Background puts the process into background processing mode, or takes it out of it again.
+// Entering it twice, or leaving it when not in it, is an error Windows reports.
+func Background(on bool) error {
+	mode := uintptr(modeBackgroundEnd)
+	if on {
+		mode = modeBackgroundBegin
+	}
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	self, _, _ := kernel32.NewProc("GetCurrentProcess").Call()
+	if ret, _, err := kernel32.NewProc("SetPriorityClass").Call(self, mode); ret == 0 {
+		return fmt.Errorf("background mode %v: %v", on, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// Package queue tracks which files are already waiting to be organized.
+package queue
+
+import (
+	"lume-go/internal/metadata"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Set holds the queued files by normalized path and by file identity, so a file dropped twice,
+// directly, through its folder or through another path to the same data (hardlink, subst drive,
+// mapped letter), is only queued once. The zero value is not usable; call New.
+type Set struct {
+	paths map[string]string // path key -> identity key
+	ids   map[string]string // identity key -> path key
+}
+
+func New() *Set { return &Set{paths: make(map[string]string), ids: make(map[string]string)} }
+
+// Key normalizes path for comparison: absolute, cleaned and, on Windows, case-folded.
+func Key(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
+// identityKey is the file identity of path, or its path key when the file cannot be opened.
+func identityKey(path, key string) string {
+	if id, err := metadata.GetFileIdentity(path); err == nil {
+		return "id:" + id.String()
+	}
+	return key
+}
+
+// Add queues path and reports whether it was new.
+func (s *Set) Add(path string) bool {
+	k := Key(path)
+	if _, ok := s.paths[k]; ok {
+		return false
+	}
+	id := identityKey(path, k)
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	s.paths[k], s.ids[id] = id, k
+	return true
+}
+
+// Remove takes path out of the set so it can be queued again. It works for files that are gone.
+func (s *Set) Remove(path string) {
+	k := Key(path)
+	if id, ok := s.paths[k]; ok {
+		delete(s.ids, id)
+		delete(s.paths, k)
+	}
+}
+
+// Has reports whether path, or another path to the same file, is queued.
+func (s *Set) Has(path string) bool {
+	k := Key(path)
+	if _, ok := s.paths[k]; ok {
+		return true
+	}
+	_, ok := s.ids[identityKey(path, k)]
+	return ok
+}
+
+// Len is the number of queued files.
+func (s *Set) Len() int { return len(s.paths) }
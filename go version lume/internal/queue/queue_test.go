@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDedupesDrops(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.jpg")
+	wd, _ := os.Getwd()
+	rel, err := filepath.Rel(wd, a)
+	if err != nil {
+		t.Skip("no relative path to temp dir")
+	}
+
+	s := New()
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{a, true},
+		{a, false}, // same path dropped twice
+		{filepath.Join(dir, ".", "sub", "..", "a.jpg"), false}, // unclean spelling
+		{rel, false}, // relative spelling
+		{filepath.Join(dir, "b.jpg"), true},
+	}
+	for _, tt := range tests {
+		if got := s.Add(tt.path); got != tt.want {
+			t.Errorf("Add(%q) = %v; want %v", tt.path, got, tt.want)
+		}
+	}
+	if s.Len() != 2 {
+		t.Errorf("Len = %d; want 2", s.Len())
+	}
+
+	s.Remove(rel)
+	if s.Has(a) {
+		t.Error("Remove did not drop the path")
+	}
+	if !s.Add(a) {
+		t.Error("removed path could not be queued again")
+	}
+}
+
+func TestSetDedupesHardlinks(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.jpg"), filepath.Join(dir, "b.jpg")
+	os.WriteFile(a, []byte("a"), 0644)
+	if err := os.Link(a, b); err != nil {
+		t.Skipf("hardlinks unavailable: %v", err)
+	}
+	s := New()
+	if !s.Add(a) || s.Add(b) || !s.Has(b) {
+		t.Fatal("second path to the same file was queued")
+	}
+	// Once the file is gone its path can still be taken out.
+	os.Remove(a)
+	os.Remove(b)
+	s.Remove(a)
+	if s.Len() != 0 {
+		t.Errorf("Len = %d after Remove; want 0", s.Len())
+	}
+}
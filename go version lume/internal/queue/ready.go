@@ -0,0 +1,28 @@
+package queue
+
+// Blocker says what keeps a queue from being started.
+type Blocker int
+
+const (
+	Ready Blocker = iota
+	Busy          // a run or another task is going on
+	NoTargetOrFiles
+	NoTarget
+	NoFiles
+)
+
+// CanStart decides whether the Start button is enabled: a target has been chosen (and passed the
+// checks made when choosing it), at least one file is queued and nothing else is running.
+func CanStart(hasTarget bool, files int, busy bool) Blocker {
+	switch {
+	case busy:
+		return Busy
+	case !hasTarget && files == 0:
+		return NoTargetOrFiles
+	case !hasTarget:
+		return NoTarget
+	case files == 0:
+		return NoFiles
+	}
+	return Ready
+}
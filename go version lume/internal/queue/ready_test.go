@@ -0,0 +1,26 @@
+package queue
+
+import "testing"
+
+func TestCanStart(t *testing.T) {
+	tests := []struct {
+		hasTarget bool
+		files     int
+		busy      bool
+		want      Blocker
+	}{
+		{true, 3, false, Ready},
+		{false, 3, false, NoTarget},
+		{true, 0, false, NoFiles},
+		{false, 0, false, NoTargetOrFiles},
+		{true, 3, true, Busy},
+		{false, 0, true, Busy},
+		{true, 0, true, Busy},
+		{false, 3, true, Busy},
+	}
+	for _, tt := range tests {
+		if got := CanStart(tt.hasTarget, tt.files, tt.busy); got != tt.want {
+			t.Errorf("CanStart(%v, %d, %v) = %v; want %v", tt.hasTarget, tt.files, tt.busy, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package ratelimit paces the bytes Lume copies and reads back, so a run onto a NAS during the day leaves room on
+// the link for everything else. One Limiter is shared by every copy of a run, whatever the number of
+// files moving at once.
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// Clock is the time a Limiter paces by; tests use a fake one.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// Limiter is a token bucket of bytes, filled at its rate up to a second's worth. The rate can be
+// changed at any time, also while writers wait for it; zero or less is no limit.
+type Limiter struct {
+	mu     sync.Mutex
+	clock  Clock
+	rate   int64
+	tokens float64
+	last   time.Time
+	// changed is closed, and replaced, when the rate changes, so waiters pace by the new one.
+	changed chan struct{}
+}
+
+// New returns a Limiter of rate bytes per second.
+func New(rate int64) *Limiter {
+	return NewClock(rate, realClock{})
+}
+
+// NewClock is New pacing by clock.
+func NewClock(rate int64, clock Clock) *Limiter {
+	return &Limiter{clock: clock, rate: rate, tokens: float64(max(rate, 0)), last: clock.Now(), changed: make(chan struct{})}
+}
+
+// Rate is the current rate in bytes per second; zero or less is no limit.
+func (l *Limiter) Rate() int64 {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// SetRate changes the rate, waking the writers waiting so they go on at the new one. A nil Limiter
+// stays unlimited.
+func (l *Limiter) SetRate(rate int64) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	l.rate = rate
+	l.tokens = min(l.tokens, float64(max(rate, 0)))
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// refill adds the tokens earned since the last look, up to a second's worth. l.mu is held.
+func (l *Limiter) refill() {
+	now := l.clock.Now()
+	if l.rate > 0 {
+		l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.rate), float64(l.rate))
+	}
+	l.last = now
+}
+
+// Wait blocks until n bytes may pass, or ctx is done. More than the bucket holds is let through a
+// bucket at a time. A nil Limiter never waits.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	for n > 0 {
+		l.mu.Lock()
+		l.refill()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		take := float64(min(int64(n), l.rate))
+		if l.tokens >= take {
+			l.tokens -= take
+			n -= int(take)
+			l.mu.Unlock()
+			continue
+		}
+		wait := time.Duration((take - l.tokens) / float64(l.rate) * float64(time.Second))
+		changed := l.changed
+		l.mu.Unlock()
+		select {
+		case <-l.clock.After(wait):
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Writer returns w with its writes paced by l under ctx; a nil Limiter returns w itself.
+func (l *Limiter) Writer(ctx context.Context, w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &writer{ctx: ctx, l: l, w: w}
+}
+
+type writer struct {
+	ctx context.Context
+	l   *Limiter
+	w   io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	if err := w.l.Wait(w.ctx, len(p)); err != nil {
+		return 0, err
+	}
+	return w.w.Write(p)
+}
+
+// Reader returns r with its reads paced by l under ctx; a nil Limiter returns r itself. The bytes
+// read are paid for after the read, as their number is only known then.
+func (l *Limiter) Reader(ctx context.Context, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, l: l, r: r}
+}
+
+type reader struct {
+	ctx context.Context
+	l   *Limiter
+	r   io.Reader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if werr := r.l.Wait(r.ctx, n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
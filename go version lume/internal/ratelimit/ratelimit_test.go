@@ -0,0 +1,136 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock moves on by itself: a wait is over as soon as it starts, the time waited added to now.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// stoppedClock never lets a wait end.
+type stoppedClock struct{ fakeClock }
+
+func (*stoppedClock) After(time.Duration) <-chan time.Time { return nil }
+
+func TestLimiterPaces(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	start := clock.Now()
+	l := NewClock(1000, clock)
+	var out bytes.Buffer
+	w := l.Writer(context.Background(), &out)
+	write := func(n int) {
+		t.Helper()
+		for i := 0; i < n; i += 100 {
+			if _, err := w.Write(make([]byte, 100)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	elapsed := func() time.Duration { return clock.Now().Sub(start) }
+
+	// The full bucket lets the first second's worth through at once, the rest at the rate.
+	write(1000)
+	if elapsed() != 0 {
+		t.Errorf("first 1000 bytes took %s; want none", elapsed())
+	}
+	write(2000)
+	if d := elapsed(); d < 1990*time.Millisecond || d > 2010*time.Millisecond {
+		t.Errorf("3000 bytes at 1000/s took %s; want 2s", d)
+	}
+
+	// A new rate applies to what follows; more than the bucket holds passes a bucket at a time.
+	l.SetRate(2000)
+	if _, err := w.Write(make([]byte, 3000)); err != nil {
+		t.Fatal(err)
+	}
+	if d := elapsed(); d < 3490*time.Millisecond || d > 3510*time.Millisecond {
+		t.Errorf("3000 more bytes at 2000/s: %s in all; want 3.5s", d)
+	}
+	l.SetRate(0)
+	write(100000)
+	if d := elapsed(); d > 3510*time.Millisecond || out.Len() != 106000 {
+		t.Errorf("unlimited: %s in all and %d bytes written", d, out.Len())
+	}
+}
+
+func TestReaderPaces(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	start := clock.Now()
+	l := NewClock(1000, clock)
+	r := l.Reader(context.Background(), bytes.NewReader(make([]byte, 3000)))
+	n, err := io.Copy(io.Discard, r)
+	if err != nil || n != 3000 {
+		t.Fatalf("copied %d bytes: %v", n, err)
+	}
+	if d := clock.Now().Sub(start); d < 1990*time.Millisecond || d > 2010*time.Millisecond {
+		t.Errorf("reading 3000 bytes at 1000/s took %s; want 2s", d)
+	}
+	var nilLimiter *Limiter
+	if src := bytes.NewReader(nil); nilLimiter.Reader(context.Background(), src) != io.Reader(src) {
+		t.Error("nil Limiter wrapped the reader")
+	}
+}
+
+// The nil Limiter is unlimited whatever is done with it.
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	l.SetRate(1000)
+	if r := l.Rate(); r != 0 {
+		t.Errorf("nil Limiter rate %d after SetRate; want 0", r)
+	}
+	if err := l.Wait(context.Background(), 1<<20); err != nil {
+		t.Errorf("nil Limiter waited: %v", err)
+	}
+}
+
+func TestLimiterWakesOnRateChange(t *testing.T) {
+	clock := &stoppedClock{}
+	l := NewClock(10, clock)
+	l.Wait(context.Background(), 10) // empty the bucket
+	done := make(chan error)
+	go func() { done <- l.Wait(context.Background(), 10) }()
+	select {
+	case err := <-done:
+		t.Fatalf("wait ended before the rate changed: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.SetRate(0)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	l.SetRate(10) // an empty bucket, as it was
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() { done <- l.Wait(ctx, 10) }()
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("cancelled wait = %v; want context.Canceled", err)
+	}
+	var nilLimiter *Limiter
+	if err := nilLimiter.Wait(context.Background(), 1<<30); err != nil || nilLimiter.Rate() != 0 {
+		t.Errorf("nil Limiter: %v, rate %d", err, nilLimiter.Rate())
+	}
+}
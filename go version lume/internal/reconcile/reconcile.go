@@ -0,0 +1,140 @@
+// Package reconcile checks after a run that the archive holds what the run reported: every archived
+// file is at its destination with its size, and a sample of them still has the content verified when
+// it was moved. It catches what happens to a file after its move, such as an antivirus quarantining
+// it, as well as a move that reported the wrong destination.
+package reconcile
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"lume-go/internal/fsys"
+	"lume-go/internal/ioutil"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// DefaultSample is the Sample of Lume's runs.
+const DefaultSample = 100
+
+// Kinds of Problem.
+const (
+	Missing    = "missing"    // nothing is at the destination
+	Size       = "size"       // the destination has another size
+	Content    = "content"    // the sampled hash differs
+	Unreadable = "unreadable" // the destination could not be checked
+)
+
+// Entry is one file a run reports archived.
+type Entry struct {
+	Dest string
+	Size int64
+	// MD5 is the hash the move verified; without one the file is left out of the sample.
+	MD5 string
+}
+
+// Problem is an entry the archive does not match.
+type Problem struct {
+	Entry
+	Kind string
+	// Got is the size found, for Size; Err the failure, for Unreadable.
+	Got int64
+	Err error
+}
+
+// Options tunes Check.
+type Options struct {
+	// Workers bounds the concurrent checks; zero means one per CPU.
+	Workers int
+	// Sample is how many entries with an MD5 are hashed again, spread evenly over them; zero hashes
+	// none, and a Sample beyond their number hashes all of them.
+	Sample int
+	// FS is the file system checked; nil is the real one.
+	FS fsys.FS
+}
+
+// Check checks entries and returns their problems in the order of entries. Cancelling ctx stops it
+// with the problems found so far and ctx's error.
+func Check(ctx context.Context, entries []Entry, opts Options) ([]Problem, error) {
+	fs, hashed := fsys.Or(opts.FS), sample(entries, opts.Sample)
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	found := make([]*Problem, len(entries))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(workers, len(entries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				found[i] = check(ctx, fs, entries[i], hashed[i])
+			}
+		}()
+	}
+feed:
+	for i := range entries {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	var problems []Problem
+	for _, p := range found {
+		if p != nil {
+			problems = append(problems, *p)
+		}
+	}
+	return problems, ctx.Err()
+}
+
+// sample marks n of the entries with an MD5, evenly spread.
+func sample(entries []Entry, n int) map[int]bool {
+	var withHash []int
+	for i, e := range entries {
+		if e.MD5 != "" {
+			withHash = append(withHash, i)
+		}
+	}
+	n = min(n, len(withHash))
+	marked := make(map[int]bool, n)
+	for k := range n {
+		marked[withHash[k*len(withHash)/n]] = true
+	}
+	return marked
+}
+
+// check checks one entry, hashing it when hash is set.
+func check(ctx context.Context, fs fsys.FS, e Entry, hash bool) *Problem {
+	st, err := fs.Stat(e.Dest)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return &Problem{Entry: e, Kind: Missing}
+	case err != nil:
+		return &Problem{Entry: e, Kind: Unreadable, Err: err}
+	case st.Size() != e.Size:
+		return &Problem{Entry: e, Kind: Size, Got: st.Size()}
+	case !hash:
+		return nil
+	}
+	f, err := fs.Open(e.Dest)
+	if err != nil {
+		return &Problem{Entry: e, Kind: Unreadable, Err: err}
+	}
+	defer f.Close()
+	sum, err := ioutil.HashReader(ctx, f, md5.New)
+	switch {
+	case ctx.Err() != nil:
+		return nil
+	case err != nil:
+		return &Problem{Entry: e, Kind: Unreadable, Err: err}
+	case sum != e.MD5:
+		return &Problem{Entry: e, Kind: Content}
+	}
+	return nil
+}
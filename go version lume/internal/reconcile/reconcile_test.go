@@ -0,0 +1,63 @@
+package reconcile
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		os.WriteFile(p, []byte(content), 0644)
+		return p
+	}
+	const helloMD5 = "5d41402abc4b2a76b9719d911017c592"
+	entries := []Entry{
+		{Dest: write("ok.jpg", "hello"), Size: 5, MD5: helloMD5},
+		{Dest: filepath.Join(dir, "quarantined.jpg"), Size: 5, MD5: helloMD5},
+		{Dest: write("short.jpg", "hell"), Size: 5, MD5: helloMD5},
+		{Dest: write("changed.jpg", "jello"), Size: 5, MD5: helloMD5},
+		{Dest: write("unhashed.jpg", "jello"), Size: 5},
+	}
+
+	problems, err := Check(context.Background(), entries, Options{Workers: 2, Sample: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, filepath.Base(p.Dest)+" "+p.Kind)
+	}
+	want := []string{"quarantined.jpg missing", "short.jpg size", "changed.jpg content"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems = %v; want %v", got, want)
+	}
+	if problems[1].Got != 4 {
+		t.Errorf("size found = %d; want 4", problems[1].Got)
+	}
+
+	// Without a sample only sizes are compared.
+	problems, _ = Check(context.Background(), entries, Options{})
+	if len(problems) != 2 {
+		t.Errorf("unsampled check found %d problems; want 2", len(problems))
+	}
+}
+
+func TestSample(t *testing.T) {
+	entries := make([]Entry, 10)
+	for i := range entries {
+		if i%2 == 0 {
+			entries[i].MD5 = "x"
+		}
+	}
+	if got := sample(entries, 2); !reflect.DeepEqual(got, map[int]bool{0: true, 4: true}) {
+		t.Errorf("sample of 2 = %v", got)
+	}
+	if got := sample(entries, 50); len(got) != 5 || got[1] {
+		t.Errorf("sample of 50 = %v; want the five entries with a hash", got)
+	}
+}
@@ -0,0 +1,260 @@
+// Package report delivers the summary of an unattended run to where its owner reads it without
+// opening the machine: a file in a drop folder such as a synced notes folder, a block on standard
+// output that a scheduler keeping the output can parse, and mail.
+package report
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds one mail delivery attempt when the config sets none.
+const DefaultTimeout = 30 * time.Second
+
+// The lines around the report printed on standard output.
+const (
+	BeginMarker = "----- BEGIN LUME SUMMARY -----"
+	EndMarker   = "----- END LUME SUMMARY -----"
+)
+
+// retryDelay is the pause before the single retry of a failed delivery.
+var retryDelay = 5 * time.Second
+
+// stdout receives the report block; tests replace it.
+var stdout io.Writer = os.Stdout
+
+// Report is the summary of one run as it is delivered.
+type Report struct {
+	Time    time.Time
+	Subject string
+	Body    string
+}
+
+// Config selects where reports go; any number of the ways may be set, none turns delivery off.
+type Config struct {
+	// Drop is a folder each report is written into as a file of its own.
+	Drop string `json:"drop,omitempty"`
+	// Stdout prints each report between BeginMarker and EndMarker.
+	Stdout bool `json:"stdout,omitempty"`
+	// SMTP mails each report.
+	SMTP SMTP `json:"smtp"`
+}
+
+// SMTP is the mail server reports are sent through. The password is never kept in the config: it is
+// read from the environment variable PasswordEnv names when a report is sent.
+type SMTP struct {
+	Host string `json:"host,omitempty"`
+	// Port defaults to 587 with StartTLS and to 25 without.
+	Port     int      `json:"port,omitempty"`
+	StartTLS bool     `json:"starttls,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	User     string   `json:"user,omitempty"`
+	// PasswordEnv names the environment variable holding the password of User.
+	PasswordEnv string `json:"password_env,omitempty"`
+	// Timeout bounds each attempt in seconds; 0 means DefaultTimeout.
+	Timeout int `json:"timeout,omitempty"`
+}
+
+// Enabled reports whether reports go anywhere.
+func (c Config) Enabled() bool { return c.Drop != "" || c.Stdout || c.SMTP.Host != "" }
+
+// Check rejects a mail server without a sender or a recipient, or with a user but no password variable.
+func (c Config) Check() error {
+	s := c.SMTP
+	if s.Host == "" {
+		return nil
+	}
+	switch {
+	case s.From == "" || len(s.To) == 0:
+		return errors.New("report: smtp needs a from and a to address")
+	case s.User != "" && s.PasswordEnv == "":
+		return errors.New("report: smtp user set without password_env")
+	}
+	return nil
+}
+
+func (s SMTP) addr() string {
+	port := s.Port
+	if port == 0 {
+		port = 25
+		if s.StartTLS {
+			port = 587
+		}
+	}
+	return net.JoinHostPort(s.Host, strconv.Itoa(port))
+}
+
+func (s SMTP) timeout() time.Duration {
+	if s.Timeout > 0 {
+		return time.Duration(s.Timeout) * time.Second
+	}
+	return DefaultTimeout
+}
+
+// Deliver sends r each configured way. A way that fails is retried once; the errors of those that fail
+// twice are joined.
+func Deliver(ctx context.Context, c Config, r Report) error {
+	if err := c.Check(); err != nil || !c.Enabled() {
+		return err
+	}
+	var errs []error
+	if c.Stdout {
+		fmt.Fprintf(stdout, "%s\n%s\n\n%s\n%s\n", BeginMarker, r.Subject, strings.TrimRight(r.Body, "\n"), EndMarker)
+	}
+	if c.Drop != "" {
+		errs = append(errs, retry(ctx, func() error { return drop(c.Drop, r) }))
+	}
+	if c.SMTP.Host != "" {
+		errs = append(errs, retry(ctx, func() error { return mail(ctx, c.SMTP, r) }))
+	}
+	return errors.Join(errs...)
+}
+
+// retry runs send, and once more after retryDelay should it fail.
+func retry(ctx context.Context, send func() error) error {
+	err := send()
+	if err == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return err
+	case <-time.After(retryDelay):
+	}
+	if err2 := send(); err2 != nil {
+		return fmt.Errorf("failed twice: %w", err2)
+	}
+	return nil
+}
+
+// FileName is the name of the report of a run finished at t in a drop folder. n numbers the
+// reports of runs finished in the same second from 1, the first keeping the plain name.
+func FileName(t time.Time, n int) string {
+	name := "lume_summary_" + t.Format("20060102_150405")
+	if n > 1 {
+		name += "_" + strconv.Itoa(n)
+	}
+	return name + ".txt"
+}
+
+// drop writes r into dir, beside and then over its file, so a sync client never uploads half of it.
+func drop(dir string, r Report) error {
+	tmp, err := os.CreateTemp(dir, ".lume_summary_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	text := strings.ReplaceAll(r.Subject+"\n\n"+strings.TrimRight(r.Body, "\n")+"\n", "\n", "\r\n")
+	if _, err := tmp.WriteString(text); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		err := place(tmp.Name(), filepath.Join(dir, FileName(r.Time, n)))
+		if !errors.Is(err, fs.ErrExist) || n == maxSameSecond {
+			return err
+		}
+	}
+}
+
+// maxSameSecond bounds the reports drop keeps apart for runs finished in the same second.
+const maxSameSecond = 100
+
+// place gives the written report at tmp the name dst unless a report of another run holds it. A
+// hard link never replaces dst; where the folder takes none (FAT, some shares) a dst found free is
+// renamed over, which leaves only two runs racing for the same name in the same instant.
+func place(tmp, dst string) error {
+	err := os.Link(tmp, dst)
+	if err == nil || errors.Is(err, fs.ErrExist) {
+		return err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return fs.ErrExist
+	}
+	return os.Rename(tmp, dst)
+}
+
+// message is r as a plain text mail from s.From to s.To.
+func message(s SMTP, r Report) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", s.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", r.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", r.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&b)
+	w.Write([]byte(strings.ReplaceAll(r.Body, "\n", "\r\n")))
+	w.Close()
+	return b.Bytes()
+}
+
+// mail sends r through s. The connection is encrypted with STARTTLS when s asks for it; the password
+// only goes over an encrypted connection, or to this machine.
+func mail(ctx context.Context, s SMTP, r Report) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout())
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", s.addr())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if s.StartTLS {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+	if s.User != "" {
+		password := os.Getenv(s.PasswordEnv)
+		if password == "" {
+			return fmt.Errorf("environment variable %s holds no password", s.PasswordEnv)
+		}
+		if err := c.Auth(smtp.PlainAuth("", s.User, password, s.Host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(s, r)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
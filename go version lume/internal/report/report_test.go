@@ -0,0 +1,166 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var sample = Report{Time: time.Date(2026, 3, 1, 2, 30, 0, 0, time.UTC), Subject: "Lume: 2 archived, 1 error", Body: "2 archived, 1 error.\n\n- a.jpg: Arşiv dolu\n"}
+
+func TestDropAndStdout(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	stdout = &out
+	defer func() { stdout = os.Stdout }()
+	if err := Deliver(context.Background(), Config{Drop: dir, Stdout: true}, sample); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "lume_summary_20260301_023000.txt"))
+	if err != nil || string(b) != "Lume: 2 archived, 1 error\r\n\r\n2 archived, 1 error.\r\n\r\n- a.jpg: Arşiv dolu\r\n" {
+		t.Errorf("dropped %q, %v", b, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("drop folder holds %d files", len(entries))
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if lines[0] != BeginMarker || lines[1] != sample.Subject || lines[len(lines)-1] != EndMarker || lines[len(lines)-2] != "- a.jpg: Arşiv dolu" {
+		t.Errorf("stdout block:\n%s", out.String())
+	}
+}
+
+func TestDropSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	later := sample
+	later.Subject = "Lume: 5 archived"
+	for _, r := range []Report{sample, later} {
+		if err := drop(dir, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first, _ := os.ReadFile(filepath.Join(dir, "lume_summary_20260301_023000.txt"))
+	second, err := os.ReadFile(filepath.Join(dir, "lume_summary_20260301_023000_2.txt"))
+	if !strings.HasPrefix(string(first), sample.Subject) || err != nil || !strings.HasPrefix(string(second), later.Subject) {
+		t.Errorf("reports of the same second: %q, %q, %v", first, second, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("drop folder holds %d files", len(entries))
+	}
+}
+
+func TestDropRetriesOnceAndFails(t *testing.T) {
+	retryDelay = time.Millisecond
+	err := Deliver(context.Background(), Config{Drop: filepath.Join(t.TempDir(), "missing")}, sample)
+	if err == nil || !strings.Contains(err.Error(), "failed twice") {
+		t.Errorf("Deliver = %v; want a failure after the retry", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, tt := range []struct {
+		smtp SMTP
+		ok   bool
+	}{
+		{SMTP{}, true},
+		{SMTP{Host: "mail.example.com", From: "lume@example.com", To: []string{"me@example.com"}}, true},
+		{SMTP{Host: "mail.example.com", From: "lume@example.com"}, false},
+		{SMTP{Host: "mail.example.com", From: "lume@example.com", To: []string{"me@example.com"}, User: "me"}, false},
+	} {
+		if err := (Config{SMTP: tt.smtp}).Check(); (err == nil) != tt.ok {
+			t.Errorf("Check(%+v) = %v", tt.smtp, err)
+		}
+	}
+}
+
+// serveSMTP answers mail on a local port, refusing the first fail sessions, and returns the port and
+// the messages received.
+func serveSMTP(t *testing.T, fail int) (int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	got := make(chan string, 4)
+	go func() {
+		for session := 0; ; session++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if session < fail {
+				conn.Write([]byte("554 busy\r\n"))
+				conn.Close()
+				continue
+			}
+			r, w := bufio.NewReader(conn), conn
+			w.Write([]byte("220 test\r\n"))
+			var data strings.Builder
+			inData := false
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					break
+				}
+				if inData {
+					if line == ".\r\n" {
+						inData = false
+						got <- data.String()
+						w.Write([]byte("250 queued\r\n"))
+					} else {
+						data.WriteString(line)
+					}
+					continue
+				}
+				switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+				case "EHLO", "HELO":
+					w.Write([]byte("250 test\r\n"))
+				case "DATA":
+					inData = true
+					w.Write([]byte("354 go\r\n"))
+				case "QUIT":
+					w.Write([]byte("221 bye\r\n"))
+				default:
+					w.Write([]byte("250 ok\r\n"))
+				}
+			}
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, got
+}
+
+func TestMailRetriesOnce(t *testing.T) {
+	retryDelay = time.Millisecond
+	port, got := serveSMTP(t, 1)
+	c := Config{SMTP: SMTP{Host: "127.0.0.1", Port: port, From: "lume@example.com", To: []string{"me@example.com"}, Timeout: 5}}
+	if err := Deliver(context.Background(), c, sample); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-got
+	for _, want := range []string{"To: me@example.com\r\n", "Subject: Lume: 2 archived, 1 error\r\n", "charset=utf-8", "- a.jpg: Ar=C5=9Fiv dolu"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("message lacks %q:\n%s", want, msg)
+		}
+	}
+}
+
+func TestMailPasswordFromEnvironment(t *testing.T) {
+	retryDelay = time.Millisecond
+	port, _ := serveSMTP(t, 0)
+	c := Config{SMTP: SMTP{Host: "127.0.0.1", Port: port, From: "lume@example.com", To: []string{"me@example.com"}, User: "me", PasswordEnv: "LUME_TEST_SMTP_PASSWORD"}}
+	t.Setenv("LUME_TEST_SMTP_PASSWORD", "")
+	if err := Deliver(context.Background(), c, sample); err == nil || !strings.Contains(err.Error(), "LUME_TEST_SMTP_PASSWORD") {
+		t.Errorf("Deliver without a password = %v", err)
+	}
+	if got := (SMTP{Host: "h", StartTLS: true}).addr(); got != "h:"+strconv.Itoa(587) {
+		t.Errorf("addr = %s", got)
+	}
+}
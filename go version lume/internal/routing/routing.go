@@ -0,0 +1,98 @@
+package routing
+
+import (
+	"fmt"
+	"lume-go/internal/metadata"
+	"lume-go/internal/units"
+	"path/filepath"
+	"strings"
+)
+
+// Rule sends matching files to their own target folder. Empty matcher fields match everything,
+// so a rule with only Extensions routes by type alone. Source also matches the sources nested in it:
+// WhatsApp matches WhatsApp/Images/Sent.
+type Rule struct {
+	Extensions []string   `json:"extensions,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	MinSize    units.Size `json:"min_size,omitempty"`
+	Target     string     `json:"target"`
+	// Template overrides the folder template for this target; empty keeps the default layout.
+	Template string `json:"template,omitempty"`
+}
+
+// Match reports whether info satisfies every matcher of the rule.
+func (r Rule) Match(info metadata.FileInfo) bool {
+	if len(r.Extensions) > 0 {
+		ext, ok := strings.ToLower(filepath.Ext(info.Filename)), false
+		for _, e := range r.Extensions {
+			if e = strings.ToLower(strings.TrimSpace(e)); e == ext || "."+e == ext {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if r.Source != "" && !metadata.SourceIn(info.Source, r.Source) {
+		return false
+	}
+	return info.Size >= int64(r.MinSize)
+}
+
+// String describes the rule for display, e.g. ".mp4,.mov ≥ 100 MB → E:\Video".
+func (r Rule) String() string {
+	var parts []string
+	if len(r.Extensions) > 0 {
+		parts = append(parts, strings.Join(r.Extensions, ","))
+	}
+	if r.Source != "" {
+		parts = append(parts, r.Source)
+	}
+	if r.MinSize > 0 {
+		parts = append(parts, fmt.Sprintf("≥ %d MB", r.MinSize/(1024*1024)))
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "*")
+	}
+	return strings.Join(parts, " ") + " → " + r.Target
+}
+
+// Router picks the target folder of each file: the first matching rule, else Default.
+type Router struct {
+	Rules   []Rule
+	Default string
+}
+
+// Route returns the target base folder for info and the template override of the matching rule.
+func (rt Router) Route(info metadata.FileInfo) (target, template string) {
+	for _, r := range rt.Rules {
+		if r.Target != "" && r.Match(info) {
+			return r.Target, r.Template
+		}
+	}
+	return rt.Default, ""
+}
+
+// Targets lists every folder the router can send files to, the default first.
+func (rt Router) Targets() []string {
+	out := []string{rt.Default}
+	seen := map[string]bool{strings.ToLower(filepath.Clean(rt.Default)): true}
+	for _, r := range rt.Rules {
+		if key := strings.ToLower(filepath.Clean(r.Target)); r.Target != "" && !seen[key] {
+			seen[key] = true
+			out = append(out, r.Target)
+		}
+	}
+	return out
+}
+
+// Totals sums the bytes each target will receive, for per-target disk space checks.
+func (rt Router) Totals(files []metadata.FileInfo) map[string]int64 {
+	out := make(map[string]int64)
+	for _, f := range files {
+		target, _ := rt.Route(f)
+		out[target] += f.Size
+	}
+	return out
+}
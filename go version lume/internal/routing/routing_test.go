@@ -0,0 +1,44 @@
+package routing
+
+import (
+	"lume-go/internal/metadata"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	rt := Router{
+		Default: `D:\Archive`,
+		Rules: []Rule{
+			{Extensions: []string{".mp4", "MOV"}, MinSize: 100, Target: `E:\Video`, Template: "{year}"},
+			{Source: "whatsapp", Target: `D:\Chats`},
+			{Extensions: []string{".mp4"}, Target: `E:\Clips`},
+		},
+	}
+	tests := []struct {
+		name     string
+		info     metadata.FileInfo
+		target   string
+		template string
+	}{
+		{"big video", metadata.FileInfo{Filename: "a.MP4", Size: 500}, `E:\Video`, "{year}"},
+		{"extension without dot", metadata.FileInfo{Filename: "b.mov", Size: 500}, `E:\Video`, "{year}"},
+		{"small video falls through", metadata.FileInfo{Filename: "c.mp4", Size: 10}, `E:\Clips`, ""},
+		{"source", metadata.FileInfo{Filename: "IMG-WA0001.jpg", Source: "WhatsApp"}, `D:\Chats`, ""},
+		{"nested source", metadata.FileInfo{Filename: "IMG-20230514-WA0001.jpg", Source: "WhatsApp/Images/Sent"}, `D:\Chats`, ""},
+		{"unmatched", metadata.FileInfo{Filename: "d.jpg", Source: "Camera"}, `D:\Archive`, ""},
+	}
+	for _, tt := range tests {
+		target, template := rt.Route(tt.info)
+		if target != tt.target || template != tt.template {
+			t.Errorf("%s: Route = %q, %q; want %q, %q", tt.name, target, template, tt.target, tt.template)
+		}
+	}
+
+	totals := rt.Totals([]metadata.FileInfo{{Filename: "a.mp4", Size: 500}, {Filename: "b.jpg", Size: 7}, {Filename: "c.jpg", Size: 3}})
+	if totals[`E:\Video`] != 500 || totals[`D:\Archive`] != 10 || len(totals) != 2 {
+		t.Errorf("Totals = %v", totals)
+	}
+	if got := rt.Targets(); len(got) != 4 || got[0] != `D:\Archive` {
+		t.Errorf("Targets = %v", got)
+	}
+}
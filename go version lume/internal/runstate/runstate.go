@@ -0,0 +1,231 @@
+// Package runstate keeps a record of each window run while it goes, so a run cut short by a crash
+// or a Windows Update reboot is found on the next start with the results it streamed, instead of an
+// empty window. A run that ends removes its record; an interrupted one is kept until the user
+// resumes or dismisses it, and pruned some days after.
+package runstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"lume-go/internal/lock"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultKeepDays is how long the record of an interrupted run is kept once it is found.
+const DefaultKeepDays = 14
+
+// The statuses of a record.
+const (
+	Running     = "running"
+	Interrupted = "interrupted"
+	Resumed     = "resumed"
+	Dismissed   = "dismissed"
+)
+
+// State is the record of a run: what it was asked to move and where its results stream to.
+type State struct {
+	ID      string    `json:"id"`
+	PID     int       `json:"pid"`
+	Status  string    `json:"status"`
+	Started time.Time `json:"started"`
+	// Ended is when an interrupted run was last heard of: its journal's last write, else its record's.
+	Ended   time.Time `json:"ended,omitzero"`
+	Target  string    `json:"target"`
+	Roots   []string  `json:"roots,omitempty"`
+	Queued  []string  `json:"queued"`
+	Journal string    `json:"journal,omitempty"`
+}
+
+// Remaining is the queued files of the run that have no result among done, in the order queued.
+func (st State) Remaining(done iter.Seq[string]) []string {
+	seen := make(map[string]bool)
+	for p := range done {
+		seen[p] = true
+	}
+	var left []string
+	for _, p := range st.Queued {
+		if !seen[p] {
+			left = append(left, p)
+		}
+	}
+	return left
+}
+
+// Store is the folder the records, and the journals of interrupted runs, are kept in.
+type Store struct {
+	dir   string
+	alive func(pid int, started time.Time) bool // replaced in tests
+}
+
+// New returns the store in dir, created on the first record.
+func New(dir string) *Store {
+	return &Store{dir: dir, alive: lock.AliveSince}
+}
+
+// NewID names a run started at t by this process.
+func NewID(t time.Time) string {
+	return fmt.Sprintf("%s-%d", t.Format("20060102-150405"), os.Getpid())
+}
+
+func (s *Store) path(id, ext string) string { return filepath.Join(s.dir, id+ext) }
+
+// Begin records st as a run of this process in progress.
+func (s *Store) Begin(st State) error {
+	st.Status, st.PID = Running, os.Getpid()
+	return s.save(st)
+}
+
+// End removes the record of a run that ended, whatever its outcome.
+func (s *Store) End(id string) error {
+	if err := os.Remove(s.path(id, ".json")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Settle records what the user chose for an interrupted run, so it is not offered again.
+func (s *Store) Settle(st State, status string) error {
+	st.Status = status
+	return s.save(st)
+}
+
+// save writes the record of st through a temporary file, so a crash never leaves half of one.
+func (s *Store) save(st State) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path(st.ID, ".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(st.ID, ".json"))
+}
+
+// records reads every record of the store with the modification time of its file. Records that
+// cannot be read are left to Prune.
+func (s *Store) records() ([]State, []time.Time, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var states []State
+	var mods []time.Time
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var st State
+		if json.Unmarshal(data, &st) != nil || st.ID+".json" != e.Name() {
+			continue
+		}
+		states = append(states, st)
+		mods = append(mods, fi.ModTime())
+	}
+	return states, mods, nil
+}
+
+// Interrupted returns the runs that ended without removing their record and have not been resumed
+// or dismissed, the latest first. A record still marked running whose process is gone, or whose pid
+// now belongs to a process started after the run, is marked interrupted on the way, and its journal
+// moved into the store, so the next run's journal does not replace it.
+func (s *Store) Interrupted() ([]State, error) {
+	states, mods, err := s.records()
+	if err != nil {
+		return nil, err
+	}
+	var found []State
+	for i, st := range states {
+		if st.Status == Running {
+			// A pid reused by a process started after the run is not the run's.
+			if s.alive(st.PID, st.Started) {
+				continue
+			}
+			st.Status, st.Ended = Interrupted, mods[i]
+			if st.Journal != "" {
+				if fi, err := os.Stat(st.Journal); err == nil {
+					st.Ended = fi.ModTime()
+				}
+				kept := s.path(st.ID, ".jsonl")
+				if err := os.Rename(st.Journal, kept); err == nil {
+					st.Journal = kept
+				} else if !os.IsNotExist(err) {
+					return nil, err
+				} else {
+					st.Journal = ""
+				}
+			}
+			if err := s.save(st); err != nil {
+				return nil, err
+			}
+		}
+		if st.Status == Interrupted {
+			found = append(found, st)
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Started.After(found[j].Started) })
+	return found, nil
+}
+
+// Prune removes the records of interrupted runs, and their journals, last heard of more than keep
+// before now, whether or not the user settled them, and any other file of the store as old.
+// Records of runs in progress are never pruned. It returns the number of runs removed.
+func (s *Store) Prune(keep time.Duration, now time.Time) (int, error) {
+	states, _, err := s.records()
+	if err != nil {
+		return 0, err
+	}
+	live, known := make(map[string]bool), make(map[string]bool)
+	for _, st := range states {
+		known[st.ID] = true
+		if st.Status == Running || st.Ended.IsZero() || now.Sub(st.Ended) <= keep {
+			live[st.ID] = true
+		}
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return 0, nil
+	}
+	n := 0
+	for _, e := range entries {
+		id := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		if e.IsDir() || live[id] {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			continue
+		}
+		// A file of no record, such as one that cannot be read, goes by its own age.
+		if !known[id] && now.Sub(fi.ModTime()) <= keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, e.Name())); err != nil {
+			return n, err
+		}
+		if filepath.Ext(e.Name()) == ".json" {
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,156 @@
+package runstate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// writeFile writes a file of the store last modified at mod.
+func writeFile(t *testing.T, path, data string, mod time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	os.MkdirAll(runs, 0755)
+	s := New(runs)
+	s.alive = func(pid int, _ time.Time) bool { return pid == 100 }
+	crashed := time.Date(2024, 5, 6, 12, 30, 0, 0, time.UTC)
+	journal := filepath.Join(dir, "results.jsonl")
+	writeFile(t, journal, "{\"File\":\"a.jpg\"}\n", crashed)
+
+	// A run of a process that is gone, one of a process still running, one already found before,
+	// one the user dismissed, and a record that cannot be read.
+	writeFile(t, filepath.Join(runs, "20240506-120000-200.json"), `{"id":"20240506-120000-200","pid":200,"status":"running","started":"2024-05-06T12:00:00Z","target":"D:\\Photos","queued":["a.jpg","b.jpg"],"journal":`+quote(journal)+`}`, crashed.Add(-time.Hour))
+	writeFile(t, filepath.Join(runs, "20240507-090000-100.json"), `{"id":"20240507-090000-100","pid":100,"status":"running","started":"2024-05-07T09:00:00Z"}`, crashed)
+	writeFile(t, filepath.Join(runs, "20240501-080000-300.json"), `{"id":"20240501-080000-300","pid":300,"status":"interrupted","started":"2024-05-01T08:00:00Z","ended":"2024-05-01T08:10:00Z"}`, crashed)
+	writeFile(t, filepath.Join(runs, "20240502-080000-300.json"), `{"id":"20240502-080000-300","pid":300,"status":"dismissed","started":"2024-05-02T08:00:00Z","ended":"2024-05-02T08:10:00Z"}`, crashed)
+	writeFile(t, filepath.Join(runs, "20240503-080000-300.json"), `{"id":"20240503-08`, crashed)
+
+	found, err := s.Interrupted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, st := range found {
+		ids = append(ids, st.ID)
+	}
+	if !slices.Equal(ids, []string{"20240506-120000-200", "20240501-080000-300"}) {
+		t.Fatalf("interrupted runs %v", ids)
+	}
+	st := found[0]
+	kept := filepath.Join(runs, "20240506-120000-200.jsonl")
+	if st.Status != Interrupted || !st.Ended.Equal(crashed) || st.Journal != kept {
+		t.Errorf("crashed run found as %+v", st)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("journal not kept in the store: %v", err)
+	}
+	if _, err := os.Stat(journal); !os.IsNotExist(err) {
+		t.Errorf("journal left where the next run replaces it: %v", err)
+	}
+	if left := st.Remaining(slices.Values([]string{"a.jpg"})); !slices.Equal(left, []string{"b.jpg"}) {
+		t.Errorf("Remaining = %v", left)
+	}
+
+	// Found once, it stays as it is; settled, it is not offered again.
+	again, _ := s.Interrupted()
+	if len(again) != 2 || again[0].Journal != kept || !again[0].Ended.Equal(crashed) {
+		t.Errorf("found again as %+v", again)
+	}
+	if err := s.Settle(st, Dismissed); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := s.Interrupted(); len(again) != 1 {
+		t.Errorf("%d runs offered after one was dismissed", len(again))
+	}
+	if err := s.End("20240507-090000-100"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(runs, "20240507-090000-100.json")); !os.IsNotExist(err) {
+		t.Errorf("record of an ended run kept: %v", err)
+	}
+	if found, err := New(filepath.Join(dir, "none")).Interrupted(); err != nil || len(found) != 0 {
+		t.Errorf("missing store: %v, %v", found, err)
+	}
+}
+
+func TestInterruptedReusedPID(t *testing.T) {
+	runs := t.TempDir()
+	s := New(runs)
+	// Process 100 has run since 2024-05-07 08:00: a run it recorded later is its own, an earlier one
+	// was of another process that had the pid before.
+	since := time.Date(2024, 5, 7, 8, 0, 0, 0, time.UTC)
+	s.alive = func(pid int, started time.Time) bool { return pid == 100 && !since.After(started) }
+	writeFile(t, filepath.Join(runs, "20240507-090000-100.json"), `{"id":"20240507-090000-100","pid":100,"status":"running","started":"2024-05-07T09:00:00Z"}`, since)
+	writeFile(t, filepath.Join(runs, "20240506-090000-100.json"), `{"id":"20240506-090000-100","pid":100,"status":"running","started":"2024-05-06T09:00:00Z"}`, since)
+	found, err := s.Interrupted()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "20240506-090000-100" {
+		t.Errorf("interrupted runs %+v; want the one before the pid was reused", found)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	runs := t.TempDir()
+	s := New(runs)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	keep := 14 * 24 * time.Hour
+	old, recent := now.Add(-20*24*time.Hour), now.Add(-24*time.Hour)
+	record := func(id, status string, ended time.Time) {
+		t.Helper()
+		end := ""
+		if !ended.IsZero() {
+			end = `,"ended":"` + ended.Format(time.RFC3339) + `"`
+		}
+		// The file is as old as the run, so only the record's own date can keep it.
+		writeFile(t, filepath.Join(runs, id+".json"), `{"id":"`+id+`","status":"`+status+`","started":"2024-01-01T00:00:00Z"`+end+`}`, old)
+		writeFile(t, filepath.Join(runs, id+".jsonl"), "{}\n", old)
+	}
+	record("old-interrupted", Interrupted, old)
+	record("old-dismissed", Dismissed, old)
+	record("recent-interrupted", Interrupted, recent)
+	record("running", Running, time.Time{})
+	writeFile(t, filepath.Join(runs, "broken.json"), "{", old)
+	writeFile(t, filepath.Join(runs, "fresh-broken.json"), "{", recent)
+	writeFile(t, filepath.Join(runs, "stray.jsonl"), "{}\n", old)
+
+	n, err := s.Prune(keep, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("Prune removed %d runs; want the 2 old ones and the broken record", n)
+	}
+	entries, _ := os.ReadDir(runs)
+	var left []string
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	want := []string{"fresh-broken.json", "recent-interrupted.json", "recent-interrupted.jsonl", "running.json", "running.jsonl"}
+	if !slices.Equal(left, want) {
+		t.Errorf("left %v; want %v", left, want)
+	}
+	if n, err := New(filepath.Join(runs, "none")).Prune(keep, now); n != 0 || err != nil {
+		t.Errorf("missing store pruned %d: %v", n, err)
+	}
+}
+
+// quote is path as a JSON string.
+func quote(path string) string {
+	b, _ := json.Marshal(path)
+	return string(b)
+}
@@ -0,0 +1,167 @@
+package scan
+
+import (
+	"encoding/json"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// cacheVersion changes when cached metadata would be read differently now; 2 added MetadataNote and
+// the EXIF date formats beyond the standard one, 3 the subseconds of EXIF dates, 4 the nested
+// WhatsApp sources and the WhatsApp name date ahead of the headers, 5 the creation time.
+const cacheVersion = 5
+
+// DefaultMaxEntries caps a persisted cache when the caller sets no limit.
+const DefaultMaxEntries = 100000
+
+// minTrim is the fewest entries a persisted cache grows past its cap before the least recently used
+// are dropped, so small caps are not sorted again on every file.
+const minTrim = 16
+
+type cacheFile struct {
+	Version int         `json:"version"`
+	Entries []diskEntry `json:"entries"`
+}
+
+type diskEntry struct {
+	Path  string            `json:"p"`
+	Links string            `json:"l,omitempty"`
+	Size  int64             `json:"s"`
+	MTime int64             `json:"m"`
+	Used  int64             `json:"u"`
+	Info  metadata.FileInfo `json:"i"`
+}
+
+// OpenCache loads the cache persisted at path, keeping at most max entries when it is saved
+// (DefaultMaxEntries for max <= 0). In memory it holds a tenth more before the least recently used
+// are dropped. A missing file starts an empty cache; an unreadable one is
+// discarded and rebuilt from the files as they are scanned again.
+func OpenCache(path string, max int) *Cache {
+	if max <= 0 {
+		max = DefaultMaxEntries
+	}
+	c := NewCache()
+	c.file, c.max = path, max
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Scan cache unreadable, starting empty: %v", err)
+		}
+		return c
+	}
+	var f cacheFile
+	if err := json.Unmarshal(data, &f); err != nil || f.Version != cacheVersion {
+		logger.Error("Scan cache %s is corrupt (version %d, %v); rebuilding it", path, f.Version, err)
+		os.Remove(path)
+		return c
+	}
+	for _, e := range f.Entries {
+		if e.Path == "" || e.Info.Path != e.Path {
+			continue
+		}
+		c.entries[e.Path] = entry{links: e.Links, size: e.Size, mtime: e.MTime, used: e.Used, info: e.Info}
+	}
+	logger.Info("Scan cache loaded: %d files from %s", len(c.entries), path)
+	return c
+}
+
+// Save writes the cache back to the file it was opened from, dropping the least recently used
+// entries beyond the cap. The file is replaced atomically. A cache from NewCache has no file and
+// Save does nothing.
+func (c *Cache) Save() error {
+	if c == nil || c.file == "" {
+		return nil
+	}
+	c.mu.Lock()
+	f := cacheFile{Version: cacheVersion, Entries: make([]diskEntry, 0, len(c.entries))}
+	for p, e := range c.entries {
+		f.Entries = append(f.Entries, diskEntry{Path: p, Links: e.links, Size: e.size, MTime: e.mtime, Used: e.used, Info: e.info})
+	}
+	c.mu.Unlock()
+	if len(f.Entries) > c.max {
+		sort.Slice(f.Entries, func(i, j int) bool { return f.Entries[i].Used > f.Entries[j].Used })
+		f.Entries = f.Entries[:c.max]
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.file+".tmp", data, 0644); err != nil {
+		return err
+	}
+	hits, misses := c.Stats()
+	logger.Info("Scan cache saved: %d files (this session %d hits, %d misses)", len(f.Entries), hits, misses)
+	return os.Rename(c.file+".tmp", c.file)
+}
+
+// trim drops the least recently used entries down to the cap once a persisted cache outgrew it by a
+// tenth; c.mu must be held.
+func (c *Cache) trim() {
+	if c.max <= 0 || len(c.entries) <= c.max+max(c.max/10, minTrim) {
+		return
+	}
+	paths := make([]string, 0, len(c.entries))
+	for p := range c.entries {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool { return c.entries[paths[i]].used > c.entries[paths[j]].used })
+	for _, p := range paths[c.max:] {
+		delete(c.entries, p)
+	}
+}
+
+// Hash returns the MD5 of path, from the cache while the file is unchanged. Hashes are kept with the
+// metadata, so an unchanged source is not read again by duplicate checks on the next run either.
+// Files that were never scanned are hashed without being cached.
+func (c *Cache) Hash(path string) (string, error) {
+	if c == nil {
+		return metadata.GetFileHash(path)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	e, ok := c.lookup(path, st)
+	if ok && e.info.MD5 != "" {
+		c.hits.Add(1)
+		return e.info.MD5, nil
+	}
+	c.misses.Add(1)
+	h, err := metadata.GetFileHash(path)
+	if err != nil || !ok {
+		return h, err
+	}
+	c.keep(path, e, h)
+	return h, nil
+}
+
+// Remember keeps md5, read by someone else (e.g. a move's duplicate checks), as the hash of path
+// while the file is unchanged.
+func (c *Cache) Remember(path, md5 string) {
+	if c == nil {
+		return
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if e, ok := c.lookup(path, st); ok {
+		c.keep(path, e, md5)
+	}
+}
+
+// keep stores md5 in the entry of path unless the file changed since e was looked up.
+func (c *Cache) keep(path string, e entry, md5 string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cur, still := c.entries[path]; still && cur.size == e.size && cur.mtime == e.mtime {
+		cur.info.MD5 = md5
+		c.entries[path] = cur
+	}
+}
@@ -0,0 +1,91 @@
+package scan
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistedCache(t *testing.T) {
+	paths := writeFiles(t, 5)
+	file := filepath.Join(t.TempDir(), "Lume", "scan_cache.json")
+
+	c := OpenCache(file, 3)
+	if _, err := All(context.Background(), paths, Options{Cache: c}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Hash(paths[4]); err != nil {
+		t.Fatal(err)
+	}
+	// The first two files were looked up least recently and are evicted by the cap.
+	past := time.Now().Add(-time.Hour).Unix()
+	for _, p := range paths[:2] {
+		e := c.entries[p]
+		e.used = past
+		c.entries[p] = e
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	c = OpenCache(file, 3)
+	if c.Len() != 3 {
+		t.Fatalf("reloaded %d entries; want 3", c.Len())
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(paths[3], later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := All(context.Background(), paths, Options{Cache: c}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := c.Stats(); hits != 2 || misses != 3 {
+		t.Errorf("after reload: %d hits, %d misses; want 2, 3", hits, misses)
+	}
+	if h, err := c.Hash(paths[4]); err != nil || h == "" {
+		t.Errorf("Hash = %q, %v", h, err)
+	}
+	if hits, _ := c.Stats(); hits != 3 {
+		t.Errorf("persisted hash not reused: %d hits", hits)
+	}
+}
+
+func TestCacheBoundedInMemory(t *testing.T) {
+	paths := writeFiles(t, 3+minTrim+1)
+	c := OpenCache(filepath.Join(t.TempDir(), "scan_cache.json"), 3)
+	if _, err := All(context.Background(), paths[:3+minTrim], Options{Cache: c}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 3+minTrim {
+		t.Fatalf("%d entries before the cap is exceeded; want %d", c.Len(), 3+minTrim)
+	}
+	if _, err := All(context.Background(), paths, Options{Cache: c}); err != nil {
+		t.Fatal(err)
+	}
+	if c.Len() != 3 {
+		t.Errorf("%d entries after the cap was exceeded; want 3", c.Len())
+	}
+}
+
+func TestCorruptCacheRebuilds(t *testing.T) {
+	paths := writeFiles(t, 2)
+	file := filepath.Join(t.TempDir(), "scan_cache.json")
+	if err := os.WriteFile(file, []byte(`{"version":1,"entries":[{"p":`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := OpenCache(file, 0)
+	if c.Len() != 0 {
+		t.Fatalf("corrupt cache loaded %d entries", c.Len())
+	}
+	if _, err := All(context.Background(), paths, Options{Cache: c}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if c = OpenCache(file, 0); c.Len() != 2 {
+		t.Errorf("rebuilt cache has %d entries; want 2", c.Len())
+	}
+}
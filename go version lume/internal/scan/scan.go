@@ -0,0 +1,199 @@
+// Package scan reads the metadata of many files at once with a bounded pool of workers.
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/logger"
+	"lume-go/internal/metadata"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPanic marks a file whose metadata reader panicked, e.g. on a malformed EXIF block. The scan
+// goes on with the other files.
+var ErrPanic = errors.New("metadata reader crashed")
+
+// readInfo reads the metadata of one file; tests replace it.
+var readInfo = metadata.GetFileInfoLinks
+
+// Result is the metadata of one scanned path, or the error reading it.
+type Result struct {
+	Path string
+	Info metadata.FileInfo
+	Err  error
+}
+
+// Options tunes Scan.
+type Options struct {
+	// Workers bounds the concurrent reads; zero means one per CPU.
+	Workers int
+	// Links is the link policy handed to metadata.GetFileInfoLinks.
+	Links string
+	// Cache, when set, answers unchanged files without reading them again.
+	Cache *Cache
+	// Devices renames the camera models read; the cache keeps them as read, so edited aliases apply
+	// at once. Nil applies the built-in names.
+	Devices *metadata.DeviceNames
+	// Clocks corrects the dates of devices whose clock was off, after their names; like the names, the
+	// cache keeps the dates as read. Nil corrects nothing.
+	Clocks *metadata.Clocks
+	// Progress is called after every finished path, concurrently from the worker goroutines.
+	Progress func(done, total int)
+}
+
+// Scan reads the metadata of paths and sends the results in completion order. The channel is
+// closed once every path is done or, after cancelling ctx, once the workers have stopped; paths not
+// started by then get no result. The caller must drain the channel.
+func Scan(ctx context.Context, paths []string, opts Options) <-chan Result {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	jobs := make(chan string)
+	out := make(chan Result, workers)
+	var wg sync.WaitGroup
+	var done atomic.Int64
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for p := range jobs {
+				r := Result{Path: p}
+				r.Info, r.Err = opts.Cache.info(p, opts.Links)
+				r.Info.Device = opts.Devices.Normalize(r.Info.Device)
+				opts.Clocks.Apply(&r.Info)
+				out <- r
+				if opts.Progress != nil {
+					opts.Progress(int(done.Add(1)), len(paths))
+				}
+			}
+		}()
+	}
+	go func() {
+		defer close(out)
+		defer wg.Wait()
+		defer close(jobs)
+		for _, p := range paths {
+			select {
+			case jobs <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// All runs Scan and returns the results in the order of paths. After a cancellation it returns
+// the results finished so far together with ctx's error.
+func All(ctx context.Context, paths []string, opts Options) ([]Result, error) {
+	byPath := make(map[string]Result, len(paths))
+	for r := range Scan(ctx, paths, opts) {
+		byPath[r.Path] = r
+	}
+	out := make([]Result, 0, len(byPath))
+	for _, p := range paths {
+		if r, ok := byPath[p]; ok {
+			out = append(out, r)
+			delete(byPath, p)
+		}
+	}
+	return out, ctx.Err()
+}
+
+// Cache keeps the metadata of scanned files, validated by size and modification time, so dropping
+// the same files again (e.g. after a cancelled run) costs one stat per file. It is safe for
+// concurrent use; a nil Cache reads every file. OpenCache makes it last across sessions, and bounds
+// it in memory too, so a long run does not keep every file it scanned.
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	hits    atomic.Int64
+	misses  atomic.Int64
+	// file and max are set for a persisted cache (see OpenCache).
+	file string
+	max  int
+}
+
+// Files are cached with the link policy they were read under, since the policy decides what a link
+// path resolves to.
+type entry struct {
+	links       string
+	size, mtime int64
+	used        int64 // Unix seconds of the last lookup, for eviction
+	info        metadata.FileInfo
+}
+
+func NewCache() *Cache { return &Cache{entries: make(map[string]entry)} }
+
+// lookup returns the entry of path if the file still has the recorded size and modification time.
+func (c *Cache) lookup(path string, st os.FileInfo) (entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[path]
+	if !ok || e.size != st.Size() || e.mtime != st.ModTime().UnixNano() {
+		return entry{}, false
+	}
+	e.used = time.Now().Unix()
+	c.entries[path] = e
+	return e, true
+}
+
+// info returns the cached metadata of path when the file is unchanged, reading it otherwise.
+func (c *Cache) info(path, links string) (metadata.FileInfo, error) {
+	if c == nil {
+		return safeRead(path, links)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return metadata.FileInfo{}, err
+	}
+	if e, ok := c.lookup(path, st); ok && e.links == links {
+		c.hits.Add(1)
+		// The file may have been rewritten in place since, keeping its size and time: its digests
+		// are read again when asked for.
+		return e.info.FreshHashes(), nil
+	}
+	c.misses.Add(1)
+	info, err := safeRead(path, links)
+	if err != nil {
+		return info, err
+	}
+	c.mu.Lock()
+	c.entries[path] = entry{links: links, size: st.Size(), mtime: st.ModTime().UnixNano(), used: time.Now().Unix(), info: info}
+	c.trim()
+	c.mu.Unlock()
+	return info, nil
+}
+
+// safeRead is readInfo with a panic turned into an ErrPanic error for that file.
+func safeRead(path, links string) (info metadata.FileInfo, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("Metadata reader panicked on %s: %v\n%s", path, r, debug.Stack())
+			info, err = metadata.FileInfo{}, fmt.Errorf("%w: %v", ErrPanic, r)
+		}
+	}()
+	return readInfo(path, links)
+}
+
+// Stats reports how many lookups were answered from the cache and how many read the file.
+func (c *Cache) Stats() (hits, misses int) {
+	return int(c.hits.Load()), int(c.misses.Load())
+}
+
+// Len is the number of cached files.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
@@ -0,0 +1,129 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, n int) []string {
+	dir := t.TempDir()
+	var paths []string
+	for i := 0; i < n; i++ {
+		p := filepath.Join(dir, fmt.Sprintf("IMG_%04d.jpg", i))
+		if err := os.WriteFile(p, []byte("not really a jpeg"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+func TestAllKeepsOrderAndCaches(t *testing.T) {
+	paths := writeFiles(t, 50)
+	paths = append(paths, filepath.Join(filepath.Dir(paths[0]), "gone.jpg"))
+	cache := NewCache()
+	var mu sync.Mutex
+	var last int
+	res, err := All(context.Background(), paths, Options{Workers: 4, Cache: cache, Progress: func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != len(paths) {
+			t.Errorf("Progress total %d", total)
+		}
+		last = max(last, done)
+	}})
+	if err != nil || len(res) != len(paths) || last != len(paths) {
+		t.Fatalf("All = %d results, %v; progress %d", len(res), err, last)
+	}
+	for i, r := range res {
+		if r.Path != paths[i] {
+			t.Fatalf("result %d is %s; want %s", i, r.Path, paths[i])
+		}
+	}
+	if res[len(res)-1].Err == nil || res[0].Err != nil || res[0].Info.Filename != "IMG_0000.jpg" {
+		t.Errorf("results: first %+v, last %+v", res[0], res[len(res)-1])
+	}
+
+	// A rescan hits the cache, except for the file that changed since.
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(paths[3], later, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := All(context.Background(), paths[:50], Options{Cache: cache}); err != nil {
+		t.Fatal(err)
+	}
+	if hits, misses := cache.Stats(); hits != 49 || misses != 51 || cache.Len() != 50 {
+		t.Errorf("Stats = %d hits, %d misses, %d cached; want 49, 51, 50", hits, misses, cache.Len())
+	}
+}
+
+func TestScanCancelDoesNotLeak(t *testing.T) {
+	paths := writeFiles(t, 200)
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	res, err := All(ctx, paths, Options{Workers: 4, Progress: func(done, total int) {
+		if done == 10 {
+			cancel()
+		}
+	}})
+	if err != context.Canceled || len(res) >= len(paths) {
+		t.Errorf("All after cancel = %d results, %v", len(res), err)
+	}
+	// The workers exit before the channel closes; allow the runtime a moment to reap them.
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines after cancel; %d before", n, before)
+	}
+}
+
+func TestScanSurvivesPanickingReader(t *testing.T) {
+	paths := writeFiles(t, 20)
+	read := readInfo
+	defer func() { readInfo = read }()
+	readInfo = func(path, links string) (metadata.FileInfo, error) {
+		if filepath.Base(path) == "IMG_0007.jpg" {
+			var exif map[string]int
+			exif["Orientation"] = 1
+		}
+		return metadata.GetFileInfoLinks(path, links)
+	}
+	res, err := All(context.Background(), paths, Options{Workers: 4, Cache: NewCache()})
+	if err != nil || len(res) != len(paths) {
+		t.Fatalf("All = %d results, %v", len(res), err)
+	}
+	for i, r := range res {
+		if crashed := i == 7; crashed != errors.Is(r.Err, ErrPanic) || !crashed && r.Err != nil {
+			t.Errorf("%s: %v", r.Path, r.Err)
+		}
+	}
+}
+
+// A cache hit does not carry the digests read in an earlier run.
+func TestCacheHitHashesAgain(t *testing.T) {
+	p := writeFiles(t, 1)[0]
+	st, _ := os.Stat(p)
+	cache := NewCache()
+	res, _ := All(context.Background(), []string{p}, Options{Cache: cache})
+	before, err := res[0].Info.EnsureHash(context.Background(), nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rewritten with the same size and time, the file still hits the cache.
+	os.WriteFile(p, []byte("NOT REALLY A JPEG"), 0644)
+	os.Chtimes(p, st.ModTime(), st.ModTime())
+	res, _ = All(context.Background(), []string{p}, Options{Cache: cache})
+	after, err := res[0].Info.EnsureHash(context.Background(), nil, "")
+	if hits, _ := cache.Stats(); err != nil || hits != 1 || after == before {
+		t.Errorf("rescan: %d hits, digest %s, %v; want one other than %s", hits, after, err, before)
+	}
+}
@@ -0,0 +1,107 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tick is how often a running schedule checks whether its job is due.
+const Tick = 30 * time.Second
+
+// Job organizes every file of Source into Target, daily at At ("21:30") or, when At is empty,
+// every Every ("6h", "90m").
+type Job struct {
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	At      string `json:"at,omitempty"`
+	Every   string `json:"every,omitempty"`
+	// CatchUp runs a job missed while Lume was closed as soon as it starts again.
+	CatchUp bool `json:"catch_up"`
+	// LastRun is when the job last started; Lume keeps it up to date.
+	LastRun time.Time `json:"last_run,omitempty"`
+}
+
+// Check reports what keeps the job from running.
+func (j Job) Check() error {
+	if strings.TrimSpace(j.Source) == "" || strings.TrimSpace(j.Target) == "" {
+		return errors.New("schedule needs a source and a target folder")
+	}
+	if j.At != "" {
+		_, _, err := j.clock()
+		return err
+	}
+	d, err := time.ParseDuration(j.Every)
+	if err != nil {
+		return fmt.Errorf("invalid schedule interval %q", j.Every)
+	}
+	if d < time.Minute {
+		return fmt.Errorf("schedule interval %s is shorter than a minute", d)
+	}
+	return nil
+}
+
+func (j Job) clock() (hour, min int, err error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(j.At))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid schedule time %q, want HH:MM", j.At)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Next is the first time the job is due after after, in after's location. A job that does not
+// pass Check is never due.
+func (j Job) Next(after time.Time) time.Time {
+	if j.Check() != nil {
+		return time.Time{}
+	}
+	if j.At == "" {
+		d, _ := time.ParseDuration(j.Every)
+		return after.Add(d)
+	}
+	h, m, _ := j.clock()
+	y, mo, d := after.Date()
+	next := time.Date(y, mo, d, h, m, 0, 0, after.Location())
+	if !next.After(after) {
+		next = time.Date(y, mo, d+1, h, m, 0, 0, after.Location())
+	}
+	return next
+}
+
+// Anchor is the time the schedule counts from when Lume starts at now. A run missed since LastRun
+// is due at once with CatchUp; otherwise, and for a job that never ran, counting starts over.
+// Several missed runs are caught up by a single one.
+func (j Job) Anchor(now time.Time) time.Time {
+	if j.LastRun.IsZero() {
+		return now
+	}
+	if next := j.Next(j.LastRun); !j.CatchUp && !next.After(now) {
+		return now
+	}
+	return j.LastRun
+}
+
+// Run calls fire every time the job falls due until ctx is done, counting from anchor. fire gets
+// the due time; a job skipped by fire (e.g. because a manual run is going on) waits for its next
+// turn rather than firing again at the next tick.
+func (j Job) Run(ctx context.Context, anchor time.Time, fire func(due time.Time)) error {
+	if err := j.Check(); err != nil {
+		return err
+	}
+	ticker := time.NewTicker(Tick)
+	defer ticker.Stop()
+	for {
+		if next := j.Next(anchor); !next.After(time.Now()) {
+			anchor = time.Now()
+			fire(next)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
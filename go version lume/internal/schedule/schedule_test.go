@@ -0,0 +1,55 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2024, 3, 10, h, m, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		job   Job
+		after time.Time
+		want  time.Time
+	}{
+		{"later today", Job{Source: "a", Target: "b", At: "21:30"}, at(9, 0), at(21, 30)},
+		{"exactly due moves to tomorrow", Job{Source: "a", Target: "b", At: "21:30"}, at(21, 30), at(21, 30).AddDate(0, 0, 1)},
+		{"past today", Job{Source: "a", Target: "b", At: "02:00"}, at(9, 0), at(2, 0).AddDate(0, 0, 1)},
+		{"interval", Job{Source: "a", Target: "b", Every: "6h"}, at(9, 0), at(15, 0)},
+		{"time wins over interval", Job{Source: "a", Target: "b", At: "10:00", Every: "6h"}, at(9, 0), at(10, 0)},
+		{"no target", Job{Source: "a", At: "10:00"}, at(9, 0), time.Time{}},
+		{"bad time", Job{Source: "a", Target: "b", At: "25:00"}, at(9, 0), time.Time{}},
+		{"interval too short", Job{Source: "a", Target: "b", Every: "10s"}, at(9, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := tt.job.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("%s: Next = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnchor(t *testing.T) {
+	now := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	nightly := Job{Source: "a", Target: "b", At: "02:00"}
+
+	if got := nightly.Anchor(now); !got.Equal(now) {
+		t.Errorf("never run: Anchor = %v, want now", got)
+	}
+	// Last ran two nights ago, so last night's run was missed.
+	missed := nightly
+	missed.LastRun = now.AddDate(0, 0, -2)
+	if got := missed.Anchor(now); !got.Equal(now) {
+		t.Errorf("missed without catch-up: Anchor = %v, want now", got)
+	}
+	missed.CatchUp = true
+	if got := missed.Anchor(now); !missed.Next(got).Before(now) {
+		t.Errorf("missed with catch-up: next run %v should be due now", missed.Next(got))
+	}
+	// Last night's run happened; nothing to catch up.
+	done := missed
+	done.LastRun = time.Date(2024, 3, 10, 2, 0, 0, 0, time.UTC)
+	if next := done.Next(done.Anchor(now)); !next.Equal(done.LastRun.AddDate(0, 0, 1)) {
+		t.Errorf("up to date: next run %v, want tomorrow 02:00", next)
+	}
+}
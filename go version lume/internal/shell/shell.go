@@ -0,0 +1,96 @@
+// Package shell integrates Lume with Windows Explorer.
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ShortcutName is the entry Lume adds to the Explorer "Send to" menu.
+const ShortcutName = "Lume.lnk"
+
+// SendToDir returns the current user's "Send to" folder.
+func SendToDir() (string, error) {
+	appdata := os.Getenv("APPDATA")
+	if appdata == "" {
+		return "", errors.New("APPDATA is not set")
+	}
+	return filepath.Join(appdata, "Microsoft", "Windows", "SendTo"), nil
+}
+
+// CreateSendToShortcut adds a shortcut to exe to the "Send to" menu and returns its path. Explorer then
+// launches exe with the selected files as arguments, which a running Lume window picks up.
+func CreateSendToShortcut(exe string) (string, error) {
+	dir, err := SendToDir()
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(exe); err != nil {
+		return "", fmt.Errorf("executable: %w", err)
+	}
+	lnk := filepath.Join(dir, ShortcutName)
+	// .lnk files are COM objects; the shell's own WScript.Shell writes them without extra dependencies.
+	script := fmt.Sprintf("$s=(New-Object -ComObject WScript.Shell).CreateShortcut(%s);$s.TargetPath=%s;$s.WorkingDirectory=%s;$s.Save()",
+		psQuote(lnk), psQuote(exe), psQuote(filepath.Dir(exe)))
+	cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	hideWindow(cmd)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("create shortcut: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return lnk, nil
+}
+
+// psQuote makes s a PowerShell single-quoted literal; only the quote itself needs escaping there.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// OpenFolder opens dir in Explorer.
+func OpenFolder(dir string) error {
+	st, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("%s is not a folder", dir)
+	}
+	args, err := explorerArgs(dir, false)
+	if err != nil {
+		return err
+	}
+	return startExplorer(args)
+}
+
+// Reveal opens the folder holding path in Explorer with the file selected.
+func Reveal(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	args, err := explorerArgs(path, true)
+	if err != nil {
+		return err
+	}
+	return startExplorer(args)
+}
+
+// explorerArgs builds the raw argument string for explorer.exe. Explorer parses "/select," itself and
+// only accepts the quotes around the path after the comma, which the standard argument escaping of
+// os/exec would put around the whole switch instead.
+func explorerArgs(path string, selectFile bool) (string, error) {
+	if path == "" {
+		return "", errors.New("empty path")
+	}
+	// Windows file names cannot contain quotes, so one here can only be an injection attempt.
+	if strings.ContainsRune(path, '"') {
+		return "", fmt.Errorf("invalid path %q", path)
+	}
+	quoted := `"` + filepath.Clean(path) + `"`
+	if selectFile {
+		return "/select," + quoted, nil
+	}
+	return quoted, nil
+}
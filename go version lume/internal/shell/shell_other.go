@@ -0,0 +1,12 @@
+//go:build !windows
+
+package shell
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func hideWindow(*exec.Cmd) {}
+
+func startExplorer(string) error { return errors.New("explorer is only available on Windows") }
@@ -0,0 +1,39 @@
+package shell
+
+import "testing"
+
+func TestPSQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`C:\Program Files\Lume\lume.exe`, `'C:\Program Files\Lume\lume.exe'`},
+		{`C:\Users\O'Brien\SendTo\Lume.lnk`, `'C:\Users\O''Brien\SendTo\Lume.lnk'`},
+		{`C:\$env:x;rm -r`, `'C:\$env:x;rm -r'`}, // no expansion inside single quotes
+	}
+	for _, tt := range tests {
+		if got := psQuote(tt.in); got != tt.want {
+			t.Errorf("psQuote(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExplorerArgs(t *testing.T) {
+	tests := []struct {
+		path       string
+		selectFile bool
+		want       string
+		wantErr    bool
+	}{
+		{`D:\Archive`, false, `"D:\Archive"`, false},
+		{`D:\Archive\2024\01\IMG 0001.jpg`, true, `/select,"D:\Archive\2024\01\IMG 0001.jpg"`, false},
+		{`D:\Archive\a&b,c.jpg`, true, `/select,"D:\Archive\a&b,c.jpg"`, false},
+		{`D:\x" /e,"C:\`, false, "", true},
+		{"", true, "", true},
+	}
+	for _, tt := range tests {
+		got, err := explorerArgs(tt.path, tt.selectFile)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("explorerArgs(%q, %v) = %q, %v; want %q, err %v", tt.path, tt.selectFile, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow keeps helper consoles from flashing over the GUI.
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
+
+// startExplorer launches explorer.exe with a prebuilt command line. Explorer exits with status 1 even
+// on success, so it is only started and reaped in the background.
+func startExplorer(args string) error {
+	cmd := exec.Command("explorer.exe")
+	cmd.SysProcAttr = &syscall.SysProcAttr{CmdLine: "explorer.exe " + args}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
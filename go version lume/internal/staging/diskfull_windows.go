@@ -0,0 +1,16 @@
+package staging
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Win32 codes for a full volume; syscall only names a few Windows errors.
+const (
+	errorHandleDiskFull syscall.Errno = 39
+	errorDiskFull       syscall.Errno = 112
+)
+
+func isDiskFull(err error) bool {
+	return errors.Is(err, errorDiskFull) || errors.Is(err, errorHandleDiskFull)
+}
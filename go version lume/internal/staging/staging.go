@@ -0,0 +1,243 @@
+// Package staging copies files from places that do not last into a folder Lume owns, at drop time,
+// so a run organizes copies that stay put. A phone connected over MTP has no drive letter: Explorer
+// hands a drop from it over as shadow copies in a temporary folder, which are gone by the time a run
+// comes to them. Each window session stages into a folder of its own, removed once its files are
+// done with; Sweep removes those of sessions that ended without.
+package staging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"lume-go/internal/ioutil"
+	"lume-go/internal/lock"
+	"lume-go/internal/validator"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrFull is matched when the files to stage do not fit under the size cap of the area, or on the
+// disk it is on.
+var ErrFull = errors.New("staging area full")
+
+// FullError reports files that do not fit: Need bytes to stage, of which Have are left under the cap
+// of the area or, with Disk, free on its disk.
+type FullError struct {
+	Need, Have int64
+	Disk       bool
+}
+
+func (e *FullError) Error() string {
+	where := "under the cap"
+	if e.Disk {
+		where = "on the disk"
+	}
+	return fmt.Sprintf("%v: %d bytes to stage, %d left %s", ErrFull, e.Need, e.Have, where)
+}
+
+func (e *FullError) Is(target error) bool { return target == ErrFull }
+
+// shadowDir is the folder under the temp directory where Explorer shadows the files of portable
+// devices (Windows Portable Devices namespace extension).
+const shadowDir = "WPDNSE"
+
+// sessionPrefix names the folder of a session, after the process it belongs to.
+const sessionPrefix = "session-"
+
+// Ephemeral tells whether path only lasts as long as the drop that handed it over: a shadow copy of
+// a file on an MTP device. Other files under the temp directory are left to the run like any drop.
+func Ephemeral(path string) bool {
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '\\' || r == '/' }) {
+		if strings.EqualFold(part, shadowDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// Area is the staging folder of a session. It is safe for concurrent use.
+type Area struct {
+	dir  string
+	max  int64
+	free func(string) (int64, error) // replaced in tests
+
+	mu   sync.Mutex
+	used int64
+	n    int // the drops staged, each into a folder of its own
+}
+
+// Open returns the area of this session under root, holding at most max bytes; 0 is no cap but the
+// free space of its disk. Its folder is made on the first file staged.
+func Open(root string, max int64) *Area {
+	return &Area{dir: filepath.Join(root, sessionPrefix+strconv.Itoa(os.Getpid())), max: max, free: validator.FreeSpace}
+}
+
+// Dir is the folder of the area.
+func (a *Area) Dir() string { return a.dir }
+
+// Contains tells whether path is a staged copy.
+func (a *Area) Contains(path string) bool { return validator.IsSubPath(a.dir, path) }
+
+// Used is the bytes staged so far, those of copies still going included.
+func (a *Area) Used() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.used
+}
+
+// file is a file to stage and where its copy goes: dst, under the folder of the drop-th path staged.
+type file struct {
+	src, dst string
+	drop     int
+	info     fs.FileInfo
+}
+
+// Stage copies paths, files and folders alike, into the area and returns their copies in the same
+// order, a folder's copy holding the files under it as they were. progress is told the bytes copied
+// of the total as the copy goes. Nothing is copied when the files do not fit, under the cap or on the
+// disk, and an error matching ErrFull is returned; a copy that fails or is cancelled leaves nothing
+// of this call behind.
+//
+// The area is locked only to reserve the room and the folders of the copies and to give the room back
+// when they fail, so Used answers at once while a large copy goes on.
+func (a *Area) Stage(ctx context.Context, paths []string, progress func(done, total int64)) ([]string, error) {
+	var files []file
+	var total int64
+	for i, p := range paths {
+		err := filepath.Walk(p, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(p, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, file{src: path, dst: filepath.Join(filepath.Base(p), rel), drop: i, info: info})
+			total += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	first, err := a.reserve(total, len(paths))
+	if err != nil {
+		return nil, err
+	}
+	// Every path is copied into a folder of its own, numbered from the first one reserved.
+	folder := func(i int) string { return filepath.Join(a.dir, strconv.Itoa(first+i)) }
+	staged := make([]string, len(paths))
+	for i, p := range paths {
+		staged[i] = filepath.Join(folder(i), filepath.Base(p))
+	}
+	var done int64
+	for _, f := range files {
+		f.dst = filepath.Join(folder(f.drop), f.dst)
+		n, err := copyFile(ctx, f, func(n int64) {
+			if progress != nil {
+				progress(done+n, total)
+			}
+		})
+		done += n
+		if err != nil {
+			for i := range paths {
+				os.RemoveAll(folder(i))
+			}
+			a.mu.Lock()
+			a.used = max(a.used-total, 0) // Clean may have emptied the area meanwhile
+			a.mu.Unlock()
+			if isDiskFull(err) {
+				err = fmt.Errorf("%w: %w", ErrFull, err)
+			}
+			return nil, fmt.Errorf("staging %s: %w", f.src, err)
+		}
+	}
+	return staged, nil
+}
+
+// reserve takes total bytes of the area and count folders for a Stage, returning the number of the
+// first folder, or an error matching ErrFull when total does not fit.
+func (a *Area) reserve(total int64, count int) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.max > 0 && a.used+total > a.max {
+		return 0, &FullError{Need: total, Have: max(a.max-a.used, 0)}
+	}
+	if err := os.MkdirAll(a.dir, 0755); err != nil {
+		return 0, err
+	}
+	if free, err := a.free(a.dir); err == nil && free < total {
+		return 0, &FullError{Need: total, Have: free, Disk: true}
+	}
+	first := a.n
+	a.used += total
+	a.n += count
+	return first, nil
+}
+
+// copyFile copies f, keeping its modification time, which dates a file without other date.
+func copyFile(ctx context.Context, f file, progress func(int64)) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(f.dst), 0755); err != nil {
+		return 0, err
+	}
+	in, err := os.Open(f.src)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+	out, err := os.Create(f.dst)
+	if err != nil {
+		return 0, err
+	}
+	n, err := ioutil.CopyContext(ctx, out, in, nil, progress)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, os.Chtimes(f.dst, f.info.ModTime(), f.info.ModTime())
+}
+
+// Clean removes the area with whatever is left in it.
+func (a *Area) Clean() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.used, a.n = 0, 0
+	return os.RemoveAll(a.dir)
+}
+
+// Sweep removes the folders under root of sessions whose process no longer runs, left by a window
+// that was closed by a crash. It returns how many it removed.
+func Sweep(root string) (int, error) {
+	return sweep(root, lock.Alive)
+}
+
+func sweep(root string, alive func(int) bool) (int, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		pid, err := strconv.Atoi(strings.TrimPrefix(e.Name(), sessionPrefix))
+		if !e.IsDir() || !strings.HasPrefix(e.Name(), sessionPrefix) || err != nil || alive(pid) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, e.Name())); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
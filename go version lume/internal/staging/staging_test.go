@@ -0,0 +1,152 @@
+package staging
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEphemeral(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want bool
+	}{
+		{`C:\Users\ayse\AppData\Local\Temp\WPDNSE\{00000009-0001-0001-0000-000000000000}\IMG_0001.JPG`, true},
+		{"/media/wpdnse/{1}/IMG_0001.JPG", true},
+		{`C:\Users\ayse\AppData\Local\Temp\Temp1_photos.zip\IMG_0002.JPG`, false},
+		{filepath.Join(string(filepath.Separator), "Photos", "WPDNSE backup", "IMG_0003.JPG"), false},
+		{filepath.Join(string(filepath.Separator), "Photos", "IMG_0004.JPG"), false},
+	} {
+		if got := Ephemeral(tc.path); got != tc.want {
+			t.Errorf("Ephemeral(%s) = %v; want %v", tc.path, got, tc.want)
+		}
+	}
+}
+
+// phone lays out the files a drop from a phone hands over: a photo and a folder of them.
+func phone(t *testing.T) (photo, folder string, mod time.Time) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "WPDNSE", "{1}")
+	folder = filepath.Join(src, "Camera")
+	os.MkdirAll(filepath.Join(folder, "Burst"), 0755)
+	photo = filepath.Join(src, "IMG_0001.JPG")
+	mod = time.Date(2023, 7, 1, 9, 30, 0, 0, time.UTC)
+	for path, data := range map[string]string{photo: "photo one", filepath.Join(folder, "IMG_0002.JPG"): "photo two", filepath.Join(folder, "Burst", "IMG_0003.JPG"): "photo three"} {
+		os.WriteFile(path, []byte(data), 0644)
+		os.Chtimes(path, mod, mod)
+	}
+	return photo, folder, mod
+}
+
+func TestStageLifecycle(t *testing.T) {
+	photo, folder, mod := phone(t)
+	root := filepath.Join(t.TempDir(), "staging")
+	a := Open(root, 0)
+	var seen int64
+	staged, err := a.Stage(context.Background(), []string{photo, folder}, func(done, total int64) {
+		if done < seen || done > total || total != 29 {
+			t.Errorf("progress %d of %d after %d", done, total, seen)
+		}
+		// The copy does not hold the area: its room is reserved and can be asked for meanwhile.
+		if used := a.Used(); used != total {
+			t.Errorf("%d bytes used during the copy; want %d", used, total)
+		}
+		seen = done
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(staged) != 2 || seen != 29 || a.Used() != 29 {
+		t.Fatalf("staged %v, progress at %d, %d bytes used", staged, seen, a.Used())
+	}
+	for path, want := range map[string]string{staged[0]: "photo one", filepath.Join(staged[1], "IMG_0002.JPG"): "photo two", filepath.Join(staged[1], "Burst", "IMG_0003.JPG"): "photo three"} {
+		data, err := os.ReadFile(path)
+		if err != nil || string(data) != want {
+			t.Errorf("%s holds %q, %v; want %q", path, data, err, want)
+		}
+		if st, err := os.Stat(path); err != nil || !st.ModTime().Equal(mod) {
+			t.Errorf("%s lost its time: %v", path, err)
+		}
+		if !a.Contains(path) || Ephemeral(path) {
+			t.Errorf("%s not a lasting staged copy", path)
+		}
+	}
+	if filepath.Base(staged[0]) != "IMG_0001.JPG" || filepath.Base(staged[1]) != "Camera" {
+		t.Errorf("staged under other names: %v", staged)
+	}
+
+	// The same names dropped again are staged beside the first copies.
+	again, err := a.Stage(context.Background(), []string{photo}, nil)
+	if err != nil || again[0] == staged[0] {
+		t.Errorf("second drop staged to %v, %v", again, err)
+	}
+	if err := a.Clean(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(a.Dir()); !os.IsNotExist(err) || a.Used() != 0 {
+		t.Errorf("area left after Clean: %v, %d bytes used", err, a.Used())
+	}
+	if _, err := os.Stat(photo); err != nil {
+		t.Errorf("the dropped file went with the area: %v", err)
+	}
+}
+
+func TestStageFull(t *testing.T) {
+	photo, folder, _ := phone(t)
+	root := filepath.Join(t.TempDir(), "staging")
+
+	// Over the cap: nothing is copied.
+	a := Open(root, 20)
+	if _, err := a.Stage(context.Background(), []string{photo}, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := a.Stage(context.Background(), []string{folder}, nil)
+	var full *FullError
+	if !errors.As(err, &full) || !errors.Is(err, ErrFull) || full.Need != 20 || full.Have != 11 || full.Disk {
+		t.Fatalf("over the cap: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(a.Dir(), "1")); !os.IsNotExist(err) || a.Used() != 9 {
+		t.Errorf("files of a refused drop staged: %v, %d bytes used", err, a.Used())
+	}
+
+	// A full disk refuses it the same way.
+	b := Open(filepath.Join(t.TempDir(), "staging"), 0)
+	b.free = func(string) (int64, error) { return 10, nil }
+	if _, err := b.Stage(context.Background(), []string{folder}, nil); !errors.As(err, &full) || !full.Disk || full.Have != 10 {
+		t.Errorf("full disk: %v", err)
+	}
+
+	// A cancelled copy leaves nothing of its drop.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.max = 0
+	if _, err := a.Stage(ctx, []string{folder}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled stage: %v", err)
+	}
+	entries, _ := os.ReadDir(a.Dir())
+	if len(entries) != 1 || a.Used() != 9 {
+		t.Errorf("%d drops left in the area after a cancelled one, %d bytes used", len(entries), a.Used())
+	}
+}
+
+func TestSweep(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"session-100", "session-200", "session-x", "notes"} {
+		os.MkdirAll(filepath.Join(root, name, "0"), 0755)
+	}
+	n, err := sweep(root, func(pid int) bool { return pid == 100 })
+	if err != nil || n != 1 {
+		t.Fatalf("swept %d: %v", n, err)
+	}
+	for name, want := range map[string]bool{"session-100": true, "session-200": false, "session-x": true, "notes": true} {
+		if _, err := os.Stat(filepath.Join(root, name)); (err == nil) != want {
+			t.Errorf("%s kept = %v; want %v", name, err == nil, want)
+		}
+	}
+	if n, err := sweep(filepath.Join(root, "none"), nil); n != 0 || err != nil {
+		t.Errorf("missing root: %d, %v", n, err)
+	}
+}
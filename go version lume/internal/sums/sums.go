@@ -0,0 +1,289 @@
+// Package sums keeps SHA256SUMS files in an archive, one in each top folder (the year folders of the
+// default layout), in the format of GNU sha256sum so `sha256sum -c SHA256SUMS` run in that folder
+// verifies it without Lume.
+package sums
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"lume-go/internal/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the digest list of a top folder.
+const FileName = "SHA256SUMS"
+
+// Entry is one line of a digest list.
+type Entry struct {
+	// Hash is the lowercase hex SHA-256 of the file.
+	Hash string
+	// Name is the path of the file relative to the folder of the list, with forward slashes.
+	Name string
+}
+
+// nameEscaper and nameUnescaper apply sha256sum's escaping: a name holding a backslash, newline or
+// carriage return has them escaped and its line starts with a backslash. Other bytes, spaces and
+// UTF-8 included, are written as they are.
+var (
+	nameEscaper   = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+	nameUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\r`, "\r")
+)
+
+// String renders e as a line without its newline.
+func (e Entry) String() string {
+	if strings.ContainsAny(e.Name, "\\\n\r") {
+		return `\` + e.Hash + "  " + nameEscaper.Replace(e.Name)
+	}
+	return e.Hash + "  " + e.Name
+}
+
+// ParseLine reads a line written by String or by sha256sum, in text ("  ") or binary (" *") mode.
+func ParseLine(line string) (Entry, error) {
+	line = strings.TrimSuffix(line, "\r")
+	escaped := strings.HasPrefix(line, `\`)
+	if escaped {
+		line = line[1:]
+	}
+	if len(line) < sha256.Size*2+3 || line[sha256.Size*2] != ' ' || (line[sha256.Size*2+1] != ' ' && line[sha256.Size*2+1] != '*') {
+		return Entry{}, fmt.Errorf("malformed line %q", line)
+	}
+	e := Entry{Hash: strings.ToLower(line[:sha256.Size*2]), Name: line[sha256.Size*2+2:]}
+	if _, err := hex.DecodeString(e.Hash); err != nil {
+		return Entry{}, fmt.Errorf("malformed hash in %q", line)
+	}
+	if escaped {
+		e.Name = nameUnescaper.Replace(e.Name)
+	}
+	return e, nil
+}
+
+// Read returns the entries of the list at path; a missing list has none.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if sc.Text() == "" {
+			continue
+		}
+		e, err := ParseLine(sc.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// Write replaces the list at path with entries, sorted by name. The list is written beside it and
+// renamed over it, so a reader or a crash never sees half a list.
+func Write(path string, entries []Entry) error {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".lume_sums_*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	for _, e := range entries {
+		w.WriteString(e.String() + "\n")
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// HashFile returns the SHA-256 of the file at path in lowercase hex.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Folder returns the top folder under root that holds path, whose list covers it, and the name of
+// path in that list. Files directly in root (Lume's own files) and under folders Lume keeps for
+// itself are not listed.
+func Folder(root, path string) (dir, name string, ok bool) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return "", "", false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." || skip(parts[0]) || skip(parts[len(parts)-1]) {
+		return "", "", false
+	}
+	return filepath.Join(root, parts[0]), strings.Join(parts[1:], "/"), true
+}
+
+// skip reports the names never listed: the lists themselves, the month indexes, which change as files
+// are added, and Lume's own files and folders.
+func skip(name string) bool { return metadata.OwnFile(name) }
+
+// Sums queues the digests of files archived into root and merges them into the lists of their top
+// folders on Flush. Lists that cannot be written keep their entries queued for the next Flush.
+type Sums struct {
+	root    string
+	mu      sync.Mutex
+	pending map[string]map[string]string // top folder -> name -> hash
+}
+
+// New returns a Sums for the archive at root.
+func New(root string) *Sums {
+	return &Sums{root: root, pending: make(map[string]map[string]string)}
+}
+
+// Add hashes the archived file at path and queues its entry.
+func (s *Sums) Add(path string) error {
+	dir, name, ok := Folder(s.root, path)
+	if !ok {
+		return nil
+	}
+	hash, err := HashFile(path)
+	if err != nil {
+		return fmt.Errorf("sha256 of %s: %w", path, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[dir] == nil {
+		s.pending[dir] = make(map[string]string)
+	}
+	s.pending[dir][name] = hash
+	return nil
+}
+
+// Queued reports how many entries are still waiting to be written.
+func (s *Sums) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, names := range s.pending {
+		n += len(names)
+	}
+	return n
+}
+
+// Flush merges the queued entries into their lists, replacing older entries of the same name.
+func (s *Sums) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var first error
+	for dir, names := range s.pending {
+		path := filepath.Join(dir, FileName)
+		entries, err := Read(path)
+		if err == nil {
+			kept := entries[:0]
+			for _, e := range entries {
+				if _, ok := names[e.Name]; !ok {
+					kept = append(kept, e)
+				}
+			}
+			for name, hash := range names {
+				kept = append(kept, Entry{Hash: hash, Name: name})
+			}
+			err = Write(path, kept)
+		}
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		delete(s.pending, dir)
+	}
+	return first
+}
+
+// Close flushes the queue, retrying briefly while a list stays locked.
+func (s *Sums) Close() error {
+	var err error
+	for i := 0; i < 5; i++ {
+		if err = s.Flush(); err == nil {
+			return nil
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	return fmt.Errorf("sums: %d entries not written: %w", s.Queued(), err)
+}
+
+// Rebuild hashes every file of the archive at root and replaces the list of each top folder,
+// reporting its progress in files. A cancelled rebuild leaves the folders it has not finished with
+// their old lists. It returns the number of files listed.
+func Rebuild(ctx context.Context, root string, progress func(done, total int)) (int, error) {
+	folders := make(map[string][]string)
+	var order []string
+	total := 0
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skip(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		dir, name, ok := Folder(root, path)
+		if !ok || !d.Type().IsRegular() {
+			return nil
+		}
+		if folders[dir] == nil {
+			order = append(order, dir)
+		}
+		folders[dir] = append(folders[dir], name)
+		total++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	done, listed := 0, 0
+	for _, dir := range order {
+		entries := make([]Entry, 0, len(folders[dir]))
+		for _, name := range folders[dir] {
+			if err := ctx.Err(); err != nil {
+				return listed, err
+			}
+			hash, err := HashFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil {
+				return listed, err
+			}
+			entries = append(entries, Entry{Hash: hash, Name: name})
+			done++
+			if progress != nil {
+				progress(done, total)
+			}
+		}
+		if err := Write(filepath.Join(dir, FileName), entries); err != nil {
+			return listed, err
+		}
+		listed += len(entries)
+	}
+	return listed, nil
+}
@@ -0,0 +1,142 @@
+package sums
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// abc is the SHA-256 of "abc".
+const abc = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+
+func TestLineRoundTrip(t *testing.T) {
+	tests := []struct {
+		name, line string
+	}{
+		{"IMG_0001.jpg", abc + "  IMG_0001.jpg"},
+		{"03/Tatil fotoğrafı 1.jpg", abc + "  03/Tatil fotoğrafı 1.jpg"},
+		{" leading and trailing ", abc + "   leading and trailing "},
+		{`back\slash.jpg`, `\` + abc + `  back\\slash.jpg`},
+		{"new\nline.jpg", `\` + abc + `  new\nline.jpg`},
+		{"cr\r.jpg", `\` + abc + `  cr\r.jpg`},
+	}
+	for _, tt := range tests {
+		e := Entry{Hash: abc, Name: tt.name}
+		if got := e.String(); got != tt.line {
+			t.Errorf("String(%q) = %q, want %q", tt.name, got, tt.line)
+		}
+		if got, err := ParseLine(tt.line); err != nil || got != e {
+			t.Errorf("ParseLine(%q) = %+v, %v", tt.line, got, err)
+		}
+	}
+	if e, err := ParseLine(strings.ToUpper(abc) + " *binary.jpg"); err != nil || e.Hash != abc || e.Name != "binary.jpg" {
+		t.Errorf("binary mode line: %+v, %v", e, err)
+	}
+	for _, bad := range []string{"", abc, abc + " x", "zz" + abc[2:] + "  x.jpg", abc[1:] + "  x.jpg"} {
+		if _, err := ParseLine(bad); err == nil {
+			t.Errorf("ParseLine(%q) accepted", bad)
+		}
+	}
+}
+
+func TestFolder(t *testing.T) {
+	root := filepath.Join("archive")
+	tests := []struct {
+		path, dir, name string
+		ok              bool
+	}{
+		{filepath.Join(root, "2024", "03", "a b.jpg"), filepath.Join(root, "2024"), "03/a b.jpg", true},
+		{filepath.Join(root, "2024", "a.jpg"), filepath.Join(root, "2024"), "a.jpg", true},
+		{filepath.Join(root, "a.jpg"), "", "", false},
+		{filepath.Join(root, ".lume_trash", "a.jpg"), "", "", false},
+		{filepath.Join(root, "2024", FileName), "", "", false},
+		{filepath.Join(root, "2024", "03", "index.json"), "", "", false},
+		{filepath.Join("elsewhere", "2024", "a.jpg"), "", "", false},
+	}
+	for _, tt := range tests {
+		dir, name, ok := Folder(root, tt.path)
+		if dir != tt.dir || name != tt.name || ok != tt.ok {
+			t.Errorf("Folder(%s) = %q, %q, %v", tt.path, dir, name, ok)
+		}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAddAndRebuild(t *testing.T) {
+	root := t.TempDir()
+	year := filepath.Join(root, "2024")
+	write(t, filepath.Join(year, "01", "old.jpg"), "old")
+	write(t, filepath.Join(year, "01", "şehir gecesi.jpg"), "abc")
+	write(t, filepath.Join(year, "02", "new.jpg"), "new")
+	write(t, filepath.Join(root, ".lume_catalog.jsonl"), "{}")
+
+	s := New(root)
+	for _, p := range []string{filepath.Join(year, "01", "şehir gecesi.jpg"), filepath.Join(root, ".lume_catalog.jsonl")} {
+		if err := s.Add(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(filepath.Join(year, FileName))
+	if err != nil || len(got) != 1 || got[0] != (Entry{Hash: abc, Name: "01/şehir gecesi.jpg"}) {
+		t.Fatalf("after Add: %+v, %v", got, err)
+	}
+
+	// A second run merges into the list and replaces the entry of a file written again.
+	write(t, filepath.Join(year, "01", "şehir gecesi.jpg"), "changed")
+	s.Add(filepath.Join(year, "01", "şehir gecesi.jpg"))
+	s.Add(filepath.Join(year, "02", "new.jpg"))
+	if err := s.Flush(); err != nil || s.Queued() != 0 {
+		t.Fatalf("Flush: %v, %d queued", err, s.Queued())
+	}
+	got, _ = Read(filepath.Join(year, FileName))
+	want, _ := HashFile(filepath.Join(year, "01", "şehir gecesi.jpg"))
+	if len(got) != 2 || got[0].Name != "01/şehir gecesi.jpg" || got[0].Hash != want || got[1].Name != "02/new.jpg" {
+		t.Fatalf("after merge: %+v", got)
+	}
+
+	var steps int
+	n, err := Rebuild(context.Background(), root, func(done, total int) {
+		if steps++; done != steps || total != 3 {
+			t.Errorf("progress %d/%d at step %d", done, total, steps)
+		}
+	})
+	if err != nil || n != 3 {
+		t.Fatalf("Rebuild = %d, %v", n, err)
+	}
+	got, _ = Read(filepath.Join(year, FileName))
+	if len(got) != 3 || got[0].Name != "01/old.jpg" || got[2].Name != "02/new.jpg" {
+		t.Fatalf("after rebuild: %+v", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, FileName)); !os.IsNotExist(err) {
+		t.Errorf("list written at the archive root: %v", err)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(year, ".lume_sums_*"))
+	if len(leftovers) != 0 {
+		t.Errorf("temporary lists left: %v", leftovers)
+	}
+}
+
+func TestRebuildCancelled(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "2024", "a.jpg"), "a")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if n, err := Rebuild(ctx, root, nil); err != context.Canceled || n != 0 {
+		t.Fatalf("Rebuild = %d, %v", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "2024", FileName)); !os.IsNotExist(err) {
+		t.Errorf("list written by a cancelled rebuild: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Package trash deletes files recoverably: into the Recycle Bin on Windows, into a .lume_trash
+// folder beside the file elsewhere. When recycling fails the file is kept and the error returned;
+// a deletion never silently turns permanent.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DirName is the folder non-Windows builds move deleted files into.
+const DirName = ".lume_trash"
+
+// ErrNotRecycled reports a file that was kept because it could not be recycled.
+var ErrNotRecycled = errors.New("file kept, could not be recycled")
+
+// Remove deletes path recoverably, or permanently when hard is set.
+func Remove(path string, hard bool) error {
+	if hard {
+		return os.Remove(path)
+	}
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		err = recycle(abs)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrNotRecycled, path, err)
+	}
+	return nil
+}
+
+// moveToDir is the portable recycle: a rename into DirName next to the file, so it stays on the
+// same volume. Earlier entries of the same name are never overwritten.
+func moveToDir(path string) error {
+	dir := filepath.Join(filepath.Dir(path), DirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	dst := filepath.Join(dir, filepath.Base(path))
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dst = filepath.Join(dir, fmt.Sprintf("%d_%s", i, filepath.Base(path)))
+	}
+	return os.Rename(path, dst)
+}
@@ -0,0 +1,5 @@
+//go:build !windows
+
+package trash
+
+func recycle(path string) error { return moveToDir(path) }
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package trash
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		p := filepath.Join(dir, "a.jpg")
+		os.WriteFile(p, []byte{byte(i)}, 0644)
+		if err := Remove(p, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Lstat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Fatal("file still in place")
+		}
+	}
+	// Both deletions are kept, the second under a new name.
+	if entries, _ := os.ReadDir(filepath.Join(dir, DirName)); len(entries) != 2 {
+		t.Errorf("trash holds %d files, want 2", len(entries))
+	}
+
+	hard := filepath.Join(dir, "b.jpg")
+	os.WriteFile(hard, []byte("b"), 0644)
+	if err := Remove(hard, true); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, DirName)); len(entries) != 2 {
+		t.Error("hard delete went to the trash")
+	}
+	if err := Remove(filepath.Join(dir, "missing.jpg"), false); !errors.Is(err, ErrNotRecycled) {
+		t.Errorf("missing file: %v, want ErrNotRecycled", err)
+	}
+}
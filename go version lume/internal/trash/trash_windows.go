@@ -0,0 +1,56 @@
+package trash
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	foDelete           = 0x3
+	fofSilent          = 0x4
+	fofNoConfirmation  = 0x10
+	fofAllowUndo       = 0x40
+	fofNoErrorUI       = 0x400
+	fofWantNukeWarning = 0x4000
+)
+
+var procSHFileOperation = syscall.NewLazyDLL("shell32.dll").NewProc("SHFileOperationW")
+
+// shFileOpStruct is SHFILEOPSTRUCTW with the natural alignment of 64-bit Windows.
+type shFileOpStruct struct {
+	hwnd                  uintptr
+	wFunc                 uint32
+	pFrom                 *uint16
+	pTo                   *uint16
+	fFlags                uint16
+	fAnyOperationsAborted int32
+	hNameMappings         uintptr
+	lpszProgressTitle     *uint16
+}
+
+// recycle sends path to the Recycle Bin. A drive without one (many USB sticks and SD cards) would
+// delete permanently, so the shell is asked to warn first; declining keeps the file.
+func recycle(path string) error {
+	from, err := syscall.UTF16FromString(path)
+	if err != nil {
+		return err
+	}
+	from = append(from, 0) // pFrom is a double-NUL-terminated list
+	op := shFileOpStruct{
+		wFunc:  foDelete,
+		pFrom:  &from[0],
+		fFlags: fofAllowUndo | fofNoConfirmation | fofSilent | fofNoErrorUI | fofWantNukeWarning,
+	}
+	if r, _, _ := procSHFileOperation.Call(uintptr(unsafe.Pointer(&op))); r != 0 {
+		return syscall.Errno(r)
+	}
+	if op.fAnyOperationsAborted != 0 {
+		return errors.New("cancelled")
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return errors.New("still present")
+	}
+	return nil
+}
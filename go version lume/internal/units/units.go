@@ -0,0 +1,72 @@
+package units
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var multipliers = map[string]float64{
+	"": 1, "b": 1,
+	"k": 1 << 10, "kb": 1 << 10, "kib": 1 << 10,
+	"m": 1 << 20, "mb": 1 << 20, "mib": 1 << 20,
+	"g": 1 << 30, "gb": 1 << 30, "gib": 1 << 30,
+	"t": 1 << 40, "tb": 1 << 40, "tib": 1 << 40,
+}
+
+// ParseSize reads a byte count with an optional unit such as 512, 50KB, 1.5 MB or 2GiB.
+// Units are case-insensitive and binary (1 KB = 1024 bytes), matching how Lume reports sizes.
+func ParseSize(s string) (int64, error) {
+	in := strings.TrimSpace(s)
+	i := 0
+	for i < len(in) && (in[i] >= '0' && in[i] <= '9' || in[i] == '.') {
+		i++
+	}
+	num, unit := in[:i], strings.ToLower(strings.TrimSpace(in[i:]))
+	m, ok := multipliers[unit]
+	if num == "" || !ok {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(v * m), nil
+}
+
+// ParseRate reads a transfer rate in bytes per second, a size per second such as 20MB/s; the /s
+// may be left out.
+func ParseRate(s string) (int64, error) {
+	in := strings.TrimSpace(s)
+	if i := len(in) - 2; i >= 0 && strings.EqualFold(in[i:], "/s") {
+		in = in[:i]
+	}
+	n, err := ParseSize(in)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return n, nil
+}
+
+// Size is a byte count that reads from JSON either as a number or as a human string ("50KB").
+type Size int64
+
+// UnmarshalJSON accepts 51200 as well as "50KB".
+func (s *Size) UnmarshalJSON(data []byte) error {
+	var n int64
+	if err := json.Unmarshal(data, &n); err == nil {
+		*s = Size(n)
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	n, err := ParseSize(str)
+	if err != nil {
+		return err
+	}
+	*s = Size(n)
+	return nil
+}
@@ -0,0 +1,70 @@
+package units
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"512", 512, true},
+		{"50KB", 50 * 1024, true},
+		{"50 kb", 50 * 1024, true},
+		{"1.5MB", 1536 * 1024, true},
+		{"2GiB", 2 << 30, true},
+		{"2g", 2 << 30, true},
+		{"1TB", 1 << 40, true},
+		{"0", 0, true},
+		{"", 0, false},
+		{"KB", 0, false},
+		{"-5MB", 0, false},
+		{"10 parsecs", 0, false},
+		{"1.2.3MB", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d (ok=%v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"20MB/s", 20 << 20, true},
+		{"512 KB/S", 512 << 10, true},
+		{"1.5MB", 1536 << 10, true},
+		{"0/s", 0, true},
+		{"/s", 0, false},
+		{"fast", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseRate(%q) = %d, %v; want %d (ok=%v)", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSizeUnmarshal(t *testing.T) {
+	var v struct {
+		A, B Size
+	}
+	if err := json.Unmarshal([]byte(`{"A": 2048, "B": "2KB"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 2048 || v.B != 2048 {
+		t.Errorf("got %d, %d", v.A, v.B)
+	}
+	if err := json.Unmarshal([]byte(`{"A": "lots"}`), &v); err == nil {
+		t.Error("invalid size accepted")
+	}
+}
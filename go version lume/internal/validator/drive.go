@@ -0,0 +1,9 @@
+package validator
+
+// The kinds of drive DriveType tells apart.
+const (
+	DriveUnknown   = "unknown"
+	DriveFixed     = "fixed"
+	DriveRemovable = "removable"
+	DriveRemote    = "remote"
+)
@@ -0,0 +1,93 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The file systems Lume adapts to, as FileSystem names them.
+const (
+	FileSystemFAT32 = "FAT32"
+	FileSystemFAT   = "FAT" // FAT12 or FAT16, or a FAT volume whose kind Linux does not tell
+	FileSystemExFAT = "exFAT"
+)
+
+// FATMaxFile is the largest file FAT holds: 4 GiB less a byte.
+const FATMaxFile int64 = 1<<32 - 1
+
+// FATTimeGranularity is how coarsely FAT keeps modification times. exFAT can keep them to 10 ms,
+// but not every camera or driver writing it does, so it counts as FAT.
+const FATTimeGranularity = 2 * time.Second
+
+// ErrTooLarge is matched by a file larger than the file system of its target holds.
+var ErrTooLarge = errors.New("file too large for the target's file system")
+
+// SizeLimitError reports a file of Size bytes that the FileSystem of Path, holding files of at most
+// Limit bytes, cannot take. It matches ErrTooLarge.
+type SizeLimitError struct {
+	Path        string
+	FileSystem  string
+	Size, Limit int64
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("%s holds files up to %d bytes on %s: this one has %d", e.FileSystem, e.Limit, e.Path, e.Size)
+}
+
+func (e *SizeLimitError) Unwrap() error { return ErrTooLarge }
+
+// isFAT reports whether fs is one of the FAT file systems, exFAT included.
+func isFAT(fs string) bool {
+	return strings.EqualFold(fs, FileSystemFAT32) || strings.EqualFold(fs, FileSystemFAT) || strings.EqualFold(fs, FileSystemExFAT)
+}
+
+// MaxFileSize is the largest file the file system fs holds, 0 when Lume knows of no limit, as for
+// exFAT and NTFS.
+func MaxFileSize(fs string) int64 {
+	if strings.EqualFold(fs, FileSystemFAT32) || strings.EqualFold(fs, FileSystemFAT) {
+		return FATMaxFile
+	}
+	return 0
+}
+
+// TimeGranularity is how coarsely the file system fs keeps modification times, 0 when it keeps
+// them as they are set.
+func TimeGranularity(fs string) time.Duration {
+	if isFAT(fs) {
+		return FATTimeGranularity
+	}
+	return 0
+}
+
+// SameModTime reports whether the modification times a and b are the same on a file system of
+// granularity g (see TimeGranularity): a time written there comes back rounded to it.
+func SameModTime(a, b time.Time, g time.Duration) bool {
+	d := a.Sub(b).Abs()
+	return d == 0 || d < g
+}
+
+// TooLarge lists the paths of files (path to size) larger than the file system of target holds, as
+// fileSystem names it, largest first; fs is that file system. Nothing is listed for a file system
+// without a limit.
+func TooLarge(target string, files map[string]int64, fileSystem func(string) string) (fs string, paths []string) {
+	fs = fileSystem(target)
+	limit := MaxFileSize(fs)
+	if limit == 0 {
+		return fs, nil
+	}
+	for path, size := range files {
+		if size > limit {
+			paths = append(paths, path)
+		}
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		if files[paths[i]] != files[paths[j]] {
+			return files[paths[i]] > files[paths[j]]
+		}
+		return paths[i] < paths[j]
+	})
+	return fs, paths
+}
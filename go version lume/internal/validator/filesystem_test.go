@@ -0,0 +1,71 @@
+package validator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFileSystemLimits(t *testing.T) {
+	tests := []struct {
+		fs    string
+		max   int64
+		grain time.Duration
+	}{
+		{"FAT32", FATMaxFile, 2 * time.Second},
+		{"fat32", FATMaxFile, 2 * time.Second},
+		{"FAT", FATMaxFile, 2 * time.Second},
+		{"exFAT", 0, 2 * time.Second},
+		{"NTFS", 0, 0},
+		{"", 0, 0},
+	}
+	for _, tt := range tests {
+		if got := MaxFileSize(tt.fs); got != tt.max {
+			t.Errorf("MaxFileSize(%q) = %d; want %d", tt.fs, got, tt.max)
+		}
+		if got := TimeGranularity(tt.fs); got != tt.grain {
+			t.Errorf("TimeGranularity(%q) = %v; want %v", tt.fs, got, tt.grain)
+		}
+	}
+}
+
+func TestSameModTime(t *testing.T) {
+	set := time.Date(2024, 5, 1, 10, 0, 1, 500_000_000, time.UTC)
+	onFAT := time.Date(2024, 5, 1, 10, 0, 2, 0, time.UTC)
+	if SameModTime(set, onFAT, 0) {
+		t.Error("times half a second apart are the same where times are kept exactly")
+	}
+	if !SameModTime(set, onFAT, FATTimeGranularity) {
+		t.Error("a time rounded by FAT differs from the one set")
+	}
+	if SameModTime(set, onFAT.Add(2*time.Second), FATTimeGranularity) {
+		t.Error("times 2.5 seconds apart are the same on FAT")
+	}
+	if !SameModTime(set, set, 0) {
+		t.Error("a time differs from itself")
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	files := map[string]int64{"small.jpg": 1 << 20, "a.mp4": 5 << 30, "b.mp4": FATMaxFile + 1, "edge.mp4": FATMaxFile}
+	probe := func(fs string) func(string) string {
+		return func(target string) string {
+			if target != `E:\Archive` {
+				t.Errorf("probed %q", target)
+			}
+			return fs
+		}
+	}
+	fs, paths := TooLarge(`E:\Archive`, files, probe("FAT32"))
+	if want := []string{"a.mp4", "b.mp4"}; fs != "FAT32" || !reflect.DeepEqual(paths, want) {
+		t.Errorf("FAT32: %q, %v; want %v", fs, paths, want)
+	}
+	if fs, paths := TooLarge(`E:\Archive`, files, probe("exFAT")); fs != "exFAT" || paths != nil {
+		t.Errorf("exFAT: %q, %v; want none", fs, paths)
+	}
+	err := &SizeLimitError{Path: `E:\Archive`, FileSystem: fs, Size: files["a.mp4"], Limit: FATMaxFile}
+	if !errors.Is(err, ErrTooLarge) {
+		t.Error("SizeLimitError does not match ErrTooLarge")
+	}
+}
@@ -0,0 +1,179 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// caseInsensitive matches the file system rules of the platform: NTFS and FAT ignore case.
+var caseInsensitive = runtime.GOOS == "windows"
+
+// Canonical returns the absolute, cleaned form of path with links resolved. On Windows resolving also
+// expands 8.3 short names (PROGRA~1), so two spellings of one folder compare equal. Paths that do
+// not exist (yet) keep their cleaned absolute form.
+func Canonical(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	return filepath.Clean(path)
+}
+
+// IsSubPath reports whether child is parent itself or lies anywhere underneath it.
+func IsSubPath(parent, child string) bool {
+	if parent == "" || child == "" {
+		return false
+	}
+	return subPath(Canonical(parent), Canonical(child), string(filepath.Separator), caseInsensitive)
+}
+
+// SameFile reports whether a and b name the same file or folder, also through links, junctions and
+// short names. When either cannot be stat'ed the canonical paths are compared instead.
+func SameFile(a, b string) bool {
+	sa, errA := os.Stat(a)
+	sb, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(sa, sb)
+	}
+	return samePath(Canonical(a), Canonical(b), caseInsensitive)
+}
+
+// ErrTargetInSource matches a NestError.
+var ErrTargetInSource = errors.New("target is inside a source folder")
+
+// NestError reports a Target that lies inside the source folder Source, or is the folder of a source file.
+type NestError struct {
+	Target, Source string
+}
+
+func (e *NestError) Error() string {
+	return fmt.Sprintf("%v: %s is inside %s", ErrTargetInSource, e.Target, e.Source)
+}
+
+func (e *NestError) Unwrap() error { return ErrTargetInSource }
+
+// CheckNesting makes sure no target lies inside one of the source folders or is the folder holding
+// one of the source files: a run there would archive files into their own source, and they would
+// come back with the next scan of it.
+func CheckNesting(folders, files, targets []string) error {
+	for _, t := range targets {
+		for _, root := range folders {
+			if IsSubPath(root, t) {
+				return &NestError{Target: t, Source: root}
+			}
+		}
+		seen := make(map[string]bool)
+		for _, f := range files {
+			dir := filepath.Dir(f)
+			if seen[dir] {
+				continue
+			}
+			seen[dir] = true
+			if SameFile(dir, t) {
+				return &NestError{Target: t, Source: dir}
+			}
+		}
+	}
+	return nil
+}
+
+// subPath compares two cleaned absolute paths. A trailing separator is ignored except on a volume
+// root such as `D:\`, and "D:\Photos2" is not under "D:\Photos".
+func subPath(parent, child, sep string, fold bool) bool {
+	parent, child = trimSep(parent, sep), trimSep(child, sep)
+	if fold {
+		parent, child = strings.ToLower(parent), strings.ToLower(child)
+	}
+	if parent == child {
+		return true
+	}
+	if !strings.HasSuffix(parent, sep) {
+		parent += sep
+	}
+	return strings.HasPrefix(child, parent)
+}
+
+func samePath(a, b string, fold bool) bool {
+	if fold {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// trimSep drops trailing separators but keeps the one that makes a root (`/`, `D:\`).
+func trimSep(p, sep string) string {
+	for len(p) > 1 && strings.HasSuffix(p, sep) && !strings.HasSuffix(p, ":"+sep) {
+		p = strings.TrimSuffix(p, sep)
+	}
+	return p
+}
+
+// Reasons a path is refused by CheckPath.
+const (
+	// ReasonReserved is a component Windows reserves for a device (CON, NUL.jpg, COM4, ...).
+	ReasonReserved = "reserved"
+	// ReasonTrailing is a component ending in a dot or space, which Windows trims and then cannot find.
+	ReasonTrailing = "trailing"
+)
+
+// PathError reports the component of Path that CheckPath refused and why.
+type PathError struct {
+	Path, Component string
+	Reason          string
+}
+
+func (e *PathError) Error() string {
+	why := "a reserved device name"
+	if e.Reason == ReasonTrailing {
+		why = "ending in a dot or space"
+	}
+	return fmt.Sprintf("invalid path %s: %q is %s", e.Path, e.Component, why)
+}
+
+// reservedNames are the device names Windows reserves in every folder, with or without an extension.
+var reservedNames = map[string]bool{"CON": true, "PRN": true, "AUX": true, "NUL": true}
+
+func init() {
+	for i := 1; i <= 9; i++ {
+		reservedNames[fmt.Sprintf("COM%d", i)] = true
+		reservedNames[fmt.Sprintf("LPT%d", i)] = true
+	}
+}
+
+// IsReservedName reports whether Windows reserves name for a device. The extension does not matter:
+// "NUL.txt" and "com4.jpg" are as unusable as NUL and COM4.
+func IsReservedName(name string) bool {
+	stem, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+// CheckPath makes sure every component of path can be opened on Windows: none is a reserved device
+// name and none ends in a dot or space. The path is made absolute and cleaned first, so ".." is
+// resolved rather than refused and names like "img..jpg" are fine.
+func CheckPath(path string) error {
+	clean := path
+	if abs, err := filepath.Abs(path); err == nil {
+		clean = abs
+	}
+	clean = filepath.Clean(clean)
+	for _, c := range strings.FieldsFunc(strings.TrimPrefix(clean, filepath.VolumeName(clean)), isSep) {
+		if c == "." || c == ".." {
+			continue
+		}
+		if strings.HasSuffix(c, ".") || strings.HasSuffix(c, " ") {
+			return &PathError{Path: path, Component: c, Reason: ReasonTrailing}
+		}
+		if IsReservedName(c) {
+			return &PathError{Path: path, Component: c, Reason: ReasonReserved}
+		}
+	}
+	return nil
+}
+
+func isSep(r rune) bool { return r == '/' || r == '\\' }
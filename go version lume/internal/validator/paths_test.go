@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSubPathWindows(t *testing.T) {
+	tests := []struct {
+		parent, child string
+		want          bool
+	}{
+		{`C:\Photos`, `C:\Photos`, true},
+		{`C:\Photos`, `c:\photos\2024\IMG_1.jpg`, true},
+		{`C:\Photos\`, `C:\Photos`, true},
+		{`C:\Photos`, `C:\Photos\\`, true},
+		{`C:\Photos`, `C:\Photos2\IMG_1.jpg`, false},
+		{`C:\Photos\2024`, `C:\Photos`, false},
+		{`D:\`, `D:\Archive\2024`, true},
+		{`D:\`, `E:\Archive`, false},
+		{`\\NAS\share\Photos`, `\\nas\SHARE\photos\a.jpg`, true},
+		{`\\NAS\share\Photos`, `\\NAS\share\PhotosOld`, false},
+	}
+	for _, tt := range tests {
+		if got := subPath(tt.parent, tt.child, `\`, true); got != tt.want {
+			t.Errorf("subPath(%q, %q) = %v; want %v", tt.parent, tt.child, got, tt.want)
+		}
+	}
+	if subPath("/data/Photos", "/data/photos/a.jpg", "/", false) {
+		t.Error("case-sensitive file systems must not fold case")
+	}
+	if !subPath("/", "/data", "/", false) {
+		t.Error("everything is under the root")
+	}
+}
+
+func TestIsSubPathAndSameFile(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "archive")
+	os.MkdirAll(filepath.Join(target, "2024"), 0755)
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("symlinks unavailable:", err)
+	}
+
+	if !IsSubPath(target, filepath.Join(link, "2024")) {
+		t.Error("a path through a link into the target is not detected")
+	}
+	if !IsSubPath(target+string(filepath.Separator), filepath.Join(target, ".", "2024", "..", "2024")) {
+		t.Error("unclean spellings are not detected")
+	}
+	if IsSubPath(target, root) || IsSubPath("", target) {
+		t.Error("parent reported as sub path")
+	}
+	if !SameFile(target, link) || SameFile(target, root) {
+		t.Error("SameFile wrong")
+	}
+	if !SameFile(filepath.Join(root, "missing"), filepath.Join(root, ".", "missing")) {
+		t.Error("SameFile does not fall back to path comparison")
+	}
+}
+
+func TestCheckNesting(t *testing.T) {
+	root := t.TempDir()
+	dump, other := filepath.Join(root, "Dump"), filepath.Join(root, "Other")
+	os.MkdirAll(filepath.Join(dump, "Sorted"), 0755)
+	os.MkdirAll(other, 0755)
+	files := []string{filepath.Join(other, "a.jpg"), filepath.Join(other, "b.jpg")}
+	tests := []struct {
+		target, source string
+	}{
+		{filepath.Join(dump, "Sorted"), dump},
+		{dump, dump},
+		{other, other},
+		{filepath.Join(root, "Archive"), ""},
+		{root, ""},
+	}
+	for _, tt := range tests {
+		err := CheckNesting([]string{dump}, files, []string{filepath.Join(root, "Archive"), tt.target})
+		var ne *NestError
+		if tt.source == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.target, err)
+			}
+		} else if !errors.As(err, &ne) || !errors.Is(err, ErrTargetInSource) || ne.Target != tt.target || ne.Source != tt.source {
+			t.Errorf("%s: err = %v, want inside %s", tt.target, err, tt.source)
+		}
+	}
+}
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		reason string
+	}{
+		{`C:/Photos..2020/img..jpg`, ""},
+		{`C:/Photos/2020/../2021/a.jpg`, ""},
+		{`C:/Console/CONTRACT.jpg`, ""},
+		{`C:/x/CON/file.jpg`, ReasonReserved},
+		{`C:/x/con.jpg`, ReasonReserved},
+		{`C:/x/Nul .txt.jpg`, ReasonReserved},
+		{`C:/x/LPT7/a.jpg`, ReasonReserved},
+		{`C:/x/COM4.jpeg`, ReasonReserved},
+		{`C:/x/COM10.jpg`, ""},
+		{`C:/x/Trip./a.jpg`, ReasonTrailing},
+		{`C:/x/Trip /a.jpg`, ReasonTrailing},
+		{`C:\x\AUX\a.jpg`, ReasonReserved},
+	}
+	for _, tt := range tests {
+		err := CheckPath(tt.path)
+		var pe *PathError
+		if tt.reason == "" && err != nil {
+			t.Errorf("CheckPath(%q) = %v; want nil", tt.path, err)
+		} else if tt.reason != "" && (!errors.As(err, &pe) || pe.Reason != tt.reason || pe.Path != tt.path) {
+			t.Errorf("CheckPath(%q) = %v; want %s", tt.path, err, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ProtectMarker is the file that protects the folder holding it and everything below: no run, nor a
+// reorganization, writes into it. Its content does not matter.
+const ProtectMarker = ".lume_protected"
+
+// ErrProtected matches a ProtectedError.
+var ErrProtected = errors.New("folder is protected")
+
+// ProtectedError reports a Path that lies within Root, a protected folder.
+type ProtectedError struct {
+	Path, Root string
+}
+
+func (e *ProtectedError) Error() string {
+	return fmt.Sprintf("%v: %s is within %s", ErrProtected, e.Path, e.Root)
+}
+
+func (e *ProtectedError) Unwrap() error { return ErrProtected }
+
+// Protection tells the protected folders: those holding a ProtectMarker, those listed, and everything
+// below either. Each folder is looked at once, so a marker placed after that is only seen by a new
+// Protection. It is safe for concurrent use; a nil Protection protects nothing.
+type Protection struct {
+	paths []string
+	mu    sync.Mutex
+	roots map[string]string
+}
+
+// NewProtection protects paths as well as the folders that hold a marker.
+func NewProtection(paths []string) *Protection {
+	p := &Protection{roots: make(map[string]string)}
+	for _, path := range paths {
+		if path != "" {
+			p.paths = append(p.paths, Canonical(path))
+		}
+	}
+	return p
+}
+
+// Check returns a ProtectedError when path, a folder or a file, lies within a protected folder.
+func (p *Protection) Check(path string) error {
+	if p == nil {
+		return nil
+	}
+	if root := p.root(Canonical(path)); root != "" {
+		return &ProtectedError{Path: path, Root: root}
+	}
+	return nil
+}
+
+// root is the protected folder dir lies within, or "".
+func (p *Protection) root(dir string) string {
+	p.mu.Lock()
+	root, ok := p.roots[dir]
+	p.mu.Unlock()
+	if ok {
+		return root
+	}
+	if p.listed(dir) {
+		root = dir
+	} else if _, err := os.Lstat(filepath.Join(dir, ProtectMarker)); err == nil {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = p.root(parent)
+	}
+	p.mu.Lock()
+	p.roots[dir] = root
+	p.mu.Unlock()
+	return root
+}
+
+func (p *Protection) listed(dir string) bool {
+	for _, path := range p.paths {
+		if samePath(path, dir, caseInsensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package validator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProtection(t *testing.T) {
+	archive := t.TempDir()
+	for _, dir := range []string{"2015/06/Camera", "2016/01", "2024/05", "Listed/Deep"} {
+		os.MkdirAll(filepath.Join(archive, filepath.FromSlash(dir)), 0755)
+	}
+	os.WriteFile(filepath.Join(archive, "2015", ProtectMarker), nil, 0644)
+	// An unprotected folder inside a protected one is still protected.
+	os.WriteFile(filepath.Join(archive, "2016", "01", ProtectMarker), nil, 0644)
+	p := NewProtection([]string{filepath.Join(archive, "Listed")})
+
+	tests := []struct {
+		path, root string
+	}{
+		{"2015", "2015"},
+		{"2015/06/Camera", "2015"},
+		{"2015/06/Camera/IMG_1.jpg", "2015"},
+		{"2015/07/Not Yet", "2015"}, // a folder a run would create
+		{"2016/01", "2016/01"},
+		{"2016/02", ""},
+		{"2016", ""},
+		{"2024/05", ""},
+		{"", ""},
+		{"Listed/Deep", "Listed"},
+		{"Listed2", ""},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(archive, filepath.FromSlash(tt.path))
+		err := p.Check(path)
+		var pe *ProtectedError
+		switch {
+		case tt.root == "" && err != nil:
+			t.Errorf("Check(%s) = %v; want nil", tt.path, err)
+		case tt.root != "" && (!errors.Is(err, ErrProtected) || !errors.As(err, &pe) || pe.Root != Canonical(filepath.Join(archive, filepath.FromSlash(tt.root)))):
+			t.Errorf("Check(%s) = %v; want protected by %s", tt.path, err, tt.root)
+		}
+	}
+
+	var none *Protection
+	if err := none.Check(filepath.Join(archive, "2015")); err != nil {
+		t.Errorf("nil Protection: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package validator
+
+import "path/filepath"
+
+// SpaceMargin is kept free on a target on top of the bytes a run copies there, for the catalog, the
+// index and whatever else writes to the drive meanwhile.
+const SpaceMargin int64 = 64 << 20
+
+// VolumeFunc names the volume of a path, e.g. VolumeSerial. Paths on one volume give the same name.
+type VolumeFunc func(path string) (string, error)
+
+// SameVolume reports whether a and b are on one volume, so moving between them is a rename. A path
+// whose volume cannot be told counts as elsewhere.
+func SameVolume(a, b string, volume VolumeFunc) bool {
+	va, err := volume(a)
+	if err != nil {
+		return false
+	}
+	vb, err := volume(b)
+	return err == nil && va == vb
+}
+
+// Requirement is the free space a target needs for a run.
+type Requirement struct {
+	// Copy is the size of the files on other volumes, which are copied; Renamed counts the files on
+	// the target's volume, which are renamed in place and need no space.
+	Copy    int64
+	Renamed int
+	// Need is Copy plus SpaceMargin, or zero when nothing is copied.
+	Need int64
+}
+
+// SpaceNeeded works out the Requirement of moving files (path to size) into target. Volumes are
+// looked up once per source folder.
+func SpaceNeeded(target string, files map[string]int64, volume VolumeFunc) Requirement {
+	var req Requirement
+	tv, terr := volume(target)
+	dirs := make(map[string]bool)
+	for path, size := range files {
+		dir := filepath.Dir(path)
+		same, ok := dirs[dir]
+		if !ok {
+			v, err := volume(dir)
+			same = terr == nil && err == nil && v == tv
+			dirs[dir] = same
+		}
+		if same {
+			req.Renamed++
+		} else {
+			req.Copy += size
+		}
+	}
+	if req.Copy > 0 {
+		req.Need = req.Copy + SpaceMargin
+	}
+	return req
+}
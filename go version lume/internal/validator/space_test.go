@@ -0,0 +1,53 @@
+package validator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// driveOf resolves volumes by drive letter, like separate disks C: and D: with an unreadable X:. The
+// paths use forward slashes so filepath splits them on every platform.
+func driveOf(path string) (string, error) {
+	if len(path) < 2 || strings.HasPrefix(path, `X:`) {
+		return "", errors.New("no volume")
+	}
+	return strings.ToUpper(path[:2]), nil
+}
+
+func TestSpaceNeeded(t *testing.T) {
+	files := map[string]int64{
+		`D:/Inbox/a.jpg`:      300,
+		`d:/Inbox/b.jpg`:      200,
+		`C:/Camera/c.mp4`:     5000,
+		`C:/Camera/100/d.jpg`: 40,
+		`X:/Phone/e.jpg`:      7,
+	}
+	tests := []struct {
+		target string
+		want   Requirement
+	}{
+		{`D:/Archive`, Requirement{Copy: 5047, Renamed: 2, Need: 5047 + SpaceMargin}},
+		{`C:/Archive`, Requirement{Copy: 507, Renamed: 2, Need: 507 + SpaceMargin}},
+		{`X:/Archive`, Requirement{Copy: 5547, Need: 5547 + SpaceMargin}},
+	}
+	for _, tt := range tests {
+		if got := SpaceNeeded(tt.target, files, driveOf); got != tt.want {
+			t.Errorf("SpaceNeeded(%s) = %+v; want %+v", tt.target, got, tt.want)
+		}
+	}
+	if got := SpaceNeeded(`D:/Archive`, map[string]int64{`D:/Inbox/a.jpg`: 300}, driveOf); got != (Requirement{Renamed: 1}) {
+		t.Errorf("renames only = %+v; want no space needed", got)
+	}
+
+	// Volumes are resolved per folder, not per file.
+	calls := 0
+	counting := func(path string) (string, error) { calls++; return driveOf(path) }
+	SpaceNeeded(`D:/Archive`, map[string]int64{`C:/A/1.jpg`: 1, `C:/A/2.jpg`: 1, `C:/A/3.jpg`: 1, `C:/B/4.jpg`: 1}, counting)
+	if calls != 3 {
+		t.Errorf("%d volume lookups for two folders and the target", calls)
+	}
+	if !SameVolume(`C:/A`, `c:/B`, driveOf) || SameVolume(`C:/A`, `D:/B`, driveOf) || SameVolume(`X:/A`, `X:/B`, driveOf) {
+		t.Error("SameVolume")
+	}
+}
@@ -1,83 +1,174 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"lume-go/internal/fsys"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
-	"unsafe"
+	"time"
 )
 
+// Sentinel errors of the checks below; callers match them with errors.Is to pick a message.
+var (
+	ErrInsufficientSpace = errors.New("insufficient disk space")
+	ErrNotWritable       = errors.New("folder is not writable")
+)
+
+// SpaceError reports a failed disk space check with the amounts involved. It matches ErrInsufficientSpace.
+// Path is the drive checked, or a folder on it.
+type SpaceError struct {
+	Path       string
+	Need, Have int64
+}
+
+func (e *SpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space on %s: need %d bytes, have %d", e.Path, e.Need, e.Have)
+}
+
+func (e *SpaceError) Unwrap() error { return ErrInsufficientSpace }
+
 // CheckDiskSpace checks if there is enough space on the destination drive
 func CheckDiskSpace(path string, requiredBytes int64) error {
+	freeBytes, err := FreeSpace(path)
+	if err != nil {
+		return err
+	}
+	if freeBytes < requiredBytes {
+		volName, _ := volumeName(path)
+		return &SpaceError{Path: volName, Need: requiredBytes, Have: freeBytes}
+	}
+	return nil
+}
+
+// volumeName is the drive or UNC share of path.
+func volumeName(path string) (string, error) {
 	// Robust volume name detection for UNC or relative paths
 	volName := filepath.VolumeName(path)
 	if volName == "" {
 		absPath, err := filepath.Abs(path)
 		if err != nil {
-			return fmt.Errorf("could not resolve absolute path: %v", err)
+			return "", fmt.Errorf("could not resolve absolute path: %v", err)
 		}
 		volName = filepath.VolumeName(absPath)
 	}
-	
-	pathPtr, err := syscall.UTF16PtrFromString(volName + "\\")
-	if err != nil {
-		return err
-	}
+	return volName, nil
+}
+
+// WriteCause tells why CheckWritability refused a folder, so the message can say what to fix.
+type WriteCause int
 
-	var freeBytes int64
-	var totalBytes int64
-	var totalFreeBytes int64
+const (
+	WriteDenied   WriteCause = iota // no permission, or a cause not listed below
+	WriteMissing                    // the folder does not exist
+	WriteNotDir                     // the path is a file
+	WriteReadOnly                   // the volume is write-protected (e.g. a locked SD card)
+	WriteFull                       // the volume has no room left for even the probe
+)
 
-	kernel32 := syscall.NewLazyDLL("kernel32.dll")
-	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+// Windows errors that set the cause apart from a plain access denial.
+const (
+	errWriteProtect   = syscall.Errno(19)  // ERROR_WRITE_PROTECT
+	errHandleDiskFull = syscall.Errno(39)  // ERROR_HANDLE_DISK_FULL
+	errDiskFull       = syscall.Errno(112) // ERROR_DISK_FULL
+)
 
-	ret, _, err := getDiskFreeSpaceEx.Call(
-		uintptr(unsafe.Pointer(pathPtr)),
-		uintptr(unsafe.Pointer(&freeBytes)),
-		uintptr(unsafe.Pointer(&totalBytes)),
-		uintptr(unsafe.Pointer(&totalFreeBytes)),
-	)
+// WriteError reports a folder that failed CheckWritability, with the Cause. It matches ErrNotWritable
+// and the error behind it.
+type WriteError struct {
+	Path  string
+	Cause WriteCause
+	Err   error
+}
 
-	if ret == 0 {
-		return fmt.Errorf("failed to get disk space: %v", err)
+func (e *WriteError) Error() string {
+	switch e.Cause {
+	case WriteMissing:
+		return fmt.Sprintf("%v: target directory does not exist: %s", ErrNotWritable, e.Path)
+	case WriteNotDir:
+		return fmt.Sprintf("%v: not a directory: %s", ErrNotWritable, e.Path)
 	}
+	return fmt.Sprintf("%v: %v", ErrNotWritable, e.Err)
+}
 
-	if freeBytes < requiredBytes {
-		return fmt.Errorf("insufficient disk space: need %d bytes, have %d", requiredBytes, freeBytes)
-	}
+func (e *WriteError) Unwrap() []error { return []error{ErrNotWritable, e.Err} }
 
-	return nil
-}
+// probePattern names the files CheckWritability writes; probeStale is the age after which one left
+// behind by a process that died mid-check is swept away.
+const (
+	probePattern = ".lume_probe_*"
+	probeStale   = time.Hour
+)
+
+// CheckWritability verifies if the application has write permissions for the folder. It writes a
+// probe file under a unique name, so checks of the same folder by two apps at once do not collide.
+func CheckWritability(path string) error { return CheckWritabilityFS(fsys.OS, path) }
 
-// CheckWritability verifies if the application has write permissions for the folder
-func CheckWritability(path string) error {
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return fmt.Errorf("target directory does not exist: %s", path)
+// CheckWritabilityFS is CheckWritability on fs.
+func CheckWritabilityFS(fs fsys.FS, path string) error {
+	st, err := fs.Stat(path)
+	if os.IsNotExist(err) {
+		return &WriteError{Path: path, Cause: WriteMissing, Err: err}
+	} else if err != nil {
+		return &WriteError{Path: path, Cause: writeCause(err), Err: err}
+	} else if !st.IsDir() {
+		return &WriteError{Path: path, Cause: WriteNotDir, Err: syscall.ENOTDIR}
 	}
+	sweepProbes(fs, path)
 
-	tempFile := filepath.Join(path, ".lume_write_test")
-	err := os.WriteFile(tempFile, []byte("test"), 0644)
+	f, err := createProbe(fs, path)
 	if err != nil {
-		return fmt.Errorf("folder is not writable: %v", err)
+		return &WriteError{Path: path, Cause: writeCause(err), Err: err}
+	}
+	defer fs.Remove(f.Name())
+	_, err = f.Write([]byte("test"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return &WriteError{Path: path, Cause: writeCause(err), Err: err}
 	}
-	os.Remove(tempFile)
 	return nil
 }
 
-// IsPathSafe checks for reserved Windows names and traversal
-func IsPathSafe(path string) bool {
-	base := filepath.Base(path)
-	reserved := []string{"CON", "PRN", "AUX", "NUL", "COM1", "COM2", "COM3", "LPT1", "LPT2", "LPT3"}
-	upperBase := strings.ToUpper(base)
-	for _, r := range reserved {
-		if upperBase == r {
-			return false
+func writeCause(err error) WriteCause {
+	switch {
+	case errors.Is(err, errWriteProtect):
+		return WriteReadOnly
+	case errors.Is(err, errDiskFull), errors.Is(err, errHandleDiskFull):
+		return WriteFull
+	}
+	return WriteDenied
+}
+
+// createProbe creates a new probe file in dir the way os.CreateTemp does: the '*' of probePattern
+// becomes a random number, drawn again while the name is taken.
+func createProbe(fs fsys.FS, dir string) (fsys.File, error) {
+	prefix, suffix, _ := strings.Cut(probePattern, "*")
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, prefix+strconv.FormatUint(uint64(rand.Uint32()), 10)+suffix)
+		f, err := fs.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if errors.Is(err, os.ErrExist) && try < 10000 {
+			continue
 		}
+		return f, err
 	}
-	if strings.Contains(path, "..") {
-		return false
+}
+
+// sweepProbes removes stale probe files from dir, and the fixed-name one older versions used.
+func sweepProbes(fs fsys.FS, dir string) {
+	entries, _ := fs.ReadDir(dir)
+	for _, e := range entries {
+		if ok, _ := filepath.Match(probePattern, e.Name()); !ok && e.Name() != ".lume_write_test" {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		if st, err := fs.Stat(p); err == nil && time.Since(st.ModTime()) > probeStale {
+			fs.Remove(p)
+		}
 	}
-	return true
 }
@@ -0,0 +1,96 @@
+package validator
+
+import (
+	"errors"
+	"lume-go/internal/fsys"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCheckWritability(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckWritability(dir); err != nil {
+		t.Fatalf("writable folder: %v", err)
+	}
+	missing := filepath.Join(dir, "gone")
+	err := CheckWritability(missing)
+	var we *WriteError
+	if !errors.As(err, &we) || we.Cause != WriteMissing || we.Path != missing || !errors.Is(err, ErrNotWritable) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("missing folder: %#v", err)
+	}
+
+	file := filepath.Join(dir, "file.jpg")
+	os.WriteFile(file, []byte("x"), 0644)
+	if err := CheckWritability(file); !errors.As(err, &we) || we.Cause != WriteNotDir || !errors.Is(err, ErrNotWritable) {
+		t.Fatalf("file as folder: %#v", err)
+	}
+
+	probes, _ := filepath.Glob(filepath.Join(dir, probePattern))
+	if len(probes) != 0 {
+		t.Fatalf("probe files left behind: %v", probes)
+	}
+}
+
+func TestCheckWritabilitySweepsStaleProbes(t *testing.T) {
+	dir := t.TempDir()
+	stale, fresh := filepath.Join(dir, ".lume_probe_1"), filepath.Join(dir, ".lume_probe_2")
+	for _, p := range []string{stale, fresh, filepath.Join(dir, ".lume_write_test")} {
+		os.WriteFile(p, nil, 0644)
+	}
+	old := time.Now().Add(-2 * probeStale)
+	os.Chtimes(stale, old, old)
+	os.Chtimes(filepath.Join(dir, ".lume_write_test"), old, old)
+	if err := CheckWritability(dir); err != nil {
+		t.Fatal(err)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, ".lume_*"))
+	if len(left) != 1 || left[0] != fresh {
+		t.Fatalf("after sweep: %v, want only %s", left, fresh)
+	}
+}
+
+func TestCheckWritabilityFS(t *testing.T) {
+	m := fsys.NewMem()
+	dir := filepath.Join(string(filepath.Separator), "target")
+	var we *WriteError
+	if err := CheckWritabilityFS(m, dir); !errors.As(err, &we) || we.Cause != WriteMissing {
+		t.Errorf("missing folder: %v", err)
+	}
+	m.WriteFile(dir, []byte("a file"))
+	if err := CheckWritabilityFS(m, dir); !errors.As(err, &we) || we.Cause != WriteNotDir {
+		t.Errorf("file: %v", err)
+	}
+	m.Remove(dir)
+	stale := filepath.Join(dir, ".lume_probe_1")
+	m.WriteFile(stale, nil)
+	old := time.Now().Add(-2 * probeStale)
+	m.Chtimes(stale, old, old)
+	if err := CheckWritabilityFS(m, dir); err != nil {
+		t.Fatalf("writable folder: %v", err)
+	}
+	if entries, _ := m.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("left behind: %v", entries)
+	}
+}
+
+// A folder without the write bit stands in for a read-only one; where the bit does not stop writes
+// (Windows, or running as root) the test is skipped.
+func TestCheckWritabilityReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Skip(err)
+	}
+	defer os.Chmod(dir, 0755)
+	if f, err := os.CreateTemp(dir, "probe"); err == nil {
+		f.Close()
+		os.Remove(f.Name())
+		t.Skip("write bit not enforced here")
+	}
+	err := CheckWritability(dir)
+	var we *WriteError
+	if !errors.As(err, &we) || we.Cause != WriteDenied || !errors.Is(err, ErrNotWritable) || !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("read-only folder: %#v", err)
+	}
+}
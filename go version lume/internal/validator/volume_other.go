@@ -0,0 +1,58 @@
+//go:build !windows
+
+package validator
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// FreeSpace returns the bytes available to this user on the file system of path.
+func FreeSpace(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, fmt.Errorf("failed to get disk space: %v", err)
+	}
+	return int64(st.Bavail) * int64(st.Bsize), nil
+}
+
+// VolumeSerial identifies the file system holding path by its device number.
+func VolumeSerial(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", fmt.Errorf("volume of %s: no device number", path)
+	}
+	return fmt.Sprintf("%08X", uint64(st.Dev)), nil
+}
+
+// DriveType is DriveUnknown outside Windows, where drives are not told apart.
+func DriveType(path string) string {
+	return DriveUnknown
+}
+
+// The statfs magic numbers of the FAT file systems.
+const (
+	msdosMagic = 0x4d44
+	exfatMagic = 0x2011bab0
+)
+
+// FileSystem names the FAT file systems by their statfs magic number, FileSystemFAT for any of FAT12,
+// FAT16 and FAT32, which Linux does not tell apart; "" for any other.
+func FileSystem(path string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return ""
+	}
+	switch int64(st.Type) {
+	case msdosMagic:
+		return FileSystemFAT
+	case exfatMagic:
+		return FileSystemExFAT
+	}
+	return ""
+}
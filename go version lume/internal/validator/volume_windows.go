@@ -0,0 +1,103 @@
+package validator
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// FreeSpace returns the bytes available to this user on the drive of path.
+func FreeSpace(path string) (int64, error) {
+	volName, err := volumeName(path)
+	if err != nil {
+		return 0, err
+	}
+	pathPtr, err := syscall.UTF16PtrFromString(volName + "\\")
+	if err != nil {
+		return 0, err
+	}
+
+	var freeBytes int64
+	var totalBytes int64
+	var totalFreeBytes int64
+
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDiskFreeSpaceEx := kernel32.NewProc("GetDiskFreeSpaceExW")
+
+	ret, _, err := getDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&freeBytes)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFreeBytes)),
+	)
+
+	if ret == 0 {
+		return 0, fmt.Errorf("failed to get disk space: %v", err)
+	}
+	return freeBytes, nil
+}
+
+// VolumeSerial identifies the volume holding path by its serial number, which tells volumes apart
+// even when one is mounted in a folder of another or reached through a subst drive letter.
+func VolumeSerial(path string) (string, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return "", err
+	}
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	root := make([]uint16, 261)
+	ret, _, err := kernel32.NewProc("GetVolumePathNameW").Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&root[0])), uintptr(len(root)))
+	if ret == 0 {
+		return "", fmt.Errorf("volume of %s: %v", path, err)
+	}
+	var serial uint32
+	ret, _, err = kernel32.NewProc("GetVolumeInformationW").Call(uintptr(unsafe.Pointer(&root[0])), 0, 0, uintptr(unsafe.Pointer(&serial)), 0, 0, 0, 0)
+	if ret == 0 {
+		return "", fmt.Errorf("volume information of %s: %v", path, err)
+	}
+	return fmt.Sprintf("%08X", serial), nil
+}
+
+// DriveType tells the kind of drive path is on, as GetDriveType reports it for its volume: a fixed
+// disk (or a RAM disk), a removable one (USB sticks, memory cards, optical drives) or a network
+// share. Anything else is DriveUnknown.
+func DriveType(path string) string {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return DriveUnknown
+	}
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	root := make([]uint16, 261)
+	if ret, _, _ := kernel32.NewProc("GetVolumePathNameW").Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&root[0])), uintptr(len(root))); ret == 0 {
+		return DriveUnknown
+	}
+	t, _, _ := kernel32.NewProc("GetDriveTypeW").Call(uintptr(unsafe.Pointer(&root[0])))
+	switch t {
+	case 2, 5: // DRIVE_REMOVABLE, DRIVE_CDROM
+		return DriveRemovable
+	case 3, 6: // DRIVE_FIXED, DRIVE_RAMDISK
+		return DriveFixed
+	case 4: // DRIVE_REMOTE
+		return DriveRemote
+	}
+	return DriveUnknown
+}
+
+// FileSystem names the file system of the volume holding path as GetVolumeInformation reports it,
+// such as NTFS, FAT32 or exFAT; "" when it cannot be told.
+func FileSystem(path string) string {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return ""
+	}
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	root := make([]uint16, 261)
+	if ret, _, _ := kernel32.NewProc("GetVolumePathNameW").Call(uintptr(unsafe.Pointer(pathPtr)), uintptr(unsafe.Pointer(&root[0])), uintptr(len(root))); ret == 0 {
+		return ""
+	}
+	name := make([]uint16, 261)
+	if ret, _, _ := kernel32.NewProc("GetVolumeInformationW").Call(uintptr(unsafe.Pointer(&root[0])), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name))); ret == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(name)
+}
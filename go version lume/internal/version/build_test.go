@@ -0,0 +1,48 @@
+package version
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestBuildCommands builds both commands the way a release does, with the version stamped in by the
+// linker, and runs lume-lite --version. The GUI is built for Windows, the only system walk supports.
+func TestBuildCommands(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds both commands")
+	}
+	gotool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("no go command")
+	}
+	const pkg = "lume-go/internal/version"
+	ldflags := "-X " + pkg + ".Version=9.9.9-test -X " + pkg + ".Commit=abc1234 -X " + pkg + ".Date=2026-01-02"
+	out := t.TempDir()
+	lite := filepath.Join(out, "lume-lite")
+	if runtime.GOOS == "windows" {
+		lite += ".exe"
+	}
+	for _, c := range []struct{ pkg, goos, bin string }{
+		{"./cmd/lume-lite", runtime.GOOS, lite},
+		{"./cmd/lume", "windows", filepath.Join(out, "lume.exe")},
+	} {
+		cmd := exec.Command(gotool, "build", "-ldflags", ldflags, "-o", c.bin, c.pkg)
+		cmd.Dir = filepath.Join("..", "..")
+		cmd.Env = append(os.Environ(), "GOOS="+c.goos)
+		if msg, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go build %s for %s: %v\n%s", c.pkg, c.goos, err, msg)
+		}
+	}
+
+	got, err := exec.Command(lite, "--version").Output()
+	if err != nil {
+		t.Fatalf("lume-lite --version: %v", err)
+	}
+	if want := "lume-lite 9.9.9-test (commit abc1234, built 2026-01-02)"; strings.TrimSpace(string(got)) != want {
+		t.Errorf("lume-lite --version = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,50 @@
+// Package version is the version of the lume and lume-lite binaries. Release builds set it through
+// the linker:
+//
+//	go build -ldflags "-X lume-go/internal/version.Version=2.2 -X lume-go/internal/version.Commit=$(git rev-parse --short HEAD) -X lume-go/internal/version.Date=$(date -u +%Y-%m-%d)" ./cmd/lume-lite
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Version, Commit and Date describe the build; Commit and Date are empty unless the linker or the
+// go command's VCS stamp provides them.
+var (
+	Version = "2.1"
+	Commit  = ""
+	Date    = ""
+)
+
+// Build returns the commit and date of the build. Values the linker left empty are taken from the
+// VCS information the go command records when it builds inside a git checkout.
+func Build() (commit, date string) {
+	commit, date = Commit, Date
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && commit == "":
+				commit = s.Value
+			case s.Key == "vcs.time" && date == "":
+				date = s.Value
+			}
+		}
+	}
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	return commit, date
+}
+
+// String is the line --version prints for app, e.g. "lume 2.1 (commit 1a2b3c4, built 2026-10-16)".
+func String(app string) string {
+	commit, date := Build()
+	if commit == "" {
+		commit = "unknown"
+	}
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("%s %s (commit %s, built %s)", app, Version, commit, date)
+}
@@ -0,0 +1,111 @@
+package lume_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lume-go/pkg/lume"
+)
+
+// sampleCard makes a folder with two photos taken in May 2023, dated by their file times.
+func sampleCard() (card, target string) {
+	card, _ = os.MkdirTemp("", "card")
+	target, _ = os.MkdirTemp("", "archive")
+	taken := time.Date(2023, 5, 6, 12, 0, 0, 0, time.Local)
+	for _, name := range []string{"IMG_0001.JPG", "IMG_0002.JPG"} {
+		p := filepath.Join(card, name)
+		os.WriteFile(p, []byte(name), 0644)
+		os.Chtimes(p, taken, taken)
+	}
+	return card, target
+}
+
+func Example() {
+	card, target := sampleCard()
+	defer os.RemoveAll(card)
+	defer os.RemoveAll(target)
+	ctx := context.Background()
+	opts := lume.Options{Template: "{year}/{month}", Conflicts: lume.ConflictHash}
+
+	files, err := lume.Scan(ctx, []string{card}, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	plan, err := lume.Plan(files, target, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sum, err := lume.Execute(ctx, plan, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, r := range sum.Results {
+		rel, _ := filepath.Rel(target, r.Dest)
+		fmt.Println(filepath.ToSlash(rel))
+	}
+	fmt.Printf("%d moved, %d errors\n", sum.Moved, sum.Errors)
+	// Output:
+	// 2023/05/IMG_0001.JPG
+	// 2023/05/IMG_0002.JPG
+	// 2 moved, 0 errors
+}
+
+func ExampleScan() {
+	card, target := sampleCard()
+	defer os.RemoveAll(card)
+	defer os.RemoveAll(target)
+
+	files, err := lume.Scan(context.Background(), []string{card}, lume.Options{Hash: lume.HashSHA256, Filters: lume.Filters{Extensions: []string{".jpg"}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, f := range files {
+		fmt.Println(f.Name, f.Date.Format("2006-01-02"), f.DateSource, f.Hash[:12])
+	}
+	// Output:
+	// IMG_0001.JPG 2023-05-06 modified c8ae06e33f1e
+	// IMG_0002.JPG 2023-05-06 modified 772ee3644ae0
+}
+
+func ExamplePlan() {
+	card, target := sampleCard()
+	defer os.RemoveAll(card)
+	defer os.RemoveAll(target)
+	opts := lume.Options{Template: "{year}/{month}", NameTemplate: "{year}{month}{day}_{name}"}
+
+	files, _ := lume.Scan(context.Background(), []string{card}, opts)
+	plan, err := lume.Plan(files, target, opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, m := range plan.Moves {
+		rel, _ := filepath.Rel(target, m.Dest)
+		fmt.Println(m.File.Name, "->", filepath.ToSlash(rel))
+	}
+	// Output:
+	// IMG_0001.JPG -> 2023/05/20230506_IMG_0001.JPG
+	// IMG_0002.JPG -> 2023/05/20230506_IMG_0002.JPG
+}
+
+func ExampleExecute() {
+	card, target := sampleCard()
+	defer os.RemoveAll(card)
+	defer os.RemoveAll(target)
+	ctx := context.Background()
+
+	files, _ := lume.Scan(ctx, []string{card}, lume.Options{})
+	plan, _ := lume.Plan(files, target, lume.Options{})
+	_, err := lume.Execute(ctx, plan, func(done, total int, r lume.Result) {
+		fmt.Printf("%d/%d %s archived: %v\n", done, total, filepath.Base(r.Path), r.Archived)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// Output:
+	// 1/2 IMG_0001.JPG archived: true
+	// 2/2 IMG_0002.JPG archived: true
+}
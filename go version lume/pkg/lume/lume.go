@@ -0,0 +1,329 @@
+// Package lume is Lume's organizing pipeline for embedding in other programs: Scan reads the media
+// files under some sources, Plan works out where each of them goes in a target, and Execute moves
+// them there, verifying every copy. Execute runs the engine the lume and lume-lite commands organize
+// with; lume-lite's where command is built on Scan and Plan.
+//
+// This package is the module's stable surface and follows semantic versioning: within a major version
+// its identifiers are only added to, never removed or changed in meaning. Everything under internal,
+// the window and the config file included, may change at any time.
+package lume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"lume-go/internal/engine"
+	"lume-go/internal/metadata"
+	"lume-go/internal/organizer"
+	"lume-go/internal/scan"
+	"lume-go/internal/validator"
+	"lume-go/internal/version"
+)
+
+// Version is the version of Lume this package belongs to, as the release stamped it.
+func Version() string { return version.Version }
+
+// DefaultTemplate is the folder template of an Options without one.
+const DefaultTemplate = organizer.DefaultTemplate
+
+// Conflict policies: how a different file is named when its name is taken in the target.
+const (
+	ConflictNumber = organizer.ConflictNumber // IMG_0001_1.JPG: the first free _N
+	ConflictHash   = organizer.ConflictHash   // IMG_0001_a1b2c3d4.JPG: the start of the content hash
+)
+
+// Hash algorithms Scan can compute.
+const (
+	HashMD5    = metadata.HashMD5
+	HashSHA256 = metadata.HashSHA256
+)
+
+// Where the Date of a FileInfo came from.
+const (
+	DateFromExif     = metadata.DateFromExif
+	DateFromVideo    = metadata.DateFromVideo
+	DateFromCreation = metadata.DateFromCreation
+	DateFromFilename = metadata.DateFromFilename
+	DateFromModTime  = metadata.DateFromModTime
+	DateUndated      = metadata.DateUndated
+)
+
+// Errors a Result may wrap, to tell failures apart with errors.Is.
+var (
+	ErrSourceMissing     = organizer.ErrSourceMissing
+	ErrIntegrityMismatch = organizer.ErrIntegrityMismatch
+	ErrUnverified        = organizer.ErrUnverified
+	ErrDestinationExists = organizer.ErrDestinationExists
+	ErrRolledBack        = organizer.ErrRolledBack
+	ErrUnsupportedType   = organizer.ErrUnsupportedType
+	ErrInsufficientSpace = organizer.ErrInsufficientSpace
+	ErrNotWritable       = organizer.ErrNotWritable
+	ErrProtected         = organizer.ErrProtected
+)
+
+// Filters pick the files Scan takes from its sources.
+type Filters struct {
+	// MinSize and MaxSize bound the file size in bytes; 0 turns a bound off.
+	MinSize, MaxSize int64
+	// MinMegapixels leaves out smaller images; images whose dimensions cannot be read pass.
+	MinMegapixels float64
+	// Extensions, when set, takes only files with these extensions, e.g. ".jpg"; case is ignored. The
+	// rest of the supported types are left out. Empty takes every supported type.
+	Extensions []string
+	// IncludeHidden also takes hidden files and the files of hidden folders.
+	IncludeHidden bool
+}
+
+// Options are the settings of a run, from the scan to the moves.
+type Options struct {
+	// Template lays out the folders of the target, e.g. "{year}/{month}/{device}"; empty is
+	// DefaultTemplate. NameTemplate renames the files, e.g. "{year}{month}{day}_{time}"; empty keeps
+	// their names.
+	Template     string
+	NameTemplate string
+	// Conflicts is the conflict policy; empty is ConflictNumber.
+	Conflicts string
+	// Hash, when set, has Scan compute the digest of every file with it into FileInfo.Hash.
+	Hash    string
+	Filters Filters
+	// Workers bounds the files read at once; zero means one per CPU.
+	Workers int
+	// HardDelete deletes a copy that failed verification permanently instead of sending it to the
+	// Recycle Bin. Sources are never deleted: a source is only removed once its copy is verified,
+	// and the source of a duplicate is left where it is.
+	HardDelete bool
+}
+
+// FileInfo is a file Scan read.
+type FileInfo struct {
+	Path    string
+	Name    string
+	Size    int64
+	ModTime time.Time
+	// Date is when the photo or video was taken, as far as DateSource tells.
+	Date       time.Time
+	DateSource string
+	// Device names the camera or phone; empty when unknown.
+	Device string
+	// Width and Height are the pixel dimensions of an image; 0 when unknown.
+	Width, Height int
+	// Hash is the digest under Options.Hash; empty when none was asked for.
+	Hash string
+
+	info metadata.FileInfo
+}
+
+// RunPlan is what Execute does: every file and where it goes.
+type RunPlan struct {
+	Target  string
+	Moves   []Move
+	Options Options
+}
+
+// Move is one file of a plan. Dest is where it goes, before a conflict renames it; a file InPlace is
+// already there and stays.
+type Move struct {
+	File    FileInfo
+	Dest    string
+	InPlace bool
+}
+
+// Result is the outcome of one file of Execute.
+type Result struct {
+	Path string
+	// Dest is where the file was archived, or the archived copy it duplicates.
+	Dest string
+	Size int64
+	// Archived, InPlace and Missing are the outcomes that are not errors: moved into the target,
+	// already there, or gone from its source before its turn. Err is set on the others.
+	Archived, InPlace, Missing bool
+	// DuplicateOf, when set, is the file this one is a copy of: another file of the plan, archived as
+	// Dest, or the copy at Dest the target already held. It is not an error either; the file is left
+	// in its source.
+	DuplicateOf string
+	Err         error
+}
+
+// Summary is a finished Execute.
+type Summary struct {
+	Results []Result
+	// Total files; Moved ones and their bytes; Missing sources; InPlace files; Duplicates of another
+	// file of the plan; Errors counts the rest, the ones a cancelled run never reached included.
+	Total, Moved, Missing, InPlace, Duplicates, Errors int
+	Bytes                                              int64
+}
+
+// Progress is told of every file Execute finished: done of total, and its result. It is called from
+// the goroutine of Execute; nil reports nothing.
+type Progress func(done, total int, r Result)
+
+// Scan lists the supported media files under sources, each a folder or a single file, that pass the
+// filters of opts, and reads their metadata. Files that cannot be read are left out. After a
+// cancellation it returns the files read so far together with ctx's error.
+func Scan(ctx context.Context, sources []string, opts Options) ([]FileInfo, error) {
+	if opts.Hash != "" && opts.Hash != HashMD5 && opts.Hash != HashSHA256 {
+		return nil, fmt.Errorf("unknown hash algorithm %q", opts.Hash)
+	}
+	f := opts.Filters
+	paths, _ := engine.Collect(sources, nil, engine.Filters{MinSize: f.MinSize, MaxSize: f.MaxSize, MinMegapixels: f.MinMegapixels, IncludeHidden: f.IncludeHidden})
+	if len(f.Extensions) > 0 {
+		paths = slices.DeleteFunc(paths, func(p string) bool {
+			return !slices.ContainsFunc(f.Extensions, func(ext string) bool { return strings.EqualFold(filepath.Ext(p), ext) })
+		})
+	}
+	res, err := scan.All(ctx, paths, scan.Options{Workers: opts.Workers})
+	files := make([]FileInfo, 0, len(res))
+	for _, r := range res {
+		if r.Err != nil {
+			continue
+		}
+		fi := fileInfo(r.Info)
+		if opts.Hash != "" {
+			h, herr := fi.info.EnsureHash(ctx, nil, opts.Hash)
+			if herr != nil {
+				if ctx.Err() != nil {
+					return files, ctx.Err()
+				}
+				continue
+			}
+			fi.Hash = h
+		}
+		files = append(files, fi)
+	}
+	return files, err
+}
+
+func fileInfo(info metadata.FileInfo) FileInfo {
+	return FileInfo{Path: info.Path, Name: info.Filename, Size: info.Size, ModTime: info.ModTime, Date: info.Date, DateSource: info.DateSource,
+		Device: info.Device, Width: info.Width, Height: info.Height, info: info}
+}
+
+// token matches the placeholders of a template.
+var token = regexp.MustCompile(`\{[^{}]*\}`)
+
+// checkTemplate refuses a template with a placeholder it does not know.
+func checkTemplate(template string, known []string) error {
+	for _, t := range token.FindAllString(template, -1) {
+		if !slices.Contains(known, t) {
+			return fmt.Errorf("unknown placeholder %s in template %q", t, template)
+		}
+	}
+	return nil
+}
+
+func (o Options) layout() organizer.Layout {
+	return organizer.Layout{Template: o.Template, NameTemplate: o.NameTemplate}
+}
+
+// Plan works out where each of files goes in target under opts, without touching either. Files of one
+// device shot in the same second are numbered for {seq} here, so plan them together.
+func Plan(files []FileInfo, target string, opts Options) (RunPlan, error) {
+	if target == "" {
+		return RunPlan{}, errors.New("no target")
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return RunPlan{}, err
+	}
+	if st, err := os.Stat(target); err != nil {
+		return RunPlan{}, err
+	} else if !st.IsDir() {
+		return RunPlan{}, fmt.Errorf("target %s is not a folder", target)
+	}
+	if err := checkTemplate(opts.Template, organizer.TemplateTokens); err != nil {
+		return RunPlan{}, err
+	}
+	if err := checkTemplate(opts.NameTemplate, append(slices.Clone(organizer.TemplateTokens), organizer.NameTokens...)); err != nil {
+		return RunPlan{}, err
+	}
+	switch opts.Conflicts {
+	case "", ConflictNumber, ConflictHash:
+	default:
+		return RunPlan{}, fmt.Errorf("unknown conflict policy %q", opts.Conflicts)
+	}
+	infos := make([]metadata.FileInfo, len(files))
+	for i, f := range files {
+		infos[i] = f.info
+	}
+	organizer.MarkSequences(infos)
+	layout := opts.layout()
+	p := RunPlan{Target: target, Moves: make([]Move, len(infos)), Options: opts}
+	for i, info := range infos {
+		f := files[i]
+		f.info = info
+		if organizer.InPlace(info, target, layout) {
+			p.Moves[i] = Move{File: f, Dest: info.Path, InPlace: true}
+			continue
+		}
+		name := organizer.SanitizeFileName(organizer.FileName(info, layout))
+		p.Moves[i] = Move{File: f, Dest: filepath.Join(organizer.TargetDir(info, target, layout), name)}
+	}
+	return p, nil
+}
+
+// SanitizeFileName makes a file name valid on Windows the way Execute names the files it archives:
+// forbidden characters become underscores, trailing dots and spaces go and a reserved base gets a
+// suffix. The extension is kept; valid names come back unchanged.
+func SanitizeFileName(name string) string { return organizer.SanitizeFileName(name) }
+
+// SanitizeFolderName makes a folder name valid on Windows the way the folders of a template are named.
+func SanitizeFolderName(name string) string { return organizer.SanitizeFolderName(name) }
+
+// progress adapts a Progress to the engine's.
+type progress Progress
+
+func (p progress) Phase(string) {}
+
+func (p progress) Step(done, total int, r engine.OrganizeResult) {
+	if p != nil {
+		p(done, total, result(r))
+	}
+}
+
+// result is r for the caller. The engine counts a file already archived as moved, its source left
+// where it is, which is how it is told apart here.
+func result(r engine.OrganizeResult) Result {
+	res := Result{Path: r.Path, Dest: r.Dest, Size: r.Size, Archived: r.Success, InPlace: r.InPlace, Missing: r.Missing, DuplicateOf: r.DuplicateOf, Err: r.Error}
+	if r.Success && r.DuplicateOf == "" && !r.Unverified && r.Dest != r.Path {
+		if _, err := os.Lstat(r.Path); err == nil {
+			res.DuplicateOf = r.Dest
+		}
+	}
+	res.Archived = res.Archived && res.DuplicateOf == ""
+	return res
+}
+
+// Execute moves the files of p into its target, verifying each copy before its source is removed.
+// Cancelling ctx stops it, rolling back a copy in flight; the summary then covers the files done.
+func Execute(ctx context.Context, p RunPlan, prog Progress) (Summary, error) {
+	if err := validator.CheckWritability(p.Target); err != nil {
+		return Summary{}, err
+	}
+	infos := make([]metadata.FileInfo, len(p.Moves))
+	for i, m := range p.Moves {
+		infos[i] = m.File.info
+	}
+	o := p.Options
+	sum, err := engine.Run(ctx, engine.RunSpec{Files: infos, Progress: progress(prog), Options: engine.Options{Target: p.Target, Layout: o.layout(), Conflicts: o.Conflicts,
+		HardDelete: o.HardDelete, Workers: o.Workers}})
+	s := Summary{Total: sum.Total, Missing: sum.Missing, InPlace: sum.InPlace, Errors: sum.Errors}
+	for _, r := range sum.Results {
+		res := result(r)
+		switch {
+		case res.Archived:
+			s.Moved++
+			s.Bytes += res.Size
+		case res.DuplicateOf != "":
+			s.Duplicates++
+		}
+		s.Results = append(s.Results, res)
+	}
+	return s, err
+}
@@ -0,0 +1,78 @@
+package lume
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanRefusesBadOptions(t *testing.T) {
+	target := t.TempDir()
+	for _, opts := range []Options{
+		{Template: "{year}/{camera}"},
+		{NameTemplate: "{date}_{name}"},
+		{Conflicts: "overwrite"},
+	} {
+		if _, err := Plan(nil, target, opts); err == nil {
+			t.Errorf("%+v planned without an error", opts)
+		}
+	}
+	if _, err := Plan(nil, filepath.Join(target, "missing"), Options{}); err == nil {
+		t.Error("a missing target planned without an error")
+	}
+	if _, err := Plan(nil, target, Options{Template: "{year}/{month}/{device}", NameTemplate: "{year}{month}{day}_{seq}_{name}"}); err != nil {
+		t.Errorf("valid templates refused: %v", err)
+	}
+}
+
+func TestScanFilters(t *testing.T) {
+	src := t.TempDir()
+	for name, size := range map[string]int{"a.jpg": 10, "b.JPG": 100, "c.mp4": 100, "notes.txt": 100} {
+		os.WriteFile(filepath.Join(src, name), make([]byte, size), 0644)
+	}
+	files, err := Scan(context.Background(), []string{src}, Options{Filters: Filters{MinSize: 50, Extensions: []string{".jpg"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name != "b.JPG" {
+		t.Errorf("scanned %+v; want only b.JPG", files)
+	}
+	if _, err := Scan(context.Background(), []string{src}, Options{Hash: "crc32"}); err == nil {
+		t.Error("an unknown hash algorithm scanned without an error")
+	}
+}
+
+func TestExecuteDuplicates(t *testing.T) {
+	src, target := t.TempDir(), t.TempDir()
+	os.WriteFile(filepath.Join(src, "a.jpg"), []byte("same photo"), 0644)
+	os.WriteFile(filepath.Join(src, "b.jpg"), []byte("same photo"), 0644)
+	os.WriteFile(filepath.Join(src, "c.jpg"), []byte("other photo"), 0644)
+	files, err := Scan(context.Background(), []string{src}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Plan(files, target, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := Execute(context.Background(), p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Total != 3 || sum.Moved != 2 || sum.Duplicates != 1 || sum.Errors != 0 {
+		t.Errorf("summary %+v; want 2 moved and 1 duplicate of 3", sum)
+	}
+	if n := sum.Moved + sum.Missing + sum.InPlace + sum.Duplicates + sum.Errors; n != sum.Total {
+		t.Errorf("the outcomes add up to %d of %d", n, sum.Total)
+	}
+	for _, r := range sum.Results {
+		if dup := r.DuplicateOf != ""; dup == r.Archived || r.Err != nil {
+			t.Errorf("%s: archived %v, duplicate of %q, %v", r.Path, r.Archived, r.DuplicateOf, r.Err)
+		} else if dup {
+			if _, err := os.Stat(r.Path); err != nil {
+				t.Errorf("duplicate %s not left in its source: %v", r.Path, err)
+			}
+		}
+	}
+}